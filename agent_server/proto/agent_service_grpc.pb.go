@@ -32,6 +32,144 @@ type AgentServiceClient interface {
 	ReportFirewallStatus(ctx context.Context, in *FirewallStatusRequest, opts ...grpc.CallOption) (*FirewallStatusResponse, error)
 	// 5. إرسال تقرير بالتطبيقات المثبتة
 	ReportInstalledApps(ctx context.Context, in *InstalledAppsRequest, opts ...grpc.CallOption) (*InstalledAppsResponse, error)
+	// تقارير الجرد الموسع، كل تقرير لقطة كاملة مثل التطبيقات المثبتة
+	ReportNetworkInterfaces(ctx context.Context, in *NetworkInterfacesRequest, opts ...grpc.CallOption) (*NetworkInterfacesResponse, error)
+	ReportLocalUsers(ctx context.Context, in *LocalUsersRequest, opts ...grpc.CallOption) (*LocalUsersResponse, error)
+	ReportServices(ctx context.Context, in *ServicesRequest, opts ...grpc.CallOption) (*ServicesResponse, error)
+	ReportListeningSockets(ctx context.Context, in *ListeningSocketsRequest, opts ...grpc.CallOption) (*ListeningSocketsResponse, error)
+	ReportProcesses(ctx context.Context, in *ProcessesRequest, opts ...grpc.CallOption) (*ProcessesResponse, error)
+	// حالة تحديثات النظام: المثبتة والمعلقة وآخر تحديث وهل ينتظر إعادة التشغيل
+	ReportPatchStatus(ctx context.Context, in *PatchStatusRequest, opts ...grpc.CallOption) (*PatchStatusResponse, error)
+	// تحديث برنامج الوكيل: ينزل الوكيل الحزمة المعروضة في RegisterResponse أو HeartbeatResponse
+	// أجزاءً (ويمكنه الاستئناف من offset)، ثم يبلغ عن نتيجة التثبيت
+	DownloadAgentPackage(ctx context.Context, in *DownloadAgentPackageRequest, opts ...grpc.CallOption) (AgentService_DownloadAgentPackageClient, error)
+	ReportUpdateResult(ctx context.Context, in *UpdateResultRequest, opts ...grpc.CallOption) (*UpdateResultResponse, error)
+	// جمع الملفات: الوكيل يرفع الملف المطلوب بأمر COLLECT_FILE أجزاءً، وبعد انقطاع الرفع
+	// يسأل عن عدد البايتات التي وصلت ويستأنف منها
+	GetFileUploadStatus(ctx context.Context, in *FileUploadStatusRequest, opts ...grpc.CallOption) (*FileUploadStatusResponse, error)
+	UploadCollectedFile(ctx context.Context, opts ...grpc.CallOption) (AgentService_UploadCollectedFileClient, error)
+	// --------------------------- SERVICES (متعلق السيرفر يرسل للوكيل) ---------------------------
+	// 6. تكوين جدار الحماية: السيرفر يضع أمرًا في طابور الوكيل لتعديل إعدادات جدار الحماية
+	ConfigureFirewall(ctx context.Context, in *FirewallConfigurationRequest, opts ...grpc.CallOption) (*FirewallConfigurationResponse, error)
+	// 7. الوكيل يسحب الأوامر المعلقة له
+	PollCommands(ctx context.Context, in *PollCommandsRequest, opts ...grpc.CallOption) (*PollCommandsResponse, error)
+	// 8. الوكيل يبلغ عن نتيجة تنفيذ أمر
+	ReportCommandResult(ctx context.Context, in *CommandResultRequest, opts ...grpc.CallOption) (*CommandResultResponse, error)
+	// تنفيذ سكربت على الوكيل: نص السكربت أو اسمه في مكتبة الخادم، وما خارج القائمة المسموحة ينتظر الموافقة
+	RunScript(ctx context.Context, in *RunScriptRequest, opts ...grpc.CallOption) (*RunScriptResponse, error)
+	// الوكيل يرسل مخرجات السكربت أجزاءً أثناء التنفيذ، ثم رمز الخروج مع ReportCommandResult
+	StreamCommandOutput(ctx context.Context, opts ...grpc.CallOption) (AgentService_StreamCommandOutputClient, error)
+	// --------------------------- SERVICES (الاستعلام والإدارة) ---------------------------
+	// 9. قائمة الوكلاء مع التصفية حسب الحالة
+	ListAgents(ctx context.Context, in *ListAgentsRequest, opts ...grpc.CallOption) (*ListAgentsResponse, error)
+	// 10. إيقاف وكيل نهائيًا (DECOMMISSIONED)
+	DecommissionAgent(ctx context.Context, in *DecommissionAgentRequest, opts ...grpc.CallOption) (*DecommissionAgentResponse, error)
+	// 11. آخر قواعد جدار حماية أبلغ عنها الوكيل
+	GetFirewallRules(ctx context.Context, in *GetFirewallRulesRequest, opts ...grpc.CallOption) (*GetFirewallRulesResponse, error)
+	// 12. آخر قائمة تطبيقات أبلغ عنها الوكيل
+	GetInstalledApps(ctx context.Context, in *GetInstalledAppsRequest, opts ...grpc.CallOption) (*GetInstalledAppsResponse, error)
+	// 13. قائمة الأوامر وحالتها
+	ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (*ListCommandsResponse, error)
+	// 14. إلغاء أمر لم يستلمه الوكيل بعد
+	CancelCommand(ctx context.Context, in *CancelCommandRequest, opts ...grpc.CallOption) (*CancelCommandResponse, error)
+	// --- اشتراكات الـ webhooks لأحداث الأسطول (للمشرف) ---
+	CreateWebhookSubscription(ctx context.Context, in *CreateWebhookSubscriptionRequest, opts ...grpc.CallOption) (*CreateWebhookSubscriptionResponse, error)
+	ListWebhookSubscriptions(ctx context.Context, in *ListWebhookSubscriptionsRequest, opts ...grpc.CallOption) (*ListWebhookSubscriptionsResponse, error)
+	DeleteWebhookSubscription(ctx context.Context, in *DeleteWebhookSubscriptionRequest, opts ...grpc.CallOption) (*DeleteWebhookSubscriptionResponse, error)
+	ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error)
+	// يعيد التسليمات المحددة (أو كل التسليمات DEAD لاشتراك) إلى الطابور
+	ReplayWebhookDeliveries(ctx context.Context, in *ReplayWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ReplayWebhookDeliveriesResponse, error)
+	// بث مباشر لأحداث الأسطول للوحات المراقبة، مع الاستئناف من رقم تسلسلي
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (AgentService_WatchEventsClient, error)
+	// --- مطابقة الثغرات مع التطبيقات المثبتة (للمشرف) ---
+	// يستورد ملف ثغرات (OSV أو NVD) على دفعات ويستبدل الملف السابق ثم يعيد تقييم كل الوكلاء
+	ImportVulnerabilityFeed(ctx context.Context, opts ...grpc.CallOption) (AgentService_ImportVulnerabilityFeedClient, error)
+	// التطبيقات المصابة لوكيل أو لثغرة معينة ("أي الأجهزة مصابة بـ CVE-X")
+	ListVulnerabilityFindings(ctx context.Context, in *ListVulnerabilityFindingsRequest, opts ...grpc.CallOption) (*ListVulnerabilityFindingsResponse, error)
+	// الثغرات الموجودة في الأسطول مع عدد الوكلاء المصابين
+	ListFleetVulnerabilities(ctx context.Context, in *ListFleetVulnerabilitiesRequest, opts ...grpc.CallOption) (*ListFleetVulnerabilitiesResponse, error)
+	// --- كتالوج البرامج في الأسطول (للمشرف) ---
+	// المنتجات الموحدة مع عدد التثبيتات لكل إصدار والتثبيتات المتأخرة عن الإصدار السائد
+	ListSoftwareCatalog(ctx context.Context, in *ListSoftwareCatalogRequest, opts ...grpc.CallOption) (*ListSoftwareCatalogResponse, error)
+	// تثبيتات منتج واحد، من الإصدار الأقدم إلى الأحدث
+	ListSoftwareInstalls(ctx context.Context, in *ListSoftwareInstallsRequest, opts ...grpc.CallOption) (*ListSoftwareInstallsResponse, error)
+	// --- تحليل قواعد جدار الحماية (للمشرف) ---
+	// القواعد المحجوبة والمتعارضة والمنافذ الحساسة المكشوفة، تُحسب مع كل ReportFirewallStatus
+	ListFirewallFindings(ctx context.Context, in *ListFirewallFindingsRequest, opts ...grpc.CallOption) (*ListFirewallFindingsResponse, error)
+	// --- سياسات الامتثال المقيّمة على مخزون الوكلاء (للمشرف) ---
+	// السياسات المحملة من compliance.policies_path، أو كل الإصدارات المحفوظة لسياسة
+	ListCompliancePolicies(ctx context.Context, in *ListCompliancePoliciesRequest, opts ...grpc.CallOption) (*ListCompliancePoliciesResponse, error)
+	// يعيد تقييم وكيل واحد أو كل الوكلاء فورًا بدل انتظار الدورة التالية
+	EvaluateCompliance(ctx context.Context, in *EvaluateComplianceRequest, opts ...grpc.CallOption) (*EvaluateComplianceResponse, error)
+	// النتيجة الحالية لكل فحص مع الدليل
+	ListComplianceResults(ctx context.Context, in *ListComplianceResultsRequest, opts ...grpc.CallOption) (*ListComplianceResultsResponse, error)
+	// تغيرات نتائج سياسة على وكيل عبر الزمن
+	GetComplianceHistory(ctx context.Context, in *GetComplianceHistoryRequest, opts ...grpc.CallOption) (*GetComplianceHistoryResponse, error)
+	// ملخص الأسطول: عدد الوكلاء الملتزمين ونتائج كل فحص
+	GetComplianceReport(ctx context.Context, in *GetComplianceReportRequest, opts ...grpc.CallOption) (*GetComplianceReportResponse, error)
+	// --- تصدير بيانات الأسطول للتقارير (للمشرف) ---
+	// يقرأ الصفوف بمؤشر من قاعدة البيانات ويرسل الملف على دفعات، لذلك يعمل مع الأساطيل الكبيرة
+	ExportFleetData(ctx context.Context, in *ExportFleetDataRequest, opts ...grpc.CallOption) (AgentService_ExportFleetDataClient, error)
+	// --- التوفر من سجل حالات الوكلاء (للمشرف) ---
+	// نسبة التوفر وعدد الانقطاعات ومتوسط زمن التعافي لكل وكيل ولكل مجموعة خلال فترة زمنية
+	GetAvailabilityReport(ctx context.Context, in *GetAvailabilityReportRequest, opts ...grpc.CallOption) (*GetAvailabilityReportResponse, error)
+	// --- سجل عناوين IP للوكلاء (للمشرف) ---
+	// العناوين التي ظهر بها الوكلاء، مثل "أي الوكلاء كانوا على 10.2.3.4 في يوم معين"
+	ListAgentIPHistory(ctx context.Context, in *ListAgentIPHistoryRequest, opts ...grpc.CallOption) (*ListAgentIPHistoryResponse, error)
+	// --- الوكلاء المستنسخون: أكثر من جهاز بنفس agent_id (للمشرف) ---
+	ListSuspectedClones(ctx context.Context, in *ListSuspectedClonesRequest, opts ...grpc.CallOption) (*ListSuspectedClonesResponse, error)
+	// يزيل الاشتباه بعد معالجة الأجهزة المكررة
+	ClearSuspectedClone(ctx context.Context, in *ClearSuspectedCloneRequest, opts ...grpc.CallOption) (*ClearSuspectedCloneResponse, error)
+	// --- مقاييس الموارد من النبضات (للمشرف) ---
+	// سلاسل زمنية لوكيل خلال فترة، خام أو مجمعة لكل 5 دقائق أو ساعة
+	GetAgentMetrics(ctx context.Context, in *GetAgentMetricsRequest, opts ...grpc.CallOption) (*GetAgentMetricsResponse, error)
+	// --- التنبيهات من قواعد الخادم (للمشرف) ---
+	// القواعد المحملة من alerts.rules_path
+	ListAlertRules(ctx context.Context, in *ListAlertRulesRequest, opts ...grpc.CallOption) (*ListAlertRulesResponse, error)
+	// التنبيهات المفتوحة والمنتهية، الأحدث أولًا
+	ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error)
+	// --- الجرد الموسع من آخر تقرير للوكيل (للمشرف) ---
+	GetNetworkInterfaces(ctx context.Context, in *GetNetworkInterfacesRequest, opts ...grpc.CallOption) (*GetNetworkInterfacesResponse, error)
+	// المستخدمون والمجموعات المحلية معًا
+	GetLocalUsers(ctx context.Context, in *GetLocalUsersRequest, opts ...grpc.CallOption) (*GetLocalUsersResponse, error)
+	GetServices(ctx context.Context, in *GetServicesRequest, opts ...grpc.CallOption) (*GetServicesResponse, error)
+	GetListeningSockets(ctx context.Context, in *GetListeningSocketsRequest, opts ...grpc.CallOption) (*GetListeningSocketsResponse, error)
+	GetProcesses(ctx context.Context, in *GetProcessesRequest, opts ...grpc.CallOption) (*GetProcessesResponse, error)
+	// --- حالة تحديثات النظام (للمشرف) ---
+	GetPatchStatus(ctx context.Context, in *GetPatchStatusRequest, opts ...grpc.CallOption) (*GetPatchStatusResponse, error)
+	// مثل "الأجهزة التي ينقصها KB معين" و"الأجهزة التي تنتظر إعادة التشغيل منذ أكثر من 7 أيام"
+	ListPatchStatus(ctx context.Context, in *ListPatchStatusRequest, opts ...grpc.CallOption) (*ListPatchStatusResponse, error)
+	// --- السكربتات وموافقتها ومخرجاتها (للمشرف) ---
+	ListScripts(ctx context.Context, in *ListScriptsRequest, opts ...grpc.CallOption) (*ListScriptsResponse, error)
+	// إرسال أمر ينتظر الموافقة إلى الوكيل، والرفض يكون بـ CancelCommand
+	ApproveCommand(ctx context.Context, in *ApproveCommandRequest, opts ...grpc.CallOption) (*ApproveCommandResponse, error)
+	GetCommandOutput(ctx context.Context, in *GetCommandOutputRequest, opts ...grpc.CallOption) (*GetCommandOutputResponse, error)
+	// --- حزم الوكيل والنشر التدريجي للتحديثات (للمشرف) ---
+	// رفع حزمة موقعة: الرسالة الأولى بيانات الحزمة ثم أجزاء المحتوى
+	UploadAgentPackage(ctx context.Context, opts ...grpc.CallOption) (AgentService_UploadAgentPackageClient, error)
+	ListAgentPackages(ctx context.Context, in *ListAgentPackagesRequest, opts ...grpc.CallOption) (*ListAgentPackagesResponse, error)
+	CreateRollout(ctx context.Context, in *CreateRolloutRequest, opts ...grpc.CallOption) (*CreateRolloutResponse, error)
+	// تغيير النسبة أو المجموعات، أو إيقاف النشر واستئنافه وإلغاؤه
+	UpdateRollout(ctx context.Context, in *UpdateRolloutRequest, opts ...grpc.CallOption) (*UpdateRolloutResponse, error)
+	ListRollouts(ctx context.Context, in *ListRolloutsRequest, opts ...grpc.CallOption) (*ListRolloutsResponse, error)
+	ListAgentUpdates(ctx context.Context, in *ListAgentUpdatesRequest, opts ...grpc.CallOption) (*ListAgentUpdatesResponse, error)
+	// --- جمع الملفات من الوكلاء (للمشرف) ---
+	// يضع أمر COLLECT_FILE في طابور الوكيل، مثل السجلات والإعدادات وتفريغ الذاكرة
+	CollectFile(ctx context.Context, in *CollectFileRequest, opts ...grpc.CallOption) (*CollectFileResponse, error)
+	ListFileCollections(ctx context.Context, in *ListFileCollectionsRequest, opts ...grpc.CallOption) (*ListFileCollectionsResponse, error)
+	// تنزيل ملف مكتمل أجزاءً، ويُسجل كل تنزيل حدثًا file.downloaded
+	DownloadCollectedFile(ctx context.Context, in *DownloadCollectedFileRequest, opts ...grpc.CallOption) (AgentService_DownloadCollectedFileClient, error)
+	// --- المهام المجدولة والمتكررة على مجموعات من الوكلاء (للمشرف) ---
+	CreateScheduledJob(ctx context.Context, in *CreateScheduledJobRequest, opts ...grpc.CallOption) (*CreateScheduledJobResponse, error)
+	// إيقاف المهمة مؤقتًا واستئنافها
+	UpdateScheduledJob(ctx context.Context, in *UpdateScheduledJobRequest, opts ...grpc.CallOption) (*UpdateScheduledJobResponse, error)
+	DeleteScheduledJob(ctx context.Context, in *DeleteScheduledJobRequest, opts ...grpc.CallOption) (*DeleteScheduledJobResponse, error)
+	ListScheduledJobs(ctx context.Context, in *ListScheduledJobsRequest, opts ...grpc.CallOption) (*ListScheduledJobsResponse, error)
+	// تشغيل المهمة الآن خارج جدولها
+	RunScheduledJob(ctx context.Context, in *RunScheduledJobRequest, opts ...grpc.CallOption) (*RunScheduledJobResponse, error)
+	ListJobRuns(ctx context.Context, in *ListJobRunsRequest, opts ...grpc.CallOption) (*ListJobRunsResponse, error)
+	// تشغيل واحد مع حالة كل وكيل فيه
+	GetJobRun(ctx context.Context, in *GetJobRunRequest, opts ...grpc.CallOption) (*GetJobRunResponse, error)
 }
 
 type agentServiceClient struct {
@@ -87,6 +225,846 @@ func (c *agentServiceClient) ReportInstalledApps(ctx context.Context, in *Instal
 	return out, nil
 }
 
+func (c *agentServiceClient) ReportNetworkInterfaces(ctx context.Context, in *NetworkInterfacesRequest, opts ...grpc.CallOption) (*NetworkInterfacesResponse, error) {
+	out := new(NetworkInterfacesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReportNetworkInterfaces", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ReportLocalUsers(ctx context.Context, in *LocalUsersRequest, opts ...grpc.CallOption) (*LocalUsersResponse, error) {
+	out := new(LocalUsersResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReportLocalUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ReportServices(ctx context.Context, in *ServicesRequest, opts ...grpc.CallOption) (*ServicesResponse, error) {
+	out := new(ServicesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReportServices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ReportListeningSockets(ctx context.Context, in *ListeningSocketsRequest, opts ...grpc.CallOption) (*ListeningSocketsResponse, error) {
+	out := new(ListeningSocketsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReportListeningSockets", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ReportProcesses(ctx context.Context, in *ProcessesRequest, opts ...grpc.CallOption) (*ProcessesResponse, error) {
+	out := new(ProcessesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReportProcesses", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ReportPatchStatus(ctx context.Context, in *PatchStatusRequest, opts ...grpc.CallOption) (*PatchStatusResponse, error) {
+	out := new(PatchStatusResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReportPatchStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) DownloadAgentPackage(ctx context.Context, in *DownloadAgentPackageRequest, opts ...grpc.CallOption) (AgentService_DownloadAgentPackageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[0], "/proto.AgentService/DownloadAgentPackage", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceDownloadAgentPackageClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AgentService_DownloadAgentPackageClient interface {
+	Recv() (*AgentPackageChunk, error)
+	grpc.ClientStream
+}
+
+type agentServiceDownloadAgentPackageClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceDownloadAgentPackageClient) Recv() (*AgentPackageChunk, error) {
+	m := new(AgentPackageChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) ReportUpdateResult(ctx context.Context, in *UpdateResultRequest, opts ...grpc.CallOption) (*UpdateResultResponse, error) {
+	out := new(UpdateResultResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReportUpdateResult", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetFileUploadStatus(ctx context.Context, in *FileUploadStatusRequest, opts ...grpc.CallOption) (*FileUploadStatusResponse, error) {
+	out := new(FileUploadStatusResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetFileUploadStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) UploadCollectedFile(ctx context.Context, opts ...grpc.CallOption) (AgentService_UploadCollectedFileClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[1], "/proto.AgentService/UploadCollectedFile", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceUploadCollectedFileClient{stream}
+	return x, nil
+}
+
+type AgentService_UploadCollectedFileClient interface {
+	Send(*UploadCollectedFileRequest) error
+	CloseAndRecv() (*UploadCollectedFileResponse, error)
+	grpc.ClientStream
+}
+
+type agentServiceUploadCollectedFileClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceUploadCollectedFileClient) Send(m *UploadCollectedFileRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *agentServiceUploadCollectedFileClient) CloseAndRecv() (*UploadCollectedFileResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadCollectedFileResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) ConfigureFirewall(ctx context.Context, in *FirewallConfigurationRequest, opts ...grpc.CallOption) (*FirewallConfigurationResponse, error) {
+	out := new(FirewallConfigurationResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ConfigureFirewall", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) PollCommands(ctx context.Context, in *PollCommandsRequest, opts ...grpc.CallOption) (*PollCommandsResponse, error) {
+	out := new(PollCommandsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/PollCommands", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ReportCommandResult(ctx context.Context, in *CommandResultRequest, opts ...grpc.CallOption) (*CommandResultResponse, error) {
+	out := new(CommandResultResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReportCommandResult", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) RunScript(ctx context.Context, in *RunScriptRequest, opts ...grpc.CallOption) (*RunScriptResponse, error) {
+	out := new(RunScriptResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/RunScript", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) StreamCommandOutput(ctx context.Context, opts ...grpc.CallOption) (AgentService_StreamCommandOutputClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[2], "/proto.AgentService/StreamCommandOutput", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceStreamCommandOutputClient{stream}
+	return x, nil
+}
+
+type AgentService_StreamCommandOutputClient interface {
+	Send(*CommandOutputChunk) error
+	CloseAndRecv() (*StreamCommandOutputResponse, error)
+	grpc.ClientStream
+}
+
+type agentServiceStreamCommandOutputClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceStreamCommandOutputClient) Send(m *CommandOutputChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *agentServiceStreamCommandOutputClient) CloseAndRecv() (*StreamCommandOutputResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(StreamCommandOutputResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) ListAgents(ctx context.Context, in *ListAgentsRequest, opts ...grpc.CallOption) (*ListAgentsResponse, error) {
+	out := new(ListAgentsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListAgents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) DecommissionAgent(ctx context.Context, in *DecommissionAgentRequest, opts ...grpc.CallOption) (*DecommissionAgentResponse, error) {
+	out := new(DecommissionAgentResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/DecommissionAgent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetFirewallRules(ctx context.Context, in *GetFirewallRulesRequest, opts ...grpc.CallOption) (*GetFirewallRulesResponse, error) {
+	out := new(GetFirewallRulesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetFirewallRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetInstalledApps(ctx context.Context, in *GetInstalledAppsRequest, opts ...grpc.CallOption) (*GetInstalledAppsResponse, error) {
+	out := new(GetInstalledAppsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetInstalledApps", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListCommands(ctx context.Context, in *ListCommandsRequest, opts ...grpc.CallOption) (*ListCommandsResponse, error) {
+	out := new(ListCommandsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListCommands", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) CancelCommand(ctx context.Context, in *CancelCommandRequest, opts ...grpc.CallOption) (*CancelCommandResponse, error) {
+	out := new(CancelCommandResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/CancelCommand", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) CreateWebhookSubscription(ctx context.Context, in *CreateWebhookSubscriptionRequest, opts ...grpc.CallOption) (*CreateWebhookSubscriptionResponse, error) {
+	out := new(CreateWebhookSubscriptionResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/CreateWebhookSubscription", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListWebhookSubscriptions(ctx context.Context, in *ListWebhookSubscriptionsRequest, opts ...grpc.CallOption) (*ListWebhookSubscriptionsResponse, error) {
+	out := new(ListWebhookSubscriptionsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListWebhookSubscriptions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) DeleteWebhookSubscription(ctx context.Context, in *DeleteWebhookSubscriptionRequest, opts ...grpc.CallOption) (*DeleteWebhookSubscriptionResponse, error) {
+	out := new(DeleteWebhookSubscriptionResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/DeleteWebhookSubscription", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error) {
+	out := new(ListWebhookDeliveriesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListWebhookDeliveries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ReplayWebhookDeliveries(ctx context.Context, in *ReplayWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ReplayWebhookDeliveriesResponse, error) {
+	out := new(ReplayWebhookDeliveriesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReplayWebhookDeliveries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (AgentService_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[3], "/proto.AgentService/WatchEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AgentService_WatchEventsClient interface {
+	Recv() (*FleetEvent, error)
+	grpc.ClientStream
+}
+
+type agentServiceWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceWatchEventsClient) Recv() (*FleetEvent, error) {
+	m := new(FleetEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) ImportVulnerabilityFeed(ctx context.Context, opts ...grpc.CallOption) (AgentService_ImportVulnerabilityFeedClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[4], "/proto.AgentService/ImportVulnerabilityFeed", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceImportVulnerabilityFeedClient{stream}
+	return x, nil
+}
+
+type AgentService_ImportVulnerabilityFeedClient interface {
+	Send(*VulnerabilityFeedChunk) error
+	CloseAndRecv() (*ImportVulnerabilityFeedResponse, error)
+	grpc.ClientStream
+}
+
+type agentServiceImportVulnerabilityFeedClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceImportVulnerabilityFeedClient) Send(m *VulnerabilityFeedChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *agentServiceImportVulnerabilityFeedClient) CloseAndRecv() (*ImportVulnerabilityFeedResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportVulnerabilityFeedResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) ListVulnerabilityFindings(ctx context.Context, in *ListVulnerabilityFindingsRequest, opts ...grpc.CallOption) (*ListVulnerabilityFindingsResponse, error) {
+	out := new(ListVulnerabilityFindingsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListVulnerabilityFindings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListFleetVulnerabilities(ctx context.Context, in *ListFleetVulnerabilitiesRequest, opts ...grpc.CallOption) (*ListFleetVulnerabilitiesResponse, error) {
+	out := new(ListFleetVulnerabilitiesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListFleetVulnerabilities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListSoftwareCatalog(ctx context.Context, in *ListSoftwareCatalogRequest, opts ...grpc.CallOption) (*ListSoftwareCatalogResponse, error) {
+	out := new(ListSoftwareCatalogResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListSoftwareCatalog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListSoftwareInstalls(ctx context.Context, in *ListSoftwareInstallsRequest, opts ...grpc.CallOption) (*ListSoftwareInstallsResponse, error) {
+	out := new(ListSoftwareInstallsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListSoftwareInstalls", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListFirewallFindings(ctx context.Context, in *ListFirewallFindingsRequest, opts ...grpc.CallOption) (*ListFirewallFindingsResponse, error) {
+	out := new(ListFirewallFindingsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListFirewallFindings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListCompliancePolicies(ctx context.Context, in *ListCompliancePoliciesRequest, opts ...grpc.CallOption) (*ListCompliancePoliciesResponse, error) {
+	out := new(ListCompliancePoliciesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListCompliancePolicies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) EvaluateCompliance(ctx context.Context, in *EvaluateComplianceRequest, opts ...grpc.CallOption) (*EvaluateComplianceResponse, error) {
+	out := new(EvaluateComplianceResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/EvaluateCompliance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListComplianceResults(ctx context.Context, in *ListComplianceResultsRequest, opts ...grpc.CallOption) (*ListComplianceResultsResponse, error) {
+	out := new(ListComplianceResultsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListComplianceResults", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetComplianceHistory(ctx context.Context, in *GetComplianceHistoryRequest, opts ...grpc.CallOption) (*GetComplianceHistoryResponse, error) {
+	out := new(GetComplianceHistoryResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetComplianceHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetComplianceReport(ctx context.Context, in *GetComplianceReportRequest, opts ...grpc.CallOption) (*GetComplianceReportResponse, error) {
+	out := new(GetComplianceReportResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetComplianceReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ExportFleetData(ctx context.Context, in *ExportFleetDataRequest, opts ...grpc.CallOption) (AgentService_ExportFleetDataClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[5], "/proto.AgentService/ExportFleetData", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceExportFleetDataClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AgentService_ExportFleetDataClient interface {
+	Recv() (*ExportChunk, error)
+	grpc.ClientStream
+}
+
+type agentServiceExportFleetDataClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceExportFleetDataClient) Recv() (*ExportChunk, error) {
+	m := new(ExportChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) GetAvailabilityReport(ctx context.Context, in *GetAvailabilityReportRequest, opts ...grpc.CallOption) (*GetAvailabilityReportResponse, error) {
+	out := new(GetAvailabilityReportResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetAvailabilityReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListAgentIPHistory(ctx context.Context, in *ListAgentIPHistoryRequest, opts ...grpc.CallOption) (*ListAgentIPHistoryResponse, error) {
+	out := new(ListAgentIPHistoryResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListAgentIPHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListSuspectedClones(ctx context.Context, in *ListSuspectedClonesRequest, opts ...grpc.CallOption) (*ListSuspectedClonesResponse, error) {
+	out := new(ListSuspectedClonesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListSuspectedClones", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ClearSuspectedClone(ctx context.Context, in *ClearSuspectedCloneRequest, opts ...grpc.CallOption) (*ClearSuspectedCloneResponse, error) {
+	out := new(ClearSuspectedCloneResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ClearSuspectedClone", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetAgentMetrics(ctx context.Context, in *GetAgentMetricsRequest, opts ...grpc.CallOption) (*GetAgentMetricsResponse, error) {
+	out := new(GetAgentMetricsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetAgentMetrics", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListAlertRules(ctx context.Context, in *ListAlertRulesRequest, opts ...grpc.CallOption) (*ListAlertRulesResponse, error) {
+	out := new(ListAlertRulesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListAlertRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error) {
+	out := new(ListAlertsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListAlerts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetNetworkInterfaces(ctx context.Context, in *GetNetworkInterfacesRequest, opts ...grpc.CallOption) (*GetNetworkInterfacesResponse, error) {
+	out := new(GetNetworkInterfacesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetNetworkInterfaces", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetLocalUsers(ctx context.Context, in *GetLocalUsersRequest, opts ...grpc.CallOption) (*GetLocalUsersResponse, error) {
+	out := new(GetLocalUsersResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetLocalUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetServices(ctx context.Context, in *GetServicesRequest, opts ...grpc.CallOption) (*GetServicesResponse, error) {
+	out := new(GetServicesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetServices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetListeningSockets(ctx context.Context, in *GetListeningSocketsRequest, opts ...grpc.CallOption) (*GetListeningSocketsResponse, error) {
+	out := new(GetListeningSocketsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetListeningSockets", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetProcesses(ctx context.Context, in *GetProcessesRequest, opts ...grpc.CallOption) (*GetProcessesResponse, error) {
+	out := new(GetProcessesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetProcesses", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetPatchStatus(ctx context.Context, in *GetPatchStatusRequest, opts ...grpc.CallOption) (*GetPatchStatusResponse, error) {
+	out := new(GetPatchStatusResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetPatchStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListPatchStatus(ctx context.Context, in *ListPatchStatusRequest, opts ...grpc.CallOption) (*ListPatchStatusResponse, error) {
+	out := new(ListPatchStatusResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListPatchStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListScripts(ctx context.Context, in *ListScriptsRequest, opts ...grpc.CallOption) (*ListScriptsResponse, error) {
+	out := new(ListScriptsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListScripts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ApproveCommand(ctx context.Context, in *ApproveCommandRequest, opts ...grpc.CallOption) (*ApproveCommandResponse, error) {
+	out := new(ApproveCommandResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ApproveCommand", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetCommandOutput(ctx context.Context, in *GetCommandOutputRequest, opts ...grpc.CallOption) (*GetCommandOutputResponse, error) {
+	out := new(GetCommandOutputResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetCommandOutput", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) UploadAgentPackage(ctx context.Context, opts ...grpc.CallOption) (AgentService_UploadAgentPackageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[6], "/proto.AgentService/UploadAgentPackage", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceUploadAgentPackageClient{stream}
+	return x, nil
+}
+
+type AgentService_UploadAgentPackageClient interface {
+	Send(*UploadAgentPackageRequest) error
+	CloseAndRecv() (*UploadAgentPackageResponse, error)
+	grpc.ClientStream
+}
+
+type agentServiceUploadAgentPackageClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceUploadAgentPackageClient) Send(m *UploadAgentPackageRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *agentServiceUploadAgentPackageClient) CloseAndRecv() (*UploadAgentPackageResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadAgentPackageResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) ListAgentPackages(ctx context.Context, in *ListAgentPackagesRequest, opts ...grpc.CallOption) (*ListAgentPackagesResponse, error) {
+	out := new(ListAgentPackagesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListAgentPackages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) CreateRollout(ctx context.Context, in *CreateRolloutRequest, opts ...grpc.CallOption) (*CreateRolloutResponse, error) {
+	out := new(CreateRolloutResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/CreateRollout", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) UpdateRollout(ctx context.Context, in *UpdateRolloutRequest, opts ...grpc.CallOption) (*UpdateRolloutResponse, error) {
+	out := new(UpdateRolloutResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/UpdateRollout", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListRollouts(ctx context.Context, in *ListRolloutsRequest, opts ...grpc.CallOption) (*ListRolloutsResponse, error) {
+	out := new(ListRolloutsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListRollouts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListAgentUpdates(ctx context.Context, in *ListAgentUpdatesRequest, opts ...grpc.CallOption) (*ListAgentUpdatesResponse, error) {
+	out := new(ListAgentUpdatesResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListAgentUpdates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) CollectFile(ctx context.Context, in *CollectFileRequest, opts ...grpc.CallOption) (*CollectFileResponse, error) {
+	out := new(CollectFileResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/CollectFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListFileCollections(ctx context.Context, in *ListFileCollectionsRequest, opts ...grpc.CallOption) (*ListFileCollectionsResponse, error) {
+	out := new(ListFileCollectionsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListFileCollections", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) DownloadCollectedFile(ctx context.Context, in *DownloadCollectedFileRequest, opts ...grpc.CallOption) (AgentService_DownloadCollectedFileClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[7], "/proto.AgentService/DownloadCollectedFile", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceDownloadCollectedFileClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AgentService_DownloadCollectedFileClient interface {
+	Recv() (*CollectedFileChunk, error)
+	grpc.ClientStream
+}
+
+type agentServiceDownloadCollectedFileClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceDownloadCollectedFileClient) Recv() (*CollectedFileChunk, error) {
+	m := new(CollectedFileChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) CreateScheduledJob(ctx context.Context, in *CreateScheduledJobRequest, opts ...grpc.CallOption) (*CreateScheduledJobResponse, error) {
+	out := new(CreateScheduledJobResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/CreateScheduledJob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) UpdateScheduledJob(ctx context.Context, in *UpdateScheduledJobRequest, opts ...grpc.CallOption) (*UpdateScheduledJobResponse, error) {
+	out := new(UpdateScheduledJobResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/UpdateScheduledJob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) DeleteScheduledJob(ctx context.Context, in *DeleteScheduledJobRequest, opts ...grpc.CallOption) (*DeleteScheduledJobResponse, error) {
+	out := new(DeleteScheduledJobResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/DeleteScheduledJob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListScheduledJobs(ctx context.Context, in *ListScheduledJobsRequest, opts ...grpc.CallOption) (*ListScheduledJobsResponse, error) {
+	out := new(ListScheduledJobsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListScheduledJobs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) RunScheduledJob(ctx context.Context, in *RunScheduledJobRequest, opts ...grpc.CallOption) (*RunScheduledJobResponse, error) {
+	out := new(RunScheduledJobResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/RunScheduledJob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListJobRuns(ctx context.Context, in *ListJobRunsRequest, opts ...grpc.CallOption) (*ListJobRunsResponse, error) {
+	out := new(ListJobRunsResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ListJobRuns", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) GetJobRun(ctx context.Context, in *GetJobRunRequest, opts ...grpc.CallOption) (*GetJobRunResponse, error) {
+	out := new(GetJobRunResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/GetJobRun", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServiceServer is the server API for AgentService service.
 // All implementations must embed UnimplementedAgentServiceServer
 // for forward compatibility
@@ -101,127 +1079,1821 @@ type AgentServiceServer interface {
 	ReportFirewallStatus(context.Context, *FirewallStatusRequest) (*FirewallStatusResponse, error)
 	// 5. إرسال تقرير بالتطبيقات المثبتة
 	ReportInstalledApps(context.Context, *InstalledAppsRequest) (*InstalledAppsResponse, error)
+	// تقارير الجرد الموسع، كل تقرير لقطة كاملة مثل التطبيقات المثبتة
+	ReportNetworkInterfaces(context.Context, *NetworkInterfacesRequest) (*NetworkInterfacesResponse, error)
+	ReportLocalUsers(context.Context, *LocalUsersRequest) (*LocalUsersResponse, error)
+	ReportServices(context.Context, *ServicesRequest) (*ServicesResponse, error)
+	ReportListeningSockets(context.Context, *ListeningSocketsRequest) (*ListeningSocketsResponse, error)
+	ReportProcesses(context.Context, *ProcessesRequest) (*ProcessesResponse, error)
+	// حالة تحديثات النظام: المثبتة والمعلقة وآخر تحديث وهل ينتظر إعادة التشغيل
+	ReportPatchStatus(context.Context, *PatchStatusRequest) (*PatchStatusResponse, error)
+	// تحديث برنامج الوكيل: ينزل الوكيل الحزمة المعروضة في RegisterResponse أو HeartbeatResponse
+	// أجزاءً (ويمكنه الاستئناف من offset)، ثم يبلغ عن نتيجة التثبيت
+	DownloadAgentPackage(*DownloadAgentPackageRequest, AgentService_DownloadAgentPackageServer) error
+	ReportUpdateResult(context.Context, *UpdateResultRequest) (*UpdateResultResponse, error)
+	// جمع الملفات: الوكيل يرفع الملف المطلوب بأمر COLLECT_FILE أجزاءً، وبعد انقطاع الرفع
+	// يسأل عن عدد البايتات التي وصلت ويستأنف منها
+	GetFileUploadStatus(context.Context, *FileUploadStatusRequest) (*FileUploadStatusResponse, error)
+	UploadCollectedFile(AgentService_UploadCollectedFileServer) error
+	// --------------------------- SERVICES (متعلق السيرفر يرسل للوكيل) ---------------------------
+	// 6. تكوين جدار الحماية: السيرفر يضع أمرًا في طابور الوكيل لتعديل إعدادات جدار الحماية
+	ConfigureFirewall(context.Context, *FirewallConfigurationRequest) (*FirewallConfigurationResponse, error)
+	// 7. الوكيل يسحب الأوامر المعلقة له
+	PollCommands(context.Context, *PollCommandsRequest) (*PollCommandsResponse, error)
+	// 8. الوكيل يبلغ عن نتيجة تنفيذ أمر
+	ReportCommandResult(context.Context, *CommandResultRequest) (*CommandResultResponse, error)
+	// تنفيذ سكربت على الوكيل: نص السكربت أو اسمه في مكتبة الخادم، وما خارج القائمة المسموحة ينتظر الموافقة
+	RunScript(context.Context, *RunScriptRequest) (*RunScriptResponse, error)
+	// الوكيل يرسل مخرجات السكربت أجزاءً أثناء التنفيذ، ثم رمز الخروج مع ReportCommandResult
+	StreamCommandOutput(AgentService_StreamCommandOutputServer) error
+	// --------------------------- SERVICES (الاستعلام والإدارة) ---------------------------
+	// 9. قائمة الوكلاء مع التصفية حسب الحالة
+	ListAgents(context.Context, *ListAgentsRequest) (*ListAgentsResponse, error)
+	// 10. إيقاف وكيل نهائيًا (DECOMMISSIONED)
+	DecommissionAgent(context.Context, *DecommissionAgentRequest) (*DecommissionAgentResponse, error)
+	// 11. آخر قواعد جدار حماية أبلغ عنها الوكيل
+	GetFirewallRules(context.Context, *GetFirewallRulesRequest) (*GetFirewallRulesResponse, error)
+	// 12. آخر قائمة تطبيقات أبلغ عنها الوكيل
+	GetInstalledApps(context.Context, *GetInstalledAppsRequest) (*GetInstalledAppsResponse, error)
+	// 13. قائمة الأوامر وحالتها
+	ListCommands(context.Context, *ListCommandsRequest) (*ListCommandsResponse, error)
+	// 14. إلغاء أمر لم يستلمه الوكيل بعد
+	CancelCommand(context.Context, *CancelCommandRequest) (*CancelCommandResponse, error)
+	// --- اشتراكات الـ webhooks لأحداث الأسطول (للمشرف) ---
+	CreateWebhookSubscription(context.Context, *CreateWebhookSubscriptionRequest) (*CreateWebhookSubscriptionResponse, error)
+	ListWebhookSubscriptions(context.Context, *ListWebhookSubscriptionsRequest) (*ListWebhookSubscriptionsResponse, error)
+	DeleteWebhookSubscription(context.Context, *DeleteWebhookSubscriptionRequest) (*DeleteWebhookSubscriptionResponse, error)
+	ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error)
+	// يعيد التسليمات المحددة (أو كل التسليمات DEAD لاشتراك) إلى الطابور
+	ReplayWebhookDeliveries(context.Context, *ReplayWebhookDeliveriesRequest) (*ReplayWebhookDeliveriesResponse, error)
+	// بث مباشر لأحداث الأسطول للوحات المراقبة، مع الاستئناف من رقم تسلسلي
+	WatchEvents(*WatchEventsRequest, AgentService_WatchEventsServer) error
+	// --- مطابقة الثغرات مع التطبيقات المثبتة (للمشرف) ---
+	// يستورد ملف ثغرات (OSV أو NVD) على دفعات ويستبدل الملف السابق ثم يعيد تقييم كل الوكلاء
+	ImportVulnerabilityFeed(AgentService_ImportVulnerabilityFeedServer) error
+	// التطبيقات المصابة لوكيل أو لثغرة معينة ("أي الأجهزة مصابة بـ CVE-X")
+	ListVulnerabilityFindings(context.Context, *ListVulnerabilityFindingsRequest) (*ListVulnerabilityFindingsResponse, error)
+	// الثغرات الموجودة في الأسطول مع عدد الوكلاء المصابين
+	ListFleetVulnerabilities(context.Context, *ListFleetVulnerabilitiesRequest) (*ListFleetVulnerabilitiesResponse, error)
+	// --- كتالوج البرامج في الأسطول (للمشرف) ---
+	// المنتجات الموحدة مع عدد التثبيتات لكل إصدار والتثبيتات المتأخرة عن الإصدار السائد
+	ListSoftwareCatalog(context.Context, *ListSoftwareCatalogRequest) (*ListSoftwareCatalogResponse, error)
+	// تثبيتات منتج واحد، من الإصدار الأقدم إلى الأحدث
+	ListSoftwareInstalls(context.Context, *ListSoftwareInstallsRequest) (*ListSoftwareInstallsResponse, error)
+	// --- تحليل قواعد جدار الحماية (للمشرف) ---
+	// القواعد المحجوبة والمتعارضة والمنافذ الحساسة المكشوفة، تُحسب مع كل ReportFirewallStatus
+	ListFirewallFindings(context.Context, *ListFirewallFindingsRequest) (*ListFirewallFindingsResponse, error)
+	// --- سياسات الامتثال المقيّمة على مخزون الوكلاء (للمشرف) ---
+	// السياسات المحملة من compliance.policies_path، أو كل الإصدارات المحفوظة لسياسة
+	ListCompliancePolicies(context.Context, *ListCompliancePoliciesRequest) (*ListCompliancePoliciesResponse, error)
+	// يعيد تقييم وكيل واحد أو كل الوكلاء فورًا بدل انتظار الدورة التالية
+	EvaluateCompliance(context.Context, *EvaluateComplianceRequest) (*EvaluateComplianceResponse, error)
+	// النتيجة الحالية لكل فحص مع الدليل
+	ListComplianceResults(context.Context, *ListComplianceResultsRequest) (*ListComplianceResultsResponse, error)
+	// تغيرات نتائج سياسة على وكيل عبر الزمن
+	GetComplianceHistory(context.Context, *GetComplianceHistoryRequest) (*GetComplianceHistoryResponse, error)
+	// ملخص الأسطول: عدد الوكلاء الملتزمين ونتائج كل فحص
+	GetComplianceReport(context.Context, *GetComplianceReportRequest) (*GetComplianceReportResponse, error)
+	// --- تصدير بيانات الأسطول للتقارير (للمشرف) ---
+	// يقرأ الصفوف بمؤشر من قاعدة البيانات ويرسل الملف على دفعات، لذلك يعمل مع الأساطيل الكبيرة
+	ExportFleetData(*ExportFleetDataRequest, AgentService_ExportFleetDataServer) error
+	// --- التوفر من سجل حالات الوكلاء (للمشرف) ---
+	// نسبة التوفر وعدد الانقطاعات ومتوسط زمن التعافي لكل وكيل ولكل مجموعة خلال فترة زمنية
+	GetAvailabilityReport(context.Context, *GetAvailabilityReportRequest) (*GetAvailabilityReportResponse, error)
+	// --- سجل عناوين IP للوكلاء (للمشرف) ---
+	// العناوين التي ظهر بها الوكلاء، مثل "أي الوكلاء كانوا على 10.2.3.4 في يوم معين"
+	ListAgentIPHistory(context.Context, *ListAgentIPHistoryRequest) (*ListAgentIPHistoryResponse, error)
+	// --- الوكلاء المستنسخون: أكثر من جهاز بنفس agent_id (للمشرف) ---
+	ListSuspectedClones(context.Context, *ListSuspectedClonesRequest) (*ListSuspectedClonesResponse, error)
+	// يزيل الاشتباه بعد معالجة الأجهزة المكررة
+	ClearSuspectedClone(context.Context, *ClearSuspectedCloneRequest) (*ClearSuspectedCloneResponse, error)
+	// --- مقاييس الموارد من النبضات (للمشرف) ---
+	// سلاسل زمنية لوكيل خلال فترة، خام أو مجمعة لكل 5 دقائق أو ساعة
+	GetAgentMetrics(context.Context, *GetAgentMetricsRequest) (*GetAgentMetricsResponse, error)
+	// --- التنبيهات من قواعد الخادم (للمشرف) ---
+	// القواعد المحملة من alerts.rules_path
+	ListAlertRules(context.Context, *ListAlertRulesRequest) (*ListAlertRulesResponse, error)
+	// التنبيهات المفتوحة والمنتهية، الأحدث أولًا
+	ListAlerts(context.Context, *ListAlertsRequest) (*ListAlertsResponse, error)
+	// --- الجرد الموسع من آخر تقرير للوكيل (للمشرف) ---
+	GetNetworkInterfaces(context.Context, *GetNetworkInterfacesRequest) (*GetNetworkInterfacesResponse, error)
+	// المستخدمون والمجموعات المحلية معًا
+	GetLocalUsers(context.Context, *GetLocalUsersRequest) (*GetLocalUsersResponse, error)
+	GetServices(context.Context, *GetServicesRequest) (*GetServicesResponse, error)
+	GetListeningSockets(context.Context, *GetListeningSocketsRequest) (*GetListeningSocketsResponse, error)
+	GetProcesses(context.Context, *GetProcessesRequest) (*GetProcessesResponse, error)
+	// --- حالة تحديثات النظام (للمشرف) ---
+	GetPatchStatus(context.Context, *GetPatchStatusRequest) (*GetPatchStatusResponse, error)
+	// مثل "الأجهزة التي ينقصها KB معين" و"الأجهزة التي تنتظر إعادة التشغيل منذ أكثر من 7 أيام"
+	ListPatchStatus(context.Context, *ListPatchStatusRequest) (*ListPatchStatusResponse, error)
+	// --- السكربتات وموافقتها ومخرجاتها (للمشرف) ---
+	ListScripts(context.Context, *ListScriptsRequest) (*ListScriptsResponse, error)
+	// إرسال أمر ينتظر الموافقة إلى الوكيل، والرفض يكون بـ CancelCommand
+	ApproveCommand(context.Context, *ApproveCommandRequest) (*ApproveCommandResponse, error)
+	GetCommandOutput(context.Context, *GetCommandOutputRequest) (*GetCommandOutputResponse, error)
+	// --- حزم الوكيل والنشر التدريجي للتحديثات (للمشرف) ---
+	// رفع حزمة موقعة: الرسالة الأولى بيانات الحزمة ثم أجزاء المحتوى
+	UploadAgentPackage(AgentService_UploadAgentPackageServer) error
+	ListAgentPackages(context.Context, *ListAgentPackagesRequest) (*ListAgentPackagesResponse, error)
+	CreateRollout(context.Context, *CreateRolloutRequest) (*CreateRolloutResponse, error)
+	// تغيير النسبة أو المجموعات، أو إيقاف النشر واستئنافه وإلغاؤه
+	UpdateRollout(context.Context, *UpdateRolloutRequest) (*UpdateRolloutResponse, error)
+	ListRollouts(context.Context, *ListRolloutsRequest) (*ListRolloutsResponse, error)
+	ListAgentUpdates(context.Context, *ListAgentUpdatesRequest) (*ListAgentUpdatesResponse, error)
+	// --- جمع الملفات من الوكلاء (للمشرف) ---
+	// يضع أمر COLLECT_FILE في طابور الوكيل، مثل السجلات والإعدادات وتفريغ الذاكرة
+	CollectFile(context.Context, *CollectFileRequest) (*CollectFileResponse, error)
+	ListFileCollections(context.Context, *ListFileCollectionsRequest) (*ListFileCollectionsResponse, error)
+	// تنزيل ملف مكتمل أجزاءً، ويُسجل كل تنزيل حدثًا file.downloaded
+	DownloadCollectedFile(*DownloadCollectedFileRequest, AgentService_DownloadCollectedFileServer) error
+	// --- المهام المجدولة والمتكررة على مجموعات من الوكلاء (للمشرف) ---
+	CreateScheduledJob(context.Context, *CreateScheduledJobRequest) (*CreateScheduledJobResponse, error)
+	// إيقاف المهمة مؤقتًا واستئنافها
+	UpdateScheduledJob(context.Context, *UpdateScheduledJobRequest) (*UpdateScheduledJobResponse, error)
+	DeleteScheduledJob(context.Context, *DeleteScheduledJobRequest) (*DeleteScheduledJobResponse, error)
+	ListScheduledJobs(context.Context, *ListScheduledJobsRequest) (*ListScheduledJobsResponse, error)
+	// تشغيل المهمة الآن خارج جدولها
+	RunScheduledJob(context.Context, *RunScheduledJobRequest) (*RunScheduledJobResponse, error)
+	ListJobRuns(context.Context, *ListJobRunsRequest) (*ListJobRunsResponse, error)
+	// تشغيل واحد مع حالة كل وكيل فيه
+	GetJobRun(context.Context, *GetJobRunRequest) (*GetJobRunResponse, error)
+	mustEmbedUnimplementedAgentServiceServer()
+}
+
+// UnimplementedAgentServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAgentServiceServer struct {
+}
+
+func (UnimplementedAgentServiceServer) RegisterAgent(context.Context, *RegisterRequest) (*RegisterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterAgent not implemented")
+}
+func (UnimplementedAgentServiceServer) FindAgent(context.Context, *FindAgentRequest) (*FindAgentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindAgent not implemented")
+}
+func (UnimplementedAgentServiceServer) SendHeartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendHeartbeat not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportFirewallStatus(context.Context, *FirewallStatusRequest) (*FirewallStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportFirewallStatus not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportInstalledApps(context.Context, *InstalledAppsRequest) (*InstalledAppsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportInstalledApps not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportNetworkInterfaces(context.Context, *NetworkInterfacesRequest) (*NetworkInterfacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportNetworkInterfaces not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportLocalUsers(context.Context, *LocalUsersRequest) (*LocalUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportLocalUsers not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportServices(context.Context, *ServicesRequest) (*ServicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportServices not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportListeningSockets(context.Context, *ListeningSocketsRequest) (*ListeningSocketsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportListeningSockets not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportProcesses(context.Context, *ProcessesRequest) (*ProcessesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportProcesses not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportPatchStatus(context.Context, *PatchStatusRequest) (*PatchStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportPatchStatus not implemented")
+}
+func (UnimplementedAgentServiceServer) DownloadAgentPackage(*DownloadAgentPackageRequest, AgentService_DownloadAgentPackageServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadAgentPackage not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportUpdateResult(context.Context, *UpdateResultRequest) (*UpdateResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportUpdateResult not implemented")
+}
+func (UnimplementedAgentServiceServer) GetFileUploadStatus(context.Context, *FileUploadStatusRequest) (*FileUploadStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFileUploadStatus not implemented")
+}
+func (UnimplementedAgentServiceServer) UploadCollectedFile(AgentService_UploadCollectedFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadCollectedFile not implemented")
+}
+func (UnimplementedAgentServiceServer) ConfigureFirewall(context.Context, *FirewallConfigurationRequest) (*FirewallConfigurationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfigureFirewall not implemented")
+}
+func (UnimplementedAgentServiceServer) PollCommands(context.Context, *PollCommandsRequest) (*PollCommandsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PollCommands not implemented")
+}
+func (UnimplementedAgentServiceServer) ReportCommandResult(context.Context, *CommandResultRequest) (*CommandResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportCommandResult not implemented")
+}
+func (UnimplementedAgentServiceServer) RunScript(context.Context, *RunScriptRequest) (*RunScriptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunScript not implemented")
+}
+func (UnimplementedAgentServiceServer) StreamCommandOutput(AgentService_StreamCommandOutputServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamCommandOutput not implemented")
+}
+func (UnimplementedAgentServiceServer) ListAgents(context.Context, *ListAgentsRequest) (*ListAgentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAgents not implemented")
+}
+func (UnimplementedAgentServiceServer) DecommissionAgent(context.Context, *DecommissionAgentRequest) (*DecommissionAgentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DecommissionAgent not implemented")
+}
+func (UnimplementedAgentServiceServer) GetFirewallRules(context.Context, *GetFirewallRulesRequest) (*GetFirewallRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFirewallRules not implemented")
+}
+func (UnimplementedAgentServiceServer) GetInstalledApps(context.Context, *GetInstalledAppsRequest) (*GetInstalledAppsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInstalledApps not implemented")
+}
+func (UnimplementedAgentServiceServer) ListCommands(context.Context, *ListCommandsRequest) (*ListCommandsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCommands not implemented")
+}
+func (UnimplementedAgentServiceServer) CancelCommand(context.Context, *CancelCommandRequest) (*CancelCommandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelCommand not implemented")
+}
+func (UnimplementedAgentServiceServer) CreateWebhookSubscription(context.Context, *CreateWebhookSubscriptionRequest) (*CreateWebhookSubscriptionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWebhookSubscription not implemented")
+}
+func (UnimplementedAgentServiceServer) ListWebhookSubscriptions(context.Context, *ListWebhookSubscriptionsRequest) (*ListWebhookSubscriptionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhookSubscriptions not implemented")
+}
+func (UnimplementedAgentServiceServer) DeleteWebhookSubscription(context.Context, *DeleteWebhookSubscriptionRequest) (*DeleteWebhookSubscriptionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhookSubscription not implemented")
+}
+func (UnimplementedAgentServiceServer) ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhookDeliveries not implemented")
+}
+func (UnimplementedAgentServiceServer) ReplayWebhookDeliveries(context.Context, *ReplayWebhookDeliveriesRequest) (*ReplayWebhookDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayWebhookDeliveries not implemented")
+}
+func (UnimplementedAgentServiceServer) WatchEvents(*WatchEventsRequest, AgentService_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedAgentServiceServer) ImportVulnerabilityFeed(AgentService_ImportVulnerabilityFeedServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportVulnerabilityFeed not implemented")
+}
+func (UnimplementedAgentServiceServer) ListVulnerabilityFindings(context.Context, *ListVulnerabilityFindingsRequest) (*ListVulnerabilityFindingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVulnerabilityFindings not implemented")
+}
+func (UnimplementedAgentServiceServer) ListFleetVulnerabilities(context.Context, *ListFleetVulnerabilitiesRequest) (*ListFleetVulnerabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFleetVulnerabilities not implemented")
+}
+func (UnimplementedAgentServiceServer) ListSoftwareCatalog(context.Context, *ListSoftwareCatalogRequest) (*ListSoftwareCatalogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSoftwareCatalog not implemented")
+}
+func (UnimplementedAgentServiceServer) ListSoftwareInstalls(context.Context, *ListSoftwareInstallsRequest) (*ListSoftwareInstallsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSoftwareInstalls not implemented")
+}
+func (UnimplementedAgentServiceServer) ListFirewallFindings(context.Context, *ListFirewallFindingsRequest) (*ListFirewallFindingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFirewallFindings not implemented")
+}
+func (UnimplementedAgentServiceServer) ListCompliancePolicies(context.Context, *ListCompliancePoliciesRequest) (*ListCompliancePoliciesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCompliancePolicies not implemented")
+}
+func (UnimplementedAgentServiceServer) EvaluateCompliance(context.Context, *EvaluateComplianceRequest) (*EvaluateComplianceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EvaluateCompliance not implemented")
+}
+func (UnimplementedAgentServiceServer) ListComplianceResults(context.Context, *ListComplianceResultsRequest) (*ListComplianceResultsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListComplianceResults not implemented")
+}
+func (UnimplementedAgentServiceServer) GetComplianceHistory(context.Context, *GetComplianceHistoryRequest) (*GetComplianceHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetComplianceHistory not implemented")
+}
+func (UnimplementedAgentServiceServer) GetComplianceReport(context.Context, *GetComplianceReportRequest) (*GetComplianceReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetComplianceReport not implemented")
+}
+func (UnimplementedAgentServiceServer) ExportFleetData(*ExportFleetDataRequest, AgentService_ExportFleetDataServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportFleetData not implemented")
+}
+func (UnimplementedAgentServiceServer) GetAvailabilityReport(context.Context, *GetAvailabilityReportRequest) (*GetAvailabilityReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAvailabilityReport not implemented")
+}
+func (UnimplementedAgentServiceServer) ListAgentIPHistory(context.Context, *ListAgentIPHistoryRequest) (*ListAgentIPHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAgentIPHistory not implemented")
+}
+func (UnimplementedAgentServiceServer) ListSuspectedClones(context.Context, *ListSuspectedClonesRequest) (*ListSuspectedClonesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSuspectedClones not implemented")
+}
+func (UnimplementedAgentServiceServer) ClearSuspectedClone(context.Context, *ClearSuspectedCloneRequest) (*ClearSuspectedCloneResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClearSuspectedClone not implemented")
+}
+func (UnimplementedAgentServiceServer) GetAgentMetrics(context.Context, *GetAgentMetricsRequest) (*GetAgentMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAgentMetrics not implemented")
+}
+func (UnimplementedAgentServiceServer) ListAlertRules(context.Context, *ListAlertRulesRequest) (*ListAlertRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAlertRules not implemented")
+}
+func (UnimplementedAgentServiceServer) ListAlerts(context.Context, *ListAlertsRequest) (*ListAlertsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAlerts not implemented")
+}
+func (UnimplementedAgentServiceServer) GetNetworkInterfaces(context.Context, *GetNetworkInterfacesRequest) (*GetNetworkInterfacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNetworkInterfaces not implemented")
+}
+func (UnimplementedAgentServiceServer) GetLocalUsers(context.Context, *GetLocalUsersRequest) (*GetLocalUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLocalUsers not implemented")
+}
+func (UnimplementedAgentServiceServer) GetServices(context.Context, *GetServicesRequest) (*GetServicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServices not implemented")
+}
+func (UnimplementedAgentServiceServer) GetListeningSockets(context.Context, *GetListeningSocketsRequest) (*GetListeningSocketsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetListeningSockets not implemented")
+}
+func (UnimplementedAgentServiceServer) GetProcesses(context.Context, *GetProcessesRequest) (*GetProcessesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProcesses not implemented")
+}
+func (UnimplementedAgentServiceServer) GetPatchStatus(context.Context, *GetPatchStatusRequest) (*GetPatchStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPatchStatus not implemented")
+}
+func (UnimplementedAgentServiceServer) ListPatchStatus(context.Context, *ListPatchStatusRequest) (*ListPatchStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPatchStatus not implemented")
+}
+func (UnimplementedAgentServiceServer) ListScripts(context.Context, *ListScriptsRequest) (*ListScriptsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListScripts not implemented")
+}
+func (UnimplementedAgentServiceServer) ApproveCommand(context.Context, *ApproveCommandRequest) (*ApproveCommandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveCommand not implemented")
+}
+func (UnimplementedAgentServiceServer) GetCommandOutput(context.Context, *GetCommandOutputRequest) (*GetCommandOutputResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCommandOutput not implemented")
+}
+func (UnimplementedAgentServiceServer) UploadAgentPackage(AgentService_UploadAgentPackageServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadAgentPackage not implemented")
+}
+func (UnimplementedAgentServiceServer) ListAgentPackages(context.Context, *ListAgentPackagesRequest) (*ListAgentPackagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAgentPackages not implemented")
+}
+func (UnimplementedAgentServiceServer) CreateRollout(context.Context, *CreateRolloutRequest) (*CreateRolloutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRollout not implemented")
+}
+func (UnimplementedAgentServiceServer) UpdateRollout(context.Context, *UpdateRolloutRequest) (*UpdateRolloutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRollout not implemented")
+}
+func (UnimplementedAgentServiceServer) ListRollouts(context.Context, *ListRolloutsRequest) (*ListRolloutsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRollouts not implemented")
+}
+func (UnimplementedAgentServiceServer) ListAgentUpdates(context.Context, *ListAgentUpdatesRequest) (*ListAgentUpdatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAgentUpdates not implemented")
+}
+func (UnimplementedAgentServiceServer) CollectFile(context.Context, *CollectFileRequest) (*CollectFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CollectFile not implemented")
+}
+func (UnimplementedAgentServiceServer) ListFileCollections(context.Context, *ListFileCollectionsRequest) (*ListFileCollectionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFileCollections not implemented")
+}
+func (UnimplementedAgentServiceServer) DownloadCollectedFile(*DownloadCollectedFileRequest, AgentService_DownloadCollectedFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadCollectedFile not implemented")
+}
+func (UnimplementedAgentServiceServer) CreateScheduledJob(context.Context, *CreateScheduledJobRequest) (*CreateScheduledJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateScheduledJob not implemented")
+}
+func (UnimplementedAgentServiceServer) UpdateScheduledJob(context.Context, *UpdateScheduledJobRequest) (*UpdateScheduledJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateScheduledJob not implemented")
+}
+func (UnimplementedAgentServiceServer) DeleteScheduledJob(context.Context, *DeleteScheduledJobRequest) (*DeleteScheduledJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteScheduledJob not implemented")
+}
+func (UnimplementedAgentServiceServer) ListScheduledJobs(context.Context, *ListScheduledJobsRequest) (*ListScheduledJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListScheduledJobs not implemented")
+}
+func (UnimplementedAgentServiceServer) RunScheduledJob(context.Context, *RunScheduledJobRequest) (*RunScheduledJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunScheduledJob not implemented")
+}
+func (UnimplementedAgentServiceServer) ListJobRuns(context.Context, *ListJobRunsRequest) (*ListJobRunsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobRuns not implemented")
+}
+func (UnimplementedAgentServiceServer) GetJobRun(context.Context, *GetJobRunRequest) (*GetJobRunResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJobRun not implemented")
+}
+func (UnimplementedAgentServiceServer) mustEmbedUnimplementedAgentServiceServer() {}
+
+// UnsafeAgentServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AgentServiceServer will
+// result in compilation errors.
+type UnsafeAgentServiceServer interface {
 	mustEmbedUnimplementedAgentServiceServer()
 }
 
-// UnimplementedAgentServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedAgentServiceServer struct {
+func RegisterAgentServiceServer(s grpc.ServiceRegistrar, srv AgentServiceServer) {
+	s.RegisterService(&AgentService_ServiceDesc, srv)
+}
+
+func _AgentService_RegisterAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).RegisterAgent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/RegisterAgent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).RegisterAgent(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_FindAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindAgentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).FindAgent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/FindAgent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).FindAgent(ctx, req.(*FindAgentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_SendHeartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).SendHeartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/SendHeartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).SendHeartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReportFirewallStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FirewallStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportFirewallStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportFirewallStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportFirewallStatus(ctx, req.(*FirewallStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReportInstalledApps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstalledAppsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportInstalledApps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportInstalledApps",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportInstalledApps(ctx, req.(*InstalledAppsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReportNetworkInterfaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NetworkInterfacesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportNetworkInterfaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportNetworkInterfaces",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportNetworkInterfaces(ctx, req.(*NetworkInterfacesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReportLocalUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LocalUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportLocalUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportLocalUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportLocalUsers(ctx, req.(*LocalUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReportServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportServices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportServices(ctx, req.(*ServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReportListeningSockets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListeningSocketsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportListeningSockets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportListeningSockets",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportListeningSockets(ctx, req.(*ListeningSocketsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReportProcesses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportProcesses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportProcesses",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportProcesses(ctx, req.(*ProcessesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReportPatchStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportPatchStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportPatchStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportPatchStatus(ctx, req.(*PatchStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_DownloadAgentPackage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadAgentPackageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServiceServer).DownloadAgentPackage(m, &agentServiceDownloadAgentPackageServer{stream})
+}
+
+type AgentService_DownloadAgentPackageServer interface {
+	Send(*AgentPackageChunk) error
+	grpc.ServerStream
+}
+
+type agentServiceDownloadAgentPackageServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentServiceDownloadAgentPackageServer) Send(m *AgentPackageChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AgentService_ReportUpdateResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportUpdateResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportUpdateResult",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportUpdateResult(ctx, req.(*UpdateResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetFileUploadStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileUploadStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetFileUploadStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetFileUploadStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetFileUploadStatus(ctx, req.(*FileUploadStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_UploadCollectedFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentServiceServer).UploadCollectedFile(&agentServiceUploadCollectedFileServer{stream})
+}
+
+type AgentService_UploadCollectedFileServer interface {
+	SendAndClose(*UploadCollectedFileResponse) error
+	Recv() (*UploadCollectedFileRequest, error)
+	grpc.ServerStream
+}
+
+type agentServiceUploadCollectedFileServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentServiceUploadCollectedFileServer) SendAndClose(m *UploadCollectedFileResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *agentServiceUploadCollectedFileServer) Recv() (*UploadCollectedFileRequest, error) {
+	m := new(UploadCollectedFileRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AgentService_ConfigureFirewall_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FirewallConfigurationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ConfigureFirewall(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ConfigureFirewall",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ConfigureFirewall(ctx, req.(*FirewallConfigurationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_PollCommands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PollCommandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).PollCommands(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/PollCommands",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).PollCommands(ctx, req.(*PollCommandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReportCommandResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportCommandResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportCommandResult",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportCommandResult(ctx, req.(*CommandResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_RunScript_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunScriptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).RunScript(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/RunScript",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).RunScript(ctx, req.(*RunScriptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_StreamCommandOutput_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentServiceServer).StreamCommandOutput(&agentServiceStreamCommandOutputServer{stream})
+}
+
+type AgentService_StreamCommandOutputServer interface {
+	SendAndClose(*StreamCommandOutputResponse) error
+	Recv() (*CommandOutputChunk, error)
+	grpc.ServerStream
+}
+
+type agentServiceStreamCommandOutputServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentServiceStreamCommandOutputServer) SendAndClose(m *StreamCommandOutputResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *agentServiceStreamCommandOutputServer) Recv() (*CommandOutputChunk, error) {
+	m := new(CommandOutputChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AgentService_ListAgents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAgentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListAgents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListAgents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListAgents(ctx, req.(*ListAgentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_DecommissionAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecommissionAgentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).DecommissionAgent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/DecommissionAgent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).DecommissionAgent(ctx, req.(*DecommissionAgentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetFirewallRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFirewallRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetFirewallRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetFirewallRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetFirewallRules(ctx, req.(*GetFirewallRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetInstalledApps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInstalledAppsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetInstalledApps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetInstalledApps",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetInstalledApps(ctx, req.(*GetInstalledAppsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListCommands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCommandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListCommands(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListCommands",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListCommands(ctx, req.(*ListCommandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_CancelCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelCommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).CancelCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/CancelCommand",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).CancelCommand(ctx, req.(*CancelCommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_CreateWebhookSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWebhookSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).CreateWebhookSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/CreateWebhookSubscription",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).CreateWebhookSubscription(ctx, req.(*CreateWebhookSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListWebhookSubscriptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhookSubscriptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListWebhookSubscriptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListWebhookSubscriptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListWebhookSubscriptions(ctx, req.(*ListWebhookSubscriptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_DeleteWebhookSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).DeleteWebhookSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/DeleteWebhookSubscription",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).DeleteWebhookSubscription(ctx, req.(*DeleteWebhookSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListWebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhookDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListWebhookDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListWebhookDeliveries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListWebhookDeliveries(ctx, req.(*ListWebhookDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ReplayWebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplayWebhookDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReplayWebhookDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReplayWebhookDeliveries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReplayWebhookDeliveries(ctx, req.(*ReplayWebhookDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServiceServer).WatchEvents(m, &agentServiceWatchEventsServer{stream})
+}
+
+type AgentService_WatchEventsServer interface {
+	Send(*FleetEvent) error
+	grpc.ServerStream
+}
+
+type agentServiceWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentServiceWatchEventsServer) Send(m *FleetEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AgentService_ImportVulnerabilityFeed_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentServiceServer).ImportVulnerabilityFeed(&agentServiceImportVulnerabilityFeedServer{stream})
+}
+
+type AgentService_ImportVulnerabilityFeedServer interface {
+	SendAndClose(*ImportVulnerabilityFeedResponse) error
+	Recv() (*VulnerabilityFeedChunk, error)
+	grpc.ServerStream
+}
+
+type agentServiceImportVulnerabilityFeedServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentServiceImportVulnerabilityFeedServer) SendAndClose(m *ImportVulnerabilityFeedResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *agentServiceImportVulnerabilityFeedServer) Recv() (*VulnerabilityFeedChunk, error) {
+	m := new(VulnerabilityFeedChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AgentService_ListVulnerabilityFindings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVulnerabilityFindingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListVulnerabilityFindings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListVulnerabilityFindings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListVulnerabilityFindings(ctx, req.(*ListVulnerabilityFindingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListFleetVulnerabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFleetVulnerabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListFleetVulnerabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListFleetVulnerabilities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListFleetVulnerabilities(ctx, req.(*ListFleetVulnerabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListSoftwareCatalog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSoftwareCatalogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListSoftwareCatalog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListSoftwareCatalog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListSoftwareCatalog(ctx, req.(*ListSoftwareCatalogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListSoftwareInstalls_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSoftwareInstallsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListSoftwareInstalls(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListSoftwareInstalls",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListSoftwareInstalls(ctx, req.(*ListSoftwareInstallsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListFirewallFindings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFirewallFindingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListFirewallFindings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListFirewallFindings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListFirewallFindings(ctx, req.(*ListFirewallFindingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListCompliancePolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCompliancePoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListCompliancePolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListCompliancePolicies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListCompliancePolicies(ctx, req.(*ListCompliancePoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_EvaluateCompliance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateComplianceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).EvaluateCompliance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/EvaluateCompliance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).EvaluateCompliance(ctx, req.(*EvaluateComplianceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListComplianceResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListComplianceResultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListComplianceResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListComplianceResults",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListComplianceResults(ctx, req.(*ListComplianceResultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetComplianceHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetComplianceHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetComplianceHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetComplianceHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetComplianceHistory(ctx, req.(*GetComplianceHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetComplianceReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetComplianceReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetComplianceReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetComplianceReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetComplianceReport(ctx, req.(*GetComplianceReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ExportFleetData_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportFleetDataRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServiceServer).ExportFleetData(m, &agentServiceExportFleetDataServer{stream})
+}
+
+type AgentService_ExportFleetDataServer interface {
+	Send(*ExportChunk) error
+	grpc.ServerStream
+}
+
+type agentServiceExportFleetDataServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentServiceExportFleetDataServer) Send(m *ExportChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AgentService_GetAvailabilityReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAvailabilityReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetAvailabilityReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetAvailabilityReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetAvailabilityReport(ctx, req.(*GetAvailabilityReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListAgentIPHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAgentIPHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListAgentIPHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListAgentIPHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListAgentIPHistory(ctx, req.(*ListAgentIPHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListSuspectedClones_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSuspectedClonesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListSuspectedClones(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListSuspectedClones",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListSuspectedClones(ctx, req.(*ListSuspectedClonesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ClearSuspectedClone_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearSuspectedCloneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ClearSuspectedClone(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ClearSuspectedClone",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ClearSuspectedClone(ctx, req.(*ClearSuspectedCloneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetAgentMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAgentMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetAgentMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetAgentMetrics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetAgentMetrics(ctx, req.(*GetAgentMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListAlertRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAlertRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListAlertRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListAlertRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListAlertRules(ctx, req.(*ListAlertRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListAlerts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAlertsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListAlerts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListAlerts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListAlerts(ctx, req.(*ListAlertsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetNetworkInterfaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNetworkInterfacesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetNetworkInterfaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetNetworkInterfaces",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetNetworkInterfaces(ctx, req.(*GetNetworkInterfacesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetLocalUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLocalUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetLocalUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetLocalUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetLocalUsers(ctx, req.(*GetLocalUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetServices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetServices(ctx, req.(*GetServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetListeningSockets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetListeningSocketsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetListeningSockets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetListeningSockets",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetListeningSockets(ctx, req.(*GetListeningSocketsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetProcesses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProcessesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetProcesses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetProcesses",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetProcesses(ctx, req.(*GetProcessesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetPatchStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPatchStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetPatchStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetPatchStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetPatchStatus(ctx, req.(*GetPatchStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListPatchStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPatchStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListPatchStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListPatchStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListPatchStatus(ctx, req.(*ListPatchStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListScripts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListScriptsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListScripts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListScripts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListScripts(ctx, req.(*ListScriptsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ApproveCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveCommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ApproveCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ApproveCommand",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ApproveCommand(ctx, req.(*ApproveCommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetCommandOutput_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCommandOutputRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetCommandOutput(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetCommandOutput",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetCommandOutput(ctx, req.(*GetCommandOutputRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_UploadAgentPackage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentServiceServer).UploadAgentPackage(&agentServiceUploadAgentPackageServer{stream})
+}
+
+type AgentService_UploadAgentPackageServer interface {
+	SendAndClose(*UploadAgentPackageResponse) error
+	Recv() (*UploadAgentPackageRequest, error)
+	grpc.ServerStream
+}
+
+type agentServiceUploadAgentPackageServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentServiceUploadAgentPackageServer) SendAndClose(m *UploadAgentPackageResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *agentServiceUploadAgentPackageServer) Recv() (*UploadAgentPackageRequest, error) {
+	m := new(UploadAgentPackageRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AgentService_ListAgentPackages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAgentPackagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListAgentPackages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListAgentPackages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListAgentPackages(ctx, req.(*ListAgentPackagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_CreateRollout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRolloutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).CreateRollout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/CreateRollout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).CreateRollout(ctx, req.(*CreateRolloutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_UpdateRollout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRolloutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).UpdateRollout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/UpdateRollout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).UpdateRollout(ctx, req.(*UpdateRolloutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListRollouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRolloutsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListRollouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListRollouts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListRollouts(ctx, req.(*ListRolloutsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListAgentUpdates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAgentUpdatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListAgentUpdates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListAgentUpdates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListAgentUpdates(ctx, req.(*ListAgentUpdatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (UnimplementedAgentServiceServer) RegisterAgent(context.Context, *RegisterRequest) (*RegisterResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method RegisterAgent not implemented")
+func _AgentService_CollectFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).CollectFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/CollectFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).CollectFile(ctx, req.(*CollectFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedAgentServiceServer) FindAgent(context.Context, *FindAgentRequest) (*FindAgentResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method FindAgent not implemented")
+
+func _AgentService_ListFileCollections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFileCollectionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListFileCollections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ListFileCollections",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListFileCollections(ctx, req.(*ListFileCollectionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedAgentServiceServer) SendHeartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SendHeartbeat not implemented")
+
+func _AgentService_DownloadCollectedFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadCollectedFileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServiceServer).DownloadCollectedFile(m, &agentServiceDownloadCollectedFileServer{stream})
 }
-func (UnimplementedAgentServiceServer) ReportFirewallStatus(context.Context, *FirewallStatusRequest) (*FirewallStatusResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ReportFirewallStatus not implemented")
+
+type AgentService_DownloadCollectedFileServer interface {
+	Send(*CollectedFileChunk) error
+	grpc.ServerStream
 }
-func (UnimplementedAgentServiceServer) ReportInstalledApps(context.Context, *InstalledAppsRequest) (*InstalledAppsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ReportInstalledApps not implemented")
+
+type agentServiceDownloadCollectedFileServer struct {
+	grpc.ServerStream
 }
-func (UnimplementedAgentServiceServer) mustEmbedUnimplementedAgentServiceServer() {}
 
-// UnsafeAgentServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to AgentServiceServer will
-// result in compilation errors.
-type UnsafeAgentServiceServer interface {
-	mustEmbedUnimplementedAgentServiceServer()
+func (x *agentServiceDownloadCollectedFileServer) Send(m *CollectedFileChunk) error {
+	return x.ServerStream.SendMsg(m)
 }
 
-func RegisterAgentServiceServer(s grpc.ServiceRegistrar, srv AgentServiceServer) {
-	s.RegisterService(&AgentService_ServiceDesc, srv)
+func _AgentService_CreateScheduledJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateScheduledJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).CreateScheduledJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/CreateScheduledJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).CreateScheduledJob(ctx, req.(*CreateScheduledJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _AgentService_RegisterAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RegisterRequest)
+func _AgentService_UpdateScheduledJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateScheduledJobRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AgentServiceServer).RegisterAgent(ctx, in)
+		return srv.(AgentServiceServer).UpdateScheduledJob(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.AgentService/RegisterAgent",
+		FullMethod: "/proto.AgentService/UpdateScheduledJob",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AgentServiceServer).RegisterAgent(ctx, req.(*RegisterRequest))
+		return srv.(AgentServiceServer).UpdateScheduledJob(ctx, req.(*UpdateScheduledJobRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AgentService_FindAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(FindAgentRequest)
+func _AgentService_DeleteScheduledJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteScheduledJobRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AgentServiceServer).FindAgent(ctx, in)
+		return srv.(AgentServiceServer).DeleteScheduledJob(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.AgentService/FindAgent",
+		FullMethod: "/proto.AgentService/DeleteScheduledJob",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AgentServiceServer).FindAgent(ctx, req.(*FindAgentRequest))
+		return srv.(AgentServiceServer).DeleteScheduledJob(ctx, req.(*DeleteScheduledJobRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AgentService_SendHeartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(HeartbeatRequest)
+func _AgentService_ListScheduledJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListScheduledJobsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AgentServiceServer).SendHeartbeat(ctx, in)
+		return srv.(AgentServiceServer).ListScheduledJobs(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.AgentService/SendHeartbeat",
+		FullMethod: "/proto.AgentService/ListScheduledJobs",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AgentServiceServer).SendHeartbeat(ctx, req.(*HeartbeatRequest))
+		return srv.(AgentServiceServer).ListScheduledJobs(ctx, req.(*ListScheduledJobsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AgentService_ReportFirewallStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(FirewallStatusRequest)
+func _AgentService_RunScheduledJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunScheduledJobRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AgentServiceServer).ReportFirewallStatus(ctx, in)
+		return srv.(AgentServiceServer).RunScheduledJob(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.AgentService/ReportFirewallStatus",
+		FullMethod: "/proto.AgentService/RunScheduledJob",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AgentServiceServer).ReportFirewallStatus(ctx, req.(*FirewallStatusRequest))
+		return srv.(AgentServiceServer).RunScheduledJob(ctx, req.(*RunScheduledJobRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AgentService_ReportInstalledApps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(InstalledAppsRequest)
+func _AgentService_ListJobRuns_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobRunsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AgentServiceServer).ReportInstalledApps(ctx, in)
+		return srv.(AgentServiceServer).ListJobRuns(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/proto.AgentService/ReportInstalledApps",
+		FullMethod: "/proto.AgentService/ListJobRuns",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AgentServiceServer).ReportInstalledApps(ctx, req.(*InstalledAppsRequest))
+		return srv.(AgentServiceServer).ListJobRuns(ctx, req.(*ListJobRunsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_GetJobRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetJobRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/GetJobRun",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetJobRun(ctx, req.(*GetJobRunRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -253,7 +2925,304 @@ var AgentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReportInstalledApps",
 			Handler:    _AgentService_ReportInstalledApps_Handler,
 		},
+		{
+			MethodName: "ReportNetworkInterfaces",
+			Handler:    _AgentService_ReportNetworkInterfaces_Handler,
+		},
+		{
+			MethodName: "ReportLocalUsers",
+			Handler:    _AgentService_ReportLocalUsers_Handler,
+		},
+		{
+			MethodName: "ReportServices",
+			Handler:    _AgentService_ReportServices_Handler,
+		},
+		{
+			MethodName: "ReportListeningSockets",
+			Handler:    _AgentService_ReportListeningSockets_Handler,
+		},
+		{
+			MethodName: "ReportProcesses",
+			Handler:    _AgentService_ReportProcesses_Handler,
+		},
+		{
+			MethodName: "ReportPatchStatus",
+			Handler:    _AgentService_ReportPatchStatus_Handler,
+		},
+		{
+			MethodName: "ReportUpdateResult",
+			Handler:    _AgentService_ReportUpdateResult_Handler,
+		},
+		{
+			MethodName: "GetFileUploadStatus",
+			Handler:    _AgentService_GetFileUploadStatus_Handler,
+		},
+		{
+			MethodName: "ConfigureFirewall",
+			Handler:    _AgentService_ConfigureFirewall_Handler,
+		},
+		{
+			MethodName: "PollCommands",
+			Handler:    _AgentService_PollCommands_Handler,
+		},
+		{
+			MethodName: "ReportCommandResult",
+			Handler:    _AgentService_ReportCommandResult_Handler,
+		},
+		{
+			MethodName: "RunScript",
+			Handler:    _AgentService_RunScript_Handler,
+		},
+		{
+			MethodName: "ListAgents",
+			Handler:    _AgentService_ListAgents_Handler,
+		},
+		{
+			MethodName: "DecommissionAgent",
+			Handler:    _AgentService_DecommissionAgent_Handler,
+		},
+		{
+			MethodName: "GetFirewallRules",
+			Handler:    _AgentService_GetFirewallRules_Handler,
+		},
+		{
+			MethodName: "GetInstalledApps",
+			Handler:    _AgentService_GetInstalledApps_Handler,
+		},
+		{
+			MethodName: "ListCommands",
+			Handler:    _AgentService_ListCommands_Handler,
+		},
+		{
+			MethodName: "CancelCommand",
+			Handler:    _AgentService_CancelCommand_Handler,
+		},
+		{
+			MethodName: "CreateWebhookSubscription",
+			Handler:    _AgentService_CreateWebhookSubscription_Handler,
+		},
+		{
+			MethodName: "ListWebhookSubscriptions",
+			Handler:    _AgentService_ListWebhookSubscriptions_Handler,
+		},
+		{
+			MethodName: "DeleteWebhookSubscription",
+			Handler:    _AgentService_DeleteWebhookSubscription_Handler,
+		},
+		{
+			MethodName: "ListWebhookDeliveries",
+			Handler:    _AgentService_ListWebhookDeliveries_Handler,
+		},
+		{
+			MethodName: "ReplayWebhookDeliveries",
+			Handler:    _AgentService_ReplayWebhookDeliveries_Handler,
+		},
+		{
+			MethodName: "ListVulnerabilityFindings",
+			Handler:    _AgentService_ListVulnerabilityFindings_Handler,
+		},
+		{
+			MethodName: "ListFleetVulnerabilities",
+			Handler:    _AgentService_ListFleetVulnerabilities_Handler,
+		},
+		{
+			MethodName: "ListSoftwareCatalog",
+			Handler:    _AgentService_ListSoftwareCatalog_Handler,
+		},
+		{
+			MethodName: "ListSoftwareInstalls",
+			Handler:    _AgentService_ListSoftwareInstalls_Handler,
+		},
+		{
+			MethodName: "ListFirewallFindings",
+			Handler:    _AgentService_ListFirewallFindings_Handler,
+		},
+		{
+			MethodName: "ListCompliancePolicies",
+			Handler:    _AgentService_ListCompliancePolicies_Handler,
+		},
+		{
+			MethodName: "EvaluateCompliance",
+			Handler:    _AgentService_EvaluateCompliance_Handler,
+		},
+		{
+			MethodName: "ListComplianceResults",
+			Handler:    _AgentService_ListComplianceResults_Handler,
+		},
+		{
+			MethodName: "GetComplianceHistory",
+			Handler:    _AgentService_GetComplianceHistory_Handler,
+		},
+		{
+			MethodName: "GetComplianceReport",
+			Handler:    _AgentService_GetComplianceReport_Handler,
+		},
+		{
+			MethodName: "GetAvailabilityReport",
+			Handler:    _AgentService_GetAvailabilityReport_Handler,
+		},
+		{
+			MethodName: "ListAgentIPHistory",
+			Handler:    _AgentService_ListAgentIPHistory_Handler,
+		},
+		{
+			MethodName: "ListSuspectedClones",
+			Handler:    _AgentService_ListSuspectedClones_Handler,
+		},
+		{
+			MethodName: "ClearSuspectedClone",
+			Handler:    _AgentService_ClearSuspectedClone_Handler,
+		},
+		{
+			MethodName: "GetAgentMetrics",
+			Handler:    _AgentService_GetAgentMetrics_Handler,
+		},
+		{
+			MethodName: "ListAlertRules",
+			Handler:    _AgentService_ListAlertRules_Handler,
+		},
+		{
+			MethodName: "ListAlerts",
+			Handler:    _AgentService_ListAlerts_Handler,
+		},
+		{
+			MethodName: "GetNetworkInterfaces",
+			Handler:    _AgentService_GetNetworkInterfaces_Handler,
+		},
+		{
+			MethodName: "GetLocalUsers",
+			Handler:    _AgentService_GetLocalUsers_Handler,
+		},
+		{
+			MethodName: "GetServices",
+			Handler:    _AgentService_GetServices_Handler,
+		},
+		{
+			MethodName: "GetListeningSockets",
+			Handler:    _AgentService_GetListeningSockets_Handler,
+		},
+		{
+			MethodName: "GetProcesses",
+			Handler:    _AgentService_GetProcesses_Handler,
+		},
+		{
+			MethodName: "GetPatchStatus",
+			Handler:    _AgentService_GetPatchStatus_Handler,
+		},
+		{
+			MethodName: "ListPatchStatus",
+			Handler:    _AgentService_ListPatchStatus_Handler,
+		},
+		{
+			MethodName: "ListScripts",
+			Handler:    _AgentService_ListScripts_Handler,
+		},
+		{
+			MethodName: "ApproveCommand",
+			Handler:    _AgentService_ApproveCommand_Handler,
+		},
+		{
+			MethodName: "GetCommandOutput",
+			Handler:    _AgentService_GetCommandOutput_Handler,
+		},
+		{
+			MethodName: "ListAgentPackages",
+			Handler:    _AgentService_ListAgentPackages_Handler,
+		},
+		{
+			MethodName: "CreateRollout",
+			Handler:    _AgentService_CreateRollout_Handler,
+		},
+		{
+			MethodName: "UpdateRollout",
+			Handler:    _AgentService_UpdateRollout_Handler,
+		},
+		{
+			MethodName: "ListRollouts",
+			Handler:    _AgentService_ListRollouts_Handler,
+		},
+		{
+			MethodName: "ListAgentUpdates",
+			Handler:    _AgentService_ListAgentUpdates_Handler,
+		},
+		{
+			MethodName: "CollectFile",
+			Handler:    _AgentService_CollectFile_Handler,
+		},
+		{
+			MethodName: "ListFileCollections",
+			Handler:    _AgentService_ListFileCollections_Handler,
+		},
+		{
+			MethodName: "CreateScheduledJob",
+			Handler:    _AgentService_CreateScheduledJob_Handler,
+		},
+		{
+			MethodName: "UpdateScheduledJob",
+			Handler:    _AgentService_UpdateScheduledJob_Handler,
+		},
+		{
+			MethodName: "DeleteScheduledJob",
+			Handler:    _AgentService_DeleteScheduledJob_Handler,
+		},
+		{
+			MethodName: "ListScheduledJobs",
+			Handler:    _AgentService_ListScheduledJobs_Handler,
+		},
+		{
+			MethodName: "RunScheduledJob",
+			Handler:    _AgentService_RunScheduledJob_Handler,
+		},
+		{
+			MethodName: "ListJobRuns",
+			Handler:    _AgentService_ListJobRuns_Handler,
+		},
+		{
+			MethodName: "GetJobRun",
+			Handler:    _AgentService_GetJobRun_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DownloadAgentPackage",
+			Handler:       _AgentService_DownloadAgentPackage_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadCollectedFile",
+			Handler:       _AgentService_UploadCollectedFile_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "StreamCommandOutput",
+			Handler:       _AgentService_StreamCommandOutput_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _AgentService_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportVulnerabilityFeed",
+			Handler:       _AgentService_ImportVulnerabilityFeed_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ExportFleetData",
+			Handler:       _AgentService_ExportFleetData_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadAgentPackage",
+			Handler:       _AgentService_UploadAgentPackage_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadCollectedFile",
+			Handler:       _AgentService_DownloadCollectedFile_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/agent_service.proto",
 }
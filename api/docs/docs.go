@@ -0,0 +1,10 @@
+// Package docs embeds the generated API documentation so the server can
+// serve it: index.html is the protoc-gen-doc reference for the proto and
+// openapi.json the spec for the REST gateway (see go generate in
+// internal/gateway).
+package docs
+
+import "embed"
+
+//go:embed index.html openapi.json
+var FS embed.FS
@@ -0,0 +1,193 @@
+// cmd/agentctl/agents.go
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newAgentsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "agents",
+		Aliases: []string{"agent"},
+		Short:   "List, inspect and decommission agents",
+	}
+	cmd.AddCommand(newAgentsListCommand(), newAgentsGetCommand(), newAgentsDecommissionCommand())
+	return cmd
+}
+
+func newAgentsListCommand() *cobra.Command {
+	var (
+		statusName string
+		pageSize   int32
+		all        bool
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List agents",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status, err := parseAgentStatus(statusName)
+			if err != nil {
+				return err
+			}
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			resp := &pb.ListAgentsResponse{}
+			req := &pb.ListAgentsRequest{Status: status, PageSize: pageSize}
+			for {
+				ctx, cancel := c.context(cmd.Context())
+				page, err := c.ListAgents(ctx, req)
+				cancel()
+				if err != nil {
+					return err
+				}
+				resp.Agents = append(resp.Agents, page.GetAgents()...)
+				resp.NextPageToken = page.GetNextPageToken()
+				if !all || page.GetNextPageToken() == "" {
+					break
+				}
+				req.PageToken = page.GetNextPageToken()
+			}
+
+			t := agentTable(resp.GetAgents()...)
+			if resp.GetNextPageToken() != "" {
+				defer fmt.Fprintf(cmd.ErrOrStderr(), "More agents available; use --all to fetch every page.\n")
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&statusName, "status", "", "only list agents with this status: ONLINE, OFFLINE or DECOMMISSIONED")
+	cmd.Flags().Int32Var(&pageSize, "page-size", 100, "agents per request")
+	cmd.Flags().BoolVar(&all, "all", false, "fetch every page")
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions([]string{"ONLINE", "OFFLINE", "DECOMMISSIONED"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newAgentsGetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "get AGENT_ID",
+		Short:             "Show one agent",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.FindAgent(ctx, &pb.FindAgentRequest{AgentId: args[0]})
+			if err != nil {
+				return err
+			}
+			if !resp.GetFound() {
+				return fmt.Errorf("agent %s not found", args[0])
+			}
+			return c.render(cmd.OutOrStdout(), resp.GetAgent(), agentDetails(resp.GetAgent()))
+		},
+	}
+}
+
+func newAgentsDecommissionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "decommission AGENT_ID",
+		Short:             "Retire an agent so it is no longer monitored or accepted",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.DecommissionAgent(ctx, &pb.DecommissionAgentRequest{AgentId: args[0]})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp.GetAgent(), agentTable(resp.GetAgent()))
+		},
+	}
+}
+
+func agentTable(agents ...*pb.Agent) *table {
+	t := &table{header: []string{"AGENT ID", "HOSTNAME", "STATUS", "OS", "IP", "LAST SEEN"}}
+	for _, a := range agents {
+		t.add(a.GetAgentId(), orDash(a.GetHostname()), a.GetStatus().String(),
+			orDash(strings.TrimSpace(a.GetOsName()+" "+a.GetOsVersion())),
+			orDash(a.GetLastKnownIp()), formatTime(a.GetLastSeen()))
+	}
+	return t
+}
+
+func agentDetails(a *pb.Agent) *table {
+	t := &table{header: []string{"FIELD", "VALUE"}}
+	t.add("Agent ID", a.GetAgentId())
+	t.add("Hostname", orDash(a.GetHostname()))
+	t.add("Status", a.GetStatus().String())
+	t.add("OS", orDash(strings.TrimSpace(a.GetOsName()+" "+a.GetOsVersion())))
+	t.add("Kernel", orDash(a.GetKernelVersion()))
+	t.add("Agent version", orDash(strings.TrimSpace(a.GetAgentVersion()+" "+a.GetArch())))
+	t.add("CPU cores", strconv.Itoa(int(a.GetCpuCores())))
+	t.add("Memory (GB)", strconv.FormatFloat(a.GetMemoryGb(), 'f', 1, 64))
+	t.add("Disk (GB)", strconv.FormatFloat(a.GetDiskSpaceGb(), 'f', 1, 64))
+	t.add("Last IP", orDash(a.GetLastKnownIp()))
+	t.add("Peer IP", orDash(a.GetLastPeerIp()))
+	t.add("Last seen", formatTime(a.GetLastSeen()))
+	if a.GetCloneSuspectedAt() != nil {
+		t.add("Clone suspected", formatTime(a.GetCloneSuspectedAt())+": "+a.GetCloneReason())
+	}
+	return t
+}
+
+func parseAgentStatus(name string) (pb.AgentStatus, error) {
+	if name == "" {
+		return pb.AgentStatus_UNKNOWN, nil
+	}
+	v, ok := pb.AgentStatus_value[strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown agent status %q", name)
+	}
+	return pb.AgentStatus(v), nil
+}
+
+// completeAgentIDs suggests agent IDs from the server for shell completion.
+func completeAgentIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	c, err := dial(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer c.Close()
+
+	ctx, cancel := c.context(cmd.Context())
+	defer cancel()
+	resp, err := c.ListAgents(ctx, &pb.ListAgentsRequest{PageSize: 1000})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var ids []string
+	for _, a := range resp.GetAgents() {
+		if strings.HasPrefix(a.GetAgentId(), toComplete) {
+			ids = append(ids, a.GetAgentId()+"\t"+a.GetHostname())
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
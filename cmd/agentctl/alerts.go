@@ -0,0 +1,137 @@
+// cmd/agentctl/alerts.go
+
+package main
+
+import (
+	pb "agent_server/agent_server/proto"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var alertStateNames = []string{"pending", "firing", "resolved"}
+
+func newAlertsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alerts",
+		Short: "Show alert rules and the alerts they raised",
+		Long: "Show alert rules and the alerts they raised. Rules are YAML files loaded from\n" +
+			"alerts.rules_path on the server; an alert is pending until its condition has\n" +
+			"held for the rule's for-duration, then firing until the condition clears.",
+	}
+	cmd.AddCommand(newAlertsListCommand(), newAlertsRulesCommand())
+	return cmd
+}
+
+func newAlertsListCommand() *cobra.Command {
+	var (
+		req         pb.ListAlertsRequest
+		states      []string
+		minSeverity string
+		since       string
+	)
+	cmd := &cobra.Command{
+		Use:   "list [AGENT_ID]",
+		Short: "List alerts, newest first",
+		Example: "  agentctl alerts list --state firing\n" +
+			"  agentctl alerts list web-01 --since 168h --min-severity high",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				req.AgentId = args[0]
+			}
+			for _, name := range states {
+				s, ok := pb.AlertState_value["ALERT_"+strings.ToUpper(name)]
+				if !ok || s == 0 {
+					return fmt.Errorf("unknown state %q (want one of %s)", name, strings.Join(alertStateNames, ", "))
+				}
+				req.States = append(req.States, pb.AlertState(s))
+			}
+			var err error
+			if req.MinSeverity, err = parseSeverity(minSeverity); err != nil {
+				return err
+			}
+			if since != "" {
+				t, err := parseSince(since)
+				if err != nil {
+					return err
+				}
+				req.Since = timestamppb.New(t)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListAlerts(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"ID", "RULE", "STATE", "SEVERITY", "AGENT ID", "SUMMARY", "STARTED", "FIRED", "RESOLVED"}}
+			for _, a := range resp.GetAlerts() {
+				t.add(strconv.FormatUint(a.GetId(), 10), a.GetRuleId(), strings.TrimPrefix(a.GetState().String(), "ALERT_"),
+					describeSeverity(a.GetSeverity()), orDash(a.GetAgentId()), a.GetSummary(),
+					formatTime(a.GetStartedAt()), formatTime(a.GetFiredAt()), formatTime(a.GetResolvedAt()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&req.RuleId, "rule", "", "only alerts of this rule")
+	cmd.Flags().StringSliceVar(&states, "state", nil, "only alerts in these states: "+strings.Join(alertStateNames, ", "))
+	cmd.Flags().StringVar(&minSeverity, "min-severity", "", "only alerts of at least this severity: low, medium, high or critical")
+	cmd.Flags().StringVar(&since, "since", "", "only alerts started after this time (duration such as 24h, or RFC 3339)")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of alerts")
+	_ = cmd.RegisterFlagCompletionFunc("state", cobra.FixedCompletions(alertStateNames, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("min-severity", cobra.FixedCompletions(severityNames, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newAlertsRulesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rules",
+		Short: "List the loaded alert rules",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListAlertRules(ctx, &pb.ListAlertRulesRequest{})
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"RULE", "NAME", "SEVERITY", "CONDITION", "FOR", "LABELS", "NOTIFY"}}
+			for _, r := range resp.GetRules() {
+				t.add(r.GetId(), r.GetName(), describeSeverity(r.GetSeverity()), r.GetCondition(),
+					formatSeconds(r.GetForSeconds()), formatLabels(r.GetLabels()), strings.Join(r.GetNotify(), ","))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+}
+
+// formatLabels renders a label selector as sorted key=value pairs.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,48 @@
+// cmd/agentctl/apps.go
+
+package main
+
+import (
+	"fmt"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newAppsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apps",
+		Short: "Show applications reported by agents",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:               "show AGENT_ID",
+		Short:             "Show the installed applications from the agent's latest report",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.GetInstalledApps(ctx, &pb.GetInstalledAppsRequest{AgentId: args[0]})
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"NAME", "VERSION", "PUBLISHER", "INSTALLED"}}
+			for _, a := range resp.GetApps() {
+				t.add(a.GetName(), orDash(a.GetVersion()), orDash(a.GetPublisher()), formatTime(a.GetInstallDate()))
+			}
+			if c.output == "table" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Reported at %s\n", formatTime(resp.GetReportedAt()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,99 @@
+// cmd/agentctl/availability.go
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newAvailabilityCommand() *cobra.Command {
+	var (
+		req                  pb.GetAvailabilityReportRequest
+		sinceFlag, untilFlag string
+	)
+	cmd := &cobra.Command{
+		Use:   "availability [AGENT_ID]",
+		Short: "Report availability, outages and mean time to recover over a time window",
+		Long: "Report availability, outages and mean time to recover from the agent status\n" +
+			"history. The first row covers every selected agent, followed by one row per\n" +
+			"--group-by label value and then the agents, least available first. Only time\n" +
+			"spent ONLINE or OFFLINE is observed; the window defaults to the last 30 days.",
+		Example: "  agentctl availability --since 720h --group-by site\n" +
+			"  agentctl availability web-01 --since 2024-05-01T00:00:00Z --until 2024-06-01T00:00:00Z",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				req.AgentId = args[0]
+			}
+			if sinceFlag != "" {
+				t, err := parseSince(sinceFlag)
+				if err != nil {
+					return err
+				}
+				req.Since = timestamppb.New(t)
+			}
+			if untilFlag != "" {
+				t, err := parseSince(untilFlag)
+				if err != nil {
+					return err
+				}
+				req.Until = timestamppb.New(t)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.GetAvailabilityReport(ctx, &req)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "From %s to %s\n", formatTime(resp.GetSince()), formatTime(resp.GetUntil()))
+			t := &table{header: []string{"SCOPE", "AGENTS", "STATUS", "AVAILABILITY", "DOWNTIME", "OUTAGES", "RECOVERED", "MTTR"}}
+			addAvailabilityRow(t, "(fleet)", strconv.Itoa(int(resp.GetFleet().GetAgents())), "-", resp.GetFleet().GetStats())
+			for _, g := range resp.GetGroups() {
+				addAvailabilityRow(t, req.GroupBy+"="+orDash(g.GetGroup()), strconv.Itoa(int(g.GetAgents())), "-", g.GetStats())
+			}
+			for _, a := range resp.GetAgents() {
+				addAvailabilityRow(t, a.GetAgentId(), "1", a.GetStatus().String(), a.GetStats())
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&req.GroupBy, "group-by", "", "also summarize agents by this label key, such as site")
+	cmd.Flags().StringToStringVar(&req.Labels, "label", nil, "only agents with this label, as key=value (repeatable, all must match)")
+	cmd.Flags().StringVar(&sinceFlag, "since", "", "start of the window: a duration ago (720h) or an RFC 3339 time")
+	cmd.Flags().StringVar(&untilFlag, "until", "", "end of the window: a duration ago or an RFC 3339 time (default now)")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of agents")
+	return cmd
+}
+
+func addAvailabilityRow(t *table, scope, agents, status string, s *pb.AvailabilityStats) {
+	if s.GetObservedSeconds() == 0 {
+		t.add(scope, agents, status, "-", "-", "-", "-", "-")
+		return
+	}
+	mttr := "-"
+	if s.GetRecoveredOutages() > 0 {
+		mttr = formatSeconds(s.GetMeanTimeToRecoverSeconds())
+	}
+	t.add(scope, agents, status, strconv.FormatFloat(s.GetAvailabilityPercent(), 'f', 2, 64)+"%",
+		formatSeconds(s.GetDowntimeSeconds()), strconv.Itoa(int(s.GetOutages())), strconv.Itoa(int(s.GetRecoveredOutages())), mttr)
+}
+
+// formatSeconds renders a duration in seconds such as 3h25m0s.
+func formatSeconds(seconds int64) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,76 @@
+// cmd/agentctl/clones.go
+
+package main
+
+import (
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newClonesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clones",
+		Short: "Review agents whose ID is used by more than one host",
+		Long: "Review agents whose ID is used by more than one host, typically VMs cloned from\n" +
+			"an image with the agent already installed. With clones.reissue_identities the\n" +
+			"server gives the extra instances new agent IDs when they register again.",
+	}
+	cmd.AddCommand(newClonesListCommand(), newClonesClearCommand())
+	return cmd
+}
+
+func newClonesListCommand() *cobra.Command {
+	var limit int32
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List suspected clones, most recently flagged first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListSuspectedClones(ctx, &pb.ListSuspectedClonesRequest{Limit: limit})
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"AGENT ID", "HOSTNAME", "STATUS", "IP", "SUSPECTED", "REASON"}}
+			for _, a := range resp.GetAgents() {
+				t.add(a.GetAgentId(), orDash(a.GetHostname()), a.GetStatus().String(), orDash(a.GetLastKnownIp()),
+					formatTime(a.GetCloneSuspectedAt()), a.GetCloneReason())
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().Int32Var(&limit, "limit", 100, "maximum number of agents")
+	return cmd
+}
+
+func newClonesClearCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "clear AGENT_ID",
+		Short:             "Clear the flag once the duplicate hosts have been dealt with",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ClearSuspectedClone(ctx, &pb.ClearSuspectedCloneRequest{AgentId: args[0]})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp.GetAgent(), agentDetails(resp.GetAgent()))
+		},
+	}
+}
@@ -0,0 +1,222 @@
+// cmd/agentctl/commands.go
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newCommandsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "commands",
+		Aliases: []string{"command", "cmd"},
+		Short:   "List, approve and cancel queued agent commands",
+	}
+	cmd.AddCommand(newCommandsListCommand(), newCommandsCancelCommand(), newCommandsApproveCommand(), newCommandsOutputCommand())
+	return cmd
+}
+
+func newCommandsListCommand() *cobra.Command {
+	var (
+		agentID    string
+		statusName string
+		limit      int32
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List commands, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status := pb.CommandStatus_COMMAND_STATUS_UNKNOWN
+			if statusName != "" {
+				v, ok := pb.CommandStatus_value["COMMAND_"+strings.ToUpper(statusName)]
+				if !ok {
+					return fmt.Errorf("unknown command status %q", statusName)
+				}
+				status = pb.CommandStatus(v)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListCommands(ctx, &pb.ListCommandsRequest{AgentId: agentID, Status: status, Limit: limit})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, commandTable(resp.GetCommands()...))
+		},
+	}
+	cmd.Flags().StringVar(&agentID, "agent", "", "only list commands for this agent")
+	cmd.Flags().StringVar(&statusName, "status", "", "only list commands with this status: awaiting_approval, pending, sent, succeeded, failed or cancelled")
+	cmd.Flags().Int32Var(&limit, "limit", 100, "maximum number of commands")
+	_ = cmd.RegisterFlagCompletionFunc("agent", completeAgentIDs)
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions([]string{"awaiting_approval", "pending", "sent", "succeeded", "failed", "cancelled"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newCommandsCancelCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel COMMAND_ID",
+		Short: "Cancel a command the agent has not picked up yet, or reject one awaiting approval",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseCommandID(args[0])
+			if err != nil {
+				return err
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.CancelCommand(ctx, &pb.CancelCommandRequest{CommandId: id})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp.GetCommand(), commandTable(resp.GetCommand()))
+		},
+	}
+}
+
+func newCommandsApproveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "approve COMMAND_ID",
+		Short: "Release a command awaiting approval to its agent",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseCommandID(args[0])
+			if err != nil {
+				return err
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ApproveCommand(ctx, &pb.ApproveCommandRequest{CommandId: id})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp.GetCommand(), commandTable(resp.GetCommand()))
+		},
+	}
+}
+
+func newCommandsOutputCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "output COMMAND_ID",
+		Short: "Print the stdout and stderr a script command sent back",
+		Long: "Print the stored stdout of a script command to stdout and its stderr to stderr.\n" +
+			"The exit code and whether the output was cut at scripts.max_output_bytes\n" +
+			"go to stderr as well.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseCommandID(args[0])
+			if err != nil {
+				return err
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.GetCommandOutput(ctx, &pb.GetCommandOutputRequest{CommandId: id})
+			if err != nil {
+				return err
+			}
+			command := resp.GetCommand()
+			exitCode := "-"
+			if command.ExitCode != nil {
+				exitCode = strconv.Itoa(int(command.GetExitCode()))
+			}
+			if c.output != "table" {
+				t := &table{header: []string{"STATUS", "EXIT CODE", "TRUNCATED", "STDOUT", "STDERR"}}
+				t.add(strings.TrimPrefix(command.GetStatus().String(), "COMMAND_"), exitCode,
+					strconv.FormatBool(command.GetOutputTruncated()), string(resp.GetStdout()), string(resp.GetStderr()))
+				return c.render(cmd.OutOrStdout(), resp, t)
+			}
+
+			_, _ = cmd.OutOrStdout().Write(resp.GetStdout())
+			_, _ = cmd.ErrOrStderr().Write(resp.GetStderr())
+			fmt.Fprintf(cmd.ErrOrStderr(), "Status %s, exit code %s, %d bytes stored",
+				strings.TrimPrefix(command.GetStatus().String(), "COMMAND_"), exitCode, command.GetOutputBytes())
+			if command.GetOutputTruncated() {
+				fmt.Fprint(cmd.ErrOrStderr(), " (truncated)")
+			}
+			fmt.Fprintln(cmd.ErrOrStderr())
+			return nil
+		},
+	}
+}
+
+func parseCommandID(arg string) (uint64, error) {
+	id, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid command ID %q", arg)
+	}
+	return id, nil
+}
+
+func commandTable(cmds ...*pb.Command) *table {
+	t := &table{header: []string{"ID", "AGENT ID", "OPERATION", "STATUS", "CREATED", "UPDATED", "RESULT"}}
+	for _, c := range cmds {
+		t.add(strconv.FormatUint(c.GetCommandId(), 10), c.GetAgentId(), describeCommand(c),
+			strings.TrimPrefix(c.GetStatus().String(), "COMMAND_"),
+			formatTime(c.GetCreatedAt()), formatTime(c.GetUpdatedAt()), orDash(c.GetResultMessage()))
+	}
+	return t
+}
+
+// describeCommand gives a one-line summary of the command payload.
+func describeCommand(c *pb.Command) string {
+	if script := c.GetRunScript(); script != nil {
+		return describeScript(script)
+	}
+	if collect := c.GetCollectFile(); collect != nil {
+		return "collect " + collect.GetPath()
+	}
+	if c.GetReportInventory() != nil {
+		return "inventory report"
+	}
+	fw := c.GetFirewallConfiguration()
+	if fw == nil {
+		return "-"
+	}
+	switch op := fw.GetOperationType().(type) {
+	case *pb.FirewallConfigurationRequest_AddRule:
+		r := op.AddRule.GetRule()
+		return fmt.Sprintf("firewall add-rule %s (%s %s/%s)", r.GetName(), r.GetAction(), r.GetPort(), r.GetProtocol())
+	case *pb.FirewallConfigurationRequest_UpdateRule:
+		return "firewall update-rule " + op.UpdateRule.GetTargetRuleName()
+	case *pb.FirewallConfigurationRequest_DeleteRule:
+		return "firewall delete-rule " + op.DeleteRule.GetRuleName()
+	case *pb.FirewallConfigurationRequest_EnableFirewall:
+		return "firewall enable"
+	case *pb.FirewallConfigurationRequest_DisableFirewall:
+		return "firewall disable"
+	}
+	return "firewall"
+}
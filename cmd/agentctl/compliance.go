@@ -0,0 +1,290 @@
+// cmd/agentctl/compliance.go
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var complianceStatusNames = []string{"pass", "fail", "not_applicable"}
+
+func newComplianceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compliance",
+		Short: "Show compliance policies, per-agent results, history and fleet reports",
+		Long: "Show compliance policies, per-agent results, history and fleet reports.\n" +
+			"Policies are YAML files loaded from compliance.policies_path on the server;\n" +
+			"use -o csv to export results or reports.",
+	}
+	cmd.AddCommand(
+		newCompliancePoliciesCommand(),
+		newComplianceEvaluateCommand(),
+		newComplianceResultsCommand(),
+		newComplianceHistoryCommand(),
+		newComplianceReportCommand(),
+	)
+	return cmd
+}
+
+func newCompliancePoliciesCommand() *cobra.Command {
+	var req pb.ListCompliancePoliciesRequest
+	cmd := &cobra.Command{
+		Use:   "policies [POLICY_ID]",
+		Short: "List the loaded policies, or every stored version of one policy",
+		Example: "  agentctl compliance policies\n" +
+			"  agentctl compliance policies workstation-baseline --versions -o yaml --definition",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				req.PolicyId = args[0]
+			}
+			if req.AllVersions && req.PolicyId == "" {
+				return fmt.Errorf("--versions needs a POLICY_ID")
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListCompliancePolicies(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"POLICY", "VERSION", "NAME", "CHECKS", "ACTIVE", "DIGEST", "LOADED"}}
+			for _, p := range resp.GetPolicies() {
+				t.add(p.GetPolicyId(), strconv.Itoa(int(p.GetVersion())), p.GetName(), strconv.Itoa(int(p.GetChecks())),
+					strconv.FormatBool(p.GetActive()), shortDigest(p.GetDigest()), formatTime(p.GetLoadedAt()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().BoolVar(&req.AllVersions, "versions", false, "list every stored version of POLICY_ID")
+	cmd.Flags().BoolVar(&req.IncludeDefinition, "definition", false, "include the YAML definition (json and yaml output)")
+	return cmd
+}
+
+func newComplianceEvaluateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "evaluate [AGENT_ID]",
+		Short:             "Evaluate one agent, or every agent, against the loaded policies now",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &pb.EvaluateComplianceRequest{}
+			if len(args) > 0 {
+				req.AgentId = args[0]
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			if req.AgentId == "" {
+				// Evaluating a large fleet can outlast the request timeout.
+				cancel()
+				ctx, cancel = context.WithCancel(c.streamContext(cmd.Context()))
+			}
+			defer cancel()
+			resp, err := c.EvaluateCompliance(ctx, req)
+			if err != nil {
+				return err
+			}
+			if req.AgentId == "" {
+				t := &table{header: []string{"AGENTS EVALUATED"}}
+				t.add(strconv.Itoa(int(resp.GetAgentsEvaluated())))
+				return c.render(cmd.OutOrStdout(), resp, t)
+			}
+			return c.render(cmd.OutOrStdout(), resp, complianceResultsTable(resp.GetResults()))
+		},
+	}
+}
+
+func newComplianceResultsCommand() *cobra.Command {
+	var (
+		req    pb.ListComplianceResultsRequest
+		status string
+	)
+	cmd := &cobra.Command{
+		Use:   "results [AGENT_ID]",
+		Short: "List the current result and evidence of each check",
+		Example: "  agentctl compliance results web-01\n" +
+			"  agentctl compliance results --policy workstation-baseline --status fail -o csv > failing.csv",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				req.AgentId = args[0]
+			}
+			if status != "" {
+				s, ok := pb.ComplianceStatus_value["COMPLIANCE_"+strings.ToUpper(status)]
+				if !ok || s == 0 {
+					return fmt.Errorf("unknown status %q (want one of %s)", status, strings.Join(complianceStatusNames, ", "))
+				}
+				req.Status = pb.ComplianceStatus(s)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListComplianceResults(ctx, &req)
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, complianceResultsTable(resp.GetResults()))
+		},
+	}
+	cmd.Flags().StringVar(&req.PolicyId, "policy", "", "only results of this policy")
+	cmd.Flags().StringVar(&req.CheckId, "check", "", "only results of this check")
+	cmd.Flags().StringVar(&status, "status", "", "only results with this status: "+strings.Join(complianceStatusNames, ", "))
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of results")
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions(complianceStatusNames, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func complianceResultsTable(results []*pb.ComplianceResult) *table {
+	t := &table{header: []string{"AGENT ID", "POLICY", "CHECK", "SEVERITY", "STATUS", "EVIDENCE", "SINCE"}}
+	for _, r := range results {
+		t.add(r.GetAgentId(), fmt.Sprintf("%s v%d", r.GetPolicyId(), r.GetPolicyVersion()), r.GetCheckId(),
+			describeSeverity(r.GetSeverity()), describeComplianceStatus(r.GetStatus()), orDash(r.GetEvidence()), formatTime(r.GetSince()))
+	}
+	return t
+}
+
+func newComplianceHistoryCommand() *cobra.Command {
+	var (
+		req   pb.GetComplianceHistoryRequest
+		since string
+	)
+	cmd := &cobra.Command{
+		Use:   "history [AGENT_ID]",
+		Short: "Show how policy results changed over time, newest first",
+		Example: "  agentctl compliance history web-01 --since 168h\n" +
+			"  agentctl compliance history --policy workstation-baseline --since 2024-05-01T00:00:00Z",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				req.AgentId = args[0]
+			}
+			if since != "" {
+				t, err := parseSince(since)
+				if err != nil {
+					return err
+				}
+				req.Since = timestamppb.New(t)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.GetComplianceHistory(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"EVALUATED", "AGENT ID", "POLICY", "PASSED", "FAILED", "N/A", "FAILED CHECKS"}}
+			for _, e := range resp.GetEvaluations() {
+				t.add(formatTime(e.GetEvaluatedAt()), e.GetAgentId(), fmt.Sprintf("%s v%d", e.GetPolicyId(), e.GetPolicyVersion()),
+					strconv.Itoa(int(e.GetPassed())), strconv.Itoa(int(e.GetFailed())), strconv.Itoa(int(e.GetNotApplicable())),
+					orDash(strings.Join(e.GetFailedChecks(), ", ")))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&req.PolicyId, "policy", "", "only the history of this policy")
+	cmd.Flags().StringVar(&since, "since", "", "only changes after this time: a duration ago (24h) or an RFC 3339 time")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of entries")
+	return cmd
+}
+
+// parseSince accepts a duration before now or an RFC 3339 time.
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (want a duration such as 24h or an RFC 3339 time)", s)
+	}
+	return t, nil
+}
+
+func newComplianceReportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "report [POLICY_ID]",
+		Short: "Summarize compliant agents and per-check results for each policy",
+		Long: "Summarize compliant agents and per-check results for each policy. In table\n" +
+			"and CSV output the first row of each policy counts agents: PASS are agents\n" +
+			"with no failed check, FAIL agents with at least one.",
+		Example: "  agentctl compliance report\n" +
+			"  agentctl compliance report workstation-baseline -o csv > report.csv",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &pb.GetComplianceReportRequest{}
+			if len(args) > 0 {
+				req.PolicyId = args[0]
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.GetComplianceReport(ctx, req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"POLICY", "CHECK", "TITLE", "SEVERITY", "PASS", "FAIL", "N/A"}}
+			for _, r := range resp.GetPolicies() {
+				p := r.GetPolicy()
+				t.add(fmt.Sprintf("%s v%d", p.GetPolicyId(), p.GetVersion()), "(agents)", p.GetName(), "-",
+					strconv.Itoa(int(r.GetCompliantAgents())), strconv.Itoa(int(r.GetAgents()-r.GetCompliantAgents())), "-")
+				for _, check := range r.GetChecks() {
+					t.add(p.GetPolicyId(), check.GetCheckId(), check.GetTitle(), describeSeverity(check.GetSeverity()),
+						strconv.Itoa(int(check.GetPassed())), strconv.Itoa(int(check.GetFailed())), strconv.Itoa(int(check.GetNotApplicable())))
+				}
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+}
+
+func describeComplianceStatus(s pb.ComplianceStatus) string {
+	return strings.TrimPrefix(s.String(), "COMPLIANCE_")
+}
+
+// shortDigest abbreviates a SHA-256 the way git abbreviates commits.
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return orDash(digest)
+}
@@ -0,0 +1,64 @@
+// cmd/agentctl/config.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// cliConfig is the agentctl config file, by default
+// ~/.config/agentctl/config.yaml:
+//
+//	server: agents.example.com:50051
+//	token: s3cret
+//	output: table
+//	tls:
+//	  enabled: true
+//	  ca_file: /etc/agent_server/ca.pem
+type cliConfig struct {
+	Server string `yaml:"server"`
+	Token  string `yaml:"token"`
+	Output string `yaml:"output"`
+	TLS    struct {
+		Enabled            bool   `yaml:"enabled"`
+		CAFile             string `yaml:"ca_file"`
+		InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	} `yaml:"tls"`
+}
+
+func defaultConfigPath() string {
+	if p := os.Getenv("AGENTCTL_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "agentctl", "config.yaml")
+}
+
+// loadCLIConfig reads the config file. A missing file is not an error, since
+// every setting can also be given as a flag.
+func loadCLIConfig(path string) (*cliConfig, error) {
+	cfg := &cliConfig{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
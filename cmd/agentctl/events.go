@@ -0,0 +1,144 @@
+// cmd/agentctl/events.go
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var eventTypes = []string{
+	"agent.registered", "agent.updated", "agent.status_changed", "agent.offline", "agent.heartbeat",
+	"firewall.changed", "inventory.changed", "command.state_changed", "command.failed", "command.approval_required",
+	"agent.clone_suspected", "alert.firing", "alert.resolved", "update.rollout_paused",
+	"file.collected", "file.downloaded", "job.run_finished",
+}
+
+func newEventsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Watch fleet events",
+	}
+	cmd.AddCommand(newEventsWatchCommand())
+	return cmd
+}
+
+func newEventsWatchCommand() *cobra.Command {
+	var req pb.WatchEventsRequest
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Stream fleet events until interrupted",
+		Long: "Stream fleet events until interrupted. When the server disconnects a client\n" +
+			"that fell behind, watch resumes after the last event it printed.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx := c.streamContext(cmd.Context())
+			for {
+				err := c.watchEvents(ctx, cmd.OutOrStdout(), &req)
+				if status.Code(err) != codes.ResourceExhausted {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "fell behind, resuming after sequence %d\n", req.AfterSequence)
+			}
+		},
+	}
+	cmd.Flags().StringSliceVar(&req.AgentIds, "agent", nil, "only events for these agents (repeatable)")
+	cmd.Flags().StringToStringVar(&req.Labels, "label", nil, "only events for agents with this label, as key=value (repeatable, all must match)")
+	cmd.Flags().StringSliceVar(&req.EventTypes, "type", nil, "only these event types (repeatable)")
+	cmd.Flags().Uint64Var(&req.AfterSequence, "after", 0, "resume after this sequence instead of starting with new events")
+	cmd.Flags().StringVar(&req.StreamEpoch, "epoch", "", "stream epoch of the --after sequence, from the event's stream_epoch")
+	_ = cmd.RegisterFlagCompletionFunc("agent", completeAgentIDs)
+	_ = cmd.RegisterFlagCompletionFunc("type", cobra.FixedCompletions(eventTypes, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// watchEvents prints events until the stream ends, advancing
+// req.AfterSequence and req.StreamEpoch so that the caller can resume.
+func (c *client) watchEvents(ctx context.Context, w io.Writer, req *pb.WatchEventsRequest) error {
+	stream, err := c.WatchEvents(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		ev, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.writeEvent(w, ev); err != nil {
+			return err
+		}
+		req.AfterSequence = ev.GetSequence()
+		req.StreamEpoch = ev.GetStreamEpoch()
+	}
+}
+
+var eventJSONOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+// writeEvent prints one event: a line in table output, a JSON line or a YAML document.
+func (c *client) writeEvent(w io.Writer, ev *pb.FleetEvent) error {
+	switch c.output {
+	case "json":
+		data, err := eventJSONOptions.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "yaml":
+		fmt.Fprintln(w, "---")
+		return writeYAML(w, ev, nil)
+	}
+	_, err := fmt.Fprintf(w, "%-8s %s  %-22s %-20s %s\n", strconv.FormatUint(ev.GetSequence(), 10),
+		formatTime(ev.GetOccurredAt()), ev.GetType(), orDash(ev.GetAgentId()), describeEventData(ev))
+	return err
+}
+
+// describeEventData renders the event data as sorted key=value pairs.
+func describeEventData(ev *pb.FleetEvent) string {
+	fields := ev.GetData().GetFields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v, _ := protojson.Marshal(fields[k])
+		parts = append(parts, k+"="+string(v))
+	}
+	return strings.Join(parts, " ")
+}
+
+// streamContext returns a context for long-lived streams: it carries the
+// admin token but no timeout.
+func (c *client) streamContext(parent context.Context) context.Context {
+	if c.token == "" {
+		return parent
+	}
+	return metadata.AppendToOutgoingContext(parent, "authorization", "Bearer "+c.token)
+}
@@ -0,0 +1,212 @@
+// cmd/agentctl/files.go
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newFilesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "files",
+		Short: "Collect files such as logs and memory dumps from agents",
+	}
+	cmd.AddCommand(newFilesCollectCommand(), newFilesListCommand(), newFilesDownloadCommand())
+	return cmd
+}
+
+func newFilesCollectCommand() *cobra.Command {
+	var req pb.CollectFileRequest
+	cmd := &cobra.Command{
+		Use:   "collect AGENT_ID PATH",
+		Short: "Ask an agent to upload a file",
+		Long: "Ask an agent to upload a file. The agent picks up the command with its next\n" +
+			"poll and uploads the file in chunks, resuming after an interruption. Once\n" +
+			"\"agentctl files list\" shows it as COMPLETE, \"agentctl files download\"\n" +
+			"fetches it.",
+		Example: "  agentctl files collect web-01 /var/log/nginx/error.log --reason \"INC-4711 502 errors\"\n" +
+			"  agentctl files collect win-07 'C:\\Windows\\MEMORY.DMP' --max-bytes 8589934592",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req.AgentId, req.Path = args[0], args[1]
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.CollectFile(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"COLLECTION ID", "COMMAND ID", "MESSAGE"}}
+			t.add(strconv.FormatUint(resp.GetCollectionId(), 10), strconv.FormatUint(resp.GetCommandId(), 10), resp.GetMessage())
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().Int64Var(&req.MaxBytes, "max-bytes", 0, "fail the collection if the file is larger (default collection.max_file_bytes)")
+	cmd.Flags().StringVar(&req.Reason, "reason", "", "why the file is needed, kept with the collection")
+	return cmd
+}
+
+func newFilesListCommand() *cobra.Command {
+	var (
+		req        pb.ListFileCollectionsRequest
+		statusName string
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List files requested from agents, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if statusName != "" {
+				v, ok := pb.FileCollectionStatus_value["COLLECTION_"+strings.ToUpper(statusName)]
+				if !ok {
+					return fmt.Errorf("unknown collection status %q", statusName)
+				}
+				req.Status = pb.FileCollectionStatus(v)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListFileCollections(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"ID", "AGENT ID", "COMMAND", "PATH", "STATUS", "RECEIVED", "SIZE", "REQUESTED", "COMPLETED", "MESSAGE"}}
+			for _, f := range resp.GetCollections() {
+				t.add(strconv.FormatUint(f.GetCollectionId(), 10), f.GetAgentId(), strconv.FormatUint(f.GetCommandId(), 10), f.GetPath(),
+					strings.TrimPrefix(f.GetStatus().String(), "COLLECTION_"), strconv.FormatInt(f.GetReceivedBytes(), 10),
+					strconv.FormatInt(f.GetSizeBytes(), 10), formatTime(f.GetCreatedAt()), formatTime(f.GetCompletedAt()), orDash(f.GetMessage()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&req.AgentId, "agent", "", "only files from this agent")
+	cmd.Flags().StringVar(&statusName, "status", "", "only collections with this status: pending, uploading, complete, failed or expired")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of collections")
+	_ = cmd.RegisterFlagCompletionFunc("agent", completeAgentIDs)
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions([]string{"pending", "uploading", "complete", "failed", "expired"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newFilesDownloadCommand() *cobra.Command {
+	var (
+		file   string
+		resume bool
+	)
+	cmd := &cobra.Command{
+		Use:   "download COLLECTION_ID",
+		Short: "Download a collected file and check its SHA-256",
+		Example: "  agentctl files download 12 -f error.log\n" +
+			"  agentctl files download 13 -f MEMORY.DMP --resume",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid collection ID %q", args[0])
+			}
+			toStdout := file == "" || file == "-"
+			if resume && toStdout {
+				return fmt.Errorf("--resume needs --file")
+			}
+
+			sum := sha256.New()
+			var (
+				out    io.Writer = cmd.OutOrStdout()
+				offset int64
+			)
+			if !toStdout {
+				flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+				if resume {
+					flags = os.O_RDWR | os.O_CREATE
+				}
+				f, err := os.OpenFile(file, flags, 0o644)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				// The part already downloaded counts towards the checksum;
+				// reading it leaves f positioned at its end.
+				if offset, err = io.Copy(sum, f); err != nil {
+					return err
+				}
+				out = f
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			// Large files can outlast the request timeout.
+			stream, err := c.DownloadCollectedFile(c.streamContext(cmd.Context()), &pb.DownloadCollectedFileRequest{CollectionId: id, Offset: offset})
+			if err != nil {
+				return err
+			}
+			size, want, err := receiveCollectedFile(stream, io.MultiWriter(out, sum))
+			if err != nil {
+				return err
+			}
+			if got := hex.EncodeToString(sum.Sum(nil)); got != want {
+				if !toStdout && !resume {
+					os.Remove(file) // do not leave a corrupt file behind
+				}
+				return fmt.Errorf("SHA-256 mismatch: the server has %s, downloaded %s", want, got)
+			}
+			if !toStdout {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Downloaded %d bytes to %s (sha256 %s)\n", size, file, want)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "write the file here instead of standard output")
+	cmd.Flags().BoolVar(&resume, "resume", false, "continue an interrupted download into --file")
+	return cmd
+}
+
+// receiveCollectedFile writes a DownloadCollectedFile stream to out and
+// returns the file's size and SHA-256 from the first chunk.
+func receiveCollectedFile(stream pb.AgentService_DownloadCollectedFileClient, out io.Writer) (int64, string, error) {
+	var (
+		size int64
+		want string
+	)
+	for first := true; ; first = false {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return size, want, nil
+		}
+		if err != nil {
+			return size, want, err
+		}
+		if first {
+			size, want = chunk.GetSizeBytes(), chunk.GetSha256()
+		}
+		if _, err := out.Write(chunk.GetData()); err != nil {
+			return size, want, err
+		}
+	}
+}
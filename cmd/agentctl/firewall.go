@@ -0,0 +1,225 @@
+// cmd/agentctl/firewall.go
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newFirewallCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "firewall",
+		Short: "Show reported firewall rules and queue firewall changes",
+	}
+	cmd.AddCommand(
+		newFirewallShowCommand(),
+		newFirewallFindingsCommand(),
+		newFirewallAddRuleCommand(),
+		newFirewallDeleteRuleCommand(),
+		newFirewallToggleCommand("enable", "Queue a command that enables the agent's firewall"),
+		newFirewallToggleCommand("disable", "Queue a command that disables the agent's firewall"),
+	)
+	return cmd
+}
+
+func newFirewallShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "show AGENT_ID",
+		Short:             "Show the firewall rules from the agent's latest report",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.GetFirewallRules(ctx, &pb.GetFirewallRulesRequest{AgentId: args[0]})
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"NAME", "PORT", "PROTOCOL", "ACTION", "DIRECTION", "ENABLED"}}
+			for _, r := range resp.GetRules() {
+				t.add(r.GetName(), orDash(r.GetPort()), orDash(r.GetProtocol()),
+					r.GetAction().String(), r.GetDirection().String(), strconv.FormatBool(r.GetEnabled()))
+			}
+			if c.output == "table" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Reported at %s\n", formatTime(resp.GetReportedAt()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+}
+
+var firewallFindingKinds = []string{"shadowed_rule", "conflicting_rules", "risky_exposure", "firewall_disabled", "invalid_rule"}
+
+func newFirewallFindingsCommand() *cobra.Command {
+	var (
+		req         pb.ListFirewallFindingsRequest
+		kind        string
+		minSeverity string
+	)
+	cmd := &cobra.Command{
+		Use:   "findings [AGENT_ID]",
+		Short: "List shadowed, conflicting and risky rules, most severe first",
+		Example: "  agentctl firewall findings web-01\n" +
+			"  agentctl firewall findings --kind risky_exposure --min-severity critical",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				req.AgentId = args[0]
+			}
+			if kind != "" {
+				k, ok := pb.FirewallFindingKind_value[strings.ToUpper(kind)]
+				if !ok || k == 0 {
+					return fmt.Errorf("unknown finding kind %q (want one of %s)", kind, strings.Join(firewallFindingKinds, ", "))
+				}
+				req.Kind = pb.FirewallFindingKind(k)
+			}
+			var err error
+			if req.MinSeverity, err = parseSeverity(minSeverity); err != nil {
+				return err
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListFirewallFindings(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"AGENT ID", "SEVERITY", "KIND", "RULE", "PORT", "MESSAGE", "DETECTED"}}
+			for _, f := range resp.GetFindings() {
+				t.add(f.GetAgentId(), describeSeverity(f.GetSeverity()), f.GetKind().String(), orDash(f.GetRuleName()),
+					orDash(f.GetPort()), f.GetMessage(), formatTime(f.GetDetectedAt()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&kind, "kind", "", "only findings of this kind: "+strings.Join(firewallFindingKinds, ", "))
+	cmd.Flags().StringVar(&minSeverity, "min-severity", "", "only findings of at least this severity: low, medium, high or critical")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of findings")
+	_ = cmd.RegisterFlagCompletionFunc("kind", cobra.FixedCompletions(firewallFindingKinds, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("min-severity", cobra.FixedCompletions(severityNames, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newFirewallAddRuleCommand() *cobra.Command {
+	var (
+		rule      pb.FirewallRule
+		action    string
+		direction string
+		disabled  bool
+	)
+	cmd := &cobra.Command{
+		Use:               "add-rule AGENT_ID",
+		Short:             "Queue a command that adds a firewall rule on the agent",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, ok := pb.FirewallAction_value[strings.ToUpper(action)]
+			if !ok || a == 0 {
+				return fmt.Errorf("unknown action %q (want allow or deny)", action)
+			}
+			d, ok := pb.FirewallDirection_value["DIRECTION_"+strings.ToUpper(direction)]
+			if !ok || d == 0 {
+				return fmt.Errorf("unknown direction %q (want in or out)", direction)
+			}
+			rule.Action = pb.FirewallAction(a)
+			rule.Direction = pb.FirewallDirection(d)
+			rule.Enabled = !disabled
+
+			return configureFirewall(cmd, &pb.FirewallConfigurationRequest{
+				AgentId:       args[0],
+				OperationType: &pb.FirewallConfigurationRequest_AddRule{AddRule: &pb.AddFirewallRuleRequest{Rule: &rule}},
+			})
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&rule.Name, "name", "", "rule name")
+	f.StringVar(&rule.Port, "port", "", "port or port range")
+	f.StringVar(&rule.Protocol, "protocol", "tcp", "protocol")
+	f.StringVar(&action, "action", "allow", "allow or deny")
+	f.StringVar(&direction, "direction", "in", "in or out")
+	f.BoolVar(&disabled, "disabled", false, "add the rule in a disabled state")
+	_ = cmd.MarkFlagRequired("name")
+	_ = cmd.RegisterFlagCompletionFunc("action", cobra.FixedCompletions([]string{"allow", "deny"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("direction", cobra.FixedCompletions([]string{"in", "out"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("protocol", cobra.FixedCompletions([]string{"tcp", "udp", "icmp", "any"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newFirewallDeleteRuleCommand() *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:               "delete-rule AGENT_ID",
+		Short:             "Queue a command that deletes a firewall rule on the agent",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return configureFirewall(cmd, &pb.FirewallConfigurationRequest{
+				AgentId:       args[0],
+				OperationType: &pb.FirewallConfigurationRequest_DeleteRule{DeleteRule: &pb.DeleteFirewallRuleRequest{RuleName: name}},
+			})
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "name of the rule to delete")
+	_ = cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func newFirewallToggleCommand(verb, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:               verb + " AGENT_ID",
+		Short:             short,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &pb.FirewallConfigurationRequest{AgentId: args[0]}
+			if verb == "enable" {
+				req.OperationType = &pb.FirewallConfigurationRequest_EnableFirewall{EnableFirewall: &pb.EnableFirewallRequest{}}
+			} else {
+				req.OperationType = &pb.FirewallConfigurationRequest_DisableFirewall{DisableFirewall: &pb.DisableFirewallRequest{}}
+			}
+			return configureFirewall(cmd, req)
+		},
+	}
+}
+
+func configureFirewall(cmd *cobra.Command, req *pb.FirewallConfigurationRequest) error {
+	c, err := dial(cmd)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := c.context(cmd.Context())
+	defer cancel()
+	resp, err := c.ConfigureFirewall(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.GetSuccess() {
+		return fmt.Errorf("server rejected the command: %s", resp.GetMessage())
+	}
+
+	t := &table{header: []string{"COMMAND ID", "AGENT ID", "MESSAGE"}}
+	t.add(strconv.FormatUint(resp.GetCommandId(), 10), req.GetAgentId(), resp.GetMessage())
+	return c.render(cmd.OutOrStdout(), resp, t)
+}
@@ -0,0 +1,138 @@
+// cmd/agentctl/inventory.go
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// inventoryFetch reads one kind of inventory of an agent and returns the
+// response, when it was reported and its rows for table and CSV output.
+type inventoryFetch func(ctx context.Context, c *client, agentID string) (proto.Message, *timestamppb.Timestamp, *table, error)
+
+func newInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "Show network interfaces, local users, services, listening sockets and processes reported by agents",
+	}
+	cmd.AddCommand(
+		newInventoryShowCommand("interfaces", "Show the network interfaces from the agent's latest report", fetchNetworkInterfaces),
+		newInventoryShowCommand("users", "Show the local users from the agent's latest report", fetchLocalUsers),
+		newInventoryShowCommand("groups", "Show the local groups and their members from the agent's latest report", fetchLocalGroups),
+		newInventoryShowCommand("services", "Show the system services from the agent's latest report", fetchServices),
+		newInventoryShowCommand("sockets", "Show the listening sockets from the agent's latest report", fetchListeningSockets),
+		newInventoryShowCommand("processes", "Show the running processes from the agent's latest report", fetchProcesses),
+	)
+	return cmd
+}
+
+func newInventoryShowCommand(name, short string, fetch inventoryFetch) *cobra.Command {
+	return &cobra.Command{
+		Use:               name + " AGENT_ID",
+		Short:             short,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, reportedAt, t, err := fetch(ctx, c, args[0])
+			if err != nil {
+				return err
+			}
+			if c.output == "table" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Reported at %s\n", formatTime(reportedAt))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+}
+
+func fetchNetworkInterfaces(ctx context.Context, c *client, agentID string) (proto.Message, *timestamppb.Timestamp, *table, error) {
+	resp, err := c.GetNetworkInterfaces(ctx, &pb.GetNetworkInterfacesRequest{AgentId: agentID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t := &table{header: []string{"NAME", "MAC", "ADDRESSES", "MTU", "UP"}}
+	for _, i := range resp.GetInterfaces() {
+		t.add(i.GetName(), orDash(i.GetMac()), orDash(strings.Join(i.GetIpAddresses(), ",")), strconv.Itoa(int(i.GetMtu())), strconv.FormatBool(i.GetUp()))
+	}
+	return resp, resp.GetReportedAt(), t, nil
+}
+
+func fetchLocalUsers(ctx context.Context, c *client, agentID string) (proto.Message, *timestamppb.Timestamp, *table, error) {
+	resp, err := c.GetLocalUsers(ctx, &pb.GetLocalUsersRequest{AgentId: agentID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t := &table{header: []string{"NAME", "UID", "FULL NAME", "GROUPS", "DISABLED", "LAST LOGON"}}
+	for _, u := range resp.GetUsers() {
+		t.add(u.GetName(), orDash(u.GetUid()), orDash(u.GetFullName()), orDash(strings.Join(u.GetGroups(), ",")), strconv.FormatBool(u.GetDisabled()), formatTime(u.GetLastLogon()))
+	}
+	return resp, resp.GetReportedAt(), t, nil
+}
+
+func fetchLocalGroups(ctx context.Context, c *client, agentID string) (proto.Message, *timestamppb.Timestamp, *table, error) {
+	resp, err := c.GetLocalUsers(ctx, &pb.GetLocalUsersRequest{AgentId: agentID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t := &table{header: []string{"NAME", "GID", "MEMBERS"}}
+	for _, g := range resp.GetGroups() {
+		t.add(g.GetName(), orDash(g.GetGid()), orDash(strings.Join(g.GetMembers(), ",")))
+	}
+	return resp, resp.GetReportedAt(), t, nil
+}
+
+func fetchServices(ctx context.Context, c *client, agentID string) (proto.Message, *timestamppb.Timestamp, *table, error) {
+	resp, err := c.GetServices(ctx, &pb.GetServicesRequest{AgentId: agentID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t := &table{header: []string{"NAME", "DISPLAY NAME", "START", "STATE", "ACCOUNT", "PATH"}}
+	for _, s := range resp.GetServices() {
+		t.add(s.GetName(), orDash(s.GetDisplayName()),
+			strings.TrimPrefix(s.GetStartType().String(), "SERVICE_START_"), strings.TrimPrefix(s.GetState().String(), "SERVICE_"),
+			orDash(s.GetAccount()), orDash(s.GetBinaryPath()))
+	}
+	return resp, resp.GetReportedAt(), t, nil
+}
+
+func fetchListeningSockets(ctx context.Context, c *client, agentID string) (proto.Message, *timestamppb.Timestamp, *table, error) {
+	resp, err := c.GetListeningSockets(ctx, &pb.GetListeningSocketsRequest{AgentId: agentID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t := &table{header: []string{"PROTOCOL", "ADDRESS", "PORT", "PID", "PROCESS"}}
+	for _, s := range resp.GetSockets() {
+		t.add(s.GetProtocol(), s.GetAddress(), strconv.Itoa(int(s.GetPort())), strconv.Itoa(int(s.GetPid())), orDash(s.GetProcessName()))
+	}
+	return resp, resp.GetReportedAt(), t, nil
+}
+
+func fetchProcesses(ctx context.Context, c *client, agentID string) (proto.Message, *timestamppb.Timestamp, *table, error) {
+	resp, err := c.GetProcesses(ctx, &pb.GetProcessesRequest{AgentId: agentID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t := &table{header: []string{"PID", "PPID", "NAME", "USER", "CPU %", "MEMORY MB", "COMMAND"}}
+	for _, p := range resp.GetProcesses() {
+		t.add(strconv.Itoa(int(p.GetPid())), strconv.Itoa(int(p.GetParentPid())), p.GetName(), orDash(p.GetUser()),
+			strconv.FormatFloat(p.GetCpuPercent(), 'f', 1, 64), strconv.FormatFloat(p.GetMemoryMb(), 'f', 1, 64), orDash(p.GetCommandLine()))
+	}
+	return resp, resp.GetReportedAt(), t, nil
+}
@@ -0,0 +1,92 @@
+// cmd/agentctl/ip_history.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newIPHistoryCommand() *cobra.Command {
+	var (
+		req                          pb.ListAgentIPHistoryRequest
+		onFlag, sinceFlag, untilFlag string
+	)
+	cmd := &cobra.Command{
+		Use:   "ip-history [AGENT_ID]",
+		Short: "List the addresses agents reported and connected from",
+		Long: "List the addresses agents were seen at, most recently seen first. IP is the\n" +
+			"address the agent reported and PEER IP the one the server saw the connection\n" +
+			"come from; a mismatch points to NAT or a spoofed address. --ip matches either.",
+		Example: "  agentctl ip-history --ip 10.2.3.4 --on 2024-05-01\n" +
+			"  agentctl ip-history web-01\n" +
+			"  agentctl ip-history --mismatch",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				req.AgentId = args[0]
+			}
+			if onFlag != "" {
+				if sinceFlag != "" || untilFlag != "" {
+					return errors.New("--on cannot be combined with --since or --until")
+				}
+				day, err := time.Parse(time.DateOnly, onFlag)
+				if err != nil {
+					return fmt.Errorf("invalid --on %q: want a date such as 2024-05-01", onFlag)
+				}
+				req.Since = timestamppb.New(day)
+				req.Until = timestamppb.New(day.AddDate(0, 0, 1))
+			}
+			if sinceFlag != "" {
+				t, err := parseSince(sinceFlag)
+				if err != nil {
+					return err
+				}
+				req.Since = timestamppb.New(t)
+			}
+			if untilFlag != "" {
+				t, err := parseSince(untilFlag)
+				if err != nil {
+					return err
+				}
+				req.Until = timestamppb.New(t)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListAgentIPHistory(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"AGENT ID", "IP", "PEER IP", "MISMATCH", "FIRST SEEN", "LAST SEEN"}}
+			for _, a := range resp.GetAddresses() {
+				mismatch := "-"
+				if a.GetMismatch() {
+					mismatch = "yes"
+				}
+				t.add(a.GetAgentId(), orDash(a.GetIp()), orDash(a.GetPeerIp()), mismatch, formatTime(a.GetFirstSeen()), formatTime(a.GetLastSeen()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&req.Ip, "ip", "", "only this address, reported or seen by the server")
+	cmd.Flags().StringVar(&onFlag, "on", "", "only addresses in use on this UTC date, such as 2024-05-01")
+	cmd.Flags().StringVar(&sinceFlag, "since", "", "only addresses seen since: a duration ago (24h) or an RFC 3339 time")
+	cmd.Flags().StringVar(&untilFlag, "until", "", "only addresses first seen before: a duration ago or an RFC 3339 time")
+	cmd.Flags().BoolVar(&req.MismatchOnly, "mismatch", false, "only addresses where the reported IP differs from the peer IP")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of addresses")
+	return cmd
+}
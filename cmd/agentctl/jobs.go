@@ -0,0 +1,430 @@
+// cmd/agentctl/jobs.go
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newJobsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "jobs",
+		Aliases: []string{"job"},
+		Short:   "Schedule firewall changes, scripts and inventory reports on groups of agents",
+	}
+	cmd.AddCommand(newJobsCreateCommand(), newJobsListCommand(), newJobsStatusCommand("pause", "Stop a job from starting runs and queuing commands", pb.JobStatus_JOB_PAUSED),
+		newJobsStatusCommand("resume", "Resume a paused job from its next scheduled time", pb.JobStatus_JOB_ACTIVE),
+		newJobsDeleteCommand(), newJobsRunCommand(), newJobsRunsCommand(), newJobsRunShowCommand())
+	return cmd
+}
+
+func newJobsCreateCommand() *cobra.Command {
+	var (
+		job           pb.ScheduledJob
+		target        pb.JobTarget
+		at            string
+		catchUp       string
+		firewallFile  string
+		scriptFile    string
+		library       string
+		script        pb.RunScriptRequest
+		scriptTimeout time.Duration
+		inventory     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "create NAME (--cron EXPR | --at TIME) (--firewall FILE | --script-file PATH | --script-library NAME | --inventory)",
+		Short: "Schedule a command on a cron expression or once, against a set of agents",
+		Long: "Schedule a command on a cron expression or once, against a set of agents.\n" +
+			"The agents are picked when each run starts: every agent with --all, or the\n" +
+			"--agent IDs together with the agents carrying all --label pairs. With\n" +
+			"--max-concurrency the run queues commands for that many agents at a time\n" +
+			"and moves on as they finish. A run missed while the server was down is\n" +
+			"skipped, or run once on startup with --catch-up run-once.\n\n" +
+			"--firewall takes a FirewallConfigurationRequest in JSON, such as\n" +
+			"{\"add_rule\": {\"rule\": {\"name\": \"block-telnet\", \"port\": \"23\", \"protocol\": \"tcp\",\n" +
+			"\"action\": \"DENY\", \"direction\": \"DIRECTION_IN\", \"enabled\": true}}}.\n" +
+			"Scheduled scripts must be on the server's allowlist; runs after a script is taken\n" +
+			"off it wait for an operator to approve each command.",
+		Example: "  agentctl jobs create nightly-inventory --cron '0 2 * * *' --timezone Europe/Berlin --inventory --all\n" +
+			"  agentctl jobs create block-telnet --cron '@hourly' --firewall block-telnet.json --label env=prod --max-concurrency 20\n" +
+			"  agentctl jobs create rotate-logs --at 2026-11-01T03:00:00Z --script-library rotate-logs.sh --agent web-01 --agent web-02",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			job.Name = args[0]
+
+			commands := 0
+			if firewallFile != "" {
+				data, err := os.ReadFile(firewallFile)
+				if err != nil {
+					return err
+				}
+				var fw pb.FirewallConfigurationRequest
+				if err := protojson.Unmarshal(data, &fw); err != nil {
+					return fmt.Errorf("%s: %w", firewallFile, err)
+				}
+				job.Command = &pb.ScheduledJob_FirewallConfiguration{FirewallConfiguration: &fw}
+				commands++
+			}
+			if scriptFile != "" || library != "" {
+				switch {
+				case scriptFile != "" && library != "":
+					return fmt.Errorf("--script-file and --script-library are mutually exclusive")
+				case scriptFile != "":
+					body, err := os.ReadFile(scriptFile)
+					if err != nil {
+						return err
+					}
+					script.Source = &pb.RunScriptRequest_ScriptBody{ScriptBody: string(body)}
+				default:
+					script.Source = &pb.RunScriptRequest_LibraryScript{LibraryScript: library}
+				}
+				if scriptTimeout < 0 {
+					return fmt.Errorf("--script-timeout must not be negative")
+				}
+				script.TimeoutSeconds = int32(scriptTimeout / time.Second)
+				job.Command = &pb.ScheduledJob_RunScript{RunScript: &script}
+				commands++
+			}
+			if inventory {
+				job.Command = &pb.ScheduledJob_ReportInventory{ReportInventory: &pb.ReportInventoryRequest{}}
+				commands++
+			}
+			if commands != 1 {
+				return fmt.Errorf("exactly one of --firewall, --script-file, --script-library or --inventory is required")
+			}
+
+			if (job.Cron == "") == (at == "") {
+				return fmt.Errorf("exactly one of --cron or --at is required")
+			}
+			if at != "" {
+				t, err := time.Parse(time.RFC3339, at)
+				if err != nil {
+					return fmt.Errorf("invalid --at %q: want RFC 3339, such as 2026-11-01T03:00:00Z", at)
+				}
+				job.RunAt = timestamppb.New(t)
+			}
+			if catchUp != "" {
+				v, ok := pb.CatchUpPolicy_value["CATCH_UP_"+strings.ToUpper(strings.ReplaceAll(catchUp, "-", "_"))]
+				if !ok {
+					return fmt.Errorf("unknown catch-up policy %q (want skip or run-once)", catchUp)
+				}
+				job.CatchUp = pb.CatchUpPolicy(v)
+			}
+			job.Target = &target
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.CreateScheduledJob(ctx, &pb.CreateScheduledJobRequest{Job: &job})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, jobTable(resp.GetJob()))
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&job.Cron, "cron", "", "five-field cron expression or a macro such as @daily")
+	f.StringVar(&at, "at", "", "run once at this RFC 3339 time instead of on a schedule")
+	f.StringVar(&job.Timezone, "timezone", "", "IANA time zone the cron expression is evaluated in (default UTC)")
+	f.StringVar(&catchUp, "catch-up", "", "what to do with a run missed while the server was down: skip or run-once (default skip)")
+	f.Int32Var(&job.MaxConcurrency, "max-concurrency", 0, "agents with an unfinished command at a time (default no limit)")
+	f.BoolVar(&target.All, "all", false, "target every agent")
+	f.StringArrayVar(&target.AgentIds, "agent", nil, "target this agent (repeatable)")
+	f.StringToStringVar(&target.Labels, "label", nil, "target agents with this label, as key=value (repeatable, all must match)")
+	f.StringVar(&firewallFile, "firewall", "", "apply the firewall change in this JSON file")
+	f.StringVar(&scriptFile, "script-file", "", "run the contents of this local file")
+	f.StringVar(&library, "script-library", "", "run this script from the server-side library")
+	f.StringVar(&script.Interpreter, "interpreter", "", "sh, bash, powershell, cmd or python (default from the library script's extension)")
+	f.StringArrayVar(&script.Args, "arg", nil, "argument passed to the script (repeatable)")
+	f.DurationVar(&scriptTimeout, "script-timeout", 0, "stop the script after this long (default scripts.default_timeout_seconds)")
+	f.StringVar(&script.RunAsUser, "run-as", "", "run the script as this user (default the agent's own account)")
+	f.BoolVar(&inventory, "inventory", false, "have the agents send all their inventory reports now")
+	_ = cmd.RegisterFlagCompletionFunc("agent", completeAgentIDs)
+	_ = cmd.RegisterFlagCompletionFunc("catch-up", cobra.FixedCompletions([]string{"skip", "run-once"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("interpreter", cobra.FixedCompletions([]string{"sh", "bash", "powershell", "cmd", "python"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("script-library", completeLibraryScripts)
+	return cmd
+}
+
+func newJobsListCommand() *cobra.Command {
+	var statusName string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List scheduled jobs with their next and last run",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status := pb.JobStatus_JOB_STATUS_UNKNOWN
+			if statusName != "" {
+				v, ok := pb.JobStatus_value["JOB_"+strings.ToUpper(statusName)]
+				if !ok {
+					return fmt.Errorf("unknown job status %q", statusName)
+				}
+				status = pb.JobStatus(v)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListScheduledJobs(ctx, &pb.ListScheduledJobsRequest{Status: status})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, jobTable(resp.GetJobs()...))
+		},
+	}
+	cmd.Flags().StringVar(&statusName, "status", "", "only jobs with this status: active, paused or finished")
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions([]string{"active", "paused", "finished"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func jobTable(jobs ...*pb.ScheduledJob) *table {
+	t := &table{header: []string{"ID", "NAME", "COMMAND", "SCHEDULE", "TARGET", "STATUS", "NEXT RUN", "LAST RUN"}}
+	for _, j := range jobs {
+		schedule := formatTime(j.GetRunAt())
+		if j.GetCron() != "" {
+			schedule = j.GetCron()
+			if tz := j.GetTimezone(); tz != "" && tz != "UTC" {
+				schedule += " (" + tz + ")"
+			}
+		}
+		t.add(strconv.FormatUint(j.GetJobId(), 10), j.GetName(), describeJobCommand(j), schedule, describeJobTarget(j.GetTarget()),
+			strings.TrimPrefix(j.GetStatus().String(), "JOB_"), formatTime(j.GetNextRunAt()), formatTime(j.GetLastRunAt()))
+	}
+	return t
+}
+
+// describeJobCommand summarizes a job's command like the commands it queues.
+func describeJobCommand(j *pb.ScheduledJob) string {
+	c := &pb.Command{}
+	switch command := j.GetCommand().(type) {
+	case *pb.ScheduledJob_FirewallConfiguration:
+		c.Payload = &pb.Command_FirewallConfiguration{FirewallConfiguration: command.FirewallConfiguration}
+	case *pb.ScheduledJob_RunScript:
+		c.Payload = &pb.Command_RunScript{RunScript: command.RunScript}
+	case *pb.ScheduledJob_ReportInventory:
+		c.Payload = &pb.Command_ReportInventory{ReportInventory: command.ReportInventory}
+	}
+	return describeCommand(c)
+}
+
+func describeJobTarget(target *pb.JobTarget) string {
+	if target.GetAll() {
+		return "all agents"
+	}
+	var parts []string
+	if ids := target.GetAgentIds(); len(ids) > 0 {
+		parts = append(parts, strings.Join(ids, ","))
+	}
+	if labels := target.GetLabels(); len(labels) > 0 {
+		parts = append(parts, formatLabels(labels))
+	}
+	return orDash(strings.Join(parts, " + "))
+}
+
+func newJobsStatusCommand(verb, short string, status pb.JobStatus) *cobra.Command {
+	return &cobra.Command{
+		Use:   verb + " JOB_ID",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseJobID(args[0])
+			if err != nil {
+				return err
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.UpdateScheduledJob(ctx, &pb.UpdateScheduledJobRequest{JobId: id, Status: status})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, jobTable(resp.GetJob()))
+		},
+	}
+}
+
+func newJobsDeleteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete JOB_ID",
+		Short: "Delete a job and its run history; commands already queued stay queued",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseJobID(args[0])
+			if err != nil {
+				return err
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.DeleteScheduledJob(ctx, &pb.DeleteScheduledJobRequest{JobId: id})
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"SUCCESS", "MESSAGE"}}
+			t.add(strconv.FormatBool(resp.GetSuccess()), resp.GetMessage())
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+}
+
+func newJobsRunCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "run JOB_ID",
+		Short: "Start a run of a job now, outside its schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseJobID(args[0])
+			if err != nil {
+				return err
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.RunScheduledJob(ctx, &pb.RunScheduledJobRequest{JobId: id})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, jobRunTable(resp.GetRun()))
+		},
+	}
+}
+
+func newJobsRunsCommand() *cobra.Command {
+	var (
+		req        pb.ListJobRunsRequest
+		statusName string
+	)
+	cmd := &cobra.Command{
+		Use:   "runs [JOB_ID]",
+		Short: "List runs of one or all jobs, including skipped ones, newest first",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				id, err := parseJobID(args[0])
+				if err != nil {
+					return err
+				}
+				req.JobId = id
+			}
+			if statusName != "" {
+				v, ok := pb.JobRunStatus_value["JOB_RUN_"+strings.ToUpper(statusName)]
+				if !ok {
+					return fmt.Errorf("unknown run status %q", statusName)
+				}
+				req.Status = pb.JobRunStatus(v)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListJobRuns(ctx, &req)
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, jobRunTable(resp.GetRuns()...))
+		},
+	}
+	cmd.Flags().StringVar(&statusName, "status", "", "only runs with this status: running, succeeded, failed or skipped")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of runs")
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions([]string{"running", "succeeded", "failed", "skipped"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func jobRunTable(runs ...*pb.JobRun) *table {
+	t := &table{header: []string{"RUN ID", "JOB ID", "TRIGGER", "STATUS", "SCHEDULED FOR", "STARTED", "FINISHED", "TARGETS", "SUCCEEDED", "FAILED", "MESSAGE"}}
+	for _, r := range runs {
+		t.add(strconv.FormatUint(r.GetRunId(), 10), strconv.FormatUint(r.GetJobId(), 10), strings.TrimPrefix(r.GetTrigger().String(), "TRIGGER_"),
+			strings.TrimPrefix(r.GetStatus().String(), "JOB_RUN_"), formatTime(r.GetScheduledFor()), formatTime(r.GetStartedAt()),
+			formatTime(r.GetFinishedAt()), strconv.Itoa(int(r.GetTargets())), strconv.Itoa(int(r.GetSucceeded())),
+			strconv.Itoa(int(r.GetFailed())), orDash(r.GetMessage()))
+	}
+	return t
+}
+
+func newJobsRunShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "run-show RUN_ID",
+		Short: "Show the status and command of each agent in a run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid run ID %q", args[0])
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.GetJobRun(ctx, &pb.GetJobRunRequest{RunId: id})
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"AGENT ID", "STATUS", "COMMAND ID", "DISPATCHED", "FINISHED", "MESSAGE"}}
+			for _, target := range resp.GetTargets() {
+				command := "-"
+				if target.GetCommandId() != 0 {
+					command = strconv.FormatUint(target.GetCommandId(), 10)
+				}
+				t.add(target.GetAgentId(), strings.TrimPrefix(target.GetStatus().String(), "JOB_TARGET_"), command,
+					formatTime(target.GetDispatchedAt()), formatTime(target.GetFinishedAt()), orDash(target.GetMessage()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+}
+
+func parseJobID(s string) (uint64, error) {
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid job ID %q", s)
+	}
+	return id, nil
+}
@@ -0,0 +1,173 @@
+// cmd/agentctl/main.go
+//
+// agentctl is the admin CLI for the agent server: it lists and inspects
+// agents, shows their reported firewall rules and applications, and queues
+// firewall commands.
+//
+//	agentctl agents list --status ONLINE
+//	agentctl firewall add-rule host-1 --name ssh --port 22 --protocol tcp --action allow
+//	agentctl commands list --agent host-1 -o yaml
+//	agentctl completion bash > /etc/bash_completion.d/agentctl
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// globalFlags are the flags shared by every subcommand. They override the
+// values from the config file.
+type globalFlags struct {
+	configPath string
+	server     string
+	token      string
+	caFile     string
+	useTLS     bool
+	insecure   bool
+	timeout    time.Duration
+	output     string
+}
+
+var flags globalFlags
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "agentctl",
+		Short:        "Inspect the agent fleet and issue commands to agents",
+		SilenceUsage: true,
+	}
+
+	pf := root.PersistentFlags()
+	pf.StringVar(&flags.configPath, "config", defaultConfigPath(), "path to the agentctl config file")
+	pf.StringVar(&flags.server, "server", "", "server address (default from config, then localhost:50051)")
+	pf.StringVar(&flags.token, "token", "", "admin token sent as a bearer token")
+	pf.StringVar(&flags.caFile, "ca-file", "", "CA certificate used to verify the server (implies --tls)")
+	pf.BoolVar(&flags.useTLS, "tls", false, "connect with TLS")
+	pf.BoolVar(&flags.insecure, "insecure-skip-verify", false, "do not verify the server certificate")
+	pf.DurationVar(&flags.timeout, "timeout", 10*time.Second, "per-request timeout")
+	pf.StringVarP(&flags.output, "output", "o", "", "output format: table, csv, json or yaml")
+	_ = root.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"table", "csv", "json", "yaml"}, cobra.ShellCompDirectiveNoFileComp))
+
+	root.AddCommand(
+		newAgentsCommand(),
+		newFirewallCommand(),
+		newAppsCommand(),
+		newInventoryCommand(),
+		newPatchesCommand(),
+		newScriptsCommand(),
+		newPackagesCommand(),
+		newRolloutsCommand(),
+		newFilesCommand(),
+		newJobsCommand(),
+		newCommandsCommand(),
+		newEventsCommand(),
+		newVulnsCommand(),
+		newSoftwareCommand(),
+		newComplianceCommand(),
+		newExportCommand(),
+		newAvailabilityCommand(),
+		newIPHistoryCommand(),
+		newClonesCommand(),
+		newMetricsCommand(),
+		newAlertsCommand(),
+	)
+	return root
+}
+
+// client is an open connection together with the settings resolved from
+// flags and the config file.
+type client struct {
+	pb.AgentServiceClient
+	conn    *grpc.ClientConn
+	token   string
+	timeout time.Duration
+	output  string
+}
+
+// dial resolves the effective settings and connects to the server.
+func dial(cmd *cobra.Command) (*client, error) {
+	cfg, err := loadCLIConfig(flags.configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	server := firstNonEmpty(flags.server, cfg.Server, "localhost:50051")
+	token := firstNonEmpty(flags.token, os.Getenv("AGENTCTL_TOKEN"), cfg.Token)
+	output := firstNonEmpty(flags.output, cfg.Output, "table")
+	caFile := firstNonEmpty(flags.caFile, cfg.TLS.CAFile)
+	useTLS := flags.useTLS || cfg.TLS.Enabled || caFile != ""
+	skipVerify := flags.insecure || cfg.TLS.InsecureSkipVerify
+
+	if _, ok := formatters[output]; !ok {
+		return nil, fmt.Errorf("unknown output format %q (want table, csv, json or yaml)", output)
+	}
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: skipVerify}
+		if caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, fmt.Errorf("read CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", caFile)
+			}
+			tlsCfg.RootCAs = pool
+		}
+		creds = credentials.NewTLS(tlsCfg)
+	}
+
+	conn, err := grpc.NewClient(server, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", server, err)
+	}
+	return &client{
+		AgentServiceClient: pb.NewAgentServiceClient(conn),
+		conn:               conn,
+		token:              token,
+		timeout:            flags.timeout,
+		output:             output,
+	}, nil
+}
+
+// context returns a request context carrying the timeout and the admin token.
+func (c *client) context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, c.timeout)
+	if c.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.token)
+	}
+	return ctx, cancel
+}
+
+func (c *client) Close() error {
+	return c.conn.Close()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
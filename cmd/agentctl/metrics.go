@@ -0,0 +1,111 @@
+// cmd/agentctl/metrics.go
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var metricResolutions = map[string]pb.MetricResolution{
+	"auto":   pb.MetricResolution_METRIC_RESOLUTION_AUTO,
+	"raw":    pb.MetricResolution_METRIC_RAW,
+	"5m":     pb.MetricResolution_METRIC_FIVE_MINUTES,
+	"hourly": pb.MetricResolution_METRIC_HOURLY,
+}
+
+var (
+	metricResolutionNames = []string{"auto", "raw", "5m", "hourly"}
+	metricNames           = []string{"cpu_percent", "memory_used_gb", "disk_used_gb", "load_average", "uptime_seconds", "process_count"}
+)
+
+func newMetricsCommand() *cobra.Command {
+	var (
+		req                  pb.GetAgentMetricsRequest
+		sinceFlag, untilFlag string
+		resolution           string
+	)
+	cmd := &cobra.Command{
+		Use:   "metrics AGENT_ID",
+		Short: "Show the resource usage an agent sent with its heartbeats",
+		Long: "Show the resource usage series of an agent, oldest point first. Raw samples\n" +
+			"are rolled up into 5-minute and hourly buckets that keep the average, minimum\n" +
+			"and maximum; the auto resolution picks one from the length of the window.\n" +
+			"The window defaults to the last 24 hours.",
+		Example: "  agentctl metrics web-01 --metric cpu_percent --since 6h\n" +
+			"  agentctl metrics web-01 --metric disk_used_gb --since 720h --resolution hourly",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req.AgentId = args[0]
+			var ok bool
+			if req.Resolution, ok = metricResolutions[resolution]; !ok {
+				return fmt.Errorf("unknown resolution %q (want one of %s)", resolution, strings.Join(metricResolutionNames, ", "))
+			}
+			if sinceFlag != "" {
+				t, err := parseSince(sinceFlag)
+				if err != nil {
+					return err
+				}
+				req.Since = timestamppb.New(t)
+			}
+			if untilFlag != "" {
+				t, err := parseSince(untilFlag)
+				if err != nil {
+					return err
+				}
+				req.Until = timestamppb.New(t)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.GetAgentMetrics(ctx, &req)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "From %s to %s at %s resolution\n", formatTime(resp.GetSince()), formatTime(resp.GetUntil()), resolutionName(resp.GetResolution()))
+			t := &table{header: []string{"METRIC", "MOUNT", "TIME", "AVG", "MIN", "MAX", "SAMPLES"}}
+			for _, s := range resp.GetSeries() {
+				for _, p := range s.GetPoints() {
+					t.add(s.GetMetric(), orDash(s.GetMount()), formatTime(p.GetTime()), formatMetric(p.GetAvg()),
+						formatMetric(p.GetMin()), formatMetric(p.GetMax()), strconv.Itoa(int(p.GetSamples())))
+				}
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringSliceVar(&req.Metrics, "metric", nil, "only this metric (repeatable): "+strings.Join(metricNames, ", "))
+	cmd.Flags().StringVar(&sinceFlag, "since", "", "start of the window: a duration ago (24h) or an RFC 3339 time")
+	cmd.Flags().StringVar(&untilFlag, "until", "", "end of the window: a duration ago or an RFC 3339 time (default now)")
+	cmd.Flags().StringVar(&resolution, "resolution", "auto", "resolution: "+strings.Join(metricResolutionNames, ", "))
+	_ = cmd.RegisterFlagCompletionFunc("metric", cobra.FixedCompletions(metricNames, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("resolution", cobra.FixedCompletions(metricResolutionNames, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func resolutionName(r pb.MetricResolution) string {
+	for name, v := range metricResolutions {
+		if v == r {
+			return name
+		}
+	}
+	return r.String()
+}
+
+// formatMetric renders a metric value with at most two decimals.
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
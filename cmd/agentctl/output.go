@@ -0,0 +1,105 @@
+// cmd/agentctl/output.go
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+)
+
+// table is the tabular view of a response: a header row and one row per item.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+type formatter func(w io.Writer, msg proto.Message, t *table) error
+
+var formatters = map[string]formatter{
+	"table": writeTable,
+	"csv":   writeCSV,
+	"json":  writeJSON,
+	"yaml":  writeYAML,
+}
+
+// render writes msg in the client's output format. Table and CSV output
+// use t; JSON and YAML print the full response message.
+func (c *client) render(w io.Writer, msg proto.Message, t *table) error {
+	return formatters[c.output](w, msg, t)
+}
+
+func writeTable(w io.Writer, _ proto.Message, t *table) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeCSV writes the table view as CSV for spreadsheets and reports.
+func writeCSV(w io.Writer, _ proto.Message, t *table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.header); err != nil {
+		return err
+	}
+	return cw.WriteAll(t.rows)
+}
+
+var jsonOptions = protojson.MarshalOptions{Multiline: true, Indent: "  ", UseProtoNames: true}
+
+func writeJSON(w io.Writer, msg proto.Message, _ *table) error {
+	data, err := jsonOptions.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// writeYAML goes through protojson so that field names, enums and timestamps
+// look the same as in the JSON output.
+func writeYAML(w io.Writer, msg proto.Message, _ *table) error {
+	data, err := jsonOptions.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func formatTime(ts *timestamppb.Timestamp) string {
+	if ts == nil || (ts.GetSeconds() == 0 && ts.GetNanos() == 0) {
+		return "-"
+	}
+	return ts.AsTime().Local().Format(time.DateTime)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+// cmd/agentctl/patches.go
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newPatchesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "patches",
+		Short: "Show OS patch status reported by agents",
+	}
+	cmd.AddCommand(newPatchesShowCommand(), newPatchesListCommand())
+	return cmd
+}
+
+func newPatchesShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "show AGENT_ID",
+		Short:             "Show the installed and pending patches from the agent's latest report",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.GetPatchStatus(ctx, &pb.GetPatchStatusRequest{AgentId: args[0]})
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"STATE", "PATCH", "TITLE", "VERSION", "SECURITY", "INSTALLED"}}
+			for _, p := range resp.GetInstalled() {
+				t.add("installed", p.GetPatchId(), orDash(p.GetTitle()), orDash(p.GetVersion()), "-", formatTime(p.GetInstalledAt()))
+			}
+			for _, p := range resp.GetPending() {
+				t.add("pending", p.GetPatchId(), orDash(p.GetTitle()), orDash(p.GetVersion()), strconv.FormatBool(p.GetSecurity()), "-")
+			}
+			if c.output == "table" {
+				if s := resp.GetStatus(); s != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Reported at %s, last update %s, reboot pending %s\n",
+						formatTime(s.GetReportedAt()), formatTime(s.GetLastUpdateAt()), describeRebootPending(s))
+				} else {
+					fmt.Fprintln(cmd.ErrOrStderr(), "No patch status reported")
+				}
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+}
+
+func newPatchesListCommand() *cobra.Command {
+	var (
+		req           pb.ListPatchStatusRequest
+		rebootPending time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the patch status of agents",
+		Example: "  agentctl patches list --missing KB5034441 --os Windows\n" +
+			"  agentctl patches list --reboot-pending-for 168h --label site=berlin",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rebootPending < 0 {
+				return fmt.Errorf("--reboot-pending-for must not be negative")
+			}
+			req.RebootPendingForSeconds = int64(rebootPending / time.Second)
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListPatchStatus(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"AGENT ID", "HOSTNAME", "OS", "INSTALLED", "PENDING", "SECURITY", "LAST UPDATE", "REBOOT PENDING", "REPORTED"}}
+			for _, s := range resp.GetStatuses() {
+				t.add(s.GetAgentId(), s.GetHostname(), orDash(s.GetOsName()),
+					strconv.Itoa(int(s.GetInstalledCount())), strconv.Itoa(int(s.GetPendingCount())), strconv.Itoa(int(s.GetSecurityPendingCount())),
+					formatTime(s.GetLastUpdateAt()), describeRebootPending(s), formatTime(s.GetReportedAt()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&req.MissingPatch, "missing", "", "only agents whose latest report does not list this patch, such as KB5034441")
+	cmd.Flags().DurationVar(&rebootPending, "reboot-pending-for", 0, "only agents waiting for a reboot for longer than this, such as 168h")
+	cmd.Flags().StringVar(&req.OsName, "os", "", "only agents running this OS")
+	cmd.Flags().StringToStringVar(&req.Labels, "label", nil, "only agents with this label, as key=value (repeatable, all must match)")
+	cmd.Flags().BoolVar(&req.PendingOnly, "pending", false, "only agents with pending updates")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of agents")
+	return cmd
+}
+
+// describeRebootPending is "no", or since when the reboot has been pending.
+func describeRebootPending(s *pb.PatchStatus) string {
+	if !s.GetRebootPending() {
+		return "no"
+	}
+	return "since " + formatTime(s.GetRebootPendingSince())
+}
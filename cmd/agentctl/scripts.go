@@ -0,0 +1,159 @@
+// cmd/agentctl/scripts.go
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newScriptsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "scripts",
+		Aliases: []string{"script"},
+		Short:   "Run scripts on agents and list the server-side script library",
+	}
+	cmd.AddCommand(newScriptsListCommand(), newScriptsRunCommand())
+	return cmd
+}
+
+func newScriptsListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the scripts in the server-side library",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListScripts(ctx, &pb.ListScriptsRequest{})
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"NAME", "INTERPRETER", "SIZE", "ALLOWLISTED", "DIGEST"}}
+			for _, s := range resp.GetScripts() {
+				t.add(s.GetName(), orDash(s.GetInterpreter()), strconv.FormatInt(s.GetSizeBytes(), 10),
+					strconv.FormatBool(s.GetAllowlisted()), s.GetDigest())
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+}
+
+func newScriptsRunCommand() *cobra.Command {
+	var (
+		file    string
+		library string
+		req     pb.RunScriptRequest
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "run AGENT_ID (--file PATH | --library NAME)",
+		Short: "Queue a script for the agent",
+		Long: "Queue a script for the agent. Scripts that are not on the server's allowlist\n" +
+			"wait until an operator runs \"agentctl commands approve\". Once the agent has\n" +
+			"run the script, \"agentctl commands output\" prints what it sent back.",
+		Example: "  agentctl scripts run web-01 --library collect-logs.sh --arg /var/log/nginx\n" +
+			"  agentctl scripts run win-07 --file fix-dns.ps1 --interpreter powershell --timeout 2m",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case file != "" && library != "":
+				return fmt.Errorf("--file and --library are mutually exclusive")
+			case file != "":
+				body, err := os.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				req.Source = &pb.RunScriptRequest_ScriptBody{ScriptBody: string(body)}
+			case library != "":
+				req.Source = &pb.RunScriptRequest_LibraryScript{LibraryScript: library}
+			default:
+				return fmt.Errorf("one of --file or --library is required")
+			}
+			if timeout < 0 {
+				return fmt.Errorf("--timeout must not be negative")
+			}
+			req.AgentId = args[0]
+			req.TimeoutSeconds = int32(timeout / time.Second)
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.RunScript(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"COMMAND ID", "APPROVAL REQUIRED", "MESSAGE"}}
+			t.add(strconv.FormatUint(resp.GetCommandId(), 10), strconv.FormatBool(resp.GetApprovalRequired()), resp.GetMessage())
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", "", "run the contents of this local file")
+	cmd.Flags().StringVar(&library, "library", "", "run this script from the server-side library")
+	cmd.Flags().StringVar(&req.Interpreter, "interpreter", "", "sh, bash, powershell, cmd or python (default from the library script's extension)")
+	cmd.Flags().StringArrayVar(&req.Args, "arg", nil, "argument passed to the script (repeatable)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "stop the script after this long (default scripts.default_timeout_seconds)")
+	cmd.Flags().StringVar(&req.RunAsUser, "run-as", "", "run the script as this user (default the agent's own account)")
+	_ = cmd.RegisterFlagCompletionFunc("interpreter", cobra.FixedCompletions([]string{"sh", "bash", "powershell", "cmd", "python"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("library", completeLibraryScripts)
+	return cmd
+}
+
+// completeLibraryScripts completes --library with the scripts in the
+// server-side library.
+func completeLibraryScripts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c, err := dial(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer c.Close()
+
+	ctx, cancel := c.context(cmd.Context())
+	defer cancel()
+	resp, err := c.ListScripts(ctx, &pb.ListScriptsRequest{})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, s := range resp.GetScripts() {
+		if strings.HasPrefix(s.GetName(), toComplete) {
+			names = append(names, s.GetName())
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// describeScript gives a one-line summary of a RunScript command.
+func describeScript(s *pb.RunScriptRequest) string {
+	name := s.GetLibraryScript()
+	if name == "" {
+		name = s.GetDigest()
+		if len(name) > len("sha256:")+12 {
+			name = name[:len("sha256:")+12]
+		}
+	}
+	desc := fmt.Sprintf("script %s (%s)", name, s.GetInterpreter())
+	if s.GetRunAsUser() != "" {
+		desc += " as " + s.GetRunAsUser()
+	}
+	return desc
+}
@@ -0,0 +1,112 @@
+// cmd/agentctl/software.go
+
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newSoftwareCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "software",
+		Aliases: []string{"sw", "catalog"},
+		Short:   "Browse the fleet software catalog",
+	}
+	cmd.AddCommand(newSoftwareListCommand(), newSoftwareInstallsCommand())
+	return cmd
+}
+
+func newSoftwareListCommand() *cobra.Command {
+	var (
+		req      pb.ListSoftwareCatalogRequest
+		versions bool
+	)
+	cmd := &cobra.Command{
+		Use:   "list [QUERY]",
+		Short: "List products with install counts, most installed first",
+		Long: "List canonical products with install counts, most installed first. Installs\n" +
+			"older than the prevailing (most installed) version are counted as outdated.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				req.Query = args[0]
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListSoftwareCatalog(ctx, &req)
+			if err != nil {
+				return err
+			}
+			if versions {
+				t := &table{header: []string{"PRODUCT", "VERSION", "INSTALLS", "OUTDATED"}}
+				for _, p := range resp.GetProducts() {
+					for _, v := range p.GetVersions() {
+						t.add(p.GetProduct(), orDash(v.GetVersion()), strconv.Itoa(int(v.GetInstalls())), strconv.FormatBool(v.GetOutdated()))
+					}
+				}
+				return c.render(cmd.OutOrStdout(), resp, t)
+			}
+			t := &table{header: []string{"PRODUCT", "NAME", "INSTALLS", "AGENTS", "VERSIONS", "LATEST", "PREVAILING", "OUTDATED"}}
+			for _, p := range resp.GetProducts() {
+				t.add(p.GetProduct(), p.GetName(), strconv.Itoa(int(p.GetInstalls())), strconv.Itoa(int(p.GetAgents())),
+					strconv.Itoa(len(p.GetVersions())), orDash(p.GetLatestVersion()), orDash(p.GetPrevailingVersion()),
+					strconv.Itoa(int(p.GetOutdatedInstalls())))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringToStringVar(&req.Labels, "label", nil, "only agents with this label, as key=value (repeatable, all must match)")
+	cmd.Flags().BoolVar(&req.OutdatedOnly, "outdated", false, "only products with installs behind the prevailing version")
+	cmd.Flags().BoolVar(&versions, "versions", false, "show one row per product version")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of products")
+	return cmd
+}
+
+func newSoftwareInstallsCommand() *cobra.Command {
+	var req pb.ListSoftwareInstallsRequest
+	cmd := &cobra.Command{
+		Use:     "installs PRODUCT",
+		Short:   "List the installs of a product, oldest version first",
+		Example: "  agentctl software installs google:chrome --outdated",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req.Product = args[0]
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListSoftwareInstalls(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"AGENT ID", "HOSTNAME", "VERSION", "OUTDATED", "APPLICATION", "PUBLISHER", "REPORTED"}}
+			for _, in := range resp.GetInstalls() {
+				t.add(in.GetAgentId(), orDash(in.GetHostname()), orDash(in.GetVersion()), strconv.FormatBool(in.GetOutdated()),
+					in.GetAppName(), orDash(strings.TrimSpace(in.GetPublisher())), formatTime(in.GetReportedAt()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&req.Version, "version", "", "only installs of this exact version")
+	cmd.Flags().StringToStringVar(&req.Labels, "label", nil, "only agents with this label, as key=value (repeatable, all must match)")
+	cmd.Flags().BoolVar(&req.OutdatedOnly, "outdated", false, "only installs behind the prevailing version")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of installs")
+	return cmd
+}
@@ -0,0 +1,330 @@
+// cmd/agentctl/updates.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+func newPackagesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "packages",
+		Aliases: []string{"package"},
+		Short:   "Upload and list agent packages",
+	}
+	cmd.AddCommand(newPackagesUploadCommand(), newPackagesListCommand())
+	return cmd
+}
+
+func newPackagesUploadCommand() *cobra.Command {
+	var info pb.AgentPackageInfo
+	cmd := &cobra.Command{
+		Use:   "upload FILE --version VERSION --os OS --arch ARCH",
+		Short: "Upload an agent package for one OS and architecture",
+		Long: "Upload an agent package for one OS and architecture. --signature must be the\n" +
+			"base64 ed25519 signature of the package's hex SHA-256 digest, made with the key\n" +
+			"in the server's updates.signing_public_key. Servers without a key refuse packages\n" +
+			"unless updates.allow_unsigned is set.",
+		Example: "  agentctl packages upload agent-2.4.0-linux-amd64.tar.gz --version 2.4.0 --os linux --arch amd64 --signature \"$(cat agent.sig)\"",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			// Large packages can outlast the request timeout.
+			stream, err := c.UploadAgentPackage(c.streamContext(cmd.Context()))
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(&pb.UploadAgentPackageRequest{Part: &pb.UploadAgentPackageRequest_Info{Info: &info}}); err == nil {
+				buf := make([]byte, feedChunkSize)
+				for {
+					n, err := f.Read(buf)
+					if n > 0 {
+						if err := stream.Send(&pb.UploadAgentPackageRequest{Part: &pb.UploadAgentPackageRequest_Data{Data: buf[:n]}}); err != nil {
+							break // the server's error is returned by CloseAndRecv
+						}
+					}
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					if err != nil {
+						return err
+					}
+				}
+			}
+			resp, err := stream.CloseAndRecv()
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, packageTable(resp.GetPackage()))
+		},
+	}
+	cmd.Flags().StringVar(&info.Version, "version", "", "agent version of the package (required)")
+	cmd.Flags().StringVar(&info.Os, "os", "", "windows, linux or darwin (required)")
+	cmd.Flags().StringVar(&info.Arch, "arch", "", "architecture such as amd64 or arm64 (required)")
+	cmd.Flags().StringVar(&info.Signature, "signature", "", "base64 ed25519 signature of the package's hex SHA-256")
+	_ = cmd.MarkFlagRequired("version")
+	_ = cmd.MarkFlagRequired("os")
+	_ = cmd.MarkFlagRequired("arch")
+	_ = cmd.RegisterFlagCompletionFunc("os", cobra.FixedCompletions([]string{"windows", "linux", "darwin"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newPackagesListCommand() *cobra.Command {
+	var req pb.ListAgentPackagesRequest
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List uploaded agent packages, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListAgentPackages(ctx, &req)
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, packageTable(resp.GetPackages()...))
+		},
+	}
+	cmd.Flags().StringVar(&req.Version, "version", "", "only list packages of this version")
+	return cmd
+}
+
+func packageTable(pkgs ...*pb.AgentPackage) *table {
+	t := &table{header: []string{"ID", "VERSION", "OS", "ARCH", "SIZE", "SIGNED", "SHA256", "UPLOADED"}}
+	for _, p := range pkgs {
+		t.add(strconv.FormatUint(p.GetPackageId(), 10), p.GetVersion(), p.GetOs(), p.GetArch(),
+			strconv.FormatInt(p.GetSizeBytes(), 10), strconv.FormatBool(p.GetSignature() != ""), p.GetSha256(), formatTime(p.GetCreatedAt()))
+	}
+	return t
+}
+
+func newRolloutsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "rollouts",
+		Aliases: []string{"rollout"},
+		Short:   "Roll out agent versions in stages",
+	}
+	cmd.AddCommand(newRolloutsCreateCommand(), newRolloutsUpdateCommand(), newRolloutsListCommand(), newRolloutsUpdatesCommand())
+	return cmd
+}
+
+func newRolloutsCreateCommand() *cobra.Command {
+	var req pb.CreateRolloutRequest
+	cmd := &cobra.Command{
+		Use:   "create VERSION",
+		Short: "Start offering an agent version to a share of the fleet",
+		Long: "Start offering an agent version to a share of the fleet. Agents running an\n" +
+			"older version are offered the update with their next heartbeat if they match\n" +
+			"one of the --group label selectors and fall within --percentage. The rollout\n" +
+			"pauses itself once at least --min-results updates have finished and more\n" +
+			"than --max-failure-rate of them failed.",
+		Example: "  agentctl rollouts create 2.4.0 --percentage 10 --group ring=canary --max-failure-rate 0.2 --min-results 5",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req.Version = args[0]
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.CreateRollout(ctx, &req)
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, rolloutTable(resp.GetRollout()))
+		},
+	}
+	cmd.Flags().Int32Var(&req.Percentage, "percentage", 0, "share of the matching agents to update, 0 to 100")
+	cmd.Flags().StringArrayVar(&req.Groups, "group", nil, "only update agents with this key=value label (repeatable; any one matches)")
+	cmd.Flags().Float64Var(&req.MaxFailureRate, "max-failure-rate", 0, "pause when more than this share of updates failed, 0 to 1 (0 never pauses)")
+	cmd.Flags().Int32Var(&req.MinResults, "min-results", 0, "finished updates needed before the failure rate is checked (default 1)")
+	return cmd
+}
+
+func newRolloutsUpdateCommand() *cobra.Command {
+	var (
+		percentage                  int32
+		groups                      []string
+		clearGroups                 bool
+		pause, resume, cancelUpdate bool
+	)
+	cmd := &cobra.Command{
+		Use:   "update ROLLOUT_ID",
+		Short: "Change the percentage or groups of a rollout, or pause, resume or cancel it",
+		Example: "  agentctl rollouts update 3 --percentage 50\n" +
+			"  agentctl rollouts update 3 --resume",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid rollout ID %q", args[0])
+			}
+			req := &pb.UpdateRolloutRequest{RolloutId: id, Groups: groups, ClearGroups: clearGroups}
+			if cmd.Flags().Changed("percentage") {
+				req.Percentage = &percentage
+			}
+			switch {
+			case pause && !resume && !cancelUpdate:
+				req.Status = pb.RolloutStatus_ROLLOUT_PAUSED
+			case resume && !pause && !cancelUpdate:
+				req.Status = pb.RolloutStatus_ROLLOUT_ACTIVE
+			case cancelUpdate && !pause && !resume:
+				req.Status = pb.RolloutStatus_ROLLOUT_CANCELLED
+			case pause || resume || cancelUpdate:
+				return fmt.Errorf("--pause, --resume and --cancel are mutually exclusive")
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.UpdateRollout(ctx, req)
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, rolloutTable(resp.GetRollout()))
+		},
+	}
+	cmd.Flags().Int32Var(&percentage, "percentage", 0, "share of the matching agents to update, 0 to 100")
+	cmd.Flags().StringArrayVar(&groups, "group", nil, "replace the groups with these key=value label selectors (repeatable)")
+	cmd.Flags().BoolVar(&clearGroups, "clear-groups", false, "remove the groups so every agent matches")
+	cmd.Flags().BoolVar(&pause, "pause", false, "stop offering the update")
+	cmd.Flags().BoolVar(&resume, "resume", false, "offer the update again after a pause")
+	cmd.Flags().BoolVar(&cancelUpdate, "cancel", false, "end the rollout for good")
+	return cmd
+}
+
+func newRolloutsListCommand() *cobra.Command {
+	var (
+		statusName string
+		limit      int32
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List rollouts with the outcome of their updates, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status := pb.RolloutStatus_ROLLOUT_STATUS_UNKNOWN
+			if statusName != "" {
+				v, ok := pb.RolloutStatus_value["ROLLOUT_"+strings.ToUpper(statusName)]
+				if !ok {
+					return fmt.Errorf("unknown rollout status %q", statusName)
+				}
+				status = pb.RolloutStatus(v)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListRollouts(ctx, &pb.ListRolloutsRequest{Status: status, Limit: limit})
+			if err != nil {
+				return err
+			}
+			return c.render(cmd.OutOrStdout(), resp, rolloutTable(resp.GetRollouts()...))
+		},
+	}
+	cmd.Flags().StringVar(&statusName, "status", "", "only list rollouts with this status: active, paused or cancelled")
+	cmd.Flags().Int32Var(&limit, "limit", 100, "maximum number of rollouts")
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions([]string{"active", "paused", "cancelled"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func rolloutTable(rollouts ...*pb.Rollout) *table {
+	t := &table{header: []string{"ID", "VERSION", "STATUS", "PERCENT", "GROUPS", "OFFERED", "SUCCEEDED", "FAILED", "PAUSE REASON", "UPDATED"}}
+	for _, r := range rollouts {
+		t.add(strconv.FormatUint(r.GetRolloutId(), 10), r.GetVersion(), strings.TrimPrefix(r.GetStatus().String(), "ROLLOUT_"),
+			strconv.Itoa(int(r.GetPercentage())), orDash(strings.Join(r.GetGroups(), ",")),
+			strconv.Itoa(int(r.GetOffered())), strconv.Itoa(int(r.GetSucceeded())), strconv.Itoa(int(r.GetFailed())),
+			orDash(r.GetPauseReason()), formatTime(r.GetUpdatedAt()))
+	}
+	return t
+}
+
+func newRolloutsUpdatesCommand() *cobra.Command {
+	var (
+		statusName string
+		limit      int32
+	)
+	cmd := &cobra.Command{
+		Use:   "updates ROLLOUT_ID",
+		Short: "List the agents a rollout offered the update to and the outcome",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid rollout ID %q", args[0])
+			}
+			status := pb.AgentUpdateStatus_AGENT_UPDATE_STATUS_UNKNOWN
+			if statusName != "" {
+				v, ok := pb.AgentUpdateStatus_value["AGENT_UPDATE_"+strings.ToUpper(statusName)]
+				if !ok {
+					return fmt.Errorf("unknown update status %q", statusName)
+				}
+				status = pb.AgentUpdateStatus(v)
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListAgentUpdates(ctx, &pb.ListAgentUpdatesRequest{RolloutId: id, Status: status, Limit: limit})
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"AGENT ID", "HOSTNAME", "FROM", "TO", "STATUS", "OFFERED", "FINISHED", "MESSAGE"}}
+			for _, u := range resp.GetUpdates() {
+				t.add(u.GetAgentId(), orDash(u.GetHostname()), orDash(u.GetFromVersion()), u.GetToVersion(),
+					strings.TrimPrefix(u.GetStatus().String(), "AGENT_UPDATE_"), formatTime(u.GetOfferedAt()),
+					formatTime(u.GetFinishedAt()), orDash(u.GetMessage()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&statusName, "status", "", "only list updates with this status: offered, succeeded or failed")
+	cmd.Flags().Int32Var(&limit, "limit", 100, "maximum number of updates")
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions([]string{"offered", "succeeded", "failed"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
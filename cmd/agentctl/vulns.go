@@ -0,0 +1,206 @@
+// cmd/agentctl/vulns.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	pb "agent_server/agent_server/proto"
+
+	"github.com/spf13/cobra"
+)
+
+// feedChunkSize stays well below the default 4 MiB gRPC message limit.
+const feedChunkSize = 1 << 20
+
+var severityNames = []string{"low", "medium", "high", "critical"}
+
+func newVulnsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "vulns",
+		Aliases: []string{"vuln", "vulnerabilities"},
+		Short:   "Import vulnerability feeds and list vulnerable installs",
+	}
+	cmd.AddCommand(newVulnsImportCommand(), newVulnsListCommand(), newVulnsSummaryCommand())
+	return cmd
+}
+
+func newVulnsImportCommand() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Replace the vulnerability feed and re-evaluate every agent",
+		Long: "Replace the vulnerability feed and re-evaluate every agent. FILE is an OSV\n" +
+			"advisory or list of advisories, an osv.dev zip export, or an NVD CVE API 2.0\n" +
+			"JSON file, optionally gzip-compressed. Use - to read standard input.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			// Re-evaluating a large fleet can outlast the request timeout.
+			stream, err := c.ImportVulnerabilityFeed(c.streamContext(cmd.Context()))
+			if err != nil {
+				return err
+			}
+			buf := make([]byte, feedChunkSize)
+			first := true
+			for {
+				n, err := in.Read(buf)
+				if n > 0 {
+					chunk := &pb.VulnerabilityFeedChunk{Data: buf[:n]}
+					if first {
+						chunk.Format, first = format, false
+					}
+					if err := stream.Send(chunk); err != nil {
+						break // the server's error is returned by CloseAndRecv
+					}
+				}
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					return err
+				}
+			}
+			resp, err := stream.CloseAndRecv()
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"VULNERABILITIES", "PRODUCTS", "AGENTS EVALUATED", "FINDINGS"}}
+			t.add(strconv.Itoa(int(resp.GetVulnerabilities())), strconv.Itoa(int(resp.GetProducts())),
+				strconv.Itoa(int(resp.GetAgentsEvaluated())), strconv.Itoa(int(resp.GetFindings())))
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "", "feed format: osv or nvd (default: detect)")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"osv", "nvd"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newVulnsListCommand() *cobra.Command {
+	var (
+		req         pb.ListVulnerabilityFindingsRequest
+		minSeverity string
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List vulnerable applications, highest CVSS score first",
+		Example: "  agentctl vulns list --agent web-01\n" +
+			"  agentctl vulns list --cve CVE-2023-4863",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if req.MinSeverity, err = parseSeverity(minSeverity); err != nil {
+				return err
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListVulnerabilityFindings(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"AGENT ID", "VULNERABILITY", "SEVERITY", "CVSS", "APPLICATION", "VERSION", "FIXED IN", "DETECTED"}}
+			for _, f := range resp.GetFindings() {
+				t.add(f.GetAgentId(), f.GetVulnerabilityId(), describeSeverity(f.GetSeverity()), formatScore(f.GetCvssScore()),
+					f.GetAppName(), f.GetAppVersion(), orDash(f.GetFixedVersion()), formatTime(f.GetDetectedAt()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&req.AgentId, "agent", "", "only findings on this agent")
+	cmd.Flags().StringVar(&req.VulnerabilityId, "cve", "", "only findings for this vulnerability ID or alias")
+	cmd.Flags().StringVar(&minSeverity, "min-severity", "", "only findings of at least this severity: low, medium, high or critical")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of findings")
+	_ = cmd.RegisterFlagCompletionFunc("agent", completeAgentIDs)
+	_ = cmd.RegisterFlagCompletionFunc("min-severity", cobra.FixedCompletions(severityNames, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newVulnsSummaryCommand() *cobra.Command {
+	var (
+		req         pb.ListFleetVulnerabilitiesRequest
+		minSeverity string
+	)
+	cmd := &cobra.Command{
+		Use:   "summary",
+		Short: "List the vulnerabilities found in the fleet with the number of affected agents",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if req.MinSeverity, err = parseSeverity(minSeverity); err != nil {
+				return err
+			}
+
+			c, err := dial(cmd)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			ctx, cancel := c.context(cmd.Context())
+			defer cancel()
+			resp, err := c.ListFleetVulnerabilities(ctx, &req)
+			if err != nil {
+				return err
+			}
+			t := &table{header: []string{"VULNERABILITY", "SEVERITY", "CVSS", "AGENTS", "ALIASES", "SUMMARY"}}
+			for _, v := range resp.GetVulnerabilities() {
+				t.add(v.GetVulnerabilityId(), describeSeverity(v.GetSeverity()), formatScore(v.GetCvssScore()),
+					strconv.FormatInt(v.GetAffectedAgents(), 10), orDash(strings.Join(v.GetAliases(), ",")), orDash(v.GetSummary()))
+			}
+			return c.render(cmd.OutOrStdout(), resp, t)
+		},
+	}
+	cmd.Flags().StringVar(&minSeverity, "min-severity", "", "only vulnerabilities of at least this severity: low, medium, high or critical")
+	cmd.Flags().Int32Var(&req.Limit, "limit", 100, "maximum number of vulnerabilities")
+	_ = cmd.RegisterFlagCompletionFunc("min-severity", cobra.FixedCompletions(severityNames, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func parseSeverity(name string) (pb.VulnerabilitySeverity, error) {
+	if name == "" {
+		return pb.VulnerabilitySeverity_SEVERITY_UNKNOWN, nil
+	}
+	v, ok := pb.VulnerabilitySeverity_value["SEVERITY_"+strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown severity %q", name)
+	}
+	return pb.VulnerabilitySeverity(v), nil
+}
+
+func describeSeverity(s pb.VulnerabilitySeverity) string {
+	return strings.TrimPrefix(s.String(), "SEVERITY_")
+}
+
+func formatScore(score float64) string {
+	if score == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(score, 'f', 1, 64)
+}
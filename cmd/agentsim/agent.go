@@ -0,0 +1,318 @@
+// cmd/agentsim/agent.go
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// simOptions are the knobs shared by every simulated agent.
+type simOptions struct {
+	heartbeatInterval time.Duration // 0 = use the interval returned by RegisterAgent
+	reportInterval    time.Duration
+	jitter            float64 // fraction of each interval added or removed at random
+	churn             float64 // probability per heartbeat that the agent goes away
+	awayMin, awayMax  time.Duration
+	mutationRate      float64 // fraction of inventory entries changed per report
+	rpcTimeout        time.Duration
+}
+
+type osProfile struct {
+	name, version, kernel string
+}
+
+var osProfiles = []osProfile{
+	{"Windows", "11 Pro", "10.0.22621"},
+	{"Windows", "10 Enterprise", "10.0.19045"},
+	{"Windows", "Server 2022", "10.0.20348"},
+	{"Ubuntu", "22.04", "5.15.0-105-generic"},
+	{"Ubuntu", "24.04", "6.8.0-31-generic"},
+	{"Debian", "12", "6.1.0-21-amd64"},
+	{"Rocky Linux", "9.3", "5.14.0-362.el9.x86_64"},
+	{"macOS", "14.4", "23.4.0"},
+}
+
+type appTemplate struct {
+	name, publisher string
+	major, minor    int
+}
+
+var appCatalog = []appTemplate{
+	{"Google Chrome", "Google LLC", 125, 6422},
+	{"Visual Studio Code", "Microsoft Corporation", 1, 90},
+	{"Mozilla Firefox", "Mozilla", 126, 0},
+	{"7-Zip", "Igor Pavlov", 23, 1},
+	{"Notepad++", "Notepad++ Team", 8, 6},
+	{"Microsoft Office", "Microsoft Corporation", 16, 0},
+	{"Zoom", "Zoom Video Communications, Inc.", 6, 0},
+	{"Slack", "Slack Technologies", 4, 38},
+	{"Python", "Python Software Foundation", 3, 12},
+	{"Git", "The Git Development Community", 2, 45},
+	{"openssl", "OpenSSL Project", 3, 0},
+	{"nginx", "F5, Inc.", 1, 24},
+	{"PuTTY", "Simon Tatham", 0, 80},
+	{"Telnet Client", "Microsoft Corporation", 10, 0},
+	{"VLC media player", "VideoLAN", 3, 0},
+}
+
+// simAgent is one fake agent with its own identity and inventory.
+type simAgent struct {
+	id     string
+	client pb.AgentServiceClient
+	opts   simOptions
+	rec    *Recorder
+	rng    *rand.Rand
+
+	details         *pb.Agent
+	nonce           string // from the latest registration, sent back with every heartbeat
+	apps            []*pb.ApplicationInfo
+	rules           []*pb.FirewallRule
+	firewallEnabled bool
+	inventory       hostInventory
+	patches         patchState
+
+	// Resource usage sent with heartbeats; CPU and memory drift between beats.
+	bootedAt             time.Time
+	cpuPercent, memoryGB float64
+	diskUsedGB           float64
+}
+
+func newSimAgent(index int, client pb.AgentServiceClient, opts simOptions, rec *Recorder, seed int64) *simAgent {
+	rng := rand.New(rand.NewSource(seed))
+	profile := osProfiles[rng.Intn(len(osProfiles))]
+	a := &simAgent{
+		id:     fmt.Sprintf("sim-agent-%06d", index),
+		client: client,
+		opts:   opts,
+		rec:    rec,
+		rng:    rng,
+	}
+	a.details = &pb.Agent{
+		AgentId:       a.id,
+		Hostname:      fmt.Sprintf("sim-host-%06d", index),
+		OsName:        profile.name,
+		OsVersion:     profile.version,
+		KernelVersion: profile.kernel,
+		CpuCores:      int32(2 << rng.Intn(5)),
+		MemoryGb:      float64(int(4) << rng.Intn(5)),
+		DiskSpaceGb:   float64(int(128) << rng.Intn(4)),
+		LastKnownIp:   a.randomIP(),
+		AgentVersion:  "1.0.0",
+		Arch:          "amd64",
+	}
+	for _, i := range rng.Perm(len(appCatalog))[:5+rng.Intn(len(appCatalog)-5)] {
+		a.apps = append(a.apps, a.newApp(appCatalog[i]))
+	}
+	a.rules = []*pb.FirewallRule{
+		{Name: "Allow HTTP", Port: "80", Protocol: "TCP", Action: pb.FirewallAction_ALLOW, Direction: pb.FirewallDirection_DIRECTION_IN, Enabled: true},
+		{Name: "Allow HTTPS", Port: "443", Protocol: "TCP", Action: pb.FirewallAction_ALLOW, Direction: pb.FirewallDirection_DIRECTION_IN, Enabled: true},
+		{Name: "Deny all outgoing", Port: "ANY", Protocol: "ANY", Action: pb.FirewallAction_DENY, Direction: pb.FirewallDirection_DIRECTION_OUT, Enabled: rng.Intn(2) == 0},
+	}
+	if rng.Float64() < 0.2 {
+		a.rules = append(a.rules, &pb.FirewallRule{Name: "Allow RDP", Port: "3389", Protocol: "TCP", Action: pb.FirewallAction_ALLOW, Direction: pb.FirewallDirection_DIRECTION_IN, Enabled: true})
+	}
+	a.firewallEnabled = rng.Float64() >= 0.05
+	a.bootedAt = time.Now().Add(-time.Duration(rng.Int63n(int64(30 * 24 * time.Hour))))
+	a.cpuPercent = 5 + 30*rng.Float64()
+	a.memoryGB = a.details.MemoryGb * (0.2 + 0.5*rng.Float64())
+	a.diskUsedGB = a.details.DiskSpaceGb * (0.1 + 0.6*rng.Float64())
+	a.newHostInventory()
+	a.newPatchState()
+	return a
+}
+
+func (a *simAgent) newApp(t appTemplate) *pb.ApplicationInfo {
+	return &pb.ApplicationInfo{
+		Name:        t.name,
+		Publisher:   t.publisher,
+		Version:     fmt.Sprintf("%d.%d.%d", t.major, t.minor, a.rng.Intn(200)),
+		InstallDate: timestamppb.New(time.Now().AddDate(0, 0, -a.rng.Intn(720))),
+	}
+}
+
+func (a *simAgent) randomIP() string {
+	return fmt.Sprintf("10.%d.%d.%d", a.rng.Intn(256), a.rng.Intn(256), 1+a.rng.Intn(254))
+}
+
+// run registers the agent and then beats and reports until ctx is done.
+func (a *simAgent) run(ctx context.Context) {
+	// Spread the initial registrations over the first heartbeat interval.
+	if !a.sleep(ctx, time.Duration(a.rng.Int63n(int64(a.opts.heartbeatIntervalOr(time.Second))+1))) {
+		return
+	}
+
+	for ctx.Err() == nil {
+		interval, ok := a.register(ctx)
+		if !ok {
+			if !a.sleep(ctx, a.jittered(5*time.Second)) {
+				return
+			}
+			continue
+		}
+		a.reportInventory(ctx)
+		if !a.session(ctx, interval) {
+			return
+		}
+	}
+}
+
+// session beats and reports until the agent churns away; it returns false when ctx ends.
+func (a *simAgent) session(ctx context.Context, heartbeatInterval time.Duration) bool {
+	nextReport := time.Now().Add(a.jittered(a.opts.reportInterval))
+	for {
+		if !a.sleep(ctx, a.jittered(heartbeatInterval)) {
+			return false
+		}
+		if a.rng.Float64() < a.opts.churn {
+			away := a.opts.awayMin + time.Duration(a.rng.Int63n(int64(a.opts.awayMax-a.opts.awayMin)+1))
+			// Returning agents sometimes come back with a new address.
+			if a.rng.Float64() < 0.3 {
+				a.details.LastKnownIp = a.randomIP()
+			}
+			return a.sleep(ctx, away)
+		}
+		if a.heartbeat(ctx) {
+			// Another instance registered under our ID: register again.
+			return true
+		}
+		if time.Now().After(nextReport) {
+			a.mutateInventory()
+			a.reportInventory(ctx)
+			nextReport = time.Now().Add(a.jittered(a.opts.reportInterval))
+		}
+	}
+}
+
+func (a *simAgent) register(ctx context.Context) (time.Duration, bool) {
+	cctx, cancel := context.WithTimeout(ctx, a.opts.rpcTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := a.client.RegisterAgent(cctx, &pb.RegisterRequest{AgentDetails: a.details, InstanceNonce: a.nonce})
+	a.observe("RegisterAgent", start, err)
+	if err != nil {
+		return 0, false
+	}
+	a.nonce = resp.GetInstanceNonce()
+	if id := resp.GetAgentId(); id != "" && id != a.id {
+		a.id = id
+		a.details.AgentId = id
+	}
+	interval := time.Duration(resp.GetReportIntervalSeconds()) * time.Second
+	return a.opts.heartbeatIntervalOr(interval), true
+}
+
+// heartbeat beats once and reports whether the server asked the agent to
+// register again.
+func (a *simAgent) heartbeat(ctx context.Context) bool {
+	cctx, cancel := context.WithTimeout(ctx, a.opts.rpcTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := a.client.SendHeartbeat(cctx, &pb.HeartbeatRequest{AgentId: a.id, CurrentIp: a.details.LastKnownIp, InstanceNonce: a.nonce, Metrics: a.sampleMetrics()})
+	a.observe("SendHeartbeat", start, err)
+	return resp.GetReregister()
+}
+
+// sampleMetrics moves the simulated resource usage a little and reports it.
+func (a *simAgent) sampleMetrics() *pb.ResourceMetrics {
+	a.cpuPercent = math.Max(0, math.Min(100, a.cpuPercent+10*(a.rng.Float64()-0.5)))
+	a.memoryGB = math.Max(0.5, math.Min(a.details.MemoryGb, a.memoryGB+0.2*(a.rng.Float64()-0.5)))
+	a.diskUsedGB = math.Min(a.details.DiskSpaceGb, a.diskUsedGB+0.01*a.rng.Float64())
+	mount := "/"
+	if a.details.OsName == "Windows" {
+		mount = "C:"
+	}
+	return &pb.ResourceMetrics{
+		CpuPercent:    proto.Float64(a.cpuPercent),
+		MemoryUsedGb:  proto.Float64(a.memoryGB),
+		Disks:         []*pb.DiskUsage{{Mount: mount, UsedGb: a.diskUsedGB, TotalGb: a.details.DiskSpaceGb}},
+		LoadAverage:   proto.Float64(a.cpuPercent / 100 * float64(a.details.CpuCores)),
+		UptimeSeconds: proto.Int64(int64(time.Since(a.bootedAt).Seconds())),
+		ProcessCount:  proto.Int32(int32(80 + a.rng.Intn(200))),
+	}
+}
+
+func (a *simAgent) reportInventory(ctx context.Context) {
+	cctx, cancel := context.WithTimeout(ctx, a.opts.rpcTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := a.client.ReportFirewallStatus(cctx, &pb.FirewallStatusRequest{AgentId: a.id, Rules: a.rules, FirewallEnabled: &a.firewallEnabled})
+	a.observe("ReportFirewallStatus", start, err)
+
+	start = time.Now()
+	_, err = a.client.ReportInstalledApps(cctx, &pb.InstalledAppsRequest{AgentId: a.id, Apps: a.apps})
+	a.observe("ReportInstalledApps", start, err)
+
+	a.reportHostInventory(cctx)
+	a.reportPatchStatus(cctx)
+}
+
+// mutateInventory upgrades, removes or installs apps and toggles firewall rules
+// and, more rarely, the firewall itself.
+func (a *simAgent) mutateInventory() {
+	for i, app := range a.apps {
+		if a.rng.Float64() < a.opts.mutationRate {
+			a.apps[i] = a.newApp(appTemplate{name: app.Name, publisher: app.Publisher, major: a.rng.Intn(130), minor: a.rng.Intn(100)})
+		}
+	}
+	if a.rng.Float64() < a.opts.mutationRate && len(a.apps) > 1 {
+		i := a.rng.Intn(len(a.apps))
+		a.apps = append(a.apps[:i], a.apps[i+1:]...)
+	}
+	if a.rng.Float64() < a.opts.mutationRate {
+		a.apps = append(a.apps, a.newApp(appCatalog[a.rng.Intn(len(appCatalog))]))
+	}
+	for _, rule := range a.rules {
+		if a.rng.Float64() < a.opts.mutationRate {
+			rule.Enabled = !rule.Enabled
+		}
+	}
+	if a.rng.Float64() < a.opts.mutationRate/10 {
+		a.firewallEnabled = !a.firewallEnabled
+	}
+	a.mutateHostInventory()
+	a.mutatePatches()
+}
+
+func (a *simAgent) observe(rpc string, start time.Time, err error) {
+	if a.rec != nil {
+		a.rec.Observe(rpc, time.Since(start), err)
+	}
+}
+
+func (a *simAgent) jittered(d time.Duration) time.Duration {
+	if a.opts.jitter <= 0 || d <= 0 {
+		return d
+	}
+	delta := (a.rng.Float64()*2 - 1) * a.opts.jitter * float64(d)
+	return d + time.Duration(delta)
+}
+
+func (a *simAgent) sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (o simOptions) heartbeatIntervalOr(fallback time.Duration) time.Duration {
+	if o.heartbeatInterval > 0 {
+		return o.heartbeatInterval
+	}
+	return fallback
+}
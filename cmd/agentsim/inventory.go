@@ -0,0 +1,164 @@
+// cmd/agentsim/inventory.go
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// serviceTemplate is a system service with the port it listens on, if any.
+type serviceTemplate struct {
+	name, display, account, path string
+	port                         int32
+}
+
+var serviceCatalog = []serviceTemplate{
+	{"sshd", "OpenSSH server", "root", "/usr/sbin/sshd", 22},
+	{"nginx", "nginx web server", "www-data", "/usr/sbin/nginx", 443},
+	{"postgresql", "PostgreSQL database", "postgres", "/usr/lib/postgresql/16/bin/postgres", 5432},
+	{"cron", "Regular background program processing", "root", "/usr/sbin/cron", 0},
+	{"rsyslog", "System logging", "syslog", "/usr/sbin/rsyslogd", 0},
+	{"TermService", "Remote Desktop Services", "NetworkService", `C:\Windows\System32\svchost.exe`, 3389},
+	{"Spooler", "Print Spooler", "LocalSystem", `C:\Windows\System32\spoolsv.exe`, 0},
+}
+
+var userNames = []string{"alice", "bob", "carol", "dave", "erin", "frank"}
+
+// hostInventory is the extended inventory of a simulated agent, reported
+// together with firewall rules and installed apps.
+type hostInventory struct {
+	interfaces []*pb.NetworkInterfaceInfo
+	users      []*pb.LocalUserInfo
+	groups     []*pb.LocalGroupInfo
+	services   []*pb.ServiceInfo
+	sockets    []*pb.ListeningSocketInfo
+	processes  []*pb.ProcessInfo
+}
+
+func (a *simAgent) newHostInventory() {
+	inv := &a.inventory
+	inv.interfaces = []*pb.NetworkInterfaceInfo{
+		{Name: "lo", IpAddresses: []string{"127.0.0.1/8"}, Mtu: 65536, Up: true},
+		{Name: "eth0", Mac: a.randomMAC(), IpAddresses: []string{a.details.LastKnownIp + "/16"}, Mtu: 1500, Up: true},
+	}
+	if a.rng.Intn(3) == 0 {
+		inv.interfaces = append(inv.interfaces, &pb.NetworkInterfaceInfo{Name: "docker0", Mac: a.randomMAC(), IpAddresses: []string{"172.17.0.1/16"}, Mtu: 1500})
+	}
+
+	admins := &pb.LocalGroupInfo{Name: "sudo", Gid: "27"}
+	inv.groups = []*pb.LocalGroupInfo{admins, {Name: "users", Gid: "100"}}
+	inv.users = []*pb.LocalUserInfo{{Name: "root", Uid: "0", FullName: "root", Groups: []string{"root"}}}
+	for i, name := range userNames[:1+a.rng.Intn(len(userNames))] {
+		user := &pb.LocalUserInfo{Name: name, Uid: fmt.Sprint(1000 + i), Groups: []string{"users"}, Disabled: a.rng.Intn(10) == 0}
+		if a.rng.Intn(3) == 0 {
+			user.Groups = append(user.Groups, admins.Name)
+			admins.Members = append(admins.Members, name)
+		}
+		if !user.Disabled {
+			user.LastLogon = timestamppb.New(time.Now().Add(-time.Duration(a.rng.Int63n(int64(14 * 24 * time.Hour)))))
+		}
+		inv.users = append(inv.users, user)
+		inv.groups[1].Members = append(inv.groups[1].Members, name)
+	}
+
+	pid := int32(300)
+	inv.processes = []*pb.ProcessInfo{{Pid: 1, Name: "systemd", User: "root", CommandLine: "/sbin/init", StartedAt: timestamppb.New(a.bootedAt)}}
+	for _, i := range a.rng.Perm(len(serviceCatalog))[:2+a.rng.Intn(len(serviceCatalog)-2)] {
+		t := serviceCatalog[i]
+		svc := &pb.ServiceInfo{Name: t.name, DisplayName: t.display, StartType: pb.ServiceStartType_SERVICE_START_AUTOMATIC, State: pb.ServiceState_SERVICE_RUNNING, Account: t.account, BinaryPath: t.path}
+		if a.rng.Intn(5) == 0 {
+			svc.StartType, svc.State = pb.ServiceStartType_SERVICE_START_MANUAL, pb.ServiceState_SERVICE_STOPPED
+		}
+		inv.services = append(inv.services, svc)
+		if svc.State != pb.ServiceState_SERVICE_RUNNING {
+			continue
+		}
+		pid += int32(1 + a.rng.Intn(500))
+		inv.processes = append(inv.processes, &pb.ProcessInfo{
+			Pid: pid, ParentPid: 1, Name: t.name, User: t.account, CommandLine: t.path,
+			StartedAt:  timestamppb.New(a.bootedAt.Add(time.Duration(a.rng.Intn(120)) * time.Second)),
+			CpuPercent: 5 * a.rng.Float64(), MemoryMb: float64(10 + a.rng.Intn(500)),
+		})
+		if t.port > 0 {
+			inv.sockets = append(inv.sockets, &pb.ListeningSocketInfo{Protocol: "TCP", Address: "0.0.0.0", Port: t.port, Pid: pid, ProcessName: t.name})
+		}
+	}
+}
+
+func (a *simAgent) randomMAC() string {
+	return fmt.Sprintf("02:42:%02x:%02x:%02x:%02x", a.rng.Intn(256), a.rng.Intn(256), a.rng.Intn(256), a.rng.Intn(256))
+}
+
+// mutateHostInventory starts and stops services, with their processes and
+// sockets following, and lets process resource usage drift.
+func (a *simAgent) mutateHostInventory() {
+	inv := &a.inventory
+	for _, svc := range inv.services {
+		if a.rng.Float64() >= a.opts.mutationRate {
+			continue
+		}
+		if svc.State == pb.ServiceState_SERVICE_RUNNING {
+			svc.State = pb.ServiceState_SERVICE_STOPPED
+			inv.processes = removeProcess(inv.processes, svc.Name)
+			inv.sockets = removeSocket(inv.sockets, svc.Name)
+		} else {
+			svc.State = pb.ServiceState_SERVICE_RUNNING
+			inv.processes = append(inv.processes, &pb.ProcessInfo{
+				Pid: int32(1000 + a.rng.Intn(60000)), ParentPid: 1, Name: svc.Name, User: svc.Account, CommandLine: svc.BinaryPath,
+				StartedAt: timestamppb.Now(), MemoryMb: float64(10 + a.rng.Intn(500)),
+			})
+		}
+	}
+	for _, p := range inv.processes {
+		p.CpuPercent = 5 * a.rng.Float64()
+	}
+}
+
+func removeProcess(procs []*pb.ProcessInfo, name string) []*pb.ProcessInfo {
+	kept := procs[:0]
+	for _, p := range procs {
+		if p.Name != name {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func removeSocket(sockets []*pb.ListeningSocketInfo, name string) []*pb.ListeningSocketInfo {
+	kept := sockets[:0]
+	for _, s := range sockets {
+		if s.ProcessName != name {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+func (a *simAgent) reportHostInventory(ctx context.Context) {
+	inv := &a.inventory
+	start := time.Now()
+	_, err := a.client.ReportNetworkInterfaces(ctx, &pb.NetworkInterfacesRequest{AgentId: a.id, Interfaces: inv.interfaces})
+	a.observe("ReportNetworkInterfaces", start, err)
+
+	start = time.Now()
+	_, err = a.client.ReportLocalUsers(ctx, &pb.LocalUsersRequest{AgentId: a.id, Users: inv.users, Groups: inv.groups})
+	a.observe("ReportLocalUsers", start, err)
+
+	start = time.Now()
+	_, err = a.client.ReportServices(ctx, &pb.ServicesRequest{AgentId: a.id, Services: inv.services})
+	a.observe("ReportServices", start, err)
+
+	start = time.Now()
+	_, err = a.client.ReportListeningSockets(ctx, &pb.ListeningSocketsRequest{AgentId: a.id, Sockets: inv.sockets})
+	a.observe("ReportListeningSockets", start, err)
+
+	start = time.Now()
+	_, err = a.client.ReportProcesses(ctx, &pb.ProcessesRequest{AgentId: a.id, Processes: inv.processes})
+	a.observe("ReportProcesses", start, err)
+}
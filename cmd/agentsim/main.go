@@ -0,0 +1,201 @@
+// cmd/agentsim/main.go
+//
+// agentsim simulates a fleet of agents against an AgentService to measure how
+// many agents one server can handle.
+//
+//	go run ./cmd/agentsim -agents 5000 -heartbeat 10s -duration 2m
+//	go run ./cmd/agentsim -inprocess sqlite -agents 1000
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/artifacts"
+	"agent_server/internal/config"
+	"agent_server/internal/repository"
+	"agent_server/internal/service"
+	"agent_server/internal/usecase"
+	"agent_server/internal/webhook"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	var (
+		addr          = flag.String("addr", "localhost:50051", "server address (ignored with -inprocess)")
+		inProcess     = flag.String("inprocess", "", "start a server inside this process with the given backend: memory or sqlite")
+		sqlitePath    = flag.String("sqlite-path", "", "SQLite file for -inprocess sqlite (default: temporary file)")
+		agents        = flag.Int("agents", 100, "number of simulated agents")
+		heartbeat     = flag.Duration("heartbeat", 10*time.Second, "heartbeat interval (0 = use the interval returned by RegisterAgent)")
+		report        = flag.Duration("report", time.Minute, "firewall/app inventory report interval")
+		jitter        = flag.Float64("jitter", 0.1, "random fraction added to or removed from each interval")
+		churn         = flag.Float64("churn", 0.001, "probability per heartbeat that an agent goes away and later re-registers")
+		awayMin       = flag.Duration("away-min", 30*time.Second, "minimum time a churned agent stays away")
+		awayMax       = flag.Duration("away-max", 5*time.Minute, "maximum time a churned agent stays away")
+		mutation      = flag.Float64("mutation", 0.05, "fraction of inventory entries changed per report")
+		duration      = flag.Duration("duration", time.Minute, "how long to run (0 = until interrupted)")
+		printInterval = flag.Duration("print", 10*time.Second, "how often to print intermediate statistics")
+		rpcTimeout    = flag.Duration("timeout", 10*time.Second, "per-RPC timeout")
+		seed          = flag.Int64("seed", time.Now().UnixNano(), "random seed")
+	)
+	flag.Parse()
+
+	if *awayMax < *awayMin {
+		log.Fatalf("-away-max must not be smaller than -away-min")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	target := *addr
+	if *inProcess != "" {
+		var shutdown func()
+		var err error
+		target, shutdown, err = startInProcessServer(*inProcess, *sqlitePath)
+		if err != nil {
+			log.Fatalf("Failed to start in-process server: %v", err)
+		}
+		defer shutdown()
+		log.Printf("In-process %s server listening on %s", *inProcess, target)
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect to %s: %v", target, err)
+	}
+	defer conn.Close()
+	client := pb.NewAgentServiceClient(conn)
+
+	opts := simOptions{
+		heartbeatInterval: *heartbeat,
+		reportInterval:    *report,
+		jitter:            *jitter,
+		churn:             *churn,
+		awayMin:           *awayMin,
+		awayMax:           *awayMax,
+		mutationRate:      *mutation,
+		rpcTimeout:        *rpcTimeout,
+	}
+	rec := NewRecorder()
+
+	log.Printf("Starting %d simulated agents against %s...", *agents, target)
+	var wg sync.WaitGroup
+	for i := 0; i < *agents; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			newSimAgent(i, client, opts, rec, *seed+int64(i)).run(ctx)
+		}(i)
+	}
+
+	ticker := time.NewTicker(*printInterval)
+	defer ticker.Stop()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Println()
+			rec.Report(os.Stdout)
+		case <-done:
+			fmt.Println("\nFinal results:")
+			rec.Report(os.Stdout)
+			return
+		}
+	}
+}
+
+// startInProcessServer runs the AgentService on a loopback port with an
+// in-memory or SQLite backend and returns its address.
+func startInProcessServer(backend, sqlitePath string) (string, func(), error) {
+	cfg := config.Default()
+
+	var store *repository.Store
+	switch backend {
+	case "memory":
+		store = repository.NewMemoryStore()
+	case "sqlite":
+		if sqlitePath == "" {
+			dir, err := os.MkdirTemp("", "agentsim")
+			if err != nil {
+				return "", nil, err
+			}
+			sqlitePath = dir + "/agents.db"
+		}
+		db, err := repository.ConnectSQLite(sqlitePath)
+		if err != nil {
+			return "", nil, err
+		}
+		store = repository.NewStore(db)
+	default:
+		return "", nil, fmt.Errorf("unknown backend %q (want memory or sqlite)", backend)
+	}
+
+	provider := config.StaticProvider(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	beats := usecase.NewHeartbeatBuffer(store.Agents, provider)
+	flushed := make(chan struct{})
+	go func() {
+		beats.Run(ctx)
+		close(flushed)
+	}()
+	webhooks := usecase.NewWebhookUseCase(store.Webhooks, webhook.NewClient(), provider)
+	catalog := usecase.NewSoftwareUseCase(store.Agents)
+	vulns := usecase.NewVulnerabilityUseCase(store.Vulnerabilities, store.Agents, catalog)
+	firewall := usecase.NewFirewallUseCase(store.Firewall, store.Agents)
+	compliance := usecase.NewComplianceUseCase(store.Compliance, store.Agents, catalog)
+	events := usecase.NewEventBus(store.Agents, provider, webhooks, vulns, firewall, compliance)
+	go events.Run(ctx)
+	logic := usecase.NewAgentUseCase(store.Agents, beats, events)
+	commands := usecase.NewCommandUseCase(store.Agents, store.Commands, events)
+	metrics := usecase.NewMetricsUseCase(store.Metrics, store.Agents, provider)
+	go metrics.Run(ctx)
+
+	artifactDir, err := os.MkdirTemp("", "agentsim-artifacts")
+	if err != nil {
+		cancel()
+		return "", nil, err
+	}
+	artifactStore, err := artifacts.NewLocalStore(artifactDir)
+	if err != nil {
+		cancel()
+		return "", nil, err
+	}
+	updates := usecase.NewUpdateUseCase(store.Updates, store.Agents, artifactStore, events, provider)
+	collections := usecase.NewCollectionUseCase(store.Collections, commands, artifactStore, events, provider)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		cancel()
+		return "", nil, err
+	}
+	srv := grpc.NewServer()
+	pb.RegisterAgentServiceServer(srv, service.NewAgentServer(logic, commands, webhooks, vulns, catalog, firewall, compliance, usecase.NewExportUseCase(store.Agents, store.Inventory), usecase.NewAvailabilityUseCase(store.Agents), usecase.NewCloneUseCase(store.Agents, events, provider), metrics, usecase.NewAlertUseCase(store.Alerts, store.Agents, store.Metrics, events, provider), usecase.NewInventoryUseCase(store.Inventory, store.Agents, events), usecase.NewPatchUseCase(store.Patches, store.Agents, events), usecase.NewScriptUseCase(provider), updates, collections, usecase.NewJobUseCase(store.Jobs, store.Agents, store.Commands, commands, events, provider), events, provider))
+	go srv.Serve(lis)
+
+	return lis.Addr().String(), func() {
+		srv.GracefulStop()
+		cancel()
+		<-flushed
+		os.RemoveAll(artifactDir)
+	}, nil
+}
@@ -0,0 +1,128 @@
+// cmd/agentsim/patches.go
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "agent_server/agent_server/proto"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// patchTemplate is an update a simulated host can have installed or pending.
+// Windows updates are KB numbers; Linux ones are packages with a version.
+type patchTemplate struct {
+	id, title string
+	security  bool
+}
+
+var windowsPatches = []patchTemplate{
+	{"KB5034441", "Windows Recovery Environment update", true},
+	{"KB5031356", "Cumulative Update for Windows", true},
+	{"KB5035845", "Cumulative Update for Windows", true},
+	{"KB5034763", "Cumulative Update for Windows", true},
+	{"KB890830", "Windows Malicious Software Removal Tool", false},
+	{"KB5033375", "Cumulative Update for .NET Framework", false},
+	{"KB2267602", "Security Intelligence Update for Microsoft Defender", true},
+}
+
+var linuxPatches = []patchTemplate{
+	{"openssl", "Secure Sockets Layer toolkit", true},
+	{"openssh-server", "Secure shell server", true},
+	{"linux-image-generic", "Generic Linux kernel image", true},
+	{"curl", "Command line tool for transferring data with URL syntax", true},
+	{"tzdata", "Time zone and daylight-saving time data", false},
+	{"vim", "Vi IMproved", false},
+	{"python3", "Interactive high-level object-oriented language", false},
+}
+
+// patchState is the OS patch status of a simulated agent.
+type patchState struct {
+	installed     []*pb.PatchInfo
+	pending       []*pb.PendingUpdateInfo
+	lastUpdateAt  time.Time
+	rebootPending bool
+}
+
+func (a *simAgent) newPatchState() {
+	catalog := a.patchCatalog()
+	p := &a.patches
+	p.lastUpdateAt = a.bootedAt.Add(-time.Duration(a.rng.Int63n(int64(30 * 24 * time.Hour))))
+	for _, i := range a.rng.Perm(len(catalog)) {
+		t := catalog[i]
+		if a.rng.Intn(3) == 0 {
+			p.pending = append(p.pending, &pb.PendingUpdateInfo{PatchId: t.id, Title: t.title, Version: a.patchVersion(t, 1), Security: t.security})
+			continue
+		}
+		installedAt := p.lastUpdateAt.Add(-time.Duration(a.rng.Int63n(int64(90 * 24 * time.Hour))))
+		p.installed = append(p.installed, &pb.PatchInfo{PatchId: t.id, Title: t.title, Version: a.patchVersion(t, 0), InstalledAt: timestamppb.New(installedAt)})
+	}
+	p.rebootPending = a.rng.Intn(10) == 0
+}
+
+func (a *simAgent) patchCatalog() []patchTemplate {
+	if a.details.OsName == "Windows" {
+		return windowsPatches
+	}
+	return linuxPatches
+}
+
+// patchVersion is empty for KB numbers, which carry no version.
+func (a *simAgent) patchVersion(t patchTemplate, bump int) string {
+	if a.details.OsName == "Windows" {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d", 1+len(t.id)%4, a.rng.Intn(20), bump+a.rng.Intn(10))
+}
+
+// mutatePatches installs pending updates, which then need a reboot, lets
+// hosts reboot, and now and then makes a new update available.
+func (a *simAgent) mutatePatches() {
+	p := &a.patches
+	if p.rebootPending && a.rng.Float64() < a.opts.mutationRate {
+		p.rebootPending = false
+	}
+	kept := p.pending[:0]
+	for _, u := range p.pending {
+		if a.rng.Float64() >= a.opts.mutationRate {
+			kept = append(kept, u)
+			continue
+		}
+		p.installed = installPatch(p.installed, &pb.PatchInfo{PatchId: u.PatchId, Title: u.Title, Version: u.Version, InstalledAt: timestamppb.Now()})
+		p.lastUpdateAt = time.Now()
+		p.rebootPending = true
+	}
+	p.pending = kept
+	if a.rng.Float64() < a.opts.mutationRate/2 && len(p.installed) > 0 {
+		t := p.installed[a.rng.Intn(len(p.installed))]
+		p.pending = append(p.pending, &pb.PendingUpdateInfo{PatchId: t.PatchId, Title: t.Title, Version: a.patchVersion(patchTemplate{id: t.PatchId}, 10), Security: a.rng.Intn(2) == 0})
+	}
+}
+
+// installPatch replaces an installed patch of the same ID, as a package
+// upgrade does, or adds it.
+func installPatch(installed []*pb.PatchInfo, patch *pb.PatchInfo) []*pb.PatchInfo {
+	for i, p := range installed {
+		if p.PatchId == patch.PatchId {
+			installed[i] = patch
+			return installed
+		}
+	}
+	return append(installed, patch)
+}
+
+func (a *simAgent) reportPatchStatus(ctx context.Context) {
+	p := &a.patches
+	start := time.Now()
+	_, err := a.client.ReportPatchStatus(ctx, &pb.PatchStatusRequest{
+		AgentId:       a.id,
+		Installed:     p.installed,
+		Pending:       p.pending,
+		LastUpdateAt:  timestamppb.New(p.lastUpdateAt),
+		RebootPending: p.rebootPending,
+	})
+	a.observe("ReportPatchStatus", start, err)
+}
@@ -0,0 +1,108 @@
+// cmd/agentsim/stats.go
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// maxSamples bounds memory per RPC; beyond it we keep a uniform reservoir
+// for the percentiles. The maximum is tracked over every successful call.
+const maxSamples = 200_000
+
+type rpcStats struct {
+	calls     int64
+	succeeded int64 // calls offered to the reservoir
+	samples   []time.Duration
+	max       time.Duration
+	errors    map[string]int64
+}
+
+// Recorder collects latency samples and error counts per RPC.
+type Recorder struct {
+	mu    sync.Mutex
+	rpcs  map[string]*rpcStats
+	rng   *rand.Rand
+	start time.Time
+}
+
+// NewRecorder creates an empty recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{rpcs: make(map[string]*rpcStats), rng: rand.New(rand.NewSource(time.Now().UnixNano())), start: time.Now()}
+}
+
+// Observe records one call of rpc that took d and returned err.
+func (r *Recorder) Observe(rpc string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.rpcs[rpc]
+	if !ok {
+		s = &rpcStats{errors: make(map[string]int64)}
+		r.rpcs[rpc] = s
+	}
+	s.calls++
+	if err != nil {
+		s.errors[status.Code(err).String()]++
+		return
+	}
+	s.succeeded++
+	if d > s.max {
+		s.max = d
+	}
+	if len(s.samples) < maxSamples {
+		s.samples = append(s.samples, d)
+	} else if i := r.rng.Int63n(s.succeeded); i < maxSamples {
+		s.samples[i] = d
+	}
+}
+
+// Report writes a table of call counts, throughput, latency percentiles and errors.
+func (r *Recorder) Report(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := time.Since(r.start).Seconds()
+	names := make([]string, 0, len(r.rpcs))
+	for name := range r.rpcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "%-24s %10s %9s %10s %10s %10s %10s  %s\n", "RPC", "CALLS", "RPS", "P50", "P90", "P99", "MAX", "ERRORS")
+	for _, name := range names {
+		s := r.rpcs[name]
+		sorted := append([]time.Duration(nil), s.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		errs := "-"
+		if len(s.errors) > 0 {
+			errs = ""
+			codes := make([]string, 0, len(s.errors))
+			for code := range s.errors {
+				codes = append(codes, code)
+			}
+			sort.Strings(codes)
+			for _, code := range codes {
+				errs += fmt.Sprintf("%s=%d ", code, s.errors[code])
+			}
+		}
+		fmt.Fprintf(w, "%-24s %10d %9.1f %10s %10s %10s %10s  %s\n", name, s.calls, float64(s.calls)/elapsed,
+			percentile(sorted, 0.50), percentile(sorted, 0.90), percentile(sorted, 0.99), s.max.Round(time.Microsecond), errs)
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i].Round(time.Microsecond)
+}
@@ -3,66 +3,303 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net"
-	
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	pb "agent_server/agent_server/proto"
+	"agent_server/internal/artifacts"
+	"agent_server/internal/certs"
 	"agent_server/internal/config"
+	"agent_server/internal/gateway"
+	"agent_server/internal/logging"
+	"agent_server/internal/ratelimit"
 
 	"agent_server/internal/repository"
 	"agent_server/internal/service"
 	"agent_server/internal/usecase"
+	"agent_server/internal/webhook"
 	"agent_server/internal/worker"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 )
 
 const (
-	port                  = ":50051"
-	configPath            = "config.yaml"
-	
+	configPath = "config.yaml"
 )
 
 func main() {
-	// 1. تحميل الإعدادات
-	cfg, err := config.LoadConfig(configPath)
+	// الأمر الفرعي migrate يدير مخطط قاعدة البيانات ثم يخرج دون تشغيل الخادم
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	// 1. تحميل الإعدادات ومراقبة التغييرات عليها أثناء التشغيل
+	cfgManager, err := config.NewManager(configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config from %s: %v", configPath, err)
 	}
+	cfg := cfgManager.Current()
+	applyLogLevel(cfg.Log.Level)
 
-	// 2. الاتصال بقاعدة البيانات
-	db, err := repository.ConnectDB(&cfg.Database)
+	// 2. الاتصال بقاعدة البيانات وإنشاء المستودع (Repository) حسب db.driver
+	store, err := repository.Open(&cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	log.Println("Database connection successful.")
+	log.Printf("Database connection successful (driver: %s).", cfg.Database.Driver)
 
-	// 3. إنشاء المستودع (Repository)
-	agentRepo := repository.NewAgentRepository(db)
+	// 3. جديد: إنشاء طبقة منطق العمل (Use Case)
+	// النبضات تُجمع في الذاكرة وتُكتب دفعة واحدة بدل UPDATE لكل نبضة
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// 4. جديد: إنشاء طبقة منطق العمل (Use Case)
-	agentLogic := usecase.NewAgentUseCase(agentRepo)
+	heartbeats := usecase.NewHeartbeatBuffer(store.Agents, cfgManager)
+	if err := heartbeats.LoadKnownAgents(); err != nil {
+		log.Fatalf("Failed to load registered agents: %v", err)
+	}
+	flushDone := make(chan struct{})
+	go func() {
+		heartbeats.Run(ctx)
+		close(flushDone)
+	}()
+	// الأحداث تمر عبر ناقل الأحداث إلى مشتركي WatchEvents وإلى جدول الـ outbox
+	// الذي يسلمه موزع الـ webhooks في الخلفية، وإلى مطابقة الثغرات عند تغير التطبيقات المثبتة
+	// وإلى تحليل جدار الحماية لحذف نتائج الوكلاء المتوقفين نهائيًا، وإلى تقييم سياسات الامتثال عند كل تقرير مخزون
+	webhookLogic := usecase.NewWebhookUseCase(store.Webhooks, webhook.NewClient(), cfgManager)
+	// قواعد الكتالوج توحد أسماء التطبيقات لكتالوج البرامج ولمطابقة الثغرات
+	softwareLogic := usecase.NewSoftwareUseCase(store.Agents)
+	if err := softwareLogic.ReloadRules(cfg.Software.RulesFile); err != nil {
+		log.Fatalf("Failed to load software catalog rules: %v", err)
+	}
+	vulnLogic := usecase.NewVulnerabilityUseCase(store.Vulnerabilities, store.Agents, softwareLogic)
+	if err := vulnLogic.LoadFeed(); err != nil {
+		log.Fatalf("Failed to load vulnerability feed: %v", err)
+	}
+	firewallLogic := usecase.NewFirewallUseCase(store.Firewall, store.Agents)
+	complianceLogic := usecase.NewComplianceUseCase(store.Compliance, store.Agents, softwareLogic)
+	if err := complianceLogic.ReloadPolicies(cfg.Compliance.PoliciesPath); err != nil {
+		log.Fatalf("Failed to load compliance policies: %v", err)
+	}
+	events := usecase.NewEventBus(store.Agents, cfgManager, webhookLogic, vulnLogic, firewallLogic, complianceLogic)
+	// صندوق الـ webhooks يُكتب قبل أن يعود النشر حتى لا يضيع حدث عند توقف الخادم فجأة،
+	// أما بقية المستهلكين فيعملون في الخلفية حتى لا ينتظر كل طلب عمل قاعدة البيانات الخاص بهم
+	eventsDone := make(chan struct{})
+	go func() {
+		events.Run(ctx)
+		close(eventsDone)
+	}()
+	agentLogic := usecase.NewAgentUseCase(store.Agents, heartbeats, events)
+	commandLogic := usecase.NewCommandUseCase(store.Agents, store.Commands, events)
+	// التصدير يقرأ الصفوف بمؤشر مباشرة من المستودع دون تحميل الجداول في الذاكرة
+	exportLogic := usecase.NewExportUseCase(store.Agents, store.Inventory)
+	availabilityLogic := usecase.NewAvailabilityUseCase(store.Agents)
+	// كشف الأجهزة المستنسخة التي تستخدم نفس agent_id عند التسجيل ومع كل نبضة
+	cloneLogic := usecase.NewCloneUseCase(store.Agents, events, cfgManager)
+	// مقاييس الموارد المرسلة مع النبضات تُجمع في الذاكرة وتُكتب دفعة واحدة مثل النبضات
+	metricsLogic := usecase.NewMetricsUseCase(store.Metrics, store.Agents, cfgManager)
+	metricsDone := make(chan struct{})
+	go func() {
+		metricsLogic.Run(ctx)
+		close(metricsDone)
+	}()
+	// الجرد الموسع: الواجهات والمستخدمون والخدمات والمنافذ والعمليات
+	inventoryLogic := usecase.NewInventoryUseCase(store.Inventory, store.Agents, events)
+	// حالة تحديثات النظام: المثبتة والمعلقة وإعادة التشغيل المنتظرة
+	patchLogic := usecase.NewPatchUseCase(store.Patches, store.Agents, events)
+	// قواعد التنبيه ترسل إشعاراتها كأحداث على الناقل فتصل إلى الـ webhooks و WatchEvents
+	alertLogic := usecase.NewAlertUseCase(store.Alerts, store.Agents, store.Metrics, events, cfgManager)
+	if err := alertLogic.ReloadRules(cfg.Alerts.RulesPath); err != nil {
+		log.Fatalf("Failed to load alert rules: %v", err)
+	}
+	// مكتبة السكربتات التي تشير إليها أوامر RunScript بالاسم
+	scriptLogic := usecase.NewScriptUseCase(cfgManager)
+	if err := scriptLogic.ReloadLibrary(cfg.Scripts.LibraryPath); err != nil {
+		log.Fatalf("Failed to load script library: %v", err)
+	}
+	// مخزن الملفات لحزم الوكيل والملفات المجمعة: على القرص المحلي أو في خدمة متوافقة مع S3
+	artifactStore, err := openArtifactStore(cfg.Artifacts)
+	if err != nil {
+		log.Fatalf("Failed to open artifact store: %v", err)
+	}
+	// نشر إصدارات الوكيل الجديدة على مراحل
+	if cfg.Updates.SigningPublicKey == "" && cfg.Updates.AllowUnsigned {
+		log.Println("⚠️  WARNING: updates.allow_unsigned is set and no signing key is configured; unsigned agent packages can be rolled out to the whole fleet. Do not use this in production.")
+	}
+	updateLogic := usecase.NewUpdateUseCase(store.Updates, store.Agents, artifactStore, events, cfgManager)
+	if err := updateLogic.Load(); err != nil {
+		log.Fatalf("Failed to load agent rollouts: %v", err)
+	}
+	// جمع الملفات من الوكلاء برفع قابل للاستئناف
+	collectionLogic := usecase.NewCollectionUseCase(store.Collections, commandLogic, artifactStore, events, cfgManager)
+	// المهام المجدولة والمتكررة على مجموعات من الوكلاء
+	jobLogic := usecase.NewJobUseCase(store.Jobs, store.Agents, store.Commands, commandLogic, events, cfgManager)
 
-	// 5. إنشاء الخادم (Handler) مع حقن طبقة منطق العمل
-	agentServer := service.NewAgentServer(agentLogic)
-	monitor := worker.NewMonitor(agentLogic)
+	// 4. إنشاء الخادم (Handler) مع حقن طبقة منطق العمل
+	agentServer := service.NewAgentServer(agentLogic, commandLogic, webhookLogic, vulnLogic, softwareLogic, firewallLogic, complianceLogic, exportLogic, availabilityLogic, cloneLogic, metricsLogic, alertLogic, inventoryLogic, patchLogic, scriptLogic, updateLogic, collectionLogic, jobLogic, events, cfgManager)
+	monitor := worker.NewMonitor(agentLogic, inventoryLogic, patchLogic, collectionLogic, jobLogic, cfgManager)
+	dispatcher := worker.NewWebhookDispatcher(webhookLogic, cfgManager)
+	evaluator := worker.NewComplianceEvaluator(complianceLogic, cfgManager)
+	rollup := worker.NewMetricsRollup(metricsLogic, cfgManager)
+	alerter := worker.NewAlertEvaluator(alertLogic, events, cfgManager)
+	scheduler := worker.NewScheduler(jobLogic, cfgManager)
 
 	go monitor.Start()
+	go dispatcher.Run(ctx)
+	go evaluator.Run(ctx)
+	go rollup.Run(ctx)
+	go alerter.Run(ctx)
+	go scheduler.Run(ctx)
+	// السياسات ربما تغيرت أثناء توقف الخادم، فنقيّم كل الوكلاء مرة عند البدء
+	go complianceLogic.EvaluateAll()
+
+	// 5. إعداد الحد من الطلبات و TLS بحيث يمكن تحديثهما دون قطع اتصالات الوكلاء
+	limiter := ratelimit.New(cfg.RateLimit)
+	unaryInterceptors := []grpc.UnaryServerInterceptor{limiter.UnaryServerInterceptor(), service.AdminAuthUnaryInterceptor(cfgManager)}
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(limiter.StreamServerInterceptor(), service.AdminAuthStreamInterceptor(cfgManager)),
+	}
+
+	var certReloader *certs.Reloader
+	if cfg.TLS.Enabled {
+		certReloader, err = certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(certReloader.TLSConfig())))
+	}
+
+	cfgManager.Subscribe(func(old, updated *config.Config) {
+		applyLogLevel(updated.Log.Level)
+		limiter.Update(updated.RateLimit)
+		if certReloader != nil {
+			if err := certReloader.Load(updated.TLS.CertFile, updated.TLS.KeyFile); err != nil {
+				log.Printf("❌ Keeping previous TLS certificate: %v", err)
+			}
+		}
+		// يعاد قراءة ملف القواعد مع كل إعادة تحميل حتى لو لم يتغير مساره
+		if err := softwareLogic.ReloadRules(updated.Software.RulesFile); err != nil {
+			log.Printf("❌ Keeping previous software catalog rules: %v", err)
+		}
+		if err := alertLogic.ReloadRules(updated.Alerts.RulesPath); err != nil {
+			log.Printf("❌ Keeping previous alert rules: %v", err)
+		}
+		if err := scriptLogic.ReloadLibrary(updated.Scripts.LibraryPath); err != nil {
+			log.Printf("❌ Keeping previous script library: %v", err)
+		}
+		// تغيير ملفات السياسات يتطلب رفع رقم الإصدار، وإلا نبقي على السياسات السابقة
+		if err := complianceLogic.ReloadPolicies(updated.Compliance.PoliciesPath); err != nil {
+			log.Printf("❌ Keeping previous compliance policies: %v", err)
+			return
+		}
+		go complianceLogic.EvaluateAll()
+	})
+	go func() {
+		if err := cfgManager.Watch(ctx); err != nil {
+			log.Printf("❌ Config hot reload disabled: %v", err)
+		}
+	}()
+
 	// 6. بدء خادم gRPC
-	lis, err := net.Listen("tcp", port)
+	lis, err := net.Listen("tcp", cfg.Server.ListenAddr)
 	if err != nil {
-		log.Fatalf("Failed to listen on port %s: %v", port, err)
+		log.Fatalf("Failed to listen on %s: %v", cfg.Server.ListenAddr, err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(opts...)
 	pb.RegisterAgentServiceServer(grpcServer, agentServer)
-	
+
 	reflection.Register(grpcServer)
 
-	log.Printf("gRPC server listening on %s", port)
+	// 7. بوابة REST/JSON تمر بنفس الـ interceptors (الحد من الطلبات والتحقق من رمز المشرف)
+	var httpServer *http.Server
+	if cfg.Server.HTTPListenAddr != "" {
+		httpServer = &http.Server{
+			Addr:    cfg.Server.HTTPListenAddr,
+			Handler: gateway.New(agentServer, unaryInterceptors...),
+		}
+		if certReloader != nil {
+			httpServer.TLSConfig = certReloader.TLSConfig()
+		}
+		go func() {
+			var err error
+			if httpServer.TLSConfig != nil {
+				err = httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Failed to serve REST gateway: %v", err)
+			}
+		}()
+		log.Printf("REST gateway listening on %s (OpenAPI at /openapi.json)", cfg.Server.HTTPListenAddr)
+	}
+
+	// عند الإيقاف نغلق الخادم بهدوء ثم نكتب آخر النبضات والمقاييس والأحداث المعلقة
+	// إغلاق ناقل الأحداث ينهي بث WatchEvents، وإلا انتظر GracefulStop المشتركين إلى الأبد
+	// وإن بقي بث آخر مفتوحًا بعد المهلة نوقف الخادم فورًا
+	go func() {
+		<-ctx.Done()
+		log.Println("Shutting down gRPC server...")
+		events.Close()
+		if httpServer != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			httpServer.Shutdown(shutdownCtx)
+			cancel()
+		}
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(10 * time.Second):
+			log.Println("Graceful stop timed out, closing remaining streams.")
+			grpcServer.Stop()
+		}
+	}()
+
+	log.Printf("gRPC server listening on %s", cfg.Server.ListenAddr)
 	if err := grpcServer.Serve(lis); err != nil {
 		log.Fatalf("Failed to serve gRPC server: %v", err)
 	}
+	<-flushDone
+	<-metricsDone
+	<-eventsDone
+}
+
+// applyLogLevel يضبط مستوى السجلات حسب الإعدادات
+func applyLogLevel(level string) {
+	l, err := logging.ParseLevel(level)
+	if err != nil {
+		log.Printf("❌ %v, keeping current log level", err)
+		return
+	}
+	logging.SetLevel(l)
+}
+
+// openArtifactStore يفتح مخزن الملفات الذي يحدده backend في الإعدادات
+func openArtifactStore(cfg config.ArtifactsConfig) (artifacts.Store, error) {
+	if cfg.Backend == "s3" {
+		return artifacts.NewS3Store(artifacts.S3Options{
+			Endpoint:        cfg.S3.Endpoint,
+			Bucket:          cfg.S3.Bucket,
+			Region:          cfg.S3.Region,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			Prefix:          cfg.S3.Prefix,
+			PathStyle:       cfg.S3.PathStyle,
+		})
+	}
+	return artifacts.NewLocalStore(cfg.Path)
 }
@@ -0,0 +1,124 @@
+// cmd/server/migrate.go
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"agent_server/internal/config"
+	"agent_server/internal/migrations"
+	"agent_server/internal/repository"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down      revert the most recently applied migration
+  status    list migrations and whether they are applied
+  to N      migrate up or down to version N`
+
+// runMigrate ينفذ الأمر الفرعي migrate ويعيد رمز الخروج
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config from %s: %v\n", configPath, err)
+		return 1
+	}
+	if cfg.Database.Driver != "postgres" {
+		fmt.Fprintf(os.Stderr, "migrations only apply to the postgres driver (configured: %s)\n", cfg.Database.Driver)
+		return 1
+	}
+	db, err := repository.OpenDB(&cfg.Database)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer sqlDB.Close()
+
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		target, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return 2
+		}
+		err = migrator.To(ctx, target)
+	case "status":
+		return printMigrationStatus(ctx, migrator)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	latest, err := migrations.Latest()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	version, _ := migrator.Version(ctx)
+	fmt.Printf("Schema is at version %d (binary knows %d).\n", version, latest)
+	return 0
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrations.Migrator) int {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	latest, err := migrations.Latest()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for _, s := range statuses {
+		applied := "pending"
+		if s.Applied {
+			applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, applied)
+	}
+	if version > latest {
+		fmt.Printf("Database is at version %d, which is newer than this binary (%d).\n", version, latest)
+	}
+	return 0
+}
@@ -0,0 +1,243 @@
+// internal/alerting/conditions.go
+
+package alerting
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"agent_server/internal/firewall"
+	"agent_server/internal/model"
+)
+
+// maxSummaryItems caps the applications listed in a summary.
+const maxSummaryItems = 5
+
+// Finding is a rule condition that holds for one key. A rule raises at
+// most one open alert per key.
+type Finding struct {
+	Key     string
+	AgentID string // empty for group findings
+	Summary string
+	Value   float64
+	Since   time.Time // when the condition started, if known; zero = now
+}
+
+// Fleet is what periodic rules are evaluated against.
+type Fleet struct {
+	Agents  []model.Agent                   // every agent that is not decommissioned
+	Samples map[string][]model.MetricSample // the latest sample of each metric, by agent ID
+	Now     time.Time
+}
+
+// Key joins the parts that identify a finding, such as an agent ID and a
+// mount point.
+func Key(parts ...string) string {
+	return strings.Join(parts, "|")
+}
+
+// Evaluate returns the findings of a periodic rule. Event-driven rules
+// return none.
+func (r *Rule) Evaluate(fleet *Fleet) []Finding {
+	switch r.Kind {
+	case KindAgentStatus:
+		return r.evaluateStatus(fleet)
+	case KindMetric:
+		return r.evaluateMetric(fleet)
+	case KindGroupOffline:
+		return r.evaluateGroup(fleet)
+	}
+	return nil
+}
+
+func (r *Rule) evaluateStatus(fleet *Fleet) []Finding {
+	var findings []Finding
+	for i := range fleet.Agents {
+		a := &fleet.Agents[i]
+		if a.Status != r.status || !r.Applies(a.Labels) {
+			continue
+		}
+		f := Finding{Key: a.AgentID, AgentID: a.AgentID, Summary: fmt.Sprintf("%s (%s) is %s", a.Hostname, a.AgentID, a.Status)}
+		if a.Status == "OFFLINE" && !a.LastSeen.IsZero() {
+			f.Since = a.LastSeen
+			f.Value = fleet.Now.Sub(a.LastSeen).Seconds()
+			f.Summary += ", last seen " + a.LastSeen.UTC().Format(time.RFC3339)
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+func (r *Rule) evaluateMetric(fleet *Fleet) []Finding {
+	c := r.metric
+	var findings []Finding
+	for i := range fleet.Agents {
+		a := &fleet.Agents[i]
+		if !r.Applies(a.Labels) {
+			continue
+		}
+		for _, s := range fleet.Samples[a.AgentID] {
+			if s.Metric != c.name || (c.mount != "" && s.Mount != c.mount) || !c.holds(s.Value) {
+				continue
+			}
+			f := Finding{Key: a.AgentID, AgentID: a.AgentID, Value: s.Value}
+			on := ""
+			if s.Mount != "" {
+				f.Key = Key(a.AgentID, s.Mount)
+				on = " on " + s.Mount
+			}
+			f.Summary = fmt.Sprintf("%s%s of %s (%s) is %s %s %s",
+				s.Metric, on, a.Hostname, a.AgentID, formatValue(round(s.Value)), c.op, formatValue(c.threshold))
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+func (c *metricCondition) holds(v float64) bool {
+	switch c.op {
+	case ">":
+		return v > c.threshold
+	case ">=":
+		return v >= c.threshold
+	case "<":
+		return v < c.threshold
+	default:
+		return v <= c.threshold
+	}
+}
+
+func (r *Rule) evaluateGroup(fleet *Fleet) []Finding {
+	type count struct{ total, offline int }
+	groups := map[string]*count{}
+	for i := range fleet.Agents {
+		a := &fleet.Agents[i]
+		if !r.Applies(a.Labels) {
+			continue
+		}
+		group := "fleet"
+		if r.group.GroupBy != "" {
+			value, ok := a.Labels[r.group.GroupBy]
+			if !ok {
+				continue
+			}
+			group = r.group.GroupBy + "=" + value
+		}
+		c := groups[group]
+		if c == nil {
+			c = &count{}
+			groups[group] = c
+		}
+		c.total++
+		if a.Status == "OFFLINE" {
+			c.offline++
+		}
+	}
+
+	var findings []Finding
+	for group, c := range groups {
+		percent := 100 * float64(c.offline) / float64(c.total)
+		if c.offline == 0 || percent <= r.group.Percent {
+			continue
+		}
+		findings = append(findings, Finding{
+			Key:   group,
+			Value: percent,
+			Summary: fmt.Sprintf("%s%% of agents in %s are offline (%d of %d), more than %s%%",
+				formatValue(round(percent)), group, c.offline, c.total, formatValue(r.group.Percent)),
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Key < findings[j].Key })
+	return findings
+}
+
+// EvaluateFirewallRules returns a finding for every rule in added, as
+// listed in a firewall.changed event, that the rule matches.
+func (r *Rule) EvaluateFirewallRules(agentID string, added []string) []Finding {
+	if r.Kind != KindFirewallRuleAdded {
+		return nil
+	}
+	var findings []Finding
+	for _, s := range added {
+		if !r.firewall.matches(s) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Key:     Key(agentID, s),
+			AgentID: agentID,
+			Summary: fmt.Sprintf("%s added firewall rule %q", agentID, s),
+		})
+	}
+	return findings
+}
+
+// matches parses a rule as formatted in firewall.changed events,
+// "name: direction action port/protocol enabled", and compares it to the
+// condition. Disabled rules never match.
+func (c *firewallCondition) matches(s string) bool {
+	i := strings.LastIndex(s, ": ")
+	if i < 0 {
+		return false
+	}
+	fields := strings.Fields(s[i+2:])
+	if len(fields) != 4 || fields[3] != "enabled" {
+		return false
+	}
+	direction, action := fields[0], fields[1]
+	port, protocol := fields[2], ""
+	if j := strings.LastIndex(port, "/"); j >= 0 {
+		port, protocol = port[:j], port[j+1:]
+	}
+	if c.direction != "" && direction != c.direction {
+		return false
+	}
+	if c.action != "" && action != c.action {
+		return false
+	}
+	if p := firewall.NormalizeProtocol(protocol); c.protocol != "" && p != firewall.ProtocolAny && p != c.protocol {
+		return false
+	}
+	if c.port >= 0 {
+		ports, err := firewall.ParsePorts(port)
+		if err != nil || !ports.Contains(c.port) {
+			return false
+		}
+	}
+	return true
+}
+
+// EvaluateApps returns a finding if any of the applications installed on
+// the agent matches the rule.
+func (r *Rule) EvaluateApps(agentID string, apps []model.InstalledApplication) []Finding {
+	if r.Kind != KindAppInstalled {
+		return nil
+	}
+	var matched []string
+	for _, app := range apps {
+		if !r.app.name.MatchString(app.Name) || (r.app.publisher != nil && !r.app.publisher.MatchString(app.Publisher)) {
+			continue
+		}
+		matched = append(matched, strings.TrimSpace(app.Name+" "+app.Version))
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	sort.Strings(matched)
+	listed := matched
+	if len(listed) > maxSummaryItems {
+		listed = listed[:maxSummaryItems]
+	}
+	summary := fmt.Sprintf("%s has %s installed", agentID, strings.Join(listed, ", "))
+	if more := len(matched) - len(listed); more > 0 {
+		summary += fmt.Sprintf(" and %d more", more)
+	}
+	return []Finding{{Key: agentID, AgentID: agentID, Summary: summary, Value: float64(len(matched))}}
+}
+
+// round keeps two decimals for summaries.
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
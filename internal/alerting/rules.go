@@ -0,0 +1,450 @@
+// internal/alerting/rules.go
+
+// Package alerting evaluates declarative YAML alert rules against the state
+// of the fleet and the events agents raise.
+//
+// A rules file holds a list of rules:
+//
+//	rules:
+//	  - id: agent-offline
+//	    name: Agent offline
+//	    severity: high        # low, medium (default), high or critical
+//	    for: 15m              # the condition must hold this long before the alert fires
+//	    labels: {env: prod}   # optional; every agent when omitted
+//	    notify: [webhooks, log]
+//	    agent_status: OFFLINE
+//	  - id: disk-full
+//	    severity: critical
+//	    for: 10m
+//	    metric: {name: disk_used_percent, op: '>', threshold: 90}
+//
+// Each rule has exactly one of these conditions:
+//
+//	agent_status:        OFFLINE                          the agent has this status
+//	metric:              {name, mount, op, threshold}     the latest sample compares to threshold; op is >, >=, < or <=
+//	group_offline:       {percent, group_by}              more than percent of the agents sharing a label value are offline
+//	firewall_rule_added: {direction, action, port, protocol}  an enabled rule like this appeared in a report
+//	app_installed:       {name, publisher}                a matching application is installed
+//
+// agent_status, metric and group_offline are evaluated periodically and
+// resolve when the condition no longer holds. firewall_rule_added and
+// app_installed are evaluated when the agent reports its inventory and fire
+// at once, so they take no for. A firewall alert resolves when the rule is
+// removed or after resolve_after (24h by default); an application alert
+// when no matching application is left.
+//
+// Names and publishers are case-insensitive regular expressions. notify
+// routes the firing and resolved notifications: webhooks publishes
+// alert.firing and alert.resolved events, log writes them to the alert log.
+package alerting
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent_server/internal/firewall"
+	"agent_server/internal/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Notification routes.
+const (
+	NotifyWebhooks = "webhooks"
+	NotifyLog      = "log"
+)
+
+// Condition kinds.
+const (
+	KindAgentStatus       = "agent_status"
+	KindMetric            = "metric"
+	KindGroupOffline      = "group_offline"
+	KindFirewallRuleAdded = "firewall_rule_added"
+	KindAppInstalled      = "app_installed"
+)
+
+// defaultResolveAfter closes firewall alerts whose rule is never removed.
+const defaultResolveAfter = 24 * time.Hour
+
+type rulesFile struct {
+	Rules []ruleSpec `yaml:"rules"`
+}
+
+type ruleSpec struct {
+	ID                string            `yaml:"id"`
+	Name              string            `yaml:"name"`
+	Description       string            `yaml:"description"`
+	Severity          string            `yaml:"severity"`
+	For               string            `yaml:"for"`
+	ResolveAfter      string            `yaml:"resolve_after"`
+	Labels            map[string]string `yaml:"labels"`
+	Notify            []string          `yaml:"notify"`
+	AgentStatus       string            `yaml:"agent_status"`
+	Metric            *metricSpec       `yaml:"metric"`
+	GroupOffline      *groupSpec        `yaml:"group_offline"`
+	FirewallRuleAdded *firewallSpec     `yaml:"firewall_rule_added"`
+	AppInstalled      *appSpec          `yaml:"app_installed"`
+}
+
+type metricSpec struct {
+	Name      string   `yaml:"name"`
+	Mount     string   `yaml:"mount"`
+	Op        string   `yaml:"op"`
+	Threshold *float64 `yaml:"threshold"`
+}
+
+type groupSpec struct {
+	Percent float64 `yaml:"percent"`
+	GroupBy string  `yaml:"group_by"`
+}
+
+type firewallSpec struct {
+	Direction string `yaml:"direction"`
+	Action    string `yaml:"action"`
+	Port      string `yaml:"port"`
+	Protocol  string `yaml:"protocol"`
+}
+
+type appSpec struct {
+	Name      string `yaml:"name"`
+	Publisher string `yaml:"publisher"`
+}
+
+// Rule is a parsed alert rule. A Rule is immutable and safe for concurrent
+// use.
+type Rule struct {
+	ID           string
+	Name         string
+	Description  string
+	Severity     string
+	For          time.Duration
+	ResolveAfter time.Duration // firewall_rule_added only
+	Labels       map[string]string
+	Notify       []string
+	Kind         string // one of the Kind constants
+	Condition    string // the condition in readable form, e.g. "disk_used_percent > 90"
+
+	status   string
+	metric   *metricCondition
+	group    *groupSpec
+	firewall *firewallCondition
+	app      *appMatcher
+}
+
+type metricCondition struct {
+	name, mount, op string
+	threshold       float64
+}
+
+type firewallCondition struct {
+	direction, action, protocol string // as stored, e.g. DIRECTION_IN and ALLOW; empty = any
+	port                        int    // -1 = any
+}
+
+type appMatcher struct {
+	name, publisher *regexp.Regexp
+}
+
+// LoadRules reads the rules at path or, if path is a directory, every
+// *.yaml and *.yml file in it. An empty path means no rules.
+func LoadRules(path string) ([]*Rule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if ext := filepath.Ext(e.Name()); !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+
+	var rules []*Rule
+	seen := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseRules(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, r := range parsed {
+			if other, ok := seen[r.ID]; ok {
+				return nil, fmt.Errorf("%s: rule %q is also defined in %s", file, r.ID, other)
+			}
+			seen[r.ID] = file
+		}
+		rules = append(rules, parsed...)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+// ParseRules parses and validates one rules file.
+func ParseRules(data []byte) ([]*Rule, error) {
+	var f rulesFile
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+
+	var rules []*Rule
+	ids := map[string]bool{}
+	for i, spec := range f.Rules {
+		if spec.ID == "" {
+			return nil, fmt.Errorf("rules[%d]: id is required", i)
+		}
+		if ids[spec.ID] {
+			return nil, fmt.Errorf("rule %s appears twice", spec.ID)
+		}
+		ids[spec.ID] = true
+		r, err := compileRule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", spec.ID, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func compileRule(spec ruleSpec) (*Rule, error) {
+	r := &Rule{
+		ID:          spec.ID,
+		Name:        spec.Name,
+		Description: spec.Description,
+		Severity:    model.SeverityMedium,
+		Labels:      spec.Labels,
+		Notify:      []string{NotifyWebhooks, NotifyLog},
+	}
+	if r.Name == "" {
+		r.Name = r.ID
+	}
+	if spec.Severity != "" {
+		r.Severity = strings.ToUpper(spec.Severity)
+		if model.SeverityRank(r.Severity) == 0 {
+			return nil, fmt.Errorf("unknown severity %q (want low, medium, high or critical)", spec.Severity)
+		}
+	}
+	if spec.Notify != nil {
+		r.Notify = nil
+		for _, route := range spec.Notify {
+			route = strings.ToLower(route)
+			if route != NotifyWebhooks && route != NotifyLog {
+				return nil, fmt.Errorf("unknown notify route %q (want webhooks or log)", route)
+			}
+			r.Notify = append(r.Notify, route)
+		}
+	}
+
+	var conditions []string
+	var err error
+	if spec.AgentStatus != "" {
+		conditions = append(conditions, KindAgentStatus)
+		err = r.compileAgentStatus(spec.AgentStatus)
+	}
+	if spec.Metric != nil {
+		conditions = append(conditions, KindMetric)
+		err = r.compileMetric(*spec.Metric)
+	}
+	if spec.GroupOffline != nil {
+		conditions = append(conditions, KindGroupOffline)
+		err = r.compileGroupOffline(*spec.GroupOffline)
+	}
+	if spec.FirewallRuleAdded != nil {
+		conditions = append(conditions, KindFirewallRuleAdded)
+		err = r.compileFirewallRuleAdded(*spec.FirewallRuleAdded)
+	}
+	if spec.AppInstalled != nil {
+		conditions = append(conditions, KindAppInstalled)
+		err = r.compileAppInstalled(*spec.AppInstalled)
+	}
+	switch {
+	case len(conditions) == 0:
+		return nil, fmt.Errorf("no condition (want one of agent_status, metric, group_offline, firewall_rule_added or app_installed)")
+	case len(conditions) > 1:
+		return nil, fmt.Errorf("only one condition is allowed, got %s", strings.Join(conditions, " and "))
+	case err != nil:
+		return nil, fmt.Errorf("%s: %w", conditions[0], err)
+	}
+	r.Kind = conditions[0]
+
+	if spec.For != "" {
+		if r.EventDriven() {
+			return nil, fmt.Errorf("for is not supported by %s, which fires when the agent reports", r.Kind)
+		}
+		if r.For, err = parseDuration(spec.For); err != nil {
+			return nil, fmt.Errorf("for: %w", err)
+		}
+	}
+	if spec.ResolveAfter != "" {
+		if r.Kind != KindFirewallRuleAdded {
+			return nil, fmt.Errorf("resolve_after is only supported by firewall_rule_added")
+		}
+		if r.ResolveAfter, err = parseDuration(spec.ResolveAfter); err != nil {
+			return nil, fmt.Errorf("resolve_after: %w", err)
+		}
+	} else if r.Kind == KindFirewallRuleAdded {
+		r.ResolveAfter = defaultResolveAfter
+	}
+	return r, nil
+}
+
+func (r *Rule) compileAgentStatus(status string) error {
+	r.status = strings.ToUpper(status)
+	switch r.status {
+	case "ONLINE", "OFFLINE":
+	default:
+		return fmt.Errorf("unknown status %q (want ONLINE or OFFLINE)", status)
+	}
+	r.Condition = "status is " + r.status
+	return nil
+}
+
+func (r *Rule) compileMetric(spec metricSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch spec.Op {
+	case ">", ">=", "<", "<=":
+	default:
+		return fmt.Errorf("unknown op %q (want >, >=, < or <=)", spec.Op)
+	}
+	if spec.Threshold == nil {
+		return fmt.Errorf("threshold is required")
+	}
+	r.metric = &metricCondition{name: spec.Name, mount: spec.Mount, op: spec.Op, threshold: *spec.Threshold}
+	r.Condition = fmt.Sprintf("%s %s %s", spec.Name, spec.Op, formatValue(*spec.Threshold))
+	if spec.Mount != "" {
+		r.Condition = fmt.Sprintf("%s on %s %s %s", spec.Name, spec.Mount, spec.Op, formatValue(*spec.Threshold))
+	}
+	return nil
+}
+
+func (r *Rule) compileGroupOffline(spec groupSpec) error {
+	if spec.Percent < 0 || spec.Percent >= 100 {
+		return fmt.Errorf("percent must be between 0 and 100")
+	}
+	r.group = &spec
+	r.Condition = fmt.Sprintf("more than %s%% of agents offline", formatValue(spec.Percent))
+	if spec.GroupBy != "" {
+		r.Condition += " per " + spec.GroupBy
+	}
+	return nil
+}
+
+func (r *Rule) compileFirewallRuleAdded(spec firewallSpec) error {
+	c := &firewallCondition{port: -1}
+	parts := []string{"firewall rule added"}
+	switch strings.ToLower(spec.Direction) {
+	case "":
+	case "inbound", "in":
+		c.direction = "DIRECTION_IN"
+		parts = append(parts, "inbound")
+	case "outbound", "out":
+		c.direction = "DIRECTION_OUT"
+		parts = append(parts, "outbound")
+	default:
+		return fmt.Errorf("direction must be inbound or outbound, got %q", spec.Direction)
+	}
+	switch c.action = strings.ToUpper(spec.Action); c.action {
+	case "":
+	case "ALLOW", "DENY":
+		parts = append(parts, c.action)
+	default:
+		return fmt.Errorf("action must be allow or deny, got %q", spec.Action)
+	}
+	if spec.Port != "" {
+		port, err := strconv.Atoi(spec.Port)
+		if err != nil || port < 0 || port > 65535 {
+			return fmt.Errorf("invalid port %q", spec.Port)
+		}
+		c.port = port
+		parts = append(parts, "port "+spec.Port)
+	}
+	if spec.Protocol != "" {
+		c.protocol = firewall.NormalizeProtocol(spec.Protocol)
+		parts = append(parts, c.protocol)
+	}
+	r.firewall = c
+	r.Condition = strings.Join(parts, " ")
+	return nil
+}
+
+func (r *Rule) compileAppInstalled(spec appSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	m := &appMatcher{}
+	var err error
+	if m.name, err = regexp.Compile("(?i)" + spec.Name); err != nil {
+		return err
+	}
+	if spec.Publisher != "" {
+		if m.publisher, err = regexp.Compile("(?i)" + spec.Publisher); err != nil {
+			return err
+		}
+	}
+	r.app = m
+	r.Condition = fmt.Sprintf("application matching %q installed", spec.Name)
+	return nil
+}
+
+// parseDuration accepts time.ParseDuration values and whole days ("7d").
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// EventDriven reports whether the rule is evaluated on agent reports
+// rather than periodically.
+func (r *Rule) EventDriven() bool {
+	return r.Kind == KindFirewallRuleAdded || r.Kind == KindAppInstalled
+}
+
+// Notifies reports whether the rule routes its notifications to route.
+func (r *Rule) Notifies(route string) bool {
+	for _, n := range r.Notify {
+		if n == route {
+			return true
+		}
+	}
+	return false
+}
+
+// Applies reports whether the rule covers an agent with these labels.
+func (r *Rule) Applies(labels model.Labels) bool {
+	return labels.Match(r.Labels)
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
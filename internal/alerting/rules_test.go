@@ -0,0 +1,280 @@
+package alerting_test
+
+import (
+	"agent_server/internal/alerting"
+	"agent_server/internal/model"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadFixtures(t *testing.T) map[string]*alerting.Rule {
+	t.Helper()
+	rules, err := alerting.LoadRules("testdata/rules")
+	if err != nil {
+		t.Fatalf("LoadRules: %v", err)
+	}
+	byID := map[string]*alerting.Rule{}
+	for _, r := range rules {
+		byID[r.ID] = r
+	}
+	return byID
+}
+
+func TestLoadRules(t *testing.T) {
+	rules, err := alerting.LoadRules("testdata/rules")
+	if err != nil {
+		t.Fatalf("LoadRules: %v", err)
+	}
+	// README.txt is skipped; both files are read and the rules sorted by ID.
+	var ids []string
+	for _, r := range rules {
+		ids = append(ids, r.ID)
+	}
+	want := []string{"agent-offline", "disk-full", "fleet-down", "low-memory", "outbound-allow",
+		"rdp-opened", "remote-access-tool", "root-full", "site-down", "torrent"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("LoadRules = %v, want %v", ids, want)
+	}
+
+	single, err := alerting.LoadRules("testdata/rules/inventory.yml")
+	if err != nil || len(single) != 4 || single[0].ID != "outbound-allow" {
+		t.Fatalf("LoadRules(file) = %v, %v", single, err)
+	}
+	if none, err := alerting.LoadRules(""); err != nil || none != nil {
+		t.Fatalf("LoadRules(\"\") = %v, %v; want no rules", none, err)
+	}
+	if _, err := alerting.LoadRules("testdata/duplicate"); err == nil || !strings.Contains(err.Error(), `rule "offline" is also defined in`) {
+		t.Fatalf("LoadRules(duplicate IDs) = %v", err)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules := loadFixtures(t)
+	both := []string{alerting.NotifyWebhooks, alerting.NotifyLog}
+	tests := []struct {
+		id           string
+		name         string
+		severity     string
+		kind         string
+		condition    string
+		wait         time.Duration
+		resolveAfter time.Duration
+		notify       []string
+	}{
+		{"agent-offline", "Agent offline", model.SeverityHigh, alerting.KindAgentStatus, "status is OFFLINE", 15 * time.Minute, 0, []string{alerting.NotifyWebhooks}},
+		{"disk-full", "disk-full", model.SeverityCritical, alerting.KindMetric, "disk_used_percent > 90", 10 * time.Minute, 0, both},
+		{"root-full", "root-full", model.SeverityMedium, alerting.KindMetric, "disk_used_percent on / >= 95.5", 0, 0, both},
+		{"low-memory", "low-memory", model.SeverityMedium, alerting.KindMetric, "mem_available_mb < 256", 0, 0, both},
+		{"site-down", "site-down", model.SeverityMedium, alerting.KindGroupOffline, "more than 50% of agents offline per site", 24 * time.Hour, 0, both},
+		{"fleet-down", "fleet-down", model.SeverityMedium, alerting.KindGroupOffline, "more than 0% of agents offline", 0, 0, both},
+		{"rdp-opened", "rdp-opened", model.SeverityHigh, alerting.KindFirewallRuleAdded, "firewall rule added inbound ALLOW port 3389 TCP", 0, 24 * time.Hour, []string{alerting.NotifyLog}},
+		{"outbound-allow", "outbound-allow", model.SeverityMedium, alerting.KindFirewallRuleAdded, "firewall rule added outbound ALLOW", 0, 2 * time.Hour, both},
+		{"remote-access-tool", "remote-access-tool", model.SeverityMedium, alerting.KindAppInstalled, `application matching "anydesk|teamviewer" installed`, 0, 0, nil},
+		{"torrent", "torrent", model.SeverityMedium, alerting.KindAppInstalled, `application matching "torrent" installed`, 0, 0, both},
+	}
+	for _, tt := range tests {
+		r := rules[tt.id]
+		if r == nil {
+			t.Errorf("rule %s not loaded", tt.id)
+			continue
+		}
+		if r.Name != tt.name || r.Severity != tt.severity || r.Kind != tt.kind || r.Condition != tt.condition ||
+			r.For != tt.wait || r.ResolveAfter != tt.resolveAfter || !reflect.DeepEqual(r.Notify, tt.notify) {
+			t.Errorf("rule %s = %+v", tt.id, r)
+		}
+		if eventDriven := tt.kind == alerting.KindFirewallRuleAdded || tt.kind == alerting.KindAppInstalled; r.EventDriven() != eventDriven {
+			t.Errorf("rule %s EventDriven = %v, want %v", tt.id, r.EventDriven(), eventDriven)
+		}
+	}
+	if r := rules["disk-full"]; r.Description != "A volume is almost full." {
+		t.Errorf("disk-full description = %q", r.Description)
+	}
+	if r := rules["rdp-opened"]; r.Notifies(alerting.NotifyWebhooks) || !r.Notifies(alerting.NotifyLog) {
+		t.Errorf("rdp-opened notifies %v, want the log only", r.Notify)
+	}
+
+	offline := rules["agent-offline"]
+	if !offline.Applies(model.Labels{"env": "prod", "site": "ams"}) || offline.Applies(model.Labels{"env": "dev"}) || offline.Applies(nil) {
+		t.Errorf("agent-offline with labels %v applies to the wrong agents", offline.Labels)
+	}
+	if !rules["disk-full"].Applies(nil) {
+		t.Errorf("disk-full has no labels but does not apply to every agent")
+	}
+}
+
+func TestParseRulesRejectsInvalidFiles(t *testing.T) {
+	want := map[string]string{
+		"no-id":                     "rules[0]: id is required",
+		"duplicate-rule":            "rule offline appears twice",
+		"unknown-field":             "field agent_statsu not found",
+		"no-condition":              "rule nothing: no condition",
+		"two-conditions":            "only one condition is allowed, got agent_status and metric",
+		"unknown-severity":          `unknown severity "urgent"`,
+		"unknown-notify":            `unknown notify route "email"`,
+		"unknown-status":            `agent_status: unknown status "DOWN"`,
+		"metric-without-name":       "metric: name is required",
+		"metric-bad-op":             `metric: unknown op "=="`,
+		"metric-without-threshold":  "metric: threshold is required",
+		"group-percent":             "group_offline: percent must be between 0 and 100",
+		"firewall-direction":        `firewall_rule_added: direction must be inbound or outbound, got "sideways"`,
+		"firewall-action":           `firewall_rule_added: action must be allow or deny, got "reject"`,
+		"firewall-port":             `firewall_rule_added: invalid port "http"`,
+		"app-without-name":          "app_installed: name is required",
+		"app-bad-regexp":            "app_installed: error parsing regexp",
+		"for-on-event-rule":         "for is not supported by app_installed",
+		"resolve-after-on-periodic": "resolve_after is only supported by firewall_rule_added",
+		"bad-for":                   `for: invalid duration "soon"`,
+		"zero-days":                 `resolve_after: invalid duration "0d"`,
+	}
+	files, err := filepath.Glob("testdata/invalid/*.yaml")
+	if err != nil || len(files) != len(want) {
+		t.Fatalf("%d invalid fixtures for %d expected errors (%v)", len(files), len(want), err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := alerting.ParseRules(data); err == nil || !strings.Contains(err.Error(), want[name]) {
+			t.Errorf("ParseRules(%s) = %v, want an error containing %q", name, err, want[name])
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rules := loadFixtures(t)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	lastSeen := now.Add(-20 * time.Minute)
+	fleet := &alerting.Fleet{
+		Agents: []model.Agent{
+			{AgentID: "a1", Hostname: "web-1", Status: "OFFLINE", LastSeen: lastSeen, Labels: model.Labels{"env": "prod", "site": "ams"}},
+			{AgentID: "a2", Hostname: "web-2", Status: "ONLINE", LastSeen: now, Labels: model.Labels{"env": "prod", "site": "ams"}},
+			{AgentID: "a3", Hostname: "db-1", Status: "OFFLINE", Labels: model.Labels{"env": "prod", "site": "fra"}},
+			{AgentID: "a4", Hostname: "dev-1", Status: "OFFLINE", Labels: model.Labels{"env": "dev", "site": "fra"}},
+			{AgentID: "a5", Hostname: "lab", Status: "ONLINE"},
+		},
+		Samples: map[string][]model.MetricSample{
+			"a1": {
+				{Metric: "disk_used_percent", Mount: "/", Value: 96.123},
+				{Metric: "disk_used_percent", Mount: "/data", Value: 91},
+				{Metric: "mem_available_mb", Value: 1024},
+			},
+			"a2": {
+				{Metric: "disk_used_percent", Mount: "/", Value: 95.5},
+				{Metric: "disk_used_percent", Mount: "/data", Value: 90},
+				{Metric: "mem_available_mb", Value: 200},
+			},
+			"a5": {{Metric: "disk_used_percent", Mount: "/", Value: 50}},
+		},
+		Now: now,
+	}
+
+	tests := []struct {
+		rule string
+		want []alerting.Finding
+	}{
+		// Only prod agents are covered; a3 was never seen, so its outage has no start.
+		{"agent-offline", []alerting.Finding{
+			{Key: "a1", AgentID: "a1", Summary: "web-1 (a1) is OFFLINE, last seen 2026-03-10T11:40:00Z", Value: 1200, Since: lastSeen},
+			{Key: "a3", AgentID: "a3", Summary: "db-1 (a3) is OFFLINE"},
+		}},
+		{"disk-full", []alerting.Finding{
+			{Key: "a1|/", AgentID: "a1", Summary: "disk_used_percent on / of web-1 (a1) is 96.12 > 90", Value: 96.123},
+			{Key: "a1|/data", AgentID: "a1", Summary: "disk_used_percent on /data of web-1 (a1) is 91 > 90", Value: 91},
+			{Key: "a2|/", AgentID: "a2", Summary: "disk_used_percent on / of web-2 (a2) is 95.5 > 90", Value: 95.5},
+		}},
+		{"root-full", []alerting.Finding{
+			{Key: "a1|/", AgentID: "a1", Summary: "disk_used_percent on / of web-1 (a1) is 96.12 >= 95.5", Value: 96.123},
+			{Key: "a2|/", AgentID: "a2", Summary: "disk_used_percent on / of web-2 (a2) is 95.5 >= 95.5", Value: 95.5},
+		}},
+		{"low-memory", []alerting.Finding{
+			{Key: "a2", AgentID: "a2", Summary: "mem_available_mb of web-2 (a2) is 200 < 256", Value: 200},
+		}},
+		// Half of ams is offline, which is not more than 50%; a5 has no site.
+		{"site-down", []alerting.Finding{
+			{Key: "site=fra", Summary: "100% of agents in site=fra are offline (2 of 2), more than 50%", Value: 100},
+		}},
+		{"fleet-down", []alerting.Finding{
+			{Key: "fleet", Summary: "60% of agents in fleet are offline (3 of 5), more than 0%", Value: 60},
+		}},
+		{"rdp-opened", nil},
+		{"torrent", nil},
+	}
+	for _, tt := range tests {
+		if got := rules[tt.rule].Evaluate(fleet); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s findings =\n%+v\nwant\n%+v", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateFirewallRules(t *testing.T) {
+	rules := loadFixtures(t)
+	added := []string{
+		"RDP: DIRECTION_IN ALLOW 3389/TCP enabled",
+		"Remote tools: DIRECTION_IN ALLOW 3000-4000/ANY enabled",
+		"RDP over UDP: DIRECTION_IN ALLOW 3389/UDP enabled",
+		"Old RDP: DIRECTION_IN ALLOW 3389/TCP disabled",
+		"Block RDP: DIRECTION_IN DENY 3389/TCP enabled",
+		"Proxy: web: DIRECTION_OUT ALLOW ANY/ANY enabled",
+		"not a firewall rule",
+	}
+	tests := []struct {
+		rule    string
+		matched []string
+	}{
+		{"rdp-opened", []string{added[0], added[1]}},
+		{"outbound-allow", []string{added[5]}},
+		{"agent-offline", nil},
+	}
+	for _, tt := range tests {
+		var want []alerting.Finding
+		for _, s := range tt.matched {
+			want = append(want, alerting.Finding{Key: alerting.Key("a1", s), AgentID: "a1", Summary: `a1 added firewall rule "` + s + `"`})
+		}
+		if got := rules[tt.rule].EvaluateFirewallRules("a1", added); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s findings =\n%+v\nwant\n%+v", tt.rule, got, want)
+		}
+	}
+}
+
+func TestEvaluateApps(t *testing.T) {
+	rules := loadFixtures(t)
+	apps := []model.InstalledApplication{
+		{Name: "TeamViewer", Version: "15.51.5", Publisher: "TeamViewer Germany GmbH"},
+		{Name: "AnyDesk", Version: "8.0.8", Publisher: "AnyDesk Software GmbH"},
+		{Name: "AnyDesk Helper", Version: "1.0", Publisher: "Unknown"},
+		{Name: "qBittorrent", Version: "4.6.3"},
+		{Name: "uTorrent", Version: "3.6"},
+		{Name: "BitTorrent", Version: "7.11"},
+		{Name: "Deluge Torrent"},
+		{Name: "Transmission Torrent", Version: "4.0"},
+		{Name: "Vuze Torrent", Version: "5.7"},
+		{Name: "Tixati torrent", Version: "3.2"},
+	}
+	tests := []struct {
+		rule string
+		want []alerting.Finding
+	}{
+		// The helper's publisher does not match.
+		{"remote-access-tool", []alerting.Finding{
+			{Key: "a1", AgentID: "a1", Summary: "a1 has AnyDesk 8.0.8, TeamViewer 15.51.5 installed", Value: 2},
+		}},
+		{"torrent", []alerting.Finding{
+			{Key: "a1", AgentID: "a1", Summary: "a1 has BitTorrent 7.11, Deluge Torrent, Tixati torrent 3.2, Transmission Torrent 4.0, Vuze Torrent 5.7 installed and 2 more", Value: 7},
+		}},
+		{"rdp-opened", nil},
+	}
+	for _, tt := range tests {
+		if got := rules[tt.rule].EvaluateApps("a1", apps); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s findings =\n%+v\nwant\n%+v", tt.rule, got, tt.want)
+		}
+	}
+	if got := rules["torrent"].EvaluateApps("a1", apps[:3]); got != nil {
+		t.Errorf("torrent findings without torrent clients = %+v", got)
+	}
+}
@@ -0,0 +1,295 @@
+// internal/artifacts/s3.go
+
+package artifacts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// S3Options configures an S3Store.
+type S3Options struct {
+	// Endpoint is the service's base URL, such as https://s3.eu-west-1.amazonaws.com
+	// or http://minio.internal:9000.
+	Endpoint string
+	Bucket   string
+	Region   string // default us-east-1
+	// AccessKeyID and SecretAccessKey default to the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY environment variables.
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix is prepended to every key, so several servers can share a
+	// bucket.
+	Prefix string
+	// PathStyle addresses the bucket as a path (endpoint/bucket/key) rather
+	// than a host name (bucket.endpoint/key); most S3-compatible services
+	// need it.
+	PathStyle bool
+}
+
+// S3Store is a Store in a bucket of an S3-compatible service. Requests are
+// signed with AWS Signature Version 4.
+type S3Store struct {
+	opts     S3Options
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Store creates a store for the bucket in opts. It does not contact the
+// service.
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3 endpoint %q must be an http or https URL", opts.Endpoint)
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.AccessKeyID == "" {
+		opts.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if opts.SecretAccessKey == "" {
+		opts.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, errors.New("s3 credentials are required")
+	}
+	opts.Prefix = strings.Trim(opts.Prefix, "/")
+	// Objects can be large; requests are bounded by their context instead
+	// of a client timeout.
+	return &S3Store{opts: opts, endpoint: endpoint, client: &http.Client{}, now: time.Now}, nil
+}
+
+// Put spools the content to a temporary file first: S3 needs the length and
+// digest of an object before it is sent, and the limit has to be checked
+// before anything is stored.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, maxBytes int64) (Object, error) {
+	if err := checkKey(key); err != nil {
+		return Object{}, err
+	}
+	tmp, err := os.CreateTemp("", "artifact-*")
+	if err != nil {
+		return Object{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	sum := sha256.New()
+	src := r
+	if maxBytes > 0 {
+		src = io.LimitReader(r, maxBytes+1)
+	}
+	n, err := io.Copy(io.MultiWriter(tmp, sum), contextReader{ctx, src})
+	if err != nil {
+		return Object{}, err
+	}
+	if maxBytes > 0 && n > maxBytes {
+		return Object{}, fmt.Errorf("%w of %d bytes", ErrTooLarge, maxBytes)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return Object{}, err
+	}
+	digest := hex.EncodeToString(sum.Sum(nil))
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, digest)
+	if err != nil {
+		return Object{}, err
+	}
+	req.Body = io.NopCloser(tmp)
+	req.ContentLength = n
+	resp, err := s.do(req)
+	if err != nil {
+		return Object{}, err
+	}
+	resp.Body.Close()
+	return Object{Key: key, Size: n, SHA256: digest}, nil
+}
+
+func (s *S3Store) Open(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	req, err := s.newRequest(ctx, http.MethodGet, key, emptySHA256)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := s.do(req)
+	var statusErr *s3Error
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusRequestedRangeNotSatisfiable {
+		// Reading from the end of an object.
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodDelete, key, emptySHA256)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// emptySHA256 is the digest of an empty request body.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// newRequest builds a signed request for the object at key.
+func (s *S3Store) newRequest(ctx context.Context, method, key, payloadSHA256 string) (*http.Request, error) {
+	objectKey := key
+	if s.opts.Prefix != "" {
+		objectKey = s.opts.Prefix + "/" + key
+	}
+	u := *s.endpoint
+	if s.opts.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.opts.Bucket + "/" + objectKey
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + objectKey
+	}
+	u.RawPath = uriEncodePath(u.Path)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-amz-content-sha256", payloadSHA256)
+	return req, nil
+}
+
+// do signs and sends req. Responses other than 2xx are returned as errors;
+// 404 is ErrNotFound.
+func (s *S3Store) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, s.now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	var parsed struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.Unmarshal(body, &parsed)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, req.URL.Path)
+	}
+	return nil, &s3Error{status: resp.StatusCode, code: parsed.Code, message: parsed.Message, method: req.Method, path: req.URL.Path}
+}
+
+// s3Error is an error response from the service.
+type s3Error struct {
+	status        int
+	code, message string
+	method, path  string
+}
+
+func (e *s3Error) Error() string {
+	if e.code == "" {
+		return fmt.Sprintf("s3 %s %s: HTTP %d", e.method, e.path, e.status)
+	}
+	return fmt.Sprintf("s3 %s %s: %s: %s", e.method, e.path, e.code, e.message)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req, signing
+// the host, the range if there is one and the x-amz-* headers.
+func (s *S3Store) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+
+	names := []string{"host"}
+	values := map[string]string{"host": req.URL.Host}
+	if r := req.Header.Get("Range"); r != "" {
+		names = append(names, "range")
+		values["range"] = r
+	}
+	names = append(names, "x-amz-content-sha256", "x-amz-date")
+	values["x-amz-content-sha256"] = req.Header.Get("x-amz-content-sha256")
+	values["x-amz-date"] = amzDate
+
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(name + ":" + strings.TrimSpace(values[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		signedHeaders,
+		values["x-amz-content-sha256"],
+	}, "\n")
+
+	scope := day + "/" + s.opts.Region + "/s3/aws4_request"
+	canonicalSum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalSum[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretAccessKey), day)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncodePath percent-encodes a path as Signature Version 4 requires:
+// everything but unreserved characters and "/".
+func uriEncodePath(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,168 @@
+// internal/artifacts/store.go
+
+// Package artifacts keeps large files, such as agent packages and files
+// collected from agents, outside the database. Objects are addressed by a
+// slash-separated key like "packages/2.4.0/linux-amd64"; callers record the
+// key, size and SHA-256 that Put returns alongside their own metadata.
+//
+// LocalStore keeps objects on local disk and S3Store in a bucket of an
+// S3-compatible service; a LocalStore stands in for S3 where there is none.
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned for a key with no object.
+	ErrNotFound = errors.New("artifact not found")
+	// ErrTooLarge is returned by Put when the content exceeds its limit.
+	ErrTooLarge = errors.New("artifact exceeds the size limit")
+)
+
+// Object describes a stored object.
+type Object struct {
+	Key    string
+	Size   int64
+	SHA256 string // hex
+}
+
+// Store stores objects by key.
+type Store interface {
+	// Put stores the content of r under key, replacing any object there. It
+	// reads at most maxBytes (0 = no limit) and stores nothing when r holds
+	// more.
+	Put(ctx context.Context, key string, r io.Reader, maxBytes int64) (Object, error)
+	// Open returns the object's content starting at offset.
+	Open(ctx context.Context, key string, offset int64) (io.ReadCloser, error)
+	// Delete removes the object. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalStore is a Store in a directory on local disk; a key maps to the
+// file with the same relative path.
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore creates a store in dir, creating the directory if needed.
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if dir == "" {
+		return nil, errors.New("artifact directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, maxBytes int64) (Object, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return Object{}, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return Object{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	sum := sha256.New()
+	src := r
+	if maxBytes > 0 {
+		src = io.LimitReader(r, maxBytes+1)
+	}
+	n, err := io.Copy(io.MultiWriter(tmp, sum), contextReader{ctx, src})
+	if err != nil {
+		return Object{}, err
+	}
+	if maxBytes > 0 && n > maxBytes {
+		return Object{}, fmt.Errorf("%w of %d bytes", ErrTooLarge, maxBytes)
+	}
+	if err := tmp.Sync(); err != nil {
+		return Object{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Object{}, err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return Object{}, err
+	}
+	return Object{Key: key, Size: n, SHA256: hex.EncodeToString(sum.Sum(nil))}, nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps key to a file in the store's directory.
+func (s *LocalStore) path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// checkKey rejects keys that are not clean relative paths, so no key can
+// leave a LocalStore's directory or an S3Store's prefix.
+func checkKey(key string) error {
+	clean := path.Clean(key)
+	if key == "" || clean != key || path.IsAbs(key) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(key, `\`) {
+		return fmt.Errorf("invalid artifact key %q", key)
+	}
+	return nil
+}
+
+// contextReader stops a copy when ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,53 @@
+// Package certs holds the server TLS certificate and swaps it in place when
+// the files are replaced, so renewals do not require a restart.
+package certs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+)
+
+// Reloader serves the most recently loaded key pair to new TLS handshakes.
+// Existing connections keep the certificate they were established with.
+type Reloader struct {
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewReloader loads the initial key pair.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{}
+	if err := r.Load(certFile, keyFile); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Load reads the key pair from disk and replaces the current one.
+// On error the previous certificate stays in use.
+func (r *Reloader) Load(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	return nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// TLSConfig returns a server TLS configuration backed by the reloader.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
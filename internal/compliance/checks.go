@@ -0,0 +1,236 @@
+// internal/compliance/checks.go
+
+package compliance
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"agent_server/internal/firewall"
+	"agent_server/internal/model"
+	"agent_server/internal/software"
+)
+
+// maxEvidenceItems caps the applications or rules listed as evidence.
+const maxEvidenceItems = 5
+
+// Inventory is what a policy is evaluated against: the agent and its
+// latest firewall and application reports.
+type Inventory struct {
+	Agent         *model.Agent
+	FirewallRules []model.FirewallRule
+	Apps          []model.InstalledApplication
+	Catalog       *software.Rules // maps apps to the product keys of app checks
+	Now           time.Time
+}
+
+// ReportedAt returns the time of the newest firewall or application report,
+// or the zero time if the agent has not reported either.
+func (inv *Inventory) ReportedAt() time.Time {
+	var latest time.Time
+	if len(inv.FirewallRules) > 0 {
+		latest = inv.FirewallRules[0].ReportedAt
+	}
+	if len(inv.Apps) > 0 && inv.Apps[0].ReportedAt.After(latest) {
+		latest = inv.Apps[0].ReportedAt
+	}
+	return latest
+}
+
+// appMatcher selects installed applications by name, publisher or product.
+type appMatcher struct {
+	name, publisher *regexp.Regexp
+	product         string
+}
+
+func compileAppMatcher(spec appSpec) (*appMatcher, error) {
+	if spec.Name == "" && spec.Product == "" {
+		return nil, fmt.Errorf("name or product is required")
+	}
+	m := &appMatcher{product: strings.ToLower(spec.Product)}
+	var err error
+	if spec.Name != "" {
+		if m.name, err = regexp.Compile("(?i)" + spec.Name); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Publisher != "" {
+		if m.publisher, err = regexp.Compile("(?i)" + spec.Publisher); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// find returns the matching applications with the version scheme of each.
+func (m *appMatcher) find(inv *Inventory) (apps []model.InstalledApplication, schemes []string) {
+	for _, a := range inv.Apps {
+		if m.name != nil && !m.name.MatchString(a.Name) {
+			continue
+		}
+		if m.publisher != nil && !m.publisher.MatchString(a.Publisher) {
+			continue
+		}
+		product := inv.Catalog.Normalize(a.Name, a.Publisher)
+		if m.product != "" && product.Key != m.product {
+			continue
+		}
+		apps = append(apps, a)
+		schemes = append(schemes, product.Scheme)
+	}
+	return apps, schemes
+}
+
+// describeApps lists applications as evidence, "name version; ...".
+func describeApps(apps []model.InstalledApplication) string {
+	parts := make([]string, 0, maxEvidenceItems)
+	for i, a := range apps {
+		if i == maxEvidenceItems {
+			parts = append(parts, fmt.Sprintf("and %d more", len(apps)-i))
+			break
+		}
+		parts = append(parts, strings.TrimSpace(a.Name+" "+a.Version))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func appAbsent(spec appSpec) (func(*Inventory) (string, string), error) {
+	if spec.MinVersion != "" {
+		return nil, fmt.Errorf("app_absent does not take min_version")
+	}
+	m, err := compileAppMatcher(spec)
+	if err != nil {
+		return nil, err
+	}
+	return func(inv *Inventory) (string, string) {
+		apps, _ := m.find(inv)
+		if len(apps) > 0 {
+			return model.ComplianceFail, "installed: " + describeApps(apps)
+		}
+		return model.CompliancePass, fmt.Sprintf("not among %d reported applications", len(inv.Apps))
+	}, nil
+}
+
+func appPresent(spec appSpec) (func(*Inventory) (string, string), error) {
+	m, err := compileAppMatcher(spec)
+	if err != nil {
+		return nil, err
+	}
+	return func(inv *Inventory) (string, string) {
+		apps, schemes := m.find(inv)
+		if len(apps) == 0 {
+			return model.ComplianceFail, fmt.Sprintf("not among %d reported applications", len(inv.Apps))
+		}
+		if spec.MinVersion == "" {
+			return model.CompliancePass, "installed: " + describeApps(apps)
+		}
+		for i, a := range apps {
+			if software.Compare(schemes[i], a.Version, spec.MinVersion) >= 0 {
+				return model.CompliancePass, "installed: " + describeApps(apps[i:i+1])
+			}
+		}
+		return model.ComplianceFail, fmt.Sprintf("older than %s: %s", spec.MinVersion, describeApps(apps))
+	}, nil
+}
+
+func osVersion(spec osSpec) (func(*Inventory) (string, string), error) {
+	if spec.Min == "" {
+		return nil, fmt.Errorf("os_version.min is required")
+	}
+	var name *regexp.Regexp
+	if spec.Name != "" {
+		var err error
+		if name, err = regexp.Compile("(?i)" + spec.Name); err != nil {
+			return nil, err
+		}
+	}
+	field := spec.Field
+	switch field {
+	case "":
+		field = "os_version"
+	case "os_version", "kernel_version":
+	default:
+		return nil, fmt.Errorf("unknown os_version.field %q (want os_version or kernel_version)", spec.Field)
+	}
+	return func(inv *Inventory) (string, string) {
+		agent := inv.Agent
+		if name != nil && !name.MatchString(agent.OSName) {
+			return model.ComplianceNotApplicable, fmt.Sprintf("OS is %q", agent.OSName)
+		}
+		version := agent.OSVersion
+		if field == "kernel_version" {
+			version = agent.KernelVersion
+		}
+		if version == "" {
+			return model.ComplianceFail, field + " not reported"
+		}
+		evidence := fmt.Sprintf("%s %s %s", agent.OSName, field, version)
+		release := version
+		if field == "kernel_version" {
+			// The suffix of "5.15.0-91-generic" is the distribution's ABI
+			// and flavour, not a semver pre-release of 5.15.0.
+			release, _, _ = strings.Cut(version, "-")
+		}
+		if software.Compare(software.SchemeAuto, release, spec.Min) < 0 {
+			return model.ComplianceFail, evidence + ", below " + spec.Min
+		}
+		return model.CompliancePass, evidence
+	}, nil
+}
+
+func firewallDefaultDeny(direction string) (func(*Inventory) (string, string), error) {
+	var want string
+	switch strings.ToLower(direction) {
+	case "inbound", "in":
+		want, direction = "DIRECTION_IN", "inbound"
+	case "outbound", "out":
+		want, direction = "DIRECTION_OUT", "outbound"
+	default:
+		return nil, fmt.Errorf("firewall_default_deny must be inbound or outbound, got %q", direction)
+	}
+	return func(inv *Inventory) (string, string) {
+		for _, r := range inv.FirewallRules {
+			if !r.Enabled || r.Direction != want || r.Action != "DENY" || firewall.NormalizeProtocol(r.Protocol) != firewall.ProtocolAny {
+				continue
+			}
+			if ports, err := firewall.ParsePorts(r.Port); err == nil && ports.Equal(firewall.AllPorts) {
+				return model.CompliancePass, fmt.Sprintf("rule %q denies all %s traffic", r.Name, direction)
+			}
+		}
+		return model.ComplianceFail, fmt.Sprintf("no enabled %s DENY rule for any port and protocol among %d reported rules",
+			direction, len(inv.FirewallRules))
+	}, nil
+}
+
+func reportedWithin(s string) (func(*Inventory) (string, string), error) {
+	window, err := parseDuration(s)
+	if err != nil {
+		return nil, err
+	}
+	return func(inv *Inventory) (string, string) {
+		return within(inv.Now, inv.Agent.LastSeen, window, "last seen")
+	}, nil
+}
+
+func inventoryWithin(s string) (func(*Inventory) (string, string), error) {
+	window, err := parseDuration(s)
+	if err != nil {
+		return nil, err
+	}
+	return func(inv *Inventory) (string, string) {
+		return within(inv.Now, inv.ReportedAt(), window, "last inventory report")
+	}, nil
+}
+
+func within(now, t time.Time, window time.Duration, what string) (string, string) {
+	if t.IsZero() {
+		return model.ComplianceFail, what + ": never"
+	}
+	evidence := fmt.Sprintf("%s at %s", what, t.UTC().Format(time.RFC3339))
+	if now.Sub(t) > window {
+		return model.ComplianceFail, evidence
+	}
+	return model.CompliancePass, evidence
+}
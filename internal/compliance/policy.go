@@ -0,0 +1,302 @@
+// internal/compliance/policy.go
+
+// Package compliance evaluates declarative YAML policies against the
+// inventory an agent reports.
+//
+// A policy file holds one policy:
+//
+//	id: workstation-baseline
+//	version: 3              # bump on every change to the file
+//	name: Workstation baseline
+//	applies_to:             # optional; every agent when omitted
+//	  labels: {env: prod}
+//	  os: '^windows'        # regular expression on the OS name
+//	checks:
+//	  - id: no-telnet
+//	    title: Telnet must not be installed
+//	    severity: high      # low, medium (default), high or critical
+//	    app_absent: {name: '^telnet'}
+//
+// Each check has exactly one of these conditions:
+//
+//	app_absent:      {name, publisher, product}     no matching application is installed
+//	app_present:     {name, publisher, product, min_version}
+//	os_version:      {name, field, min}             field is os_version (default) or kernel_version
+//	firewall_default_deny: inbound|outbound         an enabled DENY rule for any port and protocol
+//	reported_within: 24h                            the agent was last seen this recently
+//	inventory_within: 7d                            the newest firewall or application report
+//
+// Names and publishers are case-insensitive regular expressions; product is
+// a software catalog key such as google:chrome.
+package compliance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agent_server/internal/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+type policyFile struct {
+	ID          string `yaml:"id"`
+	Version     int    `yaml:"version"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	AppliesTo   struct {
+		Labels map[string]string `yaml:"labels"`
+		OS     string            `yaml:"os"`
+	} `yaml:"applies_to"`
+	Checks []checkSpec `yaml:"checks"`
+}
+
+type checkSpec struct {
+	ID                  string   `yaml:"id"`
+	Title               string   `yaml:"title"`
+	Severity            string   `yaml:"severity"`
+	AppAbsent           *appSpec `yaml:"app_absent"`
+	AppPresent          *appSpec `yaml:"app_present"`
+	OSVersion           *osSpec  `yaml:"os_version"`
+	FirewallDefaultDeny string   `yaml:"firewall_default_deny"`
+	ReportedWithin      string   `yaml:"reported_within"`
+	InventoryWithin     string   `yaml:"inventory_within"`
+}
+
+type appSpec struct {
+	Name       string `yaml:"name"`
+	Publisher  string `yaml:"publisher"`
+	Product    string `yaml:"product"`
+	MinVersion string `yaml:"min_version"`
+}
+
+type osSpec struct {
+	Name  string `yaml:"name"`
+	Field string `yaml:"field"`
+	Min   string `yaml:"min"`
+}
+
+// Policy is a parsed policy file. A Policy is immutable and safe for
+// concurrent use.
+type Policy struct {
+	ID          string
+	Version     int
+	Name        string
+	Description string
+	Digest      string // hex SHA-256 of Definition
+	Definition  []byte
+
+	labels map[string]string
+	os     *regexp.Regexp // nil when every OS applies
+	checks []check
+}
+
+type check struct {
+	id, title, severity string
+	eval                func(inv *Inventory) (status, evidence string)
+}
+
+// LoadPolicies reads the policy at path or, if path is a directory, every
+// *.yaml and *.yml file in it. An empty path means no policies.
+func LoadPolicies(path string) ([]*Policy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if ext := filepath.Ext(e.Name()); !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+
+	var policies []*Policy
+	seen := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		p, err := ParsePolicy(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if other, ok := seen[p.ID]; ok {
+			return nil, fmt.Errorf("%s: policy %q is also defined in %s", file, p.ID, other)
+		}
+		seen[p.ID] = file
+		policies = append(policies, p)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+	return policies, nil
+}
+
+// ParsePolicy parses and validates one policy file.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var f policyFile
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	if f.ID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	if f.Version <= 0 {
+		return nil, fmt.Errorf("policy %s: version must be a positive number", f.ID)
+	}
+	if len(f.Checks) == 0 {
+		return nil, fmt.Errorf("policy %s: at least one check is required", f.ID)
+	}
+
+	sum := sha256.Sum256(data)
+	p := &Policy{
+		ID:          f.ID,
+		Version:     f.Version,
+		Name:        f.Name,
+		Description: f.Description,
+		Digest:      hex.EncodeToString(sum[:]),
+		Definition:  data,
+		labels:      f.AppliesTo.Labels,
+	}
+	if p.Name == "" {
+		p.Name = p.ID
+	}
+	if f.AppliesTo.OS != "" {
+		re, err := regexp.Compile("(?i)" + f.AppliesTo.OS)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: applies_to.os: %w", f.ID, err)
+		}
+		p.os = re
+	}
+
+	ids := map[string]bool{}
+	for i, spec := range f.Checks {
+		if spec.ID == "" {
+			return nil, fmt.Errorf("policy %s: checks[%d]: id is required", f.ID, i)
+		}
+		if ids[spec.ID] {
+			return nil, fmt.Errorf("policy %s: check %s appears twice", f.ID, spec.ID)
+		}
+		ids[spec.ID] = true
+		c, err := compileCheck(spec)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: check %s: %w", f.ID, spec.ID, err)
+		}
+		p.checks = append(p.checks, c)
+	}
+	return p, nil
+}
+
+func compileCheck(spec checkSpec) (check, error) {
+	c := check{id: spec.ID, title: spec.Title, severity: model.SeverityMedium}
+	if c.title == "" {
+		c.title = spec.ID
+	}
+	if spec.Severity != "" {
+		c.severity = strings.ToUpper(spec.Severity)
+		if model.SeverityRank(c.severity) == 0 {
+			return c, fmt.Errorf("unknown severity %q (want low, medium, high or critical)", spec.Severity)
+		}
+	}
+
+	var conditions []string
+	var err error
+	if spec.AppAbsent != nil {
+		conditions = append(conditions, "app_absent")
+		c.eval, err = appAbsent(*spec.AppAbsent)
+	}
+	if spec.AppPresent != nil {
+		conditions = append(conditions, "app_present")
+		c.eval, err = appPresent(*spec.AppPresent)
+	}
+	if spec.OSVersion != nil {
+		conditions = append(conditions, "os_version")
+		c.eval, err = osVersion(*spec.OSVersion)
+	}
+	if spec.FirewallDefaultDeny != "" {
+		conditions = append(conditions, "firewall_default_deny")
+		c.eval, err = firewallDefaultDeny(spec.FirewallDefaultDeny)
+	}
+	if spec.ReportedWithin != "" {
+		conditions = append(conditions, "reported_within")
+		c.eval, err = reportedWithin(spec.ReportedWithin)
+	}
+	if spec.InventoryWithin != "" {
+		conditions = append(conditions, "inventory_within")
+		c.eval, err = inventoryWithin(spec.InventoryWithin)
+	}
+	switch {
+	case len(conditions) == 0:
+		return c, fmt.Errorf("no condition (want one of app_absent, app_present, os_version, firewall_default_deny, reported_within or inventory_within)")
+	case len(conditions) > 1:
+		return c, fmt.Errorf("only one condition is allowed, got %s", strings.Join(conditions, " and "))
+	}
+	return c, err
+}
+
+// parseDuration accepts time.ParseDuration values and whole days ("7d").
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// Applies reports whether the policy covers the agent.
+func (p *Policy) Applies(agent *model.Agent) bool {
+	if !agent.Labels.Match(p.labels) {
+		return false
+	}
+	return p.os == nil || p.os.MatchString(agent.OSName)
+}
+
+// Len returns the number of checks in the policy.
+func (p *Policy) Len() int {
+	return len(p.checks)
+}
+
+// Evaluate runs every check of the policy against inv. The results carry
+// the policy and check identity, status and evidence.
+func (p *Policy) Evaluate(inv *Inventory) []model.ComplianceResult {
+	results := make([]model.ComplianceResult, len(p.checks))
+	for i, c := range p.checks {
+		status, evidence := c.eval(inv)
+		results[i] = model.ComplianceResult{
+			PolicyID:      p.ID,
+			PolicyVersion: p.Version,
+			CheckID:       c.id,
+			Title:         c.title,
+			Severity:      c.severity,
+			Status:        status,
+			Evidence:      evidence,
+		}
+	}
+	return results
+}
@@ -0,0 +1,239 @@
+package compliance_test
+
+import (
+	"agent_server/internal/compliance"
+	"agent_server/internal/model"
+	"agent_server/internal/software"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadFixtures(t *testing.T) map[string]*compliance.Policy {
+	t.Helper()
+	policies, err := compliance.LoadPolicies("testdata/policies")
+	if err != nil {
+		t.Fatalf("LoadPolicies: %v", err)
+	}
+	byID := map[string]*compliance.Policy{}
+	for _, p := range policies {
+		byID[p.ID] = p
+	}
+	return byID
+}
+
+func TestLoadPolicies(t *testing.T) {
+	policies, err := compliance.LoadPolicies("testdata/policies")
+	if err != nil {
+		t.Fatalf("LoadPolicies: %v", err)
+	}
+	// README.txt is skipped; .yaml and .yml files are read and sorted by ID.
+	if len(policies) != 2 || policies[0].ID != "linux-servers" || policies[1].ID != "workstation-baseline" {
+		t.Fatalf("LoadPolicies = %v, want linux-servers and workstation-baseline", policies)
+	}
+
+	linux, baseline := policies[0], policies[1]
+	if linux.Name != "linux-servers" || linux.Version != 1 || linux.Len() != 1 {
+		t.Fatalf("linux-servers = %+v, want the ID as its name and one check", linux)
+	}
+	if baseline.Name != "Workstation baseline" || baseline.Version != 3 || baseline.Len() != 8 ||
+		baseline.Description != "Minimum configuration of office laptops." {
+		t.Fatalf("workstation-baseline = %+v", baseline)
+	}
+	data, err := os.ReadFile("testdata/policies/workstation-baseline.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	if baseline.Digest != hex.EncodeToString(sum[:]) || string(baseline.Definition) != string(data) {
+		t.Fatalf("workstation-baseline digest = %s, want the SHA-256 of the file", baseline.Digest)
+	}
+
+	single, err := compliance.LoadPolicies("testdata/policies/linux-servers.yml")
+	if err != nil || len(single) != 1 || single[0].ID != "linux-servers" {
+		t.Fatalf("LoadPolicies(file) = %v, %v", single, err)
+	}
+	if none, err := compliance.LoadPolicies(""); err != nil || none != nil {
+		t.Fatalf("LoadPolicies(\"\") = %v, %v; want no policies", none, err)
+	}
+	if _, err := compliance.LoadPolicies("testdata/duplicate"); err == nil || !strings.Contains(err.Error(), `policy "baseline" is also defined in`) {
+		t.Fatalf("LoadPolicies(duplicate IDs) = %v", err)
+	}
+}
+
+func TestParsePolicyRejectsInvalidFiles(t *testing.T) {
+	want := map[string]string{
+		"no-id":              "id is required",
+		"zero-version":       "version must be a positive number",
+		"no-checks":          "at least one check is required",
+		"check-without-id":   "checks[0]: id is required",
+		"duplicate-check":    "check checked-in appears twice",
+		"unknown-field":      "field app_absnt not found",
+		"no-condition":       "check nothing: no condition",
+		"two-conditions":     "only one condition is allowed, got reported_within and inventory_within",
+		"unknown-severity":   `unknown severity "urgent"`,
+		"bad-regexp":         "check no-telnet: error parsing regexp",
+		"bad-os-regexp":      "applies_to.os: error parsing regexp",
+		"app-without-name":   "name or product is required",
+		"absent-min-version": "app_absent does not take min_version",
+		"os-without-min":     "os_version.min is required",
+		"unknown-os-field":   `unknown os_version.field "build"`,
+		"bad-direction":      `firewall_default_deny must be inbound or outbound, got "sideways"`,
+		"bad-duration":       `invalid duration "soon"`,
+		"zero-days":          `invalid duration "0d"`,
+	}
+	files, err := filepath.Glob("testdata/invalid/*.yaml")
+	if err != nil || len(files) != len(want) {
+		t.Fatalf("%d invalid fixtures for %d expected errors (%v)", len(files), len(want), err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = compliance.ParsePolicy(data)
+		if err == nil || !strings.Contains(err.Error(), want[name]) {
+			t.Errorf("ParsePolicy(%s) = %v, want an error containing %q", name, err, want[name])
+		}
+	}
+}
+
+func TestPolicyApplies(t *testing.T) {
+	policies := loadFixtures(t)
+	tests := []struct {
+		agent    model.Agent
+		baseline bool
+	}{
+		{model.Agent{OSName: "Windows 11 Pro", Labels: model.Labels{"env": "prod", "team": "sales"}}, true},
+		{model.Agent{OSName: "windows 10 enterprise", Labels: model.Labels{"env": "prod"}}, true},
+		{model.Agent{OSName: "Windows 11 Pro", Labels: model.Labels{"env": "staging"}}, false},
+		{model.Agent{OSName: "Ubuntu", Labels: model.Labels{"env": "prod"}}, false},
+		{model.Agent{OSName: "Windows 11 Pro"}, false},
+	}
+	for _, tt := range tests {
+		if got := policies["workstation-baseline"].Applies(&tt.agent); got != tt.baseline {
+			t.Errorf("workstation-baseline applies to %s %v = %v, want %v", tt.agent.OSName, tt.agent.Labels, got, tt.baseline)
+		}
+		if !policies["linux-servers"].Applies(&tt.agent) {
+			t.Errorf("linux-servers has no applies_to but does not apply to %s %v", tt.agent.OSName, tt.agent.Labels)
+		}
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	policies := loadFixtures(t)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	catalog := software.DefaultRules()
+
+	compliant := &compliance.Inventory{
+		Agent: &model.Agent{OSName: "Windows 11 Pro", OSVersion: "10.0.22631", LastSeen: now.Add(-time.Hour)},
+		FirewallRules: []model.FirewallRule{
+			{Name: "Allow RDP from VPN", Port: "3389", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", Enabled: true, ReportedAt: now.Add(-48 * time.Hour)},
+			{Name: "Block inbound", Port: "ANY", Protocol: "ANY", Action: "DENY", Direction: "DIRECTION_IN", Enabled: true, ReportedAt: now.Add(-48 * time.Hour)},
+		},
+		Apps: []model.InstalledApplication{
+			{Name: "Google Chrome", Version: "119.0.6045.160", Publisher: "Google LLC", ReportedAt: now.Add(-72 * time.Hour)},
+			{Name: "Google Chrome", Version: "121.0.6167.85", Publisher: "Google LLC", ReportedAt: now.Add(-72 * time.Hour)},
+			{Name: "Microsoft Defender", Version: "4.18.24010.12", Publisher: "Microsoft Corporation", ReportedAt: now.Add(-72 * time.Hour)},
+		},
+		Catalog: catalog,
+		Now:     now,
+	}
+	outdated := &compliance.Inventory{
+		Agent: &model.Agent{OSName: "Windows 10 Enterprise", OSVersion: "10.0.19044", LastSeen: now.Add(-48 * time.Hour)},
+		FirewallRules: []model.FirewallRule{
+			{Name: "Block inbound", Port: "ANY", Protocol: "ANY", Action: "DENY", Direction: "DIRECTION_IN", Enabled: false, ReportedAt: now.Add(-10 * 24 * time.Hour)},
+			{Name: "Block outbound TCP", Port: "ANY", Protocol: "TCP", Action: "DENY", Direction: "DIRECTION_OUT", Enabled: true, ReportedAt: now.Add(-10 * 24 * time.Hour)},
+		},
+		Apps: []model.InstalledApplication{
+			{Name: "Telnet Client", Version: "1.0", ReportedAt: now.Add(-10 * 24 * time.Hour)},
+			{Name: "Google Chrome", Version: "119.0.6045.160", Publisher: "Google LLC", ReportedAt: now.Add(-10 * 24 * time.Hour)},
+			{Name: "Defender Pro", Version: "2.0", Publisher: "Not Microsoft", ReportedAt: now.Add(-10 * 24 * time.Hour)},
+		},
+		Catalog: catalog,
+		Now:     now,
+	}
+	linux := &compliance.Inventory{
+		Agent:   &model.Agent{OSName: "Ubuntu", OSVersion: "22.04", KernelVersion: "5.15.0-91-generic", LastSeen: now},
+		Catalog: catalog,
+		Now:     now,
+	}
+
+	type result struct{ status, evidence string }
+	tests := []struct {
+		name   string
+		policy string
+		inv    *compliance.Inventory
+		want   map[string]result
+	}{
+		{"compliant workstation", "workstation-baseline", compliant, map[string]result{
+			"no-telnet":       {model.CompliancePass, "not among 3 reported applications"},
+			"chrome-current":  {model.CompliancePass, "installed: Google Chrome 121.0.6167.85"},
+			"antivirus":       {model.CompliancePass, "installed: Microsoft Defender 4.18.24010.12"},
+			"os-build":        {model.CompliancePass, "Windows 11 Pro os_version 10.0.22631"},
+			"inbound-deny":    {model.CompliancePass, `rule "Block inbound" denies all inbound traffic`},
+			"outbound-deny":   {model.ComplianceFail, "no enabled outbound DENY rule for any port and protocol among 2 reported rules"},
+			"checked-in":      {model.CompliancePass, "last seen at 2026-03-10T11:00:00Z"},
+			"fresh-inventory": {model.CompliancePass, "last inventory report at 2026-03-08T12:00:00Z"},
+		}},
+		{"outdated workstation", "workstation-baseline", outdated, map[string]result{
+			"no-telnet":       {model.ComplianceFail, "installed: Telnet Client 1.0"},
+			"chrome-current":  {model.ComplianceFail, "older than 120.0: Google Chrome 119.0.6045.160"},
+			"antivirus":       {model.ComplianceFail, "not among 3 reported applications"},
+			"os-build":        {model.ComplianceFail, "Windows 10 Enterprise os_version 10.0.19044, below 10.0.19045"},
+			"inbound-deny":    {model.ComplianceFail, "no enabled inbound DENY rule for any port and protocol among 2 reported rules"},
+			"outbound-deny":   {model.ComplianceFail, "no enabled outbound DENY rule for any port and protocol among 2 reported rules"},
+			"checked-in":      {model.ComplianceFail, "last seen at 2026-03-08T12:00:00Z"},
+			"fresh-inventory": {model.ComplianceFail, "last inventory report at 2026-02-28T12:00:00Z"},
+		}},
+		{"linux agent", "workstation-baseline", linux, map[string]result{
+			"no-telnet":       {model.CompliancePass, "not among 0 reported applications"},
+			"chrome-current":  {model.ComplianceFail, "not among 0 reported applications"},
+			"antivirus":       {model.ComplianceFail, "not among 0 reported applications"},
+			"os-build":        {model.ComplianceNotApplicable, `OS is "Ubuntu"`},
+			"inbound-deny":    {model.ComplianceFail, "no enabled inbound DENY rule for any port and protocol among 0 reported rules"},
+			"outbound-deny":   {model.ComplianceFail, "no enabled outbound DENY rule for any port and protocol among 0 reported rules"},
+			"checked-in":      {model.CompliancePass, "last seen at 2026-03-10T12:00:00Z"},
+			"fresh-inventory": {model.ComplianceFail, "last inventory report: never"},
+		}},
+		{"linux kernel", "linux-servers", linux, map[string]result{
+			"kernel": {model.CompliancePass, "Ubuntu kernel_version 5.15.0-91-generic"},
+		}},
+		{"old linux kernel", "linux-servers", &compliance.Inventory{
+			Agent: &model.Agent{OSName: "Ubuntu", KernelVersion: "5.4.0-150-generic"}, Now: now,
+		}, map[string]result{
+			"kernel": {model.ComplianceFail, "Ubuntu kernel_version 5.4.0-150-generic, below 5.15"},
+		}},
+		{"windows kernel", "linux-servers", compliant, map[string]result{
+			"kernel": {model.ComplianceNotApplicable, `OS is "Windows 11 Pro"`},
+		}},
+	}
+	for _, tt := range tests {
+		results := policies[tt.policy].Evaluate(tt.inv)
+		if len(results) != len(tt.want) {
+			t.Fatalf("%s: %d results, want %d", tt.name, len(results), len(tt.want))
+		}
+		for _, r := range results {
+			if r.PolicyID != tt.policy || r.PolicyVersion != policies[tt.policy].Version {
+				t.Errorf("%s: result %+v does not name its policy", tt.name, r)
+			}
+			if got := (result{r.Status, r.Evidence}); got != tt.want[r.CheckID] {
+				t.Errorf("%s: %s = %+v, want %+v", tt.name, r.CheckID, got, tt.want[r.CheckID])
+			}
+		}
+	}
+
+	// Titles and severities default to the check ID and MEDIUM.
+	results := policies["workstation-baseline"].Evaluate(compliant)
+	if r := results[0]; r.Title != "Telnet must not be installed" || r.Severity != model.SeverityHigh {
+		t.Errorf("no-telnet = %q %s", r.Title, r.Severity)
+	}
+	if r := results[2]; r.Title != "antivirus" || r.Severity != model.SeverityMedium {
+		t.Errorf("antivirus = %q %s, want the defaults", r.Title, r.Severity)
+	}
+}
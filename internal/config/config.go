@@ -1,18 +1,46 @@
 package config
 
 import (
+	"agent_server/internal/scripts"
+	"agent_server/internal/updates"
+	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config هو الهيكل الرئيسي الذي يمثل ملف الإعدادات بأكمله
 type Config struct {
-	Database DBConfig `yaml:"db"`
+	Database   DBConfig         `yaml:"db"`
+	Server     ServerConfig     `yaml:"server"`
+	Log        LogConfig        `yaml:"log"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	TLS        TLSConfig        `yaml:"tls"`
+	Retention  RetentionConfig  `yaml:"retention"`
+	Heartbeat  HeartbeatConfig  `yaml:"heartbeat"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Webhooks   WebhookConfig    `yaml:"webhooks"`
+	Events     EventsConfig     `yaml:"events"`
+	Software   SoftwareConfig   `yaml:"software"`
+	Compliance ComplianceConfig `yaml:"compliance"`
+	Clones     ClonesConfig     `yaml:"clones"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Alerts     AlertsConfig     `yaml:"alerts"`
+	Scripts    ScriptsConfig    `yaml:"scripts"`
+	Artifacts  ArtifactsConfig  `yaml:"artifacts"`
+	Updates    UpdatesConfig    `yaml:"updates"`
+	Collection CollectionConfig `yaml:"collection"`
+	Scheduler  SchedulerConfig  `yaml:"scheduler"`
 }
 
 // DBConfig يحتوي على إعدادات الاتصال بقاعدة البيانات
 type DBConfig struct {
+	// Driver يحدد نوع التخزين: postgres (الافتراضي) أو sqlite أو memory
+	Driver string `yaml:"driver"`
+	// Path مسار ملف SQLite عند استخدام driver: sqlite
+	Path string `yaml:"path"`
+
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	User     string `yaml:"user"`
@@ -20,6 +48,307 @@ type DBConfig struct {
 	DBName   string `yaml:"dbname"`
 	SSLMode  string `yaml:"sslmode"`
 	TimeZone string `yaml:"timezone"`
+
+	// MigrateOnStart يطبق الترحيلات المعلقة عند بدء الخادم بدل تشغيل "migrate up" يدويًا
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+}
+
+// ServerConfig يحتوي على إعدادات خادم gRPC ومراقبة الوكلاء
+type ServerConfig struct {
+	ListenAddr                string `yaml:"listen_addr"`
+	HTTPListenAddr            string `yaml:"http_listen_addr"` // بوابة REST/JSON، فارغ = معطلة
+	ReportIntervalSeconds     int    `yaml:"report_interval_seconds"`
+	OfflineGracePeriodSeconds int    `yaml:"offline_grace_period_seconds"`
+	MonitorIntervalSeconds    int    `yaml:"monitor_interval_seconds"`
+}
+
+// LogConfig يحدد مستوى السجلات (debug, info, warn, error)
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+// RateLimitConfig يحدد عدد الطلبات المسموح بها لكل عميل
+type RateLimitConfig struct {
+	Enabled           bool    `yaml:"enabled"`
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+// TLSConfig يحتوي على مسارات شهادة الخادم ومفتاحها
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// RetentionConfig يحدد مدة الاحتفاظ بتقارير الوكلاء القديمة (0 = للأبد)
+type RetentionConfig struct {
+	FirewallRulesDays  int `yaml:"firewall_rules_days"`
+	InstalledAppsDays  int `yaml:"installed_apps_days"`
+	HostInventoryDays  int `yaml:"host_inventory_days"`  // الواجهات والمستخدمون والخدمات والمنافذ المفتوحة
+	ProcessesDays      int `yaml:"processes_days"`       // العمليات تتغير كثيرًا فلها مدة خاصة
+	PatchesDays        int `yaml:"patches_days"`         // التحديثات المثبتة والمعلقة، آخر تقرير لكل وكيل يُحفظ دائمًا
+	CollectedFilesDays int `yaml:"collected_files_days"` // الملفات المجمعة من الوكلاء تُحذف من المخزن ويبقى سجلها
+	JobRunsDays        int `yaml:"job_runs_days"`        // سجل تشغيلات المهام المجدولة بعد انتهائها
+}
+
+// AuthConfig يحتوي على رمز المشرف المطلوب لاستدعاءات الإدارة (فارغ = بدون تحقق)
+type AuthConfig struct {
+	AdminToken string `yaml:"admin_token"`
+}
+
+// HeartbeatConfig يحدد متى تُكتب النبضات المجمعة في الذاكرة إلى قاعدة البيانات
+// تُكتب الدفعة كل flush_interval_ms أو عند وصول عدد الوكلاء المعلقين إلى max_batch_size
+type HeartbeatConfig struct {
+	FlushIntervalMs int `yaml:"flush_interval_ms"`
+	MaxBatchSize    int `yaml:"max_batch_size"`
+}
+
+// WebhookConfig يحدد كيف تُسلم الأحداث من جدول الـ outbox إلى الاشتراكات
+// كل محاولة فاشلة تضاعف الانتظار بدءًا من initial_backoff_seconds حتى max_backoff_seconds
+// وبعد max_attempts يصبح التسليم DEAD وينتظر إعادة التشغيل عبر ReplayWebhookDeliveries
+type WebhookConfig struct {
+	Enabled               bool `yaml:"enabled"`
+	PollIntervalMs        int  `yaml:"poll_interval_ms"`
+	BatchSize             int  `yaml:"batch_size"`
+	MaxAttempts           int  `yaml:"max_attempts"`
+	InitialBackoffSeconds int  `yaml:"initial_backoff_seconds"`
+	MaxBackoffSeconds     int  `yaml:"max_backoff_seconds"`
+	TimeoutSeconds        int  `yaml:"timeout_seconds"`
+}
+
+// PollInterval يعيد الفترة بين كل فحص لجدول الـ outbox
+func (w WebhookConfig) PollInterval() time.Duration {
+	return time.Duration(w.PollIntervalMs) * time.Millisecond
+}
+
+// InitialBackoff يعيد الانتظار بعد أول محاولة فاشلة
+func (w WebhookConfig) InitialBackoff() time.Duration {
+	return time.Duration(w.InitialBackoffSeconds) * time.Second
+}
+
+// MaxBackoff يعيد أقصى انتظار بين محاولتين
+func (w WebhookConfig) MaxBackoff() time.Duration {
+	return time.Duration(w.MaxBackoffSeconds) * time.Second
+}
+
+// Timeout يعيد المهلة القصوى لطلب HTTP واحد
+func (w WebhookConfig) Timeout() time.Duration {
+	return time.Duration(w.TimeoutSeconds) * time.Second
+}
+
+// EventsConfig يحدد سلوك ناقل الأحداث الذي يغذي WatchEvents
+// buffer_size عدد الأحداث المحفوظة في الذاكرة للاستئناف بعد انقطاع الاتصال
+// والمشترك الذي يتأخر بأكثر من subscriber_buffer حدث يُفصل ويستأنف من آخر رقم تسلسلي
+type EventsConfig struct {
+	BufferSize       int `yaml:"buffer_size"`
+	SubscriberBuffer int `yaml:"subscriber_buffer"`
+	// HeartbeatSampleSeconds يرسل نبضة واحدة على الأكثر لكل وكيل خلال هذه المدة (0 = كل نبضة)
+	HeartbeatSampleSeconds int `yaml:"heartbeat_sample_seconds"`
+	// SinkQueueSize عدد الأحداث التي تنتظر مطابقة الثغرات وتحليل الجدار الناري والامتثال في الخلفية
+	// عند امتلاء الطابور ينتظر الناشر مكانًا فيه بدل إسقاط الحدث أو تسليمه قبل ما سبقه
+	SinkQueueSize int `yaml:"sink_queue_size"`
+}
+
+// HeartbeatSample يعيد أقل فترة بين حدثي نبضة لنفس الوكيل
+func (e EventsConfig) HeartbeatSample() time.Duration {
+	return time.Duration(e.HeartbeatSampleSeconds) * time.Second
+}
+
+// SoftwareConfig يحدد ملف قواعد كتالوج البرامج الذي يربط أسماء التطبيقات والناشرين بمنتجات موحدة
+// قواعد الملف تُجرب قبل القواعد المدمجة، والملف يعاد قراءته مع كل إعادة تحميل للإعدادات
+type SoftwareConfig struct {
+	RulesFile string `yaml:"rules_file"` // فارغ = القواعد المدمجة فقط
+}
+
+// ComplianceConfig يحدد سياسات الامتثال التي تُقيّم على مخزون كل وكيل
+// تُقيّم السياسات عند كل تقرير مخزون، وكل evaluate_interval_seconds لكل الوكلاء حتى تفشل الفحوص المعتمدة على الوقت
+// مثل reported_within عندما يتوقف الوكيل عن الإبلاغ
+type ComplianceConfig struct {
+	PoliciesPath            string `yaml:"policies_path"` // ملف سياسة أو مجلد ملفات YAML، فارغ = الامتثال معطل
+	EvaluateIntervalSeconds int    `yaml:"evaluate_interval_seconds"`
+}
+
+// EvaluateInterval يعيد الفترة بين كل تقييم دوري لكل الوكلاء
+func (c ComplianceConfig) EvaluateInterval() time.Duration {
+	return time.Duration(c.EvaluateIntervalSeconds) * time.Second
+}
+
+// ClonesConfig يحدد كشف الوكلاء المستنسخين: نسخ من صورة جهاز تحمل نفس agent_id
+// كل تسجيل يعطي النسخة nonce جديدًا ترسله مع نبضاتها، فالنسخة التي ترسل nonce قديمًا سجلت نسخة أخرى بعدها
+type ClonesConfig struct {
+	// ReissueIdentities يطلب من النسخة ذات الـ nonce القديم إعادة التسجيل ويعطيها agent_id جديدًا
+	ReissueIdentities bool `yaml:"reissue_identities"`
+	// AddressWindowSeconds نبضات تعود إلى عنوان سابق خلال هذه المدة تعني جهازين، 0 = ضعف فترة التقرير
+	AddressWindowSeconds int `yaml:"address_window_seconds"`
+}
+
+// AddressWindow يعيد المدة التي تُعتبر فيها نبضات العناوين المختلفة متداخلة
+func (c ClonesConfig) AddressWindow(reportInterval time.Duration) time.Duration {
+	if c.AddressWindowSeconds > 0 {
+		return time.Duration(c.AddressWindowSeconds) * time.Second
+	}
+	return 2 * reportInterval
+}
+
+// MetricsConfig يحدد تخزين مقاييس الموارد التي يرسلها الوكلاء مع النبضات
+// العينات الخام تُجمع كل rollup_interval_seconds إلى فترات 5 دقائق ثم إلى ساعات، ولكل دقة مدة احتفاظ
+type MetricsConfig struct {
+	RawRetentionHours       int `yaml:"raw_retention_hours"`
+	FiveMinuteRetentionDays int `yaml:"five_minute_retention_days"`
+	HourlyRetentionDays     int `yaml:"hourly_retention_days"`
+	RollupIntervalSeconds   int `yaml:"rollup_interval_seconds"`
+}
+
+// RawRetention يعيد مدة الاحتفاظ بالعينات الخام
+func (m MetricsConfig) RawRetention() time.Duration {
+	return time.Duration(m.RawRetentionHours) * time.Hour
+}
+
+// FiveMinuteRetention يعيد مدة الاحتفاظ بتجميعات الخمس دقائق
+func (m MetricsConfig) FiveMinuteRetention() time.Duration {
+	return time.Duration(m.FiveMinuteRetentionDays) * 24 * time.Hour
+}
+
+// HourlyRetention يعيد مدة الاحتفاظ بتجميعات الساعة
+func (m MetricsConfig) HourlyRetention() time.Duration {
+	return time.Duration(m.HourlyRetentionDays) * 24 * time.Hour
+}
+
+// RollupInterval يعيد الفترة بين كل تجميع للمقاييس وتطبيق الاحتفاظ
+func (m MetricsConfig) RollupInterval() time.Duration {
+	return time.Duration(m.RollupIntervalSeconds) * time.Second
+}
+
+// AlertsConfig يحدد قواعد التنبيه التي يقيّمها الخادم وأين تُرسل إشعاراتها
+// قواعد الحالة والمقاييس والمجموعات تُقيّم كل evaluate_interval_seconds، وقواعد الجدار الناري والتطبيقات عند وصول الحدث
+type AlertsConfig struct {
+	RulesPath               string `yaml:"rules_path"` // ملف قواعد أو مجلد ملفات YAML، فارغ = التنبيه معطل
+	EvaluateIntervalSeconds int    `yaml:"evaluate_interval_seconds"`
+	LogFile                 string `yaml:"log_file"`                // مسار ملف إشعارات log بصيغة JSON lines، فارغ = سجل الخادم
+	ResolvedRetentionDays   int    `yaml:"resolved_retention_days"` // مدة الاحتفاظ بالتنبيهات المنتهية، 0 = للأبد
+}
+
+// EvaluateInterval يعيد الفترة بين كل تقييم لقواعد التنبيه
+func (a AlertsConfig) EvaluateInterval() time.Duration {
+	return time.Duration(a.EvaluateIntervalSeconds) * time.Second
+}
+
+// ResolvedRetention يعيد مدة الاحتفاظ بالتنبيهات المنتهية
+func (a AlertsConfig) ResolvedRetention() time.Duration {
+	return time.Duration(a.ResolvedRetentionDays) * 24 * time.Hour
+}
+
+// ScriptsConfig يحدد مكتبة السكربتات التي تنفذها أوامر RunScript وما يُنفذ منها دون موافقة
+type ScriptsConfig struct {
+	LibraryPath           string   `yaml:"library_path"` // مجلد السكربتات التي يُشار إليها بالاسم، فارغ = سكربتات مضمنة فقط
+	Allowlist             []string `yaml:"allowlist"`    // أسماء سكربتات المكتبة وبصمات sha256:<hex> التي تُنفذ دون موافقة المشرف
+	DefaultTimeoutSeconds int      `yaml:"default_timeout_seconds"`
+	MaxTimeoutSeconds     int      `yaml:"max_timeout_seconds"`
+	MaxOutputBytes        int64    `yaml:"max_output_bytes"` // الحد الأقصى لمخرجات الأمر الواحد، يُحذف ما بعده
+}
+
+// ArtifactsConfig يحدد مكان تخزين الملفات الكبيرة مثل حزم الوكيل خارج قاعدة البيانات
+type ArtifactsConfig struct {
+	Backend string   `yaml:"backend"` // local أو s3
+	Path    string   `yaml:"path"`    // مجلد على القرص المحلي، يُنشأ إن لم يوجد
+	S3      S3Config `yaml:"s3"`
+}
+
+// S3Config يحدد الحاوية في خدمة متوافقة مع S3 عندما يكون backend هو s3
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"` // مثل https://s3.eu-west-1.amazonaws.com أو http://minio:9000
+	Bucket    string `yaml:"bucket"`
+	Region    string `yaml:"region"`
+	Prefix    string `yaml:"prefix"`     // يُضاف قبل كل مفتاح ليتشارك عدة خوادم الحاوية نفسها
+	PathStyle bool   `yaml:"path_style"` // endpoint/bucket/key بدل bucket.endpoint/key، تحتاجه أغلب الخدمات المتوافقة
+	// فارغة = متغيرات البيئة AWS_ACCESS_KEY_ID و AWS_SECRET_ACCESS_KEY
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+}
+
+// UpdatesConfig يحدد حزم تحديث الوكيل التي يرفعها المشرف
+type UpdatesConfig struct {
+	// SigningPublicKey مفتاح ed25519 العام بترميز base64، ولا تُقبل إلا الحزم الموقعة به
+	SigningPublicKey string `yaml:"signing_public_key"`
+	// AllowUnsigned يقبل الحزم دون توقيع عندما لا يوجد مفتاح، للاختبار فقط؛ بدونه تُرفض الحزم والنشر
+	AllowUnsigned   bool  `yaml:"allow_unsigned"`
+	MaxPackageBytes int64 `yaml:"max_package_bytes"`
+}
+
+// CollectionConfig يحدد الملفات التي يطلبها المشرف من الوكلاء (السجلات والإعدادات وتفريغ الذاكرة)
+type CollectionConfig struct {
+	MaxFileBytes int64 `yaml:"max_file_bytes"` // أكبر ملف يمكن طلبه، ويمكن للطلب أن يحدد أقل منه
+}
+
+// SchedulerConfig يحدد مشغل المهام المجدولة الذي يعمل بجانب مراقب الوكلاء
+type SchedulerConfig struct {
+	IntervalSeconds int `yaml:"interval_seconds"` // كل كم ثانية يبحث عن المهام المستحقة ويتابع التشغيلات الجارية
+	// MisfireGraceSeconds تأخر أكبر منه يعني أن الموعد فات أثناء توقف الخادم فتُطبق سياسة catch_up للمهمة
+	MisfireGraceSeconds int `yaml:"misfire_grace_seconds"`
+	// CommandTimeoutSeconds أمر لم ينته بعدها يُحسب فاشلًا ويُلغى إن لم يستلمه الوكيل، فلا يحجز مكانًا من max_concurrency للأبد
+	CommandTimeoutSeconds int `yaml:"command_timeout_seconds"`
+}
+
+// Interval يعيد الفترة بين كل دورتين للمجدول
+func (s SchedulerConfig) Interval() time.Duration {
+	return time.Duration(s.IntervalSeconds) * time.Second
+}
+
+// MisfireGrace يعيد أقصى تأخر يُعد فيه الموعد في وقته
+func (s SchedulerConfig) MisfireGrace() time.Duration {
+	return time.Duration(s.MisfireGraceSeconds) * time.Second
+}
+
+// CommandTimeout يعيد المدة التي ينتظرها المجدول لنتيجة كل أمر
+func (s SchedulerConfig) CommandTimeout() time.Duration {
+	return time.Duration(s.CommandTimeoutSeconds) * time.Second
+}
+
+// DefaultTimeout يعيد مهلة السكربت عندما لا يحددها الطلب
+func (s ScriptsConfig) DefaultTimeout() time.Duration {
+	return time.Duration(s.DefaultTimeoutSeconds) * time.Second
+}
+
+// MaxTimeout يعيد أطول مهلة يمكن طلبها لسكربت
+func (s ScriptsConfig) MaxTimeout() time.Duration {
+	return time.Duration(s.MaxTimeoutSeconds) * time.Second
+}
+
+// FlushInterval يعيد الفترة بين كل كتابة دفعة للنبضات
+func (h HeartbeatConfig) FlushInterval() time.Duration {
+	return time.Duration(h.FlushIntervalMs) * time.Millisecond
+}
+
+// ReportInterval يعيد الفترة التي يطلب الخادم من الوكيل إرسال النبضة خلالها
+func (s ServerConfig) ReportInterval() time.Duration {
+	return time.Duration(s.ReportIntervalSeconds) * time.Second
+}
+
+// OfflineThreshold يعيد المدة التي يعتبر بعدها الوكيل غير متصل
+// إذا لم تُحدد فترة السماح نستخدم 10% من فترة التقرير وبحد أدنى 10 ثوانٍ
+func (s ServerConfig) OfflineThreshold() time.Duration {
+	interval := s.ReportInterval()
+	grace := time.Duration(s.OfflineGracePeriodSeconds) * time.Second
+	if grace <= 0 {
+		grace = interval / 10
+		if grace < 10*time.Second {
+			grace = 10 * time.Second
+		}
+	}
+	return interval + grace
+}
+
+// MonitorInterval يعيد الفترة بين كل فحص للوكلاء غير المتصلين
+func (s ServerConfig) MonitorInterval() time.Duration {
+	return time.Duration(s.MonitorIntervalSeconds) * time.Second
+}
+
+// Default يعيد إعدادات افتراضية كاملة دون قراءة ملف (للأدوات التي تشغل خادمًا داخل العملية)
+func Default() *Config {
+	c := &Config{}
+	c.applyDefaults()
+	return c
 }
 
 // LoadConfig يقرأ ملف الإعدادات من المسار المحدد ويقوم بتحليله
@@ -37,5 +366,213 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	config.applyDefaults()
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
+
+// applyDefaults يملأ القيم غير المحددة بالقيم الافتراضية السابقة للخادم
+func (c *Config) applyDefaults() {
+	if c.Database.Driver == "" {
+		c.Database.Driver = "postgres"
+	}
+	if c.Server.ListenAddr == "" {
+		c.Server.ListenAddr = ":50051"
+	}
+	if c.Server.ReportIntervalSeconds == 0 {
+		c.Server.ReportIntervalSeconds = 300 // 5 minutes
+	}
+	if c.Server.MonitorIntervalSeconds == 0 {
+		c.Server.MonitorIntervalSeconds = 60
+	}
+	if c.Heartbeat.FlushIntervalMs == 0 {
+		c.Heartbeat.FlushIntervalMs = 1000
+	}
+	if c.Heartbeat.MaxBatchSize == 0 {
+		c.Heartbeat.MaxBatchSize = 5000
+	}
+	if c.Webhooks.PollIntervalMs == 0 {
+		c.Webhooks.PollIntervalMs = 1000
+	}
+	if c.Webhooks.BatchSize == 0 {
+		c.Webhooks.BatchSize = 100
+	}
+	if c.Webhooks.MaxAttempts == 0 {
+		c.Webhooks.MaxAttempts = 10
+	}
+	if c.Webhooks.InitialBackoffSeconds == 0 {
+		c.Webhooks.InitialBackoffSeconds = 10
+	}
+	if c.Webhooks.MaxBackoffSeconds == 0 {
+		c.Webhooks.MaxBackoffSeconds = 3600
+	}
+	if c.Webhooks.TimeoutSeconds == 0 {
+		c.Webhooks.TimeoutSeconds = 10
+	}
+	if c.Events.BufferSize == 0 {
+		c.Events.BufferSize = 10000
+	}
+	if c.Events.SubscriberBuffer == 0 {
+		c.Events.SubscriberBuffer = 1000
+	}
+	if c.Events.SinkQueueSize == 0 {
+		c.Events.SinkQueueSize = 10000
+	}
+	if c.Compliance.EvaluateIntervalSeconds == 0 {
+		c.Compliance.EvaluateIntervalSeconds = 3600
+	}
+	if c.Metrics.RawRetentionHours == 0 {
+		c.Metrics.RawRetentionHours = 48
+	}
+	if c.Metrics.FiveMinuteRetentionDays == 0 {
+		c.Metrics.FiveMinuteRetentionDays = 30
+	}
+	if c.Metrics.HourlyRetentionDays == 0 {
+		c.Metrics.HourlyRetentionDays = 400
+	}
+	if c.Metrics.RollupIntervalSeconds == 0 {
+		c.Metrics.RollupIntervalSeconds = 300
+	}
+	if c.Alerts.EvaluateIntervalSeconds == 0 {
+		c.Alerts.EvaluateIntervalSeconds = 30
+	}
+	if c.Scripts.DefaultTimeoutSeconds == 0 {
+		c.Scripts.DefaultTimeoutSeconds = 300
+	}
+	if c.Scripts.MaxTimeoutSeconds == 0 {
+		c.Scripts.MaxTimeoutSeconds = 3600
+	}
+	if c.Scripts.MaxOutputBytes == 0 {
+		c.Scripts.MaxOutputBytes = 1 << 20
+	}
+	if c.Artifacts.Backend == "" {
+		c.Artifacts.Backend = "local"
+	}
+	if c.Artifacts.Path == "" {
+		c.Artifacts.Path = "artifacts"
+	}
+	if c.Collection.MaxFileBytes == 0 {
+		c.Collection.MaxFileBytes = 4 << 30
+	}
+	if c.Updates.MaxPackageBytes == 0 {
+		c.Updates.MaxPackageBytes = 512 << 20
+	}
+	if c.Scheduler.IntervalSeconds == 0 {
+		c.Scheduler.IntervalSeconds = 15
+	}
+	if c.Scheduler.MisfireGraceSeconds == 0 {
+		c.Scheduler.MisfireGraceSeconds = 120
+	}
+	if c.Scheduler.CommandTimeoutSeconds == 0 {
+		c.Scheduler.CommandTimeoutSeconds = 3600
+	}
+	if c.Log.Level == "" {
+		c.Log.Level = "info"
+	}
+	if c.RateLimit.Enabled && c.RateLimit.Burst == 0 {
+		c.RateLimit.Burst = int(c.RateLimit.RequestsPerSecond) + 1
+	}
+}
+
+// validate يتحقق من أن القيم المقروءة منطقية قبل استخدامها
+func (c *Config) validate() error {
+	switch c.Database.Driver {
+	case "postgres", "memory":
+	case "sqlite":
+		if c.Database.Path == "" {
+			return fmt.Errorf("db.path is required when db.driver is sqlite")
+		}
+	default:
+		return fmt.Errorf("unknown db.driver %q", c.Database.Driver)
+	}
+	if c.Server.ReportIntervalSeconds < 0 || c.Server.OfflineGracePeriodSeconds < 0 || c.Server.MonitorIntervalSeconds < 0 {
+		return fmt.Errorf("server intervals must not be negative")
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("unknown log level %q", c.Log.Level)
+	}
+	if c.RateLimit.Enabled && c.RateLimit.RequestsPerSecond <= 0 {
+		return fmt.Errorf("rate_limit.requests_per_second must be positive when rate limiting is enabled")
+	}
+	if c.TLS.Enabled && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return fmt.Errorf("tls.cert_file and tls.key_file are required when TLS is enabled")
+	}
+	if c.Heartbeat.FlushIntervalMs < 0 || c.Heartbeat.MaxBatchSize < 0 {
+		return fmt.Errorf("heartbeat settings must not be negative")
+	}
+	r := c.Retention
+	if r.FirewallRulesDays < 0 || r.InstalledAppsDays < 0 || r.HostInventoryDays < 0 || r.ProcessesDays < 0 || r.PatchesDays < 0 || r.CollectedFilesDays < 0 || r.JobRunsDays < 0 {
+		return fmt.Errorf("retention days must not be negative")
+	}
+	w := c.Webhooks
+	if w.PollIntervalMs < 0 || w.BatchSize < 0 || w.MaxAttempts < 0 || w.InitialBackoffSeconds < 0 || w.MaxBackoffSeconds < 0 || w.TimeoutSeconds < 0 {
+		return fmt.Errorf("webhook settings must not be negative")
+	}
+	if w.MaxBackoffSeconds < w.InitialBackoffSeconds {
+		return fmt.Errorf("webhooks.max_backoff_seconds must not be smaller than webhooks.initial_backoff_seconds")
+	}
+	if c.Events.BufferSize < 0 || c.Events.SubscriberBuffer < 0 || c.Events.HeartbeatSampleSeconds < 0 || c.Events.SinkQueueSize < 0 {
+		return fmt.Errorf("events settings must not be negative")
+	}
+	if c.Compliance.EvaluateIntervalSeconds < 0 {
+		return fmt.Errorf("compliance.evaluate_interval_seconds must not be negative")
+	}
+	if c.Clones.AddressWindowSeconds < 0 {
+		return fmt.Errorf("clones.address_window_seconds must not be negative")
+	}
+	m := c.Metrics
+	if m.RawRetentionHours < 0 || m.FiveMinuteRetentionDays < 0 || m.HourlyRetentionDays < 0 || m.RollupIntervalSeconds < 0 {
+		return fmt.Errorf("metrics settings must not be negative")
+	}
+	// العينات الخام يجب أن تبقى حتى تُجمع، والتجميع يتأخر حتى دورة rollup كاملة
+	if m.RawRetention() < 2*m.RollupInterval()+time.Hour {
+		return fmt.Errorf("metrics.raw_retention_hours must keep raw samples for at least an hour longer than two rollup intervals")
+	}
+	if c.Alerts.EvaluateIntervalSeconds < 0 || c.Alerts.ResolvedRetentionDays < 0 {
+		return fmt.Errorf("alerts settings must not be negative")
+	}
+	sc := c.Scripts
+	if sc.DefaultTimeoutSeconds < 0 || sc.MaxTimeoutSeconds < 0 || sc.MaxOutputBytes < 0 {
+		return fmt.Errorf("scripts settings must not be negative")
+	}
+	if sc.MaxTimeoutSeconds < sc.DefaultTimeoutSeconds {
+		return fmt.Errorf("scripts.max_timeout_seconds must not be smaller than scripts.default_timeout_seconds")
+	}
+	if err := scripts.ValidateAllowlist(sc.Allowlist); err != nil {
+		return fmt.Errorf("scripts.allowlist: %w", err)
+	}
+	switch c.Artifacts.Backend {
+	case "local":
+	case "s3":
+		if c.Artifacts.S3.Endpoint == "" || c.Artifacts.S3.Bucket == "" {
+			return fmt.Errorf("artifacts.s3.endpoint and artifacts.s3.bucket are required when artifacts.backend is s3")
+		}
+	default:
+		return fmt.Errorf("unknown artifacts.backend %q", c.Artifacts.Backend)
+	}
+	if c.Collection.MaxFileBytes < 0 {
+		return fmt.Errorf("collection.max_file_bytes must not be negative")
+	}
+	if c.Updates.MaxPackageBytes < 0 {
+		return fmt.Errorf("updates.max_package_bytes must not be negative")
+	}
+	sch := c.Scheduler
+	if sch.IntervalSeconds < 0 || sch.MisfireGraceSeconds < 0 || sch.CommandTimeoutSeconds < 0 {
+		return fmt.Errorf("scheduler settings must not be negative")
+	}
+	// وإلا بدت المواعيد العادية فائتة لأن المجدول لا يراها إلا في دورته التالية
+	if sch.MisfireGraceSeconds < sch.IntervalSeconds {
+		return fmt.Errorf("scheduler.misfire_grace_seconds must not be smaller than scheduler.interval_seconds")
+	}
+	if c.Updates.SigningPublicKey != "" {
+		if _, err := updates.ParsePublicKey(c.Updates.SigningPublicKey); err != nil {
+			return fmt.Errorf("updates.signing_public_key: %w", err)
+		}
+	}
+	return nil
+}
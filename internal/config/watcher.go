@@ -0,0 +1,158 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Provider يعطي نسخة حالية (snapshot) من الإعدادات
+// المكونات التي تدعم التحديث أثناء التشغيل تقرأ منه بدل الثوابت
+type Provider interface {
+	Current() *Config
+}
+
+type staticProvider struct {
+	cfg *Config
+}
+
+// StaticProvider يغلف إعدادات ثابتة لا تتغير (مفيد للأدوات والاختبارات)
+func StaticProvider(cfg *Config) Provider {
+	return staticProvider{cfg: cfg}
+}
+
+func (p staticProvider) Current() *Config { return p.cfg }
+
+// Manager يحتفظ بالإعدادات الحالية ويعيد تحميلها عند تغير الملف أو عند وصول SIGHUP
+type Manager struct {
+	path    string
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex
+	subscribers []func(old, updated *Config)
+}
+
+// NewManager يحمّل ملف الإعدادات لأول مرة ويعيد مديرًا جاهزًا للمراقبة
+func NewManager(path string) (*Manager, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{path: path}
+	m.current.Store(cfg)
+	return m, nil
+}
+
+// Current يعيد آخر إعدادات تم تطبيقها بنجاح
+func (m *Manager) Current() *Config {
+	return m.current.Load()
+}
+
+// Subscribe يسجل دالة تُستدعى بعد كل إعادة تحميل ناجحة
+func (m *Manager) Subscribe(fn func(old, updated *Config)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Reload يعيد قراءة الملف ويطبق التغييرات الآمنة فقط
+// إذا احتوى الملف على تغيير غير آمن (مثل عنوان قاعدة البيانات) يُرفض بالكامل
+func (m *Manager) Reload() error {
+	updated, err := LoadConfig(m.path)
+	if err != nil {
+		return fmt.Errorf("failed to reload config: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.current.Load()
+	if err := checkUnsafeChanges(old, updated); err != nil {
+		return err
+	}
+	m.current.Store(updated)
+
+	for _, fn := range m.subscribers {
+		fn(old, updated)
+	}
+	return nil
+}
+
+// Watch يراقب ملف الإعدادات وإشارة SIGHUP حتى يتم إلغاء السياق
+func (m *Manager) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// نراقب المجلد وليس الملف لأن المحررات غالبًا تستبدل الملف بملف جديد
+	if err := watcher.Add(filepath.Dir(m.path)); err != nil {
+		return err
+	}
+	target := filepath.Clean(m.path)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			log.Println("Received SIGHUP, reloading configuration...")
+			m.reloadAndLog()
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(ev.Name) != target || !ev.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+			log.Printf("Config file %s changed, reloading configuration...", m.path)
+			m.reloadAndLog()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("❌ Config watcher error: %v", err)
+		}
+	}
+}
+
+func (m *Manager) reloadAndLog() {
+	if err := m.Reload(); err != nil {
+		log.Printf("❌ Configuration change rejected, keeping previous settings: %v", err)
+		return
+	}
+	log.Println("✅ Configuration reloaded successfully.")
+}
+
+// checkUnsafeChanges يرفض التغييرات التي تتطلب إعادة تشغيل الخادم
+func checkUnsafeChanges(old, updated *Config) error {
+	if old.Database != updated.Database {
+		return fmt.Errorf("database settings cannot be changed at runtime, restart the server to apply them")
+	}
+	if old.Server.ListenAddr != updated.Server.ListenAddr {
+		return fmt.Errorf("server.listen_addr cannot be changed at runtime, restart the server to apply it")
+	}
+	if old.Server.HTTPListenAddr != updated.Server.HTTPListenAddr {
+		return fmt.Errorf("server.http_listen_addr cannot be changed at runtime, restart the server to apply it")
+	}
+	if old.Events.BufferSize != updated.Events.BufferSize {
+		return fmt.Errorf("events.buffer_size cannot be changed at runtime, restart the server to apply it")
+	}
+	if old.TLS.Enabled != updated.TLS.Enabled {
+		return fmt.Errorf("tls.enabled cannot be toggled at runtime, restart the server to apply it")
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// internal/export/export.go
+
+// Package export encodes fleet data as CSV, JSON Lines or Parquet one row at
+// a time, so that exports of large fleets never hold a whole dataset in
+// memory.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Datasets that can be exported.
+const (
+	Agents        = "agents"
+	FirewallRules = "firewall_rules"
+	InstalledApps = "installed_apps"
+	StatusHistory = "status_history"
+
+	NetworkInterfaces = "network_interfaces"
+	LocalUsers        = "local_users"
+	LocalGroups       = "local_groups"
+	Services          = "services"
+	ListeningSockets  = "listening_sockets"
+	Processes         = "processes"
+)
+
+// Output formats.
+const (
+	CSV     = "csv"
+	JSONL   = "jsonl"
+	Parquet = "parquet"
+)
+
+// parquetRowGroupSize bounds the rows a Parquet writer buffers before it
+// writes a row group to the output.
+const parquetRowGroupSize = 10000
+
+// Writer encodes rows of type T to an output stream. Close must be called to
+// flush the last rows; for Parquet it also writes the file footer.
+type Writer[T any] interface {
+	Write(row *T) error
+	Close() error
+}
+
+// NewWriter returns a Writer that encodes rows of T to w in format. The
+// column names are the json tags of T's fields.
+func NewWriter[T any](w io.Writer, format string) (Writer[T], error) {
+	switch format {
+	case CSV:
+		return newCSVWriter[T](w), nil
+	case JSONL:
+		buf := bufio.NewWriter(w)
+		return &jsonlWriter[T]{buf: buf, enc: json.NewEncoder(buf)}, nil
+	case Parquet:
+		return &parquetWriter[T]{w: parquet.NewGenericWriter[T](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize))}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+type csvWriter[T any] struct {
+	w      *csv.Writer
+	header []string
+	record []string
+}
+
+func newCSVWriter[T any](w io.Writer) *csvWriter[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	header := make([]string, t.NumField())
+	for i := range header {
+		header[i] = columnName(t.Field(i))
+	}
+	return &csvWriter[T]{w: csv.NewWriter(w), header: header, record: make([]string, len(header))}
+}
+
+func (c *csvWriter[T]) Write(row *T) error {
+	if c.header != nil {
+		if err := c.w.Write(c.header); err != nil {
+			return err
+		}
+		c.header = nil
+	}
+	v := reflect.ValueOf(row).Elem()
+	for i := range c.record {
+		c.record[i] = formatValue(v.Field(i))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter[T]) Close() error {
+	if c.header != nil {
+		// No rows: still write the header so the file has its columns.
+		if err := c.w.Write(c.header); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// columnName is the json tag of f, or its Go name when it has none.
+func columnName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// formatValue renders a row field as a CSV cell. Times use RFC 3339 in UTC;
+// nil pointers and the zero time are left empty.
+func formatValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return ""
+		}
+		return formatValue(v.Elem())
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+type jsonlWriter[T any] struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter[T]) Write(row *T) error {
+	return j.enc.Encode(row)
+}
+
+func (j *jsonlWriter[T]) Close() error {
+	return j.buf.Flush()
+}
+
+type parquetWriter[T any] struct {
+	w *parquet.GenericWriter[T]
+}
+
+func (p *parquetWriter[T]) Write(row *T) error {
+	_, err := p.w.Write([]T{*row})
+	return err
+}
+
+func (p *parquetWriter[T]) Close() error {
+	return p.w.Close()
+}
@@ -0,0 +1,324 @@
+// internal/export/rows.go
+
+package export
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"agent_server/internal/model"
+)
+
+// AgentRow is one agent in the agents dataset.
+type AgentRow struct {
+	AgentID       string    `json:"agent_id" parquet:"agent_id"`
+	Hostname      string    `json:"hostname" parquet:"hostname"`
+	OSName        string    `json:"os_name" parquet:"os_name"`
+	OSVersion     string    `json:"os_version" parquet:"os_version"`
+	KernelVersion string    `json:"kernel_version" parquet:"kernel_version"`
+	CPUCores      int32     `json:"cpu_cores" parquet:"cpu_cores"`
+	MemoryGB      float64   `json:"memory_gb" parquet:"memory_gb"`
+	DiskSpaceGB   float64   `json:"disk_space_gb" parquet:"disk_space_gb"`
+	Status        string    `json:"status" parquet:"status"`
+	LastSeen      time.Time `json:"last_seen" parquet:"last_seen"`
+	LastKnownIP   string    `json:"last_known_ip" parquet:"last_known_ip"`
+	LastPeerIP    string    `json:"last_peer_ip" parquet:"last_peer_ip"`
+	Labels        string    `json:"labels" parquet:"labels"`
+	RegisteredAt  time.Time `json:"registered_at" parquet:"registered_at"`
+}
+
+// FirewallRuleRow is one rule from an agent's latest firewall report.
+type FirewallRuleRow struct {
+	AgentID    string    `json:"agent_id" parquet:"agent_id"`
+	Hostname   string    `json:"hostname" parquet:"hostname"`
+	Labels     string    `json:"labels" parquet:"labels"`
+	Name       string    `json:"name" parquet:"name"`
+	Port       string    `json:"port" parquet:"port"`
+	Protocol   string    `json:"protocol" parquet:"protocol"`
+	Action     string    `json:"action" parquet:"action"`
+	Direction  string    `json:"direction" parquet:"direction"`
+	Enabled    bool      `json:"enabled" parquet:"enabled"`
+	ReportedAt time.Time `json:"reported_at" parquet:"reported_at"`
+}
+
+// InstalledAppRow is one application from an agent's latest inventory report.
+type InstalledAppRow struct {
+	AgentID     string     `json:"agent_id" parquet:"agent_id"`
+	Hostname    string     `json:"hostname" parquet:"hostname"`
+	Labels      string     `json:"labels" parquet:"labels"`
+	Name        string     `json:"name" parquet:"name"`
+	Version     string     `json:"version" parquet:"version"`
+	Publisher   string     `json:"publisher" parquet:"publisher"`
+	InstallDate *time.Time `json:"install_date" parquet:"install_date,optional"` // nil when the agent did not report it
+	ReportedAt  time.Time  `json:"reported_at" parquet:"reported_at"`
+}
+
+// StatusChangeRow is one agent status transition.
+type StatusChangeRow struct {
+	AgentID    string    `json:"agent_id" parquet:"agent_id"`
+	Hostname   string    `json:"hostname" parquet:"hostname"`
+	Labels     string    `json:"labels" parquet:"labels"`
+	FromStatus string    `json:"from_status" parquet:"from_status"`
+	ToStatus   string    `json:"to_status" parquet:"to_status"`
+	ChangedAt  time.Time `json:"changed_at" parquet:"changed_at"`
+}
+
+// NewAgentRow converts a stored agent to an export row.
+func NewAgentRow(a *model.Agent) *AgentRow {
+	return &AgentRow{
+		AgentID:       a.AgentID,
+		Hostname:      a.Hostname,
+		OSName:        a.OSName,
+		OSVersion:     a.OSVersion,
+		KernelVersion: a.KernelVersion,
+		CPUCores:      a.CPUCores,
+		MemoryGB:      a.MemoryGB,
+		DiskSpaceGB:   a.DiskSpaceGB,
+		Status:        a.Status,
+		LastSeen:      a.LastSeen.UTC(),
+		LastKnownIP:   a.LastKnownIP,
+		LastPeerIP:    a.LastPeerIP,
+		Labels:        FormatLabels(a.Labels),
+		RegisteredAt:  a.CreatedAt.UTC(),
+	}
+}
+
+// NewFirewallRuleRow converts a current firewall rule to an export row.
+func NewFirewallRuleRow(r *model.AgentFirewallRule) *FirewallRuleRow {
+	return &FirewallRuleRow{
+		AgentID:    r.AgentID,
+		Hostname:   r.Hostname,
+		Labels:     FormatLabels(r.Labels),
+		Name:       r.Name,
+		Port:       r.Port,
+		Protocol:   r.Protocol,
+		Action:     r.Action,
+		Direction:  r.Direction,
+		Enabled:    r.Enabled,
+		ReportedAt: r.ReportedAt.UTC(),
+	}
+}
+
+// NewInstalledAppRow converts a current installed application to an export row.
+func NewInstalledAppRow(a *model.AgentInstalledApp) *InstalledAppRow {
+	return &InstalledAppRow{
+		AgentID:     a.AgentID,
+		Hostname:    a.Hostname,
+		Labels:      FormatLabels(a.Labels),
+		Name:        a.Name,
+		Version:     a.Version,
+		Publisher:   a.Publisher,
+		InstallDate: optionalTime(a.InstallDate),
+		ReportedAt:  a.ReportedAt.UTC(),
+	}
+}
+
+// NewStatusChangeRow converts a status transition to an export row.
+func NewStatusChangeRow(c *model.AgentStatusChangeRow) *StatusChangeRow {
+	return &StatusChangeRow{
+		AgentID:    c.AgentID,
+		Hostname:   c.Hostname,
+		Labels:     FormatLabels(c.Labels),
+		FromStatus: c.FromStatus,
+		ToStatus:   c.ToStatus,
+		ChangedAt:  c.ChangedAt.UTC(),
+	}
+}
+
+// The extended inventory rows are items from an agent's latest report of
+// each kind. Lists such as IP addresses and group members are joined with
+// commas, like labels.
+
+// NetworkInterfaceRow is one network interface of an agent.
+type NetworkInterfaceRow struct {
+	AgentID     string    `json:"agent_id" parquet:"agent_id"`
+	Hostname    string    `json:"hostname" parquet:"hostname"`
+	Labels      string    `json:"labels" parquet:"labels"`
+	Name        string    `json:"name" parquet:"name"`
+	MAC         string    `json:"mac" parquet:"mac"`
+	IPAddresses string    `json:"ip_addresses" parquet:"ip_addresses"`
+	MTU         int32     `json:"mtu" parquet:"mtu"`
+	Up          bool      `json:"up" parquet:"up"`
+	ReportedAt  time.Time `json:"reported_at" parquet:"reported_at"`
+}
+
+// LocalUserRow is one local user account of an agent.
+type LocalUserRow struct {
+	AgentID    string     `json:"agent_id" parquet:"agent_id"`
+	Hostname   string     `json:"hostname" parquet:"hostname"`
+	Labels     string     `json:"labels" parquet:"labels"`
+	Name       string     `json:"name" parquet:"name"`
+	UID        string     `json:"uid" parquet:"uid"`
+	FullName   string     `json:"full_name" parquet:"full_name"`
+	Groups     string     `json:"groups" parquet:"groups"`
+	Disabled   bool       `json:"disabled" parquet:"disabled"`
+	LastLogon  *time.Time `json:"last_logon" parquet:"last_logon,optional"`
+	ReportedAt time.Time  `json:"reported_at" parquet:"reported_at"`
+}
+
+// LocalGroupRow is one local group of an agent.
+type LocalGroupRow struct {
+	AgentID    string    `json:"agent_id" parquet:"agent_id"`
+	Hostname   string    `json:"hostname" parquet:"hostname"`
+	Labels     string    `json:"labels" parquet:"labels"`
+	Name       string    `json:"name" parquet:"name"`
+	GID        string    `json:"gid" parquet:"gid"`
+	Members    string    `json:"members" parquet:"members"`
+	ReportedAt time.Time `json:"reported_at" parquet:"reported_at"`
+}
+
+// ServiceRow is one system service of an agent.
+type ServiceRow struct {
+	AgentID     string    `json:"agent_id" parquet:"agent_id"`
+	Hostname    string    `json:"hostname" parquet:"hostname"`
+	Labels      string    `json:"labels" parquet:"labels"`
+	Name        string    `json:"name" parquet:"name"`
+	DisplayName string    `json:"display_name" parquet:"display_name"`
+	StartType   string    `json:"start_type" parquet:"start_type"`
+	State       string    `json:"state" parquet:"state"`
+	Account     string    `json:"account" parquet:"account"`
+	BinaryPath  string    `json:"binary_path" parquet:"binary_path"`
+	ReportedAt  time.Time `json:"reported_at" parquet:"reported_at"`
+}
+
+// ListeningSocketRow is one listening socket of an agent.
+type ListeningSocketRow struct {
+	AgentID     string    `json:"agent_id" parquet:"agent_id"`
+	Hostname    string    `json:"hostname" parquet:"hostname"`
+	Labels      string    `json:"labels" parquet:"labels"`
+	Protocol    string    `json:"protocol" parquet:"protocol"`
+	Address     string    `json:"address" parquet:"address"`
+	Port        int32     `json:"port" parquet:"port"`
+	PID         int32     `json:"pid" parquet:"pid"`
+	ProcessName string    `json:"process_name" parquet:"process_name"`
+	ReportedAt  time.Time `json:"reported_at" parquet:"reported_at"`
+}
+
+// ProcessRow is one running process of an agent.
+type ProcessRow struct {
+	AgentID     string     `json:"agent_id" parquet:"agent_id"`
+	Hostname    string     `json:"hostname" parquet:"hostname"`
+	Labels      string     `json:"labels" parquet:"labels"`
+	PID         int32      `json:"pid" parquet:"pid"`
+	ParentPID   int32      `json:"parent_pid" parquet:"parent_pid"`
+	Name        string     `json:"name" parquet:"name"`
+	User        string     `json:"user" parquet:"user"`
+	CommandLine string     `json:"command_line" parquet:"command_line"`
+	StartedAt   *time.Time `json:"started_at" parquet:"started_at,optional"`
+	CPUPercent  float64    `json:"cpu_percent" parquet:"cpu_percent"`
+	MemoryMB    float64    `json:"memory_mb" parquet:"memory_mb"`
+	ReportedAt  time.Time  `json:"reported_at" parquet:"reported_at"`
+}
+
+// NewNetworkInterfaceRow converts a current network interface to an export row.
+func NewNetworkInterfaceRow(i *model.AgentInventory[model.NetworkInterface]) *NetworkInterfaceRow {
+	return &NetworkInterfaceRow{
+		AgentID:     i.AgentKey,
+		Hostname:    i.Hostname,
+		Labels:      FormatLabels(i.Labels),
+		Name:        i.Item.Name,
+		MAC:         i.Item.MAC,
+		IPAddresses: strings.Join(i.Item.IPAddresses, ","),
+		MTU:         i.Item.MTU,
+		Up:          i.Item.Up,
+		ReportedAt:  i.Item.ReportedAt.UTC(),
+	}
+}
+
+// NewLocalUserRow converts a current local user to an export row.
+func NewLocalUserRow(u *model.AgentInventory[model.LocalUser]) *LocalUserRow {
+	return &LocalUserRow{
+		AgentID:    u.AgentKey,
+		Hostname:   u.Hostname,
+		Labels:     FormatLabels(u.Labels),
+		Name:       u.Item.Name,
+		UID:        u.Item.UID,
+		FullName:   u.Item.FullName,
+		Groups:     strings.Join(u.Item.Groups, ","),
+		Disabled:   u.Item.Disabled,
+		LastLogon:  optionalTime(u.Item.LastLogon),
+		ReportedAt: u.Item.ReportedAt.UTC(),
+	}
+}
+
+// NewLocalGroupRow converts a current local group to an export row.
+func NewLocalGroupRow(g *model.AgentInventory[model.LocalGroup]) *LocalGroupRow {
+	return &LocalGroupRow{
+		AgentID:    g.AgentKey,
+		Hostname:   g.Hostname,
+		Labels:     FormatLabels(g.Labels),
+		Name:       g.Item.Name,
+		GID:        g.Item.GID,
+		Members:    strings.Join(g.Item.Members, ","),
+		ReportedAt: g.Item.ReportedAt.UTC(),
+	}
+}
+
+// NewServiceRow converts a current system service to an export row.
+func NewServiceRow(s *model.AgentInventory[model.SystemService]) *ServiceRow {
+	return &ServiceRow{
+		AgentID:     s.AgentKey,
+		Hostname:    s.Hostname,
+		Labels:      FormatLabels(s.Labels),
+		Name:        s.Item.Name,
+		DisplayName: s.Item.DisplayName,
+		StartType:   s.Item.StartType,
+		State:       s.Item.State,
+		Account:     s.Item.Account,
+		BinaryPath:  s.Item.BinaryPath,
+		ReportedAt:  s.Item.ReportedAt.UTC(),
+	}
+}
+
+// NewListeningSocketRow converts a current listening socket to an export row.
+func NewListeningSocketRow(s *model.AgentInventory[model.ListeningSocket]) *ListeningSocketRow {
+	return &ListeningSocketRow{
+		AgentID:     s.AgentKey,
+		Hostname:    s.Hostname,
+		Labels:      FormatLabels(s.Labels),
+		Protocol:    s.Item.Protocol,
+		Address:     s.Item.Address,
+		Port:        s.Item.Port,
+		PID:         s.Item.PID,
+		ProcessName: s.Item.ProcessName,
+		ReportedAt:  s.Item.ReportedAt.UTC(),
+	}
+}
+
+// NewProcessRow converts a current running process to an export row.
+func NewProcessRow(p *model.AgentInventory[model.RunningProcess]) *ProcessRow {
+	return &ProcessRow{
+		AgentID:     p.AgentKey,
+		Hostname:    p.Hostname,
+		Labels:      FormatLabels(p.Labels),
+		PID:         p.Item.PID,
+		ParentPID:   p.Item.ParentPID,
+		Name:        p.Item.Name,
+		User:        p.Item.User,
+		CommandLine: p.Item.CommandLine,
+		StartedAt:   optionalTime(p.Item.StartedAt),
+		CPUPercent:  p.Item.CPUPercent,
+		MemoryMB:    p.Item.MemoryMB,
+		ReportedAt:  p.Item.ReportedAt.UTC(),
+	}
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// FormatLabels renders labels as comma-separated key=value pairs sorted by
+// key, so that every format has the same single labels column.
+func FormatLabels(labels model.Labels) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
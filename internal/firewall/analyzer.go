@@ -0,0 +1,140 @@
+// internal/firewall/analyzer.go
+
+package firewall
+
+import (
+	"fmt"
+	"strconv"
+
+	"agent_server/internal/model"
+)
+
+// Rule actions and directions as stored in model.FirewallRule.
+const (
+	actionAllow      = "ALLOW"
+	actionDeny       = "DENY"
+	directionInbound = "DIRECTION_IN"
+)
+
+// RiskyPort is a TCP port that should not be reachable from any address.
+type RiskyPort struct {
+	Port     int
+	Service  string
+	Severity string
+}
+
+// RiskyPorts are checked for inbound exposure by Analyze.
+var RiskyPorts = []RiskyPort{
+	{22, "SSH", model.SeverityHigh},
+	{445, "SMB", model.SeverityCritical},
+	{3389, "RDP", model.SeverityHigh},
+}
+
+// rule is an enabled rule with its port and protocol parsed.
+type rule struct {
+	model.FirewallRule
+	protocol string
+	ports    PortSet
+}
+
+// covers reports whether r matches all the traffic that other matches.
+func (r *rule) covers(other *rule) bool {
+	return r.Direction == other.Direction && protocolCovers(r.protocol, other.protocol) && r.ports.Covers(other.ports)
+}
+
+// Analyze returns the findings for one report. Rules are evaluated in the
+// order the agent reported them and the first matching rule wins; disabled
+// rules are ignored. firewallEnabled is nil when the agent does not report
+// the state of its firewall. Rules carry no remote address, so an inbound
+// rule applies to traffic from any address.
+func Analyze(rules []model.FirewallRule, firewallEnabled *bool) []model.FirewallFinding {
+	var findings []model.FirewallFinding
+	if firewallEnabled != nil && !*firewallEnabled {
+		findings = append(findings, model.FirewallFinding{
+			Kind:     model.FirewallDisabled,
+			Severity: model.SeverityHigh,
+			Message:  "The firewall is disabled; none of its rules are enforced.",
+		})
+	}
+
+	active := make([]*rule, 0, len(rules))
+	for _, r := range rules {
+		if !r.Enabled || (r.Action != actionAllow && r.Action != actionDeny) {
+			continue
+		}
+		parsed := &rule{FirewallRule: r, protocol: NormalizeProtocol(r.Protocol), ports: AllPorts}
+		if parsed.protocol != ProtocolICMP {
+			ports, err := ParsePorts(r.Port)
+			if err != nil {
+				findings = append(findings, ruleFinding(model.FirewallInvalidRule, model.SeverityLow, parsed, nil,
+					fmt.Sprintf("Rule %q has port %q that cannot be parsed and was not analyzed.", r.Name, r.Port)))
+				continue
+			}
+			parsed.ports = ports
+		}
+		active = append(active, parsed)
+	}
+
+	for j, later := range active {
+		for _, earlier := range active[:j] {
+			if earlier.Action == later.Action || !earlier.covers(later) {
+				continue
+			}
+			if later.covers(earlier) {
+				findings = append(findings, ruleFinding(model.FirewallConflictingRules, model.SeverityMedium, later, earlier,
+					fmt.Sprintf("Rule %q (%s) contradicts the earlier rule %q (%s) on the same traffic; %q wins.",
+						later.Name, later.Action, earlier.Name, earlier.Action, earlier.Name)))
+				break
+			}
+			// A DENY that never applies leaves traffic open; an ALLOW that
+			// never applies only breaks something that was meant to work.
+			severity := model.SeverityMedium
+			if later.Action == actionDeny {
+				severity = model.SeverityHigh
+			}
+			findings = append(findings, ruleFinding(model.FirewallShadowedRule, severity, later, earlier,
+				fmt.Sprintf("Rule %q (%s) never applies: the earlier rule %q (%s %s/%s) matches all of its traffic.",
+					later.Name, later.Action, earlier.Name, earlier.Action, earlier.ports, earlier.protocol)))
+			break
+		}
+	}
+
+	for _, risky := range RiskyPorts {
+		r := firstMatch(active, directionInbound, ProtocolTCP, risky.Port)
+		if r == nil || r.Action != actionAllow {
+			continue
+		}
+		f := ruleFinding(model.FirewallRiskyExposure, risky.Severity, r, nil,
+			fmt.Sprintf("Inbound %s (%d/tcp) is allowed from any address by rule %q.", risky.Service, risky.Port, r.Name))
+		f.Port = strconv.Itoa(risky.Port)
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// firstMatch returns the rule that decides traffic to port, or nil if no
+// rule matches it.
+func firstMatch(rules []*rule, direction, protocol string, port int) *rule {
+	for _, r := range rules {
+		if r.Direction == direction && protocolCovers(r.protocol, protocol) && r.ports.Contains(port) {
+			return r
+		}
+	}
+	return nil
+}
+
+func ruleFinding(kind, severity string, r, related *rule, message string) model.FirewallFinding {
+	f := model.FirewallFinding{
+		Kind:      kind,
+		Severity:  severity,
+		RuleName:  r.Name,
+		Direction: r.Direction,
+		Protocol:  r.protocol,
+		Port:      r.Port,
+		Message:   message,
+	}
+	if related != nil {
+		f.RelatedRule = related.Name
+	}
+	return f
+}
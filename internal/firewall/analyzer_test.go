@@ -0,0 +1,76 @@
+package firewall_test
+
+import (
+	"agent_server/internal/firewall"
+	"agent_server/internal/model"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// analyzeCase is one entry of testdata/analyze.json: a reported rule set and
+// the findings Analyze must return for it, in order.
+type analyzeCase struct {
+	Name            string               `json:"name"`
+	FirewallEnabled *bool                `json:"firewall_enabled"`
+	Rules           []model.FirewallRule `json:"rules"`
+	Findings        []finding            `json:"findings"`
+}
+
+type finding struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule,omitempty"`
+	Related  string `json:"related,omitempty"`
+	Port     string `json:"port,omitempty"`
+}
+
+func TestAnalyze(t *testing.T) {
+	data, err := os.ReadFile("testdata/analyze.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []analyzeCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatalf("testdata/analyze.json: %v", err)
+	}
+	for _, tc := range cases {
+		got := []finding{}
+		for _, f := range firewall.Analyze(tc.Rules, tc.FirewallEnabled) {
+			if f.Message == "" {
+				t.Errorf("%s: %s finding without a message", tc.Name, f.Kind)
+			}
+			got = append(got, finding{Kind: f.Kind, Severity: f.Severity, Rule: f.RuleName, Related: f.RelatedRule, Port: f.Port})
+		}
+		if !reflect.DeepEqual(got, tc.Findings) {
+			t.Errorf("%s: Analyze =\n%+v\nwant\n%+v", tc.Name, got, tc.Findings)
+		}
+	}
+}
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"80", "80"},
+		{" 8000-8080 ", "8000-8080"},
+		{"443,80,81-90", "80-90,443"},
+		{"ANY", "ANY"},
+		{"", "ANY"},
+		{"*", "ANY"},
+	}
+	for _, tt := range tests {
+		ports, err := firewall.ParsePorts(tt.in)
+		if err != nil || ports.String() != tt.want {
+			t.Errorf("ParsePorts(%q) = %v, %v; want %s", tt.in, ports, err, tt.want)
+		}
+	}
+	for _, in := range []string{"http", "80-", "90-80", "65536", "-1"} {
+		if _, err := firewall.ParsePorts(in); !errors.Is(err, firewall.ErrInvalidPort) {
+			t.Errorf("ParsePorts(%q) = %v, want ErrInvalidPort", in, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// internal/firewall/ports.go
+
+// Package firewall analyzes the firewall rules reported by an agent for rules
+// that never apply, contradict each other or expose sensitive services.
+package firewall
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPort is returned for a port that is not a number, a range or ANY.
+var ErrInvalidPort = errors.New("invalid port")
+
+// Protocols as normalized by NormalizeProtocol.
+const (
+	ProtocolAny  = "ANY"
+	ProtocolTCP  = "TCP"
+	ProtocolUDP  = "UDP"
+	ProtocolICMP = "ICMP"
+)
+
+// PortRange is an inclusive range of ports.
+type PortRange struct {
+	Low, High int
+}
+
+// PortSet is a sorted list of disjoint, non-adjacent port ranges.
+type PortSet []PortRange
+
+// AllPorts matches every port.
+var AllPorts = PortSet{{0, 65535}}
+
+// ParsePorts parses "80", "8000-8080", "80,443" or "ANY". An empty port or
+// "*" also means every port.
+func ParsePorts(s string) (PortSet, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToUpper(s) {
+	case "", "ANY", "*", "ALL":
+		return AllPorts, nil
+	}
+	var ranges []PortRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		low, high, isRange := strings.Cut(part, "-")
+		if !isRange {
+			high = low
+		}
+		lo, err := parsePort(low)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q", ErrInvalidPort, s)
+		}
+		hi, err := parsePort(high)
+		if err != nil || hi < lo {
+			return nil, fmt.Errorf("%w %q", ErrInvalidPort, s)
+		}
+		ranges = append(ranges, PortRange{lo, hi})
+	}
+	return normalizePorts(ranges), nil
+}
+
+func parsePort(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 || n > 65535 {
+		return 0, ErrInvalidPort
+	}
+	return n, nil
+}
+
+// normalizePorts sorts ranges and merges the ones that overlap or touch.
+func normalizePorts(ranges []PortRange) PortSet {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Low < ranges[j].Low })
+	var out PortSet
+	for _, r := range ranges {
+		if n := len(out); n > 0 && r.Low <= out[n-1].High+1 {
+			if r.High > out[n-1].High {
+				out[n-1].High = r.High
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Contains reports whether port is in the set.
+func (s PortSet) Contains(port int) bool {
+	for _, r := range s {
+		if port >= r.Low && port <= r.High {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers reports whether every port of other is also in s.
+func (s PortSet) Covers(other PortSet) bool {
+	for _, o := range other {
+		covered := false
+		for _, r := range s {
+			if o.Low >= r.Low && o.High <= r.High {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether both sets hold the same ports.
+func (s PortSet) Equal(other PortSet) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for i := range s {
+		if s[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the set the way ParsePorts reads it.
+func (s PortSet) String() string {
+	if s.Equal(AllPorts) {
+		return "ANY"
+	}
+	parts := make([]string, len(s))
+	for i, r := range s {
+		if r.Low == r.High {
+			parts[i] = strconv.Itoa(r.Low)
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", r.Low, r.High)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// NormalizeProtocol upper-cases a protocol and maps "", "*" and "ALL" to ANY.
+func NormalizeProtocol(protocol string) string {
+	p := strings.ToUpper(strings.TrimSpace(protocol))
+	switch p {
+	case "", "*", "ALL", ProtocolAny:
+		return ProtocolAny
+	}
+	return p
+}
+
+// protocolCovers reports whether traffic of protocol b is also matched by a.
+func protocolCovers(a, b string) bool {
+	return a == ProtocolAny || a == b
+}
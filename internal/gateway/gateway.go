@@ -0,0 +1,321 @@
+// internal/gateway/gateway.go
+
+// Package gateway serves the AgentService as REST/JSON for tools that cannot
+// speak gRPC. Requests and responses use the protojson encoding of the proto
+// messages, and errors are mapped from gRPC status codes to HTTP statuses.
+package gateway
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/api/docs"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxBodyBytes limits request bodies; inventory reports are the largest.
+const maxBodyBytes = 8 << 20
+
+var (
+	marshalOptions   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshalOptions = protojson.UnmarshalOptions{}
+)
+
+// Gateway is an http.Handler that translates REST calls into calls on an
+// AgentServiceServer.
+type Gateway struct {
+	srv         pb.AgentServiceServer
+	interceptor grpc.UnaryServerInterceptor
+	mux         *http.ServeMux
+}
+
+// New creates a gateway for srv. The interceptors run around every call in
+// the same order as on the gRPC server, so rate limiting and admin
+// authentication apply to REST clients too.
+func New(srv pb.AgentServiceServer, interceptors ...grpc.UnaryServerInterceptor) *Gateway {
+	g := &Gateway{
+		srv:         srv,
+		interceptor: chainUnary(interceptors),
+		mux:         http.NewServeMux(),
+	}
+	for _, rt := range routes {
+		g.mux.HandleFunc(rt.method+" "+rt.path, g.handle(rt))
+	}
+
+	g.mux.HandleFunc("GET /openapi.json", g.serveOpenAPI)
+	g.mux.Handle("GET /docs/", http.StripPrefix("/docs/", http.FileServerFS(docs.FS)))
+	g.mux.Handle("GET /docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently))
+	g.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, status.Errorf(codes.NotFound, "No route for %s %s", r.Method, r.URL.Path))
+	})
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *Gateway) handle(rt route) http.HandlerFunc {
+	fullMethod := "/" + pb.AgentService_ServiceDesc.ServiceName + "/" + rt.rpc
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := rt.newReq()
+		if err := decodeRequest(r, rt, req); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		info := &grpc.UnaryServerInfo{Server: g.srv, FullMethod: fullMethod}
+		resp, err := g.interceptor(incomingContext(r), req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return rt.invoke(ctx, g.srv, req.(proto.Message))
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		data, err := marshalOptions.Marshal(resp.(proto.Message))
+		if err != nil {
+			writeError(w, status.Errorf(codes.Internal, "Could not encode response"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+// decodeRequest fills req from the JSON body (POST) or the query string
+// (GET, DELETE), then from the path wildcards.
+func decodeRequest(r *http.Request, rt route, req proto.Message) error {
+	if r.Method == http.MethodPost {
+		body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return status.Errorf(codes.ResourceExhausted, "Request body larger than %d bytes", maxBodyBytes)
+			}
+			return status.Errorf(codes.InvalidArgument, "Could not read request body")
+		}
+		if len(strings.TrimSpace(string(body))) > 0 {
+			if err := unmarshalOptions.Unmarshal(body, req); err != nil {
+				return status.Errorf(codes.InvalidArgument, "Invalid JSON body: %v", err)
+			}
+		}
+	} else {
+		for name, values := range r.URL.Query() {
+			if err := setQueryParam(req.ProtoReflect(), name, values); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range pathParams(rt.path) {
+		if err := setField(req.ProtoReflect(), name, r.PathValue(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pathParams returns the wildcard names in a route path.
+func pathParams(path string) []string {
+	var names []string
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			names = append(names, strings.TrimSuffix(seg[1:], "}"))
+		}
+	}
+	return names
+}
+
+// setQueryParam assigns a query parameter. String maps such as labels take
+// repeated "key:value" pairs, repeated scalar fields take every value and
+// other fields take the last value.
+func setQueryParam(msg protoreflect.Message, name string, values []string) error {
+	fd := findField(msg, name)
+	if fd != nil && fd.IsList() && fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+		list := msg.Mutable(fd).List()
+		for _, raw := range values {
+			v, err := parseScalar(fd, raw)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "Invalid value %q for %s: %v", raw, name, err)
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+	if fd == nil || !fd.IsMap() || fd.MapKey().Kind() != protoreflect.StringKind || fd.MapValue().Kind() != protoreflect.StringKind {
+		return setField(msg, name, values[len(values)-1])
+	}
+	m := msg.Mutable(fd).Map()
+	for _, raw := range values {
+		k, v, ok := strings.Cut(raw, ":")
+		if !ok || k == "" {
+			return status.Errorf(codes.InvalidArgument, "Invalid value %q for %s: want key:value", raw, name)
+		}
+		m.Set(protoreflect.ValueOfString(k).MapKey(), protoreflect.ValueOfString(v))
+	}
+	return nil
+}
+
+func findField(msg protoreflect.Message, name string) protoreflect.FieldDescriptor {
+	fields := msg.Descriptor().Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return fields.ByJSONName(name)
+}
+
+// setField assigns a string from the URL to a scalar or timestamp field of
+// msg, accepting both the proto and the JSON field name. Timestamps are
+// RFC 3339, e.g. 2024-05-01T00:00:00Z.
+func setField(msg protoreflect.Message, name, raw string) error {
+	fd := findField(msg, name)
+	if fd == nil || fd.IsList() || fd.IsMap() {
+		return status.Errorf(codes.InvalidArgument, "Unknown parameter %q", name)
+	}
+	if fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == "google.protobuf.Timestamp" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "Invalid value %q for %s: want an RFC 3339 time", raw, name)
+		}
+		msg.Set(fd, protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()))
+		return nil
+	}
+
+	v, err := parseScalar(fd, raw)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid value %q for %s: %v", raw, name, err)
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, raw string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(raw), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(raw)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(raw, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(raw, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(raw, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(raw, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(strings.ToUpper(raw))); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("not a %s value", fd.Enum().Name())
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("%s fields cannot be set from the URL", fd.Kind())
+}
+
+// incomingContext carries the headers the interceptors look at as gRPC
+// metadata and the client address as the gRPC peer.
+func incomingContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		md.Set("authorization", auth)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	return peer.NewContext(ctx, &peer.Peer{Addr: remoteAddr(r.RemoteAddr)})
+}
+
+type remoteAddr string
+
+func (a remoteAddr) Network() string { return "tcp" }
+func (a remoteAddr) String() string  { return string(a) }
+
+// httpStatus maps gRPC codes to HTTP statuses, following
+// google.golang.org/genproto/googleapis/rpc/code.
+func httpStatus(c codes.Code) int {
+	switch c {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError sends the gRPC status as a google.rpc.Status JSON body.
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	data, merr := marshalOptions.Marshal(st.Proto())
+	if merr != nil {
+		log.Printf("Failed to encode error response: %v", merr)
+		data = []byte(`{"code":13,"message":"Internal error"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+	w.Write(data)
+}
+
+// chainUnary combines interceptors into one, the first being outermost.
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			ic, h := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return ic(ctx, req, info, h)
+			}
+		}
+		return next(ctx, req)
+	}
+}
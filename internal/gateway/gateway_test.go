@@ -0,0 +1,173 @@
+package gateway_test
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/config"
+	"agent_server/internal/gateway"
+	"agent_server/internal/service"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeServer records the requests the gateway hands it and answers FindAgent
+// with err when it is set.
+type fakeServer struct {
+	pb.UnimplementedAgentServiceServer
+	err error
+
+	findAgent  *pb.FindAgentRequest
+	listAgents *pb.ListAgentsRequest
+	cancel     *pb.CancelCommandRequest
+}
+
+func (s *fakeServer) FindAgent(ctx context.Context, req *pb.FindAgentRequest) (*pb.FindAgentResponse, error) {
+	s.findAgent = req
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &pb.FindAgentResponse{Found: true, Agent: &pb.Agent{AgentId: req.AgentId}}, nil
+}
+
+func (s *fakeServer) ListAgents(ctx context.Context, req *pb.ListAgentsRequest) (*pb.ListAgentsResponse, error) {
+	s.listAgents = req
+	return &pb.ListAgentsResponse{}, nil
+}
+
+func (s *fakeServer) CancelCommand(ctx context.Context, req *pb.CancelCommandRequest) (*pb.CancelCommandResponse, error) {
+	s.cancel = req
+	return &pb.CancelCommandResponse{Command: &pb.Command{}}, nil
+}
+
+func newGateway(t *testing.T, adminToken string) (*fakeServer, *httptest.Server) {
+	cfg := config.Default()
+	cfg.Auth.AdminToken = adminToken
+	srv := &fakeServer{}
+	ts := httptest.NewServer(gateway.New(srv, service.AdminAuthUnaryInterceptor(config.StaticProvider(cfg))))
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, token, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("%s %s returned %q, not JSON: %v", method, path, data, err)
+	}
+	return resp.StatusCode, decoded
+}
+
+func TestGatewayBindsPathAndQueryParameters(t *testing.T) {
+	srv, ts := newGateway(t, "")
+
+	code, body := do(t, ts, "GET", "/v1/agents/web-01", "", "")
+	if code != http.StatusOK || srv.findAgent.GetAgentId() != "web-01" {
+		t.Fatalf("GET /v1/agents/web-01 = %d, request %v", code, srv.findAgent)
+	}
+	if agent, _ := body["agent"].(map[string]interface{}); agent["agent_id"] != "web-01" || body["found"] != true {
+		t.Fatalf("response body = %v, want the agent under its proto field names", body)
+	}
+
+	if code, _ := do(t, ts, "GET", "/v1/agents?status=online&page_size=20", "", ""); code != http.StatusOK {
+		t.Fatalf("GET /v1/agents = %d", code)
+	}
+	if srv.listAgents.Status != pb.AgentStatus_ONLINE || srv.listAgents.PageSize != 20 {
+		t.Fatalf("ListAgents request = %v, want status and page size from the query", srv.listAgents)
+	}
+
+	if code, _ := do(t, ts, "POST", "/v1/commands/42/cancel", "", ""); code != http.StatusOK || srv.cancel.GetCommandId() != 42 {
+		t.Fatalf("POST /v1/commands/42/cancel = %d, request %v", code, srv.cancel)
+	}
+
+	if code, body := do(t, ts, "GET", "/v1/agents?page_size=many", "", ""); code != http.StatusBadRequest || body["code"] != float64(codes.InvalidArgument) {
+		t.Fatalf("GET with an invalid page_size = %d %v, want 400 InvalidArgument", code, body)
+	}
+	if code, _ := do(t, ts, "GET", "/v1/agents?no_such_field=1", "", ""); code != http.StatusBadRequest {
+		t.Fatalf("GET with an unknown parameter = %d, want 400", code)
+	}
+	if code, _ := do(t, ts, "POST", "/v1/commands/42/cancel", "", "{not json"); code != http.StatusBadRequest {
+		t.Fatalf("POST with an invalid body = %d, want 400", code)
+	}
+	if code, body := do(t, ts, "GET", "/v1/nothing-here", "", ""); code != http.StatusNotFound || body["code"] != float64(codes.NotFound) {
+		t.Fatalf("GET of an unknown route = %d %v, want 404 NotFound", code, body)
+	}
+}
+
+func TestGatewayEnforcesAdminToken(t *testing.T) {
+	srv, ts := newGateway(t, "s3cret")
+
+	if code, body := do(t, ts, "GET", "/v1/agents", "", ""); code != http.StatusUnauthorized || body["code"] != float64(codes.Unauthenticated) {
+		t.Fatalf("admin route without a token = %d %v, want 401 Unauthenticated", code, body)
+	}
+	if code, _ := do(t, ts, "GET", "/v1/agents", "wrong", ""); code != http.StatusUnauthorized {
+		t.Fatalf("admin route with a wrong token = %d, want 401", code)
+	}
+	if srv.listAgents != nil {
+		t.Fatal("ListAgents ran without a valid admin token")
+	}
+	if code, _ := do(t, ts, "GET", "/v1/agents", "s3cret", ""); code != http.StatusOK || srv.listAgents == nil {
+		t.Fatalf("admin route with the token = %d, want 200", code)
+	}
+
+	// Agent RPCs such as FindAgent do not need the admin token.
+	if code, _ := do(t, ts, "GET", "/v1/agents/web-01", "", ""); code != http.StatusOK {
+		t.Fatalf("agent route without a token = %d, want 200", code)
+	}
+}
+
+func TestGatewayMapsErrorCodes(t *testing.T) {
+	srv, ts := newGateway(t, "")
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.OutOfRange, http.StatusBadRequest},
+		{codes.FailedPrecondition, http.StatusBadRequest},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.Aborted, http.StatusConflict},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.Unimplemented, http.StatusNotImplemented},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.Canceled, 499},
+		{codes.Internal, http.StatusInternalServerError},
+		{codes.DataLoss, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		srv.err = status.Error(tt.code, "boom")
+		code, body := do(t, ts, "GET", "/v1/agents/web-01", "", "")
+		if code != tt.want || body["code"] != float64(tt.code) || body["message"] != "boom" {
+			t.Errorf("%s = %d %v, want %d with the gRPC status as body", tt.code, code, body, tt.want)
+		}
+	}
+
+	// Errors that are not gRPC statuses become 500 Unknown.
+	srv.err = io.ErrUnexpectedEOF
+	if code, body := do(t, ts, "GET", "/v1/agents/web-01", "", ""); code != http.StatusInternalServerError || body["code"] != float64(codes.Unknown) {
+		t.Fatalf("plain error = %d %v, want 500 Unknown", code, body)
+	}
+}
@@ -0,0 +1,28 @@
+// internal/gateway/gen/main.go
+//
+// gen writes the OpenAPI document for the REST gateway. Run it through
+// go generate after changing the proto or the route table:
+//
+//	go generate ./internal/gateway
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"agent_server/internal/gateway"
+)
+
+func main() {
+	out := flag.String("out", "openapi.json", "output file")
+	flag.Parse()
+
+	data, err := gateway.OpenAPI()
+	if err != nil {
+		log.Fatalf("Failed to build OpenAPI document: %v", err)
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
@@ -0,0 +1,228 @@
+// internal/gateway/openapi.go
+
+package gateway
+
+//go:generate go run ./gen -out ../../api/docs/openapi.json
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/service"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// OpenAPI returns the OpenAPI 3 document for the REST routes. Schemas are
+// derived from the proto descriptors compiled into the pb package, so the
+// document always matches the messages the gateway accepts.
+func OpenAPI() ([]byte, error) {
+	doc := buildOpenAPI()
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (g *Gateway) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	data, err := OpenAPI()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+type object = map[string]interface{}
+
+func buildOpenAPI() object {
+	schemas := object{
+		"Status": object{
+			"type":        "object",
+			"description": "Error body: the gRPC status of the failed call.",
+			"properties": object{
+				"code":    object{"type": "integer", "format": "int32", "description": "gRPC status code"},
+				"message": object{"type": "string"},
+				"details": object{"type": "array", "items": object{"type": "object"}},
+			},
+		},
+	}
+	b := &schemaBuilder{schemas: schemas}
+
+	paths := object{}
+	for _, rt := range routes {
+		op := object{
+			"operationId": rt.rpc,
+			"summary":     rt.summary,
+			"tags":        []string{tagFor(rt.path)},
+			"responses": object{
+				"200":     object{"description": "OK", "content": jsonContent(b.ref(rt.response))},
+				"default": object{"description": "Error", "content": jsonContent(object{"$ref": "#/components/schemas/Status"})},
+			},
+		}
+
+		inPath := map[string]bool{}
+		var params []object
+		for _, name := range pathParams(rt.path) {
+			inPath[name] = true
+			fd := rt.request.Fields().ByName(protoreflect.Name(name))
+			params = append(params, object{"name": name, "in": "path", "required": true, "schema": b.field(fd)})
+		}
+		if rt.method == http.MethodPost {
+			op["requestBody"] = object{"content": jsonContent(b.ref(rt.request))}
+		} else {
+			fields := rt.request.Fields()
+			for i := 0; i < fields.Len(); i++ {
+				fd := fields.Get(i)
+				if inPath[string(fd.Name())] {
+					continue
+				}
+				if fd.IsMap() {
+					params = append(params, object{"name": string(fd.Name()), "in": "query", "description": "repeatable key:value pair",
+						"schema": object{"type": "array", "items": object{"type": "string"}}})
+					continue
+				}
+				params = append(params, object{"name": string(fd.Name()), "in": "query", "schema": b.field(fd)})
+			}
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if service.IsAdminMethod("/" + pb.AgentService_ServiceDesc.ServiceName + "/" + rt.rpc) {
+			op["security"] = []object{{"bearerAuth": []string{}}}
+		}
+
+		item, _ := paths[openAPIPath(rt.path)].(object)
+		if item == nil {
+			item = object{}
+			paths[openAPIPath(rt.path)] = item
+		}
+		item[strings.ToLower(rt.method)] = op
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "Agent Server API",
+			"version":     "v1",
+			"description": "REST/JSON mapping of the " + pb.AgentService_ServiceDesc.ServiceName + " gRPC service. Bodies use the protojson encoding with proto field names.",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": schemas,
+			"securitySchemes": object{
+				"bearerAuth": object{"type": "http", "scheme": "bearer", "description": "auth.admin_token from the server config"},
+			},
+		},
+	}
+}
+
+// openAPIPath converts a ServeMux pattern path to an OpenAPI path. Both use
+// {name} for wildcards, so only the trailing {name...} form would differ.
+func openAPIPath(path string) string {
+	return strings.ReplaceAll(path, "...}", "}")
+}
+
+// tagFor groups operations by the first path segment after the version.
+func tagFor(path string) string {
+	seg := strings.Split(strings.TrimPrefix(path, "/v1/"), "/")[0]
+	if i := strings.IndexByte(seg, ':'); i >= 0 {
+		seg = seg[:i]
+	}
+	return seg
+}
+
+func jsonContent(schema object) object {
+	return object{"application/json": object{"schema": schema}}
+}
+
+// schemaBuilder adds component schemas for messages and enums as they are
+// referenced.
+type schemaBuilder struct {
+	schemas object
+}
+
+func (b *schemaBuilder) ref(md protoreflect.MessageDescriptor) object {
+	if md.FullName() == "google.protobuf.Timestamp" {
+		return object{"type": "string", "format": "date-time"}
+	}
+	name := string(md.Name())
+	if _, ok := b.schemas[name]; !ok {
+		props := object{}
+		b.schemas[name] = object{"type": "object", "properties": props}
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			schema := b.field(fd)
+			if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+				schema = withDescription(schema, "Only one field of "+string(oneof.Name())+" may be set.")
+			}
+			props[string(fd.Name())] = schema
+		}
+	}
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+func (b *schemaBuilder) enumRef(ed protoreflect.EnumDescriptor) object {
+	name := string(ed.Name())
+	if _, ok := b.schemas[name]; !ok {
+		values := ed.Values()
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		b.schemas[name] = object{"type": "string", "enum": names}
+	}
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+func (b *schemaBuilder) field(fd protoreflect.FieldDescriptor) object {
+	if fd.IsMap() {
+		// protojson encodes maps as objects keyed by the map key.
+		return object{"type": "object", "additionalProperties": b.field(fd.MapValue())}
+	}
+	var schema object
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		schema = b.ref(fd.Message())
+	case protoreflect.EnumKind:
+		schema = b.enumRef(fd.Enum())
+	case protoreflect.StringKind:
+		schema = object{"type": "string"}
+	case protoreflect.BytesKind:
+		schema = object{"type": "string", "format": "byte"}
+	case protoreflect.BoolKind:
+		schema = object{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		schema = object{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		schema = object{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// protojson encodes 64-bit integers as strings.
+		schema = object{"type": "string", "format": "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		schema = object{"type": "string", "format": "uint64"}
+	case protoreflect.FloatKind:
+		schema = object{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		schema = object{"type": "number", "format": "double"}
+	default:
+		schema = object{}
+	}
+	if fd.IsList() {
+		return object{"type": "array", "items": schema}
+	}
+	return schema
+}
+
+// withDescription adds a description; $ref siblings are wrapped in allOf
+// because OpenAPI 3.0 ignores them otherwise.
+func withDescription(schema object, desc string) object {
+	if _, isRef := schema["$ref"]; isRef {
+		return object{"allOf": []object{schema}, "description": desc}
+	}
+	out := object{"description": desc}
+	for k, v := range schema {
+		out[k] = v
+	}
+	return out
+}
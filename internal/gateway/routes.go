@@ -0,0 +1,157 @@
+// internal/gateway/routes.go
+
+package gateway
+
+import (
+	pb "agent_server/agent_server/proto"
+	"context"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// route maps one REST endpoint onto an AgentService RPC. Path wildcards are
+// named after request fields and are copied into the request; POST routes
+// read the remaining fields from the JSON body and GET and DELETE routes
+// from the query string.
+//
+// http.ServeMux wildcards must span a whole path segment, so custom methods
+// such as ":register" only appear after literal segments; actions on a single
+// agent or command use a sub-path instead.
+type route struct {
+	method  string
+	path    string
+	rpc     string
+	summary string
+
+	request  protoreflect.MessageDescriptor
+	response protoreflect.MessageDescriptor
+	newReq   func() proto.Message
+	invoke   func(ctx context.Context, srv pb.AgentServiceServer, req proto.Message) (proto.Message, error)
+}
+
+// newRoute builds a route from a method expression such as
+// pb.AgentServiceServer.FindAgent, so the request and response types are
+// checked by the compiler.
+func newRoute[Req, Resp proto.Message](method, path, rpc, summary string, call func(pb.AgentServiceServer, context.Context, Req) (Resp, error)) route {
+	var req Req
+	var resp Resp
+	return route{
+		method:   method,
+		path:     path,
+		rpc:      rpc,
+		summary:  summary,
+		request:  req.ProtoReflect().Descriptor(),
+		response: resp.ProtoReflect().Descriptor(),
+		newReq: func() proto.Message {
+			return req.ProtoReflect().New().Interface()
+		},
+		invoke: func(ctx context.Context, srv pb.AgentServiceServer, m proto.Message) (proto.Message, error) {
+			return call(srv, ctx, m.(Req))
+		},
+	}
+}
+
+var routes = []route{
+	// Agent-facing RPCs.
+	newRoute("POST", "/v1/agents:register", "RegisterAgent", "Register an agent or update its details", pb.AgentServiceServer.RegisterAgent),
+	newRoute("POST", "/v1/agents/{agent_id}/heartbeat", "SendHeartbeat", "Record a heartbeat from an agent", pb.AgentServiceServer.SendHeartbeat),
+	newRoute("POST", "/v1/agents/{agent_id}/firewall-rules", "ReportFirewallStatus", "Report the agent's current firewall rules", pb.AgentServiceServer.ReportFirewallStatus),
+	newRoute("POST", "/v1/agents/{agent_id}/apps", "ReportInstalledApps", "Report the agent's installed applications", pb.AgentServiceServer.ReportInstalledApps),
+	newRoute("POST", "/v1/agents/{agent_id}/network-interfaces", "ReportNetworkInterfaces", "Report the agent's network interfaces", pb.AgentServiceServer.ReportNetworkInterfaces),
+	newRoute("POST", "/v1/agents/{agent_id}/local-users", "ReportLocalUsers", "Report the agent's local users and groups", pb.AgentServiceServer.ReportLocalUsers),
+	newRoute("POST", "/v1/agents/{agent_id}/services", "ReportServices", "Report the agent's system services", pb.AgentServiceServer.ReportServices),
+	newRoute("POST", "/v1/agents/{agent_id}/listening-sockets", "ReportListeningSockets", "Report the agent's listening sockets", pb.AgentServiceServer.ReportListeningSockets),
+	newRoute("POST", "/v1/agents/{agent_id}/processes", "ReportProcesses", "Report the agent's running processes", pb.AgentServiceServer.ReportProcesses),
+	newRoute("POST", "/v1/agents/{agent_id}/patch-status", "ReportPatchStatus", "Report the agent's installed and pending patches and whether a reboot is pending", pb.AgentServiceServer.ReportPatchStatus),
+	newRoute("POST", "/v1/agents/{agent_id}/commands:poll", "PollCommands", "Fetch pending commands and mark them as sent", pb.AgentServiceServer.PollCommands),
+	newRoute("POST", "/v1/agents/{agent_id}/commands/{command_id}/result", "ReportCommandResult", "Report the outcome of a command", pb.AgentServiceServer.ReportCommandResult),
+	newRoute("POST", "/v1/agents/{agent_id}/update-result", "ReportUpdateResult", "Report the outcome of an agent update offered by a rollout", pb.AgentServiceServer.ReportUpdateResult),
+	newRoute("GET", "/v1/agents/{agent_id}/commands/{command_id}/upload-status", "GetFileUploadStatus", "Bytes the server has of a file being collected, to resume its upload", pb.AgentServiceServer.GetFileUploadStatus),
+
+	// Query and admin RPCs. The admin ones require the admin token, see
+	// service.IsAdminMethod.
+	newRoute("GET", "/v1/agents/{agent_id}", "FindAgent", "Look up an agent by ID", pb.AgentServiceServer.FindAgent),
+	newRoute("GET", "/v1/agents", "ListAgents", "List agents, optionally filtered by status", pb.AgentServiceServer.ListAgents),
+	newRoute("POST", "/v1/agents/{agent_id}/decommission", "DecommissionAgent", "Retire an agent", pb.AgentServiceServer.DecommissionAgent),
+	newRoute("GET", "/v1/agents/{agent_id}/firewall-rules", "GetFirewallRules", "Firewall rules from the agent's latest report", pb.AgentServiceServer.GetFirewallRules),
+	newRoute("GET", "/v1/agents/{agent_id}/apps", "GetInstalledApps", "Installed applications from the agent's latest report", pb.AgentServiceServer.GetInstalledApps),
+	newRoute("GET", "/v1/agents/{agent_id}/network-interfaces", "GetNetworkInterfaces", "Network interfaces from the agent's latest report", pb.AgentServiceServer.GetNetworkInterfaces),
+	newRoute("GET", "/v1/agents/{agent_id}/local-users", "GetLocalUsers", "Local users and groups from the agent's latest report", pb.AgentServiceServer.GetLocalUsers),
+	newRoute("GET", "/v1/agents/{agent_id}/services", "GetServices", "System services from the agent's latest report", pb.AgentServiceServer.GetServices),
+	newRoute("GET", "/v1/agents/{agent_id}/listening-sockets", "GetListeningSockets", "Listening sockets from the agent's latest report", pb.AgentServiceServer.GetListeningSockets),
+	newRoute("GET", "/v1/agents/{agent_id}/processes", "GetProcesses", "Running processes from the agent's latest report", pb.AgentServiceServer.GetProcesses),
+	newRoute("GET", "/v1/agents/{agent_id}/patch-status", "GetPatchStatus", "Patch status with installed and pending patches from the agent's latest report", pb.AgentServiceServer.GetPatchStatus),
+	newRoute("POST", "/v1/agents/{agent_id}/firewall:configure", "ConfigureFirewall", "Queue a firewall change for the agent", pb.AgentServiceServer.ConfigureFirewall),
+	newRoute("POST", "/v1/agents/{agent_id}/scripts:run", "RunScript", "Queue a script for the agent; scripts outside the allowlist wait for approval", pb.AgentServiceServer.RunScript),
+	newRoute("GET", "/v1/commands", "ListCommands", "List commands, newest first", pb.AgentServiceServer.ListCommands),
+	newRoute("POST", "/v1/commands/{command_id}/cancel", "CancelCommand", "Cancel a command the agent has not picked up, or reject one awaiting approval", pb.AgentServiceServer.CancelCommand),
+	newRoute("POST", "/v1/commands/{command_id}/approve", "ApproveCommand", "Release a command awaiting approval to the agent", pb.AgentServiceServer.ApproveCommand),
+	newRoute("GET", "/v1/commands/{command_id}/output", "GetCommandOutput", "Exit code and stored stdout and stderr of a script command", pb.AgentServiceServer.GetCommandOutput),
+	newRoute("GET", "/v1/scripts", "ListScripts", "Scripts in the server-side library and whether they are allowlisted", pb.AgentServiceServer.ListScripts),
+	newRoute("POST", "/v1/agents/{agent_id}/files:collect", "CollectFile", "Queue a command asking the agent to upload a file", pb.AgentServiceServer.CollectFile),
+
+	// Webhooks.
+	newRoute("POST", "/v1/webhooks", "CreateWebhookSubscription", "Subscribe a URL to fleet events", pb.AgentServiceServer.CreateWebhookSubscription),
+	newRoute("GET", "/v1/webhooks", "ListWebhookSubscriptions", "List webhook subscriptions", pb.AgentServiceServer.ListWebhookSubscriptions),
+	newRoute("DELETE", "/v1/webhooks/{subscription_id}", "DeleteWebhookSubscription", "Delete a subscription and its deliveries", pb.AgentServiceServer.DeleteWebhookSubscription),
+	newRoute("GET", "/v1/webhook-deliveries", "ListWebhookDeliveries", "List webhook deliveries, newest first", pb.AgentServiceServer.ListWebhookDeliveries),
+	newRoute("POST", "/v1/webhook-deliveries:replay", "ReplayWebhookDeliveries", "Re-queue failed webhook deliveries", pb.AgentServiceServer.ReplayWebhookDeliveries),
+
+	// Vulnerabilities. Feeds are imported with the streaming
+	// ImportVulnerabilityFeed RPC only (agentctl vulns import).
+	newRoute("GET", "/v1/vulnerability-findings", "ListVulnerabilityFindings", "Vulnerable applications per agent, highest CVSS score first", pb.AgentServiceServer.ListVulnerabilityFindings),
+	newRoute("GET", "/v1/vulnerabilities", "ListFleetVulnerabilities", "Vulnerabilities found in the fleet with the number of affected agents", pb.AgentServiceServer.ListFleetVulnerabilities),
+
+	// Software catalog.
+	newRoute("GET", "/v1/software", "ListSoftwareCatalog", "Canonical products with install counts per version, most installed first", pb.AgentServiceServer.ListSoftwareCatalog),
+	newRoute("GET", "/v1/software-installs", "ListSoftwareInstalls", "Installs of one product, oldest version first", pb.AgentServiceServer.ListSoftwareInstalls),
+
+	// Firewall analysis.
+	newRoute("GET", "/v1/firewall-findings", "ListFirewallFindings", "Shadowed, conflicting and risky firewall rules, most severe first", pb.AgentServiceServer.ListFirewallFindings),
+
+	// Compliance. Policies are loaded from compliance.policies_path.
+	newRoute("GET", "/v1/compliance-policies", "ListCompliancePolicies", "Loaded compliance policies, or every stored version of one", pb.AgentServiceServer.ListCompliancePolicies),
+	newRoute("POST", "/v1/compliance:evaluate", "EvaluateCompliance", "Evaluate one agent, or every agent, against the loaded policies now", pb.AgentServiceServer.EvaluateCompliance),
+	newRoute("GET", "/v1/compliance-results", "ListComplianceResults", "Current result and evidence of each check per agent", pb.AgentServiceServer.ListComplianceResults),
+	newRoute("GET", "/v1/compliance-history", "GetComplianceHistory", "Changes in policy results over time, newest first", pb.AgentServiceServer.GetComplianceHistory),
+	newRoute("GET", "/v1/compliance-report", "GetComplianceReport", "Compliant agents and per-check results for each policy", pb.AgentServiceServer.GetComplianceReport),
+
+	// Availability from the agent status history.
+	newRoute("GET", "/v1/availability-report", "GetAvailabilityReport", "Availability, outages and mean time to recover per agent and group over a time window", pb.AgentServiceServer.GetAvailabilityReport),
+	// Addresses agents were seen at.
+	newRoute("GET", "/v1/ip-history", "ListAgentIPHistory", "Addresses agents reported and connected from, such as which agents used an IP on a given day", pb.AgentServiceServer.ListAgentIPHistory),
+	// Hosts sharing an agent ID.
+	newRoute("GET", "/v1/suspected-clones", "ListSuspectedClones", "Agents whose ID is used by more than one host, such as VMs cloned from one image", pb.AgentServiceServer.ListSuspectedClones),
+	newRoute("DELETE", "/v1/suspected-clones/{agent_id}", "ClearSuspectedClone", "Clear the suspected clone flag of an agent", pb.AgentServiceServer.ClearSuspectedClone),
+	// Resource usage sent with heartbeats.
+	newRoute("GET", "/v1/agents/{agent_id}/metrics", "GetAgentMetrics", "Resource usage series of an agent over a time range, raw or in 5-minute or hourly buckets", pb.AgentServiceServer.GetAgentMetrics),
+	// Server-side alert rules and the alerts they raised.
+	newRoute("GET", "/v1/alert-rules", "ListAlertRules", "The loaded alert rules with their condition, severity, for-duration and notification routes", pb.AgentServiceServer.ListAlertRules),
+	newRoute("GET", "/v1/alerts", "ListAlerts", "Pending, firing and resolved alerts, newest first", pb.AgentServiceServer.ListAlerts),
+	// OS patch status.
+	newRoute("GET", "/v1/patch-status", "ListPatchStatus", "Patch status per agent, such as hosts missing a KB or with a reboot pending for over a week", pb.AgentServiceServer.ListPatchStatus),
+	// Agent updates. Packages are uploaded and downloaded with the
+	// streaming UploadAgentPackage and DownloadAgentPackage RPCs only
+	// (agentctl packages upload).
+	newRoute("GET", "/v1/agent-packages", "ListAgentPackages", "Uploaded agent packages, newest first", pb.AgentServiceServer.ListAgentPackages),
+	newRoute("POST", "/v1/rollouts", "CreateRollout", "Start a staged rollout of an agent version", pb.AgentServiceServer.CreateRollout),
+	newRoute("POST", "/v1/rollouts/{rollout_id}", "UpdateRollout", "Widen, narrow, pause, resume or cancel a rollout", pb.AgentServiceServer.UpdateRollout),
+	newRoute("GET", "/v1/rollouts", "ListRollouts", "Rollouts with the number of offered, succeeded and failed updates, newest first", pb.AgentServiceServer.ListRollouts),
+	newRoute("GET", "/v1/rollouts/{rollout_id}/updates", "ListAgentUpdates", "Updates a rollout offered to agents and their outcome", pb.AgentServiceServer.ListAgentUpdates),
+	// Files collected from agents. Agents upload them with the streaming
+	// UploadCollectedFile RPC and they are downloaded with the streaming
+	// DownloadCollectedFile RPC only (agentctl files download).
+	newRoute("GET", "/v1/file-collections", "ListFileCollections", "Files requested from agents with their upload status, newest first", pb.AgentServiceServer.ListFileCollections),
+	// Scheduled and recurring fleet jobs.
+	newRoute("POST", "/v1/scheduled-jobs", "CreateScheduledJob", "Schedule a firewall change, script or inventory report on a cron expression or once, against a set of agents", pb.AgentServiceServer.CreateScheduledJob),
+	newRoute("GET", "/v1/scheduled-jobs", "ListScheduledJobs", "Scheduled jobs with their next and last run, by name", pb.AgentServiceServer.ListScheduledJobs),
+	newRoute("POST", "/v1/scheduled-jobs/{job_id}", "UpdateScheduledJob", "Pause or resume a scheduled job", pb.AgentServiceServer.UpdateScheduledJob),
+	newRoute("DELETE", "/v1/scheduled-jobs/{job_id}", "DeleteScheduledJob", "Delete a scheduled job and its run history", pb.AgentServiceServer.DeleteScheduledJob),
+	newRoute("POST", "/v1/scheduled-jobs/{job_id}/run", "RunScheduledJob", "Start a run of a scheduled job now, outside its schedule", pb.AgentServiceServer.RunScheduledJob),
+	newRoute("GET", "/v1/job-runs", "ListJobRuns", "Runs of scheduled jobs, including skipped ones, newest first", pb.AgentServiceServer.ListJobRuns),
+	newRoute("GET", "/v1/job-runs/{run_id}", "GetJobRun", "A run of a scheduled job with the status and command of each agent", pb.AgentServiceServer.GetJobRun),
+
+	// Fleet exports are streamed by the ExportFleetData RPC only
+	// (agentctl export).
+}
@@ -0,0 +1,79 @@
+// Package logging adds log levels on top of the standard log package.
+// The level can be changed at runtime when the configuration is reloaded.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the minimum severity that gets written.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var current atomic.Int32
+
+func init() {
+	current.Store(int32(LevelInfo))
+}
+
+// ParseLevel converts a config value such as "debug" into a Level.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// SetLevel changes the minimum level for all subsequent log calls.
+func SetLevel(l Level) {
+	current.Store(int32(l))
+}
+
+// Enabled reports whether messages at level l are currently written.
+func Enabled(l Level) bool {
+	return Level(current.Load()) <= l
+}
+
+// Debugf logs high-volume messages such as individual heartbeats.
+func Debugf(format string, args ...interface{}) {
+	if Enabled(LevelDebug) {
+		log.Printf(format, args...)
+	}
+}
+
+// Infof logs normal operational messages.
+func Infof(format string, args ...interface{}) {
+	if Enabled(LevelInfo) {
+		log.Printf(format, args...)
+	}
+}
+
+// Warnf logs recoverable problems.
+func Warnf(format string, args ...interface{}) {
+	if Enabled(LevelWarn) {
+		log.Printf(format, args...)
+	}
+}
+
+// Errorf logs failures.
+func Errorf(format string, args ...interface{}) {
+	if Enabled(LevelError) {
+		log.Printf(format, args...)
+	}
+}
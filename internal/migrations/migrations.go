@@ -0,0 +1,295 @@
+// Package migrations applies the numbered SQL files embedded in sql/ to the
+// Postgres schema. Each file pair is named NNNN_description.up.sql and
+// NNNN_description.down.sql; the highest number is the schema version this
+// binary knows about.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// advisoryLockKey is an arbitrary constant shared by every replica so only
+// one of them migrates at a time.
+const advisoryLockKey int64 = 7_302_551_004
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes whether a migration has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer binary.
+type ErrSchemaTooNew struct {
+	Database int
+	Binary   int
+}
+
+func (e *ErrSchemaTooNew) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than this binary supports (%d); upgrade the server", e.Database, e.Binary)
+}
+
+// All returns the embedded migrations ordered by version.
+func All() ([]Migration, error) {
+	return load(files)
+}
+
+// load reads the migrations in the sql directory of fsys.
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration file %q does not match NNNN_name.(up|down).sql", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, "sql/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	all := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d (%s) needs both an up and a down file", m.Version, m.Name)
+		}
+		all = append(all, *m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	for i, m := range all {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous from 1, found %d at position %d", m.Version, i+1)
+		}
+	}
+	return all, nil
+}
+
+// Latest returns the highest migration version embedded in the binary.
+func Latest() (int, error) {
+	all, err := All()
+	if err != nil {
+		return 0, err
+	}
+	if len(all) == 0 {
+		return 0, nil
+	}
+	return all[len(all)-1].Version, nil
+}
+
+// Migrator applies migrations to a Postgres database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New creates a migrator for db using the embedded migrations.
+func New(db *sql.DB) (*Migrator, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: all}, nil
+}
+
+// Version returns the schema version currently recorded in the database.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	if err := m.ensureTable(ctx, m.db); err != nil {
+		return 0, err
+	}
+	var v sql.NullInt64
+	if err := m.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&v); err != nil {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+// Check returns an error if the database cannot be used by this binary:
+// either it is newer than the embedded migrations or migrations are pending.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	latest := len(m.migrations)
+	if current > latest {
+		return &ErrSchemaTooNew{Database: current, Binary: latest}
+	}
+	if current < latest {
+		return fmt.Errorf("database schema version %d is behind %d; run `migrate up`", current, latest)
+	}
+	return nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, len(m.migrations))
+}
+
+// Down reverts the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if current == 0 {
+		return nil
+	}
+	return m.To(ctx, current-1)
+}
+
+// To migrates up or down until the schema is at the given version.
+func (m *Migrator) To(ctx context.Context, target int) error {
+	if target < 0 || target > len(m.migrations) {
+		return fmt.Errorf("unknown migration version %d (latest is %d)", target, len(m.migrations))
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		if err := m.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+		var current sql.NullInt64
+		if err := conn.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&current); err != nil {
+			return err
+		}
+		version := int(current.Int64)
+		if version > len(m.migrations) {
+			return &ErrSchemaTooNew{Database: version, Binary: len(m.migrations)}
+		}
+
+		for version < target {
+			mig := m.migrations[version]
+			if err := apply(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, NOW())`, mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("migration %d (%s) up failed: %w", mig.Version, mig.Name, err)
+			}
+			version++
+		}
+		for version > target {
+			mig := m.migrations[version-1]
+			if err := apply(ctx, conn, mig.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+				return fmt.Errorf("migration %d (%s) down failed: %w", mig.Version, mig.Name, err)
+			}
+			version--
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTable(ctx, m.db); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		applied[v] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		at, ok := applied[mig.Version]
+		out = append(out, Status{Migration: mig, Applied: ok, AppliedAt: at})
+	}
+	return out, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, db execer) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`)
+	return err
+}
+
+// withLock runs fn on a single connection holding the migration advisory lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+
+	return fn(conn)
+}
+
+// apply runs a migration body and its bookkeeping statement in one transaction.
+func apply(ctx context.Context, conn *sql.Conn, body, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
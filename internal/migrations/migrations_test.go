@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 2*len(all) {
+		t.Fatalf("%d files in sql/ for %d migrations, want an up and a down file each", len(entries), len(all))
+	}
+
+	names := map[string]int{}
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d at position %d, want versions in order from 1", m.Version, i+1)
+		}
+		if prev, ok := names[m.Name]; ok {
+			t.Errorf("migrations %d and %d are both named %q", prev, m.Version, m.Name)
+		}
+		names[m.Name] = m.Version
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d (%s) has an empty up or down file", m.Version, m.Name)
+		}
+	}
+	if all[0].Name != "initial_schema" {
+		t.Fatalf("first migration is %q, want initial_schema", all[0].Name)
+	}
+
+	latest, err := Latest()
+	if err != nil || latest != len(all) {
+		t.Fatalf("Latest = %d, %v; want %d, nil", latest, err, len(all))
+	}
+}
+
+func TestLoadOrdersByVersionNumber(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, name := range []string{"0010_tenth", "0002_second", "0001_first", "0003_third", "0004_fourth", "0005_fifth", "0006_sixth", "0007_seventh", "0008_eighth", "0009_ninth", "11_eleventh"} {
+		fsys["sql/"+name+".up.sql"] = &fstest.MapFile{Data: []byte("-- up " + name)}
+		fsys["sql/"+name+".down.sql"] = &fstest.MapFile{Data: []byte("-- down " + name)}
+	}
+	all, err := load(fsys)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	// "11_eleventh" sorts before "0010_tenth" by name but after it by number.
+	if len(all) != 11 || all[9].Name != "tenth" || all[10].Name != "eleventh" || all[10].Up != "-- up 11_eleventh" {
+		t.Fatalf("load = %+v, want migrations ordered by version", all)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"bad name", fstest.MapFS{
+			"sql/0001_first.up.sql": file("up"), "sql/0001_first.down.sql": file("down"),
+			"sql/0002-second.up.sql": file("up"),
+		}, "does not match"},
+		{"missing down", fstest.MapFS{
+			"sql/0001_first.up.sql": file("up"),
+		}, "needs both"},
+		{"conflicting names", fstest.MapFS{
+			"sql/0001_first.up.sql": file("up"), "sql/0001_other.down.sql": file("down"),
+		}, "conflicting names"},
+		{"gap", fstest.MapFS{
+			"sql/0001_first.up.sql": file("up"), "sql/0001_first.down.sql": file("down"),
+			"sql/0003_third.up.sql": file("up"), "sql/0003_third.down.sql": file("down"),
+		}, "contiguous"},
+		{"not from 1", fstest.MapFS{
+			"sql/0002_second.up.sql": file("up"), "sql/0002_second.down.sql": file("down"),
+		}, "contiguous"},
+		{"no sql directory", fstest.MapFS{}, "sql"},
+	}
+	for _, tt := range tests {
+		if _, err := load(tt.fsys); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("load(%s) = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
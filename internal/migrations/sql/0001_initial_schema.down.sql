@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS installed_applications;
+DROP TABLE IF EXISTS firewall_rules;
+DROP TABLE IF EXISTS agents;
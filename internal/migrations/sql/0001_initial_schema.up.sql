@@ -0,0 +1,50 @@
+-- Initial schema. Matches the tables previously created by gorm AutoMigrate,
+-- so existing databases can adopt versioned migrations without changes.
+
+CREATE TABLE IF NOT EXISTS agents (
+    id             BIGSERIAL PRIMARY KEY,
+    agent_id       TEXT,
+    hostname       TEXT,
+    os_name        TEXT,
+    os_version     TEXT,
+    kernel_version TEXT,
+    cpu_cores      INTEGER,
+    memory_gb      NUMERIC,
+    disk_space_gb  NUMERIC,
+    status         TEXT,
+    last_seen      TIMESTAMPTZ,
+    last_known_ip  TEXT,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    deleted_at     TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agents_agent_id ON agents (agent_id);
+CREATE INDEX IF NOT EXISTS idx_agents_deleted_at ON agents (deleted_at);
+
+CREATE TABLE IF NOT EXISTS firewall_rules (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    agent_id   BIGINT,
+    name       VARCHAR(255),
+    port       VARCHAR(50),
+    protocol   VARCHAR(50),
+    action     VARCHAR(50),
+    direction  VARCHAR(50),
+    enabled    BOOLEAN
+);
+CREATE INDEX IF NOT EXISTS idx_firewall_rules_deleted_at ON firewall_rules (deleted_at);
+
+CREATE TABLE IF NOT EXISTS installed_applications (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ,
+    agent_id     BIGINT,
+    name         VARCHAR(255),
+    version      VARCHAR(100),
+    install_date TIMESTAMPTZ,
+    publisher    VARCHAR(255)
+);
+CREATE INDEX IF NOT EXISTS idx_installed_applications_deleted_at ON installed_applications (deleted_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_installed_applications_agent_id;
+DROP INDEX IF EXISTS idx_firewall_rules_agent_id;
+DROP INDEX IF EXISTS idx_agents_status;
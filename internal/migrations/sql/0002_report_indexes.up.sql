@@ -0,0 +1,4 @@
+-- Lookups by agent and by status were sequential scans under AutoMigrate.
+CREATE INDEX IF NOT EXISTS idx_agents_status ON agents (status);
+CREATE INDEX IF NOT EXISTS idx_firewall_rules_agent_id ON firewall_rules (agent_id);
+CREATE INDEX IF NOT EXISTS idx_installed_applications_agent_id ON installed_applications (agent_id);
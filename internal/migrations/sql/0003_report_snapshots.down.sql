@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_installed_applications_reported_at;
+DROP INDEX IF EXISTS idx_firewall_rules_reported_at;
+ALTER TABLE installed_applications DROP COLUMN IF EXISTS reported_at;
+ALTER TABLE firewall_rules DROP COLUMN IF EXISTS reported_at;
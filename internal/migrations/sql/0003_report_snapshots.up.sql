@@ -0,0 +1,11 @@
+-- Each report now stamps all of its rows with the same reported_at, so the
+-- current firewall rules / apps of an agent are the rows with the latest value.
+ALTER TABLE firewall_rules ADD COLUMN IF NOT EXISTS reported_at TIMESTAMPTZ;
+ALTER TABLE installed_applications ADD COLUMN IF NOT EXISTS reported_at TIMESTAMPTZ;
+
+-- Backfill: rows written by one report were created within the same second.
+UPDATE firewall_rules SET reported_at = date_trunc('second', created_at) WHERE reported_at IS NULL;
+UPDATE installed_applications SET reported_at = date_trunc('second', created_at) WHERE reported_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_firewall_rules_reported_at ON firewall_rules (reported_at);
+CREATE INDEX IF NOT EXISTS idx_installed_applications_reported_at ON installed_applications (reported_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS commands (
+    id             BIGSERIAL PRIMARY KEY,
+    agent_id       VARCHAR(255) NOT NULL REFERENCES agents (agent_id),
+    type           VARCHAR(50) NOT NULL,
+    payload        BYTEA,
+    status         VARCHAR(20) NOT NULL,
+    result_message TEXT,
+    sent_at        TIMESTAMPTZ,
+    completed_at   TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_commands_agent_id ON commands (agent_id);
+CREATE INDEX IF NOT EXISTS idx_commands_status ON commands (status);
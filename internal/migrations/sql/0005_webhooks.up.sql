@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id          BIGSERIAL PRIMARY KEY,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    event_types TEXT,
+    description TEXT,
+    enabled     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    subscription_id  BIGINT NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id         VARCHAR(64) NOT NULL,
+    event_type       VARCHAR(64) NOT NULL,
+    agent_id         VARCHAR(255),
+    payload          BYTEA NOT NULL,
+    status           VARCHAR(16) NOT NULL,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL,
+    last_error       TEXT,
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    delivered_at     TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
@@ -0,0 +1,2 @@
+-- Labels are reported by the agent at registration and used to group agents.
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS labels TEXT NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS vulnerability_findings;
+DROP TABLE IF EXISTS vulnerabilities;
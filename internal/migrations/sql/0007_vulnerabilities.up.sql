@@ -0,0 +1,33 @@
+-- The imported feed is replaced as a whole on every import.
+CREATE TABLE IF NOT EXISTS vulnerabilities (
+    id               BIGSERIAL PRIMARY KEY,
+    vulnerability_id VARCHAR(128) NOT NULL,
+    aliases          TEXT,
+    summary          TEXT,
+    severity         VARCHAR(16) NOT NULL,
+    cvss_score       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    source           VARCHAR(16),
+    affected         TEXT NOT NULL DEFAULT '[]',
+    published_at     TIMESTAMPTZ,
+    modified_at      TIMESTAMPTZ,
+    imported_at      TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vulnerabilities_vulnerability_id ON vulnerabilities (vulnerability_id);
+CREATE INDEX IF NOT EXISTS idx_vulnerabilities_imported_at ON vulnerabilities (imported_at);
+
+-- Findings reference the feed by vulnerability_id only, so re-importing the
+-- feed does not cascade; every agent is re-evaluated after an import instead.
+CREATE TABLE IF NOT EXISTS vulnerability_findings (
+    id               BIGSERIAL PRIMARY KEY,
+    agent_id         VARCHAR(255) NOT NULL REFERENCES agents (agent_id),
+    vulnerability_id VARCHAR(128) NOT NULL,
+    severity         VARCHAR(16) NOT NULL,
+    cvss_score       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    product          TEXT,
+    app_name         TEXT,
+    app_version      TEXT,
+    fixed_version    TEXT,
+    detected_at      TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_vulnerability_findings_agent_id ON vulnerability_findings (agent_id);
+CREATE INDEX IF NOT EXISTS idx_vulnerability_findings_vulnerability_id ON vulnerability_findings (vulnerability_id);
@@ -0,0 +1,16 @@
+-- Recomputed from the latest firewall report of the agent on every report.
+CREATE TABLE IF NOT EXISTS firewall_findings (
+    id           BIGSERIAL PRIMARY KEY,
+    agent_id     VARCHAR(255) NOT NULL REFERENCES agents (agent_id),
+    kind         VARCHAR(32) NOT NULL,
+    severity     VARCHAR(16) NOT NULL,
+    rule_name    TEXT,
+    related_rule TEXT,
+    direction    VARCHAR(50),
+    protocol     VARCHAR(50),
+    port         VARCHAR(50),
+    message      TEXT,
+    detected_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_firewall_findings_agent_id ON firewall_findings (agent_id);
+CREATE INDEX IF NOT EXISTS idx_firewall_findings_kind ON firewall_findings (kind);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS compliance_evaluations;
+DROP TABLE IF EXISTS compliance_results;
+DROP TABLE IF EXISTS compliance_policies;
@@ -0,0 +1,47 @@
+-- Every loaded version of a policy is kept so that old results can be traced
+-- back to the definition they were evaluated against.
+CREATE TABLE IF NOT EXISTS compliance_policies (
+    id          BIGSERIAL PRIMARY KEY,
+    policy_id   VARCHAR(128) NOT NULL,
+    version     INTEGER NOT NULL,
+    name        TEXT,
+    description TEXT,
+    checks      INTEGER NOT NULL DEFAULT 0,
+    digest      VARCHAR(64) NOT NULL,
+    definition  TEXT NOT NULL,
+    loaded_at   TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_compliance_policy_version ON compliance_policies (policy_id, version);
+
+-- The current result of every check; replaced on each evaluation.
+CREATE TABLE IF NOT EXISTS compliance_results (
+    id             BIGSERIAL PRIMARY KEY,
+    agent_id       VARCHAR(255) NOT NULL REFERENCES agents (agent_id),
+    policy_id      VARCHAR(128) NOT NULL,
+    policy_version INTEGER NOT NULL,
+    check_id       VARCHAR(128) NOT NULL,
+    title          TEXT,
+    severity       VARCHAR(16) NOT NULL,
+    status         VARCHAR(16) NOT NULL,
+    evidence       TEXT,
+    since          TIMESTAMPTZ NOT NULL,
+    evaluated_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_compliance_results_agent_id ON compliance_results (agent_id);
+CREATE INDEX IF NOT EXISTS idx_compliance_results_policy_id ON compliance_results (policy_id);
+
+-- Appended only when the outcome of a policy on an agent changes.
+CREATE TABLE IF NOT EXISTS compliance_evaluations (
+    id             BIGSERIAL PRIMARY KEY,
+    agent_id       VARCHAR(255) NOT NULL REFERENCES agents (agent_id),
+    policy_id      VARCHAR(128) NOT NULL,
+    policy_version INTEGER NOT NULL,
+    passed         INTEGER NOT NULL DEFAULT 0,
+    failed         INTEGER NOT NULL DEFAULT 0,
+    not_applicable INTEGER NOT NULL DEFAULT 0,
+    failed_checks  TEXT,
+    evaluated_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_compliance_evaluations_agent_id ON compliance_evaluations (agent_id);
+CREATE INDEX IF NOT EXISTS idx_compliance_evaluations_policy_id ON compliance_evaluations (policy_id);
+CREATE INDEX IF NOT EXISTS idx_compliance_evaluations_evaluated_at ON compliance_evaluations (evaluated_at);
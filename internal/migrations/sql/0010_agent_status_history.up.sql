@@ -0,0 +1,11 @@
+-- One row per agent status transition, kept for fleet exports and
+-- availability reports.
+CREATE TABLE IF NOT EXISTS agent_status_history (
+    id          BIGSERIAL PRIMARY KEY,
+    agent_id    VARCHAR(255) NOT NULL REFERENCES agents (agent_id),
+    from_status VARCHAR(20),
+    to_status   VARCHAR(20) NOT NULL,
+    changed_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_agent_status_history_agent_id ON agent_status_history (agent_id);
+CREATE INDEX IF NOT EXISTS idx_agent_status_history_changed_at ON agent_status_history (changed_at);
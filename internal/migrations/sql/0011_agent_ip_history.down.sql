@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS agent_ip_history;
+ALTER TABLE agents DROP COLUMN IF EXISTS last_peer_ip;
@@ -0,0 +1,24 @@
+-- The address the server sees an agent connect from, next to the one the
+-- agent reports, to spot NAT and spoofed addresses.
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS last_peer_ip VARCHAR(64);
+
+-- One row per distinct reported/peer address pair of an agent, with the
+-- first and last time the agent was seen at it.
+CREATE TABLE IF NOT EXISTS agent_ip_history (
+    id         BIGSERIAL PRIMARY KEY,
+    agent_id   VARCHAR(255) NOT NULL REFERENCES agents (agent_id),
+    ip         VARCHAR(64) NOT NULL DEFAULT '',
+    peer_ip    VARCHAR(64) NOT NULL DEFAULT '',
+    first_seen TIMESTAMPTZ NOT NULL,
+    last_seen  TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_ip_history_address ON agent_ip_history (agent_id, ip, peer_ip);
+CREATE INDEX IF NOT EXISTS idx_agent_ip_history_ip ON agent_ip_history (ip);
+CREATE INDEX IF NOT EXISTS idx_agent_ip_history_peer_ip ON agent_ip_history (peer_ip);
+CREATE INDEX IF NOT EXISTS idx_agent_ip_history_last_seen ON agent_ip_history (last_seen);
+
+-- Backfill: the only address known so far is the last one each agent reported.
+INSERT INTO agent_ip_history (agent_id, ip, first_seen, last_seen)
+SELECT agent_id, last_known_ip, last_seen, last_seen FROM agents
+WHERE last_known_ip IS NOT NULL AND last_known_ip <> '' AND last_seen IS NOT NULL AND deleted_at IS NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_agents_clone_suspected_at;
+ALTER TABLE agents DROP COLUMN IF EXISTS clone_reason;
+ALTER TABLE agents DROP COLUMN IF EXISTS clone_suspected_at;
+ALTER TABLE agents DROP COLUMN IF EXISTS instance_nonce;
@@ -0,0 +1,7 @@
+-- Every registration gives the agent instance a new nonce that it sends back
+-- with its heartbeats; another instance using the same agent_id shows up as
+-- a heartbeat or registration with an outdated nonce.
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS instance_nonce VARCHAR(64);
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS clone_suspected_at TIMESTAMPTZ;
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS clone_reason VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_agents_clone_suspected_at ON agents (clone_suspected_at);
@@ -0,0 +1,18 @@
+-- Resource usage reported with heartbeats. Raw samples are rolled up into
+-- 5-minute and hourly buckets; each row keeps count, sum, min and max so that
+-- buckets can be merged again. resolution is the bucket length in seconds,
+-- 0 for a raw sample, and mount is only set for disk usage.
+CREATE TABLE IF NOT EXISTS agent_metrics (
+    id           BIGSERIAL PRIMARY KEY,
+    agent_id     VARCHAR(255) NOT NULL REFERENCES agents (agent_id),
+    resolution   INTEGER NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    metric       VARCHAR(64) NOT NULL,
+    mount        VARCHAR(255) NOT NULL DEFAULT '',
+    count        INTEGER NOT NULL,
+    sum          DOUBLE PRECISION NOT NULL,
+    min          DOUBLE PRECISION NOT NULL,
+    max          DOUBLE PRECISION NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_metrics_point ON agent_metrics (agent_id, resolution, bucket_start, metric, mount);
+CREATE INDEX IF NOT EXISTS idx_agent_metrics_resolution ON agent_metrics (resolution, bucket_start);
@@ -0,0 +1,21 @@
+-- Alerts raised by the server-side alert rules. A rule raises at most one
+-- open (pending or firing) alert per key, such as an agent, an agent and a
+-- mount point, or a label value; resolved alerts are kept as history.
+CREATE TABLE IF NOT EXISTS alerts (
+    id          BIGSERIAL PRIMARY KEY,
+    rule_id     VARCHAR(128) NOT NULL,
+    key         VARCHAR(512) NOT NULL,
+    agent_id    VARCHAR(255) NOT NULL DEFAULT '',
+    severity    VARCHAR(16) NOT NULL,
+    state       VARCHAR(16) NOT NULL,
+    summary     TEXT NOT NULL DEFAULT '',
+    value       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    started_at  TIMESTAMPTZ NOT NULL,
+    fired_at    TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_open ON alerts (rule_id, key) WHERE state <> 'RESOLVED';
+CREATE INDEX IF NOT EXISTS idx_alerts_agent_id ON alerts (agent_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_state ON alerts (state);
+CREATE INDEX IF NOT EXISTS idx_alerts_resolved_at ON alerts (resolved_at);
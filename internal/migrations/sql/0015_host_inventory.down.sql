@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS running_processes;
+DROP TABLE IF EXISTS listening_sockets;
+DROP TABLE IF EXISTS system_services;
+DROP TABLE IF EXISTS local_groups;
+DROP TABLE IF EXISTS local_users;
+DROP TABLE IF EXISTS network_interfaces;
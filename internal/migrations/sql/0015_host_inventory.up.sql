@@ -0,0 +1,108 @@
+-- Extended host inventory. Like firewall_rules and installed_applications,
+-- each report is a full snapshot whose rows share one reported_at, and the
+-- rows with an agent's latest reported_at are its current inventory.
+-- agent_id is agents.id; lists are stored as JSON text.
+CREATE TABLE IF NOT EXISTS network_interfaces (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ,
+    agent_id     BIGINT,
+    reported_at  TIMESTAMPTZ,
+    name         VARCHAR(255),
+    mac          VARCHAR(50),
+    ip_addresses TEXT,
+    mtu          INTEGER,
+    up           BOOLEAN
+);
+CREATE INDEX IF NOT EXISTS idx_network_interfaces_deleted_at ON network_interfaces (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_network_interfaces_agent_id ON network_interfaces (agent_id);
+CREATE INDEX IF NOT EXISTS idx_network_interfaces_reported_at ON network_interfaces (reported_at);
+
+CREATE TABLE IF NOT EXISTS local_users (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    agent_id    BIGINT,
+    reported_at TIMESTAMPTZ,
+    name        VARCHAR(255),
+    uid         VARCHAR(255),
+    full_name   VARCHAR(255),
+    groups      TEXT,
+    disabled    BOOLEAN,
+    last_logon  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_local_users_deleted_at ON local_users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_local_users_agent_id ON local_users (agent_id);
+CREATE INDEX IF NOT EXISTS idx_local_users_reported_at ON local_users (reported_at);
+
+CREATE TABLE IF NOT EXISTS local_groups (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    agent_id    BIGINT,
+    reported_at TIMESTAMPTZ,
+    name        VARCHAR(255),
+    gid         VARCHAR(255),
+    members     TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_local_groups_deleted_at ON local_groups (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_local_groups_agent_id ON local_groups (agent_id);
+CREATE INDEX IF NOT EXISTS idx_local_groups_reported_at ON local_groups (reported_at);
+
+CREATE TABLE IF NOT EXISTS system_services (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ,
+    agent_id     BIGINT,
+    reported_at  TIMESTAMPTZ,
+    name         VARCHAR(255),
+    display_name VARCHAR(255),
+    start_type   VARCHAR(20),
+    state        VARCHAR(20),
+    account      VARCHAR(255),
+    binary_path  VARCHAR(1024)
+);
+CREATE INDEX IF NOT EXISTS idx_system_services_deleted_at ON system_services (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_system_services_agent_id ON system_services (agent_id);
+CREATE INDEX IF NOT EXISTS idx_system_services_reported_at ON system_services (reported_at);
+
+CREATE TABLE IF NOT EXISTS listening_sockets (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ,
+    agent_id     BIGINT,
+    reported_at  TIMESTAMPTZ,
+    protocol     VARCHAR(10),
+    address      VARCHAR(100),
+    port         INTEGER,
+    pid          INTEGER,
+    process_name VARCHAR(255)
+);
+CREATE INDEX IF NOT EXISTS idx_listening_sockets_deleted_at ON listening_sockets (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_listening_sockets_agent_id ON listening_sockets (agent_id);
+CREATE INDEX IF NOT EXISTS idx_listening_sockets_reported_at ON listening_sockets (reported_at);
+
+CREATE TABLE IF NOT EXISTS running_processes (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ,
+    agent_id     BIGINT,
+    reported_at  TIMESTAMPTZ,
+    pid          INTEGER,
+    parent_pid   INTEGER,
+    name         VARCHAR(255),
+    "user"       VARCHAR(255),
+    command_line TEXT,
+    started_at   TIMESTAMPTZ,
+    cpu_percent  DOUBLE PRECISION,
+    memory_mb    DOUBLE PRECISION
+);
+CREATE INDEX IF NOT EXISTS idx_running_processes_deleted_at ON running_processes (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_running_processes_agent_id ON running_processes (agent_id);
+CREATE INDEX IF NOT EXISTS idx_running_processes_reported_at ON running_processes (reported_at);
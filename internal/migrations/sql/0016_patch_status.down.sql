@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pending_updates;
+DROP TABLE IF EXISTS installed_patches;
+DROP TABLE IF EXISTS agent_patch_statuses;
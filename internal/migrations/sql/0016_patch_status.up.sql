@@ -0,0 +1,53 @@
+-- OS patch status. agent_patch_statuses has one row per agent that each
+-- report replaces; installed_patches and pending_updates are snapshots like
+-- the host inventory, sharing the status's reported_at.
+CREATE TABLE IF NOT EXISTS agent_patch_statuses (
+    id                     BIGSERIAL PRIMARY KEY,
+    created_at             TIMESTAMPTZ,
+    updated_at             TIMESTAMPTZ,
+    deleted_at             TIMESTAMPTZ,
+    agent_id               BIGINT,
+    last_update_at         TIMESTAMPTZ,
+    reboot_pending         BOOLEAN,
+    reboot_pending_since   TIMESTAMPTZ,
+    installed_count        BIGINT,
+    pending_count          BIGINT,
+    security_pending_count BIGINT,
+    reported_at            TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_agent_patch_statuses_deleted_at ON agent_patch_statuses (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_patch_statuses_agent_id ON agent_patch_statuses (agent_id);
+CREATE INDEX IF NOT EXISTS idx_agent_patch_statuses_reboot_pending_since ON agent_patch_statuses (reboot_pending_since);
+
+CREATE TABLE IF NOT EXISTS installed_patches (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ,
+    agent_id     BIGINT,
+    reported_at  TIMESTAMPTZ,
+    patch_id     VARCHAR(255),
+    title        TEXT,
+    version      VARCHAR(100),
+    installed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_installed_patches_deleted_at ON installed_patches (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_installed_patches_agent_id ON installed_patches (agent_id);
+CREATE INDEX IF NOT EXISTS idx_installed_patches_reported_at ON installed_patches (reported_at);
+CREATE INDEX IF NOT EXISTS idx_installed_patches_patch_id ON installed_patches (patch_id);
+
+CREATE TABLE IF NOT EXISTS pending_updates (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    agent_id    BIGINT,
+    reported_at TIMESTAMPTZ,
+    patch_id    VARCHAR(255),
+    title       TEXT,
+    version     VARCHAR(100),
+    security    BOOLEAN
+);
+CREATE INDEX IF NOT EXISTS idx_pending_updates_deleted_at ON pending_updates (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_pending_updates_agent_id ON pending_updates (agent_id);
+CREATE INDEX IF NOT EXISTS idx_pending_updates_reported_at ON pending_updates (reported_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS command_output_chunks;
+ALTER TABLE commands DROP COLUMN IF EXISTS output_truncated;
+ALTER TABLE commands DROP COLUMN IF EXISTS output_bytes;
+ALTER TABLE commands DROP COLUMN IF EXISTS exit_code;
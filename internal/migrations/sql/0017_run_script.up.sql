@@ -0,0 +1,14 @@
+-- RunScript commands report an exit code and stream their output back in
+-- chunks; the output of each command is capped at scripts.max_output_bytes.
+ALTER TABLE commands ADD COLUMN IF NOT EXISTS exit_code INTEGER;
+ALTER TABLE commands ADD COLUMN IF NOT EXISTS output_bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE commands ADD COLUMN IF NOT EXISTS output_truncated BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS command_output_chunks (
+    id         BIGSERIAL PRIMARY KEY,
+    command_id BIGINT NOT NULL REFERENCES commands (id) ON DELETE CASCADE,
+    stream     VARCHAR(10) NOT NULL,
+    data       BYTEA,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_command_output_chunks_command_id ON command_output_chunks (command_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS agent_updates;
+DROP TABLE IF EXISTS rollouts;
+DROP TABLE IF EXISTS agent_packages;
+DROP INDEX IF EXISTS idx_agents_agent_version;
+ALTER TABLE agents DROP COLUMN IF EXISTS arch;
+ALTER TABLE agents DROP COLUMN IF EXISTS agent_version;
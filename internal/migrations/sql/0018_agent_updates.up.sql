@@ -0,0 +1,48 @@
+-- Agents report their version and architecture at registration. Signed agent
+-- packages are uploaded per version, OS and architecture and offered to
+-- agents by staged rollouts; agent_updates records each offer and its result.
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS agent_version VARCHAR(64);
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS arch VARCHAR(32);
+CREATE INDEX IF NOT EXISTS idx_agents_agent_version ON agents (agent_version);
+
+CREATE TABLE IF NOT EXISTS agent_packages (
+    id         BIGSERIAL PRIMARY KEY,
+    version    VARCHAR(64) NOT NULL,
+    os         VARCHAR(32) NOT NULL,
+    arch       VARCHAR(32) NOT NULL,
+    sha256     VARCHAR(64) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    signature  TEXT,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_packages_target ON agent_packages (version, os, arch);
+
+CREATE TABLE IF NOT EXISTS rollouts (
+    id               BIGSERIAL PRIMARY KEY,
+    version          VARCHAR(64) NOT NULL,
+    percentage       INTEGER NOT NULL DEFAULT 0,
+    groups           TEXT,
+    max_failure_rate DOUBLE PRECISION NOT NULL DEFAULT 0,
+    min_results      INTEGER NOT NULL DEFAULT 0,
+    status           VARCHAR(16) NOT NULL,
+    pause_reason     TEXT,
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_rollouts_version ON rollouts (version);
+CREATE INDEX IF NOT EXISTS idx_rollouts_status ON rollouts (status);
+
+CREATE TABLE IF NOT EXISTS agent_updates (
+    id           BIGSERIAL PRIMARY KEY,
+    rollout_id   BIGINT NOT NULL REFERENCES rollouts (id) ON DELETE CASCADE,
+    agent_id     BIGINT NOT NULL REFERENCES agents (id) ON DELETE CASCADE,
+    from_version VARCHAR(64),
+    to_version   VARCHAR(64),
+    status       VARCHAR(16) NOT NULL,
+    message      TEXT,
+    offered_at   TIMESTAMPTZ,
+    finished_at  TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_updates_rollout_agent ON agent_updates (rollout_id, agent_id);
+CREATE INDEX IF NOT EXISTS idx_agent_updates_agent_id ON agent_updates (agent_id);
+CREATE INDEX IF NOT EXISTS idx_agent_updates_status ON agent_updates (status);
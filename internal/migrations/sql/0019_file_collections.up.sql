@@ -0,0 +1,25 @@
+-- Files operators collect from agents with COLLECT_FILE commands. The content
+-- is kept in the artifact store; rows stay after it expires for the audit
+-- trail.
+CREATE TABLE IF NOT EXISTS file_collections (
+    id             BIGSERIAL PRIMARY KEY,
+    command_id     BIGINT NOT NULL,
+    agent_id       VARCHAR(255) NOT NULL,
+    path           TEXT NOT NULL,
+    reason         TEXT,
+    max_bytes      BIGINT NOT NULL DEFAULT 0,
+    status         VARCHAR(20) NOT NULL,
+    size_bytes     BIGINT NOT NULL DEFAULT 0,
+    sha256         VARCHAR(64),
+    received_bytes BIGINT NOT NULL DEFAULT 0,
+    parts          INTEGER NOT NULL DEFAULT 0,
+    storage_key    TEXT,
+    message        TEXT,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    completed_at   TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_file_collections_command_id ON file_collections (command_id);
+CREATE INDEX IF NOT EXISTS idx_file_collections_agent_id ON file_collections (agent_id);
+CREATE INDEX IF NOT EXISTS idx_file_collections_status ON file_collections (status);
+CREATE INDEX IF NOT EXISTS idx_file_collections_created_at ON file_collections (created_at);
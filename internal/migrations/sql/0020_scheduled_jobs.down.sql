@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS job_run_targets;
+DROP TABLE IF EXISTS job_runs;
+DROP TABLE IF EXISTS scheduled_jobs;
@@ -0,0 +1,53 @@
+-- Jobs that queue a command on a set of agents on a cron schedule or once at
+-- a given time, their runs and the agents each run reached.
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    id               BIGSERIAL PRIMARY KEY,
+    name             VARCHAR(255) NOT NULL,
+    command_type     VARCHAR(50) NOT NULL,
+    payload          BYTEA,
+    cron             VARCHAR(255),
+    run_at           TIMESTAMPTZ,
+    timezone         VARCHAR(64),
+    catch_up         VARCHAR(16) NOT NULL,
+    max_concurrency  INTEGER NOT NULL DEFAULT 0,
+    target_all       BOOLEAN NOT NULL DEFAULT FALSE,
+    target_agent_ids TEXT,
+    target_labels    TEXT,
+    status           VARCHAR(16) NOT NULL,
+    next_run_at      TIMESTAMPTZ,
+    last_run_at      TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_jobs_name ON scheduled_jobs (name);
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_status ON scheduled_jobs (status);
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_next_run_at ON scheduled_jobs (next_run_at);
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id            BIGSERIAL PRIMARY KEY,
+    job_id        BIGINT NOT NULL,
+    trigger       VARCHAR(16) NOT NULL,
+    status        VARCHAR(16) NOT NULL,
+    scheduled_for TIMESTAMPTZ,
+    started_at    TIMESTAMPTZ,
+    finished_at   TIMESTAMPTZ,
+    targets       INTEGER NOT NULL DEFAULT 0,
+    succeeded     INTEGER NOT NULL DEFAULT 0,
+    failed        INTEGER NOT NULL DEFAULT 0,
+    message       TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_id ON job_runs (job_id);
+CREATE INDEX IF NOT EXISTS idx_job_runs_status ON job_runs (status);
+CREATE INDEX IF NOT EXISTS idx_job_runs_finished_at ON job_runs (finished_at);
+
+CREATE TABLE IF NOT EXISTS job_run_targets (
+    id            BIGSERIAL PRIMARY KEY,
+    run_id        BIGINT NOT NULL,
+    agent_id      VARCHAR(255) NOT NULL,
+    command_id    BIGINT NOT NULL DEFAULT 0,
+    status        VARCHAR(16) NOT NULL,
+    message       TEXT,
+    dispatched_at TIMESTAMPTZ,
+    finished_at   TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_run_targets_run_agent ON job_run_targets (run_id, agent_id);
@@ -0,0 +1,2 @@
+ALTER TABLE scheduled_jobs DROP COLUMN IF EXISTS script_digest;
+ALTER TABLE scheduled_jobs DROP COLUMN IF EXISTS script_name;
@@ -0,0 +1,4 @@
+-- The script a RUN_SCRIPT job runs, so the allowlist is checked again before
+-- each command the job queues rather than only when the job was created.
+ALTER TABLE scheduled_jobs ADD COLUMN IF NOT EXISTS script_name VARCHAR(255);
+ALTER TABLE scheduled_jobs ADD COLUMN IF NOT EXISTS script_digest VARCHAR(80);
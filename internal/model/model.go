@@ -1,6 +1,10 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -19,10 +23,63 @@ type Agent struct {
 	DiskSpaceGB   float64
 	Status        string
 	LastSeen      time.Time
-	LastKnownIP   string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	DeletedAt     gorm.DeletedAt `gorm:"index"`
+	LastKnownIP   string // العنوان الذي يبلغ عنه الوكيل
+	LastPeerIP    string `gorm:"size:64"` // العنوان الذي يرى الخادم الاتصال قادمًا منه
+	Labels        Labels // يرسلها الوكيل عند التسجيل، مثل env=prod أو site=riyadh
+	InstanceNonce string `gorm:"size:64"` // يُعطى للنسخة مع كل تسجيل وترسله مع نبضاتها
+	// AgentVersion إصدار برنامج الوكيل و Arch معمارية الجهاز (مثل amd64)، يرسلهما الوكيل عند التسجيل
+	AgentVersion string `gorm:"size:64;index"`
+	Arch         string `gorm:"size:32"`
+	// CloneSuspectedAt وقت الاشتباه بأن أكثر من جهاز يستخدم نفس agent_id، nil = لا اشتباه
+	CloneSuspectedAt *time.Time `gorm:"index"`
+	CloneReason      string     `gorm:"size:255"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+}
+
+// Labels أزواج مفتاح=قيمة لتجميع الوكلاء، تُخزن كـ JSON في عمود نصي
+type Labels map[string]string
+
+// Value يحول الملصقات إلى JSON عند الكتابة في قاعدة البيانات
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+// Scan يقرأ الملصقات من عمود JSON
+func (l *Labels) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into Labels", src)
+	}
+	return json.Unmarshal(data, l)
+}
+
+// GormDataType يحدد نوع العمود عند الترحيل التلقائي (SQLite)
+func (Labels) GormDataType() string {
+	return "text"
+}
+
+// Match يتحقق أن كل ملصقات المحدد موجودة بنفس القيمة
+func (l Labels) Match(selector map[string]string) bool {
+	for k, v := range selector {
+		if got, ok := l[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
 }
 
 // FirewallRule نموذج GORM يمثل قاعدة جدار حماية واحدة يبلغ عنها الوكيل 
@@ -35,6 +92,8 @@ type FirewallRule struct {
 	Action    string `gorm:"size:50"`
 	Direction string `gorm:"size:50"`
 	Enabled   bool
+	// ReportedAt نفس القيمة لكل القواعد في التقرير الواحد، وآخر قيمة تمثل الحالة الحالية
+	ReportedAt time.Time `gorm:"index"`
 }
 
 // InstalledApplication نموذج GORM 	يمثل تطبيقًا واحدًا مثبتًا على نظام الوكيل
@@ -45,4 +104,957 @@ type InstalledApplication struct {
 	Version     string    `gorm:"size:100"`
 	InstallDate time.Time
 	Publisher   string    `gorm:"size:255"`
+	ReportedAt  time.Time `gorm:"index"`
+}
+
+// Heartbeat آخر نبضة معروفة لوكيل، تُجمع في الذاكرة ثم تُكتب دفعة واحدة (ليست جدولًا)
+type Heartbeat struct {
+	AgentID string
+	IP      string // العنوان الذي أبلغ عنه الوكيل
+	PeerIP  string // عنوان الاتصال كما يراه الخادم، فارغ إن لم يُعرف
+	SeenAt  time.Time
+}
+
+// حالات الأمر المرسل للوكيل
+const (
+	CommandPending   = "PENDING"
+	CommandSent      = "SENT"
+	CommandSucceeded = "SUCCEEDED"
+	CommandFailed    = "FAILED"
+	CommandCancelled = "CANCELLED"
+	// CommandAwaitingApproval سكربت خارج القائمة المسموحة ينتظر موافقة المشرف قبل أن يصل للوكيل
+	CommandAwaitingApproval = "AWAITING_APPROVAL"
+)
+
+// أنواع الأوامر
+const (
+	CommandTypeFirewallConfiguration = "FIREWALL_CONFIGURATION"
+	CommandTypeRunScript             = "RUN_SCRIPT"
+	CommandTypeCollectFile           = "COLLECT_FILE"
+	CommandTypeReportInventory       = "REPORT_INVENTORY" // يعيد الوكيل إرسال كل تقاريره فورًا
+)
+
+// Command نموذج GORM يمثل أمرًا في طابور الوكيل ينتظر السحب عبر PollCommands
+type Command struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement"`
+	AgentID       string `gorm:"size:255;index"` // agents.agent_id وليس الرقم الداخلي
+	Type          string `gorm:"size:50"`
+	Payload       []byte // الطلب الأصلي بصيغة protobuf
+	Status        string `gorm:"size:20;index"`
+	ResultMessage string
+	SentAt        *time.Time
+	CompletedAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+
+	// نتيجة السكربتات: رمز الخروج وحجم المخرجات المحفوظة
+	ExitCode        *int32 // فارغ إن لم يرسله الوكيل
+	OutputBytes     int64
+	OutputTruncated bool // تجاوزت المخرجات الحد فحُذف الباقي
+}
+
+// مصادر مخرجات السكربت
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+// CommandOutputChunk جزء من مخرجات أمر كما أرسله الوكيل، بترتيب الوصول
+type CommandOutputChunk struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	CommandID uint   `gorm:"index"`
+	Stream    string `gorm:"size:10"` // stdout أو stderr
+	Data      []byte
+	CreatedAt time.Time
+}
+
+// AgentFilter شروط البحث في قائمة الوكلاء مع ترقيم الصفحات بالمؤشر
+type AgentFilter struct {
+	Status  string // فارغ = كل الحالات
+	AfterID uint   // يعيد الوكلاء الذين رقمهم أكبر من هذا
+	Limit   int
+}
+
+// CommandFilter شروط البحث في الأوامر
+type CommandFilter struct {
+	AgentID string // فارغ = كل الوكلاء
+	Status  string // فارغ = كل الحالات
+	Limit   int
+}
+
+// أنواع أحداث الأسطول التي تُرسل إلى الـ webhooks وبث WatchEvents
+const (
+	EventAgentRegistered     = "agent.registered"
+	EventAgentUpdated        = "agent.updated" // إعادة تسجيل وكيل موجود
+	EventAgentStatusChanged  = "agent.status_changed"
+	EventAgentOffline        = "agent.offline"
+	EventAgentHeartbeat      = "agent.heartbeat" // للبث فقط، لا يُرسل إلى الـ webhooks
+	EventFirewallChanged     = "firewall.changed"
+	EventInventoryChanged    = "inventory.changed"
+	EventCommandStateChanged = "command.state_changed"
+	EventCommandFailed       = "command.failed"
+	EventCommandApproval     = "command.approval_required"
+	EventAgentCloneSuspected = "agent.clone_suspected" // أكثر من جهاز يستخدم نفس agent_id
+	EventAlertFiring         = "alert.firing"
+	EventAlertResolved       = "alert.resolved"
+	EventRolloutPaused       = "update.rollout_paused" // توقف النشر تلقائيًا لارتفاع نسبة الفشل
+	EventFileCollected       = "file.collected"        // اكتمل رفع ملف طلبه المشرف وتطابق SHA-256
+	EventFileDownloaded      = "file.downloaded"       // نُزّل ملف مجمع من الخادم، لسجل التدقيق
+	EventJobRunFinished      = "job.run_finished"      // انتهى تشغيل مهمة مجدولة أو تُخطي
+)
+
+// Event حدث في الأسطول تنشره طبقة منطق العمل
+type Event struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	AgentID    string                 `json:"agent_id,omitempty"`
+	Labels     Labels                 `json:"labels,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	// Sequence رقم تسلسلي يضعه ناقل الأحداث، يبدأ من جديد عند إعادة تشغيل الخادم
+	Sequence uint64 `json:"-"`
+}
+
+// حالات تسليم الـ webhook في جدول الـ outbox
+const (
+	WebhookPending   = "PENDING"
+	WebhookDelivered = "DELIVERED"
+	WebhookDead      = "DEAD" // استنفد كل المحاولات وينتظر إعادة التشغيل يدويًا
+)
+
+// WebhookSubscription اشتراك نظام خارجي في أحداث الأسطول
+type WebhookSubscription struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	URL         string `gorm:"not null"`
+	Secret      string `gorm:"not null"` // مفتاح HMAC لتوقيع الطلبات
+	EventTypes  string // أنواع الأحداث مفصولة بفواصل، فارغ = كل الأحداث
+	Description string
+	Enabled     bool `gorm:"default:true"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Matches يتحقق هل الاشتراك مهتم بنوع الحدث
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	if s.EventTypes == "" {
+		return true
+	}
+	for _, t := range strings.Split(s.EventTypes, ",") {
+		if strings.TrimSpace(t) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery صف في جدول الـ outbox: حدث واحد لاشتراك واحد حتى يتم تسليمه
+type WebhookDelivery struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	SubscriptionID uint   `gorm:"index"`
+	EventID        string `gorm:"size:64"`
+	EventType      string `gorm:"size:64"`
+	AgentID        string `gorm:"size:255"`
+	Payload        []byte // جسم الطلب JSON كما سيُرسل
+	Status         string `gorm:"size:16;index"`
+	Attempts       int
+	NextAttemptAt  time.Time `gorm:"index"`
+	LastError      string
+	LastStatusCode int
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// WebhookDeliveryFilter شروط البحث في التسليمات
+type WebhookDeliveryFilter struct {
+	SubscriptionID uint   // 0 = كل الاشتراكات
+	Status         string // فارغ = كل الحالات
+	Limit          int
+}
+
+// درجات خطورة الثغرات من الأقل إلى الأعلى
+const (
+	SeverityUnknown  = "UNKNOWN"
+	SeverityLow      = "LOW"
+	SeverityMedium   = "MEDIUM"
+	SeverityHigh     = "HIGH"
+	SeverityCritical = "CRITICAL"
+)
+
+// Severities ترتيب درجات الخطورة من الأقل إلى الأعلى
+var Severities = []string{SeverityUnknown, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// SeverityRank يعيد موقع الدرجة في Severities (الدرجة غير المعروفة = 0)
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return 0
+}
+
+// Vulnerability ثغرة مستوردة من ملف ثغرات خارجي (OSV أو NVD)
+type Vulnerability struct {
+	ID              uint   `gorm:"primaryKey;autoIncrement"`
+	VulnerabilityID string `gorm:"size:128;uniqueIndex"` // مثل CVE-2023-4863 أو GHSA-...
+	Aliases         string // معرفات أخرى لنفس الثغرة مفصولة بفواصل
+	Summary         string
+	Severity        string `gorm:"size:16"`
+	CVSSScore       float64
+	Source          string           `gorm:"size:16"` // osv أو nvd
+	Affected        AffectedProducts // المنتجات والإصدارات المصابة
+	PublishedAt     time.Time
+	ModifiedAt      time.Time
+	ImportedAt      time.Time `gorm:"index"`
+}
+
+// AliasList يعيد المعرفات البديلة كقائمة
+func (v *Vulnerability) AliasList() []string {
+	if v.Aliases == "" {
+		return nil
+	}
+	return strings.Split(v.Aliases, ",")
+}
+
+// AffectedProduct منتج مصاب بمعرفه الموحد (vendor:product أو *:product) ونطاقات إصداراته
+type AffectedProduct struct {
+	Product  string         `json:"product"`
+	Ranges   []VersionRange `json:"ranges,omitempty"`
+	Versions []string       `json:"versions,omitempty"` // إصدارات محددة مصابة
+}
+
+// VersionRange نطاق إصدارات مصابة؛ البداية الفارغة = من أول إصدار والنهاية الفارغة = بدون إصلاح
+type VersionRange struct {
+	Start         string `json:"start,omitempty"`
+	StartExcluded bool   `json:"start_excluded,omitempty"`
+	End           string `json:"end,omitempty"`
+	EndIncluded   bool   `json:"end_included,omitempty"` // false = End هو أول إصدار مُصلح
+}
+
+// AffectedProducts تُخزن كـ JSON في عمود نصي
+type AffectedProducts []AffectedProduct
+
+// Value يحول المنتجات إلى JSON عند الكتابة في قاعدة البيانات
+func (a AffectedProducts) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+// Scan يقرأ المنتجات من عمود JSON
+func (a *AffectedProducts) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	case []byte:
+		return json.Unmarshal(v, a)
+	}
+	return fmt.Errorf("cannot scan %T into AffectedProducts", src)
+}
+
+// GormDataType يحدد نوع العمود عند الترحيل التلقائي (SQLite)
+func (AffectedProducts) GormDataType() string {
+	return "text"
+}
+
+// VulnerabilityFinding تطبيق مثبت على وكيل يطابق ثغرة معروفة
+type VulnerabilityFinding struct {
+	ID              uint   `gorm:"primaryKey;autoIncrement"`
+	AgentID         string `gorm:"size:255;index"` // agents.agent_id
+	VulnerabilityID string `gorm:"size:128;index"`
+	Severity        string `gorm:"size:16"`
+	CVSSScore       float64
+	Product         string // المعرف الموحد الذي طابق التطبيق
+	AppName         string
+	AppVersion      string
+	FixedVersion    string    // فارغ = لا يوجد إصدار مُصلح معروف
+	DetectedAt      time.Time // أول مرة ظهرت فيها هذه النتيجة، تبقى عند إعادة التقييم
+}
+
+// VulnerabilityFindingFilter شروط البحث في نتائج الثغرات
+type VulnerabilityFindingFilter struct {
+	AgentID          string   // فارغ = كل الوكلاء
+	VulnerabilityIDs []string // فارغ = كل الثغرات
+	Severities       []string // فارغ = كل الدرجات
+	Limit            int
+}
+
+// أنواع نتائج تحليل قواعد جدار الحماية (نفس أسماء FirewallFindingKind في الـ proto)
+const (
+	FirewallShadowedRule     = "SHADOWED_RULE"     // قاعدة لا تُطبق أبدًا لأن قاعدة سابقة تغطيها بإجراء معاكس
+	FirewallConflictingRules = "CONFLICTING_RULES" // ALLOW و DENY على نفس المنفذ والبروتوكول والاتجاه
+	FirewallRiskyExposure    = "RISKY_EXPOSURE"    // منفذ حساس مثل 22 أو 3389 أو 445 مفتوح للدخول من أي عنوان
+	FirewallDisabled         = "FIREWALL_DISABLED"
+	FirewallInvalidRule      = "INVALID_RULE" // منفذ لا يمكن فهمه، فلا تدخل القاعدة في التحليل
+)
+
+// FirewallFinding مشكلة في قواعد جدار الحماية الحالية لوكيل، تُحسب من جديد مع كل تقرير
+type FirewallFinding struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	AgentID     string `gorm:"size:255;index"` // agents.agent_id
+	Kind        string `gorm:"size:32;index"`
+	Severity    string `gorm:"size:16"`
+	RuleName    string // فارغ في FIREWALL_DISABLED
+	RelatedRule string // القاعدة السابقة التي تحجب هذه القاعدة أو تعارضها
+	Direction   string `gorm:"size:50"`
+	Protocol    string `gorm:"size:50"`
+	Port        string `gorm:"size:50"`
+	Message     string
+	DetectedAt  time.Time // أول مرة ظهرت فيها هذه النتيجة، تبقى عند إعادة التحليل
+}
+
+// FirewallFindingFilter شروط البحث في نتائج تحليل جدار الحماية
+type FirewallFindingFilter struct {
+	AgentID    string   // فارغ = كل الوكلاء
+	Kinds      []string // فارغ = كل الأنواع
+	Severities []string // فارغ = كل الدرجات
+	Limit      int
+}
+
+// نتائج فحص الامتثال
+const (
+	CompliancePass          = "PASS"
+	ComplianceFail          = "FAIL"
+	ComplianceNotApplicable = "NOT_APPLICABLE" // مثل فحص إصدار Windows على جهاز Linux
+)
+
+// CompliancePolicy نسخة محفوظة من سياسة امتثال؛ تغيير محتوى السياسة يتطلب رفع رقم الإصدار
+type CompliancePolicy struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	PolicyID    string `gorm:"size:128;uniqueIndex:idx_compliance_policy_version"`
+	Version     int    `gorm:"uniqueIndex:idx_compliance_policy_version"`
+	Name        string
+	Description string
+	Checks      int
+	Digest      string `gorm:"size:64"` // SHA-256 لملف السياسة
+	Definition  string // نص YAML كما تم تحميله
+	LoadedAt    time.Time
+}
+
+// ComplianceResult النتيجة الحالية لفحص واحد من سياسة على وكيل
+type ComplianceResult struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement"`
+	AgentID       string `gorm:"size:255;index"` // agents.agent_id
+	PolicyID      string `gorm:"size:128;index"`
+	PolicyVersion int
+	CheckID       string `gorm:"size:128"`
+	Title         string
+	Severity      string `gorm:"size:16"`
+	Status        string `gorm:"size:16"`
+	Evidence      string    // ما وجده الفحص، مثل اسم التطبيق المخالف وإصداره
+	Since         time.Time // منذ متى والفحص بهذه النتيجة
+	EvaluatedAt   time.Time
+}
+
+// ComplianceResultFilter شروط البحث في نتائج الامتثال
+type ComplianceResultFilter struct {
+	AgentID  string // فارغ = كل الوكلاء
+	PolicyID string // فارغ = كل السياسات
+	CheckID  string // فارغ = كل الفحوص
+	Status   string // فارغ = كل النتائج
+	Limit    int
+}
+
+// ComplianceEvaluation سجل تاريخي لنتيجة سياسة على وكيل، يُضاف فقط عندما تتغير نتيجة أحد الفحوص
+type ComplianceEvaluation struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement"`
+	AgentID       string `gorm:"size:255;index"`
+	PolicyID      string `gorm:"size:128;index"`
+	PolicyVersion int
+	Passed        int
+	Failed        int
+	NotApplicable int
+	FailedChecks  string    // معرفات الفحوص الفاشلة مفصولة بفواصل
+	EvaluatedAt   time.Time `gorm:"index"`
+}
+
+// ComplianceEvaluationFilter شروط البحث في سجل الامتثال
+type ComplianceEvaluationFilter struct {
+	AgentID  string    // فارغ = كل الوكلاء
+	PolicyID string    // فارغ = كل السياسات
+	Since    time.Time // الصفر = منذ البداية
+	Limit    int
+}
+
+// AgentStatusChange سجل تاريخي لتغير حالة وكيل، مثل ONLINE إلى OFFLINE
+type AgentStatusChange struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	AgentID    string    `gorm:"size:255;index"` // agents.agent_id
+	FromStatus string    `gorm:"size:20"`        // فارغ عند أول تسجيل للوكيل
+	ToStatus   string    `gorm:"size:20"`
+	ChangedAt  time.Time `gorm:"index"`
+}
+
+// TableName يحدد اسم جدول سجل الحالات
+func (AgentStatusChange) TableName() string {
+	return "agent_status_history"
+}
+
+// AgentFirewallRule قاعدة جدار حماية حالية مع بيانات وكيلها، تُقرأ بالمؤشر عند التصدير
+type AgentFirewallRule struct {
+	AgentID    string // agents.agent_id وليس الرقم الداخلي
+	Hostname   string
+	Labels     Labels
+	Name       string
+	Port       string
+	Protocol   string
+	Action     string
+	Direction  string
+	Enabled    bool
+	ReportedAt time.Time
+}
+
+// AgentInstalledApp تطبيق مثبت حاليًا مع بيانات وكيله، يُقرأ بالمؤشر عند التصدير
+type AgentInstalledApp struct {
+	AgentID     string
+	Hostname    string
+	Labels      Labels
+	Name        string
+	Version     string
+	Publisher   string
+	InstallDate time.Time
+	ReportedAt  time.Time
+}
+
+// AgentStatusChangeRow تغير حالة مع بيانات الوكيل، يُقرأ بالمؤشر عند التصدير
+type AgentStatusChangeRow struct {
+	AgentStatusChange
+	Hostname string
+	Labels   Labels
+}
+
+// ExportFilter شروط اختيار الصفوف عند تصدير بيانات الأسطول، وتُستخدم أيضًا لتقارير التوفر
+type ExportFilter struct {
+	AgentID string            // فارغ = كل الوكلاء
+	Status  string            // حالة الوكيل الحالية، فارغ = كل الحالات
+	Labels  map[string]string // يجب أن يحمل الوكيل كل هذه الملصقات
+	Since   time.Time         // لسجل الحالات فقط، الصفر = منذ البداية
+	Until   time.Time         // لسجل الحالات فقط، الصفر = حتى الآن
+}
+
+// AgentIPAddress عنوان ظهر به وكيل: ما أبلغ عنه الوكيل وما رآه الخادم، مع أول وآخر ظهور
+type AgentIPAddress struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	AgentID   string    `gorm:"size:255;uniqueIndex:idx_agent_ip_history_address"` // agents.agent_id
+	IP        string    `gorm:"size:64;uniqueIndex:idx_agent_ip_history_address;index"`
+	PeerIP    string    `gorm:"size:64;uniqueIndex:idx_agent_ip_history_address;index"` // فارغ إن لم يُعرف عنوان الاتصال
+	FirstSeen time.Time
+	LastSeen  time.Time `gorm:"index"`
+}
+
+// TableName يحدد اسم جدول سجل العناوين
+func (AgentIPAddress) TableName() string {
+	return "agent_ip_history"
+}
+
+// Mismatch يعني أن العنوان المُبلغ عنه يختلف عن عنوان الاتصال، بسبب NAT أو انتحال
+func (a AgentIPAddress) Mismatch() bool {
+	return a.IP != "" && a.PeerIP != "" && a.IP != a.PeerIP
+}
+
+// IPHistoryFilter شروط البحث في سجل العناوين
+type IPHistoryFilter struct {
+	AgentID      string    // فارغ = كل الوكلاء
+	IP           string    // يطابق العنوان المُبلغ عنه أو عنوان الاتصال، فارغ = كل العناوين
+	Since        time.Time // الوكيل ظهر بالعنوان في هذه اللحظة أو بعدها، الصفر = منذ البداية
+	Until        time.Time // الوكيل ظهر بالعنوان قبل هذه اللحظة، الصفر = حتى الآن
+	MismatchOnly bool      // العناوين التي يختلف فيها المُبلغ عنه عن عنوان الاتصال فقط
+	Limit        int
+}
+
+// أسماء مقاييس الموارد التي يرسلها الوكيل مع النبضة
+const (
+	MetricCPUPercent      = "cpu_percent"
+	MetricMemoryUsedGB    = "memory_used_gb"
+	MetricDiskUsedGB      = "disk_used_gb"      // لكل نقطة تركيب، Mount يحدد القرص
+	MetricDiskUsedPercent = "disk_used_percent" // يُحسب من disk_used_gb وحجم القرص عندما يرسله الوكيل
+	MetricLoadAverage     = "load_average"      // لدقيقة واحدة
+	MetricUptimeSeconds   = "uptime_seconds"
+	MetricProcessCount    = "process_count"
+)
+
+// دقة تخزين المقاييس بالثواني: العينات الخام تُجمع إلى فترات 5 دقائق ثم إلى ساعات
+const (
+	MetricResolutionRaw        = 0
+	MetricResolutionFiveMinute = 300
+	MetricResolutionHour       = 3600
+)
+
+// MetricSample قيمة مقياس واحدة في نبضة (ليست جدولًا)
+type MetricSample struct {
+	Metric string
+	Mount  string // لمقياس الأقراص فقط
+	Value  float64
+}
+
+// AgentMetric نقطة في سلسلة زمنية لمقياس وكيل: عينة خام أو تجميع لفترة
+// العينة الخام لها Count = 1 و Sum = Min = Max = القيمة، والتجميع يحفظ ما يكفي لحساب المتوسط
+type AgentMetric struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	AgentID     string    `gorm:"size:255;uniqueIndex:idx_agent_metrics_point,priority:1"` // agents.agent_id
+	Resolution  int       `gorm:"uniqueIndex:idx_agent_metrics_point,priority:2;index:idx_agent_metrics_resolution,priority:1"`
+	BucketStart time.Time `gorm:"uniqueIndex:idx_agent_metrics_point,priority:3;index:idx_agent_metrics_resolution,priority:2"` // وقت العينة الخام أو بداية الفترة
+	Metric      string    `gorm:"size:64;uniqueIndex:idx_agent_metrics_point,priority:4"`
+	Mount       string    `gorm:"size:255;uniqueIndex:idx_agent_metrics_point,priority:5"`
+	Count       int
+	Sum         float64
+	Min         float64
+	Max         float64
+}
+
+// Avg يعيد متوسط القيم في النقطة
+func (m AgentMetric) Avg() float64 {
+	if m.Count == 0 {
+		return 0
+	}
+	return m.Sum / float64(m.Count)
+}
+
+// MetricFilter شروط البحث في مقاييس وكيل
+type MetricFilter struct {
+	AgentID    string
+	Metrics    []string // فارغ = كل المقاييس
+	Resolution int
+	Since      time.Time // النقاط التي تبدأ في هذه اللحظة أو بعدها
+	Until      time.Time // النقاط التي تبدأ قبل هذه اللحظة
+}
+
+// حالات التنبيه: PENDING حتى يستمر الشرط مدة for في القاعدة، ثم FIRING حتى يزول الشرط
+const (
+	AlertPending  = "PENDING"
+	AlertFiring   = "FIRING"
+	AlertResolved = "RESOLVED"
+)
+
+// Alert تنبيه واحد من قاعدة؛ Key يميز التنبيهات المفتوحة لنفس القاعدة حتى لا تتكرر
+// مثل agent_id لقاعدة حالة الوكيل، أو agent_id ونقطة التركيب لقاعدة مقياس القرص، أو قيمة الوسم لقاعدة المجموعة
+type Alert struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	RuleID     string `gorm:"size:128;index:idx_alerts_open,unique,where:state <> 'RESOLVED'"`
+	Key        string `gorm:"size:512;index:idx_alerts_open,unique,where:state <> 'RESOLVED'"`
+	AgentID    string `gorm:"size:255;index"` // فارغ لتنبيهات المجموعات
+	Severity   string `gorm:"size:16"`
+	State      string `gorm:"size:16;index"`
+	Summary    string
+	Value      float64   // القيمة التي أطلقت التنبيه، مثل نسبة القرص أو نسبة الوكلاء غير المتصلين
+	StartedAt  time.Time // أول تقييم تحقق فيه الشرط
+	FiredAt    *time.Time
+	ResolvedAt *time.Time `gorm:"index"`
+	UpdatedAt  time.Time
+}
+
+// AlertFilter شروط البحث في التنبيهات، الأحدث أولًا
+type AlertFilter struct {
+	RuleID     string    // فارغ = كل القواعد
+	AgentID    string    // فارغ = كل الوكلاء
+	States     []string  // فارغ = كل الحالات
+	Severities []string  // فارغ = كل الخطورات
+	Since      time.Time // التنبيهات التي بدأت في هذه اللحظة أو بعدها
+	Limit      int
+}
+
+// StringList قائمة نصوص تُخزن كـ JSON في عمود نصي، مثل عناوين واجهة الشبكة
+type StringList []string
+
+// Value يحول القائمة إلى JSON عند الكتابة في قاعدة البيانات
+func (s StringList) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+// Scan يقرأ القائمة من عمود JSON
+func (s *StringList) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case []byte:
+		return json.Unmarshal(v, s)
+	}
+	return fmt.Errorf("cannot scan %T into StringList", src)
+}
+
+// GormDataType يحدد نوع العمود عند الترحيل التلقائي (SQLite)
+func (StringList) GormDataType() string {
+	return "text"
+}
+
+// أنواع المخزون الموسع، وهي قيمة inventory في حدث inventory.changed مثل firewall_rules و installed_apps
+const (
+	InventoryNetworkInterfaces = "network_interfaces"
+	InventoryLocalUsers        = "local_users"
+	InventoryLocalGroups       = "local_groups"
+	InventoryServices          = "services"
+	InventoryListeningSockets  = "listening_sockets"
+	InventoryProcesses         = "processes"
+)
+
+// InventoryItem الحقول المشتركة لعناصر المخزون الموسع
+// كل تقرير لقطة كاملة: ReportedAt نفس القيمة لكل عناصر التقرير، وآخر قيمة تمثل الحالة الحالية
+type InventoryItem struct {
+	gorm.Model
+	AgentID    uint      `gorm:"index"`
+	ReportedAt time.Time `gorm:"index"`
+}
+
+// Item يعيد الحقول المشتركة، فيعامل المستودع ومنطق العمل كل أنواع المخزون بنفس الشيفرة
+func (i *InventoryItem) Item() *InventoryItem {
+	return i
+}
+
+// InventoryModel قيد للدوال العامة يحققه مؤشر أي نوع من أنواع المخزون الموسع
+type InventoryModel[T any] interface {
+	*T
+	Item() *InventoryItem
+}
+
+// NetworkInterface واجهة شبكة على جهاز الوكيل
+type NetworkInterface struct {
+	InventoryItem
+	Name        string     `gorm:"size:255"`
+	MAC         string     `gorm:"size:50"`
+	IPAddresses StringList // مع طول البادئة، مثل 10.0.0.5/24 و fe80::1/64
+	MTU         int32
+	Up          bool
+}
+
+// LocalUser حساب مستخدم محلي على جهاز الوكيل
+type LocalUser struct {
+	InventoryItem
+	Name      string     `gorm:"size:255"`
+	UID       string     `gorm:"size:255"` // UID في لينكس أو SID في ويندوز
+	FullName  string     `gorm:"size:255"`
+	Groups    StringList // المجموعات المحلية التي ينتمي إليها
+	Disabled  bool
+	LastLogon time.Time // صفر إن لم يسجل الدخول أو لم يُعرف
+}
+
+// LocalGroup مجموعة محلية مع أعضائها، تُرسل في نفس تقرير المستخدمين
+type LocalGroup struct {
+	InventoryItem
+	Name    string `gorm:"size:255"`
+	GID     string `gorm:"column:gid;size:255"`
+	Members StringList
+}
+
+// أنواع بدء خدمة النظام
+const (
+	ServiceStartAutomatic = "AUTOMATIC"
+	ServiceStartManual    = "MANUAL"
+	ServiceStartDisabled  = "DISABLED"
+)
+
+// حالات تشغيل خدمة النظام
+const (
+	ServiceRunning = "RUNNING"
+	ServiceStopped = "STOPPED"
+	ServicePaused  = "PAUSED"
+)
+
+// SystemService خدمة نظام (خدمة ويندوز أو وحدة systemd) مع نوع بدئها وحالتها
+type SystemService struct {
+	InventoryItem
+	Name        string `gorm:"size:255"`
+	DisplayName string `gorm:"size:255"`
+	StartType   string `gorm:"size:20"`
+	State       string `gorm:"size:20"`
+	Account     string `gorm:"size:255"` // الحساب الذي تعمل به الخدمة
+	BinaryPath  string `gorm:"size:1024"`
+}
+
+// ListeningSocket منفذ مفتوح للاستماع على جهاز الوكيل والعملية التي فتحته
+type ListeningSocket struct {
+	InventoryItem
+	Protocol    string `gorm:"size:10"`  // TCP أو UDP
+	Address     string `gorm:"size:100"` // 0.0.0.0 أو :: للاستماع على كل الواجهات
+	Port        int32
+	PID         int32  `gorm:"column:pid"`
+	ProcessName string `gorm:"size:255"`
+}
+
+// RunningProcess عملية في لقطة العمليات الجارية
+type RunningProcess struct {
+	InventoryItem
+	PID         int32  `gorm:"column:pid"`
+	ParentPID   int32  `gorm:"column:parent_pid"`
+	Name        string `gorm:"size:255"`
+	User        string `gorm:"size:255"`
+	CommandLine string
+	StartedAt   time.Time
+	CPUPercent  float64
+	MemoryMB    float64
+}
+
+// AgentInventory عنصر مخزون حالي مع بيانات وكيله، يُقرأ بالمؤشر عند التصدير
+type AgentInventory[T any] struct {
+	AgentKey string `gorm:"column:agent_key"` // agents.agent_id، لأن AgentID في العنصر هو الرقم الداخلي
+	Hostname string
+	Labels   Labels
+	Item     T `gorm:"embedded"`
+}
+
+// InventoryPatches نوع المخزون في حدث inventory.changed عند تغير التحديثات المثبتة
+const InventoryPatches = "patches"
+
+// InstalledPatch تحديث مثبت: رقم KB في ويندوز أو حزمة بإصدارها في لينكس
+type InstalledPatch struct {
+	InventoryItem
+	PatchID     string `gorm:"size:255;index"` // مثل KB5034441 أو openssl
+	Title       string
+	Version     string    `gorm:"size:100"` // إصدار الحزمة في لينكس، فارغ في ويندوز
+	InstalledAt time.Time // صفر إن لم يُعرف
+}
+
+// PendingUpdate تحديث متاح لم يُثبت بعد
+type PendingUpdate struct {
+	InventoryItem
+	PatchID  string `gorm:"size:255"`
+	Title    string
+	Version  string `gorm:"size:100"` // الإصدار المتاح للحزمة
+	Security bool   // تحديث أمني
+}
+
+// AgentPatchStatus حالة التحديثات من آخر تقرير للوكيل، صف واحد لكل وكيل يُستبدل مع كل تقرير
+// أما التحديثات المثبتة والمعلقة فتُحفظ لقطات مثل بقية المخزون بنفس ReportedAt
+type AgentPatchStatus struct {
+	gorm.Model
+	AgentID              uint      `gorm:"uniqueIndex"`
+	LastUpdateAt         time.Time // آخر تثبيت للتحديثات، صفر إن لم يُعرف
+	RebootPending        bool
+	RebootPendingSince   *time.Time `gorm:"index"` // منذ متى ينتظر الجهاز إعادة التشغيل
+	InstalledCount       int
+	PendingCount         int
+	SecurityPendingCount int
+	ReportedAt           time.Time
+}
+
+// AgentPatchStatusRow حالة تحديثات وكيل مع بياناته لاستعلامات الأسطول
+type AgentPatchStatusRow struct {
+	AgentKey string `gorm:"column:agent_key"` // agents.agent_id
+	Hostname string
+	OSName   string
+	Labels   Labels
+	Status   AgentPatchStatus `gorm:"embedded"`
+}
+
+// PatchStatusFilter يحدد الوكلاء في استعلامات حالة التحديثات، مثل "الأجهزة التي ينقصها KB معين"
+type PatchStatusFilter struct {
+	AgentID             string            // فارغ = كل الوكلاء
+	Labels              map[string]string // يجب أن يحمل الوكيل كل هذه الملصقات
+	OSName              string            // فارغ = كل الأنظمة
+	MissingPatch        string            // الوكلاء الذين لا يظهر هذا التحديث في آخر تقرير لهم
+	RebootPendingBefore time.Time         // الوكلاء الذين ينتظرون إعادة التشغيل منذ قبل هذه اللحظة
+	PendingOnly         bool              // الوكلاء الذين لديهم تحديثات معلقة فقط
+	Limit               int
+}
+
+// AgentPackage حزمة تثبيت موقعة لإصدار من الوكيل لنظام ومعمارية، محتواها في مخزن الملفات باسم SHA256
+type AgentPackage struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	Version   string `gorm:"size:64;uniqueIndex:idx_agent_packages_target"`
+	OS        string `gorm:"size:32;uniqueIndex:idx_agent_packages_target"` // windows أو linux أو darwin
+	Arch      string `gorm:"size:32;uniqueIndex:idx_agent_packages_target"` // amd64 أو arm64
+	SHA256    string `gorm:"size:64"`                                       // بالنظام الست عشري
+	SizeBytes int64
+	Signature string // توقيع ed25519 لـ SHA256 بترميز base64، يتحقق منه الوكيل قبل التثبيت
+	CreatedAt time.Time
+}
+
+// حالات النشر
+const (
+	RolloutActive    = "ACTIVE"
+	RolloutPaused    = "PAUSED"
+	RolloutCancelled = "CANCELLED"
+)
+
+// Rollout نشر تدريجي لإصدار من الوكيل: يُعرض التحديث على نسبة من الوكلاء المطابقين للمجموعات
+// ويتوقف تلقائيًا إذا تجاوزت نسبة الفشل MaxFailureRate بعد MinResults نتيجة على الأقل
+type Rollout struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	Version        string `gorm:"size:64;index"`
+	Percentage     int        // 0-100 من الوكلاء المؤهلين
+	Groups         StringList // ملصقات key=value، يكفي أن يطابق الوكيل واحدًا منها؛ فارغ = كل الوكلاء
+	MaxFailureRate float64    // 0-1، صفر = لا يتوقف تلقائيًا
+	MinResults     int        // عدد النتائج قبل حساب نسبة الفشل
+	Status         string     `gorm:"size:16;index"`
+	PauseReason    string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// حالات تحديث وكيل ضمن نشر
+const (
+	AgentUpdateOffered   = "OFFERED"
+	AgentUpdateSucceeded = "SUCCEEDED"
+	AgentUpdateFailed    = "FAILED"
+)
+
+// AgentUpdate عرض تحديث على وكيل ضمن نشر ونتيجته، صف واحد لكل وكيل في كل نشر
+type AgentUpdate struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	RolloutID   uint   `gorm:"uniqueIndex:idx_agent_updates_rollout_agent"`
+	AgentID     uint   `gorm:"uniqueIndex:idx_agent_updates_rollout_agent;index"`
+	FromVersion string `gorm:"size:64"`
+	ToVersion   string `gorm:"size:64"`
+	Status      string `gorm:"size:16;index"`
+	Message     string
+	OfferedAt   time.Time
+	FinishedAt  *time.Time
+}
+
+// AgentUpdateRow تحديث وكيل مع معرفه واسم جهازه
+type AgentUpdateRow struct {
+	AgentKey string `gorm:"column:agent_key"` // agents.agent_id
+	Hostname string
+	Update   AgentUpdate `gorm:"embedded"`
+}
+
+// RolloutCounts عدد تحديثات النشر في كل حالة
+type RolloutCounts struct {
+	Offered   int
+	Succeeded int
+	Failed    int
+}
+
+// حالات جمع ملف من وكيل
+const (
+	CollectionPending   = "PENDING"   // الأمر في طابور الوكيل
+	CollectionUploading = "UPLOADING" // بدأ الرفع ولم يكتمل، يستأنفه الوكيل من ReceivedBytes
+	CollectionComplete  = "COMPLETE"
+	CollectionFailed    = "FAILED"
+	CollectionExpired   = "EXPIRED" // حُذف الملف بعد مدة الاحتفاظ وبقي السجل
+)
+
+// FileCollection ملف طلبه المشرف من وكيل بأمر COLLECT_FILE
+// يرفعه الوكيل على أجزاء تُحفظ في مخزن الملفات، ثم تُجمع في ملف واحد بعد التحقق من SHA-256
+type FileCollection struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement"`
+	CommandID     uint   `gorm:"uniqueIndex"`
+	AgentID       string `gorm:"size:255;index"` // agents.agent_id مثل الأوامر
+	Path          string // مسار الملف على جهاز الوكيل
+	Reason        string // سبب الطلب كما كتبه المشرف، لسجل التدقيق
+	MaxBytes      int64
+	Status        string `gorm:"size:20;index"`
+	SizeBytes     int64  // الحجم الذي أعلنه الوكيل عند بدء الرفع
+	SHA256        string `gorm:"size:64"` // ما أعلنه الوكيل، ويُقارن بما وصل فعلًا
+	ReceivedBytes int64
+	Parts         int    // عدد الأجزاء المحفوظة حتى الآن
+	StorageKey    string // مفتاح الملف الكامل في مخزن الملفات بعد الاكتمال
+	Message       string
+	CreatedAt     time.Time `gorm:"index"`
+	UpdatedAt     time.Time
+	CompletedAt   *time.Time
+}
+
+// FileCollectionFilter شروط البحث في الملفات المجمعة
+type FileCollectionFilter struct {
+	AgentID string // فارغ = كل الوكلاء
+	Status  string // فارغ = كل الحالات
+	Limit   int
+}
+
+// حالات المهمة المجدولة
+const (
+	JobActive   = "ACTIVE"
+	JobPaused   = "PAUSED"   // لا تبدأ تشغيلات جديدة ولا تُرسل أوامر لوكلاء لم يصلهم الأمر بعد
+	JobFinished = "FINISHED" // مهمة لمرة واحدة نُفذت أو فاتت، أو تعبير cron لا يطابق أي وقت قادم
+)
+
+// سياسة التشغيلات الفائتة أثناء توقف الخادم
+const (
+	CatchUpSkip    = "SKIP"     // تُسجل كمتخطاة وتنتظر المهمة موعدها القادم
+	CatchUpRunOnce = "RUN_ONCE" // تُشغل مرة واحدة عند عودة الخادم مهما كان عدد الفائت
+)
+
+// ScheduledJob أمر يُرسل إلى مجموعة من الوكلاء حسب تعبير cron أو مرة واحدة في وقت محدد
+type ScheduledJob struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement"`
+	Name           string     `gorm:"size:255;uniqueIndex"`
+	CommandType    string     `gorm:"size:50"`
+	Payload        []byte     // نفس ترميز Command.Payload، ويُنسخ إلى كل أمر
+	// سكربت أوامر RUN_SCRIPT، يُعاد فحصه مقابل قائمة السماح قبل كل أمر
+	ScriptName     string     `gorm:"size:255"` // فارغ للسكربت المضمن
+	ScriptDigest   string     `gorm:"size:80"`  // sha256:<hex> لنص السكربت
+	Cron           string     `gorm:"size:255"` // فارغ لمهمة لمرة واحدة
+	RunAt          *time.Time // موعد المهمة لمرة واحدة
+	Timezone       string     `gorm:"size:64"` // يُقيّم فيه تعبير cron
+	CatchUp        string     `gorm:"size:16"`
+	MaxConcurrency int        // أقصى عدد أوامر غير منتهية للمهمة في وقت واحد، صفر = بلا حد
+	// الوكلاء المستهدفون: كل الوكلاء، أو المذكورون بالاسم والحاملون لكل الملصقات
+	TargetAll      bool
+	TargetAgentIDs StringList
+	TargetLabels   Labels
+	Status         string     `gorm:"size:16;index"`
+	NextRunAt      *time.Time `gorm:"index"` // فارغ بعد انتهاء المهمة
+	LastRunAt      *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// مصدر تشغيل المهمة
+const (
+	TriggerSchedule = "SCHEDULE"
+	TriggerCatchUp  = "CATCH_UP" // تشغيل متأخر لموعد فات أثناء توقف الخادم
+	TriggerManual   = "MANUAL"
+)
+
+// حالات تشغيل المهمة
+const (
+	JobRunRunning   = "RUNNING"
+	JobRunSucceeded = "SUCCEEDED"
+	JobRunFailed    = "FAILED" // فشل الأمر على وكيل واحد على الأقل
+	JobRunSkipped   = "SKIPPED"
+)
+
+// JobRun تشغيل واحد لمهمة مجدولة، ويبقى في السجل بعد انتهائه
+type JobRun struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement"`
+	JobID        uint      `gorm:"index"`
+	Trigger      string    `gorm:"size:16"`
+	Status       string    `gorm:"size:16;index"`
+	ScheduledFor time.Time // الموعد الذي يمثله التشغيل، قد يسبق StartedAt
+	StartedAt    time.Time
+	FinishedAt   *time.Time `gorm:"index"`
+	Targets      int
+	Succeeded    int
+	Failed       int
+	Message      string
+}
+
+// حالات الوكيل ضمن تشغيل مهمة
+const (
+	JobTargetWaiting    = "WAITING" // ينتظر دوره بسبب MaxConcurrency
+	JobTargetDispatched = "DISPATCHED"
+	JobTargetSucceeded  = "SUCCEEDED"
+	JobTargetFailed     = "FAILED"
+)
+
+// JobRunTarget وكيل واحد ضمن تشغيل والأمر الذي أُرسل إليه
+type JobRunTarget struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement"`
+	RunID        uint   `gorm:"uniqueIndex:idx_job_run_targets_run_agent"`
+	AgentID      string `gorm:"size:255;uniqueIndex:idx_job_run_targets_run_agent"`
+	CommandID    uint   // صفر حتى يُرسل الأمر
+	Status       string `gorm:"size:16"`
+	Message      string
+	DispatchedAt *time.Time
+	FinishedAt   *time.Time
+}
+
+// JobRunFilter شروط البحث في تشغيلات المهام
+type JobRunFilter struct {
+	JobID  uint   // صفر = كل المهام
+	Status string // فارغ = كل الحالات
+	Limit  int
 }
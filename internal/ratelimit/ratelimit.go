@@ -0,0 +1,111 @@
+// Package ratelimit provides a per-client gRPC rate limiter whose limits
+// can be changed while the server is running.
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"agent_server/internal/config"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// idleTimeout is how long a client's bucket is kept after its last request.
+const idleTimeout = 10 * time.Minute
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter keeps one token bucket per client address.
+type Limiter struct {
+	mu        sync.Mutex
+	cfg       config.RateLimitConfig
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+// New creates a limiter with the given initial settings.
+func New(cfg config.RateLimitConfig) *Limiter {
+	return &Limiter{cfg: cfg, clients: make(map[string]*client), lastSweep: time.Now()}
+}
+
+// Update applies new limits to every existing and future client.
+func (l *Limiter) Update(cfg config.RateLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+	for _, c := range l.clients {
+		c.limiter.SetLimit(rate.Limit(cfg.RequestsPerSecond))
+		c.limiter.SetBurst(cfg.Burst)
+	}
+}
+
+// Allow reports whether the client identified by key may make a request now.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.cfg.Enabled {
+		return true
+	}
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > idleTimeout {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > idleTimeout {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(rate.Limit(l.cfg.RequestsPerSecond), l.cfg.Burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter.AllowN(now, 1)
+}
+
+// UnaryServerInterceptor rejects requests over the limit with ResourceExhausted.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !l.Allow(clientKey(ctx)) {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor applies the same limit when a stream is opened.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !l.Allow(clientKey(ss.Context())) {
+			return status.Errorf(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(srv, ss)
+	}
+}
+
+// clientKey identifies a client by the host part of its peer address.
+func clientKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"agent_server/internal/model"
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,10 +15,46 @@ import (
 	FindAgentByID(agentID string) (*model.Agent, error)
 	CreateAgent(agent *model.Agent) error
 	UpdateAgent(agent *model.Agent) error
-	UpdateHeartbeat(agentID, ip string) (int64, error)
+	UpdateHeartbeat(agentID, ip, peerIP string) (int64, error)
+	BulkUpdateHeartbeats(beats []model.Heartbeat) error
+	ListAgentIDs() ([]string, error)
 	CreateFirewallRules(rules []model.FirewallRule) error
 	CreateInstalledApps(apps []model.InstalledApplication) error
 	FindAgentsByStatus(status string) ([]model.Agent, error)
+	ListAgents(filter model.AgentFilter) ([]model.Agent, error)
+	FindCurrentFirewallRules(agentPK uint) ([]model.FirewallRule, error)
+	FindCurrentInstalledApps(agentPK uint) ([]model.InstalledApplication, error)
+	FindAllCurrentInstalledApps() ([]model.InstalledApplication, error)
+	DeleteFirewallRulesBefore(before time.Time) (int64, error)
+	DeleteInstalledAppsBefore(before time.Time) (int64, error)
+	RecordStatusChange(change *model.AgentStatusChange) error
+	// RecordAddresses adds the reported and peer address of each beat to its
+	// agent's IP history. UpdateHeartbeat and BulkUpdateHeartbeats do this
+	// themselves.
+	RecordAddresses(beats []model.Heartbeat) error
+	// FindIPHistory returns the addresses matching filter, most recently
+	// seen first.
+	FindIPHistory(filter model.IPHistoryFilter) ([]model.AgentIPAddress, error)
+	// FlagSuspectedClone marks an agent as used by more than one host. It
+	// returns false when the agent is missing or already flagged, in which
+	// case the first reason is kept.
+	FlagSuspectedClone(agentID, reason string, at time.Time) (bool, error)
+	// ClearSuspectedClone removes the flag, or returns gorm.ErrRecordNotFound.
+	ClearSuspectedClone(agentID string) error
+	// FindSuspectedClones returns the flagged agents, most recently flagged first.
+	FindSuspectedClones(limit int) ([]model.Agent, error)
+
+	// The Stream methods read their rows through a database cursor and call
+	// fn once per row, so exports never hold a whole table in memory. They
+	// stop at the first error returned by fn. fn must not use the repository:
+	// on SQLite the cursor holds the only connection.
+	StreamAgents(filter model.ExportFilter, fn func(*model.Agent) error) error
+	StreamCurrentFirewallRules(filter model.ExportFilter, fn func(*model.AgentFirewallRule) error) error
+	StreamCurrentInstalledApps(filter model.ExportFilter, fn func(*model.AgentInstalledApp) error) error
+	StreamStatusHistory(filter model.ExportFilter, fn func(*model.AgentStatusChangeRow) error) error
+	// FindLastStatusChanges returns the latest change before the given time
+	// of each agent matching filter, which is the agent's status at that time.
+	FindLastStatusChanges(filter model.ExportFilter, before time.Time) ([]model.AgentStatusChangeRow, error)
 }
 
 type gormRepository struct {
@@ -44,14 +82,237 @@ func (r *gormRepository) UpdateAgent(agent *model.Agent) error {
 	return r.db.Save(agent).Error
 }
 
-func (r *gormRepository) UpdateHeartbeat(agentID, ip string) (int64, error) {
+func (r *gormRepository) UpdateHeartbeat(agentID, ip, peerIP string) (int64, error) {
+	now := time.Now()
 	updates := map[string]interface{}{
 		"status":        "ONLINE",
-		"last_seen":     time.Now(),
+		"last_seen":     now,
 		"last_known_ip": ip,
+		"last_peer_ip":  peerIP,
 	}
-	result := r.db.Model(&model.Agent{}).Where("agent_id = ?", agentID).Updates(updates)
-	return result.RowsAffected, result.Error
+	var rows int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := recordRecovery(tx, agentID, now); err != nil {
+			return err
+		}
+		result := tx.Model(&model.Agent{}).Where("agent_id = ? AND status <> ?", agentID, "DECOMMISSIONED").Updates(updates)
+		rows = result.RowsAffected
+		if result.Error != nil || rows == 0 {
+			return result.Error
+		}
+		return recordAddress(tx, model.Heartbeat{AgentID: agentID, IP: ip, PeerIP: peerIP, SeenAt: now})
+	})
+	return rows, err
+}
+
+// recordRecovery adds the transition to ONLINE of an agent whose heartbeat
+// arrives while it is OFFLINE, in the transaction that updates its status,
+// so recoveries are recorded even when the server restarted in between.
+func recordRecovery(tx *gorm.DB, agentID string, at time.Time) error {
+	return tx.Exec(`INSERT INTO agent_status_history (agent_id, from_status, to_status, changed_at)
+		SELECT agent_id, status, 'ONLINE', ? FROM agents
+		WHERE agent_id = ? AND status NOT IN ('ONLINE', 'DECOMMISSIONED') AND deleted_at IS NULL`, at, agentID).Error
+}
+
+// upsertAddress extends the last_seen of an agent's known address, or adds
+// the address when it is new. It works on both Postgres and SQLite.
+const upsertAddress = ` ON CONFLICT (agent_id, ip, peer_ip) DO UPDATE SET last_seen =
+		CASE WHEN excluded.last_seen > agent_ip_history.last_seen THEN excluded.last_seen ELSE agent_ip_history.last_seen END`
+
+// recordAddress adds a beat to its agent's IP history. Beats without any
+// address are skipped.
+func recordAddress(tx *gorm.DB, b model.Heartbeat) error {
+	if b.IP == "" && b.PeerIP == "" {
+		return nil
+	}
+	return tx.Exec(`INSERT INTO agent_ip_history (agent_id, ip, peer_ip, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?)`+upsertAddress, b.AgentID, b.IP, b.PeerIP, b.SeenAt, b.SeenAt).Error
+}
+
+func (r *gormRepository) RecordAddresses(beats []model.Heartbeat) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, b := range beats {
+			if err := recordAddress(tx, b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindIPHistory returns the addresses matching filter, most recently seen first.
+func (r *gormRepository) FindIPHistory(filter model.IPHistoryFilter) ([]model.AgentIPAddress, error) {
+	query := r.db.Model(&model.AgentIPAddress{})
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ? OR peer_ip = ?", filter.IP, filter.IP)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("last_seen >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("first_seen < ?", filter.Until)
+	}
+	if filter.MismatchOnly {
+		query = query.Where("ip <> '' AND peer_ip <> '' AND ip <> peer_ip")
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var addresses []model.AgentIPAddress
+	err := query.Order("last_seen DESC, id DESC").Find(&addresses).Error
+	return addresses, err
+}
+
+func (r *gormRepository) FlagSuspectedClone(agentID, reason string, at time.Time) (bool, error) {
+	result := r.db.Model(&model.Agent{}).Where("agent_id = ? AND clone_suspected_at IS NULL", agentID).
+		Updates(map[string]interface{}{"clone_suspected_at": at, "clone_reason": reason})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormRepository) ClearSuspectedClone(agentID string) error {
+	result := r.db.Model(&model.Agent{}).Where("agent_id = ?", agentID).
+		Updates(map[string]interface{}{"clone_suspected_at": nil, "clone_reason": ""})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (r *gormRepository) FindSuspectedClones(limit int) ([]model.Agent, error) {
+	query := r.db.Where("clone_suspected_at IS NOT NULL").Order("clone_suspected_at DESC, id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var agents []model.Agent
+	err := query.Find(&agents).Error
+	return agents, err
+}
+
+// heartbeatBatchSize keeps each bulk UPDATE well under Postgres' 65535 parameter limit.
+const heartbeatBatchSize = 1000
+
+// BulkUpdateHeartbeats writes many heartbeats at once, at most one per agent.
+// On Postgres it issues one UPDATE ... FROM (VALUES ...) per batch; other
+// dialects fall back to per-row updates inside a single transaction.
+func (r *gormRepository) BulkUpdateHeartbeats(beats []model.Heartbeat) error {
+	if len(beats) == 0 {
+		return nil
+	}
+	if r.db.Dialector.Name() != "postgres" {
+		return r.db.Transaction(func(tx *gorm.DB) error {
+			for _, b := range beats {
+				if err := recordRecovery(tx, b.AgentID, b.SeenAt); err != nil {
+					return err
+				}
+				result := tx.Model(&model.Agent{}).Where("agent_id = ? AND status <> ?", b.AgentID, "DECOMMISSIONED").Updates(map[string]interface{}{
+					"status":        "ONLINE",
+					"last_seen":     b.SeenAt,
+					"last_known_ip": b.IP,
+					"last_peer_ip":  b.PeerIP,
+				})
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					continue
+				}
+				if err := recordAddress(tx, b); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	for start := 0; start < len(beats); start += heartbeatBatchSize {
+		end := start + heartbeatBatchSize
+		if end > len(beats) {
+			end = len(beats)
+		}
+		batch := beats[start:end]
+
+		values := make([]string, 0, len(batch))
+		args := make([]interface{}, 0, len(batch)*4)
+		for _, b := range batch {
+			values = append(values, "(?, ?::timestamptz, ?, ?)")
+			args = append(args, b.AgentID, b.SeenAt, b.IP, b.PeerIP)
+		}
+		recoveries := fmt.Sprintf(`INSERT INTO agent_status_history (agent_id, from_status, to_status, changed_at)
+			SELECT a.agent_id, a.status, 'ONLINE', v.last_seen
+			FROM agents AS a JOIN (VALUES %s) AS v(agent_id, last_seen, ip, peer_ip) ON a.agent_id = v.agent_id
+			WHERE a.deleted_at IS NULL AND a.status NOT IN ('ONLINE', 'DECOMMISSIONED')`, strings.Join(values, ", "))
+		addresses := fmt.Sprintf(`INSERT INTO agent_ip_history (agent_id, ip, peer_ip, first_seen, last_seen)
+			SELECT a.agent_id, v.ip, v.peer_ip, v.last_seen, v.last_seen
+			FROM agents AS a JOIN (VALUES %s) AS v(agent_id, last_seen, ip, peer_ip) ON a.agent_id = v.agent_id
+			WHERE a.deleted_at IS NULL AND a.status <> 'DECOMMISSIONED' AND (v.ip <> '' OR v.peer_ip <> '')`, strings.Join(values, ", ")) + upsertAddress
+		query := fmt.Sprintf(`UPDATE agents AS a
+			SET status = 'ONLINE', last_seen = v.last_seen, last_known_ip = v.ip, last_peer_ip = v.peer_ip, updated_at = NOW()
+			FROM (VALUES %s) AS v(agent_id, last_seen, ip, peer_ip)
+			WHERE a.agent_id = v.agent_id AND a.deleted_at IS NULL AND a.status <> 'DECOMMISSIONED'`, strings.Join(values, ", "))
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(recoveries, args...).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(addresses, args...).Error; err != nil {
+				return err
+			}
+			return tx.Exec(query, args...).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListAgentIDs returns the agent_id of every registered, non-decommissioned agent.
+func (r *gormRepository) ListAgentIDs() ([]string, error) {
+	var ids []string
+	err := r.db.Model(&model.Agent{}).Where("status <> ?", "DECOMMISSIONED").Pluck("agent_id", &ids).Error
+	return ids, err
+}
+
+// ListAgents returns agents ordered by primary key, starting after filter.AfterID.
+func (r *gormRepository) ListAgents(filter model.AgentFilter) ([]model.Agent, error) {
+	query := r.db.Where("id > ?", filter.AfterID).Order("id")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var agents []model.Agent
+	err := query.Find(&agents).Error
+	return agents, err
+}
+
+// FindCurrentFirewallRules returns the rules from the agent's most recent report.
+func (r *gormRepository) FindCurrentFirewallRules(agentPK uint) ([]model.FirewallRule, error) {
+	var rules []model.FirewallRule
+	latest := r.db.Model(&model.FirewallRule{}).Select("MAX(reported_at)").Where("agent_id = ?", agentPK)
+	err := r.db.Where("agent_id = ? AND reported_at = (?)", agentPK, latest).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// FindCurrentInstalledApps returns the apps from the agent's most recent report.
+func (r *gormRepository) FindCurrentInstalledApps(agentPK uint) ([]model.InstalledApplication, error) {
+	var apps []model.InstalledApplication
+	latest := r.db.Model(&model.InstalledApplication{}).Select("MAX(reported_at)").Where("agent_id = ?", agentPK)
+	err := r.db.Where("agent_id = ? AND reported_at = (?)", agentPK, latest).Order("id").Find(&apps).Error
+	return apps, err
+}
+
+// FindAllCurrentInstalledApps returns the apps from every agent's most
+// recent report, ordered by agent and ID.
+func (r *gormRepository) FindAllCurrentInstalledApps() ([]model.InstalledApplication, error) {
+	var apps []model.InstalledApplication
+	latest := r.db.Model(&model.InstalledApplication{}).Select("agent_id, MAX(reported_at) AS reported_at").Group("agent_id")
+	err := r.db.Joins("JOIN (?) AS latest ON latest.agent_id = installed_applications.agent_id AND latest.reported_at = installed_applications.reported_at", latest).
+		Order("installed_applications.agent_id, installed_applications.id").Find(&apps).Error
+	return apps, err
 }
 
 func (r *gormRepository) CreateFirewallRules(rules []model.FirewallRule) error {
@@ -76,4 +337,144 @@ func (r *gormRepository) FindAgentsByStatus(status string) ([]model.Agent, error
 
 	
 	return agents, nil
-}
\ No newline at end of file
+}
+
+func (r *gormRepository) DeleteFirewallRulesBefore(before time.Time) (int64, error) {
+	// Unscoped: retention removes the rows instead of soft-deleting them.
+	result := r.db.Unscoped().Where(supersededBefore("firewall_rules"), before).Delete(&model.FirewallRule{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormRepository) DeleteInstalledAppsBefore(before time.Time) (int64, error) {
+	result := r.db.Unscoped().Where(supersededBefore("installed_applications"), before).Delete(&model.InstalledApplication{})
+	return result.RowsAffected, result.Error
+}
+
+// supersededBefore selects the rows of a report table created before the
+// cutoff that a later report of the same agent replaced. The latest report
+// is the agent's current state and is kept however old it is, so an agent
+// that stopped reporting does not lose it to retention.
+func supersededBefore(table string) string {
+	return "created_at < ? AND (reported_at IS NULL OR reported_at < (SELECT MAX(latest.reported_at) FROM " + table +
+		" latest WHERE latest.agent_id = " + table + ".agent_id AND latest.deleted_at IS NULL))"
+}
+
+func (r *gormRepository) RecordStatusChange(change *model.AgentStatusChange) error {
+	return r.db.Create(change).Error
+}
+
+// StreamAgents streams the agents matching filter, ordered by primary key.
+func (r *gormRepository) StreamAgents(filter model.ExportFilter, fn func(*model.Agent) error) error {
+	query := exportAgentScope(r.db.Model(&model.Agent{}), filter).Order("agents.id")
+	return streamRows(query, filter.Labels, func(a *model.Agent) model.Labels { return a.Labels }, fn)
+}
+
+// StreamCurrentFirewallRules streams the rules from each matching agent's
+// most recent report, ordered by agent and rule.
+func (r *gormRepository) StreamCurrentFirewallRules(filter model.ExportFilter, fn func(*model.AgentFirewallRule) error) error {
+	latest := r.db.Model(&model.FirewallRule{}).Select("agent_id, MAX(reported_at) AS reported_at").Group("agent_id")
+	query := r.db.Table("firewall_rules").
+		Select("agents.agent_id, agents.hostname, agents.labels, firewall_rules.name, firewall_rules.port, firewall_rules.protocol, "+
+			"firewall_rules.action, firewall_rules.direction, firewall_rules.enabled, firewall_rules.reported_at").
+		Joins("JOIN (?) AS latest ON latest.agent_id = firewall_rules.agent_id AND latest.reported_at = firewall_rules.reported_at", latest).
+		Joins("JOIN agents ON agents.id = firewall_rules.agent_id").
+		Where("firewall_rules.deleted_at IS NULL AND agents.deleted_at IS NULL").
+		Order("agents.id, firewall_rules.id")
+	query = exportAgentScope(query, filter)
+	return streamRows(query, filter.Labels, func(rule *model.AgentFirewallRule) model.Labels { return rule.Labels }, fn)
+}
+
+// StreamCurrentInstalledApps streams the apps from each matching agent's
+// most recent report, ordered by agent and app.
+func (r *gormRepository) StreamCurrentInstalledApps(filter model.ExportFilter, fn func(*model.AgentInstalledApp) error) error {
+	latest := r.db.Model(&model.InstalledApplication{}).Select("agent_id, MAX(reported_at) AS reported_at").Group("agent_id")
+	query := r.db.Table("installed_applications").
+		Select("agents.agent_id, agents.hostname, agents.labels, installed_applications.name, installed_applications.version, "+
+			"installed_applications.publisher, installed_applications.install_date, installed_applications.reported_at").
+		Joins("JOIN (?) AS latest ON latest.agent_id = installed_applications.agent_id AND latest.reported_at = installed_applications.reported_at", latest).
+		Joins("JOIN agents ON agents.id = installed_applications.agent_id").
+		Where("installed_applications.deleted_at IS NULL AND agents.deleted_at IS NULL").
+		Order("agents.id, installed_applications.id")
+	query = exportAgentScope(query, filter)
+	return streamRows(query, filter.Labels, func(app *model.AgentInstalledApp) model.Labels { return app.Labels }, fn)
+}
+
+// StreamStatusHistory streams the status changes of matching agents between
+// filter.Since and filter.Until, oldest first.
+func (r *gormRepository) StreamStatusHistory(filter model.ExportFilter, fn func(*model.AgentStatusChangeRow) error) error {
+	query := r.db.Table("agent_status_history").
+		Select("agent_status_history.*, agents.hostname, agents.labels").
+		Joins("JOIN agents ON agents.agent_id = agent_status_history.agent_id").
+		Where("agents.deleted_at IS NULL").
+		Order("agent_status_history.changed_at, agent_status_history.id")
+	if !filter.Since.IsZero() {
+		query = query.Where("agent_status_history.changed_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("agent_status_history.changed_at < ?", filter.Until)
+	}
+	query = exportAgentScope(query, filter)
+	return streamRows(query, filter.Labels, func(c *model.AgentStatusChangeRow) model.Labels { return c.Labels }, fn)
+}
+
+// FindLastStatusChanges returns the latest change before the given time of
+// each agent matching filter, ordered by agent ID.
+func (r *gormRepository) FindLastStatusChanges(filter model.ExportFilter, before time.Time) ([]model.AgentStatusChangeRow, error) {
+	latest := r.db.Table("agent_status_history").Select("MAX(id) AS id").Where("changed_at < ?", before).Group("agent_id")
+	query := r.db.Table("agent_status_history").
+		Select("agent_status_history.*, agents.hostname, agents.labels").
+		Joins("JOIN (?) AS latest ON latest.id = agent_status_history.id", latest).
+		Joins("JOIN agents ON agents.agent_id = agent_status_history.agent_id").
+		Where("agents.deleted_at IS NULL").
+		Order("agent_status_history.agent_id")
+	query = exportAgentScope(query, filter)
+
+	var rows []model.AgentStatusChangeRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	changes := rows[:0]
+	for _, row := range rows {
+		if row.Labels.Match(filter.Labels) {
+			changes = append(changes, row)
+		}
+	}
+	return changes, nil
+}
+
+// exportAgentScope restricts a query that includes the agents table to the
+// agent and status in filter. Labels are stored as JSON text, so they are
+// matched in Go by streamRows.
+func exportAgentScope(query *gorm.DB, filter model.ExportFilter) *gorm.DB {
+	if filter.AgentID != "" {
+		query = query.Where("agents.agent_id = ?", filter.AgentID)
+	}
+	if filter.Status != "" {
+		query = query.Where("agents.status = ?", filter.Status)
+	}
+	return query
+}
+
+// streamRows scans query row by row into T and passes the rows whose labels
+// match selector to fn.
+func streamRows[T any](query *gorm.DB, selector map[string]string, labels func(*T) model.Labels, fn func(*T) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := query.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if !labels(&row).Match(selector) {
+			continue
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AlertRepository stores the alerts raised by alert rules. A rule has at
+// most one open (pending or firing) alert per key; resolved alerts are kept
+// as history until they are purged.
+type AlertRepository interface {
+	// FindOpenAlerts returns the pending and firing alerts of a rule (of
+	// every rule when ruleID is empty), ordered by rule and key.
+	FindOpenAlerts(ruleID string) ([]model.Alert, error)
+	// CreateAlert fails if the rule already has an open alert with the same key.
+	CreateAlert(alert *model.Alert) error
+	UpdateAlert(alert *model.Alert) error
+	// DeleteAlert removes an alert, used for pending alerts whose condition
+	// cleared before they fired.
+	DeleteAlert(id uint) error
+	// FindAlerts returns alerts, newest first.
+	FindAlerts(filter model.AlertFilter) ([]model.Alert, error)
+	// DeleteResolvedAlertsBefore removes the alerts resolved before t.
+	DeleteResolvedAlertsBefore(t time.Time) (int64, error)
+}
+
+type gormAlertRepository struct {
+	db *gorm.DB
+}
+
+// NewAlertRepository creates an alert repository with a GORM connection.
+func NewAlertRepository(db *gorm.DB) AlertRepository {
+	return &gormAlertRepository{db: db}
+}
+
+func (r *gormAlertRepository) FindOpenAlerts(ruleID string) ([]model.Alert, error) {
+	query := r.db.Where("state <> ?", model.AlertResolved).Order("rule_id, key")
+	if ruleID != "" {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+	var alerts []model.Alert
+	err := query.Find(&alerts).Error
+	return alerts, err
+}
+
+func (r *gormAlertRepository) CreateAlert(alert *model.Alert) error {
+	return r.db.Create(alert).Error
+}
+
+func (r *gormAlertRepository) UpdateAlert(alert *model.Alert) error {
+	return r.db.Save(alert).Error
+}
+
+func (r *gormAlertRepository) DeleteAlert(id uint) error {
+	return r.db.Delete(&model.Alert{}, id).Error
+}
+
+func (r *gormAlertRepository) FindAlerts(filter model.AlertFilter) ([]model.Alert, error) {
+	query := r.db.Order("started_at DESC, id DESC")
+	if filter.RuleID != "" {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if len(filter.States) > 0 {
+		query = query.Where("state IN ?", filter.States)
+	}
+	if len(filter.Severities) > 0 {
+		query = query.Where("severity IN ?", filter.Severities)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("started_at >= ?", filter.Since)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var alerts []model.Alert
+	err := query.Find(&alerts).Error
+	return alerts, err
+}
+
+func (r *gormAlertRepository) DeleteResolvedAlertsBefore(t time.Time) (int64, error) {
+	result := r.db.Where("state = ? AND resolved_at < ?", model.AlertResolved, t).Delete(&model.Alert{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CollectionRepository stores the files collected from agents with
+// COLLECT_FILE commands. The content lives in the artifact store.
+type CollectionRepository interface {
+	CreateCollection(c *model.FileCollection) error
+	SaveCollection(c *model.FileCollection) error
+	FindCollection(id uint) (*model.FileCollection, error)
+	FindCollectionByCommand(commandID uint) (*model.FileCollection, error)
+	// FindCollections returns collections matching the filter, newest first.
+	FindCollections(filter model.FileCollectionFilter) ([]model.FileCollection, error)
+	// FindCollectionsCreatedBefore returns collections created before t that
+	// have not expired yet, oldest first.
+	FindCollectionsCreatedBefore(t time.Time, limit int) ([]model.FileCollection, error)
+}
+
+type gormCollectionRepository struct {
+	db *gorm.DB
+}
+
+// NewCollectionRepository creates a file collection repository with a GORM connection.
+func NewCollectionRepository(db *gorm.DB) CollectionRepository {
+	return &gormCollectionRepository{db: db}
+}
+
+func (r *gormCollectionRepository) CreateCollection(c *model.FileCollection) error {
+	return r.db.Create(c).Error
+}
+
+func (r *gormCollectionRepository) SaveCollection(c *model.FileCollection) error {
+	return r.db.Save(c).Error
+}
+
+func (r *gormCollectionRepository) FindCollection(id uint) (*model.FileCollection, error) {
+	var c model.FileCollection
+	if err := r.db.First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *gormCollectionRepository) FindCollectionByCommand(commandID uint) (*model.FileCollection, error) {
+	var c model.FileCollection
+	if err := r.db.Where("command_id = ?", commandID).First(&c).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *gormCollectionRepository) FindCollections(filter model.FileCollectionFilter) ([]model.FileCollection, error) {
+	query := r.db.Order("id DESC")
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var out []model.FileCollection
+	err := query.Find(&out).Error
+	return out, err
+}
+
+func (r *gormCollectionRepository) FindCollectionsCreatedBefore(t time.Time, limit int) ([]model.FileCollection, error) {
+	query := r.db.Where("created_at < ? AND status <> ?", t, model.CollectionExpired).Order("created_at, id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var out []model.FileCollection
+	err := query.Find(&out).Error
+	return out, err
+}
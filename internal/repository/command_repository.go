@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CommandRepository stores commands queued for agents.
+type CommandRepository interface {
+	CreateCommand(cmd *model.Command) error
+	FindCommandByID(id uint) (*model.Command, error)
+	FindCommands(filter model.CommandFilter) ([]model.Command, error)
+	// ClaimPendingCommands marks the agent's PENDING commands as SENT and returns them.
+	ClaimPendingCommands(agentID string) ([]model.Command, error)
+	// TransitionCommand moves a command to status `to` only if its current status
+	// is one of `from`. It returns false when the command was not in an allowed state.
+	TransitionCommand(id uint, from []string, to, message string) (bool, error)
+	// SetCommandExitCode records the exit code a script command reported.
+	SetCommandExitCode(id uint, exitCode int32) error
+	// AppendCommandOutput stores output chunks of a command in order. Output
+	// beyond maxBytes for the command, counted over every stream, is dropped
+	// and the command flagged as truncated; maxBytes <= 0 means no limit. It
+	// returns whether output was dropped.
+	AppendCommandOutput(commandID uint, chunks []model.CommandOutputChunk, maxBytes int64) (bool, error)
+	// FindCommandOutput returns the stored output chunks of a command in the
+	// order they arrived.
+	FindCommandOutput(commandID uint) ([]model.CommandOutputChunk, error)
+}
+
+type gormCommandRepository struct {
+	db *gorm.DB
+}
+
+// NewCommandRepository creates a command repository with a GORM connection.
+func NewCommandRepository(db *gorm.DB) CommandRepository {
+	return &gormCommandRepository{db: db}
+}
+
+func (r *gormCommandRepository) CreateCommand(cmd *model.Command) error {
+	return r.db.Create(cmd).Error
+}
+
+func (r *gormCommandRepository) FindCommandByID(id uint) (*model.Command, error) {
+	var cmd model.Command
+	if err := r.db.First(&cmd, id).Error; err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+func (r *gormCommandRepository) FindCommands(filter model.CommandFilter) ([]model.Command, error) {
+	query := r.db.Order("id DESC")
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var cmds []model.Command
+	err := query.Find(&cmds).Error
+	return cmds, err
+}
+
+func (r *gormCommandRepository) ClaimPendingCommands(agentID string) ([]model.Command, error) {
+	var cmds []model.Command
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("agent_id = ? AND status = ?", agentID, model.CommandPending).Order("id")
+		if tx.Dialector.Name() == "postgres" {
+			// Lock the rows so a concurrent CancelCommand waits for delivery to finish.
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		if err := query.Find(&cmds).Error; err != nil {
+			return err
+		}
+		if len(cmds) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(cmds))
+		for i := range cmds {
+			ids[i] = cmds[i].ID
+		}
+		now := time.Now()
+		err := tx.Model(&model.Command{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{"status": model.CommandSent, "sent_at": now, "updated_at": now}).Error
+		if err != nil {
+			return err
+		}
+		for i := range cmds {
+			cmds[i].Status = model.CommandSent
+			cmds[i].SentAt = &now
+			cmds[i].UpdatedAt = now
+		}
+		return nil
+	})
+	return cmds, err
+}
+
+func (r *gormCommandRepository) TransitionCommand(id uint, from []string, to, message string) (bool, error) {
+	now := time.Now()
+	updates := map[string]interface{}{"status": to, "result_message": message, "updated_at": now}
+	if isFinalCommandStatus(to) {
+		updates["completed_at"] = now
+	}
+	result := r.db.Model(&model.Command{}).Where("id = ? AND status IN ?", id, from).Updates(updates)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormCommandRepository) SetCommandExitCode(id uint, exitCode int32) error {
+	return r.db.Model(&model.Command{}).Where("id = ?", id).Update("exit_code", exitCode).Error
+}
+
+func (r *gormCommandRepository) AppendCommandOutput(commandID uint, chunks []model.CommandOutputChunk, maxBytes int64) (bool, error) {
+	var truncated bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&model.Command{})
+		if tx.Dialector.Name() == "postgres" {
+			// Lock the command so concurrent streams share one output budget.
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		var cmd model.Command
+		if err := query.First(&cmd, commandID).Error; err != nil {
+			return err
+		}
+		kept, size := capCommandOutput(chunks, cmd.OutputBytes, maxBytes)
+		truncated = size-cmd.OutputBytes < chunkBytes(chunks)
+		if len(kept) > 0 {
+			for i := range kept {
+				kept[i].CommandID = commandID
+			}
+			if err := tx.Create(&kept).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&model.Command{}).Where("id = ?", commandID).Updates(map[string]interface{}{
+			"output_bytes":     size,
+			"output_truncated": cmd.OutputTruncated || truncated,
+		}).Error
+	})
+	return truncated, err
+}
+
+func (r *gormCommandRepository) FindCommandOutput(commandID uint) ([]model.CommandOutputChunk, error) {
+	var chunks []model.CommandOutputChunk
+	err := r.db.Where("command_id = ?", commandID).Order("id").Find(&chunks).Error
+	return chunks, err
+}
+
+// capCommandOutput keeps as much of chunks as fits in maxBytes on top of the
+// used bytes, cutting the last chunk that fits partly. It returns the kept
+// chunks and the new total.
+func capCommandOutput(chunks []model.CommandOutputChunk, used, maxBytes int64) ([]model.CommandOutputChunk, int64) {
+	kept := make([]model.CommandOutputChunk, 0, len(chunks))
+	for _, c := range chunks {
+		if maxBytes > 0 && used+int64(len(c.Data)) > maxBytes {
+			c.Data = c.Data[:maxBytes-min(used, maxBytes)]
+		}
+		if len(c.Data) == 0 {
+			continue
+		}
+		used += int64(len(c.Data))
+		kept = append(kept, c)
+	}
+	return kept, used
+}
+
+func chunkBytes(chunks []model.CommandOutputChunk) int64 {
+	var n int64
+	for _, c := range chunks {
+		n += int64(len(c.Data))
+	}
+	return n
+}
+
+func isFinalCommandStatus(status string) bool {
+	switch status {
+	case model.CommandSucceeded, model.CommandFailed, model.CommandCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ComplianceRepository stores the versions of compliance policies, the
+// current result of every check on every agent and the history of those
+// results.
+type ComplianceRepository interface {
+	// SavePolicyVersion stores a new policy version; the ID and version
+	// must not exist yet.
+	SavePolicyVersion(policy *model.CompliancePolicy) error
+	// FindPolicyVersion returns gorm.ErrRecordNotFound if the version was never loaded.
+	FindPolicyVersion(policyID string, version int) (*model.CompliancePolicy, error)
+	// ListPolicyVersions returns the stored versions of a policy (every
+	// policy when policyID is empty), newest first.
+	ListPolicyVersions(policyID string) ([]model.CompliancePolicy, error)
+
+	// ReplaceAgentResults swaps the results of one policy on one agent for results.
+	ReplaceAgentResults(agentID, policyID string, results []model.ComplianceResult) error
+	// DeleteResults removes the results of an agent, of a policy, or of
+	// both when both are set.
+	DeleteResults(agentID, policyID string) error
+	// FindResults returns results ordered by agent, policy and check order.
+	FindResults(filter model.ComplianceResultFilter) ([]model.ComplianceResult, error)
+
+	AddEvaluation(evaluation *model.ComplianceEvaluation) error
+	// FindEvaluations returns the history, newest first.
+	FindEvaluations(filter model.ComplianceEvaluationFilter) ([]model.ComplianceEvaluation, error)
+}
+
+type gormComplianceRepository struct {
+	db *gorm.DB
+}
+
+// NewComplianceRepository creates a compliance repository with a GORM connection.
+func NewComplianceRepository(db *gorm.DB) ComplianceRepository {
+	return &gormComplianceRepository{db: db}
+}
+
+func (r *gormComplianceRepository) SavePolicyVersion(policy *model.CompliancePolicy) error {
+	return r.db.Create(policy).Error
+}
+
+func (r *gormComplianceRepository) FindPolicyVersion(policyID string, version int) (*model.CompliancePolicy, error) {
+	var policy model.CompliancePolicy
+	err := r.db.Where("policy_id = ? AND version = ?", policyID, version).First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *gormComplianceRepository) ListPolicyVersions(policyID string) ([]model.CompliancePolicy, error) {
+	query := r.db.Order("policy_id, version DESC")
+	if policyID != "" {
+		query = query.Where("policy_id = ?", policyID)
+	}
+	var policies []model.CompliancePolicy
+	err := query.Find(&policies).Error
+	return policies, err
+}
+
+func (r *gormComplianceRepository) ReplaceAgentResults(agentID, policyID string, results []model.ComplianceResult) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_id = ? AND policy_id = ?", agentID, policyID).Delete(&model.ComplianceResult{}).Error; err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+		for i := range results {
+			results[i].AgentID = agentID
+			results[i].PolicyID = policyID
+		}
+		return tx.Create(&results).Error
+	})
+}
+
+func (r *gormComplianceRepository) DeleteResults(agentID, policyID string) error {
+	query := r.db.Session(&gorm.Session{AllowGlobalUpdate: true})
+	if agentID != "" {
+		query = query.Where("agent_id = ?", agentID)
+	}
+	if policyID != "" {
+		query = query.Where("policy_id = ?", policyID)
+	}
+	return query.Delete(&model.ComplianceResult{}).Error
+}
+
+func (r *gormComplianceRepository) FindResults(filter model.ComplianceResultFilter) ([]model.ComplianceResult, error) {
+	query := r.db.Order("agent_id, policy_id, id")
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if filter.PolicyID != "" {
+		query = query.Where("policy_id = ?", filter.PolicyID)
+	}
+	if filter.CheckID != "" {
+		query = query.Where("check_id = ?", filter.CheckID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var results []model.ComplianceResult
+	err := query.Find(&results).Error
+	return results, err
+}
+
+func (r *gormComplianceRepository) AddEvaluation(evaluation *model.ComplianceEvaluation) error {
+	return r.db.Create(evaluation).Error
+}
+
+func (r *gormComplianceRepository) FindEvaluations(filter model.ComplianceEvaluationFilter) ([]model.ComplianceEvaluation, error) {
+	query := r.db.Order("evaluated_at DESC, id DESC")
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if filter.PolicyID != "" {
+		query = query.Where("policy_id = ?", filter.PolicyID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("evaluated_at >= ?", filter.Since)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var evaluations []model.ComplianceEvaluation
+	err := query.Find(&evaluations).Error
+	return evaluations, err
+}
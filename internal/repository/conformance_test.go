@@ -0,0 +1,69 @@
+package repository_test
+
+import (
+	"agent_server/internal/migrations"
+	"agent_server/internal/repository"
+	"agent_server/internal/repository/repotest"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// postgresDSNEnv enables the Postgres backend in the conformance suite,
+// e.g. "host=localhost user=postgres password=secret dbname=agent_test sslmode=disable".
+const postgresDSNEnv = "AGENT_SERVER_TEST_POSTGRES_DSN"
+
+func TestMemoryRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Store {
+		return repository.NewMemoryStore()
+	})
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Store {
+		db, err := repository.ConnectSQLite(filepath.Join(t.TempDir(), "agents.db"))
+		if err != nil {
+			t.Fatalf("ConnectSQLite: %v", err)
+		}
+		t.Cleanup(func() { closeDB(db) })
+		return repository.NewStore(db)
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresDSNEnv)
+	}
+
+	repotest.Run(t, func(t *testing.T) *repository.Store {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("open postgres: %v", err)
+		}
+		t.Cleanup(func() { closeDB(db) })
+
+		sqlDB, _ := db.DB()
+		migrator, err := migrations.New(sqlDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := migrator.Up(context.Background()); err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+		if err := db.Exec("TRUNCATE agents, firewall_rules, installed_applications, commands, webhook_subscriptions, webhook_deliveries, vulnerabilities, vulnerability_findings, agent_patch_statuses, installed_patches, pending_updates, command_output_chunks, agent_packages, rollouts, agent_updates, file_collections, scheduled_jobs, job_runs, job_run_targets, firewall_findings, compliance_policies, compliance_results, compliance_evaluations, agent_status_history, agent_ip_history, agent_metrics, alerts, network_interfaces, local_users, local_groups, system_services, listening_sockets, running_processes RESTART IDENTITY").Error; err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return repository.NewStore(db)
+	})
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
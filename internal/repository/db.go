@@ -2,15 +2,99 @@ package repository
 
 import (
 	"agent_server/internal/config"
+	"agent_server/internal/migrations"
 	"agent_server/internal/model"
+	"context"
 	"fmt"
+	"log"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// ConnectDB يتصل بقاعدة البيانات ويقوم بترحيل النموذج تلقائيًا
-func ConnectDB(cfg *config.DBConfig) (*gorm.DB, error) {
+// Store يجمع كل المستودعات التي يحتاجها الخادم لنوع تخزين واحد
+type Store struct {
+	Agents          AgentRepository
+	Commands        CommandRepository
+	Webhooks        WebhookRepository
+	Vulnerabilities VulnerabilityRepository
+	Firewall        FirewallRepository
+	Compliance      ComplianceRepository
+	Metrics         MetricsRepository
+	Alerts          AlertRepository
+	Inventory       InventoryRepository
+	Patches         PatchRepository
+	Updates         UpdateRepository
+	Collections     CollectionRepository
+	Jobs            JobRepository
+
+	// DB يكون nil عند استخدام التخزين في الذاكرة
+	DB *gorm.DB
+}
+
+// NewStore ينشئ كل المستودعات فوق اتصال GORM واحد (Postgres أو SQLite)
+func NewStore(db *gorm.DB) *Store {
+	return &Store{
+		Agents:          NewAgentRepository(db),
+		Commands:        NewCommandRepository(db),
+		Webhooks:        NewWebhookRepository(db),
+		Vulnerabilities: NewVulnerabilityRepository(db),
+		Firewall:        NewFirewallRepository(db),
+		Compliance:      NewComplianceRepository(db),
+		Metrics:         NewMetricsRepository(db),
+		Alerts:          NewAlertRepository(db),
+		Inventory:       NewInventoryRepository(db),
+		Patches:         NewPatchRepository(db),
+		Updates:         NewUpdateRepository(db),
+		Collections:     NewCollectionRepository(db),
+		Jobs:            NewJobRepository(db),
+		DB:              db,
+	}
+}
+
+// NewMemoryStore ينشئ كل المستودعات في الذاكرة
+func NewMemoryStore() *Store {
+	agents := NewMemoryAgentRepository()
+	return &Store{
+		Agents:          agents,
+		Commands:        NewMemoryCommandRepository(),
+		Webhooks:        NewMemoryWebhookRepository(),
+		Vulnerabilities: NewMemoryVulnerabilityRepository(),
+		Firewall:        NewMemoryFirewallRepository(),
+		Compliance:      NewMemoryComplianceRepository(),
+		Metrics:         NewMemoryMetricsRepository(),
+		Alerts:          NewMemoryAlertRepository(),
+		Inventory:       NewMemoryInventoryRepository(agents),
+		Patches:         NewMemoryPatchRepository(agents),
+		Updates:         NewMemoryUpdateRepository(agents),
+		Collections:     NewMemoryCollectionRepository(),
+		Jobs:            NewMemoryJobRepository(),
+	}
+}
+
+// Open يختار نوع التخزين حسب db.driver ويعيد المستودعات المناسبة
+func Open(cfg *config.DBConfig) (*Store, error) {
+	switch cfg.Driver {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "sqlite":
+		db, err := ConnectSQLite(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		return NewStore(db), nil
+	default:
+		db, err := ConnectDB(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewStore(db), nil
+	}
+}
+
+// OpenDB يفتح الاتصال بقاعدة البيانات دون أي تعديل على المخطط
+func OpenDB(cfg *config.DBConfig) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
 		cfg.Host, cfg.User, cfg.Password, cfg.DBName, cfg.Port, cfg.SSLMode, cfg.TimeZone)
 
@@ -18,15 +102,110 @@ func ConnectDB(cfg *config.DBConfig) (*gorm.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	return db, nil
+}
 
-	// AutoMigrate سيقوم بإنشاء الجداول إذا لم تكن موجودة
-	err = db.AutoMigrate(&model.Agent{}, &model.FirewallRule{}, &model.InstalledApplication{})
+// ConnectDB يتصل بقاعدة البيانات ويتأكد من أن إصدار المخطط يطابق ما يعرفه هذا البرنامج
+// إذا كان migrate_on_start مفعلًا تُطبق الترحيلات المعلقة أولًا تحت قفل Postgres الاستشاري
+func ConnectDB(cfg *config.DBConfig) (*gorm.DB, error) {
+	db, err := OpenDB(cfg)
 	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureSchema(db, cfg.MigrateOnStart); err != nil {
 		// إذا فشل، حاول إغلاق الاتصال قبل إرجاع الخطأ
 		sqlDB, _ := db.DB()
 		sqlDB.Close()
-		return nil, fmt.Errorf("failed to auto-migrate database: %w", err)
+		return nil, err
 	}
 
 	return db, nil
 }
+
+func ensureSchema(db *gorm.DB, migrate bool) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if migrate {
+		if err := migrator.Up(ctx); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+	if err := migrator.Check(ctx); err != nil {
+		return err
+	}
+
+	version, _ := migrator.Version(ctx)
+	log.Printf("Database schema is at version %d.", version)
+	return nil
+}
+
+// sqliteModels هي الجداول التي تُنشأ في SQLite
+// ملفات الترحيل مكتوبة بلهجة Postgres لذلك نستخدم AutoMigrate هنا فقط
+var sqliteModels = []interface{}{
+	&model.Agent{},
+	&model.FirewallRule{},
+	&model.InstalledApplication{},
+	&model.Command{},
+	&model.WebhookSubscription{},
+	&model.WebhookDelivery{},
+	&model.Vulnerability{},
+	&model.VulnerabilityFinding{},
+	&model.FirewallFinding{},
+	&model.CompliancePolicy{},
+	&model.ComplianceResult{},
+	&model.ComplianceEvaluation{},
+	&model.AgentStatusChange{},
+	&model.AgentIPAddress{},
+	&model.AgentMetric{},
+	&model.Alert{},
+	&model.NetworkInterface{},
+	&model.LocalUser{},
+	&model.LocalGroup{},
+	&model.SystemService{},
+	&model.ListeningSocket{},
+	&model.RunningProcess{},
+	&model.AgentPatchStatus{},
+	&model.InstalledPatch{},
+	&model.PendingUpdate{},
+	&model.CommandOutputChunk{},
+	&model.AgentPackage{},
+	&model.Rollout{},
+	&model.AgentUpdate{},
+	&model.FileCollection{},
+	&model.ScheduledJob{},
+	&model.JobRun{},
+	&model.JobRunTarget{},
+}
+
+// ConnectSQLite يفتح ملف SQLite (بدون cgo) وينشئ الجداول للنشر الصغير أو الاختبارات
+// استخدم ":memory:" لقاعدة مؤقتة في الذاكرة
+func ConnectSQLite(path string) (*gorm.DB, error) {
+	dsn := path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	// SQLite يسمح بكاتب واحد فقط، واتصال واحد يمنع أخطاء SQLITE_BUSY
+	// ويضمن أن ":memory:" تبقى نفس قاعدة البيانات
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(sqliteModels...); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// FirewallRepository stores the findings of the firewall rule analysis.
+type FirewallRepository interface {
+	// ReplaceAgentFindings swaps all findings of an agent for findings.
+	ReplaceAgentFindings(agentID string, findings []model.FirewallFinding) error
+	// FindFindings returns findings ordered by severity (highest first), then
+	// agent ID, then in the order the analyzer produced them.
+	FindFindings(filter model.FirewallFindingFilter) ([]model.FirewallFinding, error)
+}
+
+// severityOrder sorts severities by rank in SQL, highest first.
+const severityOrder = "CASE severity WHEN 'CRITICAL' THEN 4 WHEN 'HIGH' THEN 3 WHEN 'MEDIUM' THEN 2 WHEN 'LOW' THEN 1 ELSE 0 END DESC"
+
+type gormFirewallRepository struct {
+	db *gorm.DB
+}
+
+// NewFirewallRepository creates a firewall finding repository with a GORM connection.
+func NewFirewallRepository(db *gorm.DB) FirewallRepository {
+	return &gormFirewallRepository{db: db}
+}
+
+func (r *gormFirewallRepository) ReplaceAgentFindings(agentID string, findings []model.FirewallFinding) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_id = ?", agentID).Delete(&model.FirewallFinding{}).Error; err != nil {
+			return err
+		}
+		if len(findings) == 0 {
+			return nil
+		}
+		for i := range findings {
+			findings[i].AgentID = agentID
+		}
+		return tx.Create(&findings).Error
+	})
+}
+
+func (r *gormFirewallRepository) FindFindings(filter model.FirewallFindingFilter) ([]model.FirewallFinding, error) {
+	query := r.db.Order(severityOrder).Order("agent_id, id")
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if len(filter.Kinds) > 0 {
+		query = query.Where("kind IN ?", filter.Kinds)
+	}
+	if len(filter.Severities) > 0 {
+		query = query.Where("severity IN ?", filter.Severities)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var findings []model.FirewallFinding
+	err := query.Find(&findings).Error
+	return findings, err
+}
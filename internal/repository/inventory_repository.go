@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InventoryRepository stores the extended host inventory agents report:
+// network interfaces, local users and groups, system services, listening
+// sockets and running processes. Like firewall rules and installed apps,
+// every report is a full snapshot stamped with one ReportedAt, and the
+// latest snapshot of an agent is its current inventory. An empty report
+// stores nothing, so the previous snapshot stays current.
+type InventoryRepository interface {
+	CreateNetworkInterfaces(items []model.NetworkInterface) error
+	// CreateLocalUsers stores the users and groups of one report together.
+	CreateLocalUsers(users []model.LocalUser, groups []model.LocalGroup) error
+	CreateServices(items []model.SystemService) error
+	CreateListeningSockets(items []model.ListeningSocket) error
+	CreateProcesses(items []model.RunningProcess) error
+
+	// The FindCurrent methods return the items of the agent's most recent
+	// report, in reported order.
+	FindCurrentNetworkInterfaces(agentPK uint) ([]model.NetworkInterface, error)
+	FindCurrentLocalUsers(agentPK uint) ([]model.LocalUser, error)
+	FindCurrentLocalGroups(agentPK uint) ([]model.LocalGroup, error)
+	FindCurrentServices(agentPK uint) ([]model.SystemService, error)
+	FindCurrentListeningSockets(agentPK uint) ([]model.ListeningSocket, error)
+	FindCurrentProcesses(agentPK uint) ([]model.RunningProcess, error)
+
+	// The StreamCurrent methods stream the current items of every agent
+	// matching filter, ordered by agent and item, with the same cursor rules
+	// as the AgentRepository Stream methods.
+	StreamCurrentNetworkInterfaces(filter model.ExportFilter, fn func(*model.AgentInventory[model.NetworkInterface]) error) error
+	StreamCurrentLocalUsers(filter model.ExportFilter, fn func(*model.AgentInventory[model.LocalUser]) error) error
+	StreamCurrentLocalGroups(filter model.ExportFilter, fn func(*model.AgentInventory[model.LocalGroup]) error) error
+	StreamCurrentServices(filter model.ExportFilter, fn func(*model.AgentInventory[model.SystemService]) error) error
+	StreamCurrentListeningSockets(filter model.ExportFilter, fn func(*model.AgentInventory[model.ListeningSocket]) error) error
+	StreamCurrentProcesses(filter model.ExportFilter, fn func(*model.AgentInventory[model.RunningProcess]) error) error
+
+	// DeleteInventoryBefore removes the items of one inventory kind, such as
+	// model.InventoryProcesses, created before the given time, except those
+	// of each agent's latest report of that kind.
+	DeleteInventoryBefore(kind string, before time.Time) (int64, error)
+}
+
+type gormInventoryRepository struct {
+	db *gorm.DB
+}
+
+// NewInventoryRepository creates an inventory repository with a GORM connection.
+func NewInventoryRepository(db *gorm.DB) InventoryRepository {
+	return &gormInventoryRepository{db: db}
+}
+
+func (r *gormInventoryRepository) CreateNetworkInterfaces(items []model.NetworkInterface) error {
+	return createInventory(r.db, items)
+}
+
+func (r *gormInventoryRepository) CreateLocalUsers(users []model.LocalUser, groups []model.LocalGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := createInventory(tx, users); err != nil {
+			return err
+		}
+		return createInventory(tx, groups)
+	})
+}
+
+func (r *gormInventoryRepository) CreateServices(items []model.SystemService) error {
+	return createInventory(r.db, items)
+}
+
+func (r *gormInventoryRepository) CreateListeningSockets(items []model.ListeningSocket) error {
+	return createInventory(r.db, items)
+}
+
+func (r *gormInventoryRepository) CreateProcesses(items []model.RunningProcess) error {
+	return createInventory(r.db, items)
+}
+
+func (r *gormInventoryRepository) FindCurrentNetworkInterfaces(agentPK uint) ([]model.NetworkInterface, error) {
+	return findCurrentInventory[model.NetworkInterface](r.db, agentPK)
+}
+
+func (r *gormInventoryRepository) FindCurrentLocalUsers(agentPK uint) ([]model.LocalUser, error) {
+	return findCurrentInventory[model.LocalUser](r.db, agentPK)
+}
+
+func (r *gormInventoryRepository) FindCurrentLocalGroups(agentPK uint) ([]model.LocalGroup, error) {
+	return findCurrentInventory[model.LocalGroup](r.db, agentPK)
+}
+
+func (r *gormInventoryRepository) FindCurrentServices(agentPK uint) ([]model.SystemService, error) {
+	return findCurrentInventory[model.SystemService](r.db, agentPK)
+}
+
+func (r *gormInventoryRepository) FindCurrentListeningSockets(agentPK uint) ([]model.ListeningSocket, error) {
+	return findCurrentInventory[model.ListeningSocket](r.db, agentPK)
+}
+
+func (r *gormInventoryRepository) FindCurrentProcesses(agentPK uint) ([]model.RunningProcess, error) {
+	return findCurrentInventory[model.RunningProcess](r.db, agentPK)
+}
+
+func (r *gormInventoryRepository) StreamCurrentNetworkInterfaces(filter model.ExportFilter, fn func(*model.AgentInventory[model.NetworkInterface]) error) error {
+	return streamCurrentInventory(r.db, "network_interfaces", filter, fn)
+}
+
+func (r *gormInventoryRepository) StreamCurrentLocalUsers(filter model.ExportFilter, fn func(*model.AgentInventory[model.LocalUser]) error) error {
+	return streamCurrentInventory(r.db, "local_users", filter, fn)
+}
+
+func (r *gormInventoryRepository) StreamCurrentLocalGroups(filter model.ExportFilter, fn func(*model.AgentInventory[model.LocalGroup]) error) error {
+	return streamCurrentInventory(r.db, "local_groups", filter, fn)
+}
+
+func (r *gormInventoryRepository) StreamCurrentServices(filter model.ExportFilter, fn func(*model.AgentInventory[model.SystemService]) error) error {
+	return streamCurrentInventory(r.db, "system_services", filter, fn)
+}
+
+func (r *gormInventoryRepository) StreamCurrentListeningSockets(filter model.ExportFilter, fn func(*model.AgentInventory[model.ListeningSocket]) error) error {
+	return streamCurrentInventory(r.db, "listening_sockets", filter, fn)
+}
+
+func (r *gormInventoryRepository) StreamCurrentProcesses(filter model.ExportFilter, fn func(*model.AgentInventory[model.RunningProcess]) error) error {
+	return streamCurrentInventory(r.db, "running_processes", filter, fn)
+}
+
+func (r *gormInventoryRepository) DeleteInventoryBefore(kind string, before time.Time) (int64, error) {
+	var item interface{}
+	var table string
+	switch kind {
+	case model.InventoryNetworkInterfaces:
+		item, table = &model.NetworkInterface{}, "network_interfaces"
+	case model.InventoryLocalUsers:
+		item, table = &model.LocalUser{}, "local_users"
+	case model.InventoryLocalGroups:
+		item, table = &model.LocalGroup{}, "local_groups"
+	case model.InventoryServices:
+		item, table = &model.SystemService{}, "system_services"
+	case model.InventoryListeningSockets:
+		item, table = &model.ListeningSocket{}, "listening_sockets"
+	case model.InventoryProcesses:
+		item, table = &model.RunningProcess{}, "running_processes"
+	default:
+		return 0, fmt.Errorf("unknown inventory kind %q", kind)
+	}
+	// Unscoped: retention removes the rows instead of soft-deleting them.
+	result := r.db.Unscoped().Where(supersededBefore(table), before).Delete(item)
+	return result.RowsAffected, result.Error
+}
+
+func createInventory[T any](db *gorm.DB, items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return db.Create(&items).Error
+}
+
+func findCurrentInventory[T any](db *gorm.DB, agentPK uint) ([]T, error) {
+	var items []T
+	latest := db.Model(new(T)).Select("MAX(reported_at)").Where("agent_id = ?", agentPK)
+	err := db.Where("agent_id = ? AND reported_at = (?)", agentPK, latest).Order("id").Find(&items).Error
+	return items, err
+}
+
+// streamCurrentInventory streams the items of table from each matching
+// agent's most recent report, ordered by agent and item.
+func streamCurrentInventory[T any](db *gorm.DB, table string, filter model.ExportFilter, fn func(*model.AgentInventory[T]) error) error {
+	latest := db.Model(new(T)).Select("agent_id, MAX(reported_at) AS reported_at").Group("agent_id")
+	query := db.Table(table).
+		Select("agents.agent_id AS agent_key, agents.hostname, agents.labels, "+table+".*").
+		Joins("JOIN (?) AS latest ON latest.agent_id = "+table+".agent_id AND latest.reported_at = "+table+".reported_at", latest).
+		Joins("JOIN agents ON agents.id = " + table + ".agent_id").
+		Where(table + ".deleted_at IS NULL AND agents.deleted_at IS NULL").
+		Order("agents.id, " + table + ".id")
+	query = exportAgentScope(query, filter)
+	return streamRows(query, filter.Labels, func(i *model.AgentInventory[T]) model.Labels { return i.Labels }, fn)
+}
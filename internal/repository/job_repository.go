@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobRepository stores scheduled fleet jobs, their runs and the agents each
+// run targets.
+type JobRepository interface {
+	CreateJob(job *model.ScheduledJob) error
+	SaveJob(job *model.ScheduledJob) error
+	FindJob(id uint) (*model.ScheduledJob, error)
+	FindJobByName(name string) (*model.ScheduledJob, error)
+	// FindJobs returns the jobs with status, or every job if it is empty,
+	// by name.
+	FindJobs(status string) ([]model.ScheduledJob, error)
+	// FindDueJobs returns the active jobs whose next run is at or before
+	// now, the most overdue first.
+	FindDueJobs(now time.Time) ([]model.ScheduledJob, error)
+	// DeleteJob deletes a job with its runs and their targets.
+	DeleteJob(id uint) error
+
+	// CreateRun records a run together with its targets.
+	CreateRun(run *model.JobRun, targets []model.JobRunTarget) error
+	SaveRun(run *model.JobRun) error
+	FindRun(id uint) (*model.JobRun, error)
+	// FindRuns returns runs matching the filter, newest first.
+	FindRuns(filter model.JobRunFilter) ([]model.JobRun, error)
+	// FindRunningRuns returns the runs still in progress, oldest first.
+	FindRunningRuns() ([]model.JobRun, error)
+	FindRunTargets(runID uint) ([]model.JobRunTarget, error)
+	SaveRunTarget(target *model.JobRunTarget) error
+	// DeleteRunsFinishedBefore deletes runs that finished before t with
+	// their targets, and returns how many runs it deleted.
+	DeleteRunsFinishedBefore(t time.Time) (int64, error)
+}
+
+type gormJobRepository struct {
+	db *gorm.DB
+}
+
+// NewJobRepository creates a scheduled job repository with a GORM connection.
+func NewJobRepository(db *gorm.DB) JobRepository {
+	return &gormJobRepository{db: db}
+}
+
+func (r *gormJobRepository) CreateJob(job *model.ScheduledJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *gormJobRepository) SaveJob(job *model.ScheduledJob) error {
+	return r.db.Save(job).Error
+}
+
+func (r *gormJobRepository) FindJob(id uint) (*model.ScheduledJob, error) {
+	var job model.ScheduledJob
+	if err := r.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *gormJobRepository) FindJobByName(name string) (*model.ScheduledJob, error) {
+	var job model.ScheduledJob
+	if err := r.db.Where("name = ?", name).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *gormJobRepository) FindJobs(status string) ([]model.ScheduledJob, error) {
+	query := r.db.Order("name")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var out []model.ScheduledJob
+	err := query.Find(&out).Error
+	return out, err
+}
+
+func (r *gormJobRepository) FindDueJobs(now time.Time) ([]model.ScheduledJob, error) {
+	var out []model.ScheduledJob
+	err := r.db.Where("status = ? AND next_run_at <= ?", model.JobActive, now).
+		Order("next_run_at, id").Find(&out).Error
+	return out, err
+}
+
+func (r *gormJobRepository) DeleteJob(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&model.ScheduledJob{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		runs := tx.Model(&model.JobRun{}).Select("id").Where("job_id = ?", id)
+		if err := tx.Where("run_id IN (?)", runs).Delete(&model.JobRunTarget{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("job_id = ?", id).Delete(&model.JobRun{}).Error
+	})
+}
+
+func (r *gormJobRepository) CreateRun(run *model.JobRun, targets []model.JobRunTarget) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return err
+		}
+		for i := range targets {
+			targets[i].RunID = run.ID
+		}
+		if len(targets) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(targets, 500).Error
+	})
+}
+
+func (r *gormJobRepository) SaveRun(run *model.JobRun) error {
+	return r.db.Save(run).Error
+}
+
+func (r *gormJobRepository) FindRun(id uint) (*model.JobRun, error) {
+	var run model.JobRun
+	if err := r.db.First(&run, id).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *gormJobRepository) FindRuns(filter model.JobRunFilter) ([]model.JobRun, error) {
+	query := r.db.Order("id DESC")
+	if filter.JobID != 0 {
+		query = query.Where("job_id = ?", filter.JobID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var out []model.JobRun
+	err := query.Find(&out).Error
+	return out, err
+}
+
+func (r *gormJobRepository) FindRunningRuns() ([]model.JobRun, error) {
+	var out []model.JobRun
+	err := r.db.Where("status = ?", model.JobRunRunning).Order("id").Find(&out).Error
+	return out, err
+}
+
+func (r *gormJobRepository) FindRunTargets(runID uint) ([]model.JobRunTarget, error) {
+	var out []model.JobRunTarget
+	err := r.db.Where("run_id = ?", runID).Order("id").Find(&out).Error
+	return out, err
+}
+
+func (r *gormJobRepository) SaveRunTarget(target *model.JobRunTarget) error {
+	return r.db.Save(target).Error
+}
+
+func (r *gormJobRepository) DeleteRunsFinishedBefore(t time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		runs := tx.Model(&model.JobRun{}).Select("id").Where("finished_at < ?", t)
+		if err := tx.Where("run_id IN (?)", runs).Delete(&model.JobRunTarget{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("finished_at < ?", t).Delete(&model.JobRun{})
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryAlertRepository is a thread-safe AlertRepository kept in memory.
+type memoryAlertRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	alerts map[uint]model.Alert
+}
+
+// NewMemoryAlertRepository creates an empty in-memory alert repository.
+func NewMemoryAlertRepository() AlertRepository {
+	return &memoryAlertRepository{alerts: make(map[uint]model.Alert)}
+}
+
+func (r *memoryAlertRepository) FindOpenAlerts(ruleID string) ([]model.Alert, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.Alert
+	for _, a := range r.alerts {
+		if a.State != model.AlertResolved && (ruleID == "" || a.RuleID == ruleID) {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].RuleID != out[j].RuleID {
+			return out[i].RuleID < out[j].RuleID
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out, nil
+}
+
+func (r *memoryAlertRepository) CreateAlert(alert *model.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if alert.State != model.AlertResolved {
+		for _, a := range r.alerts {
+			if a.State != model.AlertResolved && a.RuleID == alert.RuleID && a.Key == alert.Key {
+				return fmt.Errorf("%w: rule %s already has an open alert for %q", gorm.ErrDuplicatedKey, alert.RuleID, alert.Key)
+			}
+		}
+	}
+	r.nextID++
+	alert.ID = r.nextID
+	if alert.UpdatedAt.IsZero() {
+		alert.UpdatedAt = time.Now()
+	}
+	r.alerts[alert.ID] = *alert
+	return nil
+}
+
+func (r *memoryAlertRepository) UpdateAlert(alert *model.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.alerts[alert.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	alert.UpdatedAt = time.Now()
+	r.alerts[alert.ID] = *alert
+	return nil
+}
+
+func (r *memoryAlertRepository) DeleteAlert(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.alerts, id)
+	return nil
+}
+
+func (r *memoryAlertRepository) FindAlerts(filter model.AlertFilter) ([]model.Alert, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.Alert
+	for _, a := range r.alerts {
+		if filter.RuleID != "" && a.RuleID != filter.RuleID {
+			continue
+		}
+		if filter.AgentID != "" && a.AgentID != filter.AgentID {
+			continue
+		}
+		if len(filter.States) > 0 && !containsString(filter.States, a.State) {
+			continue
+		}
+		if len(filter.Severities) > 0 && !containsString(filter.Severities, a.Severity) {
+			continue
+		}
+		if !filter.Since.IsZero() && a.StartedAt.Before(filter.Since) {
+			continue
+		}
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].StartedAt.Equal(out[j].StartedAt) {
+			return out[i].StartedAt.After(out[j].StartedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
+
+func (r *memoryAlertRepository) DeleteResolvedAlertsBefore(t time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for id, a := range r.alerts {
+		if a.State == model.AlertResolved && a.ResolvedAt != nil && a.ResolvedAt.Before(t) {
+			delete(r.alerts, id)
+			n++
+		}
+	}
+	return n, nil
+}
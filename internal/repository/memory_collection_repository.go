@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryCollectionRepository is a thread-safe CollectionRepository kept in
+// memory.
+type memoryCollectionRepository struct {
+	mu          sync.Mutex
+	nextID      uint
+	collections map[uint]model.FileCollection
+}
+
+// NewMemoryCollectionRepository creates an empty in-memory file collection
+// repository.
+func NewMemoryCollectionRepository() CollectionRepository {
+	return &memoryCollectionRepository{collections: make(map[uint]model.FileCollection)}
+}
+
+func (r *memoryCollectionRepository) CreateCollection(c *model.FileCollection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.collections {
+		if existing.CommandID == c.CommandID {
+			return fmt.Errorf("%w: collection for command %d", gorm.ErrDuplicatedKey, c.CommandID)
+		}
+	}
+	r.nextID++
+	c.ID = r.nextID
+	now := time.Now()
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = now
+	}
+	c.UpdatedAt = now
+	r.collections[c.ID] = *c
+	return nil
+}
+
+func (r *memoryCollectionRepository) SaveCollection(c *model.FileCollection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.collections[c.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	c.UpdatedAt = time.Now()
+	r.collections[c.ID] = *c
+	return nil
+}
+
+func (r *memoryCollectionRepository) FindCollection(id uint) (*model.FileCollection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.collections[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &c, nil
+}
+
+func (r *memoryCollectionRepository) FindCollectionByCommand(commandID uint) (*model.FileCollection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.collections {
+		if c.CommandID == commandID {
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryCollectionRepository) FindCollections(filter model.FileCollectionFilter) ([]model.FileCollection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.FileCollection
+	for _, c := range r.collections {
+		if (filter.AgentID == "" || c.AgentID == filter.AgentID) && (filter.Status == "" || c.Status == filter.Status) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
+
+func (r *memoryCollectionRepository) FindCollectionsCreatedBefore(t time.Time, limit int) ([]model.FileCollection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.FileCollection
+	for _, c := range r.collections {
+		if c.CreatedAt.Before(t) && c.Status != model.CollectionExpired {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryCommandRepository is a thread-safe CommandRepository kept in memory.
+type memoryCommandRepository struct {
+	mu          sync.Mutex
+	nextID      uint
+	commands    map[uint]*model.Command
+	nextChunkID uint
+	output      []model.CommandOutputChunk
+}
+
+// NewMemoryCommandRepository creates an empty in-memory command repository.
+func NewMemoryCommandRepository() CommandRepository {
+	return &memoryCommandRepository{commands: make(map[uint]*model.Command)}
+}
+
+func (r *memoryCommandRepository) CreateCommand(cmd *model.Command) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.nextID++
+	cmd.ID = r.nextID
+	cmd.CreatedAt = now
+	cmd.UpdatedAt = now
+	stored := *cmd
+	r.commands[cmd.ID] = &stored
+	return nil
+}
+
+func (r *memoryCommandRepository) FindCommandByID(id uint) (*model.Command, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cmd, ok := r.commands[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *cmd
+	return &found, nil
+}
+
+func (r *memoryCommandRepository) FindCommands(filter model.CommandFilter) ([]model.Command, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var cmds []model.Command
+	for _, cmd := range r.commands {
+		if (filter.AgentID == "" || cmd.AgentID == filter.AgentID) && (filter.Status == "" || cmd.Status == filter.Status) {
+			cmds = append(cmds, *cmd)
+		}
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].ID > cmds[j].ID })
+	if filter.Limit > 0 && len(cmds) > filter.Limit {
+		cmds = cmds[:filter.Limit]
+	}
+	return cmds, nil
+}
+
+func (r *memoryCommandRepository) ClaimPendingCommands(agentID string) ([]model.Command, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var cmds []model.Command
+	for _, cmd := range r.commands {
+		if cmd.AgentID == agentID && cmd.Status == model.CommandPending {
+			cmd.Status = model.CommandSent
+			cmd.SentAt = &now
+			cmd.UpdatedAt = now
+			cmds = append(cmds, *cmd)
+		}
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].ID < cmds[j].ID })
+	return cmds, nil
+}
+
+func (r *memoryCommandRepository) TransitionCommand(id uint, from []string, to, message string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cmd, ok := r.commands[id]
+	if !ok {
+		return false, nil
+	}
+	allowed := false
+	for _, status := range from {
+		if cmd.Status == status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false, nil
+	}
+
+	now := time.Now()
+	cmd.Status = to
+	cmd.ResultMessage = message
+	cmd.UpdatedAt = now
+	if isFinalCommandStatus(to) {
+		cmd.CompletedAt = &now
+	}
+	return true, nil
+}
+
+func (r *memoryCommandRepository) SetCommandExitCode(id uint, exitCode int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cmd, ok := r.commands[id]; ok {
+		cmd.ExitCode = &exitCode
+	}
+	return nil
+}
+
+func (r *memoryCommandRepository) AppendCommandOutput(commandID uint, chunks []model.CommandOutputChunk, maxBytes int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cmd, ok := r.commands[commandID]
+	if !ok {
+		return false, gorm.ErrRecordNotFound
+	}
+	kept, size := capCommandOutput(chunks, cmd.OutputBytes, maxBytes)
+	truncated := size-cmd.OutputBytes < chunkBytes(chunks)
+	now := time.Now()
+	for _, c := range kept {
+		r.nextChunkID++
+		c.ID, c.CommandID, c.CreatedAt = r.nextChunkID, commandID, now
+		c.Data = append([]byte(nil), c.Data...)
+		r.output = append(r.output, c)
+	}
+	cmd.OutputBytes = size
+	cmd.OutputTruncated = cmd.OutputTruncated || truncated
+	return truncated, nil
+}
+
+func (r *memoryCommandRepository) FindCommandOutput(commandID uint) ([]model.CommandOutputChunk, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var chunks []model.CommandOutputChunk
+	for _, c := range r.output {
+		if c.CommandID == commandID {
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks, nil
+}
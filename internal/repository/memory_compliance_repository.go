@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// memoryComplianceRepository is a thread-safe ComplianceRepository kept in memory.
+type memoryComplianceRepository struct {
+	mu          sync.Mutex
+	nextID      uint
+	policies    []model.CompliancePolicy
+	results     map[string][]model.ComplianceResult // by agent ID + "\x00" + policy ID
+	evaluations []model.ComplianceEvaluation
+}
+
+// NewMemoryComplianceRepository creates an empty in-memory compliance repository.
+func NewMemoryComplianceRepository() ComplianceRepository {
+	return &memoryComplianceRepository{results: make(map[string][]model.ComplianceResult)}
+}
+
+func (r *memoryComplianceRepository) SavePolicyVersion(policy *model.CompliancePolicy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.policies {
+		if p.PolicyID == policy.PolicyID && p.Version == policy.Version {
+			return fmt.Errorf("%w: policy %s version %d", gorm.ErrDuplicatedKey, policy.PolicyID, policy.Version)
+		}
+	}
+	r.nextID++
+	policy.ID = r.nextID
+	r.policies = append(r.policies, *policy)
+	return nil
+}
+
+func (r *memoryComplianceRepository) FindPolicyVersion(policyID string, version int) (*model.CompliancePolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.policies {
+		if p.PolicyID == policyID && p.Version == version {
+			return &p, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryComplianceRepository) ListPolicyVersions(policyID string) ([]model.CompliancePolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.CompliancePolicy
+	for _, p := range r.policies {
+		if policyID == "" || p.PolicyID == policyID {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].PolicyID != out[j].PolicyID {
+			return out[i].PolicyID < out[j].PolicyID
+		}
+		return out[i].Version > out[j].Version
+	})
+	return out, nil
+}
+
+func (r *memoryComplianceRepository) ReplaceAgentResults(agentID, policyID string, results []model.ComplianceResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := agentID + "\x00" + policyID
+	if len(results) == 0 {
+		delete(r.results, key)
+		return nil
+	}
+	stored := make([]model.ComplianceResult, len(results))
+	for i := range results {
+		r.nextID++
+		results[i].ID = r.nextID
+		results[i].AgentID = agentID
+		results[i].PolicyID = policyID
+		stored[i] = results[i]
+	}
+	r.results[key] = stored
+	return nil
+}
+
+func (r *memoryComplianceRepository) DeleteResults(agentID, policyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, results := range r.results {
+		if (agentID == "" || results[0].AgentID == agentID) && (policyID == "" || results[0].PolicyID == policyID) {
+			delete(r.results, key)
+		}
+	}
+	return nil
+}
+
+func (r *memoryComplianceRepository) FindResults(filter model.ComplianceResultFilter) ([]model.ComplianceResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.ComplianceResult
+	for _, results := range r.results {
+		for _, res := range results {
+			if (filter.AgentID != "" && res.AgentID != filter.AgentID) ||
+				(filter.PolicyID != "" && res.PolicyID != filter.PolicyID) ||
+				(filter.CheckID != "" && res.CheckID != filter.CheckID) ||
+				(filter.Status != "" && res.Status != filter.Status) {
+				continue
+			}
+			out = append(out, res)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.AgentID != b.AgentID {
+			return a.AgentID < b.AgentID
+		}
+		if a.PolicyID != b.PolicyID {
+			return a.PolicyID < b.PolicyID
+		}
+		return a.ID < b.ID
+	})
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
+
+func (r *memoryComplianceRepository) AddEvaluation(evaluation *model.ComplianceEvaluation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	evaluation.ID = r.nextID
+	r.evaluations = append(r.evaluations, *evaluation)
+	return nil
+}
+
+func (r *memoryComplianceRepository) FindEvaluations(filter model.ComplianceEvaluationFilter) ([]model.ComplianceEvaluation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.ComplianceEvaluation
+	for i := len(r.evaluations) - 1; i >= 0; i-- {
+		e := r.evaluations[i]
+		if (filter.AgentID != "" && e.AgentID != filter.AgentID) ||
+			(filter.PolicyID != "" && e.PolicyID != filter.PolicyID) ||
+			e.EvaluatedAt.Before(filter.Since) {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].EvaluatedAt.After(out[j].EvaluatedAt) })
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
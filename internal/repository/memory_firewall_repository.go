@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"sort"
+	"sync"
+)
+
+// memoryFirewallRepository is a thread-safe FirewallRepository kept in memory.
+type memoryFirewallRepository struct {
+	mu       sync.Mutex
+	nextID   uint
+	findings map[string][]model.FirewallFinding // by agent ID
+}
+
+// NewMemoryFirewallRepository creates an empty in-memory firewall finding repository.
+func NewMemoryFirewallRepository() FirewallRepository {
+	return &memoryFirewallRepository{findings: make(map[string][]model.FirewallFinding)}
+}
+
+func (r *memoryFirewallRepository) ReplaceAgentFindings(agentID string, findings []model.FirewallFinding) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(findings) == 0 {
+		delete(r.findings, agentID)
+		return nil
+	}
+	stored := make([]model.FirewallFinding, len(findings))
+	for i := range findings {
+		r.nextID++
+		findings[i].ID = r.nextID
+		findings[i].AgentID = agentID
+		stored[i] = findings[i]
+	}
+	r.findings[agentID] = stored
+	return nil
+}
+
+func (r *memoryFirewallRepository) FindFindings(filter model.FirewallFindingFilter) ([]model.FirewallFinding, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.FirewallFinding
+	for agentID, findings := range r.findings {
+		if filter.AgentID != "" && agentID != filter.AgentID {
+			continue
+		}
+		for _, f := range findings {
+			if len(filter.Kinds) > 0 && !containsString(filter.Kinds, f.Kind) {
+				continue
+			}
+			if len(filter.Severities) > 0 && !containsString(filter.Severities, f.Severity) {
+				continue
+			}
+			out = append(out, f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if ra, rb := model.SeverityRank(a.Severity), model.SeverityRank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		if a.AgentID != b.AgentID {
+			return a.AgentID < b.AgentID
+		}
+		return a.ID < b.ID
+	})
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryInventoryRepository is a thread-safe InventoryRepository kept in
+// memory. It reads agents from the agent repository to stream inventory
+// with agent details.
+type memoryInventoryRepository struct {
+	mu         sync.RWMutex
+	agents     AgentRepository
+	nextID     uint
+	interfaces []model.NetworkInterface
+	users      []model.LocalUser
+	groups     []model.LocalGroup
+	services   []model.SystemService
+	sockets    []model.ListeningSocket
+	processes  []model.RunningProcess
+}
+
+// NewMemoryInventoryRepository creates an empty in-memory inventory
+// repository for the agents in agents.
+func NewMemoryInventoryRepository(agents AgentRepository) InventoryRepository {
+	return &memoryInventoryRepository{agents: agents}
+}
+
+func (r *memoryInventoryRepository) CreateNetworkInterfaces(items []model.NetworkInterface) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	appendInventory(&r.nextID, &r.interfaces, items)
+	return nil
+}
+
+func (r *memoryInventoryRepository) CreateLocalUsers(users []model.LocalUser, groups []model.LocalGroup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	appendInventory(&r.nextID, &r.users, users)
+	appendInventory(&r.nextID, &r.groups, groups)
+	return nil
+}
+
+func (r *memoryInventoryRepository) CreateServices(items []model.SystemService) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	appendInventory(&r.nextID, &r.services, items)
+	return nil
+}
+
+func (r *memoryInventoryRepository) CreateListeningSockets(items []model.ListeningSocket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	appendInventory(&r.nextID, &r.sockets, items)
+	return nil
+}
+
+func (r *memoryInventoryRepository) CreateProcesses(items []model.RunningProcess) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	appendInventory(&r.nextID, &r.processes, items)
+	return nil
+}
+
+func (r *memoryInventoryRepository) FindCurrentNetworkInterfaces(agentPK uint) ([]model.NetworkInterface, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return currentInventory(r.interfaces, agentPK), nil
+}
+
+func (r *memoryInventoryRepository) FindCurrentLocalUsers(agentPK uint) ([]model.LocalUser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return currentInventory(r.users, agentPK), nil
+}
+
+func (r *memoryInventoryRepository) FindCurrentLocalGroups(agentPK uint) ([]model.LocalGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return currentInventory(r.groups, agentPK), nil
+}
+
+func (r *memoryInventoryRepository) FindCurrentServices(agentPK uint) ([]model.SystemService, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return currentInventory(r.services, agentPK), nil
+}
+
+func (r *memoryInventoryRepository) FindCurrentListeningSockets(agentPK uint) ([]model.ListeningSocket, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return currentInventory(r.sockets, agentPK), nil
+}
+
+func (r *memoryInventoryRepository) FindCurrentProcesses(agentPK uint) ([]model.RunningProcess, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return currentInventory(r.processes, agentPK), nil
+}
+
+func (r *memoryInventoryRepository) StreamCurrentNetworkInterfaces(filter model.ExportFilter, fn func(*model.AgentInventory[model.NetworkInterface]) error) error {
+	return streamInventory(r, &r.interfaces, filter, fn)
+}
+
+func (r *memoryInventoryRepository) StreamCurrentLocalUsers(filter model.ExportFilter, fn func(*model.AgentInventory[model.LocalUser]) error) error {
+	return streamInventory(r, &r.users, filter, fn)
+}
+
+func (r *memoryInventoryRepository) StreamCurrentLocalGroups(filter model.ExportFilter, fn func(*model.AgentInventory[model.LocalGroup]) error) error {
+	return streamInventory(r, &r.groups, filter, fn)
+}
+
+func (r *memoryInventoryRepository) StreamCurrentServices(filter model.ExportFilter, fn func(*model.AgentInventory[model.SystemService]) error) error {
+	return streamInventory(r, &r.services, filter, fn)
+}
+
+func (r *memoryInventoryRepository) StreamCurrentListeningSockets(filter model.ExportFilter, fn func(*model.AgentInventory[model.ListeningSocket]) error) error {
+	return streamInventory(r, &r.sockets, filter, fn)
+}
+
+func (r *memoryInventoryRepository) StreamCurrentProcesses(filter model.ExportFilter, fn func(*model.AgentInventory[model.RunningProcess]) error) error {
+	return streamInventory(r, &r.processes, filter, fn)
+}
+
+func (r *memoryInventoryRepository) DeleteInventoryBefore(kind string, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch kind {
+	case model.InventoryNetworkInterfaces:
+		return deleteInventoryBefore(&r.interfaces, before), nil
+	case model.InventoryLocalUsers:
+		return deleteInventoryBefore(&r.users, before), nil
+	case model.InventoryLocalGroups:
+		return deleteInventoryBefore(&r.groups, before), nil
+	case model.InventoryServices:
+		return deleteInventoryBefore(&r.services, before), nil
+	case model.InventoryListeningSockets:
+		return deleteInventoryBefore(&r.sockets, before), nil
+	case model.InventoryProcesses:
+		return deleteInventoryBefore(&r.processes, before), nil
+	}
+	return 0, fmt.Errorf("unknown inventory kind %q", kind)
+}
+
+func appendInventory[T any, P model.InventoryModel[T]](nextID *uint, table *[]T, items []T) {
+	now := time.Now()
+	for i := range items {
+		*nextID++
+		item := P(&items[i]).Item()
+		item.ID = *nextID
+		item.CreatedAt = now
+		item.UpdatedAt = now
+		*table = append(*table, items[i])
+	}
+}
+
+func currentInventory[T any, P model.InventoryModel[T]](table []T, agentPK uint) []T {
+	var latest time.Time
+	for i := range table {
+		if item := P(&table[i]).Item(); item.AgentID == agentPK && item.ReportedAt.After(latest) {
+			latest = item.ReportedAt
+		}
+	}
+	var items []T
+	for i := range table {
+		if item := P(&table[i]).Item(); item.AgentID == agentPK && item.ReportedAt.Equal(latest) {
+			items = append(items, table[i])
+		}
+	}
+	return items
+}
+
+// streamInventory copies the current items of the matching agents and
+// calls fn without holding the lock, like the memory agent repository.
+func streamInventory[T any, P model.InventoryModel[T]](r *memoryInventoryRepository, table *[]T, filter model.ExportFilter, fn func(*model.AgentInventory[T]) error) error {
+	agents := make(map[uint]model.Agent)
+	err := r.agents.StreamAgents(filter, func(a *model.Agent) error {
+		agents[a.ID] = *a
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	latest := make(map[uint]time.Time)
+	for i := range *table {
+		item := P(&(*table)[i]).Item()
+		if item.ReportedAt.After(latest[item.AgentID]) {
+			latest[item.AgentID] = item.ReportedAt
+		}
+	}
+	var items []T
+	for i := range *table {
+		item := P(&(*table)[i]).Item()
+		if _, ok := agents[item.AgentID]; ok && item.ReportedAt.Equal(latest[item.AgentID]) {
+			items = append(items, (*table)[i])
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := P(&items[i]).Item(), P(&items[j]).Item()
+		if a.AgentID != b.AgentID {
+			return a.AgentID < b.AgentID
+		}
+		return a.ID < b.ID
+	})
+	for i := range items {
+		agent := agents[P(&items[i]).Item().AgentID]
+		err := fn(&model.AgentInventory[T]{
+			AgentKey: agent.AgentID,
+			Hostname: agent.Hostname,
+			Labels:   agent.Labels,
+			Item:     items[i],
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteInventoryBefore[T any, P model.InventoryModel[T]](table *[]T, before time.Time) int64 {
+	latest := make(map[uint]time.Time)
+	for i := range *table {
+		item := P(&(*table)[i]).Item()
+		if item.ReportedAt.After(latest[item.AgentID]) {
+			latest[item.AgentID] = item.ReportedAt
+		}
+	}
+	kept := (*table)[:0]
+	for i := range *table {
+		item := P(&(*table)[i]).Item()
+		if !item.CreatedAt.Before(before) || !item.ReportedAt.Before(latest[item.AgentID]) {
+			kept = append(kept, (*table)[i])
+		}
+	}
+	deleted := int64(len(*table) - len(kept))
+	*table = kept
+	return deleted
+}
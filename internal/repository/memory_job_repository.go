@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryJobRepository is a thread-safe JobRepository kept in memory.
+type memoryJobRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	jobs    map[uint]model.ScheduledJob
+	runs    map[uint]model.JobRun
+	targets map[uint]model.JobRunTarget
+}
+
+// NewMemoryJobRepository creates an empty in-memory scheduled job repository.
+func NewMemoryJobRepository() JobRepository {
+	return &memoryJobRepository{
+		jobs:    make(map[uint]model.ScheduledJob),
+		runs:    make(map[uint]model.JobRun),
+		targets: make(map[uint]model.JobRunTarget),
+	}
+}
+
+func (r *memoryJobRepository) CreateJob(job *model.ScheduledJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.jobs {
+		if existing.Name == job.Name {
+			return fmt.Errorf("%w: job %q", gorm.ErrDuplicatedKey, job.Name)
+		}
+	}
+	r.nextID++
+	job.ID = r.nextID
+	now := time.Now()
+	job.CreatedAt, job.UpdatedAt = now, now
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *memoryJobRepository) SaveJob(job *model.ScheduledJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.jobs[job.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	job.UpdatedAt = time.Now()
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *memoryJobRepository) FindJob(id uint) (*model.ScheduledJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &job, nil
+}
+
+func (r *memoryJobRepository) FindJobByName(name string) (*model.ScheduledJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, job := range r.jobs {
+		if job.Name == name {
+			return &job, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryJobRepository) FindJobs(status string) ([]model.ScheduledJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.ScheduledJob
+	for _, job := range r.jobs {
+		if status == "" || job.Status == status {
+			out = append(out, job)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *memoryJobRepository) FindDueJobs(now time.Time) ([]model.ScheduledJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.ScheduledJob
+	for _, job := range r.jobs {
+		if job.Status == model.JobActive && job.NextRunAt != nil && !job.NextRunAt.After(now) {
+			out = append(out, job)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].NextRunAt.Equal(*out[j].NextRunAt) {
+			return out[i].NextRunAt.Before(*out[j].NextRunAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (r *memoryJobRepository) DeleteJob(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.jobs[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.jobs, id)
+	for runID, run := range r.runs {
+		if run.JobID == id {
+			r.deleteRunLocked(runID)
+		}
+	}
+	return nil
+}
+
+func (r *memoryJobRepository) deleteRunLocked(runID uint) {
+	delete(r.runs, runID)
+	for id, target := range r.targets {
+		if target.RunID == runID {
+			delete(r.targets, id)
+		}
+	}
+}
+
+func (r *memoryJobRepository) CreateRun(run *model.JobRun, targets []model.JobRunTarget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		if seen[target.AgentID] {
+			return fmt.Errorf("%w: agent %s twice in a run", gorm.ErrDuplicatedKey, target.AgentID)
+		}
+		seen[target.AgentID] = true
+	}
+	r.nextID++
+	run.ID = r.nextID
+	r.runs[run.ID] = *run
+	for i := range targets {
+		r.nextID++
+		targets[i].ID, targets[i].RunID = r.nextID, run.ID
+		r.targets[targets[i].ID] = targets[i]
+	}
+	return nil
+}
+
+func (r *memoryJobRepository) SaveRun(run *model.JobRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.runs[run.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	r.runs[run.ID] = *run
+	return nil
+}
+
+func (r *memoryJobRepository) FindRun(id uint) (*model.JobRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, ok := r.runs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &run, nil
+}
+
+func (r *memoryJobRepository) FindRuns(filter model.JobRunFilter) ([]model.JobRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.JobRun
+	for _, run := range r.runs {
+		if (filter.JobID == 0 || run.JobID == filter.JobID) && (filter.Status == "" || run.Status == filter.Status) {
+			out = append(out, run)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
+
+func (r *memoryJobRepository) FindRunningRuns() ([]model.JobRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.JobRun
+	for _, run := range r.runs {
+		if run.Status == model.JobRunRunning {
+			out = append(out, run)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (r *memoryJobRepository) FindRunTargets(runID uint) ([]model.JobRunTarget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.JobRunTarget
+	for _, target := range r.targets {
+		if target.RunID == runID {
+			out = append(out, target)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (r *memoryJobRepository) SaveRunTarget(target *model.JobRunTarget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.targets[target.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	r.targets[target.ID] = *target
+	return nil
+}
+
+func (r *memoryJobRepository) DeleteRunsFinishedBefore(t time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, run := range r.runs {
+		if run.FinishedAt != nil && run.FinishedAt.Before(t) {
+			r.deleteRunLocked(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
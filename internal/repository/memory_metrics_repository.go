@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// metricKey identifies a point: one per agent, resolution, time, metric and mount.
+type metricKey struct {
+	agentID     string
+	resolution  int
+	bucketStart int64
+	metric      string
+	mount       string
+}
+
+func newMetricKey(p *model.AgentMetric) metricKey {
+	return metricKey{p.AgentID, p.Resolution, p.BucketStart.UnixNano(), p.Metric, p.Mount}
+}
+
+// memoryMetricsRepository is a thread-safe MetricsRepository kept in memory.
+type memoryMetricsRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	points map[metricKey]model.AgentMetric
+}
+
+// NewMemoryMetricsRepository creates an empty in-memory metrics repository.
+func NewMemoryMetricsRepository() MetricsRepository {
+	return &memoryMetricsRepository{points: make(map[metricKey]model.AgentMetric)}
+}
+
+func (r *memoryMetricsRepository) InsertSamples(points []model.AgentMetric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range points {
+		key := newMetricKey(&points[i])
+		if _, ok := r.points[key]; ok {
+			continue
+		}
+		r.nextID++
+		points[i].ID = r.nextID
+		r.points[key] = points[i]
+	}
+	return nil
+}
+
+func (r *memoryMetricsRepository) UpsertBuckets(points []model.AgentMetric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range points {
+		key := newMetricKey(&points[i])
+		if existing, ok := r.points[key]; ok {
+			points[i].ID = existing.ID
+		} else {
+			r.nextID++
+			points[i].ID = r.nextID
+		}
+		r.points[key] = points[i]
+	}
+	return nil
+}
+
+func (r *memoryMetricsRepository) FindMetrics(filter model.MetricFilter) ([]model.AgentMetric, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.AgentMetric
+	for _, p := range r.points {
+		if p.AgentID != filter.AgentID || p.Resolution != filter.Resolution {
+			continue
+		}
+		if len(filter.Metrics) > 0 && !containsString(filter.Metrics, p.Metric) {
+			continue
+		}
+		if !filter.Since.IsZero() && p.BucketStart.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && !p.BucketStart.Before(filter.Until) {
+			continue
+		}
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Metric != b.Metric {
+			return a.Metric < b.Metric
+		}
+		if a.Mount != b.Mount {
+			return a.Mount < b.Mount
+		}
+		return a.BucketStart.Before(b.BucketStart)
+	})
+	return out, nil
+}
+
+func (r *memoryMetricsRepository) StreamMetrics(resolution int, since, until time.Time, fn func(*model.AgentMetric) error) error {
+	r.mu.Lock()
+	var matched []model.AgentMetric
+	for _, p := range r.points {
+		if p.Resolution == resolution && !p.BucketStart.Before(since) && p.BucketStart.Before(until) {
+			matched = append(matched, p)
+		}
+	}
+	r.mu.Unlock()
+
+	for i := range matched {
+		if err := fn(&matched[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryMetricsRepository) BucketRange(resolution int) (first, last time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.points {
+		if p.Resolution != resolution {
+			continue
+		}
+		if first.IsZero() || p.BucketStart.Before(first) {
+			first = p.BucketStart
+		}
+		if p.BucketStart.After(last) {
+			last = p.BucketStart
+		}
+	}
+	return first, last, nil
+}
+
+func (r *memoryMetricsRepository) DeleteMetricsBefore(resolution int, t time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for key, p := range r.points {
+		if p.Resolution == resolution && p.BucketStart.Before(t) {
+			delete(r.points, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryPatchRepository is a thread-safe PatchRepository kept in memory. It
+// reads agents from the agent repository to filter and describe statuses.
+type memoryPatchRepository struct {
+	mu        sync.RWMutex
+	agents    AgentRepository
+	nextID    uint
+	statuses  map[uint]model.AgentPatchStatus // by agent primary key
+	installed []model.InstalledPatch
+	pending   []model.PendingUpdate
+}
+
+// NewMemoryPatchRepository creates an empty in-memory patch status
+// repository for the agents in agents.
+func NewMemoryPatchRepository(agents AgentRepository) PatchRepository {
+	return &memoryPatchRepository{agents: agents, statuses: make(map[uint]model.AgentPatchStatus)}
+}
+
+func (r *memoryPatchRepository) SavePatchReport(status *model.AgentPatchStatus, installed []model.InstalledPatch, pending []model.PendingUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.statuses[status.AgentID]; ok {
+		status.ID, status.CreatedAt = existing.ID, existing.CreatedAt
+	} else {
+		r.nextID++
+		status.ID, status.CreatedAt = r.nextID, now
+	}
+	status.UpdatedAt = now
+	stored := *status
+	if status.RebootPendingSince != nil {
+		since := *status.RebootPendingSince
+		stored.RebootPendingSince = &since
+	}
+	r.statuses[status.AgentID] = stored
+	appendInventory(&r.nextID, &r.installed, installed)
+	appendInventory(&r.nextID, &r.pending, pending)
+	return nil
+}
+
+func (r *memoryPatchRepository) FindPatchStatus(agentPK uint) (*model.AgentPatchStatus, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status, ok := r.statuses[agentPK]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &status, nil
+}
+
+func (r *memoryPatchRepository) FindPatches(agentPK uint, reportedAt time.Time) ([]model.InstalledPatch, []model.PendingUpdate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return reportItems(r.installed, agentPK, reportedAt), reportItems(r.pending, agentPK, reportedAt), nil
+}
+
+func (r *memoryPatchRepository) FindPatchStatuses(filter model.PatchStatusFilter) ([]model.AgentPatchStatusRow, error) {
+	agents := make(map[uint]model.Agent)
+	err := r.agents.StreamAgents(model.ExportFilter{AgentID: filter.AgentID}, func(a *model.Agent) error {
+		if filter.OSName == "" || a.OSName == filter.OSName {
+			agents[a.ID] = *a
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var rows []model.AgentPatchStatusRow
+	for agentPK, status := range r.statuses {
+		agent, ok := agents[agentPK]
+		if !ok || !patchStatusMatches(&status, filter) {
+			continue
+		}
+		if filter.MissingPatch != "" && r.hasPatch(agentPK, status.ReportedAt, filter.MissingPatch) {
+			continue
+		}
+		rows = append(rows, model.AgentPatchStatusRow{
+			AgentKey: agent.AgentID,
+			Hostname: agent.Hostname,
+			OSName:   agent.OSName,
+			Labels:   agent.Labels,
+			Status:   status,
+		})
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].AgentKey < rows[j].AgentKey })
+	return limitPatchStatuses(rows, filter), nil
+}
+
+func patchStatusMatches(status *model.AgentPatchStatus, filter model.PatchStatusFilter) bool {
+	if !filter.RebootPendingBefore.IsZero() &&
+		(!status.RebootPending || status.RebootPendingSince == nil || !status.RebootPendingSince.Before(filter.RebootPendingBefore)) {
+		return false
+	}
+	return !filter.PendingOnly || status.PendingCount > 0
+}
+
+// hasPatch reports whether the agent's report at reportedAt lists patchID as
+// installed. The caller must hold r.mu.
+func (r *memoryPatchRepository) hasPatch(agentPK uint, reportedAt time.Time, patchID string) bool {
+	for _, p := range r.installed {
+		if p.AgentID == agentPK && p.ReportedAt.Equal(reportedAt) && p.PatchID == patchID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryPatchRepository) DeletePatchesBefore(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return deleteExpiredPatches(r, &r.installed, before) + deleteExpiredPatches(r, &r.pending, before), nil
+}
+
+// deleteExpiredPatches removes the items created before the given time that
+// are not part of their agent's latest report. The caller must hold r.mu.
+func deleteExpiredPatches[T any, P model.InventoryModel[T]](r *memoryPatchRepository, table *[]T, before time.Time) int64 {
+	kept := (*table)[:0]
+	for i := range *table {
+		item := P(&(*table)[i]).Item()
+		status, ok := r.statuses[item.AgentID]
+		if !item.CreatedAt.Before(before) || ok && status.ReportedAt.Equal(item.ReportedAt) {
+			kept = append(kept, (*table)[i])
+		}
+	}
+	deleted := int64(len(*table) - len(kept))
+	*table = kept
+	return deleted
+}
+
+func reportItems[T any, P model.InventoryModel[T]](table []T, agentPK uint, reportedAt time.Time) []T {
+	var items []T
+	for i := range table {
+		if item := P(&table[i]).Item(); item.AgentID == agentPK && item.ReportedAt.Equal(reportedAt) {
+			items = append(items, table[i])
+		}
+	}
+	return items
+}
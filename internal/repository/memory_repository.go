@@ -0,0 +1,615 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryRepository is a thread-safe AgentRepository kept entirely in memory.
+// It returns gorm.ErrRecordNotFound like the GORM implementation so callers
+// do not need to know which backend they are using.
+type memoryRepository struct {
+	mu            sync.RWMutex
+	nextAgentID   uint
+	nextRecordID  uint
+	agents        map[string]*model.Agent
+	firewallRules []model.FirewallRule
+	installedApps []model.InstalledApplication
+	statusHistory []model.AgentStatusChange
+	ipHistory     []model.AgentIPAddress
+}
+
+// NewMemoryAgentRepository creates an empty in-memory repository.
+func NewMemoryAgentRepository() AgentRepository {
+	return &memoryRepository{agents: make(map[string]*model.Agent)}
+}
+
+func (r *memoryRepository) FindAgentByID(agentID string) (*model.Agent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	agent, ok := r.agents[agentID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *agent
+	return &found, nil
+}
+
+func (r *memoryRepository) CreateAgent(agent *model.Agent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.agents[agent.AgentID]; exists {
+		return fmt.Errorf("%w: agent %s already exists", gorm.ErrDuplicatedKey, agent.AgentID)
+	}
+
+	now := time.Now()
+	r.nextAgentID++
+	agent.ID = r.nextAgentID
+	agent.CreatedAt = now
+	agent.UpdatedAt = now
+
+	stored := *agent
+	r.agents[agent.AgentID] = &stored
+	return nil
+}
+
+func (r *memoryRepository) UpdateAgent(agent *model.Agent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.agents[agent.AgentID]
+	if !ok {
+		// Save in GORM inserts missing rows, so do the same here.
+		r.nextAgentID++
+		agent.ID = r.nextAgentID
+		agent.CreatedAt = time.Now()
+	} else {
+		agent.ID = existing.ID
+		agent.CreatedAt = existing.CreatedAt
+	}
+	agent.UpdatedAt = time.Now()
+
+	stored := *agent
+	r.agents[agent.AgentID] = &stored
+	return nil
+}
+
+func (r *memoryRepository) UpdateHeartbeat(agentID, ip, peerIP string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	agent, ok := r.agents[agentID]
+	if !ok || agent.Status == "DECOMMISSIONED" {
+		return 0, nil
+	}
+	now := time.Now()
+	r.recordRecovery(agent, now)
+	agent.Status = "ONLINE"
+	agent.LastSeen = now
+	agent.LastKnownIP = ip
+	agent.LastPeerIP = peerIP
+	agent.UpdatedAt = now
+	r.recordAddress(model.Heartbeat{AgentID: agentID, IP: ip, PeerIP: peerIP, SeenAt: now})
+	return 1, nil
+}
+
+func (r *memoryRepository) BulkUpdateHeartbeats(beats []model.Heartbeat) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, b := range beats {
+		agent, ok := r.agents[b.AgentID]
+		if !ok || agent.Status == "DECOMMISSIONED" {
+			continue
+		}
+		r.recordRecovery(agent, b.SeenAt)
+		agent.Status = "ONLINE"
+		agent.LastSeen = b.SeenAt
+		agent.LastKnownIP = b.IP
+		agent.LastPeerIP = b.PeerIP
+		agent.UpdatedAt = now
+		r.recordAddress(b)
+	}
+	return nil
+}
+
+// recordRecovery adds the transition to ONLINE of an agent that sends a
+// heartbeat while in another status. The caller must hold r.mu.
+func (r *memoryRepository) recordRecovery(agent *model.Agent, at time.Time) {
+	if agent.Status == "ONLINE" {
+		return
+	}
+	r.nextRecordID++
+	r.statusHistory = append(r.statusHistory, model.AgentStatusChange{
+		ID:         r.nextRecordID,
+		AgentID:    agent.AgentID,
+		FromStatus: agent.Status,
+		ToStatus:   "ONLINE",
+		ChangedAt:  at,
+	})
+}
+
+// recordAddress extends the last_seen of the beat's address in the agent's
+// IP history, or adds the address. The caller must hold r.mu.
+func (r *memoryRepository) recordAddress(b model.Heartbeat) {
+	if b.IP == "" && b.PeerIP == "" {
+		return
+	}
+	for i := range r.ipHistory {
+		a := &r.ipHistory[i]
+		if a.AgentID == b.AgentID && a.IP == b.IP && a.PeerIP == b.PeerIP {
+			if b.SeenAt.After(a.LastSeen) {
+				a.LastSeen = b.SeenAt
+			}
+			return
+		}
+	}
+	r.nextRecordID++
+	r.ipHistory = append(r.ipHistory, model.AgentIPAddress{
+		ID:        r.nextRecordID,
+		AgentID:   b.AgentID,
+		IP:        b.IP,
+		PeerIP:    b.PeerIP,
+		FirstSeen: b.SeenAt,
+		LastSeen:  b.SeenAt,
+	})
+}
+
+func (r *memoryRepository) RecordAddresses(beats []model.Heartbeat) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, b := range beats {
+		r.recordAddress(b)
+	}
+	return nil
+}
+
+func (r *memoryRepository) FindIPHistory(filter model.IPHistoryFilter) ([]model.AgentIPAddress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var addresses []model.AgentIPAddress
+	for _, a := range r.ipHistory {
+		switch {
+		case filter.AgentID != "" && a.AgentID != filter.AgentID,
+			filter.IP != "" && a.IP != filter.IP && a.PeerIP != filter.IP,
+			!filter.Since.IsZero() && a.LastSeen.Before(filter.Since),
+			!filter.Until.IsZero() && !a.FirstSeen.Before(filter.Until),
+			filter.MismatchOnly && !a.Mismatch():
+			continue
+		}
+		addresses = append(addresses, a)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		if !addresses[i].LastSeen.Equal(addresses[j].LastSeen) {
+			return addresses[i].LastSeen.After(addresses[j].LastSeen)
+		}
+		return addresses[i].ID > addresses[j].ID
+	})
+	if filter.Limit > 0 && len(addresses) > filter.Limit {
+		addresses = addresses[:filter.Limit]
+	}
+	return addresses, nil
+}
+
+func (r *memoryRepository) FlagSuspectedClone(agentID, reason string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	agent, ok := r.agents[agentID]
+	if !ok || agent.CloneSuspectedAt != nil {
+		return false, nil
+	}
+	agent.CloneSuspectedAt = &at
+	agent.CloneReason = reason
+	agent.UpdatedAt = time.Now()
+	return true, nil
+}
+
+func (r *memoryRepository) ClearSuspectedClone(agentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	agent, ok := r.agents[agentID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	agent.CloneSuspectedAt = nil
+	agent.CloneReason = ""
+	agent.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *memoryRepository) FindSuspectedClones(limit int) ([]model.Agent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var agents []model.Agent
+	for _, agent := range r.agents {
+		if agent.CloneSuspectedAt != nil {
+			agents = append(agents, *agent)
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		a, b := agents[i].CloneSuspectedAt, agents[j].CloneSuspectedAt
+		if !a.Equal(*b) {
+			return a.After(*b)
+		}
+		return agents[i].ID < agents[j].ID
+	})
+	if limit > 0 && len(agents) > limit {
+		agents = agents[:limit]
+	}
+	return agents, nil
+}
+
+func (r *memoryRepository) ListAgentIDs() ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.agents))
+	for id, agent := range r.agents {
+		if agent.Status != "DECOMMISSIONED" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *memoryRepository) ListAgents(filter model.AgentFilter) ([]model.Agent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var agents []model.Agent
+	for _, agent := range r.agents {
+		if agent.ID > filter.AfterID && (filter.Status == "" || agent.Status == filter.Status) {
+			agents = append(agents, *agent)
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	if filter.Limit > 0 && len(agents) > filter.Limit {
+		agents = agents[:filter.Limit]
+	}
+	return agents, nil
+}
+
+func (r *memoryRepository) FindCurrentFirewallRules(agentPK uint) ([]model.FirewallRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest time.Time
+	for _, rule := range r.firewallRules {
+		if rule.AgentID == agentPK && rule.ReportedAt.After(latest) {
+			latest = rule.ReportedAt
+		}
+	}
+	var rules []model.FirewallRule
+	for _, rule := range r.firewallRules {
+		if rule.AgentID == agentPK && rule.ReportedAt.Equal(latest) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (r *memoryRepository) FindCurrentInstalledApps(agentPK uint) ([]model.InstalledApplication, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest time.Time
+	for _, app := range r.installedApps {
+		if app.AgentID == agentPK && app.ReportedAt.After(latest) {
+			latest = app.ReportedAt
+		}
+	}
+	var apps []model.InstalledApplication
+	for _, app := range r.installedApps {
+		if app.AgentID == agentPK && app.ReportedAt.Equal(latest) {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+func (r *memoryRepository) FindAllCurrentInstalledApps() ([]model.InstalledApplication, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	latest := make(map[uint]time.Time)
+	for _, app := range r.installedApps {
+		if app.ReportedAt.After(latest[app.AgentID]) {
+			latest[app.AgentID] = app.ReportedAt
+		}
+	}
+	var apps []model.InstalledApplication
+	for _, app := range r.installedApps {
+		if app.ReportedAt.Equal(latest[app.AgentID]) {
+			apps = append(apps, app)
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].AgentID != apps[j].AgentID {
+			return apps[i].AgentID < apps[j].AgentID
+		}
+		return apps[i].ID < apps[j].ID
+	})
+	return apps, nil
+}
+
+func (r *memoryRepository) CreateFirewallRules(rules []model.FirewallRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for i := range rules {
+		r.nextRecordID++
+		rules[i].ID = r.nextRecordID
+		rules[i].CreatedAt = now
+		rules[i].UpdatedAt = now
+		r.firewallRules = append(r.firewallRules, rules[i])
+	}
+	return nil
+}
+
+func (r *memoryRepository) CreateInstalledApps(apps []model.InstalledApplication) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for i := range apps {
+		r.nextRecordID++
+		apps[i].ID = r.nextRecordID
+		apps[i].CreatedAt = now
+		apps[i].UpdatedAt = now
+		r.installedApps = append(r.installedApps, apps[i])
+	}
+	return nil
+}
+
+func (r *memoryRepository) FindAgentsByStatus(status string) ([]model.Agent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var agents []model.Agent
+	for _, agent := range r.agents {
+		if agent.Status == status {
+			agents = append(agents, *agent)
+		}
+	}
+	return agents, nil
+}
+
+func (r *memoryRepository) DeleteFirewallRulesBefore(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	latest := make(map[uint]time.Time)
+	for _, rule := range r.firewallRules {
+		if rule.ReportedAt.After(latest[rule.AgentID]) {
+			latest[rule.AgentID] = rule.ReportedAt
+		}
+	}
+	kept := r.firewallRules[:0]
+	for _, rule := range r.firewallRules {
+		if !rule.CreatedAt.Before(before) || !rule.ReportedAt.Before(latest[rule.AgentID]) {
+			kept = append(kept, rule)
+		}
+	}
+	deleted := int64(len(r.firewallRules) - len(kept))
+	r.firewallRules = kept
+	return deleted, nil
+}
+
+func (r *memoryRepository) DeleteInstalledAppsBefore(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	latest := make(map[uint]time.Time)
+	for _, app := range r.installedApps {
+		if app.ReportedAt.After(latest[app.AgentID]) {
+			latest[app.AgentID] = app.ReportedAt
+		}
+	}
+	kept := r.installedApps[:0]
+	for _, app := range r.installedApps {
+		if !app.CreatedAt.Before(before) || !app.ReportedAt.Before(latest[app.AgentID]) {
+			kept = append(kept, app)
+		}
+	}
+	deleted := int64(len(r.installedApps) - len(kept))
+	r.installedApps = kept
+	return deleted, nil
+}
+
+func (r *memoryRepository) RecordStatusChange(change *model.AgentStatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextRecordID++
+	change.ID = r.nextRecordID
+	r.statusHistory = append(r.statusHistory, *change)
+	return nil
+}
+
+// The memory backend already holds every row, so its Stream methods copy the
+// matching rows and call fn without holding the lock.
+
+func (r *memoryRepository) StreamAgents(filter model.ExportFilter, fn func(*model.Agent) error) error {
+	r.mu.RLock()
+	var agents []model.Agent
+	for _, agent := range r.agents {
+		if exportMatches(agent, filter) {
+			agents = append(agents, *agent)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	for i := range agents {
+		if err := fn(&agents[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) StreamCurrentFirewallRules(filter model.ExportFilter, fn func(*model.AgentFirewallRule) error) error {
+	r.mu.RLock()
+	agents := r.exportAgentsByPK(filter)
+	latest := make(map[uint]time.Time)
+	for _, rule := range r.firewallRules {
+		if rule.ReportedAt.After(latest[rule.AgentID]) {
+			latest[rule.AgentID] = rule.ReportedAt
+		}
+	}
+	var rules []model.FirewallRule
+	for _, rule := range r.firewallRules {
+		if _, ok := agents[rule.AgentID]; ok && rule.ReportedAt.Equal(latest[rule.AgentID]) {
+			rules = append(rules, rule)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].AgentID != rules[j].AgentID {
+			return rules[i].AgentID < rules[j].AgentID
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	for _, rule := range rules {
+		agent := agents[rule.AgentID]
+		err := fn(&model.AgentFirewallRule{
+			AgentID:    agent.AgentID,
+			Hostname:   agent.Hostname,
+			Labels:     agent.Labels,
+			Name:       rule.Name,
+			Port:       rule.Port,
+			Protocol:   rule.Protocol,
+			Action:     rule.Action,
+			Direction:  rule.Direction,
+			Enabled:    rule.Enabled,
+			ReportedAt: rule.ReportedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) StreamCurrentInstalledApps(filter model.ExportFilter, fn func(*model.AgentInstalledApp) error) error {
+	r.mu.RLock()
+	agents := r.exportAgentsByPK(filter)
+	latest := make(map[uint]time.Time)
+	for _, app := range r.installedApps {
+		if app.ReportedAt.After(latest[app.AgentID]) {
+			latest[app.AgentID] = app.ReportedAt
+		}
+	}
+	var apps []model.InstalledApplication
+	for _, app := range r.installedApps {
+		if _, ok := agents[app.AgentID]; ok && app.ReportedAt.Equal(latest[app.AgentID]) {
+			apps = append(apps, app)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].AgentID != apps[j].AgentID {
+			return apps[i].AgentID < apps[j].AgentID
+		}
+		return apps[i].ID < apps[j].ID
+	})
+	for _, app := range apps {
+		agent := agents[app.AgentID]
+		err := fn(&model.AgentInstalledApp{
+			AgentID:     agent.AgentID,
+			Hostname:    agent.Hostname,
+			Labels:      agent.Labels,
+			Name:        app.Name,
+			Version:     app.Version,
+			Publisher:   app.Publisher,
+			InstallDate: app.InstallDate,
+			ReportedAt:  app.ReportedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) StreamStatusHistory(filter model.ExportFilter, fn func(*model.AgentStatusChangeRow) error) error {
+	r.mu.RLock()
+	var changes []model.AgentStatusChangeRow
+	for _, change := range r.statusHistory {
+		agent, ok := r.agents[change.AgentID]
+		if !ok || !exportMatches(agent, filter) ||
+			change.ChangedAt.Before(filter.Since) || (!filter.Until.IsZero() && !change.ChangedAt.Before(filter.Until)) {
+			continue
+		}
+		changes = append(changes, model.AgentStatusChangeRow{AgentStatusChange: change, Hostname: agent.Hostname, Labels: agent.Labels})
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].ChangedAt.Equal(changes[j].ChangedAt) {
+			return changes[i].ChangedAt.Before(changes[j].ChangedAt)
+		}
+		return changes[i].ID < changes[j].ID
+	})
+	for i := range changes {
+		if err := fn(&changes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) FindLastStatusChanges(filter model.ExportFilter, before time.Time) ([]model.AgentStatusChangeRow, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	last := make(map[string]model.AgentStatusChange)
+	for _, change := range r.statusHistory {
+		if change.ChangedAt.Before(before) && change.ID > last[change.AgentID].ID {
+			last[change.AgentID] = change
+		}
+	}
+	var changes []model.AgentStatusChangeRow
+	for agentID, change := range last {
+		agent, ok := r.agents[agentID]
+		if ok && exportMatches(agent, filter) {
+			changes = append(changes, model.AgentStatusChangeRow{AgentStatusChange: change, Hostname: agent.Hostname, Labels: agent.Labels})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].AgentID < changes[j].AgentID })
+	return changes, nil
+}
+
+// exportAgentsByPK returns copies of the agents matching filter by primary
+// key. The caller must hold r.mu.
+func (r *memoryRepository) exportAgentsByPK(filter model.ExportFilter) map[uint]model.Agent {
+	agents := make(map[uint]model.Agent)
+	for _, agent := range r.agents {
+		if exportMatches(agent, filter) {
+			agents[agent.ID] = *agent
+		}
+	}
+	return agents
+}
+
+func exportMatches(agent *model.Agent, filter model.ExportFilter) bool {
+	return (filter.AgentID == "" || agent.AgentID == filter.AgentID) &&
+		(filter.Status == "" || agent.Status == filter.Status) &&
+		agent.Labels.Match(filter.Labels)
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryUpdateRepository is a thread-safe UpdateRepository kept in memory.
+// It reads agents from the agent repository to describe updates.
+type memoryUpdateRepository struct {
+	mu       sync.Mutex
+	agents   AgentRepository
+	nextID   uint
+	packages map[uint]model.AgentPackage
+	rollouts map[uint]model.Rollout
+	updates  map[uint]model.AgentUpdate
+}
+
+// NewMemoryUpdateRepository creates an empty in-memory agent update
+// repository for the agents in agents.
+func NewMemoryUpdateRepository(agents AgentRepository) UpdateRepository {
+	return &memoryUpdateRepository{
+		agents:   agents,
+		packages: make(map[uint]model.AgentPackage),
+		rollouts: make(map[uint]model.Rollout),
+		updates:  make(map[uint]model.AgentUpdate),
+	}
+}
+
+func (r *memoryUpdateRepository) CreatePackage(pkg *model.AgentPackage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.packages {
+		if p.Version == pkg.Version && p.OS == pkg.OS && p.Arch == pkg.Arch {
+			return fmt.Errorf("%w: package %s for %s/%s", gorm.ErrDuplicatedKey, pkg.Version, pkg.OS, pkg.Arch)
+		}
+	}
+	r.nextID++
+	pkg.ID = r.nextID
+	if pkg.CreatedAt.IsZero() {
+		pkg.CreatedAt = time.Now()
+	}
+	r.packages[pkg.ID] = *pkg
+	return nil
+}
+
+func (r *memoryUpdateRepository) FindPackages(version string) ([]model.AgentPackage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.AgentPackage
+	for _, p := range r.packages {
+		if version == "" || p.Version == version {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out, nil
+}
+
+func (r *memoryUpdateRepository) FindPackage(id uint) (*model.AgentPackage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.packages[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &p, nil
+}
+
+func (r *memoryUpdateRepository) CreateRollout(rollout *model.Rollout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	rollout.ID = r.nextID
+	now := time.Now()
+	rollout.CreatedAt, rollout.UpdatedAt = now, now
+	r.rollouts[rollout.ID] = *rollout
+	return nil
+}
+
+func (r *memoryUpdateRepository) SaveRollout(rollout *model.Rollout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rollouts[rollout.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	rollout.UpdatedAt = time.Now()
+	r.rollouts[rollout.ID] = *rollout
+	return nil
+}
+
+func (r *memoryUpdateRepository) FindRollout(id uint) (*model.Rollout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rollout, ok := r.rollouts[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rollout, nil
+}
+
+func (r *memoryUpdateRepository) FindRollouts(statuses []string, limit int) ([]model.Rollout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.Rollout
+	for _, rollout := range r.rollouts {
+		if len(statuses) == 0 || containsString(statuses, rollout.Status) {
+			out = append(out, rollout)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *memoryUpdateRepository) CreateAgentUpdate(update *model.AgentUpdate) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.updates {
+		if u.RolloutID == update.RolloutID && u.AgentID == update.AgentID {
+			return false, nil
+		}
+	}
+	r.nextID++
+	update.ID = r.nextID
+	r.updates[update.ID] = *update
+	return true, nil
+}
+
+func (r *memoryUpdateRepository) FindAgentUpdates(rolloutID uint, status string, limit int) ([]model.AgentUpdateRow, error) {
+	r.mu.Lock()
+	var updates []model.AgentUpdate
+	for _, u := range r.updates {
+		if u.RolloutID == rolloutID && (status == "" || u.Status == status) {
+			updates = append(updates, u)
+		}
+	}
+	r.mu.Unlock()
+
+	agents := make(map[uint]*model.Agent)
+	err := r.agents.StreamAgents(model.ExportFilter{}, func(a *model.Agent) error {
+		agents[a.ID] = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []model.AgentUpdateRow
+	for _, u := range updates {
+		a, ok := agents[u.AgentID]
+		if !ok {
+			continue
+		}
+		out = append(out, model.AgentUpdateRow{AgentKey: a.AgentID, Hostname: a.Hostname, Update: u})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Update.OfferedAt.Equal(out[j].Update.OfferedAt) {
+			return out[i].Update.OfferedAt.After(out[j].Update.OfferedAt)
+		}
+		return out[i].Update.ID > out[j].Update.ID
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *memoryUpdateRepository) FinishAgentUpdate(rolloutID, agentPK uint, status, message string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, u := range r.updates {
+		if u.RolloutID == rolloutID && u.AgentID == agentPK && u.Status == model.AgentUpdateOffered {
+			u.Status, u.Message, u.FinishedAt = status, message, &at
+			r.updates[id] = u
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryUpdateRepository) CountAgentUpdates(rolloutID uint) (model.RolloutCounts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var counts model.RolloutCounts
+	for _, u := range r.updates {
+		if u.RolloutID != rolloutID {
+			continue
+		}
+		switch u.Status {
+		case model.AgentUpdateOffered:
+			counts.Offered++
+		case model.AgentUpdateSucceeded:
+			counts.Succeeded++
+		case model.AgentUpdateFailed:
+			counts.Failed++
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryVulnerabilityRepository is a thread-safe VulnerabilityRepository kept in memory.
+type memoryVulnerabilityRepository struct {
+	mu            sync.Mutex
+	nextVulnID    uint
+	nextFindingID uint
+	vulns         []model.Vulnerability
+	findings      map[string][]model.VulnerabilityFinding // by agent ID
+}
+
+// NewMemoryVulnerabilityRepository creates an empty in-memory vulnerability repository.
+func NewMemoryVulnerabilityRepository() VulnerabilityRepository {
+	return &memoryVulnerabilityRepository{findings: make(map[string][]model.VulnerabilityFinding)}
+}
+
+func (r *memoryVulnerabilityRepository) ReplaceVulnerabilities(vulns []model.Vulnerability) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]bool, len(vulns))
+	stored := make([]model.Vulnerability, len(vulns))
+	for i := range vulns {
+		if seen[vulns[i].VulnerabilityID] {
+			return fmt.Errorf("%w: vulnerability %s appears twice", gorm.ErrDuplicatedKey, vulns[i].VulnerabilityID)
+		}
+		seen[vulns[i].VulnerabilityID] = true
+		r.nextVulnID++
+		vulns[i].ID = r.nextVulnID
+		stored[i] = vulns[i]
+	}
+	r.vulns = stored
+	return nil
+}
+
+func (r *memoryVulnerabilityRepository) ListVulnerabilities() ([]model.Vulnerability, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.Vulnerability(nil), r.vulns...), nil
+}
+
+func (r *memoryVulnerabilityRepository) LatestVulnerabilityImport() (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest time.Time
+	for _, v := range r.vulns {
+		if v.ImportedAt.After(latest) {
+			latest = v.ImportedAt
+		}
+	}
+	return latest, nil
+}
+
+func (r *memoryVulnerabilityRepository) ReplaceAgentFindings(agentID string, findings []model.VulnerabilityFinding) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(findings) == 0 {
+		delete(r.findings, agentID)
+		return nil
+	}
+	stored := make([]model.VulnerabilityFinding, len(findings))
+	for i := range findings {
+		r.nextFindingID++
+		findings[i].ID = r.nextFindingID
+		findings[i].AgentID = agentID
+		stored[i] = findings[i]
+	}
+	r.findings[agentID] = stored
+	return nil
+}
+
+func (r *memoryVulnerabilityRepository) FindFindings(filter model.VulnerabilityFindingFilter) ([]model.VulnerabilityFinding, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.VulnerabilityFinding
+	for agentID, findings := range r.findings {
+		if filter.AgentID != "" && agentID != filter.AgentID {
+			continue
+		}
+		for _, f := range findings {
+			if len(filter.VulnerabilityIDs) > 0 && !containsString(filter.VulnerabilityIDs, f.VulnerabilityID) {
+				continue
+			}
+			if len(filter.Severities) > 0 && !containsString(filter.Severities, f.Severity) {
+				continue
+			}
+			out = append(out, f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.CVSSScore != b.CVSSScore {
+			return a.CVSSScore > b.CVSSScore
+		}
+		if a.AgentID != b.AgentID {
+			return a.AgentID < b.AgentID
+		}
+		if a.VulnerabilityID != b.VulnerabilityID {
+			return a.VulnerabilityID < b.VulnerabilityID
+		}
+		return a.AppName < b.AppName
+	})
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
+
+func (r *memoryVulnerabilityRepository) CountAffectedAgents() (map[string]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int64)
+	for _, findings := range r.findings {
+		seen := map[string]bool{}
+		for _, f := range findings {
+			if !seen[f.VulnerabilityID] {
+				seen[f.VulnerabilityID] = true
+				counts[f.VulnerabilityID]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
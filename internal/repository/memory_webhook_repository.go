@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryWebhookRepository is a thread-safe WebhookRepository kept in memory.
+type memoryWebhookRepository struct {
+	mu             sync.Mutex
+	nextSubID      uint
+	nextDeliveryID uint
+	subs           map[uint]*model.WebhookSubscription
+	deliveries     map[uint]*model.WebhookDelivery
+}
+
+// NewMemoryWebhookRepository creates an empty in-memory webhook repository.
+func NewMemoryWebhookRepository() WebhookRepository {
+	return &memoryWebhookRepository{
+		subs:       make(map[uint]*model.WebhookSubscription),
+		deliveries: make(map[uint]*model.WebhookDelivery),
+	}
+}
+
+func (r *memoryWebhookRepository) CreateSubscription(sub *model.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.nextSubID++
+	sub.ID = r.nextSubID
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+	stored := *sub
+	r.subs[sub.ID] = &stored
+	return nil
+}
+
+func (r *memoryWebhookRepository) FindSubscriptionByID(id uint) (*model.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *sub
+	return &found, nil
+}
+
+func (r *memoryWebhookRepository) ListSubscriptions() ([]model.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs := make([]model.WebhookSubscription, 0, len(r.subs))
+	for _, sub := range r.subs {
+		subs = append(subs, *sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs, nil
+}
+
+func (r *memoryWebhookRepository) DeleteSubscription(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subs[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.subs, id)
+	for deliveryID, d := range r.deliveries {
+		if d.SubscriptionID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (r *memoryWebhookRepository) EnqueueDeliveries(deliveries []model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for i := range deliveries {
+		r.nextDeliveryID++
+		deliveries[i].ID = r.nextDeliveryID
+		deliveries[i].CreatedAt = now
+		deliveries[i].UpdatedAt = now
+		stored := deliveries[i]
+		r.deliveries[stored.ID] = &stored
+	}
+	return nil
+}
+
+func (r *memoryWebhookRepository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*model.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == model.WebhookPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].ID < due[j].ID
+		}
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]model.WebhookDelivery, len(due))
+	for i, d := range due {
+		claimed[i] = *d
+		d.NextAttemptAt = now.Add(lease)
+	}
+	return claimed, nil
+}
+
+func (r *memoryWebhookRepository) SaveDeliveryAttempt(delivery *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.deliveries[delivery.ID]
+	if !ok {
+		// The subscription was deleted while the attempt was in flight.
+		return nil
+	}
+	d.Status = delivery.Status
+	d.Attempts = delivery.Attempts
+	d.NextAttemptAt = delivery.NextAttemptAt
+	d.LastError = delivery.LastError
+	d.LastStatusCode = delivery.LastStatusCode
+	d.DeliveredAt = delivery.DeliveredAt
+	d.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *memoryWebhookRepository) FindDeliveries(filter model.WebhookDeliveryFilter) ([]model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deliveries []model.WebhookDelivery
+	for _, d := range r.deliveries {
+		if (filter.SubscriptionID == 0 || d.SubscriptionID == filter.SubscriptionID) && (filter.Status == "" || d.Status == filter.Status) {
+			deliveries = append(deliveries, *d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	if filter.Limit > 0 && len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
+
+func (r *memoryWebhookRepository) ResetDeliveries(ids []uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var n int64
+	for _, id := range ids {
+		d, ok := r.deliveries[id]
+		if !ok {
+			continue
+		}
+		d.Status = model.WebhookPending
+		d.Attempts = 0
+		d.NextAttemptAt = now
+		d.LastError = ""
+		d.DeliveredAt = nil
+		d.UpdatedAt = now
+		n++
+	}
+	return n, nil
+}
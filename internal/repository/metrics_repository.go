@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MetricsRepository stores agent resource metrics as time series at several
+// resolutions: raw samples and rolled-up buckets.
+type MetricsRepository interface {
+	// InsertSamples stores raw samples. A sample already stored for the same
+	// agent, metric, mount and time is left unchanged.
+	InsertSamples(points []model.AgentMetric) error
+	// UpsertBuckets stores rolled-up buckets, replacing the ones that exist.
+	UpsertBuckets(points []model.AgentMetric) error
+	// FindMetrics returns an agent's points ordered by metric, mount and time.
+	FindMetrics(filter model.MetricFilter) ([]model.AgentMetric, error)
+	// StreamMetrics calls fn for every point of every agent at resolution
+	// that starts in [since, until).
+	StreamMetrics(resolution int, since, until time.Time, fn func(*model.AgentMetric) error) error
+	// BucketRange returns the start of the first and the last point stored at
+	// resolution, or zero times when there is none.
+	BucketRange(resolution int) (first, last time.Time, err error)
+	// DeleteMetricsBefore deletes the points at resolution that start before t.
+	DeleteMetricsBefore(resolution int, t time.Time) (int64, error)
+}
+
+// metricsBatchSize bounds the rows of a single INSERT.
+const metricsBatchSize = 1000
+
+var metricPointColumns = []clause.Column{{Name: "agent_id"}, {Name: "resolution"}, {Name: "bucket_start"}, {Name: "metric"}, {Name: "mount"}}
+
+type gormMetricsRepository struct {
+	db *gorm.DB
+}
+
+// NewMetricsRepository creates a metrics repository with a GORM connection.
+func NewMetricsRepository(db *gorm.DB) MetricsRepository {
+	return &gormMetricsRepository{db: db}
+}
+
+func (r *gormMetricsRepository) InsertSamples(points []model.AgentMetric) error {
+	if len(points) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{Columns: metricPointColumns, DoNothing: true}).
+		CreateInBatches(&points, metricsBatchSize).Error
+}
+
+func (r *gormMetricsRepository) UpsertBuckets(points []model.AgentMetric) error {
+	if len(points) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   metricPointColumns,
+		DoUpdates: clause.AssignmentColumns([]string{"count", "sum", "min", "max"}),
+	}).CreateInBatches(&points, metricsBatchSize).Error
+}
+
+func (r *gormMetricsRepository) FindMetrics(filter model.MetricFilter) ([]model.AgentMetric, error) {
+	query := r.db.Where("agent_id = ? AND resolution = ?", filter.AgentID, filter.Resolution).
+		Order("metric, mount, bucket_start")
+	if len(filter.Metrics) > 0 {
+		query = query.Where("metric IN ?", filter.Metrics)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("bucket_start >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("bucket_start < ?", filter.Until)
+	}
+	var points []model.AgentMetric
+	err := query.Find(&points).Error
+	return points, err
+}
+
+func (r *gormMetricsRepository) StreamMetrics(resolution int, since, until time.Time, fn func(*model.AgentMetric) error) error {
+	query := r.db.Model(&model.AgentMetric{}).
+		Where("resolution = ? AND bucket_start >= ? AND bucket_start < ?", resolution, since, until)
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var point model.AgentMetric
+		if err := query.ScanRows(rows, &point); err != nil {
+			return err
+		}
+		if err := fn(&point); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *gormMetricsRepository) BucketRange(resolution int) (first, last time.Time, err error) {
+	// Ordered lookups rather than MIN/MAX, which SQLite returns as text.
+	edge := func(order string) (time.Time, error) {
+		var points []model.AgentMetric
+		err := r.db.Select("bucket_start").Where("resolution = ?", resolution).Order(order).Limit(1).Find(&points).Error
+		if err != nil || len(points) == 0 {
+			return time.Time{}, err
+		}
+		return points[0].BucketStart, nil
+	}
+	if first, err = edge("bucket_start"); err != nil || first.IsZero() {
+		return first, last, err
+	}
+	last, err = edge("bucket_start DESC")
+	return first, last, err
+}
+
+func (r *gormMetricsRepository) DeleteMetricsBefore(resolution int, t time.Time) (int64, error) {
+	result := r.db.Where("resolution = ? AND bucket_start < ?", resolution, t).Delete(&model.AgentMetric{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PatchRepository stores the OS patch status agents report. Each agent has
+// one status row that every report replaces, while the installed and pending
+// patches are snapshots like the rest of the inventory, stamped with the
+// status's ReportedAt.
+type PatchRepository interface {
+	// SavePatchReport replaces the agent's status with status and adds the
+	// installed and pending patches of the report. The caller sets AgentID
+	// and ReportedAt on all of them.
+	SavePatchReport(status *model.AgentPatchStatus, installed []model.InstalledPatch, pending []model.PendingUpdate) error
+	// FindPatchStatus returns the agent's status, or gorm.ErrRecordNotFound
+	// if it never reported one.
+	FindPatchStatus(agentPK uint) (*model.AgentPatchStatus, error)
+	// FindPatches returns the installed and pending patches of the agent's
+	// report made at reportedAt, in reported order.
+	FindPatches(agentPK uint, reportedAt time.Time) ([]model.InstalledPatch, []model.PendingUpdate, error)
+	// FindPatchStatuses returns the status of every agent that reported one
+	// and matches filter, ordered by agent ID.
+	FindPatchStatuses(filter model.PatchStatusFilter) ([]model.AgentPatchStatusRow, error)
+	// DeletePatchesBefore removes installed and pending patches created
+	// before the given time, except those of each agent's latest report.
+	DeletePatchesBefore(before time.Time) (int64, error)
+}
+
+type gormPatchRepository struct {
+	db *gorm.DB
+}
+
+// NewPatchRepository creates a patch status repository with a GORM connection.
+func NewPatchRepository(db *gorm.DB) PatchRepository {
+	return &gormPatchRepository{db: db}
+}
+
+func (r *gormPatchRepository) SavePatchReport(status *model.AgentPatchStatus, installed []model.InstalledPatch, pending []model.PendingUpdate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.AgentPatchStatus
+		err := tx.Where("agent_id = ?", status.AgentID).First(&existing).Error
+		switch {
+		case err == nil:
+			status.ID, status.CreatedAt = existing.ID, existing.CreatedAt
+			err = tx.Save(status).Error
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			err = tx.Create(status).Error
+		}
+		if err != nil {
+			return err
+		}
+		if err := createInventory(tx, installed); err != nil {
+			return err
+		}
+		return createInventory(tx, pending)
+	})
+}
+
+func (r *gormPatchRepository) FindPatchStatus(agentPK uint) (*model.AgentPatchStatus, error) {
+	var status model.AgentPatchStatus
+	if err := r.db.Where("agent_id = ?", agentPK).First(&status).Error; err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (r *gormPatchRepository) FindPatches(agentPK uint, reportedAt time.Time) ([]model.InstalledPatch, []model.PendingUpdate, error) {
+	var installed []model.InstalledPatch
+	if err := r.db.Where("agent_id = ? AND reported_at = ?", agentPK, reportedAt).Order("id").Find(&installed).Error; err != nil {
+		return nil, nil, err
+	}
+	var pending []model.PendingUpdate
+	if err := r.db.Where("agent_id = ? AND reported_at = ?", agentPK, reportedAt).Order("id").Find(&pending).Error; err != nil {
+		return nil, nil, err
+	}
+	return installed, pending, nil
+}
+
+func (r *gormPatchRepository) FindPatchStatuses(filter model.PatchStatusFilter) ([]model.AgentPatchStatusRow, error) {
+	query := r.db.Table("agent_patch_statuses").
+		Select("agents.agent_id AS agent_key, agents.hostname, agents.os_name, agents.labels, agent_patch_statuses.*").
+		Joins("JOIN agents ON agents.id = agent_patch_statuses.agent_id").
+		Where("agent_patch_statuses.deleted_at IS NULL AND agents.deleted_at IS NULL").
+		Order("agents.agent_id")
+	if filter.AgentID != "" {
+		query = query.Where("agents.agent_id = ?", filter.AgentID)
+	}
+	if filter.OSName != "" {
+		query = query.Where("agents.os_name = ?", filter.OSName)
+	}
+	if filter.MissingPatch != "" {
+		query = query.Where(`NOT EXISTS (SELECT 1 FROM installed_patches p WHERE p.agent_id = agent_patch_statuses.agent_id
+			AND p.reported_at = agent_patch_statuses.reported_at AND p.patch_id = ? AND p.deleted_at IS NULL)`, filter.MissingPatch)
+	}
+	if !filter.RebootPendingBefore.IsZero() {
+		query = query.Where("agent_patch_statuses.reboot_pending = ? AND agent_patch_statuses.reboot_pending_since < ?", true, filter.RebootPendingBefore)
+	}
+	if filter.PendingOnly {
+		query = query.Where("agent_patch_statuses.pending_count > 0")
+	}
+	// Labels are matched in Go, so the limit only goes to SQL without them.
+	if filter.Limit > 0 && len(filter.Labels) == 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var rows []model.AgentPatchStatusRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return limitPatchStatuses(rows, filter), nil
+}
+
+func (r *gormPatchRepository) DeletePatchesBefore(before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"installed_patches", "pending_updates"} {
+			current := "EXISTS (SELECT 1 FROM agent_patch_statuses s WHERE s.agent_id = " + table + ".agent_id AND s.reported_at = " + table + ".reported_at)"
+			result := tx.Exec("DELETE FROM "+table+" WHERE created_at < ? AND NOT "+current, before)
+			if result.Error != nil {
+				return result.Error
+			}
+			deleted += result.RowsAffected
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// limitPatchStatuses keeps the rows whose labels match filter, up to its limit.
+func limitPatchStatuses(rows []model.AgentPatchStatusRow, filter model.PatchStatusFilter) []model.AgentPatchStatusRow {
+	kept := rows[:0]
+	for _, row := range rows {
+		if filter.Limit > 0 && len(kept) == filter.Limit {
+			break
+		}
+		if row.Labels.Match(filter.Labels) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
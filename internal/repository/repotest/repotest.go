@@ -0,0 +1,1920 @@
+// Package repotest is a conformance suite that every storage backend must
+// pass. Backends call Run from their own tests with a factory that returns an
+// empty store.
+package repotest
+
+import (
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Factory returns a new, empty store for a single subtest.
+type Factory func(t *testing.T) *repository.Store
+
+// Run executes every conformance check against the backend built by newStore.
+func Run(t *testing.T, newStore Factory) {
+	agentTests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.AgentRepository)
+	}{
+		{"CreateAndFind", testCreateAndFind},
+		{"FindUnknownAgent", testFindUnknownAgent},
+		{"DuplicateAgentID", testDuplicateAgentID},
+		{"UpdateAgent", testUpdateAgent},
+		{"UpdateHeartbeat", testUpdateHeartbeat},
+		{"BulkUpdateHeartbeats", testBulkUpdateHeartbeats},
+		{"ListAgentIDs", testListAgentIDs},
+		{"FindAgentsByStatus", testFindAgentsByStatus},
+		{"ReportRetention", testReportRetention},
+		{"ConcurrentHeartbeats", testConcurrentHeartbeats},
+		{"ListAgentsPaging", testListAgentsPaging},
+		{"CurrentReports", testCurrentReports},
+		{"DecommissionedIgnoresHeartbeats", testDecommissionedIgnoresHeartbeats},
+		{"AgentLabels", testAgentLabels},
+		{"ExportStreams", testExportStreams},
+		{"StatusHistory", testStatusHistory},
+		{"HeartbeatRecordsRecovery", testHeartbeatRecordsRecovery},
+		{"IPHistory", testIPHistory},
+		{"SuspectedClones", testSuspectedClones},
+	}
+	for _, tc := range agentTests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore(t).Agents)
+		})
+	}
+
+	storeTests := []struct {
+		name string
+		fn   func(t *testing.T, store *repository.Store)
+	}{
+		{"CommandLifecycle", testCommandLifecycle},
+		{"FindCommandsFilter", testFindCommandsFilter},
+		{"CommandOutput", testCommandOutput},
+		{"WebhookOutbox", testWebhookOutbox},
+		{"DeleteWebhookSubscription", testDeleteWebhookSubscription},
+		{"VulnerabilityFeed", testVulnerabilityFeed},
+		{"VulnerabilityFindings", testVulnerabilityFindings},
+		{"FirewallFindings", testFirewallFindings},
+		{"CompliancePolicies", testCompliancePolicies},
+		{"ComplianceResults", testComplianceResults},
+		{"AgentMetrics", testAgentMetrics},
+		{"Alerts", testAlerts},
+		{"HostInventory", testHostInventory},
+		{"PatchStatus", testPatchStatus},
+		{"AgentUpdates", testAgentUpdates},
+		{"FileCollections", testFileCollections},
+		{"ScheduledJobs", testScheduledJobs},
+	}
+	for _, tc := range storeTests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore(t))
+		})
+	}
+}
+
+func newAgent(id string) *model.Agent {
+	return &model.Agent{
+		AgentID:       id,
+		Hostname:      "host-" + id,
+		OSName:        "Windows",
+		OSVersion:     "11 Pro",
+		KernelVersion: "10.0.22621",
+		CPUCores:      8,
+		MemoryGB:      32,
+		DiskSpaceGB:   1024,
+		Status:        "ONLINE",
+		LastSeen:      time.Now(),
+	}
+}
+
+func mustCreate(t *testing.T, repo repository.AgentRepository, agent *model.Agent) {
+	t.Helper()
+	if err := repo.CreateAgent(agent); err != nil {
+		t.Fatalf("CreateAgent(%s): %v", agent.AgentID, err)
+	}
+}
+
+func testCreateAndFind(t *testing.T, repo repository.AgentRepository) {
+	agent := newAgent("agent-1")
+	mustCreate(t, repo, agent)
+	if agent.ID == 0 {
+		t.Fatal("CreateAgent did not assign a primary key")
+	}
+
+	found, err := repo.FindAgentByID("agent-1")
+	if err != nil {
+		t.Fatalf("FindAgentByID: %v", err)
+	}
+	if found.ID != agent.ID || found.Hostname != agent.Hostname || found.CPUCores != agent.CPUCores || found.MemoryGB != agent.MemoryGB {
+		t.Fatalf("FindAgentByID returned %+v, want %+v", found, agent)
+	}
+}
+
+func testFindUnknownAgent(t *testing.T, repo repository.AgentRepository) {
+	_, err := repo.FindAgentByID("missing")
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindAgentByID(missing) error = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+
+func testDuplicateAgentID(t *testing.T, repo repository.AgentRepository) {
+	mustCreate(t, repo, newAgent("dup"))
+	if err := repo.CreateAgent(newAgent("dup")); err == nil {
+		t.Fatal("CreateAgent with a duplicate agent_id succeeded")
+	}
+}
+
+func testUpdateAgent(t *testing.T, repo repository.AgentRepository) {
+	agent := newAgent("agent-1")
+	mustCreate(t, repo, agent)
+
+	agent.Hostname = "renamed"
+	agent.Status = "OFFLINE"
+	if err := repo.UpdateAgent(agent); err != nil {
+		t.Fatalf("UpdateAgent: %v", err)
+	}
+
+	found, err := repo.FindAgentByID("agent-1")
+	if err != nil {
+		t.Fatalf("FindAgentByID: %v", err)
+	}
+	if found.Hostname != "renamed" || found.Status != "OFFLINE" {
+		t.Fatalf("update not persisted: %+v", found)
+	}
+}
+
+func testAgentLabels(t *testing.T, repo repository.AgentRepository) {
+	agent := newAgent("agent-1")
+	agent.Labels = model.Labels{"env": "prod", "site": "riyadh"}
+	mustCreate(t, repo, agent)
+	mustCreate(t, repo, newAgent("agent-2")) // no labels
+
+	found, err := repo.FindAgentByID("agent-1")
+	if err != nil {
+		t.Fatalf("FindAgentByID: %v", err)
+	}
+	if len(found.Labels) != 2 || found.Labels["env"] != "prod" || found.Labels["site"] != "riyadh" {
+		t.Fatalf("labels = %v, want env=prod site=riyadh", found.Labels)
+	}
+
+	found.Labels = model.Labels{"env": "staging"}
+	if err := repo.UpdateAgent(found); err != nil {
+		t.Fatalf("UpdateAgent: %v", err)
+	}
+	if found, _ = repo.FindAgentByID("agent-1"); len(found.Labels) != 1 || found.Labels["env"] != "staging" {
+		t.Fatalf("labels after update = %v, want env=staging", found.Labels)
+	}
+	if other, _ := repo.FindAgentByID("agent-2"); len(other.Labels) != 0 {
+		t.Fatalf("agent without labels has %v", other.Labels)
+	}
+}
+
+func testUpdateHeartbeat(t *testing.T, repo repository.AgentRepository) {
+	agent := newAgent("agent-1")
+	agent.Status = "OFFLINE"
+	agent.LastSeen = time.Now().Add(-time.Hour)
+	mustCreate(t, repo, agent)
+
+	rows, err := repo.UpdateHeartbeat("agent-1", "10.0.0.7", "203.0.113.7")
+	if err != nil || rows != 1 {
+		t.Fatalf("UpdateHeartbeat(known) = %d, %v; want 1, nil", rows, err)
+	}
+	found, err := repo.FindAgentByID("agent-1")
+	if err != nil {
+		t.Fatalf("FindAgentByID: %v", err)
+	}
+	if found.Status != "ONLINE" || found.LastKnownIP != "10.0.0.7" || found.LastPeerIP != "203.0.113.7" || time.Since(found.LastSeen) > time.Minute {
+		t.Fatalf("heartbeat not applied: %+v", found)
+	}
+
+	rows, err = repo.UpdateHeartbeat("missing", "10.0.0.8", "")
+	if err != nil || rows != 0 {
+		t.Fatalf("UpdateHeartbeat(unknown) = %d, %v; want 0, nil", rows, err)
+	}
+}
+
+func testBulkUpdateHeartbeats(t *testing.T, repo repository.AgentRepository) {
+	for i := 0; i < 3; i++ {
+		agent := newAgent(fmt.Sprintf("agent-%d", i))
+		agent.Status = "OFFLINE"
+		agent.LastSeen = time.Now().Add(-time.Hour)
+		mustCreate(t, repo, agent)
+	}
+
+	seen := time.Now().Add(-time.Second).Truncate(time.Millisecond)
+	beats := []model.Heartbeat{
+		{AgentID: "agent-0", IP: "10.0.0.1", SeenAt: seen},
+		{AgentID: "agent-2", IP: "10.0.0.3", SeenAt: seen},
+		{AgentID: "missing", IP: "10.0.0.9", SeenAt: seen},
+	}
+	if err := repo.BulkUpdateHeartbeats(beats); err != nil {
+		t.Fatalf("BulkUpdateHeartbeats: %v", err)
+	}
+
+	for _, tc := range []struct {
+		id, status, ip string
+	}{
+		{"agent-0", "ONLINE", "10.0.0.1"},
+		{"agent-1", "OFFLINE", ""},
+		{"agent-2", "ONLINE", "10.0.0.3"},
+	} {
+		found, err := repo.FindAgentByID(tc.id)
+		if err != nil {
+			t.Fatalf("FindAgentByID(%s): %v", tc.id, err)
+		}
+		if found.Status != tc.status || found.LastKnownIP != tc.ip {
+			t.Fatalf("%s = %s/%s, want %s/%s", tc.id, found.Status, found.LastKnownIP, tc.status, tc.ip)
+		}
+		if tc.status == "ONLINE" && !found.LastSeen.Equal(seen) {
+			t.Fatalf("%s last_seen = %v, want %v", tc.id, found.LastSeen, seen)
+		}
+	}
+}
+
+func testListAgentIDs(t *testing.T, repo repository.AgentRepository) {
+	want := map[string]bool{"a": true, "b": true, "c": true}
+	for id := range want {
+		mustCreate(t, repo, newAgent(id))
+	}
+
+	ids, err := repo.ListAgentIDs()
+	if err != nil {
+		t.Fatalf("ListAgentIDs: %v", err)
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("ListAgentIDs returned %v, want %d ids", ids, len(want))
+	}
+	for _, id := range ids {
+		if !want[id] {
+			t.Fatalf("ListAgentIDs returned unexpected id %q", id)
+		}
+	}
+}
+
+func testFindAgentsByStatus(t *testing.T, repo repository.AgentRepository) {
+	for i, status := range []string{"ONLINE", "ONLINE", "OFFLINE"} {
+		agent := newAgent(fmt.Sprintf("agent-%d", i))
+		agent.Status = status
+		mustCreate(t, repo, agent)
+	}
+
+	online, err := repo.FindAgentsByStatus("ONLINE")
+	if err != nil {
+		t.Fatalf("FindAgentsByStatus: %v", err)
+	}
+	if len(online) != 2 {
+		t.Fatalf("FindAgentsByStatus(ONLINE) returned %d agents, want 2", len(online))
+	}
+	for _, a := range online {
+		if a.Status != "ONLINE" {
+			t.Fatalf("FindAgentsByStatus(ONLINE) returned %s with status %s", a.AgentID, a.Status)
+		}
+	}
+}
+
+func testReportRetention(t *testing.T, repo repository.AgentRepository) {
+	busy := newAgent("agent-1")
+	mustCreate(t, repo, busy)
+	quiet := newAgent("agent-2")
+	mustCreate(t, repo, quiet)
+
+	// agent-1 reported twice; agent-2 reported once and then went quiet.
+	older := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	newer := older.Add(24 * time.Hour)
+	rules := []model.FirewallRule{
+		{AgentID: busy.ID, Name: "Allow HTTP", Port: "80", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", Enabled: true, ReportedAt: older},
+		{AgentID: busy.ID, Name: "Deny all outgoing", Port: "ANY", Protocol: "ANY", Action: "DENY", Direction: "DIRECTION_OUT", Enabled: true, ReportedAt: older},
+		{AgentID: busy.ID, Name: "Allow HTTPS", Port: "443", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", Enabled: true, ReportedAt: newer},
+		{AgentID: quiet.ID, Name: "Allow SSH", Port: "22", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", Enabled: true, ReportedAt: older},
+	}
+	if err := repo.CreateFirewallRules(rules); err != nil {
+		t.Fatalf("CreateFirewallRules: %v", err)
+	}
+	apps := []model.InstalledApplication{
+		{AgentID: busy.ID, Name: "Google Chrome", Version: "124.0.6367.91", Publisher: "Google LLC", InstallDate: older, ReportedAt: older},
+		{AgentID: busy.ID, Name: "Google Chrome", Version: "125.0.6422.113", Publisher: "Google LLC", InstallDate: newer, ReportedAt: newer},
+		{AgentID: quiet.ID, Name: "7-Zip", Version: "23.01", Publisher: "Igor Pavlov", InstallDate: older, ReportedAt: older},
+	}
+	if err := repo.CreateInstalledApps(apps); err != nil {
+		t.Fatalf("CreateInstalledApps: %v", err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if n, err := repo.DeleteFirewallRulesBefore(past); err != nil || n != 0 {
+		t.Fatalf("DeleteFirewallRulesBefore(past) = %d, %v; want 0, nil", n, err)
+	}
+	// Every row is older than the cutoff, but only agent-1's first report
+	// has been replaced.
+	future := time.Now().Add(time.Hour)
+	if n, err := repo.DeleteFirewallRulesBefore(future); err != nil || n != 2 {
+		t.Fatalf("DeleteFirewallRulesBefore(future) = %d, %v; want 2, nil", n, err)
+	}
+	if n, err := repo.DeleteInstalledAppsBefore(future); err != nil || n != 1 {
+		t.Fatalf("DeleteInstalledAppsBefore(future) = %d, %v; want 1, nil", n, err)
+	}
+
+	if got, err := repo.FindCurrentFirewallRules(busy.ID); err != nil || len(got) != 1 || got[0].Port != "443" {
+		t.Fatalf("FindCurrentFirewallRules(agent-1) = %+v, %v; want its latest report", got, err)
+	}
+	if got, err := repo.FindCurrentFirewallRules(quiet.ID); err != nil || len(got) != 1 || got[0].Port != "22" {
+		t.Fatalf("FindCurrentFirewallRules(agent-2) = %+v, %v; want the quiet agent's last report", got, err)
+	}
+	if got, err := repo.FindCurrentInstalledApps(quiet.ID); err != nil || len(got) != 1 || got[0].Name != "7-Zip" {
+		t.Fatalf("FindCurrentInstalledApps(agent-2) = %+v, %v; want the quiet agent's last report", got, err)
+	}
+	if n, err := repo.DeleteInstalledAppsBefore(future); err != nil || n != 0 {
+		t.Fatalf("DeleteInstalledAppsBefore(future) again = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func testConcurrentHeartbeats(t *testing.T, repo repository.AgentRepository) {
+	const agents = 10
+	for i := 0; i < agents; i++ {
+		mustCreate(t, repo, newAgent(fmt.Sprintf("agent-%d", i)))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, agents*5)
+	for i := 0; i < agents; i++ {
+		for j := 0; j < 5; j++ {
+			wg.Add(1)
+			go func(id string, ip string) {
+				defer wg.Done()
+				if _, err := repo.UpdateHeartbeat(id, ip, ""); err != nil {
+					errs <- err
+				}
+			}(fmt.Sprintf("agent-%d", i), fmt.Sprintf("10.0.0.%d", j))
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent UpdateHeartbeat: %v", err)
+	}
+
+	online, err := repo.FindAgentsByStatus("ONLINE")
+	if err != nil || len(online) != agents {
+		t.Fatalf("FindAgentsByStatus after heartbeats = %d, %v; want %d", len(online), err, agents)
+	}
+}
+
+func testListAgentsPaging(t *testing.T, repo repository.AgentRepository) {
+	for i := 0; i < 5; i++ {
+		agent := newAgent(fmt.Sprintf("agent-%d", i))
+		if i%2 == 1 {
+			agent.Status = "OFFLINE"
+		}
+		mustCreate(t, repo, agent)
+	}
+
+	var seen []string
+	filter := model.AgentFilter{Limit: 2}
+	for {
+		page, err := repo.ListAgents(filter)
+		if err != nil {
+			t.Fatalf("ListAgents: %v", err)
+		}
+		for _, a := range page {
+			seen = append(seen, a.AgentID)
+		}
+		if len(page) < filter.Limit {
+			break
+		}
+		filter.AfterID = page[len(page)-1].ID
+	}
+	want := []string{"agent-0", "agent-1", "agent-2", "agent-3", "agent-4"}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Fatalf("paged through %v, want %v", seen, want)
+	}
+
+	offline, err := repo.ListAgents(model.AgentFilter{Status: "OFFLINE"})
+	if err != nil {
+		t.Fatalf("ListAgents(OFFLINE): %v", err)
+	}
+	if len(offline) != 2 {
+		t.Fatalf("ListAgents(OFFLINE) returned %d agents, want 2", len(offline))
+	}
+}
+
+func testCurrentReports(t *testing.T, repo repository.AgentRepository) {
+	agent := newAgent("agent-1")
+	mustCreate(t, repo, agent)
+
+	older := time.Now().Add(-time.Hour).Truncate(time.Second)
+	newer := older.Add(30 * time.Minute)
+	rules := []model.FirewallRule{
+		{AgentID: agent.ID, Name: "old", Port: "21", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", ReportedAt: older},
+		{AgentID: agent.ID, Name: "new-1", Port: "80", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", ReportedAt: newer},
+		{AgentID: agent.ID, Name: "new-2", Port: "443", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", ReportedAt: newer},
+	}
+	if err := repo.CreateFirewallRules(rules); err != nil {
+		t.Fatalf("CreateFirewallRules: %v", err)
+	}
+	apps := []model.InstalledApplication{
+		{AgentID: agent.ID, Name: "old", ReportedAt: older},
+		{AgentID: agent.ID, Name: "new", ReportedAt: newer},
+	}
+	if err := repo.CreateInstalledApps(apps); err != nil {
+		t.Fatalf("CreateInstalledApps: %v", err)
+	}
+
+	gotRules, err := repo.FindCurrentFirewallRules(agent.ID)
+	if err != nil {
+		t.Fatalf("FindCurrentFirewallRules: %v", err)
+	}
+	if len(gotRules) != 2 || gotRules[0].Name != "new-1" || gotRules[1].Name != "new-2" {
+		t.Fatalf("FindCurrentFirewallRules returned %+v, want new-1 and new-2", gotRules)
+	}
+	gotApps, err := repo.FindCurrentInstalledApps(agent.ID)
+	if err != nil {
+		t.Fatalf("FindCurrentInstalledApps: %v", err)
+	}
+	if len(gotApps) != 1 || gotApps[0].Name != "new" {
+		t.Fatalf("FindCurrentInstalledApps returned %+v, want only new", gotApps)
+	}
+
+	other := newAgent("agent-2")
+	mustCreate(t, repo, other)
+	if none, err := repo.FindCurrentFirewallRules(other.ID); err != nil || len(none) != 0 {
+		t.Fatalf("FindCurrentFirewallRules(no reports) = %v, %v; want empty", none, err)
+	}
+
+	if err := repo.CreateInstalledApps([]model.InstalledApplication{
+		{AgentID: other.ID, Name: "b", ReportedAt: older},
+		{AgentID: other.ID, Name: "a", ReportedAt: older},
+	}); err != nil {
+		t.Fatalf("CreateInstalledApps: %v", err)
+	}
+	all, err := repo.FindAllCurrentInstalledApps()
+	if err != nil {
+		t.Fatalf("FindAllCurrentInstalledApps: %v", err)
+	}
+	var names []string
+	for _, app := range all {
+		names = append(names, app.Name)
+	}
+	if len(names) != 3 || names[0] != "new" || names[1] != "b" || names[2] != "a" {
+		t.Fatalf("FindAllCurrentInstalledApps returned %v, want [new b a]", names)
+	}
+}
+
+func testExportStreams(t *testing.T, repo repository.AgentRepository) {
+	prod := newAgent("agent-1")
+	prod.Labels = model.Labels{"env": "prod"}
+	mustCreate(t, repo, prod)
+	staging := newAgent("agent-2")
+	staging.Labels = model.Labels{"env": "staging"}
+	mustCreate(t, repo, staging)
+	offline := newAgent("agent-3")
+	offline.Status = "OFFLINE"
+	offline.Labels = model.Labels{"env": "prod"}
+	mustCreate(t, repo, offline)
+
+	older := time.Now().Add(-time.Hour).Truncate(time.Second)
+	newer := older.Add(30 * time.Minute)
+	if err := repo.CreateFirewallRules([]model.FirewallRule{
+		{AgentID: prod.ID, Name: "old", Port: "21", ReportedAt: older},
+		{AgentID: prod.ID, Name: "web", Port: "443", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", Enabled: true, ReportedAt: newer},
+		{AgentID: staging.ID, Name: "ssh", Port: "22", ReportedAt: older},
+	}); err != nil {
+		t.Fatalf("CreateFirewallRules: %v", err)
+	}
+	if err := repo.CreateInstalledApps([]model.InstalledApplication{
+		{AgentID: prod.ID, Name: "old", ReportedAt: older},
+		{AgentID: prod.ID, Name: "7-Zip", Version: "23.01", Publisher: "Igor Pavlov", ReportedAt: newer},
+		{AgentID: offline.ID, Name: "Git", Version: "2.44.0", ReportedAt: older},
+	}); err != nil {
+		t.Fatalf("CreateInstalledApps: %v", err)
+	}
+
+	var agents []string
+	err := repo.StreamAgents(model.ExportFilter{Labels: map[string]string{"env": "prod"}}, func(a *model.Agent) error {
+		agents = append(agents, a.AgentID+"/"+a.Labels["env"])
+		return nil
+	})
+	if err != nil || len(agents) != 2 || agents[0] != "agent-1/prod" || agents[1] != "agent-3/prod" {
+		t.Fatalf("StreamAgents(env=prod) = %v, %v; want agent-1 and agent-3", agents, err)
+	}
+	agents = nil
+	if err := repo.StreamAgents(model.ExportFilter{Status: "OFFLINE"}, func(a *model.Agent) error {
+		agents = append(agents, a.AgentID)
+		return nil
+	}); err != nil || len(agents) != 1 || agents[0] != "agent-3" {
+		t.Fatalf("StreamAgents(OFFLINE) = %v, %v; want agent-3", agents, err)
+	}
+
+	var rules []model.AgentFirewallRule
+	if err := repo.StreamCurrentFirewallRules(model.ExportFilter{}, func(r *model.AgentFirewallRule) error {
+		rules = append(rules, *r)
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamCurrentFirewallRules: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Name != "web" || rules[1].Name != "ssh" {
+		t.Fatalf("StreamCurrentFirewallRules = %+v, want web then ssh", rules)
+	}
+	if r := rules[0]; r.AgentID != "agent-1" || r.Hostname != "host-agent-1" || r.Labels["env"] != "prod" ||
+		r.Port != "443" || r.Protocol != "TCP" || !r.Enabled || !r.ReportedAt.Equal(newer) {
+		t.Fatalf("rule = %+v, want the agent's details and the rule's fields", r)
+	}
+
+	var apps []model.AgentInstalledApp
+	if err := repo.StreamCurrentInstalledApps(model.ExportFilter{AgentID: "agent-1"}, func(a *model.AgentInstalledApp) error {
+		apps = append(apps, *a)
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamCurrentInstalledApps: %v", err)
+	}
+	if len(apps) != 1 || apps[0].Name != "7-Zip" || apps[0].Version != "23.01" || apps[0].Publisher != "Igor Pavlov" || apps[0].AgentID != "agent-1" {
+		t.Fatalf("StreamCurrentInstalledApps(agent-1) = %+v, want only 7-Zip", apps)
+	}
+
+	// An error from fn stops the stream and is returned.
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.StreamAgents(model.ExportFilter{}, func(*model.Agent) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("StreamAgents with a failing callback = %v after %d calls, want stop after 1", err, calls)
+	}
+}
+
+func testStatusHistory(t *testing.T, repo repository.AgentRepository) {
+	agent := newAgent("agent-1")
+	agent.Labels = model.Labels{"env": "prod"}
+	mustCreate(t, repo, agent)
+	mustCreate(t, repo, newAgent("agent-2"))
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, c := range []model.AgentStatusChange{
+		{AgentID: "agent-1", FromStatus: "", ToStatus: "ONLINE", ChangedAt: start},
+		{AgentID: "agent-2", FromStatus: "", ToStatus: "ONLINE", ChangedAt: start.Add(time.Minute)},
+		{AgentID: "agent-1", FromStatus: "ONLINE", ToStatus: "OFFLINE", ChangedAt: start.Add(10 * time.Minute)},
+		{AgentID: "agent-1", FromStatus: "OFFLINE", ToStatus: "ONLINE", ChangedAt: start.Add(20 * time.Minute)},
+	} {
+		if err := repo.RecordStatusChange(&c); err != nil {
+			t.Fatalf("RecordStatusChange: %v", err)
+		}
+		if c.ID == 0 {
+			t.Fatal("RecordStatusChange did not assign an ID")
+		}
+	}
+
+	collect := func(filter model.ExportFilter) []string {
+		t.Helper()
+		var got []string
+		if err := repo.StreamStatusHistory(filter, func(c *model.AgentStatusChangeRow) error {
+			got = append(got, fmt.Sprintf("%s:%s>%s", c.AgentID, c.FromStatus, c.ToStatus))
+			if c.Hostname != "host-"+c.AgentID {
+				t.Errorf("hostname = %q for %s", c.Hostname, c.AgentID)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("StreamStatusHistory: %v", err)
+		}
+		return got
+	}
+
+	if got := collect(model.ExportFilter{}); fmt.Sprint(got) != "[agent-1:>ONLINE agent-2:>ONLINE agent-1:ONLINE>OFFLINE agent-1:OFFLINE>ONLINE]" {
+		t.Fatalf("history = %v, want all four changes, oldest first", got)
+	}
+	window := model.ExportFilter{Labels: map[string]string{"env": "prod"}, Since: start.Add(time.Minute), Until: start.Add(20 * time.Minute)}
+	if got := collect(window); fmt.Sprint(got) != "[agent-1:ONLINE>OFFLINE]" {
+		t.Fatalf("history of env=prod in [start+1m, start+20m) = %v, want the one OFFLINE change", got)
+	}
+
+	last, err := repo.FindLastStatusChanges(model.ExportFilter{}, start.Add(15*time.Minute))
+	if err != nil {
+		t.Fatalf("FindLastStatusChanges: %v", err)
+	}
+	if len(last) != 2 || last[0].AgentID != "agent-1" || last[0].ToStatus != "OFFLINE" || last[0].Labels["env"] != "prod" ||
+		last[1].AgentID != "agent-2" || last[1].ToStatus != "ONLINE" {
+		t.Fatalf("FindLastStatusChanges(start+15m) = %+v, want agent-1 OFFLINE and agent-2 ONLINE", last)
+	}
+	if none, err := repo.FindLastStatusChanges(model.ExportFilter{AgentID: "agent-2"}, start); err != nil || len(none) != 0 {
+		t.Fatalf("FindLastStatusChanges before any change = %+v, %v; want none", none, err)
+	}
+}
+
+func testHeartbeatRecordsRecovery(t *testing.T, repo repository.AgentRepository) {
+	for _, id := range []string{"online", "offline-1", "offline-2", "retired"} {
+		agent := newAgent(id)
+		switch id {
+		case "offline-1", "offline-2":
+			agent.Status = "OFFLINE"
+		case "retired":
+			agent.Status = "DECOMMISSIONED"
+		}
+		mustCreate(t, repo, agent)
+	}
+
+	if _, err := repo.UpdateHeartbeat("offline-1", "10.0.0.1", ""); err != nil {
+		t.Fatalf("UpdateHeartbeat: %v", err)
+	}
+	if _, err := repo.UpdateHeartbeat("online", "10.0.0.2", ""); err != nil {
+		t.Fatalf("UpdateHeartbeat: %v", err)
+	}
+	seen := time.Now().Add(-time.Minute).Truncate(time.Second)
+	if err := repo.BulkUpdateHeartbeats([]model.Heartbeat{
+		{AgentID: "offline-2", IP: "10.0.0.3", SeenAt: seen},
+		{AgentID: "retired", IP: "10.0.0.4", SeenAt: seen},
+	}); err != nil {
+		t.Fatalf("BulkUpdateHeartbeats: %v", err)
+	}
+
+	var got []string
+	if err := repo.StreamStatusHistory(model.ExportFilter{}, func(c *model.AgentStatusChangeRow) error {
+		got = append(got, fmt.Sprintf("%s:%s>%s", c.AgentID, c.FromStatus, c.ToStatus))
+		if c.AgentID == "offline-2" && !c.ChangedAt.Equal(seen) {
+			t.Errorf("offline-2 recovered at %v, want the heartbeat time %v", c.ChangedAt, seen)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamStatusHistory: %v", err)
+	}
+	if fmt.Sprint(got) != "[offline-2:OFFLINE>ONLINE offline-1:OFFLINE>ONLINE]" {
+		t.Fatalf("history = %v, want only the two recoveries from OFFLINE", got)
+	}
+}
+
+func testIPHistory(t *testing.T, repo repository.AgentRepository) {
+	for _, id := range []string{"web-1", "web-2", "retired"} {
+		agent := newAgent(id)
+		if id == "retired" {
+			agent.Status = "DECOMMISSIONED"
+		}
+		mustCreate(t, repo, agent)
+	}
+
+	t0 := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	for _, batch := range [][]model.Heartbeat{
+		{
+			{AgentID: "web-1", IP: "10.0.0.1", PeerIP: "10.0.0.1", SeenAt: t0},
+			{AgentID: "web-2", IP: "10.0.0.1", PeerIP: "198.51.100.7", SeenAt: t0},
+			{AgentID: "retired", IP: "10.0.0.1", PeerIP: "10.0.0.1", SeenAt: t0},
+		},
+		{{AgentID: "web-1", IP: "10.0.0.1", PeerIP: "10.0.0.1", SeenAt: t0.Add(time.Hour)}},
+		{{AgentID: "web-1", IP: "10.0.0.2", PeerIP: "10.0.0.2", SeenAt: t0.Add(2 * time.Hour)}},
+	} {
+		if err := repo.BulkUpdateHeartbeats(batch); err != nil {
+			t.Fatalf("BulkUpdateHeartbeats: %v", err)
+		}
+	}
+	// An older sighting does not move last_seen back, and one without any
+	// address is not recorded.
+	if err := repo.RecordAddresses([]model.Heartbeat{
+		{AgentID: "web-1", IP: "10.0.0.1", PeerIP: "10.0.0.1", SeenAt: t0.Add(30 * time.Minute)},
+		{AgentID: "web-2", SeenAt: t0},
+	}); err != nil {
+		t.Fatalf("RecordAddresses: %v", err)
+	}
+
+	find := func(filter model.IPHistoryFilter) string {
+		t.Helper()
+		addresses, err := repo.FindIPHistory(filter)
+		if err != nil {
+			t.Fatalf("FindIPHistory(%+v): %v", filter, err)
+		}
+		var got []string
+		for _, a := range addresses {
+			got = append(got, a.AgentID+"@"+a.IP+"/"+a.PeerIP)
+		}
+		return strings.Join(got, " ")
+	}
+	for _, tc := range []struct {
+		filter model.IPHistoryFilter
+		want   string
+	}{
+		{model.IPHistoryFilter{IP: "10.0.0.1"}, "web-1@10.0.0.1/10.0.0.1 web-2@10.0.0.1/198.51.100.7"},
+		{model.IPHistoryFilter{IP: "198.51.100.7"}, "web-2@10.0.0.1/198.51.100.7"},
+		{model.IPHistoryFilter{IP: "10.0.0.1", Since: t0.Add(30 * time.Minute), Until: t0.Add(90 * time.Minute)}, "web-1@10.0.0.1/10.0.0.1"},
+		{model.IPHistoryFilter{IP: "10.0.0.2", Until: t0.Add(time.Hour)}, ""},
+		{model.IPHistoryFilter{AgentID: "web-1"}, "web-1@10.0.0.2/10.0.0.2 web-1@10.0.0.1/10.0.0.1"},
+		{model.IPHistoryFilter{AgentID: "web-1", Limit: 1}, "web-1@10.0.0.2/10.0.0.2"},
+		{model.IPHistoryFilter{MismatchOnly: true}, "web-2@10.0.0.1/198.51.100.7"},
+	} {
+		if got := find(tc.filter); got != tc.want {
+			t.Errorf("FindIPHistory(%+v) = %q, want %q", tc.filter, got, tc.want)
+		}
+	}
+
+	addresses, err := repo.FindIPHistory(model.IPHistoryFilter{AgentID: "web-1", IP: "10.0.0.1"})
+	if err != nil || len(addresses) != 1 {
+		t.Fatalf("FindIPHistory(web-1, 10.0.0.1) = %+v, %v; want one address", addresses, err)
+	}
+	if a := addresses[0]; !a.FirstSeen.Equal(t0) || !a.LastSeen.Equal(t0.Add(time.Hour)) || a.Mismatch() {
+		t.Fatalf("web-1 at 10.0.0.1 = %+v, want seen from %v to %v without mismatch", a, t0, t0.Add(time.Hour))
+	}
+	if agent, _ := repo.FindAgentByID("web-2"); agent.LastKnownIP != "10.0.0.1" || agent.LastPeerIP != "198.51.100.7" {
+		t.Fatalf("web-2 addresses = %s/%s, want 10.0.0.1/198.51.100.7", agent.LastKnownIP, agent.LastPeerIP)
+	}
+}
+
+func testSuspectedClones(t *testing.T, repo repository.AgentRepository) {
+	for _, id := range []string{"a", "b", "c"} {
+		agent := newAgent(id)
+		agent.InstanceNonce = "nonce-" + id
+		mustCreate(t, repo, agent)
+	}
+
+	at := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, tc := range []struct {
+		id   string
+		at   time.Time
+		want bool
+	}{
+		{"a", at, true},
+		{"b", at.Add(time.Minute), true},
+		{"a", at.Add(2 * time.Minute), false}, // already flagged
+		{"missing", at, false},
+	} {
+		flagged, err := repo.FlagSuspectedClone(tc.id, "reason "+tc.id, tc.at)
+		if err != nil || flagged != tc.want {
+			t.Fatalf("FlagSuspectedClone(%s) = %v, %v; want %v", tc.id, flagged, err, tc.want)
+		}
+	}
+
+	suspected, err := repo.FindSuspectedClones(0)
+	if err != nil || len(suspected) != 2 || suspected[0].AgentID != "b" || suspected[1].AgentID != "a" {
+		t.Fatalf("FindSuspectedClones = %+v, %v; want b then a", suspected, err)
+	}
+	if a := suspected[1]; !a.CloneSuspectedAt.Equal(at) || a.CloneReason != "reason a" || a.InstanceNonce != "nonce-a" {
+		t.Fatalf("a = %+v, want the first flag kept", a)
+	}
+	if limited, _ := repo.FindSuspectedClones(1); len(limited) != 1 {
+		t.Fatalf("FindSuspectedClones(1) returned %d agents", len(limited))
+	}
+
+	if err := repo.ClearSuspectedClone("a"); err != nil {
+		t.Fatalf("ClearSuspectedClone: %v", err)
+	}
+	if a, _ := repo.FindAgentByID("a"); a.CloneSuspectedAt != nil || a.CloneReason != "" {
+		t.Fatalf("a after clear = %+v", a)
+	}
+	if err := repo.ClearSuspectedClone("missing"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("ClearSuspectedClone(missing) = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func testDecommissionedIgnoresHeartbeats(t *testing.T, repo repository.AgentRepository) {
+	agent := newAgent("agent-1")
+	agent.Status = "DECOMMISSIONED"
+	agent.LastSeen = time.Now().Add(-time.Hour).Truncate(time.Second)
+	mustCreate(t, repo, agent)
+
+	if n, err := repo.UpdateHeartbeat("agent-1", "10.0.0.9", ""); err != nil || n != 0 {
+		t.Fatalf("UpdateHeartbeat = %d, %v; want 0, nil", n, err)
+	}
+	if err := repo.BulkUpdateHeartbeats([]model.Heartbeat{{AgentID: "agent-1", IP: "10.0.0.9", SeenAt: time.Now()}}); err != nil {
+		t.Fatalf("BulkUpdateHeartbeats: %v", err)
+	}
+	got, err := repo.FindAgentByID("agent-1")
+	if err != nil {
+		t.Fatalf("FindAgentByID: %v", err)
+	}
+	if got.Status != "DECOMMISSIONED" || got.LastKnownIP == "10.0.0.9" {
+		t.Fatalf("heartbeat revived decommissioned agent: %+v", got)
+	}
+
+	ids, err := repo.ListAgentIDs()
+	if err != nil {
+		t.Fatalf("ListAgentIDs: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("ListAgentIDs returned %v, want decommissioned agents excluded", ids)
+	}
+}
+
+func mustCreateCommand(t *testing.T, store *repository.Store, agentID string) *model.Command {
+	t.Helper()
+	cmd := &model.Command{
+		AgentID: agentID,
+		Type:    model.CommandTypeFirewallConfiguration,
+		Payload: []byte{1, 2, 3},
+		Status:  model.CommandPending,
+	}
+	if err := store.Commands.CreateCommand(cmd); err != nil {
+		t.Fatalf("CreateCommand: %v", err)
+	}
+	if cmd.ID == 0 {
+		t.Fatal("CreateCommand did not assign an ID")
+	}
+	return cmd
+}
+
+func testCommandLifecycle(t *testing.T, store *repository.Store) {
+	mustCreate(t, store.Agents, newAgent("agent-1"))
+	mustCreate(t, store.Agents, newAgent("agent-2"))
+	first := mustCreateCommand(t, store, "agent-1")
+	second := mustCreateCommand(t, store, "agent-1")
+	mustCreateCommand(t, store, "agent-2")
+
+	claimed, err := store.Commands.ClaimPendingCommands("agent-1")
+	if err != nil {
+		t.Fatalf("ClaimPendingCommands: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != first.ID || claimed[1].ID != second.ID {
+		t.Fatalf("ClaimPendingCommands returned %+v, want commands %d and %d in order", claimed, first.ID, second.ID)
+	}
+	if claimed[0].Status != model.CommandSent || claimed[0].SentAt == nil {
+		t.Fatalf("claimed command not marked SENT: %+v", claimed[0])
+	}
+	if again, err := store.Commands.ClaimPendingCommands("agent-1"); err != nil || len(again) != 0 {
+		t.Fatalf("second ClaimPendingCommands = %v, %v; want nothing", again, err)
+	}
+
+	ok, err := store.Commands.TransitionCommand(first.ID, []string{model.CommandSent}, model.CommandSucceeded, "done")
+	if err != nil || !ok {
+		t.Fatalf("TransitionCommand(SENT->SUCCEEDED) = %v, %v; want true, nil", ok, err)
+	}
+	ok, err = store.Commands.TransitionCommand(first.ID, []string{model.CommandPending}, model.CommandCancelled, "")
+	if err != nil || ok {
+		t.Fatalf("TransitionCommand from wrong state = %v, %v; want false, nil", ok, err)
+	}
+
+	got, err := store.Commands.FindCommandByID(first.ID)
+	if err != nil {
+		t.Fatalf("FindCommandByID: %v", err)
+	}
+	if got.Status != model.CommandSucceeded || got.ResultMessage != "done" || got.CompletedAt == nil {
+		t.Fatalf("FindCommandByID returned %+v", got)
+	}
+	if string(got.Payload) != string([]byte{1, 2, 3}) {
+		t.Fatalf("payload = %v, want [1 2 3]", got.Payload)
+	}
+
+	if _, err := store.Commands.FindCommandByID(9999); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindCommandByID(unknown) error = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+
+func testFindCommandsFilter(t *testing.T, store *repository.Store) {
+	mustCreate(t, store.Agents, newAgent("agent-1"))
+	mustCreate(t, store.Agents, newAgent("agent-2"))
+	for i := 0; i < 3; i++ {
+		mustCreateCommand(t, store, "agent-1")
+	}
+	last := mustCreateCommand(t, store, "agent-2")
+	if _, err := store.Commands.TransitionCommand(last.ID, []string{model.CommandPending}, model.CommandCancelled, ""); err != nil {
+		t.Fatalf("TransitionCommand: %v", err)
+	}
+
+	all, err := store.Commands.FindCommands(model.CommandFilter{})
+	if err != nil {
+		t.Fatalf("FindCommands: %v", err)
+	}
+	if len(all) != 4 || all[0].ID != last.ID {
+		t.Fatalf("FindCommands returned %d commands starting at %d, want 4 newest first", len(all), all[0].ID)
+	}
+	if got, _ := store.Commands.FindCommands(model.CommandFilter{AgentID: "agent-1", Limit: 2}); len(got) != 2 {
+		t.Fatalf("FindCommands(agent-1, limit 2) returned %d commands", len(got))
+	}
+	if got, _ := store.Commands.FindCommands(model.CommandFilter{Status: model.CommandCancelled}); len(got) != 1 || got[0].ID != last.ID {
+		t.Fatalf("FindCommands(CANCELLED) returned %+v", got)
+	}
+}
+
+func testCommandOutput(t *testing.T, store *repository.Store) {
+	mustCreate(t, store.Agents, newAgent("agent-1"))
+	cmd := mustCreateCommand(t, store, "agent-1")
+
+	truncated, err := store.Commands.AppendCommandOutput(cmd.ID, []model.CommandOutputChunk{
+		{Stream: model.OutputStdout, Data: []byte("hello ")},
+		{Stream: model.OutputStderr, Data: []byte("warn")},
+	}, 16)
+	if err != nil || truncated {
+		t.Fatalf("AppendCommandOutput = %v, %v; want everything stored", truncated, err)
+	}
+	truncated, err = store.Commands.AppendCommandOutput(cmd.ID, []model.CommandOutputChunk{
+		{Stream: model.OutputStdout, Data: []byte("world!!!")},
+		{Stream: model.OutputStdout, Data: []byte("dropped")},
+	}, 16)
+	if err != nil || !truncated {
+		t.Fatalf("AppendCommandOutput over the cap = %v, %v; want truncated", truncated, err)
+	}
+	if err := store.Commands.SetCommandExitCode(cmd.ID, 3); err != nil {
+		t.Fatalf("SetCommandExitCode: %v", err)
+	}
+
+	chunks, err := store.Commands.FindCommandOutput(cmd.ID)
+	if err != nil || len(chunks) != 3 || string(chunks[0].Data) != "hello " || string(chunks[2].Data) != "world!" || chunks[1].Stream != model.OutputStderr {
+		t.Fatalf("FindCommandOutput = %+v, %v; want the output cut at 16 bytes", chunks, err)
+	}
+	got, err := store.Commands.FindCommandByID(cmd.ID)
+	if err != nil || got.OutputBytes != 16 || !got.OutputTruncated || got.ExitCode == nil || *got.ExitCode != 3 {
+		t.Fatalf("FindCommandByID = %+v, %v; want 16 bytes, truncated, exit code 3", got, err)
+	}
+}
+
+func mustCreateSubscription(t *testing.T, store *repository.Store) *model.WebhookSubscription {
+	t.Helper()
+	sub := &model.WebhookSubscription{URL: "http://example.test/hook", Secret: "s3cret", Enabled: true}
+	if err := store.Webhooks.CreateSubscription(sub); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	return sub
+}
+
+func newDelivery(subID uint, eventID string, due time.Time) model.WebhookDelivery {
+	return model.WebhookDelivery{
+		SubscriptionID: subID,
+		EventID:        eventID,
+		EventType:      model.EventAgentOffline,
+		AgentID:        "agent-1",
+		Payload:        []byte(`{"id":"` + eventID + `"}`),
+		Status:         model.WebhookPending,
+		NextAttemptAt:  due,
+	}
+}
+
+func testWebhookOutbox(t *testing.T, store *repository.Store) {
+	sub := mustCreateSubscription(t, store)
+	now := time.Now().Truncate(time.Second)
+	err := store.Webhooks.EnqueueDeliveries([]model.WebhookDelivery{
+		newDelivery(sub.ID, "e1", now.Add(-2*time.Second)),
+		newDelivery(sub.ID, "e2", now.Add(-time.Second)),
+		newDelivery(sub.ID, "e3", now.Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("EnqueueDeliveries: %v", err)
+	}
+
+	claimed, err := store.Webhooks.ClaimDueDeliveries(now, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueDeliveries: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].EventID != "e1" || claimed[1].EventID != "e2" {
+		t.Fatalf("ClaimDueDeliveries returned %+v, want e1 and e2", claimed)
+	}
+	if string(claimed[0].Payload) != `{"id":"e1"}` {
+		t.Fatalf("payload = %s", claimed[0].Payload)
+	}
+	// Claimed deliveries are leased and not handed out again.
+	if again, err := store.Webhooks.ClaimDueDeliveries(now, time.Minute, 10); err != nil || len(again) != 0 {
+		t.Fatalf("second ClaimDueDeliveries = %v, %v; want nothing", again, err)
+	}
+
+	delivered := claimed[0]
+	delivered.Status = model.WebhookDelivered
+	delivered.Attempts = 1
+	delivered.LastStatusCode = 204
+	delivered.DeliveredAt = &now
+	if err := store.Webhooks.SaveDeliveryAttempt(&delivered); err != nil {
+		t.Fatalf("SaveDeliveryAttempt: %v", err)
+	}
+	dead := claimed[1]
+	dead.Status = model.WebhookDead
+	dead.Attempts = 3
+	dead.LastError = "connection refused"
+	if err := store.Webhooks.SaveDeliveryAttempt(&dead); err != nil {
+		t.Fatalf("SaveDeliveryAttempt: %v", err)
+	}
+
+	deadList, err := store.Webhooks.FindDeliveries(model.WebhookDeliveryFilter{SubscriptionID: sub.ID, Status: model.WebhookDead})
+	if err != nil {
+		t.Fatalf("FindDeliveries: %v", err)
+	}
+	if len(deadList) != 1 || deadList[0].ID != dead.ID || deadList[0].Attempts != 3 || deadList[0].LastError != "connection refused" {
+		t.Fatalf("FindDeliveries(DEAD) returned %+v", deadList)
+	}
+	all, _ := store.Webhooks.FindDeliveries(model.WebhookDeliveryFilter{})
+	if len(all) != 3 || all[0].EventID != "e3" {
+		t.Fatalf("FindDeliveries returned %d deliveries, want 3 newest first", len(all))
+	}
+
+	if n, err := store.Webhooks.ResetDeliveries([]uint{dead.ID}); err != nil || n != 1 {
+		t.Fatalf("ResetDeliveries = %d, %v; want 1, nil", n, err)
+	}
+	replayed, err := store.Webhooks.ClaimDueDeliveries(time.Now().Add(time.Second), time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueDeliveries after reset: %v", err)
+	}
+	if len(replayed) != 1 || replayed[0].ID != dead.ID || replayed[0].Attempts != 0 {
+		t.Fatalf("ClaimDueDeliveries after reset returned %+v", replayed)
+	}
+}
+
+func testDeleteWebhookSubscription(t *testing.T, store *repository.Store) {
+	sub := mustCreateSubscription(t, store)
+	other := mustCreateSubscription(t, store)
+	err := store.Webhooks.EnqueueDeliveries([]model.WebhookDelivery{
+		newDelivery(sub.ID, "e1", time.Now()),
+		newDelivery(other.ID, "e1", time.Now()),
+	})
+	if err != nil {
+		t.Fatalf("EnqueueDeliveries: %v", err)
+	}
+
+	if err := store.Webhooks.DeleteSubscription(sub.ID); err != nil {
+		t.Fatalf("DeleteSubscription: %v", err)
+	}
+	if _, err := store.Webhooks.FindSubscriptionByID(sub.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindSubscriptionByID(deleted) error = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if err := store.Webhooks.DeleteSubscription(sub.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("second DeleteSubscription error = %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	left, _ := store.Webhooks.FindDeliveries(model.WebhookDeliveryFilter{})
+	if len(left) != 1 || left[0].SubscriptionID != other.ID {
+		t.Fatalf("deliveries after delete = %+v, want only the other subscription's", left)
+	}
+	subs, _ := store.Webhooks.ListSubscriptions()
+	if len(subs) != 1 || subs[0].ID != other.ID {
+		t.Fatalf("ListSubscriptions = %+v", subs)
+	}
+}
+
+func testVulnerabilityFeed(t *testing.T, store *repository.Store) {
+	if latest, err := store.Vulnerabilities.LatestVulnerabilityImport(); err != nil || !latest.IsZero() {
+		t.Fatalf("LatestVulnerabilityImport on empty feed = %v, %v", latest, err)
+	}
+
+	first := time.Now().Add(-time.Hour).UTC().Truncate(time.Millisecond)
+	err := store.Vulnerabilities.ReplaceVulnerabilities([]model.Vulnerability{
+		{VulnerabilityID: "OLD-1", Severity: model.SeverityLow, ImportedAt: first},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceVulnerabilities: %v", err)
+	}
+
+	second := first.Add(time.Hour)
+	err = store.Vulnerabilities.ReplaceVulnerabilities([]model.Vulnerability{{
+		VulnerabilityID: "CVE-2023-4863",
+		Aliases:         "GHSA-j7hp-h8jx-5ppr",
+		Severity:        model.SeverityHigh,
+		CVSSScore:       8.8,
+		Source:          "nvd",
+		Affected: model.AffectedProducts{{
+			Product: "google:chrome",
+			Ranges:  []model.VersionRange{{End: "116.0.5845.187"}},
+		}},
+		ImportedAt: second,
+	}, {
+		VulnerabilityID: "CVE-2024-0001", Severity: model.SeverityUnknown, ImportedAt: second,
+	}})
+	if err != nil {
+		t.Fatalf("ReplaceVulnerabilities: %v", err)
+	}
+
+	vulns, err := store.Vulnerabilities.ListVulnerabilities()
+	if err != nil {
+		t.Fatalf("ListVulnerabilities: %v", err)
+	}
+	if len(vulns) != 2 || vulns[0].VulnerabilityID != "CVE-2023-4863" {
+		t.Fatalf("feed after replace = %+v, want the two new vulnerabilities", vulns)
+	}
+	affected := vulns[0].Affected
+	if len(affected) != 1 || affected[0].Product != "google:chrome" || len(affected[0].Ranges) != 1 || affected[0].Ranges[0].End != "116.0.5845.187" {
+		t.Fatalf("affected products did not round-trip: %+v", affected)
+	}
+	if latest, _ := store.Vulnerabilities.LatestVulnerabilityImport(); !latest.Equal(second) {
+		t.Fatalf("LatestVulnerabilityImport = %v, want %v", latest, second)
+	}
+}
+
+func testVulnerabilityFindings(t *testing.T, store *repository.Store) {
+	for _, id := range []string{"agent-1", "agent-2"} {
+		mustCreate(t, store.Agents, newAgent(id))
+	}
+	finding := func(vulnID, severity string, score float64) model.VulnerabilityFinding {
+		return model.VulnerabilityFinding{VulnerabilityID: vulnID, Severity: severity, CVSSScore: score, AppName: "app", DetectedAt: time.Now()}
+	}
+	replace := func(agentID string, findings ...model.VulnerabilityFinding) {
+		t.Helper()
+		if err := store.Vulnerabilities.ReplaceAgentFindings(agentID, findings); err != nil {
+			t.Fatalf("ReplaceAgentFindings(%s): %v", agentID, err)
+		}
+	}
+	replace("agent-1", finding("CVE-1", model.SeverityLow, 3.1))
+	replace("agent-1", finding("CVE-1", model.SeverityCritical, 9.8), finding("CVE-2", model.SeverityMedium, 5.0))
+	replace("agent-2", finding("CVE-1", model.SeverityCritical, 9.8))
+
+	all, err := store.Vulnerabilities.FindFindings(model.VulnerabilityFindingFilter{})
+	if err != nil {
+		t.Fatalf("FindFindings: %v", err)
+	}
+	if len(all) != 3 || all[0].AgentID != "agent-1" || all[1].AgentID != "agent-2" || all[2].VulnerabilityID != "CVE-2" {
+		t.Fatalf("FindFindings = %+v, want the latest findings ordered by score then agent", all)
+	}
+
+	for _, tc := range []struct {
+		filter model.VulnerabilityFindingFilter
+		want   int
+	}{
+		{model.VulnerabilityFindingFilter{AgentID: "agent-1"}, 2},
+		{model.VulnerabilityFindingFilter{VulnerabilityIDs: []string{"CVE-1"}}, 2},
+		{model.VulnerabilityFindingFilter{Severities: []string{model.SeverityMedium, model.SeverityHigh}}, 1},
+		{model.VulnerabilityFindingFilter{Limit: 1}, 1},
+	} {
+		got, err := store.Vulnerabilities.FindFindings(tc.filter)
+		if err != nil || len(got) != tc.want {
+			t.Errorf("FindFindings(%+v) = %d findings, %v; want %d", tc.filter, len(got), err, tc.want)
+		}
+	}
+
+	counts, err := store.Vulnerabilities.CountAffectedAgents()
+	if err != nil || counts["CVE-1"] != 2 || counts["CVE-2"] != 1 {
+		t.Fatalf("CountAffectedAgents = %v, %v", counts, err)
+	}
+
+	replace("agent-1")
+	if left, _ := store.Vulnerabilities.FindFindings(model.VulnerabilityFindingFilter{AgentID: "agent-1"}); len(left) != 0 {
+		t.Fatalf("findings after clearing agent-1 = %+v", left)
+	}
+}
+
+func testFirewallFindings(t *testing.T, store *repository.Store) {
+	for _, id := range []string{"agent-1", "agent-2"} {
+		mustCreate(t, store.Agents, newAgent(id))
+	}
+	finding := func(kind, severity, rule string) model.FirewallFinding {
+		return model.FirewallFinding{Kind: kind, Severity: severity, RuleName: rule, DetectedAt: time.Now()}
+	}
+	replace := func(agentID string, findings ...model.FirewallFinding) {
+		t.Helper()
+		if err := store.Firewall.ReplaceAgentFindings(agentID, findings); err != nil {
+			t.Fatalf("ReplaceAgentFindings(%s): %v", agentID, err)
+		}
+	}
+	replace("agent-1", finding(model.FirewallDisabled, model.SeverityHigh, ""))
+	replace("agent-1",
+		finding(model.FirewallShadowedRule, model.SeverityMedium, "web"),
+		finding(model.FirewallConflictingRules, model.SeverityMedium, "dns"),
+		finding(model.FirewallRiskyExposure, model.SeverityHigh, "ssh"),
+	)
+	replace("agent-2", finding(model.FirewallRiskyExposure, model.SeverityCritical, "smb"))
+
+	all, err := store.Firewall.FindFindings(model.FirewallFindingFilter{})
+	if err != nil {
+		t.Fatalf("FindFindings: %v", err)
+	}
+	var rules []string
+	for _, f := range all {
+		rules = append(rules, f.AgentID+"/"+f.RuleName)
+	}
+	if fmt.Sprint(rules) != "[agent-2/smb agent-1/ssh agent-1/web agent-1/dns]" {
+		t.Fatalf("FindFindings = %v, want the latest findings by severity, agent, then report order", rules)
+	}
+
+	for _, tc := range []struct {
+		filter model.FirewallFindingFilter
+		want   int
+	}{
+		{model.FirewallFindingFilter{AgentID: "agent-1"}, 3},
+		{model.FirewallFindingFilter{Kinds: []string{model.FirewallRiskyExposure}}, 2},
+		{model.FirewallFindingFilter{Severities: []string{model.SeverityMedium}}, 2},
+		{model.FirewallFindingFilter{AgentID: "agent-2", Kinds: []string{model.FirewallShadowedRule}}, 0},
+		{model.FirewallFindingFilter{Limit: 1}, 1},
+	} {
+		got, err := store.Firewall.FindFindings(tc.filter)
+		if err != nil || len(got) != tc.want {
+			t.Errorf("FindFindings(%+v) = %d findings, %v; want %d", tc.filter, len(got), err, tc.want)
+		}
+	}
+
+	replace("agent-1")
+	if left, _ := store.Firewall.FindFindings(model.FirewallFindingFilter{AgentID: "agent-1"}); len(left) != 0 {
+		t.Fatalf("findings after clearing agent-1 = %+v", left)
+	}
+}
+
+func testCompliancePolicies(t *testing.T, store *repository.Store) {
+	for _, v := range []int{1, 2} {
+		policy := &model.CompliancePolicy{PolicyID: "baseline", Version: v, Checks: v, Digest: fmt.Sprint(v), Definition: "id: baseline", LoadedAt: time.Now()}
+		if err := store.Compliance.SavePolicyVersion(policy); err != nil {
+			t.Fatalf("SavePolicyVersion(%d): %v", v, err)
+		}
+	}
+	other := &model.CompliancePolicy{PolicyID: "servers", Version: 1, Digest: "s", Definition: "id: servers", LoadedAt: time.Now()}
+	if err := store.Compliance.SavePolicyVersion(other); err != nil {
+		t.Fatalf("SavePolicyVersion(servers): %v", err)
+	}
+	duplicate := &model.CompliancePolicy{PolicyID: "baseline", Version: 2, Digest: "x", Definition: "id: baseline", LoadedAt: time.Now()}
+	if err := store.Compliance.SavePolicyVersion(duplicate); err == nil {
+		t.Fatal("saving an existing policy version succeeded")
+	}
+
+	got, err := store.Compliance.FindPolicyVersion("baseline", 2)
+	if err != nil || got.Checks != 2 || got.Digest != "2" {
+		t.Fatalf("FindPolicyVersion = %+v, %v; want version 2", got, err)
+	}
+	if _, err := store.Compliance.FindPolicyVersion("baseline", 3); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindPolicyVersion(unknown) error = %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	versions, err := store.Compliance.ListPolicyVersions("baseline")
+	if err != nil || len(versions) != 2 || versions[0].Version != 2 {
+		t.Fatalf("ListPolicyVersions(baseline) = %+v, %v; want versions 2 and 1", versions, err)
+	}
+	if all, _ := store.Compliance.ListPolicyVersions(""); len(all) != 3 {
+		t.Fatalf("ListPolicyVersions() returned %d versions, want 3", len(all))
+	}
+}
+
+func testComplianceResults(t *testing.T, store *repository.Store) {
+	for _, id := range []string{"agent-1", "agent-2"} {
+		mustCreate(t, store.Agents, newAgent(id))
+	}
+	result := func(check, status string) model.ComplianceResult {
+		now := time.Now()
+		return model.ComplianceResult{PolicyVersion: 1, CheckID: check, Severity: model.SeverityMedium, Status: status, Since: now, EvaluatedAt: now}
+	}
+	replace := func(agentID, policyID string, results ...model.ComplianceResult) {
+		t.Helper()
+		if err := store.Compliance.ReplaceAgentResults(agentID, policyID, results); err != nil {
+			t.Fatalf("ReplaceAgentResults(%s, %s): %v", agentID, policyID, err)
+		}
+	}
+	replace("agent-2", "baseline", result("stale", model.ComplianceFail))
+	replace("agent-2", "baseline", result("telnet", model.CompliancePass), result("firewall", model.ComplianceFail))
+	replace("agent-1", "servers", result("ssh", model.ComplianceFail))
+	replace("agent-1", "baseline", result("telnet", model.ComplianceFail), result("firewall", model.CompliancePass))
+
+	all, err := store.Compliance.FindResults(model.ComplianceResultFilter{})
+	if err != nil {
+		t.Fatalf("FindResults: %v", err)
+	}
+	var checks []string
+	for _, r := range all {
+		checks = append(checks, r.AgentID+"/"+r.PolicyID+"/"+r.CheckID)
+	}
+	want := "[agent-1/baseline/telnet agent-1/baseline/firewall agent-1/servers/ssh agent-2/baseline/telnet agent-2/baseline/firewall]"
+	if fmt.Sprint(checks) != want {
+		t.Fatalf("FindResults = %v, want %v", checks, want)
+	}
+
+	for _, tc := range []struct {
+		filter model.ComplianceResultFilter
+		want   int
+	}{
+		{model.ComplianceResultFilter{AgentID: "agent-1"}, 3},
+		{model.ComplianceResultFilter{PolicyID: "baseline"}, 4},
+		{model.ComplianceResultFilter{CheckID: "telnet", Status: model.ComplianceFail}, 1},
+		{model.ComplianceResultFilter{Status: model.ComplianceFail}, 3},
+		{model.ComplianceResultFilter{Limit: 2}, 2},
+	} {
+		got, err := store.Compliance.FindResults(tc.filter)
+		if err != nil || len(got) != tc.want {
+			t.Errorf("FindResults(%+v) = %d results, %v; want %d", tc.filter, len(got), err, tc.want)
+		}
+	}
+
+	if err := store.Compliance.DeleteResults("", "servers"); err != nil {
+		t.Fatalf("DeleteResults(policy): %v", err)
+	}
+	if err := store.Compliance.DeleteResults("agent-2", ""); err != nil {
+		t.Fatalf("DeleteResults(agent): %v", err)
+	}
+	if left, _ := store.Compliance.FindResults(model.ComplianceResultFilter{}); len(left) != 2 || left[0].AgentID != "agent-1" || left[0].PolicyID != "baseline" {
+		t.Fatalf("results after deleting = %+v, want the baseline results of agent-1", left)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	for i, agentID := range []string{"agent-1", "agent-1", "agent-2"} {
+		evaluation := &model.ComplianceEvaluation{AgentID: agentID, PolicyID: "baseline", PolicyVersion: 1, Passed: i, EvaluatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if err := store.Compliance.AddEvaluation(evaluation); err != nil {
+			t.Fatalf("AddEvaluation: %v", err)
+		}
+	}
+	history, err := store.Compliance.FindEvaluations(model.ComplianceEvaluationFilter{AgentID: "agent-1"})
+	if err != nil || len(history) != 2 || history[0].Passed != 1 {
+		t.Fatalf("FindEvaluations(agent-1) = %+v, %v; want 2 evaluations, newest first", history, err)
+	}
+	if recent, _ := store.Compliance.FindEvaluations(model.ComplianceEvaluationFilter{Since: base.Add(90 * time.Second)}); len(recent) != 1 || recent[0].AgentID != "agent-2" {
+		t.Fatalf("FindEvaluations(since) = %+v, want the evaluation of agent-2", recent)
+	}
+}
+
+func testAgentMetrics(t *testing.T, store *repository.Store) {
+	for _, id := range []string{"agent-1", "agent-2"} {
+		mustCreate(t, store.Agents, newAgent(id))
+	}
+	base := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	sample := func(agentID, metric, mount string, at time.Duration, v float64) model.AgentMetric {
+		return model.AgentMetric{AgentID: agentID, Resolution: model.MetricResolutionRaw, BucketStart: base.Add(at),
+			Metric: metric, Mount: mount, Count: 1, Sum: v, Min: v, Max: v}
+	}
+
+	first, last, err := store.Metrics.BucketRange(model.MetricResolutionRaw)
+	if err != nil || !first.IsZero() || !last.IsZero() {
+		t.Fatalf("BucketRange(empty) = %v, %v, %v; want zero times", first, last, err)
+	}
+
+	if err := store.Metrics.InsertSamples([]model.AgentMetric{
+		sample("agent-1", model.MetricCPUPercent, "", time.Minute, 10),
+		sample("agent-1", model.MetricCPUPercent, "", 6*time.Minute, 30),
+		sample("agent-1", model.MetricDiskUsedGB, "/", time.Minute, 100),
+		sample("agent-1", model.MetricDiskUsedGB, "/data", time.Minute, 500),
+		sample("agent-2", model.MetricCPUPercent, "", 2*time.Minute, 50),
+	}); err != nil {
+		t.Fatalf("InsertSamples: %v", err)
+	}
+	// A retried heartbeat stores nothing new.
+	if err := store.Metrics.InsertSamples([]model.AgentMetric{sample("agent-1", model.MetricCPUPercent, "", time.Minute, 99)}); err != nil {
+		t.Fatalf("InsertSamples(duplicate): %v", err)
+	}
+
+	points, err := store.Metrics.FindMetrics(model.MetricFilter{AgentID: "agent-1", Resolution: model.MetricResolutionRaw})
+	if err != nil {
+		t.Fatalf("FindMetrics: %v", err)
+	}
+	var got []string
+	for _, p := range points {
+		got = append(got, fmt.Sprintf("%s%s@%v=%v", p.Metric, p.Mount, p.BucketStart.Sub(base), p.Sum))
+	}
+	if fmt.Sprint(got) != "[cpu_percent@1m0s=10 cpu_percent@6m0s=30 disk_used_gb/@1m0s=100 disk_used_gb/data@1m0s=500]" {
+		t.Fatalf("FindMetrics = %v, want agent-1's samples by metric, mount and time, the duplicate ignored", got)
+	}
+	points, err = store.Metrics.FindMetrics(model.MetricFilter{AgentID: "agent-1", Metrics: []string{model.MetricCPUPercent},
+		Resolution: model.MetricResolutionRaw, Since: base.Add(5 * time.Minute), Until: base.Add(time.Hour)})
+	if err != nil || len(points) != 1 || points[0].Sum != 30 {
+		t.Fatalf("FindMetrics(cpu since 5m) = %+v, %v; want the 6m sample", points, err)
+	}
+
+	var streamed int
+	err = store.Metrics.StreamMetrics(model.MetricResolutionRaw, base, base.Add(5*time.Minute), func(p *model.AgentMetric) error {
+		streamed++
+		return nil
+	})
+	if err != nil || streamed != 4 {
+		t.Fatalf("StreamMetrics(first 5m) streamed %d, %v; want 4 samples of both agents", streamed, err)
+	}
+
+	first, last, err = store.Metrics.BucketRange(model.MetricResolutionRaw)
+	if err != nil || !first.Equal(base.Add(time.Minute)) || !last.Equal(base.Add(6*time.Minute)) {
+		t.Fatalf("BucketRange(raw) = %v, %v, %v; want the first and last sample", first, last, err)
+	}
+
+	bucket := model.AgentMetric{AgentID: "agent-1", Resolution: model.MetricResolutionFiveMinute, BucketStart: base,
+		Metric: model.MetricCPUPercent, Count: 1, Sum: 10, Min: 10, Max: 10}
+	if err := store.Metrics.UpsertBuckets([]model.AgentMetric{bucket}); err != nil {
+		t.Fatalf("UpsertBuckets: %v", err)
+	}
+	bucket.ID, bucket.Count, bucket.Sum, bucket.Max = 0, 2, 40, 30
+	if err := store.Metrics.UpsertBuckets([]model.AgentMetric{bucket}); err != nil {
+		t.Fatalf("UpsertBuckets(again): %v", err)
+	}
+	points, err = store.Metrics.FindMetrics(model.MetricFilter{AgentID: "agent-1", Resolution: model.MetricResolutionFiveMinute})
+	if err != nil || len(points) != 1 || points[0].Count != 2 || points[0].Avg() != 20 || points[0].Max != 30 {
+		t.Fatalf("FindMetrics(5m) = %+v, %v; want the replaced bucket", points, err)
+	}
+
+	n, err := store.Metrics.DeleteMetricsBefore(model.MetricResolutionRaw, base.Add(2*time.Minute))
+	if err != nil || n != 3 {
+		t.Fatalf("DeleteMetricsBefore(raw, 2m) = %d, %v; want the 3 samples at 1m", n, err)
+	}
+	first, _, err = store.Metrics.BucketRange(model.MetricResolutionRaw)
+	if err != nil || !first.Equal(base.Add(2*time.Minute)) {
+		t.Fatalf("BucketRange(raw) after delete starts at %v, %v; want 2m", first.Sub(base), err)
+	}
+	if points, _ := store.Metrics.FindMetrics(model.MetricFilter{AgentID: "agent-1", Resolution: model.MetricResolutionFiveMinute}); len(points) != 1 {
+		t.Fatalf("5m buckets after deleting raw samples = %+v, want them kept", points)
+	}
+}
+
+func testAlerts(t *testing.T, store *repository.Store) {
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	alert := func(rule, key string, minute int) *model.Alert {
+		return &model.Alert{
+			RuleID: rule, Key: key, AgentID: key, Severity: model.SeverityHigh, State: model.AlertPending,
+			StartedAt: base.Add(time.Duration(minute) * time.Minute), UpdatedAt: base,
+		}
+	}
+	offline1, offline2, disk := alert("offline", "agent-1", 0), alert("offline", "agent-2", 1), alert("disk", "agent-1|/", 2)
+	for _, a := range []*model.Alert{offline1, offline2, disk} {
+		if err := store.Alerts.CreateAlert(a); err != nil {
+			t.Fatalf("CreateAlert(%s, %s): %v", a.RuleID, a.Key, err)
+		}
+	}
+	if err := store.Alerts.CreateAlert(alert("offline", "agent-1", 3)); err == nil {
+		t.Fatal("CreateAlert accepted a second open alert for the same rule and key")
+	}
+
+	open, err := store.Alerts.FindOpenAlerts("")
+	if err != nil {
+		t.Fatalf("FindOpenAlerts: %v", err)
+	}
+	var keys []string
+	for _, a := range open {
+		keys = append(keys, a.RuleID+"/"+a.Key)
+	}
+	if want := "[disk/agent-1|/ offline/agent-1 offline/agent-2]"; fmt.Sprint(keys) != want {
+		t.Fatalf("FindOpenAlerts = %v, want %v", keys, want)
+	}
+
+	fired := base.Add(10 * time.Minute)
+	offline1.State, offline1.FiredAt = model.AlertFiring, &fired
+	if err := store.Alerts.UpdateAlert(offline1); err != nil {
+		t.Fatalf("UpdateAlert(firing): %v", err)
+	}
+	resolved := base.Add(20 * time.Minute)
+	offline1.State, offline1.ResolvedAt = model.AlertResolved, &resolved
+	if err := store.Alerts.UpdateAlert(offline1); err != nil {
+		t.Fatalf("UpdateAlert(resolved): %v", err)
+	}
+	if err := store.Alerts.DeleteAlert(offline2.ID); err != nil {
+		t.Fatalf("DeleteAlert: %v", err)
+	}
+	// A resolved alert no longer blocks a new one for the same key.
+	if err := store.Alerts.CreateAlert(alert("offline", "agent-1", 30)); err != nil {
+		t.Fatalf("CreateAlert after resolving: %v", err)
+	}
+	if open, _ := store.Alerts.FindOpenAlerts("offline"); len(open) != 1 || open[0].ID == offline1.ID {
+		t.Fatalf("FindOpenAlerts(offline) = %+v, want the new alert only", open)
+	}
+
+	all, err := store.Alerts.FindAlerts(model.AlertFilter{})
+	if err != nil || len(all) != 3 || all[0].StartedAt.Before(all[1].StartedAt) {
+		t.Fatalf("FindAlerts = %+v, %v; want 3 alerts, newest first", all, err)
+	}
+	for _, tc := range []struct {
+		filter model.AlertFilter
+		want   int
+	}{
+		{model.AlertFilter{RuleID: "offline"}, 2},
+		{model.AlertFilter{AgentID: "agent-1"}, 2},
+		{model.AlertFilter{States: []string{model.AlertResolved}}, 1},
+		{model.AlertFilter{States: []string{model.AlertPending, model.AlertFiring}}, 2},
+		{model.AlertFilter{Severities: []string{model.SeverityLow}}, 0},
+		{model.AlertFilter{Since: base.Add(2 * time.Minute)}, 2},
+		{model.AlertFilter{Limit: 1}, 1},
+	} {
+		got, err := store.Alerts.FindAlerts(tc.filter)
+		if err != nil || len(got) != tc.want {
+			t.Errorf("FindAlerts(%+v) = %d alerts, %v; want %d", tc.filter, len(got), err, tc.want)
+		}
+	}
+	stored, _ := store.Alerts.FindAlerts(model.AlertFilter{States: []string{model.AlertResolved}})
+	if len(stored) != 1 || stored[0].FiredAt == nil || !stored[0].ResolvedAt.Equal(resolved) {
+		t.Fatalf("resolved alert = %+v, want fired and resolved times kept", stored)
+	}
+
+	if n, err := store.Alerts.DeleteResolvedAlertsBefore(resolved); err != nil || n != 0 {
+		t.Fatalf("DeleteResolvedAlertsBefore(resolved) = %d, %v; want 0", n, err)
+	}
+	if n, err := store.Alerts.DeleteResolvedAlertsBefore(resolved.Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("DeleteResolvedAlertsBefore = %d, %v; want 1", n, err)
+	}
+}
+
+func testHostInventory(t *testing.T, store *repository.Store) {
+	prod := newAgent("agent-1")
+	prod.Labels = model.Labels{"env": "prod"}
+	mustCreate(t, store.Agents, prod)
+	staging := newAgent("agent-2")
+	staging.Labels = model.Labels{"env": "staging"}
+	mustCreate(t, store.Agents, staging)
+	inv := store.Inventory
+
+	older := time.Now().Add(-time.Hour).Truncate(time.Second)
+	newer := older.Add(30 * time.Minute)
+	item := func(agent *model.Agent, at time.Time) model.InventoryItem {
+		return model.InventoryItem{AgentID: agent.ID, ReportedAt: at}
+	}
+	if err := inv.CreateNetworkInterfaces([]model.NetworkInterface{
+		{InventoryItem: item(prod, older), Name: "eth0", MAC: "00:11:22:33:44:55"},
+		{InventoryItem: item(prod, newer), Name: "eth0", MAC: "00:11:22:33:44:55", IPAddresses: model.StringList{"10.0.0.5/24", "fe80::1/64"}, MTU: 1500, Up: true},
+		{InventoryItem: item(prod, newer), Name: "lo", IPAddresses: model.StringList{"127.0.0.1/8"}, MTU: 65536, Up: true},
+		{InventoryItem: item(staging, older), Name: "Ethernet"},
+	}); err != nil {
+		t.Fatalf("CreateNetworkInterfaces: %v", err)
+	}
+	ifaces, err := inv.FindCurrentNetworkInterfaces(prod.ID)
+	if err != nil || len(ifaces) != 2 || ifaces[0].Name != "eth0" || ifaces[1].Name != "lo" {
+		t.Fatalf("FindCurrentNetworkInterfaces = %+v, %v; want eth0 and lo from the newer report", ifaces, err)
+	}
+	if i := ifaces[0]; i.ID == 0 || len(i.IPAddresses) != 2 || i.IPAddresses[1] != "fe80::1/64" || i.MTU != 1500 || !i.Up || !i.ReportedAt.Equal(newer) {
+		t.Fatalf("eth0 = %+v, want its addresses, MTU and report time", i)
+	}
+
+	if err := inv.CreateLocalUsers(
+		[]model.LocalUser{{InventoryItem: item(prod, newer), Name: "root", UID: "0", Groups: model.StringList{"root", "wheel"}}},
+		[]model.LocalGroup{{InventoryItem: item(prod, newer), Name: "wheel", GID: "10", Members: model.StringList{"root", "alice"}}},
+	); err != nil {
+		t.Fatalf("CreateLocalUsers: %v", err)
+	}
+	users, err := inv.FindCurrentLocalUsers(prod.ID)
+	if err != nil || len(users) != 1 || users[0].UID != "0" || len(users[0].Groups) != 2 {
+		t.Fatalf("FindCurrentLocalUsers = %+v, %v; want root in two groups", users, err)
+	}
+	groups, err := inv.FindCurrentLocalGroups(prod.ID)
+	if err != nil || len(groups) != 1 || groups[0].Members[1] != "alice" {
+		t.Fatalf("FindCurrentLocalGroups = %+v, %v; want wheel with root and alice", groups, err)
+	}
+	if err := inv.CreateServices([]model.SystemService{
+		{InventoryItem: item(prod, newer), Name: "sshd", StartType: model.ServiceStartAutomatic, State: model.ServiceRunning, Account: "root"},
+	}); err != nil {
+		t.Fatalf("CreateServices: %v", err)
+	}
+	if err := inv.CreateListeningSockets([]model.ListeningSocket{
+		{InventoryItem: item(prod, newer), Protocol: "TCP", Address: "0.0.0.0", Port: 22, PID: 812, ProcessName: "sshd"},
+	}); err != nil {
+		t.Fatalf("CreateListeningSockets: %v", err)
+	}
+	started := older.Add(-24 * time.Hour)
+	if err := inv.CreateProcesses([]model.RunningProcess{
+		{InventoryItem: item(prod, newer), PID: 812, ParentPID: 1, Name: "sshd", User: "root", CommandLine: "/usr/sbin/sshd -D", StartedAt: started, MemoryMB: 7.5},
+	}); err != nil {
+		t.Fatalf("CreateProcesses: %v", err)
+	}
+	if err := inv.CreateProcesses(nil); err != nil {
+		t.Fatalf("CreateProcesses(nil): %v", err)
+	}
+	services, err := inv.FindCurrentServices(prod.ID)
+	if err != nil || len(services) != 1 || services[0].State != model.ServiceRunning || services[0].StartType != model.ServiceStartAutomatic {
+		t.Fatalf("FindCurrentServices = %+v, %v; want sshd running", services, err)
+	}
+	sockets, err := inv.FindCurrentListeningSockets(prod.ID)
+	if err != nil || len(sockets) != 1 || sockets[0].Port != 22 || sockets[0].ProcessName != "sshd" {
+		t.Fatalf("FindCurrentListeningSockets = %+v, %v; want sshd on 22", sockets, err)
+	}
+	procs, err := inv.FindCurrentProcesses(prod.ID)
+	if err != nil || len(procs) != 1 || procs[0].ParentPID != 1 || procs[0].User != "root" || !procs[0].StartedAt.Equal(started) {
+		t.Fatalf("FindCurrentProcesses = %+v, %v; want sshd started by init", procs, err)
+	}
+	if procs, err := inv.FindCurrentProcesses(staging.ID); err != nil || len(procs) != 0 {
+		t.Fatalf("FindCurrentProcesses(staging) = %+v, %v; want none", procs, err)
+	}
+
+	var streamed []string
+	if err := inv.StreamCurrentNetworkInterfaces(model.ExportFilter{}, func(i *model.AgentInventory[model.NetworkInterface]) error {
+		streamed = append(streamed, i.AgentKey+"/"+i.Item.Name)
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamCurrentNetworkInterfaces: %v", err)
+	}
+	if strings.Join(streamed, ",") != "agent-1/eth0,agent-1/lo,agent-2/Ethernet" {
+		t.Fatalf("StreamCurrentNetworkInterfaces = %v, want the current interfaces of both agents", streamed)
+	}
+	var groupRows []model.AgentInventory[model.LocalGroup]
+	if err := inv.StreamCurrentLocalGroups(model.ExportFilter{Labels: map[string]string{"env": "prod"}}, func(g *model.AgentInventory[model.LocalGroup]) error {
+		groupRows = append(groupRows, *g)
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamCurrentLocalGroups: %v", err)
+	}
+	if len(groupRows) != 1 || groupRows[0].Hostname != "host-agent-1" || groupRows[0].Labels["env"] != "prod" ||
+		groupRows[0].Item.GID != "10" || len(groupRows[0].Item.Members) != 2 {
+		t.Fatalf("StreamCurrentLocalGroups(env=prod) = %+v, want wheel with the agent's details", groupRows)
+	}
+	streamed = nil
+	if err := inv.StreamCurrentProcesses(model.ExportFilter{AgentID: "agent-2"}, func(p *model.AgentInventory[model.RunningProcess]) error {
+		streamed = append(streamed, p.Item.Name)
+		return nil
+	}); err != nil || len(streamed) != 0 {
+		t.Fatalf("StreamCurrentProcesses(agent-2) = %v, %v; want none", streamed, err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	if n, err := inv.DeleteInventoryBefore(model.InventoryNetworkInterfaces, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("DeleteInventoryBefore(past) = %d, %v; want 0, nil", n, err)
+	}
+	// Only agent-1's older eth0 report has been replaced; agent-2 stopped
+	// reporting and keeps its last snapshot however old it is.
+	if n, err := inv.DeleteInventoryBefore(model.InventoryNetworkInterfaces, future); err != nil || n != 1 {
+		t.Fatalf("DeleteInventoryBefore(interfaces) = %d, %v; want 1, nil", n, err)
+	}
+	if ifaces, err := inv.FindCurrentNetworkInterfaces(prod.ID); err != nil || len(ifaces) != 2 {
+		t.Fatalf("FindCurrentNetworkInterfaces(prod) after retention = %+v, %v; want eth0 and lo", ifaces, err)
+	}
+	if ifaces, err := inv.FindCurrentNetworkInterfaces(staging.ID); err != nil || len(ifaces) != 1 || ifaces[0].Name != "Ethernet" {
+		t.Fatalf("FindCurrentNetworkInterfaces(staging) after retention = %+v, %v; want its last report", ifaces, err)
+	}
+	if err := inv.CreateLocalUsers(nil, []model.LocalGroup{{InventoryItem: item(prod, newer.Add(time.Minute)), Name: "wheel", GID: "10"}}); err != nil {
+		t.Fatalf("CreateLocalUsers(groups only): %v", err)
+	}
+	if n, err := inv.DeleteInventoryBefore(model.InventoryLocalGroups, future); err != nil || n != 1 {
+		t.Fatalf("DeleteInventoryBefore(groups) = %d, %v; want 1, nil", n, err)
+	}
+	if groups, _ := inv.FindCurrentLocalGroups(prod.ID); len(groups) != 1 || len(groups[0].Members) != 0 {
+		t.Fatalf("FindCurrentLocalGroups after retention = %+v, want the newer report", groups)
+	}
+	if users, _ := inv.FindCurrentLocalUsers(prod.ID); len(users) != 1 {
+		t.Fatalf("deleting groups removed users: %+v", users)
+	}
+	if _, err := inv.DeleteInventoryBefore("printers", future); err == nil {
+		t.Fatal("DeleteInventoryBefore(unknown kind) succeeded")
+	}
+}
+
+func testPatchStatus(t *testing.T, store *repository.Store) {
+	win := newAgent("agent-1")
+	win.Labels = model.Labels{"env": "prod"}
+	mustCreate(t, store.Agents, win)
+	linux := newAgent("agent-2")
+	linux.OSName = "Ubuntu"
+	mustCreate(t, store.Agents, linux)
+	silent := newAgent("agent-3")
+	mustCreate(t, store.Agents, silent)
+	patches := store.Patches
+
+	if _, err := patches.FindPatchStatus(win.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindPatchStatus before any report: err = %v, want ErrRecordNotFound", err)
+	}
+
+	older := time.Now().Add(-time.Hour).Truncate(time.Second)
+	newer := older.Add(30 * time.Minute)
+	rebootSince := older.Add(-8 * 24 * time.Hour)
+	item := func(agent *model.Agent, at time.Time) model.InventoryItem {
+		return model.InventoryItem{AgentID: agent.ID, ReportedAt: at}
+	}
+	save := func(status *model.AgentPatchStatus, installed []model.InstalledPatch, pending []model.PendingUpdate) {
+		t.Helper()
+		if err := patches.SavePatchReport(status, installed, pending); err != nil {
+			t.Fatalf("SavePatchReport: %v", err)
+		}
+	}
+	save(&model.AgentPatchStatus{AgentID: win.ID, InstalledCount: 1, ReportedAt: older},
+		[]model.InstalledPatch{{InventoryItem: item(win, older), PatchID: "KB5034441"}}, nil)
+	save(&model.AgentPatchStatus{AgentID: win.ID, RebootPending: true, RebootPendingSince: &rebootSince, InstalledCount: 2, PendingCount: 1, SecurityPendingCount: 1, ReportedAt: newer},
+		[]model.InstalledPatch{
+			{InventoryItem: item(win, newer), PatchID: "KB5034441", Title: "2024-01 Cumulative Update", InstalledAt: older},
+			{InventoryItem: item(win, newer), PatchID: "KB5034765"},
+		},
+		[]model.PendingUpdate{{InventoryItem: item(win, newer), PatchID: "KB5035845", Title: "2024-03 Cumulative Update", Security: true}})
+	save(&model.AgentPatchStatus{AgentID: linux.ID, LastUpdateAt: older, ReportedAt: newer},
+		[]model.InstalledPatch{{InventoryItem: item(linux, newer), PatchID: "openssl", Version: "3.0.2-0ubuntu1.15"}}, nil)
+
+	status, err := patches.FindPatchStatus(win.ID)
+	if err != nil || !status.RebootPending || status.RebootPendingSince == nil || !status.RebootPendingSince.Equal(rebootSince) ||
+		status.InstalledCount != 2 || status.SecurityPendingCount != 1 || !status.ReportedAt.Equal(newer) {
+		t.Fatalf("FindPatchStatus = %+v, %v; want the newer report with a pending reboot", status, err)
+	}
+	installed, pending, err := patches.FindPatches(win.ID, status.ReportedAt)
+	if err != nil || len(installed) != 2 || installed[0].PatchID != "KB5034441" || !installed[0].InstalledAt.Equal(older) ||
+		len(pending) != 1 || !pending[0].Security {
+		t.Fatalf("FindPatches = %+v, %+v, %v; want the newer report's patches", installed, pending, err)
+	}
+
+	keys := func(filter model.PatchStatusFilter) string {
+		t.Helper()
+		rows, err := patches.FindPatchStatuses(filter)
+		if err != nil {
+			t.Fatalf("FindPatchStatuses(%+v): %v", filter, err)
+		}
+		var ids []string
+		for _, row := range rows {
+			ids = append(ids, row.AgentKey)
+		}
+		return strings.Join(ids, ",")
+	}
+	if got := keys(model.PatchStatusFilter{}); got != "agent-1,agent-2" {
+		t.Fatalf("all statuses = %s, want the two agents that reported", got)
+	}
+	if got := keys(model.PatchStatusFilter{MissingPatch: "KB5034765"}); got != "agent-2" {
+		t.Fatalf("missing KB5034765 = %s, want agent-2", got)
+	}
+	if got := keys(model.PatchStatusFilter{MissingPatch: "KB5034765", OSName: "Windows"}); got != "" {
+		t.Fatalf("Windows agents missing KB5034765 = %s, want none", got)
+	}
+	if got := keys(model.PatchStatusFilter{RebootPendingBefore: time.Now().Add(-7 * 24 * time.Hour)}); got != "agent-1" {
+		t.Fatalf("reboot pending for 7 days = %s, want agent-1", got)
+	}
+	if got := keys(model.PatchStatusFilter{RebootPendingBefore: rebootSince}); got != "" {
+		t.Fatalf("reboot pending before it started = %s, want none", got)
+	}
+	if got := keys(model.PatchStatusFilter{PendingOnly: true, Labels: map[string]string{"env": "prod"}}); got != "agent-1" {
+		t.Fatalf("prod agents with pending updates = %s, want agent-1", got)
+	}
+	if got := keys(model.PatchStatusFilter{Limit: 1}); got != "agent-1" {
+		t.Fatalf("first status = %s, want agent-1", got)
+	}
+	rows, err := patches.FindPatchStatuses(model.PatchStatusFilter{AgentID: "agent-2"})
+	if err != nil || len(rows) != 1 || rows[0].OSName != "Ubuntu" || rows[0].Hostname != "host-agent-2" || !rows[0].Status.LastUpdateAt.Equal(older) {
+		t.Fatalf("FindPatchStatuses(agent-2) = %+v, %v; want its status with agent details", rows, err)
+	}
+
+	// Only the older report of agent-1 is expired; current reports are kept.
+	if n, err := patches.DeletePatchesBefore(time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("DeletePatchesBefore = %d, %v; want 1, nil", n, err)
+	}
+	if installed, _, _ := patches.FindPatches(win.ID, newer); len(installed) != 2 {
+		t.Fatalf("current patches after retention = %+v, want both kept", installed)
+	}
+}
+
+func testAgentUpdates(t *testing.T, store *repository.Store) {
+	first, second := newAgent("agent-1"), newAgent("agent-2")
+	mustCreate(t, store.Agents, first)
+	mustCreate(t, store.Agents, second)
+	updates := store.Updates
+
+	for _, pkg := range []*model.AgentPackage{
+		{Version: "1.1.0", OS: "linux", Arch: "amd64", SHA256: "aa", SizeBytes: 10},
+		{Version: "1.1.0", OS: "windows", Arch: "amd64", SHA256: "bb", SizeBytes: 20},
+		{Version: "1.2.0", OS: "linux", Arch: "amd64", SHA256: "cc", SizeBytes: 30},
+	} {
+		if err := updates.CreatePackage(pkg); err != nil {
+			t.Fatalf("CreatePackage(%s %s): %v", pkg.Version, pkg.OS, err)
+		}
+	}
+	if err := updates.CreatePackage(&model.AgentPackage{Version: "1.1.0", OS: "linux", Arch: "amd64", SHA256: "dd"}); err == nil {
+		t.Fatal("CreatePackage of an existing version, OS and arch succeeded")
+	}
+	if pkgs, err := updates.FindPackages("1.1.0"); err != nil || len(pkgs) != 2 {
+		t.Fatalf("FindPackages(1.1.0) = %+v, %v; want 2", pkgs, err)
+	}
+	if pkgs, _ := updates.FindPackages(""); len(pkgs) != 3 || pkgs[0].Version != "1.2.0" {
+		t.Fatalf("FindPackages() = %+v, want all 3, newest first", pkgs)
+	}
+
+	old := &model.Rollout{Version: "1.1.0", Percentage: 100, Status: model.RolloutCancelled}
+	rollout := &model.Rollout{Version: "1.2.0", Percentage: 10, Groups: model.StringList{"ring=canary"}, Status: model.RolloutActive}
+	for _, r := range []*model.Rollout{old, rollout} {
+		if err := updates.CreateRollout(r); err != nil {
+			t.Fatalf("CreateRollout: %v", err)
+		}
+	}
+	rollout.Percentage = 50
+	if err := updates.SaveRollout(rollout); err != nil {
+		t.Fatalf("SaveRollout: %v", err)
+	}
+	open, err := updates.FindRollouts([]string{model.RolloutActive, model.RolloutPaused}, 0)
+	if err != nil || len(open) != 1 || open[0].Percentage != 50 || len(open[0].Groups) != 1 {
+		t.Fatalf("FindRollouts(open) = %+v, %v; want the saved active rollout", open, err)
+	}
+
+	at := time.Now().Truncate(time.Second)
+	for i, agent := range []*model.Agent{first, second} {
+		update := &model.AgentUpdate{RolloutID: rollout.ID, AgentID: agent.ID, FromVersion: "1.1.0", ToVersion: "1.2.0",
+			Status: model.AgentUpdateOffered, OfferedAt: at.Add(time.Duration(i) * time.Minute)}
+		if created, err := updates.CreateAgentUpdate(update); err != nil || !created {
+			t.Fatalf("CreateAgentUpdate(%s) = %v, %v; want created", agent.AgentID, created, err)
+		}
+	}
+	again := &model.AgentUpdate{RolloutID: rollout.ID, AgentID: first.ID, Status: model.AgentUpdateOffered, OfferedAt: at}
+	if created, err := updates.CreateAgentUpdate(again); err != nil || created {
+		t.Fatalf("second CreateAgentUpdate = %v, %v; want kept", created, err)
+	}
+
+	if ok, err := updates.FinishAgentUpdate(rollout.ID, first.ID, model.AgentUpdateFailed, "checksum mismatch", at); err != nil || !ok {
+		t.Fatalf("FinishAgentUpdate = %v, %v; want finished", ok, err)
+	}
+	if ok, _ := updates.FinishAgentUpdate(rollout.ID, first.ID, model.AgentUpdateSucceeded, "", at); ok {
+		t.Fatal("FinishAgentUpdate changed an update that had already finished")
+	}
+	counts, err := updates.CountAgentUpdates(rollout.ID)
+	if err != nil || counts != (model.RolloutCounts{Offered: 1, Failed: 1}) {
+		t.Fatalf("CountAgentUpdates = %+v, %v; want 1 offered, 1 failed", counts, err)
+	}
+	rows, err := updates.FindAgentUpdates(rollout.ID, "", 0)
+	if err != nil || len(rows) != 2 || rows[0].AgentKey != "agent-2" || rows[1].Update.Message != "checksum mismatch" {
+		t.Fatalf("FindAgentUpdates = %+v, %v; want agent-2 then the failed agent-1", rows, err)
+	}
+	if rows, _ := updates.FindAgentUpdates(rollout.ID, model.AgentUpdateFailed, 0); len(rows) != 1 || rows[0].AgentKey != "agent-1" {
+		t.Fatalf("FindAgentUpdates(failed) = %+v, want agent-1", rows)
+	}
+}
+
+func testFileCollections(t *testing.T, store *repository.Store) {
+	collections := store.Collections
+	old := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	for i, c := range []*model.FileCollection{
+		{CommandID: 1, AgentID: "agent-1", Path: "/var/log/syslog", Status: model.CollectionComplete, CreatedAt: old},
+		{CommandID: 2, AgentID: "agent-1", Path: "/etc/hosts", Status: model.CollectionPending},
+		{CommandID: 3, AgentID: "agent-2", Path: `C:\Windows\memory.dmp`, Status: model.CollectionExpired, CreatedAt: old},
+	} {
+		if err := collections.CreateCollection(c); err != nil || c.ID != uint(i+1) {
+			t.Fatalf("CreateCollection(%s) = %v, ID %d", c.Path, err, c.ID)
+		}
+	}
+	if err := collections.CreateCollection(&model.FileCollection{CommandID: 2, AgentID: "agent-1", Status: model.CollectionPending}); err == nil {
+		t.Fatal("CreateCollection for a command that already has one succeeded")
+	}
+
+	c, err := collections.FindCollectionByCommand(2)
+	if err != nil || c.Path != "/etc/hosts" {
+		t.Fatalf("FindCollectionByCommand(2) = %+v, %v", c, err)
+	}
+	c.Status, c.SizeBytes, c.ReceivedBytes, c.Parts, c.SHA256 = model.CollectionUploading, 100, 40, 1, "ab"
+	if err := collections.SaveCollection(c); err != nil {
+		t.Fatalf("SaveCollection: %v", err)
+	}
+	if got, err := collections.FindCollection(c.ID); err != nil || got.ReceivedBytes != 40 || got.Parts != 1 || got.Status != model.CollectionUploading {
+		t.Fatalf("FindCollection after save = %+v, %v", got, err)
+	}
+	if _, err := collections.FindCollectionByCommand(99); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindCollectionByCommand(99) = %v, want ErrRecordNotFound", err)
+	}
+
+	if got, err := collections.FindCollections(model.FileCollectionFilter{AgentID: "agent-1"}); err != nil || len(got) != 2 || got[0].CommandID != 2 {
+		t.Fatalf("FindCollections(agent-1) = %+v, %v; want 2, newest first", got, err)
+	}
+	if got, _ := collections.FindCollections(model.FileCollectionFilter{Status: model.CollectionExpired, Limit: 5}); len(got) != 1 || got[0].AgentID != "agent-2" {
+		t.Fatalf("FindCollections(expired) = %+v, want agent-2", got)
+	}
+	expiring, err := collections.FindCollectionsCreatedBefore(time.Now().Add(-24*time.Hour), 10)
+	if err != nil || len(expiring) != 1 || expiring[0].CommandID != 1 {
+		t.Fatalf("FindCollectionsCreatedBefore = %+v, %v; want the old complete collection", expiring, err)
+	}
+}
+
+func testScheduledJobs(t *testing.T, store *repository.Store) {
+	jobs := store.Jobs
+	now := time.Now().Truncate(time.Second)
+	due, later := now.Add(-time.Minute), now.Add(time.Hour)
+	for _, job := range []*model.ScheduledJob{
+		{Name: "nightly-inventory", CommandType: model.CommandTypeReportInventory, Cron: "0 2 * * *", Status: model.JobActive, NextRunAt: &later, TargetAll: true},
+		{Name: "firewall-push", CommandType: model.CommandTypeFirewallConfiguration, Payload: []byte{1, 2}, Cron: "*/5 * * * *", Status: model.JobActive, NextRunAt: &due, TargetLabels: model.Labels{"env": "prod"}},
+		{Name: "paused", CommandType: model.CommandTypeRunScript, ScriptName: "rotate-logs.sh", ScriptDigest: "sha256:" + strings.Repeat("ab", 32), Cron: "* * * * *", Status: model.JobPaused, NextRunAt: &due, TargetAgentIDs: model.StringList{"agent-1"}},
+	} {
+		if err := jobs.CreateJob(job); err != nil || job.ID == 0 {
+			t.Fatalf("CreateJob(%s) = %v, ID %d", job.Name, err, job.ID)
+		}
+	}
+	if err := jobs.CreateJob(&model.ScheduledJob{Name: "paused", Status: model.JobActive}); err == nil {
+		t.Fatal("CreateJob with a duplicate name succeeded")
+	}
+
+	if got, err := jobs.FindDueJobs(now); err != nil || len(got) != 1 || got[0].Name != "firewall-push" {
+		t.Fatalf("FindDueJobs = %+v, %v; want only the active overdue job", got, err)
+	}
+	if got, err := jobs.FindJobs(""); err != nil || len(got) != 3 || got[0].Name != "firewall-push" {
+		t.Fatalf("FindJobs() = %+v, %v; want 3 by name", got, err)
+	}
+	if got, _ := jobs.FindJobs(model.JobPaused); len(got) != 1 || got[0].TargetAgentIDs[0] != "agent-1" || got[0].ScriptName != "rotate-logs.sh" || len(got[0].ScriptDigest) != 71 {
+		t.Fatalf("FindJobs(PAUSED) = %+v", got)
+	}
+	job, err := jobs.FindJobByName("firewall-push")
+	if err != nil || job.TargetLabels["env"] != "prod" || len(job.Payload) != 2 {
+		t.Fatalf("FindJobByName = %+v, %v", job, err)
+	}
+	job.NextRunAt, job.LastRunAt = &later, &now
+	if err := jobs.SaveJob(job); err != nil {
+		t.Fatalf("SaveJob: %v", err)
+	}
+	if got, _ := jobs.FindDueJobs(now); len(got) != 0 {
+		t.Fatalf("FindDueJobs after advancing = %+v, want none", got)
+	}
+
+	oldRun := &model.JobRun{JobID: job.ID, Trigger: model.TriggerSchedule, Status: model.JobRunSucceeded, StartedAt: now.Add(-48 * time.Hour)}
+	finished := now.Add(-48 * time.Hour)
+	oldRun.FinishedAt = &finished
+	if err := jobs.CreateRun(oldRun, []model.JobRunTarget{{AgentID: "agent-1", Status: model.JobTargetSucceeded}}); err != nil {
+		t.Fatalf("CreateRun(old): %v", err)
+	}
+	run := &model.JobRun{JobID: job.ID, Trigger: model.TriggerManual, Status: model.JobRunRunning, StartedAt: now, Targets: 2}
+	targets := []model.JobRunTarget{{AgentID: "agent-1", Status: model.JobTargetWaiting}, {AgentID: "agent-2", Status: model.JobTargetWaiting}}
+	if err := jobs.CreateRun(run, targets); err != nil || run.ID == 0 || targets[1].RunID != run.ID {
+		t.Fatalf("CreateRun = %v, run %+v, targets %+v", err, run, targets)
+	}
+	if err := jobs.CreateRun(&model.JobRun{JobID: job.ID, Status: model.JobRunRunning}, []model.JobRunTarget{{AgentID: "a"}, {AgentID: "a"}}); err == nil {
+		t.Fatal("CreateRun with an agent twice succeeded")
+	}
+
+	got, err := jobs.FindRunTargets(run.ID)
+	if err != nil || len(got) != 2 || got[0].AgentID != "agent-1" {
+		t.Fatalf("FindRunTargets = %+v, %v", got, err)
+	}
+	got[0].Status, got[0].CommandID = model.JobTargetDispatched, 7
+	if err := jobs.SaveRunTarget(&got[0]); err != nil {
+		t.Fatalf("SaveRunTarget: %v", err)
+	}
+	if got, _ := jobs.FindRunTargets(run.ID); got[0].CommandID != 7 || got[0].Status != model.JobTargetDispatched {
+		t.Fatalf("FindRunTargets after save = %+v", got)
+	}
+	if running, err := jobs.FindRunningRuns(); err != nil || len(running) != 1 || running[0].ID != run.ID {
+		t.Fatalf("FindRunningRuns = %+v, %v", running, err)
+	}
+	run.Status, run.Succeeded = model.JobRunSucceeded, 2
+	run.FinishedAt = &now
+	if err := jobs.SaveRun(run); err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+	if got, err := jobs.FindRun(run.ID); err != nil || got.Succeeded != 2 || got.FinishedAt == nil {
+		t.Fatalf("FindRun after save = %+v, %v", got, err)
+	}
+	if runs, err := jobs.FindRuns(model.JobRunFilter{JobID: job.ID}); err != nil || len(runs) != 2 || runs[0].ID != run.ID {
+		t.Fatalf("FindRuns = %+v, %v; want 2, newest first", runs, err)
+	}
+
+	if n, err := jobs.DeleteRunsFinishedBefore(now.Add(-24 * time.Hour)); err != nil || n != 1 {
+		t.Fatalf("DeleteRunsFinishedBefore = %d, %v; want the old run", n, err)
+	}
+	if _, err := jobs.FindRun(oldRun.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindRun(old) = %v, want ErrRecordNotFound", err)
+	}
+	if err := jobs.DeleteJob(job.ID); err != nil {
+		t.Fatalf("DeleteJob: %v", err)
+	}
+	if runs, _ := jobs.FindRuns(model.JobRunFilter{JobID: job.ID}); len(runs) != 0 {
+		t.Fatalf("runs after DeleteJob = %+v, want none", runs)
+	}
+	if got, _ := jobs.FindRunTargets(run.ID); len(got) != 0 {
+		t.Fatalf("targets after DeleteJob = %+v, want none", got)
+	}
+	if err := jobs.DeleteJob(job.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("DeleteJob twice = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"agent_server/internal/migrations"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+var (
+	createTable = regexp.MustCompile(`(?is)CREATE TABLE (?:IF NOT EXISTS )?(\w+)\s*\((.*?)\n\);`)
+	alterTable  = regexp.MustCompile(`(?is)ALTER TABLE (?:IF EXISTS )?(\w+)\s+(.*?);`)
+	addColumn   = regexp.MustCompile(`(?i)ADD COLUMN (?:IF NOT EXISTS )?"?(\w+)"?`)
+	columnName  = regexp.MustCompile(`^"?(\w+)"?[\s(]`)
+
+	tableConstraints = map[string]bool{"PRIMARY": true, "UNIQUE": true, "CONSTRAINT": true, "FOREIGN": true, "CHECK": true, "EXCLUDE": true}
+)
+
+// migrationColumns returns the columns the Postgres migrations give each
+// table.
+func migrationColumns(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatalf("migrations.All: %v", err)
+	}
+	tables := map[string]map[string]bool{}
+	add := func(table, column string) {
+		if tables[table] == nil {
+			tables[table] = map[string]bool{}
+		}
+		tables[table][strings.ToLower(column)] = true
+	}
+	for _, m := range all {
+		for _, match := range createTable.FindAllStringSubmatch(m.Up, -1) {
+			for _, line := range strings.Split(match[2], "\n") {
+				c := columnName.FindStringSubmatch(strings.TrimSpace(line))
+				if c == nil || tableConstraints[strings.ToUpper(c[1])] {
+					continue
+				}
+				add(match[1], c[1])
+			}
+		}
+		for _, match := range alterTable.FindAllStringSubmatch(m.Up, -1) {
+			for _, c := range addColumn.FindAllStringSubmatch(match[2], -1) {
+				add(match[1], c[1])
+			}
+		}
+	}
+	return tables
+}
+
+// TestModelsMatchMigrations catches model fields whose GORM column name is
+// missing from the Postgres schema. SQLite builds its tables from the models
+// with AutoMigrate, so the conformance suite only sees such a mismatch when
+// it runs against Postgres.
+func TestModelsMatchMigrations(t *testing.T) {
+	tables := migrationColumns(t)
+	cache := &sync.Map{}
+	for _, m := range sqliteModels {
+		s, err := schema.Parse(m, cache, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatalf("parse %T: %v", m, err)
+		}
+		columns, ok := tables[s.Table]
+		if !ok {
+			t.Errorf("%s: no migration creates table %q", s.Name, s.Table)
+			continue
+		}
+		for _, field := range s.Fields {
+			if field.DBName != "" && !columns[field.DBName] {
+				t.Errorf("%s.%s: column %q is not in the %s migrations", s.Name, field.Name, field.DBName, s.Table)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateRepository stores agent packages, the rollouts that offer them to
+// agents, and the update each agent was offered in a rollout.
+type UpdateRepository interface {
+	CreatePackage(pkg *model.AgentPackage) error
+	// FindPackages returns the packages of version, or of every version if
+	// it is empty, newest first.
+	FindPackages(version string) ([]model.AgentPackage, error)
+	FindPackage(id uint) (*model.AgentPackage, error)
+
+	CreateRollout(rollout *model.Rollout) error
+	SaveRollout(rollout *model.Rollout) error
+	FindRollout(id uint) (*model.Rollout, error)
+	// FindRollouts returns rollouts in one of statuses, or in any status if
+	// none are given, newest first.
+	FindRollouts(statuses []string, limit int) ([]model.Rollout, error)
+
+	// CreateAgentUpdate records an update offer unless the agent already has
+	// one in the rollout, and reports whether it did.
+	CreateAgentUpdate(update *model.AgentUpdate) (bool, error)
+	// FindAgentUpdates returns the updates of a rollout, with status if it is
+	// set, most recently offered first.
+	FindAgentUpdates(rolloutID uint, status string, limit int) ([]model.AgentUpdateRow, error)
+	// FinishAgentUpdate sets the result of the agent's update in the rollout
+	// if it is still offered, and reports whether it was.
+	FinishAgentUpdate(rolloutID, agentPK uint, status, message string, at time.Time) (bool, error)
+	CountAgentUpdates(rolloutID uint) (model.RolloutCounts, error)
+}
+
+type gormUpdateRepository struct {
+	db *gorm.DB
+}
+
+// NewUpdateRepository creates an agent update repository with a GORM connection.
+func NewUpdateRepository(db *gorm.DB) UpdateRepository {
+	return &gormUpdateRepository{db: db}
+}
+
+func (r *gormUpdateRepository) CreatePackage(pkg *model.AgentPackage) error {
+	return r.db.Create(pkg).Error
+}
+
+func (r *gormUpdateRepository) FindPackages(version string) ([]model.AgentPackage, error) {
+	query := r.db.Order("created_at DESC, id DESC")
+	if version != "" {
+		query = query.Where("version = ?", version)
+	}
+	var pkgs []model.AgentPackage
+	err := query.Find(&pkgs).Error
+	return pkgs, err
+}
+
+func (r *gormUpdateRepository) FindPackage(id uint) (*model.AgentPackage, error) {
+	var pkg model.AgentPackage
+	if err := r.db.First(&pkg, id).Error; err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+func (r *gormUpdateRepository) CreateRollout(rollout *model.Rollout) error {
+	return r.db.Create(rollout).Error
+}
+
+func (r *gormUpdateRepository) SaveRollout(rollout *model.Rollout) error {
+	return r.db.Save(rollout).Error
+}
+
+func (r *gormUpdateRepository) FindRollout(id uint) (*model.Rollout, error) {
+	var rollout model.Rollout
+	if err := r.db.First(&rollout, id).Error; err != nil {
+		return nil, err
+	}
+	return &rollout, nil
+}
+
+func (r *gormUpdateRepository) FindRollouts(statuses []string, limit int) ([]model.Rollout, error) {
+	query := r.db.Order("id DESC")
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var rollouts []model.Rollout
+	err := query.Find(&rollouts).Error
+	return rollouts, err
+}
+
+func (r *gormUpdateRepository) CreateAgentUpdate(update *model.AgentUpdate) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "rollout_id"}, {Name: "agent_id"}},
+		DoNothing: true,
+	}).Create(update)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormUpdateRepository) FindAgentUpdates(rolloutID uint, status string, limit int) ([]model.AgentUpdateRow, error) {
+	query := r.db.Table("agent_updates").
+		Select("agents.agent_id AS agent_key, agents.hostname, agent_updates.*").
+		Joins("JOIN agents ON agents.id = agent_updates.agent_id").
+		Where("agent_updates.rollout_id = ? AND agents.deleted_at IS NULL", rolloutID).
+		Order("agent_updates.offered_at DESC, agent_updates.id DESC")
+	if status != "" {
+		query = query.Where("agent_updates.status = ?", status)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var rows []model.AgentUpdateRow
+	err := query.Scan(&rows).Error
+	return rows, err
+}
+
+func (r *gormUpdateRepository) FinishAgentUpdate(rolloutID, agentPK uint, status, message string, at time.Time) (bool, error) {
+	result := r.db.Model(&model.AgentUpdate{}).
+		Where("rollout_id = ? AND agent_id = ? AND status = ?", rolloutID, agentPK, model.AgentUpdateOffered).
+		Updates(map[string]interface{}{"status": status, "message": message, "finished_at": at})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *gormUpdateRepository) CountAgentUpdates(rolloutID uint) (model.RolloutCounts, error) {
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	err := r.db.Model(&model.AgentUpdate{}).Select("status, COUNT(*) AS count").
+		Where("rollout_id = ?", rolloutID).Group("status").Scan(&rows).Error
+	var counts model.RolloutCounts
+	for _, row := range rows {
+		switch row.Status {
+		case model.AgentUpdateOffered:
+			counts.Offered = row.Count
+		case model.AgentUpdateSucceeded:
+			counts.Succeeded = row.Count
+		case model.AgentUpdateFailed:
+			counts.Failed = row.Count
+		}
+	}
+	return counts, err
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// VulnerabilityRepository stores the imported vulnerability feed and the
+// findings computed from it for each agent.
+type VulnerabilityRepository interface {
+	// ReplaceVulnerabilities swaps the whole feed for vulns in one transaction.
+	ReplaceVulnerabilities(vulns []model.Vulnerability) error
+	ListVulnerabilities() ([]model.Vulnerability, error)
+	// LatestVulnerabilityImport returns when the current feed was imported,
+	// or the zero time if there is none.
+	LatestVulnerabilityImport() (time.Time, error)
+
+	// ReplaceAgentFindings swaps all findings of an agent for findings.
+	ReplaceAgentFindings(agentID string, findings []model.VulnerabilityFinding) error
+	// FindFindings returns findings ordered by CVSS score (highest first),
+	// then agent and vulnerability ID.
+	FindFindings(filter model.VulnerabilityFindingFilter) ([]model.VulnerabilityFinding, error)
+	// CountAffectedAgents returns the number of distinct agents per vulnerability ID.
+	CountAffectedAgents() (map[string]int64, error)
+}
+
+// vulnerabilityBatchSize keeps each INSERT well under Postgres' parameter limit.
+const vulnerabilityBatchSize = 500
+
+type gormVulnerabilityRepository struct {
+	db *gorm.DB
+}
+
+// NewVulnerabilityRepository creates a vulnerability repository with a GORM connection.
+func NewVulnerabilityRepository(db *gorm.DB) VulnerabilityRepository {
+	return &gormVulnerabilityRepository{db: db}
+}
+
+func (r *gormVulnerabilityRepository) ReplaceVulnerabilities(vulns []model.Vulnerability) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&model.Vulnerability{}).Error; err != nil {
+			return err
+		}
+		if len(vulns) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(vulns, vulnerabilityBatchSize).Error
+	})
+}
+
+func (r *gormVulnerabilityRepository) ListVulnerabilities() ([]model.Vulnerability, error) {
+	var vulns []model.Vulnerability
+	err := r.db.Order("id").Find(&vulns).Error
+	return vulns, err
+}
+
+func (r *gormVulnerabilityRepository) LatestVulnerabilityImport() (time.Time, error) {
+	var vuln model.Vulnerability
+	err := r.db.Order("imported_at DESC").Limit(1).Find(&vuln).Error
+	return vuln.ImportedAt, err
+}
+
+func (r *gormVulnerabilityRepository) ReplaceAgentFindings(agentID string, findings []model.VulnerabilityFinding) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("agent_id = ?", agentID).Delete(&model.VulnerabilityFinding{}).Error; err != nil {
+			return err
+		}
+		if len(findings) == 0 {
+			return nil
+		}
+		for i := range findings {
+			findings[i].AgentID = agentID
+		}
+		return tx.CreateInBatches(findings, vulnerabilityBatchSize).Error
+	})
+}
+
+func (r *gormVulnerabilityRepository) FindFindings(filter model.VulnerabilityFindingFilter) ([]model.VulnerabilityFinding, error) {
+	query := r.db.Order("cvss_score DESC, agent_id, vulnerability_id, app_name")
+	if filter.AgentID != "" {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if len(filter.VulnerabilityIDs) > 0 {
+		query = query.Where("vulnerability_id IN ?", filter.VulnerabilityIDs)
+	}
+	if len(filter.Severities) > 0 {
+		query = query.Where("severity IN ?", filter.Severities)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var findings []model.VulnerabilityFinding
+	err := query.Find(&findings).Error
+	return findings, err
+}
+
+func (r *gormVulnerabilityRepository) CountAffectedAgents() (map[string]int64, error) {
+	var rows []struct {
+		VulnerabilityID string
+		Agents          int64
+	}
+	err := r.db.Model(&model.VulnerabilityFinding{}).
+		Select("vulnerability_id, COUNT(DISTINCT agent_id) AS agents").
+		Group("vulnerability_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.VulnerabilityID] = row.Agents
+	}
+	return counts, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"agent_server/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRepository stores webhook subscriptions and the delivery outbox.
+type WebhookRepository interface {
+	CreateSubscription(sub *model.WebhookSubscription) error
+	FindSubscriptionByID(id uint) (*model.WebhookSubscription, error)
+	ListSubscriptions() ([]model.WebhookSubscription, error)
+	// DeleteSubscription removes the subscription together with its deliveries.
+	DeleteSubscription(id uint) error
+
+	EnqueueDeliveries(deliveries []model.WebhookDelivery) error
+	// ClaimDueDeliveries returns up to limit PENDING deliveries whose next attempt
+	// is due and pushes their next attempt back by lease, so another dispatcher
+	// (or this one after a crash) only retries them once the lease expires.
+	ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error)
+	// SaveDeliveryAttempt stores the outcome of an attempt.
+	SaveDeliveryAttempt(delivery *model.WebhookDelivery) error
+	FindDeliveries(filter model.WebhookDeliveryFilter) ([]model.WebhookDelivery, error)
+	// ResetDeliveries makes the deliveries PENDING again with a fresh attempt budget.
+	ResetDeliveries(ids []uint) (int64, error)
+}
+
+type gormWebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a webhook repository with a GORM connection.
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &gormWebhookRepository{db: db}
+}
+
+func (r *gormWebhookRepository) CreateSubscription(sub *model.WebhookSubscription) error {
+	return r.db.Create(sub).Error
+}
+
+func (r *gormWebhookRepository) FindSubscriptionByID(id uint) (*model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	if err := r.db.First(&sub, id).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *gormWebhookRepository) ListSubscriptions() ([]model.WebhookSubscription, error) {
+	var subs []model.WebhookSubscription
+	err := r.db.Order("id").Find(&subs).Error
+	return subs, err
+}
+
+func (r *gormWebhookRepository) DeleteSubscription(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&model.WebhookSubscription{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *gormWebhookRepository) EnqueueDeliveries(deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(&deliveries).Error
+}
+
+func (r *gormWebhookRepository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("status = ? AND next_attempt_at <= ?", model.WebhookPending, now).Order("next_attempt_at").Limit(limit)
+		if tx.Dialector.Name() == "postgres" {
+			// SKIP LOCKED lets several server instances dispatch in parallel.
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		if err := query.Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+		}
+		return tx.Model(&model.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return deliveries, err
+}
+
+func (r *gormWebhookRepository) SaveDeliveryAttempt(delivery *model.WebhookDelivery) error {
+	return r.db.Model(&model.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":           delivery.Status,
+		"attempts":         delivery.Attempts,
+		"next_attempt_at":  delivery.NextAttemptAt,
+		"last_error":       delivery.LastError,
+		"last_status_code": delivery.LastStatusCode,
+		"delivered_at":     delivery.DeliveredAt,
+		"updated_at":       time.Now(),
+	}).Error
+}
+
+func (r *gormWebhookRepository) FindDeliveries(filter model.WebhookDeliveryFilter) ([]model.WebhookDelivery, error) {
+	query := r.db.Order("id DESC")
+	if filter.SubscriptionID != 0 {
+		query = query.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var deliveries []model.WebhookDelivery
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *gormWebhookRepository) ResetDeliveries(ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	result := r.db.Model(&model.WebhookDelivery{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":          model.WebhookPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"last_error":      "",
+		"delivered_at":    nil,
+		"updated_at":      now,
+	})
+	return result.RowsAffected, result.Error
+}
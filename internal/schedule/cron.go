@@ -0,0 +1,198 @@
+// internal/schedule/cron.go
+
+// Package schedule parses the cron expressions of scheduled fleet jobs and
+// works out when they run next.
+//
+// Expressions have the five standard fields: minute, hour, day of month,
+// month and day of week. Each field takes lists ("1,15"), ranges ("1-5"),
+// steps ("*/10", "8-18/2") and, for months and weekdays, names ("jan",
+// "mon-fri"); Sunday is 0 or 7. As in Vixie cron, when both the day of month
+// and the day of week are restricted, a day matches if either one does. The
+// macros @yearly, @monthly, @weekly, @daily and @hourly are accepted too.
+//
+// Times are matched in the location of the time passed to Next, so a job
+// scheduled for 02:00 in a zone that skips that hour for daylight saving
+// time does not run that day, and one scheduled in the hour that repeats
+// when clocks go back runs only the first time.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression.
+type Cron struct {
+	expr                          string
+	minute, hour, dom, month, dow uint64 // one bit per allowed value
+	domRestricted, dowRestricted  bool
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// Parse parses a five-field cron expression or a macro.
+func Parse(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(spec)]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	c := &Cron{expr: strings.TrimSpace(expr)}
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if c.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if c.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1 // 7 is Sunday too
+	}
+	c.domRestricted = !strings.HasPrefix(fields[2], "*")
+	c.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// parseField returns the values a field allows as a bit set.
+func parseField(field string, lo, hi int, names map[string]int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		span, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			span, step = part[:i], n
+		}
+
+		first, last := lo, hi
+		switch {
+		case span == "*":
+		case strings.Contains(span, "-"):
+			i := strings.IndexByte(span, '-')
+			var err error
+			if first, err = fieldValue(span[:i], names); err != nil {
+				return 0, err
+			}
+			if last, err = fieldValue(span[i+1:], names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := fieldValue(span, names)
+			if err != nil {
+				return 0, err
+			}
+			// "5/15" means from 5 to the end in steps of 15.
+			first = v
+			if step == 1 {
+				last = v
+			}
+		}
+		if first > last {
+			return 0, fmt.Errorf("%q is not a valid range", part)
+		}
+		if first < lo || last > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, lo, hi)
+		}
+		for v := first; v <= last; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func fieldValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// String returns the expression as it was parsed.
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Next returns the first matching minute after t, in t's location. It
+// returns the zero time if nothing matches within five years, as for
+// "0 0 30 2 *".
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	after := wallClock(t)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case !has(c.month, int(m)):
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case !has(c.hour, t.Hour()):
+			// Step to the top of the next hour by elapsed time: time.Date
+			// may pick either occurrence of an hour that repeats as clocks
+			// go back, and the first one must not be passed over.
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case !has(c.minute, t.Minute()), !wallClock(t).After(after):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom, dow := has(c.dom, t.Day()), has(c.dow, int(t.Weekday()))
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<v) != 0
+}
+
+// wallClock returns the time shown on a clock in t's location, so that the
+// repeated hour when clocks go back compares as earlier.
+func wallClock(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
@@ -0,0 +1,53 @@
+package schedule_test
+
+import (
+	"agent_server/internal/schedule"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 3, 1, 10, 7, 30, 0, time.UTC), time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17/4 * * mon-fri", time.Date(2026, 3, 6, 17, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+
+		// Day of month and day of week: either one matching is enough.
+		{"0 0 13 * 5", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * sun", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+
+		// 02:30 does not exist in Berlin on 29 March 2026 and happens twice on
+		// 25 October 2026.
+		{"30 2 * * *", time.Date(2026, 3, 28, 3, 0, 0, 0, berlin), time.Date(2026, 3, 30, 2, 30, 0, 0, berlin)},
+		{"30 2 * * *", time.Date(2026, 10, 25, 0, 0, 0, 0, berlin), time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC).In(berlin), time.Date(2026, 10, 26, 2, 30, 0, 0, berlin)},
+
+		{"0 0 30 2 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := schedule.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := c.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "@every"} {
+		if _, err := schedule.Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// internal/scripts/library.go
+
+// Package scripts loads the server-side library of scripts that RunScript
+// commands can reference by name, and decides which scripts run without an
+// operator's approval.
+//
+// The library is a directory; every regular file in it is one script, named
+// by its file name:
+//
+//	scripts/
+//	  collect-logs.sh
+//	  restart-service.ps1
+//
+// The interpreter of a library script follows from its extension (see
+// InterpreterFor) unless the command names one.
+//
+// The allowlist holds library script names and "sha256:<hex>" digests of
+// script bodies. A script matching an entry runs as soon as the agent polls;
+// any other script waits for approval.
+package scripts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Interpreters an agent can run a script with.
+const (
+	InterpreterShell      = "sh"
+	InterpreterBash       = "bash"
+	InterpreterPowerShell = "powershell"
+	InterpreterCmd        = "cmd"
+	InterpreterPython     = "python"
+)
+
+// Interpreters lists the supported interpreters.
+var Interpreters = []string{InterpreterShell, InterpreterBash, InterpreterPowerShell, InterpreterCmd, InterpreterPython}
+
+var extensionInterpreters = map[string]string{
+	".sh":   InterpreterShell,
+	".bash": InterpreterBash,
+	".ps1":  InterpreterPowerShell,
+	".bat":  InterpreterCmd,
+	".cmd":  InterpreterCmd,
+	".py":   InterpreterPython,
+}
+
+// Script is a script body with the name it has in the library, if any.
+type Script struct {
+	Name        string // empty for inline scripts
+	Interpreter string
+	Body        string
+	Digest      string // "sha256:<hex>" of Body
+}
+
+// Library is a loaded script library. The zero value is an empty library.
+type Library struct {
+	scripts map[string]*Script
+}
+
+// LoadLibrary reads every regular file in dir as a script. An empty dir
+// gives an empty library.
+func LoadLibrary(dir string) (*Library, error) {
+	lib := &Library{scripts: map[string]*Script{}}
+	if dir == "" {
+		return lib, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		body := string(data)
+		lib.scripts[e.Name()] = &Script{
+			Name:        e.Name(),
+			Interpreter: InterpreterFor(e.Name()),
+			Body:        body,
+			Digest:      Digest(body),
+		}
+	}
+	return lib, nil
+}
+
+// Lookup returns the library script with the given name.
+func (l *Library) Lookup(name string) (*Script, bool) {
+	if l == nil {
+		return nil, false
+	}
+	s, ok := l.scripts[name]
+	return s, ok
+}
+
+// Scripts returns the library scripts by name.
+func (l *Library) Scripts() []*Script {
+	if l == nil {
+		return nil
+	}
+	out := make([]*Script, 0, len(l.scripts))
+	for _, s := range l.scripts {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// InterpreterFor returns the interpreter for a script file name from its
+// extension, or "" if the extension is unknown.
+func InterpreterFor(name string) string {
+	return extensionInterpreters[strings.ToLower(filepath.Ext(name))]
+}
+
+// ValidInterpreter reports whether name is one of Interpreters.
+func ValidInterpreter(name string) bool {
+	for _, i := range Interpreters {
+		if i == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Digest returns the "sha256:<hex>" digest of a script body.
+func Digest(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Allowed reports whether the allowlist names the library script or lists
+// its digest.
+func Allowed(allowlist []string, s *Script) bool {
+	for _, entry := range allowlist {
+		if entry == s.Digest || s.Name != "" && entry == s.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateAllowlist checks that every entry is a script name or a
+// well-formed sha256 digest.
+func ValidateAllowlist(allowlist []string) error {
+	for _, entry := range allowlist {
+		if digest, ok := strings.CutPrefix(entry, "sha256:"); ok {
+			if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+				return fmt.Errorf("invalid script digest %q", entry)
+			}
+			continue
+		}
+		if entry == "" || strings.ContainsAny(entry, `/\`) {
+			return fmt.Errorf("invalid script name %q in allowlist", entry)
+		}
+	}
+	return nil
+}
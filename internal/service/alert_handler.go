@@ -0,0 +1,46 @@
+// internal/service/alert_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *AgentServer) ListAlertRules(ctx context.Context, req *pb.ListAlertRulesRequest) (*pb.ListAlertRulesResponse, error) {
+	resp := &pb.ListAlertRulesResponse{}
+	for _, r := range s.alertLogic.Rules() {
+		resp.Rules = append(resp.Rules, mapModelToProtoAlertRule(r))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) ListAlerts(ctx context.Context, req *pb.ListAlertsRequest) (*pb.ListAlertsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	filter := model.AlertFilter{RuleID: req.GetRuleId(), AgentID: req.GetAgentId(), Limit: limit}
+	for _, state := range req.GetStates() {
+		if state != pb.AlertState_ALERT_STATE_UNKNOWN {
+			filter.States = append(filter.States, mapProtoToModelAlertState(state))
+		}
+	}
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+
+	alerts, err := s.alertLogic.ListAlerts(filter, mapProtoToModelSeverity(req.GetMinSeverity()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListAlertsResponse{}
+	for i := range alerts {
+		resp.Alerts = append(resp.Alerts, mapModelToProtoAlert(&alerts[i]))
+	}
+	return resp, nil
+}
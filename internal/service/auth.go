@@ -0,0 +1,137 @@
+// internal/service/auth.go
+
+package service
+
+import (
+	"agent_server/internal/config"
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// adminMethods are the RPCs used by operators and tools rather than agents.
+// They require the admin token when one is configured.
+var adminMethods = map[string]bool{
+	"/proto.AgentService/ConfigureFirewall": true,
+	"/proto.AgentService/ListAgents":        true,
+	"/proto.AgentService/DecommissionAgent": true,
+	"/proto.AgentService/GetFirewallRules":  true,
+	"/proto.AgentService/GetInstalledApps":  true,
+	"/proto.AgentService/ListCommands":      true,
+	"/proto.AgentService/CancelCommand":     true,
+
+	"/proto.AgentService/CreateWebhookSubscription": true,
+	"/proto.AgentService/ListWebhookSubscriptions":  true,
+	"/proto.AgentService/DeleteWebhookSubscription": true,
+	"/proto.AgentService/ListWebhookDeliveries":     true,
+	"/proto.AgentService/ReplayWebhookDeliveries":   true,
+
+	"/proto.AgentService/WatchEvents": true,
+
+	"/proto.AgentService/ImportVulnerabilityFeed":   true,
+	"/proto.AgentService/ListVulnerabilityFindings": true,
+	"/proto.AgentService/ListFleetVulnerabilities":  true,
+
+	"/proto.AgentService/ListSoftwareCatalog":  true,
+	"/proto.AgentService/ListSoftwareInstalls": true,
+
+	"/proto.AgentService/ListFirewallFindings": true,
+
+	"/proto.AgentService/ListCompliancePolicies": true,
+	"/proto.AgentService/EvaluateCompliance":     true,
+	"/proto.AgentService/ListComplianceResults":  true,
+	"/proto.AgentService/GetComplianceHistory":   true,
+	"/proto.AgentService/GetComplianceReport":    true,
+
+	"/proto.AgentService/ExportFleetData":       true,
+	"/proto.AgentService/GetAvailabilityReport": true,
+	"/proto.AgentService/ListAgentIPHistory":    true,
+	"/proto.AgentService/ListSuspectedClones":   true,
+	"/proto.AgentService/ClearSuspectedClone":   true,
+	"/proto.AgentService/GetAgentMetrics":       true,
+
+	"/proto.AgentService/ListAlertRules": true,
+	"/proto.AgentService/ListAlerts":     true,
+
+	"/proto.AgentService/GetNetworkInterfaces": true,
+	"/proto.AgentService/GetLocalUsers":        true,
+	"/proto.AgentService/GetServices":          true,
+	"/proto.AgentService/GetListeningSockets":  true,
+	"/proto.AgentService/GetProcesses":         true,
+
+	"/proto.AgentService/GetPatchStatus":  true,
+	"/proto.AgentService/ListPatchStatus": true,
+
+	"/proto.AgentService/RunScript":        true,
+	"/proto.AgentService/ListScripts":      true,
+	"/proto.AgentService/ApproveCommand":   true,
+	"/proto.AgentService/GetCommandOutput": true,
+
+	"/proto.AgentService/UploadAgentPackage": true,
+	"/proto.AgentService/ListAgentPackages":  true,
+	"/proto.AgentService/CreateRollout":      true,
+	"/proto.AgentService/UpdateRollout":      true,
+	"/proto.AgentService/ListRollouts":       true,
+	"/proto.AgentService/ListAgentUpdates":   true,
+
+	"/proto.AgentService/CollectFile":           true,
+	"/proto.AgentService/ListFileCollections":   true,
+	"/proto.AgentService/DownloadCollectedFile": true,
+
+	"/proto.AgentService/CreateScheduledJob": true,
+	"/proto.AgentService/UpdateScheduledJob": true,
+	"/proto.AgentService/DeleteScheduledJob": true,
+	"/proto.AgentService/ListScheduledJobs":  true,
+	"/proto.AgentService/RunScheduledJob":    true,
+	"/proto.AgentService/ListJobRuns":        true,
+	"/proto.AgentService/GetJobRun":          true,
+}
+
+// AdminAuthUnaryInterceptor checks the "authorization: Bearer <token>" metadata
+// on admin RPCs against auth.admin_token. An empty token disables the check.
+func AdminAuthUnaryInterceptor(cfg config.Provider) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if adminMethods[info.FullMethod] {
+			if err := checkAdminToken(ctx, cfg.Current().Auth.AdminToken); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AdminAuthStreamInterceptor applies the same check to streaming admin RPCs.
+func AdminAuthStreamInterceptor(cfg config.Provider) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if adminMethods[info.FullMethod] {
+			if err := checkAdminToken(ss.Context(), cfg.Current().Auth.AdminToken); err != nil {
+				return err
+			}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// IsAdminMethod reports whether the full gRPC method name is an admin RPC.
+func IsAdminMethod(fullMethod string) bool {
+	return adminMethods[fullMethod]
+}
+
+func checkAdminToken(ctx context.Context, want string) error {
+	if want == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token := strings.TrimPrefix(v, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			return nil
+		}
+	}
+	return status.Errorf(codes.Unauthenticated, "a valid admin token is required")
+}
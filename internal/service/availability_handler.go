@@ -0,0 +1,54 @@
+// internal/service/availability_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *AgentServer) GetAvailabilityReport(ctx context.Context, req *pb.GetAvailabilityReportRequest) (*pb.GetAvailabilityReportResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	filter := usecase.AvailabilityFilter{
+		AgentID: req.GetAgentId(),
+		Labels:  model.Labels(req.GetLabels()),
+		GroupBy: req.GetGroupBy(),
+		Limit:   limit,
+	}
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		filter.Until = req.GetUntil().AsTime()
+	}
+
+	report, err := s.availabilityLogic.Report(filter)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidWindow) {
+			return nil, status.Errorf(codes.InvalidArgument, "Until must be after since")
+		}
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.GetAvailabilityReportResponse{
+		Since: timestamppb.New(report.Since),
+		Until: timestamppb.New(report.Until),
+		Fleet: mapModelToProtoGroupAvailability(&report.Fleet),
+	}
+	for i := range report.Groups {
+		resp.Groups = append(resp.Groups, mapModelToProtoGroupAvailability(&report.Groups[i]))
+	}
+	for i := range report.Agents {
+		resp.Agents = append(resp.Agents, mapModelToProtoAgentAvailability(&report.Agents[i]))
+	}
+	return resp, nil
+}
@@ -0,0 +1,43 @@
+// internal/service/clone_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+func (s *AgentServer) ListSuspectedClones(ctx context.Context, req *pb.ListSuspectedClonesRequest) (*pb.ListSuspectedClonesResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	agents, err := s.cloneLogic.ListSuspected(limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListSuspectedClonesResponse{Agents: make([]*pb.Agent, 0, len(agents))}
+	for i := range agents {
+		resp.Agents = append(resp.Agents, mapModelToProtoAgent(&agents[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) ClearSuspectedClone(ctx context.Context, req *pb.ClearSuspectedCloneRequest) (*pb.ClearSuspectedCloneResponse, error) {
+	if req.GetAgentId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID is required")
+	}
+	agent, err := s.cloneLogic.Clear(req.GetAgentId())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Agent not found")
+		}
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	return &pb.ClearSuspectedCloneResponse{Agent: mapModelToProtoAgent(agent)}, nil
+}
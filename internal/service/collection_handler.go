@@ -0,0 +1,221 @@
+// internal/service/collection_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/artifacts"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+// collectedFileChunkSize is the size of the CollectedFileChunk messages of a
+// download.
+const collectedFileChunkSize = 64 << 10
+
+// CollectFile queues a COLLECT_FILE command asking the agent to upload a
+// file, and records the collection that tracks the upload.
+func (s *AgentServer) CollectFile(ctx context.Context, req *pb.CollectFileRequest) (*pb.CollectFileResponse, error) {
+	if req.GetAgentId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID is required")
+	}
+	prepared, err := s.collectionLogic.PrepareRequest(usecase.CollectionRequest{
+		AgentID:  req.GetAgentId(),
+		Path:     req.GetPath(),
+		Reason:   req.GetReason(),
+		MaxBytes: req.GetMaxBytes(),
+	})
+	if err != nil {
+		return nil, collectionError(err, "Could not prepare file collection")
+	}
+
+	payload, err := proto.Marshal(&pb.CollectFileRequest{
+		AgentId:  prepared.AgentID,
+		Path:     prepared.Path,
+		MaxBytes: prepared.MaxBytes,
+		Reason:   prepared.Reason,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not encode command")
+	}
+	cmd, err := s.commandLogic.EnqueueCommand(req.GetAgentId(), model.CommandTypeCollectFile, payload)
+	if err != nil {
+		log.Printf("Failed to queue file collection for agent %s: %v", req.GetAgentId(), err)
+		return nil, commandError(err, "Could not queue file collection")
+	}
+	collection, err := s.collectionLogic.Track(cmd, prepared)
+	if err != nil {
+		log.Printf("Failed to record file collection of command %d: %v", cmd.ID, err)
+		if _, cancelErr := s.commandLogic.CancelCommand(cmd.ID); cancelErr != nil {
+			log.Printf("Failed to cancel command %d: %v", cmd.ID, cancelErr)
+		}
+		return nil, status.Errorf(codes.Internal, "Could not record file collection")
+	}
+
+	return &pb.CollectFileResponse{
+		Success:      true,
+		Message:      fmt.Sprintf("File collection %d queued as command %d", collection.ID, cmd.ID),
+		CommandId:    uint64(cmd.ID),
+		CollectionId: uint64(collection.ID),
+	}, nil
+}
+
+func (s *AgentServer) ListFileCollections(ctx context.Context, req *pb.ListFileCollectionsRequest) (*pb.ListFileCollectionsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	collections, err := s.collectionLogic.ListCollections(model.FileCollectionFilter{
+		AgentID: req.GetAgentId(),
+		Status:  mapProtoToModelCollectionStatus(req.GetStatus()),
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListFileCollectionsResponse{}
+	for i := range collections {
+		resp.Collections = append(resp.Collections, mapModelToProtoFileCollection(&collections[i]))
+	}
+	return resp, nil
+}
+
+// GetFileUploadStatus tells an agent where to resume an interrupted upload.
+func (s *AgentServer) GetFileUploadStatus(ctx context.Context, req *pb.FileUploadStatusRequest) (*pb.FileUploadStatusResponse, error) {
+	if req.GetAgentId() == "" || req.GetCommandId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID and command ID are required")
+	}
+	c, err := s.collectionLogic.UploadStatus(req.GetAgentId(), uint(req.GetCommandId()))
+	if err != nil {
+		return nil, collectionError(err, "Could not load upload status")
+	}
+	return &pb.FileUploadStatusResponse{ReceivedBytes: c.ReceivedBytes, Status: mapModelToProtoCollectionStatus(c.Status)}, nil
+}
+
+// UploadCollectedFile stores a file an agent was asked for. The first
+// message is the header; the rest carry the file from the header's offset.
+// The agent may end the stream early and resume later from the offset
+// GetFileUploadStatus returns.
+func (s *AgentServer) UploadCollectedFile(stream pb.AgentService_UploadCollectedFileServer) error {
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Errorf(codes.InvalidArgument, "Upload header is required")
+	}
+	if err != nil {
+		return err
+	}
+	header := first.GetHeader()
+	if header == nil {
+		return status.Errorf(codes.InvalidArgument, "The first message must carry the upload header")
+	}
+	if header.GetAgentId() == "" || header.GetCommandId() == 0 {
+		return status.Errorf(codes.InvalidArgument, "Agent ID and command ID are required")
+	}
+
+	upload := usecase.UploadHeader{Offset: header.GetOffset(), Size: header.GetSizeBytes(), SHA256: header.GetSha256()}
+	c, err := s.collectionLogic.Upload(stream.Context(), header.GetAgentId(), uint(header.GetCommandId()), upload, &collectedFileReader{stream: stream})
+	if err != nil {
+		var recvErr uploadRecvError
+		if errors.As(err, &recvErr) {
+			return recvErr.err
+		}
+		log.Printf("Failed to store upload of command %d from agent %s: %v", header.GetCommandId(), header.GetAgentId(), err)
+		return collectionError(err, "Could not store file")
+	}
+	return stream.SendAndClose(&pb.UploadCollectedFileResponse{
+		ReceivedBytes: c.ReceivedBytes,
+		Status:        mapModelToProtoCollectionStatus(c.Status),
+		Message:       c.Message,
+	})
+}
+
+// collectedFileReader reads the content of an UploadCollectedFile stream.
+type collectedFileReader struct {
+	stream pb.AgentService_UploadCollectedFileServer
+	buf    []byte
+}
+
+func (r *collectedFileReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		msg, err := r.stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, uploadRecvError{err}
+		}
+		if msg.GetHeader() != nil {
+			return 0, uploadRecvError{status.Errorf(codes.InvalidArgument, "The upload header must only be sent first")}
+		}
+		r.buf = msg.GetData()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// DownloadCollectedFile streams a collected file, from the offset of an
+// interrupted download if one is given. The first chunk carries the size and
+// SHA-256 of the whole file.
+func (s *AgentServer) DownloadCollectedFile(req *pb.DownloadCollectedFileRequest, stream pb.AgentService_DownloadCollectedFileServer) error {
+	if req.GetCollectionId() == 0 {
+		return status.Errorf(codes.InvalidArgument, "Collection ID is required")
+	}
+	c, content, err := s.collectionLogic.OpenCollection(stream.Context(), uint(req.GetCollectionId()), req.GetOffset())
+	if err != nil {
+		return collectionError(err, "Could not read collected file")
+	}
+	defer content.Close()
+
+	offset := req.GetOffset()
+	buf := make([]byte, collectedFileChunkSize)
+	first := true
+	for {
+		n, err := io.ReadFull(content, buf)
+		if n > 0 || first {
+			chunk := &pb.CollectedFileChunk{Offset: offset, Data: buf[:n]}
+			if first {
+				chunk.Sha256, chunk.SizeBytes = c.SHA256, c.SizeBytes
+				first = false
+			}
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+			offset += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			log.Printf("Failed to read collected file %d: %v", c.ID, err)
+			return status.Errorf(codes.Internal, "Could not read collected file")
+		}
+	}
+	log.Printf("Collected file %d (%s from agent %s) downloaded from offset %d.", c.ID, c.Path, c.AgentID, req.GetOffset())
+	return nil
+}
+
+// collectionError converts a CollectionUseCase error to a gRPC status.
+func collectionError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, artifacts.ErrNotFound):
+		return status.Errorf(codes.NotFound, "Collection not found")
+	case errors.Is(err, usecase.ErrInvalidCollection), errors.Is(err, usecase.ErrInvalidUpload):
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	case errors.Is(err, usecase.ErrUploadOffset), errors.Is(err, usecase.ErrCollectionClosed), errors.Is(err, usecase.ErrCollectionNotReady):
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	case errors.Is(err, usecase.ErrUploadInProgress):
+		return status.Errorf(codes.Aborted, "%v", err)
+	}
+	return status.Errorf(codes.Internal, "%s", fallback)
+}
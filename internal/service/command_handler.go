@@ -0,0 +1,253 @@
+// internal/service/command_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+// ConfigureFirewall queues a firewall change for the agent. The agent applies
+// it after its next PollCommands and reports back with ReportCommandResult.
+func (s *AgentServer) ConfigureFirewall(ctx context.Context, req *pb.FirewallConfigurationRequest) (*pb.FirewallConfigurationResponse, error) {
+	if req.GetAgentId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID is required")
+	}
+	if err := validateFirewallOperation(req); err != nil {
+		return nil, err
+	}
+
+	payload, err := proto.Marshal(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not encode command")
+	}
+
+	cmd, err := s.commandLogic.EnqueueCommand(req.GetAgentId(), model.CommandTypeFirewallConfiguration, payload)
+	if err != nil {
+		log.Printf("Failed to queue firewall command for agent %s: %v", req.GetAgentId(), err)
+		return nil, commandError(err, "Could not queue firewall command")
+	}
+
+	return &pb.FirewallConfigurationResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("Firewall command %d queued", cmd.ID),
+		CommandId: uint64(cmd.ID),
+	}, nil
+}
+
+// validateFirewallOperation checks a firewall command before it is queued.
+func validateFirewallOperation(req *pb.FirewallConfigurationRequest) error {
+	if req.GetOperationType() == nil {
+		return status.Errorf(codes.InvalidArgument, "A firewall operation is required")
+	}
+	if add := req.GetAddRule(); add != nil && add.GetRule().GetName() == "" {
+		return status.Errorf(codes.InvalidArgument, "The new rule needs a name")
+	}
+	return nil
+}
+
+func (s *AgentServer) PollCommands(ctx context.Context, req *pb.PollCommandsRequest) (*pb.PollCommandsResponse, error) {
+	if req.GetAgentId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID is required")
+	}
+
+	cmds, err := s.commandLogic.PollCommands(req.GetAgentId())
+	if err != nil {
+		log.Printf("Failed to poll commands for agent %s: %v", req.GetAgentId(), err)
+		return nil, commandError(err, "Could not load commands")
+	}
+
+	resp := &pb.PollCommandsResponse{}
+	for i := range cmds {
+		p, err := mapModelToProtoCommand(&cmds[i])
+		if err != nil {
+			log.Printf("Command %d has a corrupt payload: %v", cmds[i].ID, err)
+			continue
+		}
+		resp.Commands = append(resp.Commands, p)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) ReportCommandResult(ctx context.Context, req *pb.CommandResultRequest) (*pb.CommandResultResponse, error) {
+	if req.GetAgentId() == "" || req.GetCommandId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID and command ID are required")
+	}
+
+	var exitCode *int32
+	if req.ExitCode != nil {
+		code := req.GetExitCode()
+		exitCode = &code
+	}
+	err := s.commandLogic.CompleteCommand(req.GetAgentId(), uint(req.GetCommandId()), req.GetSuccess(), req.GetMessage(), exitCode)
+	if err != nil {
+		log.Printf("Failed to record result of command %d for agent %s: %v", req.GetCommandId(), req.GetAgentId(), err)
+		return nil, commandError(err, "Could not record command result")
+	}
+	if !req.GetSuccess() {
+		if err := s.collectionLogic.CommandFailed(req.GetAgentId(), uint(req.GetCommandId()), req.GetMessage()); err != nil {
+			log.Printf("Failed to fail the file collection of command %d: %v", req.GetCommandId(), err)
+		}
+	}
+	return &pb.CommandResultResponse{Acknowledged: true}, nil
+}
+
+func (s *AgentServer) ListCommands(ctx context.Context, req *pb.ListCommandsRequest) (*pb.ListCommandsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+
+	cmds, err := s.commandLogic.ListCommands(model.CommandFilter{
+		AgentID: req.GetAgentId(),
+		Status:  mapProtoToModelCommandStatus(req.GetStatus()),
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+
+	resp := &pb.ListCommandsResponse{}
+	for i := range cmds {
+		p, err := mapModelToProtoCommand(&cmds[i])
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Command %d has a corrupt payload", cmds[i].ID)
+		}
+		resp.Commands = append(resp.Commands, p)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) CancelCommand(ctx context.Context, req *pb.CancelCommandRequest) (*pb.CancelCommandResponse, error) {
+	if req.GetCommandId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Command ID is required")
+	}
+
+	cmd, err := s.commandLogic.CancelCommand(uint(req.GetCommandId()))
+	if err != nil {
+		return nil, commandError(err, "Could not cancel command")
+	}
+	if err := s.collectionLogic.CommandFailed(cmd.AgentID, cmd.ID, "the command was cancelled"); err != nil {
+		log.Printf("Failed to fail the file collection of command %d: %v", cmd.ID, err)
+	}
+	p, err := mapModelToProtoCommand(cmd)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Command %d has a corrupt payload", cmd.ID)
+	}
+	return &pb.CancelCommandResponse{Command: p}, nil
+}
+
+// StreamCommandOutput stores the output an agent streams while it runs a
+// script, up to scripts.max_output_bytes per command. Output past the cap is
+// read and dropped, and the response tells the agent to stop sending.
+func (s *AgentServer) StreamCommandOutput(stream pb.AgentService_StreamCommandOutputServer) error {
+	var (
+		agentID   string
+		commandID uint64
+		stored    uint64
+		truncated bool
+	)
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if agentID == "" {
+			agentID, commandID = chunk.GetAgentId(), chunk.GetCommandId()
+			if agentID == "" || commandID == 0 {
+				return status.Errorf(codes.InvalidArgument, "Agent ID and command ID are required")
+			}
+		}
+		if chunk.GetAgentId() != agentID || chunk.GetCommandId() != commandID {
+			return status.Errorf(codes.InvalidArgument, "All chunks must belong to one command")
+		}
+		outputStream := mapProtoToModelOutputStream(chunk.GetStream())
+		if outputStream == "" {
+			return status.Errorf(codes.InvalidArgument, "Output stream must be stdout or stderr")
+		}
+		if truncated || len(chunk.GetData()) == 0 {
+			continue
+		}
+
+		maxBytes := s.cfg.Current().Scripts.MaxOutputBytes
+		out := []model.CommandOutputChunk{{Stream: outputStream, Data: chunk.GetData()}}
+		dropped, err := s.commandLogic.AppendCommandOutput(agentID, uint(commandID), out, maxBytes)
+		if err != nil {
+			log.Printf("Failed to store output of command %d for agent %s: %v", commandID, agentID, err)
+			return commandError(err, "Could not store command output")
+		}
+		truncated = dropped
+		stored += uint64(len(chunk.GetData()))
+		if dropped {
+			stored = uint64(maxBytes)
+		}
+	}
+	return stream.SendAndClose(&pb.StreamCommandOutputResponse{StoredBytes: stored, Truncated: truncated})
+}
+
+func (s *AgentServer) ApproveCommand(ctx context.Context, req *pb.ApproveCommandRequest) (*pb.ApproveCommandResponse, error) {
+	if req.GetCommandId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Command ID is required")
+	}
+
+	cmd, err := s.commandLogic.ApproveCommand(uint(req.GetCommandId()))
+	if err != nil {
+		return nil, commandError(err, "Could not approve command")
+	}
+	p, err := mapModelToProtoCommand(cmd)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Command %d has a corrupt payload", cmd.ID)
+	}
+	return &pb.ApproveCommandResponse{Command: p}, nil
+}
+
+func (s *AgentServer) GetCommandOutput(ctx context.Context, req *pb.GetCommandOutputRequest) (*pb.GetCommandOutputResponse, error) {
+	if req.GetCommandId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Command ID is required")
+	}
+
+	cmd, chunks, err := s.commandLogic.GetCommandOutput(uint(req.GetCommandId()))
+	if err != nil {
+		return nil, commandError(err, "Could not load command output")
+	}
+	p, err := mapModelToProtoCommand(cmd)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Command %d has a corrupt payload", cmd.ID)
+	}
+	resp := &pb.GetCommandOutputResponse{Command: p}
+	for _, c := range chunks {
+		if c.Stream == model.OutputStderr {
+			resp.Stderr = append(resp.Stderr, c.Data...)
+		} else {
+			resp.Stdout = append(resp.Stdout, c.Data...)
+		}
+	}
+	return resp, nil
+}
+
+// commandError maps use case errors to gRPC status codes.
+func commandError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Errorf(codes.NotFound, "Agent or command not found")
+	case errors.Is(err, usecase.ErrInvalidCommandState):
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	case errors.Is(err, usecase.ErrAgentDecommissioned):
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+	return status.Errorf(codes.Internal, "%s", fallback)
+}
@@ -0,0 +1,138 @@
+// internal/service/compliance_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+func (s *AgentServer) ListCompliancePolicies(ctx context.Context, req *pb.ListCompliancePoliciesRequest) (*pb.ListCompliancePoliciesResponse, error) {
+	active := map[string]int{}
+	for _, p := range s.complianceLogic.Policies() {
+		active[p.PolicyID] = p.Version
+	}
+
+	var policies []model.CompliancePolicy
+	if req.GetAllVersions() {
+		if req.GetPolicyId() == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "Policy ID is required to list all versions")
+		}
+		var err error
+		if policies, err = s.complianceLogic.PolicyVersions(req.GetPolicyId()); err != nil {
+			return nil, status.Errorf(codes.Internal, "Database error")
+		}
+		if len(policies) == 0 {
+			return nil, status.Errorf(codes.NotFound, "Policy not found")
+		}
+	} else {
+		for _, p := range s.complianceLogic.Policies() {
+			if req.GetPolicyId() == "" || p.PolicyID == req.GetPolicyId() {
+				policies = append(policies, p)
+			}
+		}
+	}
+
+	resp := &pb.ListCompliancePoliciesResponse{}
+	for i := range policies {
+		p := mapModelToProtoCompliancePolicy(&policies[i], req.GetIncludeDefinition())
+		p.Active = active[p.PolicyId] == int(p.Version)
+		resp.Policies = append(resp.Policies, p)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) EvaluateCompliance(ctx context.Context, req *pb.EvaluateComplianceRequest) (*pb.EvaluateComplianceResponse, error) {
+	if req.GetAgentId() == "" {
+		n, err := s.complianceLogic.EvaluateAll()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Database error")
+		}
+		return &pb.EvaluateComplianceResponse{AgentsEvaluated: int32(n)}, nil
+	}
+
+	results, err := s.complianceLogic.EvaluateAgent(req.GetAgentId())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Agent not found")
+		}
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.EvaluateComplianceResponse{AgentsEvaluated: 1}
+	for i := range results {
+		resp.Results = append(resp.Results, mapModelToProtoComplianceResult(&results[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) ListComplianceResults(ctx context.Context, req *pb.ListComplianceResultsRequest) (*pb.ListComplianceResultsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	if req.GetAgentId() != "" {
+		if _, err := s.agentLogic.GetAgentByID(req.GetAgentId()); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, status.Errorf(codes.NotFound, "Agent not found")
+			}
+			return nil, status.Errorf(codes.Internal, "Database error")
+		}
+	}
+
+	results, err := s.complianceLogic.ListResults(model.ComplianceResultFilter{
+		AgentID:  req.GetAgentId(),
+		PolicyID: req.GetPolicyId(),
+		CheckID:  req.GetCheckId(),
+		Status:   mapProtoToModelComplianceStatus(req.GetStatus()),
+		Limit:    limit,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListComplianceResultsResponse{}
+	for i := range results {
+		resp.Results = append(resp.Results, mapModelToProtoComplianceResult(&results[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) GetComplianceHistory(ctx context.Context, req *pb.GetComplianceHistoryRequest) (*pb.GetComplianceHistoryResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	filter := model.ComplianceEvaluationFilter{AgentID: req.GetAgentId(), PolicyID: req.GetPolicyId(), Limit: limit}
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+	evaluations, err := s.complianceLogic.ListEvaluations(filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.GetComplianceHistoryResponse{}
+	for i := range evaluations {
+		resp.Evaluations = append(resp.Evaluations, mapModelToProtoComplianceEvaluation(&evaluations[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) GetComplianceReport(ctx context.Context, req *pb.GetComplianceReportRequest) (*pb.GetComplianceReportResponse, error) {
+	summaries, err := s.complianceLogic.Report(req.GetPolicyId())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Policy not loaded")
+		}
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.GetComplianceReportResponse{}
+	for i := range summaries {
+		resp.Policies = append(resp.Policies, mapModelToProtoCompliancePolicyReport(&summaries[i]))
+	}
+	return resp, nil
+}
@@ -0,0 +1,79 @@
+// internal/service/event_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/logging"
+	"agent_server/internal/usecase"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WatchEvents streams fleet events until the client disconnects or the
+// server shuts down (Unavailable). A client that falls behind is
+// disconnected with ResourceExhausted and resumes by calling again with the
+// last sequence and stream epoch it received.
+func (s *AgentServer) WatchEvents(req *pb.WatchEventsRequest, stream pb.AgentService_WatchEventsServer) error {
+	for _, t := range req.GetEventTypes() {
+		if !isStreamEventType(t) {
+			return status.Errorf(codes.InvalidArgument, "Unknown event type %q", t)
+		}
+	}
+
+	sub, err := s.events.Subscribe(usecase.EventFilter{
+		AgentIDs:   req.GetAgentIds(),
+		Labels:     req.GetLabels(),
+		EventTypes: req.GetEventTypes(),
+	}, req.GetStreamEpoch(), req.GetAfterSequence())
+	if errors.Is(err, usecase.ErrBusClosed) {
+		return status.Errorf(codes.Unavailable, "Server is shutting down")
+	}
+	if errors.Is(err, usecase.ErrSequenceUnavailable) && req.GetStreamEpoch() != s.events.Epoch() {
+		return status.Errorf(codes.OutOfRange, "Sequence %d is from another run of the server (epoch %q, current %q); reload the current state and watch from 0", req.GetAfterSequence(), req.GetStreamEpoch(), s.events.Epoch())
+	}
+	if errors.Is(err, usecase.ErrSequenceUnavailable) {
+		return status.Errorf(codes.OutOfRange, "Events after sequence %d are no longer available (latest is %d); reload the current state and watch from 0", req.GetAfterSequence(), s.events.LastSequence())
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "Could not subscribe to events")
+	}
+	defer sub.Close()
+
+	last := req.GetAfterSequence()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok && errors.Is(sub.Err(), usecase.ErrBusClosed) {
+				return status.Errorf(codes.Unavailable, "Server is shutting down; resume after sequence %d", last)
+			}
+			if !ok {
+				logging.Warnf("WatchEvents subscriber dropped after sequence %d: %v", last, sub.Err())
+				return status.Errorf(codes.ResourceExhausted, "Client fell behind the event stream; resume after sequence %d", last)
+			}
+			msg, err := mapModelToProtoFleetEvent(&event)
+			if err != nil {
+				logging.Errorf("Failed to encode event %s: %v", event.ID, err)
+				continue
+			}
+			msg.StreamEpoch = s.events.Epoch()
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+			last = event.Sequence
+		}
+	}
+}
+
+func isStreamEventType(t string) bool {
+	for _, known := range usecase.StreamEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
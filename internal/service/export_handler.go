@@ -0,0 +1,101 @@
+// internal/service/export_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/export"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"bufio"
+	"errors"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// exportChunkSize is the most file data sent in one ExportChunk, well under
+// gRPC's default 4 MiB message limit.
+const exportChunkSize = 256 << 10
+
+var exportDatasets = map[pb.ExportDataset]string{
+	pb.ExportDataset_EXPORT_AGENTS:         export.Agents,
+	pb.ExportDataset_EXPORT_FIREWALL_RULES: export.FirewallRules,
+	pb.ExportDataset_EXPORT_INSTALLED_APPS: export.InstalledApps,
+	pb.ExportDataset_EXPORT_STATUS_HISTORY: export.StatusHistory,
+
+	pb.ExportDataset_EXPORT_NETWORK_INTERFACES: export.NetworkInterfaces,
+	pb.ExportDataset_EXPORT_LOCAL_USERS:        export.LocalUsers,
+	pb.ExportDataset_EXPORT_LOCAL_GROUPS:       export.LocalGroups,
+	pb.ExportDataset_EXPORT_SERVICES:           export.Services,
+	pb.ExportDataset_EXPORT_LISTENING_SOCKETS:  export.ListeningSockets,
+	pb.ExportDataset_EXPORT_PROCESSES:          export.Processes,
+}
+
+var exportFormats = map[pb.ExportFormat]string{
+	pb.ExportFormat_EXPORT_CSV:     export.CSV,
+	pb.ExportFormat_EXPORT_JSONL:   export.JSONL,
+	pb.ExportFormat_EXPORT_PARQUET: export.Parquet,
+}
+
+// ExportFleetData streams the selected dataset as a file split into chunks.
+// The last chunk carries no data and the number of rows exported; a stream
+// that ends with an error has sent an incomplete file.
+func (s *AgentServer) ExportFleetData(req *pb.ExportFleetDataRequest, stream pb.AgentService_ExportFleetDataServer) error {
+	dataset, ok := exportDatasets[req.GetDataset()]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "Dataset is required")
+	}
+	format, ok := exportFormats[req.GetFormat()]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "Format is required")
+	}
+
+	filter := model.ExportFilter{AgentID: req.GetAgentId(), Labels: req.GetLabels()}
+	if req.GetStatus() != pb.AgentStatus_UNKNOWN {
+		filter.Status = req.GetStatus().String()
+	}
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		filter.Until = req.GetUntil().AsTime()
+	}
+
+	out := bufio.NewWriterSize(exportChunkWriter{stream}, exportChunkSize)
+	rows, err := s.exportLogic.Export(usecase.ExportRequest{Dataset: dataset, Format: format, Filter: filter}, out)
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidExport) {
+			return status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		if stream.Context().Err() != nil {
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+		log.Printf("Failed to export %s after %d rows: %v", dataset, rows, err)
+		return status.Errorf(codes.Internal, "Could not export %s", dataset)
+	}
+	return stream.Send(&pb.ExportChunk{Rows: rows})
+}
+
+// exportChunkWriter sends everything written to it as ExportChunk messages
+// of at most exportChunkSize bytes.
+type exportChunkWriter struct {
+	stream pb.AgentService_ExportFleetDataServer
+}
+
+func (w exportChunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), exportChunkSize)
+		if err := w.stream.Send(&pb.ExportChunk{Data: p[:n]}); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
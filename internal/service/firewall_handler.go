@@ -0,0 +1,39 @@
+// internal/service/firewall_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+func (s *AgentServer) ListFirewallFindings(ctx context.Context, req *pb.ListFirewallFindingsRequest) (*pb.ListFirewallFindingsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	if req.GetAgentId() != "" {
+		if _, err := s.agentLogic.GetAgentByID(req.GetAgentId()); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, status.Errorf(codes.NotFound, "Agent not found")
+			}
+			return nil, status.Errorf(codes.Internal, "Database error")
+		}
+	}
+
+	findings, err := s.firewallLogic.ListFindings(req.GetAgentId(), mapProtoToModelFirewallFindingKind(req.GetKind()),
+		mapProtoToModelSeverity(req.GetMinSeverity()), limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListFirewallFindingsResponse{}
+	for i := range findings {
+		resp.Findings = append(resp.Findings, mapModelToProtoFirewallFinding(&findings[i]))
+	}
+	return resp, nil
+}
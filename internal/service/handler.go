@@ -3,6 +3,8 @@
 package service
 
 import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
 	"agent_server/internal/usecase"
 	pb "agent_server/agent_server/proto"
 	"context"
@@ -14,19 +16,33 @@ import (
 	"gorm.io/gorm"
 )
 
-const (
-	reportIntervalSeconds = 300 // 5 minutes
-)
-
-
 type AgentServer struct {
 	pb.UnimplementedAgentServiceServer
-	agentLogic usecase.AgentUseCase
+	agentLogic        usecase.AgentUseCase
+	commandLogic      usecase.CommandUseCase
+	webhookLogic      usecase.WebhookUseCase
+	vulnLogic         usecase.VulnerabilityUseCase
+	softwareLogic     usecase.SoftwareUseCase
+	firewallLogic     usecase.FirewallUseCase
+	complianceLogic   usecase.ComplianceUseCase
+	exportLogic       usecase.ExportUseCase
+	availabilityLogic usecase.AvailabilityUseCase
+	cloneLogic        usecase.CloneUseCase
+	metricsLogic      usecase.MetricsUseCase
+	alertLogic        usecase.AlertUseCase
+	inventoryLogic    usecase.InventoryUseCase
+	patchLogic        usecase.PatchUseCase
+	scriptLogic       usecase.ScriptUseCase
+	updateLogic       usecase.UpdateUseCase
+	collectionLogic   usecase.CollectionUseCase
+	jobLogic          usecase.JobUseCase
+	events            *usecase.EventBus
+	cfg               config.Provider
 }
 
 
-func NewAgentServer(logic usecase.AgentUseCase) *AgentServer {
-	return &AgentServer{agentLogic: logic}
+func NewAgentServer(logic usecase.AgentUseCase, commands usecase.CommandUseCase, webhooks usecase.WebhookUseCase, vulns usecase.VulnerabilityUseCase, catalog usecase.SoftwareUseCase, firewall usecase.FirewallUseCase, compliance usecase.ComplianceUseCase, exports usecase.ExportUseCase, availability usecase.AvailabilityUseCase, clones usecase.CloneUseCase, metrics usecase.MetricsUseCase, alerts usecase.AlertUseCase, inventory usecase.InventoryUseCase, patches usecase.PatchUseCase, scripts usecase.ScriptUseCase, updates usecase.UpdateUseCase, collections usecase.CollectionUseCase, jobs usecase.JobUseCase, events *usecase.EventBus, cfg config.Provider) *AgentServer {
+	return &AgentServer{agentLogic: logic, commandLogic: commands, webhookLogic: webhooks, vulnLogic: vulns, softwareLogic: catalog, firewallLogic: firewall, complianceLogic: compliance, exportLogic: exports, availabilityLogic: availability, cloneLogic: clones, metricsLogic: metrics, alertLogic: alerts, inventoryLogic: inventory, patchLogic: patches, scriptLogic: scripts, updateLogic: updates, collectionLogic: collections, jobLogic: jobs, events: events, cfg: cfg}
 }
 
 
@@ -40,15 +56,30 @@ func (s *AgentServer) RegisterAgent(ctx context.Context, req *pb.RegisterRequest
 	log.Printf("Registration request for agent: %s", agentDetailsProto.GetAgentId())
 
 	agentModel := mapProtoToModelAgent(agentDetailsProto)
+	agentModel.LastPeerIP = peerIP(ctx)
+
+	// A cloned instance may be given a new agent ID here.
+	if err := s.cloneLogic.PrepareRegistration(agentModel, req.GetInstanceNonce()); err != nil {
+		log.Printf("Failed to check registration of agent %s: %v", agentModel.AgentID, err)
+		return nil, status.Errorf(codes.Internal, "failed to register agent: %v", err)
+	}
 
-	_, err := s.agentLogic.RegisterAgent(agentModel)
+	registered, err := s.agentLogic.RegisterAgent(agentModel)
 	if err != nil {
 		log.Printf("Failed to process agent registration for %s: %v", agentModel.AgentID, err)
 		return nil, status.Errorf(codes.Internal, "failed to register agent: %v", err)
 	}
 
 	log.Printf("Successfully registered agent: %s", agentModel.AgentID)
-	return &pb.RegisterResponse{Success: true, Message: "Agent registered successfully", ReportIntervalSeconds: reportIntervalSeconds}, nil
+	reportIntervalSeconds := int32(s.cfg.Current().Server.ReportIntervalSeconds)
+	return &pb.RegisterResponse{
+		Success:               true,
+		Message:               "Agent registered successfully",
+		ReportIntervalSeconds: reportIntervalSeconds,
+		InstanceNonce:         agentModel.InstanceNonce,
+		AgentId:               agentModel.AgentID,
+		Update:                mapModelToProtoUpdateOffer(s.updateLogic.CheckRegistration(registered)),
+	}, nil
 }
 
 func (s *AgentServer) SendHeartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
@@ -56,7 +87,8 @@ func (s *AgentServer) SendHeartbeat(ctx context.Context, req *pb.HeartbeatReques
 		return nil, status.Errorf(codes.InvalidArgument, "Agent ID is required")
 	}
 
-	rowsAffected, err := s.agentLogic.ProcessHeartbeat(req.GetAgentId(), req.GetCurrentIp())
+	peer := peerIP(ctx)
+	rowsAffected, err := s.agentLogic.ProcessHeartbeat(req.GetAgentId(), req.GetCurrentIp(), peer)
 	if err != nil {
 		log.Printf("Failed to update heartbeat for agent %s: %v", req.GetAgentId(), err)
 		return nil, status.Errorf(codes.Internal, "Could not update agent status")
@@ -66,8 +98,11 @@ func (s *AgentServer) SendHeartbeat(ctx context.Context, req *pb.HeartbeatReques
 		return nil, status.Errorf(codes.NotFound, "Agent not registered")
 	}
 
-	log.Printf("Heartbeat received from agent: %s", req.GetAgentId())
-	return &pb.HeartbeatResponse{Acknowledged: true}, nil
+	logging.Debugf("Heartbeat received from agent: %s", req.GetAgentId())
+	s.metricsLogic.Record(req.GetAgentId(), mapProtoToModelMetricSamples(req.GetMetrics()))
+	reregister := s.cloneLogic.CheckHeartbeat(req.GetAgentId(), req.GetCurrentIp(), peer, req.GetInstanceNonce())
+	update := mapModelToProtoUpdateOffer(s.updateLogic.CheckHeartbeat(req.GetAgentId()))
+	return &pb.HeartbeatResponse{Acknowledged: true, Reregister: reregister, Update: update}, nil
 }
 
 func (s *AgentServer) ReportFirewallStatus(ctx context.Context, req *pb.FirewallStatusRequest) (*pb.FirewallStatusResponse, error) {
@@ -82,6 +117,11 @@ func (s *AgentServer) ReportFirewallStatus(ctx context.Context, req *pb.Firewall
 		return nil, status.Errorf(codes.Internal, "Could not save firewall rules")
 	}
 
+	// The rules are stored; a failed analysis is retried with the next report.
+	if _, err := s.firewallLogic.AnalyzeAgent(req.GetAgentId(), req.FirewallEnabled); err != nil {
+		log.Printf("Failed to analyze firewall rules for agent %s: %v", req.GetAgentId(), err)
+	}
+
 	return &pb.FirewallStatusResponse{Success: true, Message: "Firewall status received"}, nil
 }
 
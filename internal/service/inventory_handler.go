@@ -0,0 +1,134 @@
+// internal/service/inventory_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"context"
+	"errors"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+func (s *AgentServer) ReportNetworkInterfaces(ctx context.Context, req *pb.NetworkInterfacesRequest) (*pb.NetworkInterfacesResponse, error) {
+	err := s.inventoryLogic.StoreNetworkInterfaces(req.GetAgentId(), mapProtoToModelNetworkInterfaces(req.GetInterfaces()))
+	if err != nil {
+		return nil, inventoryReportError(req.GetAgentId(), "network interfaces", err)
+	}
+	return &pb.NetworkInterfacesResponse{Success: true, Message: "Network interfaces received"}, nil
+}
+
+func (s *AgentServer) ReportLocalUsers(ctx context.Context, req *pb.LocalUsersRequest) (*pb.LocalUsersResponse, error) {
+	err := s.inventoryLogic.StoreLocalUsers(req.GetAgentId(), mapProtoToModelLocalUsers(req.GetUsers()), mapProtoToModelLocalGroups(req.GetGroups()))
+	if err != nil {
+		return nil, inventoryReportError(req.GetAgentId(), "local users", err)
+	}
+	return &pb.LocalUsersResponse{Success: true, Message: "Local users received"}, nil
+}
+
+func (s *AgentServer) ReportServices(ctx context.Context, req *pb.ServicesRequest) (*pb.ServicesResponse, error) {
+	err := s.inventoryLogic.StoreServices(req.GetAgentId(), mapProtoToModelServices(req.GetServices()))
+	if err != nil {
+		return nil, inventoryReportError(req.GetAgentId(), "services", err)
+	}
+	return &pb.ServicesResponse{Success: true, Message: "Services received"}, nil
+}
+
+func (s *AgentServer) ReportListeningSockets(ctx context.Context, req *pb.ListeningSocketsRequest) (*pb.ListeningSocketsResponse, error) {
+	err := s.inventoryLogic.StoreListeningSockets(req.GetAgentId(), mapProtoToModelListeningSockets(req.GetSockets()))
+	if err != nil {
+		return nil, inventoryReportError(req.GetAgentId(), "listening sockets", err)
+	}
+	return &pb.ListeningSocketsResponse{Success: true, Message: "Listening sockets received"}, nil
+}
+
+func (s *AgentServer) ReportProcesses(ctx context.Context, req *pb.ProcessesRequest) (*pb.ProcessesResponse, error) {
+	err := s.inventoryLogic.StoreProcesses(req.GetAgentId(), mapProtoToModelProcesses(req.GetProcesses()))
+	if err != nil {
+		return nil, inventoryReportError(req.GetAgentId(), "processes", err)
+	}
+	return &pb.ProcessesResponse{Success: true, Message: "Processes received"}, nil
+}
+
+// inventoryReportError logs a failed report and converts it to a gRPC
+// status, like ReportInstalledApps does.
+func inventoryReportError(agentID, kind string, err error) error {
+	log.Printf("Failed to save %s for agent %s: %v", kind, agentID, err)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return status.Errorf(codes.NotFound, "Agent not found")
+	}
+	return status.Errorf(codes.Internal, "Could not save %s", kind)
+}
+
+func inventoryQueryError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return status.Errorf(codes.NotFound, "Agent not found")
+	}
+	return status.Errorf(codes.Internal, "Database error")
+}
+
+func (s *AgentServer) GetNetworkInterfaces(ctx context.Context, req *pb.GetNetworkInterfacesRequest) (*pb.GetNetworkInterfacesResponse, error) {
+	items, err := s.inventoryLogic.GetNetworkInterfaces(req.GetAgentId())
+	if err != nil {
+		return nil, inventoryQueryError(err)
+	}
+	resp := &pb.GetNetworkInterfacesResponse{Interfaces: mapModelToProtoNetworkInterfaces(items)}
+	if len(items) > 0 {
+		resp.ReportedAt = timestamppb.New(items[0].ReportedAt)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) GetLocalUsers(ctx context.Context, req *pb.GetLocalUsersRequest) (*pb.GetLocalUsersResponse, error) {
+	users, groups, err := s.inventoryLogic.GetLocalUsers(req.GetAgentId())
+	if err != nil {
+		return nil, inventoryQueryError(err)
+	}
+	resp := &pb.GetLocalUsersResponse{Users: mapModelToProtoLocalUsers(users), Groups: mapModelToProtoLocalGroups(groups)}
+	if len(users) > 0 {
+		resp.ReportedAt = timestamppb.New(users[0].ReportedAt)
+	} else if len(groups) > 0 {
+		resp.ReportedAt = timestamppb.New(groups[0].ReportedAt)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) GetServices(ctx context.Context, req *pb.GetServicesRequest) (*pb.GetServicesResponse, error) {
+	items, err := s.inventoryLogic.GetServices(req.GetAgentId())
+	if err != nil {
+		return nil, inventoryQueryError(err)
+	}
+	resp := &pb.GetServicesResponse{Services: mapModelToProtoServices(items)}
+	if len(items) > 0 {
+		resp.ReportedAt = timestamppb.New(items[0].ReportedAt)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) GetListeningSockets(ctx context.Context, req *pb.GetListeningSocketsRequest) (*pb.GetListeningSocketsResponse, error) {
+	items, err := s.inventoryLogic.GetListeningSockets(req.GetAgentId())
+	if err != nil {
+		return nil, inventoryQueryError(err)
+	}
+	resp := &pb.GetListeningSocketsResponse{Sockets: mapModelToProtoListeningSockets(items)}
+	if len(items) > 0 {
+		resp.ReportedAt = timestamppb.New(items[0].ReportedAt)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) GetProcesses(ctx context.Context, req *pb.GetProcessesRequest) (*pb.GetProcessesResponse, error) {
+	items, err := s.inventoryLogic.GetProcesses(req.GetAgentId())
+	if err != nil {
+		return nil, inventoryQueryError(err)
+	}
+	resp := &pb.GetProcessesResponse{Processes: mapModelToProtoProcesses(items)}
+	if len(items) > 0 {
+		resp.ReportedAt = timestamppb.New(items[0].ReportedAt)
+	}
+	return resp, nil
+}
@@ -0,0 +1,66 @@
+// internal/service/ip_history_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func (s *AgentServer) ListAgentIPHistory(ctx context.Context, req *pb.ListAgentIPHistoryRequest) (*pb.ListAgentIPHistoryResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	filter := model.IPHistoryFilter{
+		AgentID:      req.GetAgentId(),
+		IP:           req.GetIp(),
+		MismatchOnly: req.GetMismatchOnly(),
+		Limit:        limit,
+	}
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		filter.Until = req.GetUntil().AsTime()
+	}
+
+	addresses, err := s.agentLogic.GetIPHistory(filter)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidWindow) {
+			return nil, status.Errorf(codes.InvalidArgument, "Until must be after since")
+		}
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListAgentIPHistoryResponse{Addresses: make([]*pb.AgentIPAddress, 0, len(addresses))}
+	for i := range addresses {
+		resp.Addresses = append(resp.Addresses, mapModelToProtoAgentIPAddress(&addresses[i]))
+	}
+	return resp, nil
+}
+
+// peerIP is the IP the request came from, which the REST gateway sets to the
+// HTTP client address. It is empty when unknown or not an IP, as for
+// connections over a Unix socket.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host := p.Addr.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) == nil {
+		return ""
+	}
+	return host
+}
@@ -0,0 +1,214 @@
+// internal/service/job_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+// CreateScheduledJob stores a job that queues a command on the agents it
+// targets on a cron schedule or once at a given time. The command is
+// validated and encoded here, like the handler that queues it by hand would.
+func (s *AgentServer) CreateScheduledJob(ctx context.Context, req *pb.CreateScheduledJobRequest) (*pb.CreateScheduledJobResponse, error) {
+	in := req.GetJob()
+	if in == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "A job is required")
+	}
+	job := &model.ScheduledJob{
+		Name:           in.GetName(),
+		Cron:           in.GetCron(),
+		Timezone:       in.GetTimezone(),
+		CatchUp:        mapProtoToModelCatchUpPolicy(in.GetCatchUp()),
+		MaxConcurrency: int(in.GetMaxConcurrency()),
+		TargetAll:      in.GetTarget().GetAll(),
+		TargetAgentIDs: model.StringList(in.GetTarget().GetAgentIds()),
+		TargetLabels:   model.Labels(in.GetTarget().GetLabels()),
+	}
+	if in.GetRunAt() != nil {
+		runAt := in.GetRunAt().AsTime()
+		job.RunAt = &runAt
+	}
+	if err := s.jobCommand(in, job); err != nil {
+		return nil, err
+	}
+	created, err := s.jobLogic.CreateJob(job)
+	if err != nil {
+		return nil, jobError(err, "Could not create scheduled job")
+	}
+	out, err := mapModelToProtoScheduledJob(created)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not encode job")
+	}
+	return &pb.CreateScheduledJobResponse{Job: out}, nil
+}
+
+// jobCommand sets the command type and payload of a job's command, and the
+// script it runs so the allowlist can be checked again before each command.
+func (s *AgentServer) jobCommand(in *pb.ScheduledJob, job *model.ScheduledJob) error {
+	var (
+		commandType string
+		command     proto.Message
+	)
+	switch {
+	case in.GetFirewallConfiguration() != nil:
+		req := proto.Clone(in.GetFirewallConfiguration()).(*pb.FirewallConfigurationRequest)
+		if err := validateFirewallOperation(req); err != nil {
+			return err
+		}
+		req.AgentId = ""
+		commandType, command = model.CommandTypeFirewallConfiguration, req
+	case in.GetRunScript() != nil:
+		req, approvalReason, err := s.prepareScriptCommand(in.GetRunScript())
+		if err != nil {
+			return err
+		}
+		// Nobody is around to approve a command queued by the scheduler.
+		if approvalReason != "" {
+			return status.Errorf(codes.InvalidArgument, "Scheduled scripts must be on the allowlist: %s", approvalReason)
+		}
+		req.AgentId = ""
+		commandType, command = model.CommandTypeRunScript, req
+		job.ScriptName, job.ScriptDigest = in.GetRunScript().GetLibraryScript(), req.GetDigest()
+	case in.GetReportInventory() != nil:
+		commandType, command = model.CommandTypeReportInventory, &pb.ReportInventoryRequest{}
+	default:
+		return status.Errorf(codes.InvalidArgument, "A command is required: firewall_configuration, run_script or report_inventory")
+	}
+	payload, err := proto.Marshal(command)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Could not encode command")
+	}
+	job.CommandType, job.Payload = commandType, payload
+	return nil
+}
+
+// UpdateScheduledJob pauses or resumes a job.
+func (s *AgentServer) UpdateScheduledJob(ctx context.Context, req *pb.UpdateScheduledJobRequest) (*pb.UpdateScheduledJobResponse, error) {
+	if req.GetJobId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Job ID is required")
+	}
+	var (
+		job *model.ScheduledJob
+		err error
+	)
+	switch req.GetStatus() {
+	case pb.JobStatus_JOB_PAUSED:
+		job, err = s.jobLogic.PauseJob(uint(req.GetJobId()))
+	case pb.JobStatus_JOB_ACTIVE:
+		job, err = s.jobLogic.ResumeJob(uint(req.GetJobId()))
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "Status must be JOB_ACTIVE or JOB_PAUSED")
+	}
+	if err != nil {
+		return nil, jobError(err, "Could not update scheduled job")
+	}
+	out, err := mapModelToProtoScheduledJob(job)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not encode job")
+	}
+	return &pb.UpdateScheduledJobResponse{Job: out}, nil
+}
+
+func (s *AgentServer) DeleteScheduledJob(ctx context.Context, req *pb.DeleteScheduledJobRequest) (*pb.DeleteScheduledJobResponse, error) {
+	if req.GetJobId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Job ID is required")
+	}
+	if err := s.jobLogic.DeleteJob(uint(req.GetJobId())); err != nil {
+		return nil, jobError(err, "Could not delete scheduled job")
+	}
+	return &pb.DeleteScheduledJobResponse{Success: true, Message: fmt.Sprintf("Scheduled job %d deleted", req.GetJobId())}, nil
+}
+
+func (s *AgentServer) ListScheduledJobs(ctx context.Context, req *pb.ListScheduledJobsRequest) (*pb.ListScheduledJobsResponse, error) {
+	jobs, err := s.jobLogic.ListJobs(mapProtoToModelJobStatus(req.GetStatus()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListScheduledJobsResponse{}
+	for i := range jobs {
+		job, err := mapModelToProtoScheduledJob(&jobs[i])
+		if err != nil {
+			log.Printf("Failed to decode command of scheduled job %d: %v", jobs[i].ID, err)
+			continue
+		}
+		resp.Jobs = append(resp.Jobs, job)
+	}
+	return resp, nil
+}
+
+// RunScheduledJob starts a run of a job now, outside its schedule.
+func (s *AgentServer) RunScheduledJob(ctx context.Context, req *pb.RunScheduledJobRequest) (*pb.RunScheduledJobResponse, error) {
+	if req.GetJobId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Job ID is required")
+	}
+	run, err := s.jobLogic.RunJobNow(uint(req.GetJobId()))
+	if err != nil {
+		return nil, jobError(err, "Could not run scheduled job")
+	}
+	return &pb.RunScheduledJobResponse{Run: mapModelToProtoJobRun(run)}, nil
+}
+
+func (s *AgentServer) ListJobRuns(ctx context.Context, req *pb.ListJobRunsRequest) (*pb.ListJobRunsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	runs, err := s.jobLogic.ListRuns(model.JobRunFilter{
+		JobID:  uint(req.GetJobId()),
+		Status: mapProtoToModelJobRunStatus(req.GetStatus()),
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListJobRunsResponse{}
+	for i := range runs {
+		resp.Runs = append(resp.Runs, mapModelToProtoJobRun(&runs[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) GetJobRun(ctx context.Context, req *pb.GetJobRunRequest) (*pb.GetJobRunResponse, error) {
+	if req.GetRunId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Run ID is required")
+	}
+	run, targets, err := s.jobLogic.GetRun(uint(req.GetRunId()))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Run not found")
+		}
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.GetJobRunResponse{Run: mapModelToProtoJobRun(run)}
+	for i := range targets {
+		resp.Targets = append(resp.Targets, mapModelToProtoJobRunTarget(&targets[i]))
+	}
+	return resp, nil
+}
+
+// jobError converts a JobUseCase error to a gRPC status.
+func jobError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Errorf(codes.NotFound, "Scheduled job not found")
+	case errors.Is(err, usecase.ErrInvalidJob):
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	case errors.Is(err, usecase.ErrJobExists):
+		return status.Errorf(codes.AlreadyExists, "%v", err)
+	case errors.Is(err, usecase.ErrJobRunInProgress):
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+	log.Printf("%s: %v", fallback, err)
+	return status.Errorf(codes.Internal, "%s", fallback)
+}
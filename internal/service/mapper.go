@@ -3,8 +3,17 @@
 package service
 
 import (
+	"agent_server/internal/alerting"
 	"agent_server/internal/model"
+	"agent_server/internal/usecase"
 	pb "agent_server/agent_server/proto"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -23,6 +32,9 @@ func mapProtoToModelAgent(p *pb.Agent) *model.Agent {
 		MemoryGB:      p.GetMemoryGb(),
 		DiskSpaceGB:   p.GetDiskSpaceGb(),
 		LastKnownIP:   p.GetLastKnownIp(),
+		Labels:        p.GetLabels(),
+		AgentVersion:  p.GetAgentVersion(),
+		Arch:          p.GetArch(),
 	}
 }
 
@@ -31,7 +43,7 @@ func mapModelToProtoAgent(m *model.Agent) *pb.Agent {
 	if m == nil {
 		return nil
 	}
-	return &pb.Agent{
+	p := &pb.Agent{
 		AgentId:       m.AgentID,
 		Hostname:      m.Hostname,
 		OsName:        m.OSName,
@@ -43,7 +55,16 @@ func mapModelToProtoAgent(m *model.Agent) *pb.Agent {
 		Status:        pb.AgentStatus(pb.AgentStatus_value[m.Status]),
 		LastSeen:      timestamppb.New(m.LastSeen),
 		LastKnownIp:   m.LastKnownIP,
+		Labels:        m.Labels,
+		LastPeerIp:    m.LastPeerIP,
+		CloneReason:   m.CloneReason,
+		AgentVersion:  m.AgentVersion,
+		Arch:          m.Arch,
+	}
+	if m.CloneSuspectedAt != nil {
+		p.CloneSuspectedAt = timestamppb.New(*m.CloneSuspectedAt)
 	}
+	return p
 }
 
 // mapProtoToModelFirewallRules converts a slice of Protobuf FirewallRules to GORM models.
@@ -76,3 +97,959 @@ func mapProtoToModelInstalledApps(protoApps []*pb.ApplicationInfo) []model.Insta
 	return modelApps
 }
 
+
+// mapModelToProtoFirewallRules converts stored firewall rules back to Protobuf.
+func mapModelToProtoFirewallRules(modelRules []model.FirewallRule) []*pb.FirewallRule {
+	protoRules := make([]*pb.FirewallRule, 0, len(modelRules))
+	for _, m := range modelRules {
+		protoRules = append(protoRules, &pb.FirewallRule{
+			Name:      m.Name,
+			Port:      m.Port,
+			Protocol:  m.Protocol,
+			Action:    pb.FirewallAction(pb.FirewallAction_value[m.Action]),
+			Direction: pb.FirewallDirection(pb.FirewallDirection_value[m.Direction]),
+			Enabled:   m.Enabled,
+		})
+	}
+	return protoRules
+}
+
+// mapModelToProtoInstalledApps converts stored applications back to Protobuf.
+func mapModelToProtoInstalledApps(modelApps []model.InstalledApplication) []*pb.ApplicationInfo {
+	protoApps := make([]*pb.ApplicationInfo, 0, len(modelApps))
+	for _, m := range modelApps {
+		protoApps = append(protoApps, &pb.ApplicationInfo{
+			Name:        m.Name,
+			Version:     m.Version,
+			Publisher:   m.Publisher,
+			InstallDate: timestamppb.New(m.InstallDate),
+		})
+	}
+	return protoApps
+}
+
+// mapModelToProtoCommand converts a queued command to Protobuf, decoding its payload.
+func mapModelToProtoCommand(m *model.Command) (*pb.Command, error) {
+	p := &pb.Command{
+		CommandId:       uint64(m.ID),
+		AgentId:         m.AgentID,
+		Status:          mapModelToProtoCommandStatus(m.Status),
+		ResultMessage:   m.ResultMessage,
+		CreatedAt:       timestamppb.New(m.CreatedAt),
+		UpdatedAt:       timestamppb.New(m.UpdatedAt),
+		ExitCode:        m.ExitCode,
+		OutputBytes:     uint64(m.OutputBytes),
+		OutputTruncated: m.OutputTruncated,
+	}
+
+	switch m.Type {
+	case model.CommandTypeFirewallConfiguration:
+		req := &pb.FirewallConfigurationRequest{}
+		if err := proto.Unmarshal(m.Payload, req); err != nil {
+			return nil, err
+		}
+		p.Payload = &pb.Command_FirewallConfiguration{FirewallConfiguration: req}
+	case model.CommandTypeRunScript:
+		req := &pb.RunScriptRequest{}
+		if err := proto.Unmarshal(m.Payload, req); err != nil {
+			return nil, err
+		}
+		p.Payload = &pb.Command_RunScript{RunScript: req}
+	case model.CommandTypeCollectFile:
+		req := &pb.CollectFileRequest{}
+		if err := proto.Unmarshal(m.Payload, req); err != nil {
+			return nil, err
+		}
+		p.Payload = &pb.Command_CollectFile{CollectFile: req}
+	case model.CommandTypeReportInventory:
+		req := &pb.ReportInventoryRequest{}
+		if err := proto.Unmarshal(m.Payload, req); err != nil {
+			return nil, err
+		}
+		p.Payload = &pb.Command_ReportInventory{ReportInventory: req}
+	}
+	return p, nil
+}
+
+// mapProtoToModelOutputStream converts OUTPUT_STDOUT to "stdout"; UNKNOWN becomes "".
+func mapProtoToModelOutputStream(s pb.OutputStream) string {
+	switch s {
+	case pb.OutputStream_OUTPUT_STDOUT:
+		return model.OutputStdout
+	case pb.OutputStream_OUTPUT_STDERR:
+		return model.OutputStderr
+	}
+	return ""
+}
+
+// mapModelToProtoCommandStatus converts "PENDING" to COMMAND_PENDING and so on.
+func mapModelToProtoCommandStatus(status string) pb.CommandStatus {
+	return pb.CommandStatus(pb.CommandStatus_value["COMMAND_"+status])
+}
+
+// mapProtoToModelCommandStatus converts COMMAND_PENDING to "PENDING"; UNKNOWN becomes "".
+func mapProtoToModelCommandStatus(status pb.CommandStatus) string {
+	if status == pb.CommandStatus_COMMAND_STATUS_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(status.String(), "COMMAND_")
+}
+
+func mapModelToProtoWebhookSubscription(m *model.WebhookSubscription) *pb.WebhookSubscription {
+	p := &pb.WebhookSubscription{
+		SubscriptionId: uint64(m.ID),
+		Url:            m.URL,
+		Description:    m.Description,
+		Enabled:        m.Enabled,
+		CreatedAt:      timestamppb.New(m.CreatedAt),
+	}
+	if m.EventTypes != "" {
+		p.EventTypes = strings.Split(m.EventTypes, ",")
+	}
+	return p
+}
+
+func mapModelToProtoWebhookDelivery(m *model.WebhookDelivery) *pb.WebhookDelivery {
+	p := &pb.WebhookDelivery{
+		DeliveryId:     uint64(m.ID),
+		SubscriptionId: uint64(m.SubscriptionID),
+		EventId:        m.EventID,
+		EventType:      m.EventType,
+		AgentId:        m.AgentID,
+		Status:         pb.WebhookDeliveryStatus(pb.WebhookDeliveryStatus_value["WEBHOOK_"+m.Status]),
+		Attempts:       int32(m.Attempts),
+		LastError:      m.LastError,
+		LastStatusCode: int32(m.LastStatusCode),
+		CreatedAt:      timestamppb.New(m.CreatedAt),
+	}
+	if m.Status == model.WebhookPending {
+		p.NextAttemptAt = timestamppb.New(m.NextAttemptAt)
+	}
+	if m.DeliveredAt != nil {
+		p.DeliveredAt = timestamppb.New(*m.DeliveredAt)
+	}
+	return p
+}
+
+// mapProtoToModelWebhookStatus converts WEBHOOK_DEAD to "DEAD"; UNKNOWN becomes "".
+func mapProtoToModelWebhookStatus(status pb.WebhookDeliveryStatus) string {
+	if status == pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(status.String(), "WEBHOOK_")
+}
+
+// mapProtoToModelSeverity converts SEVERITY_HIGH to "HIGH"; UNKNOWN becomes "".
+func mapProtoToModelSeverity(severity pb.VulnerabilitySeverity) string {
+	if severity == pb.VulnerabilitySeverity_SEVERITY_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(severity.String(), "SEVERITY_")
+}
+
+func mapModelToProtoSeverity(severity string) pb.VulnerabilitySeverity {
+	return pb.VulnerabilitySeverity(pb.VulnerabilitySeverity_value["SEVERITY_"+severity])
+}
+
+func mapModelToProtoVulnerabilityFinding(f *usecase.FindingDetail) *pb.VulnerabilityFinding {
+	return &pb.VulnerabilityFinding{
+		AgentId:         f.AgentID,
+		VulnerabilityId: f.VulnerabilityID,
+		Aliases:         f.Aliases,
+		Summary:         f.Summary,
+		Severity:        mapModelToProtoSeverity(f.Severity),
+		CvssScore:       f.CVSSScore,
+		Product:         f.Product,
+		AppName:         f.AppName,
+		AppVersion:      f.AppVersion,
+		FixedVersion:    f.FixedVersion,
+		DetectedAt:      timestamppb.New(f.DetectedAt),
+	}
+}
+
+// mapProtoToModelFirewallFindingKind converts SHADOWED_RULE to "SHADOWED_RULE"; UNKNOWN becomes "".
+func mapProtoToModelFirewallFindingKind(kind pb.FirewallFindingKind) string {
+	if kind == pb.FirewallFindingKind_FIREWALL_FINDING_KIND_UNKNOWN {
+		return ""
+	}
+	return kind.String()
+}
+
+func mapModelToProtoFirewallFinding(f *model.FirewallFinding) *pb.FirewallFinding {
+	return &pb.FirewallFinding{
+		AgentId:     f.AgentID,
+		Kind:        pb.FirewallFindingKind(pb.FirewallFindingKind_value[f.Kind]),
+		Severity:    mapModelToProtoSeverity(f.Severity),
+		RuleName:    f.RuleName,
+		RelatedRule: f.RelatedRule,
+		Direction:   pb.FirewallDirection(pb.FirewallDirection_value[f.Direction]),
+		Protocol:    f.Protocol,
+		Port:        f.Port,
+		Message:     f.Message,
+		DetectedAt:  timestamppb.New(f.DetectedAt),
+	}
+}
+
+func mapModelToProtoFleetVulnerability(v *usecase.FleetVulnerability) *pb.FleetVulnerability {
+	p := &pb.FleetVulnerability{
+		VulnerabilityId: v.VulnerabilityID,
+		Aliases:         v.AliasList(),
+		Summary:         v.Summary,
+		Severity:        mapModelToProtoSeverity(v.Severity),
+		CvssScore:       v.CVSSScore,
+		AffectedAgents:  v.AffectedAgents,
+	}
+	if !v.PublishedAt.IsZero() {
+		p.PublishedAt = timestamppb.New(v.PublishedAt)
+	}
+	return p
+}
+
+func mapModelToProtoSoftwareProduct(p *usecase.CatalogProduct) *pb.SoftwareProduct {
+	out := &pb.SoftwareProduct{
+		Product:           p.Key,
+		Name:              p.Name,
+		VersionScheme:     p.Scheme,
+		MatchedRule:       p.Matched,
+		Installs:          int32(p.Installs),
+		Agents:            int32(p.Agents),
+		LatestVersion:     p.LatestVersion,
+		PrevailingVersion: p.PrevailingVersion,
+		OutdatedInstalls:  int32(p.OutdatedInstalls),
+	}
+	for _, v := range p.Versions {
+		out.Versions = append(out.Versions, &pb.SoftwareVersion{Version: v.Version, Installs: int32(v.Installs), Outdated: v.Outdated})
+	}
+	return out
+}
+
+func mapModelToProtoSoftwareInstall(in *usecase.SoftwareInstall) *pb.SoftwareInstall {
+	return &pb.SoftwareInstall{
+		AgentId:    in.AgentID,
+		Hostname:   in.Hostname,
+		AppName:    in.AppName,
+		Publisher:  in.Publisher,
+		Version:    in.Version,
+		Outdated:   in.Outdated,
+		ReportedAt: timestamppb.New(in.ReportedAt),
+	}
+}
+
+// mapModelToProtoFleetEvent converts a bus event to a Protobuf FleetEvent.
+// Data goes through JSON so it reads the same as in webhook payloads.
+func mapModelToProtoFleetEvent(e *model.Event) (*pb.FleetEvent, error) {
+	p := &pb.FleetEvent{
+		Sequence:   e.Sequence,
+		EventId:    e.ID,
+		Type:       e.Type,
+		AgentId:    e.AgentID,
+		Labels:     e.Labels,
+		OccurredAt: timestamppb.New(e.OccurredAt),
+	}
+	if len(e.Data) > 0 {
+		raw, err := json.Marshal(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		p.Data = &structpb.Struct{}
+		if err := protojson.Unmarshal(raw, p.Data); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// mapProtoToModelComplianceStatus converts COMPLIANCE_FAIL to "FAIL"; UNKNOWN becomes "".
+func mapProtoToModelComplianceStatus(s pb.ComplianceStatus) string {
+	if s == pb.ComplianceStatus_COMPLIANCE_STATUS_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(s.String(), "COMPLIANCE_")
+}
+
+func mapModelToProtoComplianceStatus(s string) pb.ComplianceStatus {
+	return pb.ComplianceStatus(pb.ComplianceStatus_value["COMPLIANCE_"+s])
+}
+
+func mapModelToProtoCompliancePolicy(m *model.CompliancePolicy, includeDefinition bool) *pb.CompliancePolicy {
+	p := &pb.CompliancePolicy{
+		PolicyId:    m.PolicyID,
+		Version:     int32(m.Version),
+		Name:        m.Name,
+		Description: m.Description,
+		Checks:      int32(m.Checks),
+		Digest:      m.Digest,
+		LoadedAt:    timestamppb.New(m.LoadedAt),
+	}
+	if includeDefinition {
+		p.Definition = m.Definition
+	}
+	return p
+}
+
+func mapModelToProtoComplianceResult(m *model.ComplianceResult) *pb.ComplianceResult {
+	return &pb.ComplianceResult{
+		AgentId:       m.AgentID,
+		PolicyId:      m.PolicyID,
+		PolicyVersion: int32(m.PolicyVersion),
+		CheckId:       m.CheckID,
+		Title:         m.Title,
+		Severity:      mapModelToProtoSeverity(m.Severity),
+		Status:        mapModelToProtoComplianceStatus(m.Status),
+		Evidence:      m.Evidence,
+		Since:         timestamppb.New(m.Since),
+		EvaluatedAt:   timestamppb.New(m.EvaluatedAt),
+	}
+}
+
+func mapModelToProtoComplianceEvaluation(m *model.ComplianceEvaluation) *pb.ComplianceEvaluation {
+	p := &pb.ComplianceEvaluation{
+		AgentId:       m.AgentID,
+		PolicyId:      m.PolicyID,
+		PolicyVersion: int32(m.PolicyVersion),
+		Passed:        int32(m.Passed),
+		Failed:        int32(m.Failed),
+		NotApplicable: int32(m.NotApplicable),
+		EvaluatedAt:   timestamppb.New(m.EvaluatedAt),
+	}
+	if m.FailedChecks != "" {
+		p.FailedChecks = strings.Split(m.FailedChecks, ",")
+	}
+	return p
+}
+
+func mapModelToProtoCompliancePolicyReport(s *usecase.CompliancePolicySummary) *pb.CompliancePolicyReport {
+	p := &pb.CompliancePolicyReport{
+		Policy:          mapModelToProtoCompliancePolicy(&s.CompliancePolicy, false),
+		Agents:          int32(s.Agents),
+		CompliantAgents: int32(s.CompliantAgents),
+	}
+	p.Policy.Active = true
+	for _, c := range s.Checks {
+		p.Checks = append(p.Checks, &pb.ComplianceCheckSummary{
+			CheckId:       c.CheckID,
+			Title:         c.Title,
+			Severity:      mapModelToProtoSeverity(c.Severity),
+			Passed:        int32(c.Passed),
+			Failed:        int32(c.Failed),
+			NotApplicable: int32(c.NotApplicable),
+		})
+	}
+	return p
+}
+
+func mapModelToProtoAvailabilityStats(s usecase.AvailabilityStats) *pb.AvailabilityStats {
+	return &pb.AvailabilityStats{
+		AvailabilityPercent:      s.Availability(),
+		ObservedSeconds:          int64(s.Observed.Seconds()),
+		DowntimeSeconds:          int64(s.Downtime.Seconds()),
+		Outages:                  int32(s.Outages),
+		RecoveredOutages:         int32(s.Recovered),
+		MeanTimeToRecoverSeconds: int64(s.MTTR().Seconds()),
+	}
+}
+
+func mapModelToProtoGroupAvailability(g *usecase.GroupAvailability) *pb.GroupAvailability {
+	return &pb.GroupAvailability{
+		Group:  g.Group,
+		Agents: int32(g.Agents),
+		Stats:  mapModelToProtoAvailabilityStats(g.AvailabilityStats),
+	}
+}
+
+func mapModelToProtoAgentAvailability(a *usecase.AgentAvailability) *pb.AgentAvailability {
+	return &pb.AgentAvailability{
+		AgentId:  a.AgentID,
+		Hostname: a.Hostname,
+		Labels:   a.Labels,
+		Status:   pb.AgentStatus(pb.AgentStatus_value[a.Status]),
+		Stats:    mapModelToProtoAvailabilityStats(a.AvailabilityStats),
+	}
+}
+
+// mapModelToProtoAgentIPAddress converts an IP history entry to Protobuf.
+func mapModelToProtoAgentIPAddress(m *model.AgentIPAddress) *pb.AgentIPAddress {
+	return &pb.AgentIPAddress{
+		AgentId:   m.AgentID,
+		Ip:        m.IP,
+		PeerIp:    m.PeerIP,
+		FirstSeen: timestamppb.New(m.FirstSeen),
+		LastSeen:  timestamppb.New(m.LastSeen),
+		Mismatch:  m.Mismatch(),
+	}
+}
+
+// mapProtoToModelMetricSamples converts the metrics of a heartbeat to
+// samples, leaving out the fields the agent did not send.
+func mapProtoToModelMetricSamples(m *pb.ResourceMetrics) []model.MetricSample {
+	if m == nil {
+		return nil
+	}
+	var samples []model.MetricSample
+	if m.CpuPercent != nil {
+		samples = append(samples, model.MetricSample{Metric: model.MetricCPUPercent, Value: m.GetCpuPercent()})
+	}
+	if m.MemoryUsedGb != nil {
+		samples = append(samples, model.MetricSample{Metric: model.MetricMemoryUsedGB, Value: m.GetMemoryUsedGb()})
+	}
+	for _, d := range m.GetDisks() {
+		if d.GetMount() == "" {
+			continue
+		}
+		samples = append(samples, model.MetricSample{Metric: model.MetricDiskUsedGB, Mount: d.GetMount(), Value: d.GetUsedGb()})
+		if d.GetTotalGb() > 0 {
+			samples = append(samples, model.MetricSample{Metric: model.MetricDiskUsedPercent, Mount: d.GetMount(), Value: 100 * d.GetUsedGb() / d.GetTotalGb()})
+		}
+	}
+	if m.LoadAverage != nil {
+		samples = append(samples, model.MetricSample{Metric: model.MetricLoadAverage, Value: m.GetLoadAverage()})
+	}
+	if m.UptimeSeconds != nil {
+		samples = append(samples, model.MetricSample{Metric: model.MetricUptimeSeconds, Value: float64(m.GetUptimeSeconds())})
+	}
+	if m.ProcessCount != nil {
+		samples = append(samples, model.MetricSample{Metric: model.MetricProcessCount, Value: float64(m.GetProcessCount())})
+	}
+	return samples
+}
+
+// mapModelToProtoMetricSeries converts a metric series to Protobuf.
+func mapModelToProtoMetricSeries(s *usecase.MetricSeries) *pb.MetricSeries {
+	series := &pb.MetricSeries{Metric: s.Metric, Mount: s.Mount, Points: make([]*pb.MetricPoint, 0, len(s.Points))}
+	for _, p := range s.Points {
+		series.Points = append(series.Points, &pb.MetricPoint{
+			Time:    timestamppb.New(p.BucketStart),
+			Avg:     p.Avg(),
+			Min:     p.Min,
+			Max:     p.Max,
+			Samples: int32(p.Count),
+		})
+	}
+	return series
+}
+
+// mapProtoToModelAlertState converts ALERT_FIRING to "FIRING"; UNKNOWN becomes "".
+func mapProtoToModelAlertState(s pb.AlertState) string {
+	if s == pb.AlertState_ALERT_STATE_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(s.String(), "ALERT_")
+}
+
+func mapModelToProtoAlertState(s string) pb.AlertState {
+	return pb.AlertState(pb.AlertState_value["ALERT_"+s])
+}
+
+// mapModelToProtoAlertRule converts a loaded alert rule to Protobuf.
+func mapModelToProtoAlertRule(r *alerting.Rule) *pb.AlertRule {
+	return &pb.AlertRule{
+		Id:                  r.ID,
+		Name:                r.Name,
+		Description:         r.Description,
+		Severity:            mapModelToProtoSeverity(r.Severity),
+		Kind:                r.Kind,
+		Condition:           r.Condition,
+		ForSeconds:          int64(r.For / time.Second),
+		ResolveAfterSeconds: int64(r.ResolveAfter / time.Second),
+		Labels:              r.Labels,
+		Notify:              r.Notify,
+	}
+}
+
+func mapModelToProtoAlert(m *model.Alert) *pb.Alert {
+	a := &pb.Alert{
+		Id:        uint64(m.ID),
+		RuleId:    m.RuleID,
+		Key:       m.Key,
+		AgentId:   m.AgentID,
+		Severity:  mapModelToProtoSeverity(m.Severity),
+		State:     mapModelToProtoAlertState(m.State),
+		Summary:   m.Summary,
+		Value:     m.Value,
+		StartedAt: timestamppb.New(m.StartedAt),
+	}
+	if m.FiredAt != nil {
+		a.FiredAt = timestamppb.New(*m.FiredAt)
+	}
+	if m.ResolvedAt != nil {
+		a.ResolvedAt = timestamppb.New(*m.ResolvedAt)
+	}
+	return a
+}
+
+// optionalTimestamp converts a time the agent may not know; a missing
+// timestamp stays the zero time instead of becoming the Unix epoch.
+func optionalTimestamp(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+func optionalProtoTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// mapProtoToModelServiceStartType converts SERVICE_START_MANUAL to "MANUAL"; UNKNOWN becomes "".
+func mapProtoToModelServiceStartType(s pb.ServiceStartType) string {
+	if s == pb.ServiceStartType_SERVICE_START_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(s.String(), "SERVICE_START_")
+}
+
+func mapModelToProtoServiceStartType(s string) pb.ServiceStartType {
+	return pb.ServiceStartType(pb.ServiceStartType_value["SERVICE_START_"+s])
+}
+
+// mapProtoToModelServiceState converts SERVICE_RUNNING to "RUNNING"; UNKNOWN becomes "".
+func mapProtoToModelServiceState(s pb.ServiceState) string {
+	if s == pb.ServiceState_SERVICE_STATE_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(s.String(), "SERVICE_")
+}
+
+func mapModelToProtoServiceState(s string) pb.ServiceState {
+	return pb.ServiceState(pb.ServiceState_value["SERVICE_"+s])
+}
+
+func mapProtoToModelNetworkInterfaces(in []*pb.NetworkInterfaceInfo) []model.NetworkInterface {
+	out := make([]model.NetworkInterface, 0, len(in))
+	for _, p := range in {
+		out = append(out, model.NetworkInterface{
+			Name:        p.GetName(),
+			MAC:         p.GetMac(),
+			IPAddresses: p.GetIpAddresses(),
+			MTU:         p.GetMtu(),
+			Up:          p.GetUp(),
+		})
+	}
+	return out
+}
+
+func mapModelToProtoNetworkInterfaces(in []model.NetworkInterface) []*pb.NetworkInterfaceInfo {
+	out := make([]*pb.NetworkInterfaceInfo, 0, len(in))
+	for _, m := range in {
+		out = append(out, &pb.NetworkInterfaceInfo{
+			Name:        m.Name,
+			Mac:         m.MAC,
+			IpAddresses: m.IPAddresses,
+			Mtu:         m.MTU,
+			Up:          m.Up,
+		})
+	}
+	return out
+}
+
+func mapProtoToModelLocalUsers(in []*pb.LocalUserInfo) []model.LocalUser {
+	out := make([]model.LocalUser, 0, len(in))
+	for _, p := range in {
+		out = append(out, model.LocalUser{
+			Name:      p.GetName(),
+			UID:       p.GetUid(),
+			FullName:  p.GetFullName(),
+			Groups:    p.GetGroups(),
+			Disabled:  p.GetDisabled(),
+			LastLogon: optionalTimestamp(p.GetLastLogon()),
+		})
+	}
+	return out
+}
+
+func mapModelToProtoLocalUsers(in []model.LocalUser) []*pb.LocalUserInfo {
+	out := make([]*pb.LocalUserInfo, 0, len(in))
+	for _, m := range in {
+		out = append(out, &pb.LocalUserInfo{
+			Name:      m.Name,
+			Uid:       m.UID,
+			FullName:  m.FullName,
+			Groups:    m.Groups,
+			Disabled:  m.Disabled,
+			LastLogon: optionalProtoTimestamp(m.LastLogon),
+		})
+	}
+	return out
+}
+
+func mapProtoToModelLocalGroups(in []*pb.LocalGroupInfo) []model.LocalGroup {
+	out := make([]model.LocalGroup, 0, len(in))
+	for _, p := range in {
+		out = append(out, model.LocalGroup{Name: p.GetName(), GID: p.GetGid(), Members: p.GetMembers()})
+	}
+	return out
+}
+
+func mapModelToProtoLocalGroups(in []model.LocalGroup) []*pb.LocalGroupInfo {
+	out := make([]*pb.LocalGroupInfo, 0, len(in))
+	for _, m := range in {
+		out = append(out, &pb.LocalGroupInfo{Name: m.Name, Gid: m.GID, Members: m.Members})
+	}
+	return out
+}
+
+func mapProtoToModelServices(in []*pb.ServiceInfo) []model.SystemService {
+	out := make([]model.SystemService, 0, len(in))
+	for _, p := range in {
+		out = append(out, model.SystemService{
+			Name:        p.GetName(),
+			DisplayName: p.GetDisplayName(),
+			StartType:   mapProtoToModelServiceStartType(p.GetStartType()),
+			State:       mapProtoToModelServiceState(p.GetState()),
+			Account:     p.GetAccount(),
+			BinaryPath:  p.GetBinaryPath(),
+		})
+	}
+	return out
+}
+
+func mapModelToProtoServices(in []model.SystemService) []*pb.ServiceInfo {
+	out := make([]*pb.ServiceInfo, 0, len(in))
+	for _, m := range in {
+		out = append(out, &pb.ServiceInfo{
+			Name:        m.Name,
+			DisplayName: m.DisplayName,
+			StartType:   mapModelToProtoServiceStartType(m.StartType),
+			State:       mapModelToProtoServiceState(m.State),
+			Account:     m.Account,
+			BinaryPath:  m.BinaryPath,
+		})
+	}
+	return out
+}
+
+func mapProtoToModelListeningSockets(in []*pb.ListeningSocketInfo) []model.ListeningSocket {
+	out := make([]model.ListeningSocket, 0, len(in))
+	for _, p := range in {
+		out = append(out, model.ListeningSocket{
+			Protocol:    p.GetProtocol(),
+			Address:     p.GetAddress(),
+			Port:        p.GetPort(),
+			PID:         p.GetPid(),
+			ProcessName: p.GetProcessName(),
+		})
+	}
+	return out
+}
+
+func mapModelToProtoListeningSockets(in []model.ListeningSocket) []*pb.ListeningSocketInfo {
+	out := make([]*pb.ListeningSocketInfo, 0, len(in))
+	for _, m := range in {
+		out = append(out, &pb.ListeningSocketInfo{
+			Protocol:    m.Protocol,
+			Address:     m.Address,
+			Port:        m.Port,
+			Pid:         m.PID,
+			ProcessName: m.ProcessName,
+		})
+	}
+	return out
+}
+
+func mapProtoToModelProcesses(in []*pb.ProcessInfo) []model.RunningProcess {
+	out := make([]model.RunningProcess, 0, len(in))
+	for _, p := range in {
+		out = append(out, model.RunningProcess{
+			PID:         p.GetPid(),
+			ParentPID:   p.GetParentPid(),
+			Name:        p.GetName(),
+			User:        p.GetUser(),
+			CommandLine: p.GetCommandLine(),
+			StartedAt:   optionalTimestamp(p.GetStartedAt()),
+			CPUPercent:  p.GetCpuPercent(),
+			MemoryMB:    p.GetMemoryMb(),
+		})
+	}
+	return out
+}
+
+func mapModelToProtoProcesses(in []model.RunningProcess) []*pb.ProcessInfo {
+	out := make([]*pb.ProcessInfo, 0, len(in))
+	for _, m := range in {
+		out = append(out, &pb.ProcessInfo{
+			Pid:         m.PID,
+			ParentPid:   m.ParentPID,
+			Name:        m.Name,
+			User:        m.User,
+			CommandLine: m.CommandLine,
+			StartedAt:   optionalProtoTimestamp(m.StartedAt),
+			CpuPercent:  m.CPUPercent,
+			MemoryMb:    m.MemoryMB,
+		})
+	}
+	return out
+}
+
+func mapProtoToModelInstalledPatches(in []*pb.PatchInfo) []model.InstalledPatch {
+	out := make([]model.InstalledPatch, 0, len(in))
+	for _, p := range in {
+		out = append(out, model.InstalledPatch{
+			PatchID:     p.GetPatchId(),
+			Title:       p.GetTitle(),
+			Version:     p.GetVersion(),
+			InstalledAt: optionalTimestamp(p.GetInstalledAt()),
+		})
+	}
+	return out
+}
+
+func mapModelToProtoInstalledPatches(in []model.InstalledPatch) []*pb.PatchInfo {
+	out := make([]*pb.PatchInfo, 0, len(in))
+	for _, m := range in {
+		out = append(out, &pb.PatchInfo{
+			PatchId:     m.PatchID,
+			Title:       m.Title,
+			Version:     m.Version,
+			InstalledAt: optionalProtoTimestamp(m.InstalledAt),
+		})
+	}
+	return out
+}
+
+func mapProtoToModelPendingUpdates(in []*pb.PendingUpdateInfo) []model.PendingUpdate {
+	out := make([]model.PendingUpdate, 0, len(in))
+	for _, p := range in {
+		out = append(out, model.PendingUpdate{
+			PatchID:  p.GetPatchId(),
+			Title:    p.GetTitle(),
+			Version:  p.GetVersion(),
+			Security: p.GetSecurity(),
+		})
+	}
+	return out
+}
+
+func mapModelToProtoPendingUpdates(in []model.PendingUpdate) []*pb.PendingUpdateInfo {
+	out := make([]*pb.PendingUpdateInfo, 0, len(in))
+	for _, m := range in {
+		out = append(out, &pb.PendingUpdateInfo{
+			PatchId:  m.PatchID,
+			Title:    m.Title,
+			Version:  m.Version,
+			Security: m.Security,
+		})
+	}
+	return out
+}
+
+func mapModelToProtoPatchStatus(r *model.AgentPatchStatusRow) *pb.PatchStatus {
+	out := &pb.PatchStatus{
+		AgentId:              r.AgentKey,
+		Hostname:             r.Hostname,
+		OsName:               r.OSName,
+		Labels:               r.Labels,
+		LastUpdateAt:         optionalProtoTimestamp(r.Status.LastUpdateAt),
+		RebootPending:        r.Status.RebootPending,
+		InstalledCount:       int32(r.Status.InstalledCount),
+		PendingCount:         int32(r.Status.PendingCount),
+		SecurityPendingCount: int32(r.Status.SecurityPendingCount),
+		ReportedAt:           timestamppb.New(r.Status.ReportedAt),
+	}
+	if r.Status.RebootPendingSince != nil {
+		out.RebootPendingSince = timestamppb.New(*r.Status.RebootPendingSince)
+	}
+	return out
+}
+
+func mapModelToProtoAgentPackage(m *model.AgentPackage) *pb.AgentPackage {
+	return &pb.AgentPackage{
+		PackageId: uint64(m.ID),
+		Version:   m.Version,
+		Os:        m.OS,
+		Arch:      m.Arch,
+		Sha256:    m.SHA256,
+		SizeBytes: m.SizeBytes,
+		Signature: m.Signature,
+		CreatedAt: timestamppb.New(m.CreatedAt),
+	}
+}
+
+func mapModelToProtoRollout(m *usecase.RolloutSummary) *pb.Rollout {
+	return &pb.Rollout{
+		RolloutId:      uint64(m.ID),
+		Version:        m.Version,
+		Percentage:     int32(m.Percentage),
+		Groups:         m.Groups,
+		MaxFailureRate: m.MaxFailureRate,
+		MinResults:     int32(m.MinResults),
+		Status:         pb.RolloutStatus(pb.RolloutStatus_value["ROLLOUT_"+m.Status]),
+		PauseReason:    m.PauseReason,
+		CreatedAt:      timestamppb.New(m.CreatedAt),
+		UpdatedAt:      timestamppb.New(m.UpdatedAt),
+		Offered:        int32(m.Counts.Offered),
+		Succeeded:      int32(m.Counts.Succeeded),
+		Failed:         int32(m.Counts.Failed),
+	}
+}
+
+func mapProtoToModelRolloutStatus(status pb.RolloutStatus) string {
+	if status == pb.RolloutStatus_ROLLOUT_STATUS_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(status.String(), "ROLLOUT_")
+}
+
+func mapModelToProtoAgentUpdate(r *model.AgentUpdateRow) *pb.AgentUpdate {
+	out := &pb.AgentUpdate{
+		AgentId:     r.AgentKey,
+		Hostname:    r.Hostname,
+		FromVersion: r.Update.FromVersion,
+		ToVersion:   r.Update.ToVersion,
+		Status:      pb.AgentUpdateStatus(pb.AgentUpdateStatus_value["AGENT_UPDATE_"+r.Update.Status]),
+		Message:     r.Update.Message,
+		OfferedAt:   timestamppb.New(r.Update.OfferedAt),
+	}
+	if r.Update.FinishedAt != nil {
+		out.FinishedAt = timestamppb.New(*r.Update.FinishedAt)
+	}
+	return out
+}
+
+func mapProtoToModelAgentUpdateStatus(status pb.AgentUpdateStatus) string {
+	if status == pb.AgentUpdateStatus_AGENT_UPDATE_STATUS_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(status.String(), "AGENT_UPDATE_")
+}
+
+func mapModelToProtoFileCollection(m *model.FileCollection) *pb.FileCollection {
+	p := &pb.FileCollection{
+		CollectionId:  uint64(m.ID),
+		AgentId:       m.AgentID,
+		CommandId:     uint64(m.CommandID),
+		Path:          m.Path,
+		Reason:        m.Reason,
+		Status:        mapModelToProtoCollectionStatus(m.Status),
+		SizeBytes:     m.SizeBytes,
+		ReceivedBytes: m.ReceivedBytes,
+		Sha256:        m.SHA256,
+		Message:       m.Message,
+		CreatedAt:     timestamppb.New(m.CreatedAt),
+		MaxBytes:      m.MaxBytes,
+	}
+	if m.CompletedAt != nil {
+		p.CompletedAt = timestamppb.New(*m.CompletedAt)
+	}
+	return p
+}
+
+// mapModelToProtoCollectionStatus converts "COMPLETE" to COLLECTION_COMPLETE and so on.
+func mapModelToProtoCollectionStatus(status string) pb.FileCollectionStatus {
+	return pb.FileCollectionStatus(pb.FileCollectionStatus_value["COLLECTION_"+status])
+}
+
+// mapProtoToModelCollectionStatus converts COLLECTION_COMPLETE to "COMPLETE"; UNKNOWN becomes "".
+func mapProtoToModelCollectionStatus(status pb.FileCollectionStatus) string {
+	if status == pb.FileCollectionStatus_FILE_COLLECTION_STATUS_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(status.String(), "COLLECTION_")
+}
+
+// mapModelToProtoScheduledJob converts a job and decodes its command.
+func mapModelToProtoScheduledJob(m *model.ScheduledJob) (*pb.ScheduledJob, error) {
+	p := &pb.ScheduledJob{
+		JobId:          uint64(m.ID),
+		Name:           m.Name,
+		Cron:           m.Cron,
+		Timezone:       m.Timezone,
+		CatchUp:        pb.CatchUpPolicy(pb.CatchUpPolicy_value["CATCH_UP_"+m.CatchUp]),
+		MaxConcurrency: int32(m.MaxConcurrency),
+		Target: &pb.JobTarget{
+			All:      m.TargetAll,
+			AgentIds: m.TargetAgentIDs,
+			Labels:   m.TargetLabels,
+		},
+		Status:    pb.JobStatus(pb.JobStatus_value["JOB_"+m.Status]),
+		CreatedAt: timestamppb.New(m.CreatedAt),
+	}
+	if m.RunAt != nil {
+		p.RunAt = timestamppb.New(*m.RunAt)
+	}
+	if m.NextRunAt != nil {
+		p.NextRunAt = timestamppb.New(*m.NextRunAt)
+	}
+	if m.LastRunAt != nil {
+		p.LastRunAt = timestamppb.New(*m.LastRunAt)
+	}
+
+	switch m.CommandType {
+	case model.CommandTypeFirewallConfiguration:
+		req := &pb.FirewallConfigurationRequest{}
+		if err := proto.Unmarshal(m.Payload, req); err != nil {
+			return nil, err
+		}
+		p.Command = &pb.ScheduledJob_FirewallConfiguration{FirewallConfiguration: req}
+	case model.CommandTypeRunScript:
+		req := &pb.RunScriptRequest{}
+		if err := proto.Unmarshal(m.Payload, req); err != nil {
+			return nil, err
+		}
+		p.Command = &pb.ScheduledJob_RunScript{RunScript: req}
+	case model.CommandTypeReportInventory:
+		p.Command = &pb.ScheduledJob_ReportInventory{ReportInventory: &pb.ReportInventoryRequest{}}
+	}
+	return p, nil
+}
+
+// mapProtoToModelCatchUpPolicy converts CATCH_UP_RUN_ONCE to "RUN_ONCE"; UNKNOWN becomes "".
+func mapProtoToModelCatchUpPolicy(policy pb.CatchUpPolicy) string {
+	if policy == pb.CatchUpPolicy_CATCH_UP_POLICY_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(policy.String(), "CATCH_UP_")
+}
+
+// mapProtoToModelJobStatus converts JOB_PAUSED to "PAUSED"; UNKNOWN becomes "".
+func mapProtoToModelJobStatus(status pb.JobStatus) string {
+	if status == pb.JobStatus_JOB_STATUS_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(status.String(), "JOB_")
+}
+
+func mapModelToProtoJobRun(m *model.JobRun) *pb.JobRun {
+	p := &pb.JobRun{
+		RunId:        uint64(m.ID),
+		JobId:        uint64(m.JobID),
+		Trigger:      pb.JobRunTrigger(pb.JobRunTrigger_value["TRIGGER_"+m.Trigger]),
+		Status:       pb.JobRunStatus(pb.JobRunStatus_value["JOB_RUN_"+m.Status]),
+		ScheduledFor: timestamppb.New(m.ScheduledFor),
+		StartedAt:    timestamppb.New(m.StartedAt),
+		Targets:      int32(m.Targets),
+		Succeeded:    int32(m.Succeeded),
+		Failed:       int32(m.Failed),
+		Message:      m.Message,
+	}
+	if m.FinishedAt != nil {
+		p.FinishedAt = timestamppb.New(*m.FinishedAt)
+	}
+	return p
+}
+
+// mapProtoToModelJobRunStatus converts JOB_RUN_FAILED to "FAILED"; UNKNOWN becomes "".
+func mapProtoToModelJobRunStatus(status pb.JobRunStatus) string {
+	if status == pb.JobRunStatus_JOB_RUN_STATUS_UNKNOWN {
+		return ""
+	}
+	return strings.TrimPrefix(status.String(), "JOB_RUN_")
+}
+
+func mapModelToProtoJobRunTarget(m *model.JobRunTarget) *pb.JobRunTarget {
+	p := &pb.JobRunTarget{
+		AgentId:   m.AgentID,
+		CommandId: uint64(m.CommandID),
+		Status:    pb.JobTargetStatus(pb.JobTargetStatus_value["JOB_TARGET_"+m.Status]),
+		Message:   m.Message,
+	}
+	if m.DispatchedAt != nil {
+		p.DispatchedAt = timestamppb.New(*m.DispatchedAt)
+	}
+	if m.FinishedAt != nil {
+		p.FinishedAt = timestamppb.New(*m.FinishedAt)
+	}
+	return p
+}
@@ -0,0 +1,67 @@
+// internal/service/metrics_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// metricResolutions maps the resolutions of GetAgentMetrics to seconds.
+var metricResolutions = map[pb.MetricResolution]int{
+	pb.MetricResolution_METRIC_RESOLUTION_AUTO: usecase.MetricResolutionAuto,
+	pb.MetricResolution_METRIC_RAW:             model.MetricResolutionRaw,
+	pb.MetricResolution_METRIC_FIVE_MINUTES:    model.MetricResolutionFiveMinute,
+	pb.MetricResolution_METRIC_HOURLY:          model.MetricResolutionHour,
+}
+
+func (s *AgentServer) GetAgentMetrics(ctx context.Context, req *pb.GetAgentMetricsRequest) (*pb.GetAgentMetricsResponse, error) {
+	if req.GetAgentId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID is required")
+	}
+	resolution, ok := metricResolutions[req.GetResolution()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "Unknown resolution %v", req.GetResolution())
+	}
+	query := usecase.MetricsQuery{AgentID: req.GetAgentId(), Metrics: req.GetMetrics(), Resolution: resolution}
+	if req.GetSince() != nil {
+		query.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		query.Until = req.GetUntil().AsTime()
+	}
+
+	result, err := s.metricsLogic.GetMetrics(query)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Agent not found")
+		}
+		if errors.Is(err, usecase.ErrInvalidWindow) {
+			return nil, status.Errorf(codes.InvalidArgument, "Until must be after since")
+		}
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.GetAgentMetricsResponse{
+		AgentId: result.AgentID,
+		Since:   timestamppb.New(result.Since),
+		Until:   timestamppb.New(result.Until),
+		Series:  make([]*pb.MetricSeries, 0, len(result.Series)),
+	}
+	for r, seconds := range metricResolutions {
+		if seconds == result.Resolution {
+			resp.Resolution = r
+		}
+	}
+	for i := range result.Series {
+		resp.Series = append(resp.Series, mapModelToProtoMetricSeries(&result.Series[i]))
+	}
+	return resp, nil
+}
@@ -0,0 +1,73 @@
+// internal/service/patch_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *AgentServer) ReportPatchStatus(ctx context.Context, req *pb.PatchStatusRequest) (*pb.PatchStatusResponse, error) {
+	report := usecase.PatchReport{
+		Installed:          mapProtoToModelInstalledPatches(req.GetInstalled()),
+		Pending:            mapProtoToModelPendingUpdates(req.GetPending()),
+		LastUpdateAt:       optionalTimestamp(req.GetLastUpdateAt()),
+		RebootPending:      req.GetRebootPending(),
+		RebootPendingSince: optionalTimestamp(req.GetRebootPendingSince()),
+	}
+	if err := s.patchLogic.StorePatchStatus(req.GetAgentId(), report); err != nil {
+		return nil, inventoryReportError(req.GetAgentId(), "patch status", err)
+	}
+	return &pb.PatchStatusResponse{Success: true, Message: "Patch status received"}, nil
+}
+
+func (s *AgentServer) GetPatchStatus(ctx context.Context, req *pb.GetPatchStatusRequest) (*pb.GetPatchStatusResponse, error) {
+	patchStatus, installed, pending, err := s.patchLogic.GetPatchStatus(req.GetAgentId())
+	if err != nil {
+		return nil, inventoryQueryError(err)
+	}
+	resp := &pb.GetPatchStatusResponse{
+		Installed: mapModelToProtoInstalledPatches(installed),
+		Pending:   mapModelToProtoPendingUpdates(pending),
+	}
+	if patchStatus != nil {
+		resp.Status = mapModelToProtoPatchStatus(patchStatus)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) ListPatchStatus(ctx context.Context, req *pb.ListPatchStatusRequest) (*pb.ListPatchStatusResponse, error) {
+	if req.GetRebootPendingForSeconds() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Reboot pending duration must not be negative")
+	}
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	filter := model.PatchStatusFilter{
+		Labels:       req.GetLabels(),
+		OSName:       req.GetOsName(),
+		MissingPatch: req.GetMissingPatch(),
+		PendingOnly:  req.GetPendingOnly(),
+		Limit:        limit,
+	}
+	if seconds := req.GetRebootPendingForSeconds(); seconds > 0 {
+		filter.RebootPendingBefore = time.Now().Add(-time.Duration(seconds) * time.Second)
+	}
+
+	rows, err := s.patchLogic.ListPatchStatus(filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListPatchStatusResponse{Statuses: make([]*pb.PatchStatus, 0, len(rows))}
+	for i := range rows {
+		resp.Statuses = append(resp.Statuses, mapModelToProtoPatchStatus(&rows[i]))
+	}
+	return resp, nil
+}
@@ -0,0 +1,103 @@
+// internal/service/query_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"context"
+	"errors"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+func (s *AgentServer) ListAgents(ctx context.Context, req *pb.ListAgentsRequest) (*pb.ListAgentsResponse, error) {
+	pageSize := int(req.GetPageSize())
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = defaultPageSize
+	}
+
+	var afterID uint64
+	if token := req.GetPageToken(); token != "" {
+		var err error
+		afterID, err = strconv.ParseUint(token, 10, 64)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid page token")
+		}
+	}
+
+	filter := model.AgentFilter{AfterID: uint(afterID), Limit: pageSize}
+	if req.GetStatus() != pb.AgentStatus_UNKNOWN {
+		filter.Status = req.GetStatus().String()
+	}
+
+	agents, err := s.agentLogic.ListAgents(filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+
+	resp := &pb.ListAgentsResponse{}
+	for i := range agents {
+		resp.Agents = append(resp.Agents, mapModelToProtoAgent(&agents[i]))
+	}
+	if len(agents) == pageSize {
+		resp.NextPageToken = strconv.FormatUint(uint64(agents[len(agents)-1].ID), 10)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) DecommissionAgent(ctx context.Context, req *pb.DecommissionAgentRequest) (*pb.DecommissionAgentResponse, error) {
+	if req.GetAgentId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID is required")
+	}
+
+	agent, err := s.agentLogic.DecommissionAgent(req.GetAgentId())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Agent not found")
+		}
+		return nil, status.Errorf(codes.Internal, "Could not decommission agent")
+	}
+	return &pb.DecommissionAgentResponse{Agent: mapModelToProtoAgent(agent)}, nil
+}
+
+func (s *AgentServer) GetFirewallRules(ctx context.Context, req *pb.GetFirewallRulesRequest) (*pb.GetFirewallRulesResponse, error) {
+	rules, err := s.agentLogic.GetCurrentFirewallRules(req.GetAgentId())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Agent not found")
+		}
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+
+	resp := &pb.GetFirewallRulesResponse{Rules: mapModelToProtoFirewallRules(rules)}
+	if len(rules) > 0 {
+		resp.ReportedAt = timestamppb.New(rules[0].ReportedAt)
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) GetInstalledApps(ctx context.Context, req *pb.GetInstalledAppsRequest) (*pb.GetInstalledAppsResponse, error) {
+	apps, err := s.agentLogic.GetCurrentInstalledApps(req.GetAgentId())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Agent not found")
+		}
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+
+	resp := &pb.GetInstalledAppsResponse{Apps: mapModelToProtoInstalledApps(apps)}
+	if len(apps) > 0 {
+		resp.ReportedAt = timestamppb.New(apps[0].ReportedAt)
+	}
+	return resp, nil
+}
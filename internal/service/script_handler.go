@@ -0,0 +1,103 @@
+// internal/service/script_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// RunScript queues a script for the agent. Library scripts are resolved here,
+// so the agent always receives the body it has to run; scripts that are not
+// on the allowlist wait for ApproveCommand before the agent can poll them.
+func (s *AgentServer) RunScript(ctx context.Context, req *pb.RunScriptRequest) (*pb.RunScriptResponse, error) {
+	if req.GetAgentId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID is required")
+	}
+
+	command, approvalReason, err := s.prepareScriptCommand(req)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(command)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not encode command")
+	}
+
+	var cmd *model.Command
+	if approvalReason != "" {
+		cmd, err = s.commandLogic.EnqueueCommandForApproval(req.GetAgentId(), model.CommandTypeRunScript, payload, approvalReason)
+	} else {
+		cmd, err = s.commandLogic.EnqueueCommand(req.GetAgentId(), model.CommandTypeRunScript, payload)
+	}
+	if err != nil {
+		log.Printf("Failed to queue script for agent %s: %v", req.GetAgentId(), err)
+		return nil, commandError(err, "Could not queue script")
+	}
+
+	resp := &pb.RunScriptResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("Script command %d queued", cmd.ID),
+		CommandId: uint64(cmd.ID),
+	}
+	if approvalReason != "" {
+		resp.ApprovalRequired = true
+		resp.Message = fmt.Sprintf("Script command %d awaits approval: %s", cmd.ID, approvalReason)
+	}
+	return resp, nil
+}
+
+// prepareScriptCommand resolves the script of a request and returns the
+// RUN_SCRIPT payload the agent gets, which always carries the script's body,
+// and why it needs approval if it does.
+func (s *AgentServer) prepareScriptCommand(req *pb.RunScriptRequest) (*pb.RunScriptRequest, string, error) {
+	prepared, err := s.scriptLogic.PrepareScript(usecase.ScriptRequest{
+		Name:        req.GetLibraryScript(),
+		Body:        req.GetScriptBody(),
+		Interpreter: req.GetInterpreter(),
+		Args:        req.GetArgs(),
+		Timeout:     time.Duration(req.GetTimeoutSeconds()) * time.Second,
+		RunAs:       req.GetRunAsUser(),
+	})
+	switch {
+	case errors.Is(err, usecase.ErrScriptNotFound):
+		return nil, "", status.Errorf(codes.NotFound, "%v", err)
+	case errors.Is(err, usecase.ErrInvalidScript):
+		return nil, "", status.Errorf(codes.InvalidArgument, "%v", err)
+	case err != nil:
+		return nil, "", status.Errorf(codes.Internal, "Could not prepare script")
+	}
+	return &pb.RunScriptRequest{
+		AgentId:        req.GetAgentId(),
+		Source:         &pb.RunScriptRequest_ScriptBody{ScriptBody: prepared.Body},
+		Interpreter:    prepared.Interpreter,
+		Args:           prepared.Args,
+		TimeoutSeconds: int32(prepared.Timeout / time.Second),
+		RunAsUser:      prepared.RunAs,
+		Digest:         prepared.Digest,
+	}, prepared.ApprovalReason, nil
+}
+
+func (s *AgentServer) ListScripts(ctx context.Context, req *pb.ListScriptsRequest) (*pb.ListScriptsResponse, error) {
+	resp := &pb.ListScriptsResponse{}
+	for _, script := range s.scriptLogic.Scripts() {
+		resp.Scripts = append(resp.Scripts, &pb.ScriptInfo{
+			Name:        script.Name,
+			Interpreter: script.Interpreter,
+			Digest:      script.Digest,
+			SizeBytes:   int64(len(script.Body)),
+			Allowlisted: s.scriptLogic.Allowed(script),
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,59 @@
+// internal/service/software_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *AgentServer) ListSoftwareCatalog(ctx context.Context, req *pb.ListSoftwareCatalogRequest) (*pb.ListSoftwareCatalogResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	products, err := s.softwareLogic.Catalog(usecase.CatalogFilter{
+		Query:        req.GetQuery(),
+		Labels:       model.Labels(req.GetLabels()),
+		OutdatedOnly: req.GetOutdatedOnly(),
+		Limit:        limit,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListSoftwareCatalogResponse{}
+	for i := range products {
+		resp.Products = append(resp.Products, mapModelToProtoSoftwareProduct(&products[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) ListSoftwareInstalls(ctx context.Context, req *pb.ListSoftwareInstallsRequest) (*pb.ListSoftwareInstallsResponse, error) {
+	if req.GetProduct() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Product is required")
+	}
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	installs, err := s.softwareLogic.ListInstalls(usecase.InstallFilter{
+		Product:      req.GetProduct(),
+		Version:      req.GetVersion(),
+		Labels:       model.Labels(req.GetLabels()),
+		OutdatedOnly: req.GetOutdatedOnly(),
+		Limit:        limit,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListSoftwareInstallsResponse{}
+	for i := range installs {
+		resp.Installs = append(resp.Installs, mapModelToProtoSoftwareInstall(&installs[i]))
+	}
+	return resp, nil
+}
@@ -0,0 +1,259 @@
+// internal/service/update_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/artifacts"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// packageChunkSize is the size of the AgentPackageChunk messages of a download.
+const packageChunkSize = 64 << 10
+
+// UploadAgentPackage stores an agent package. The first message carries the
+// package's version, OS, arch and signature; the rest carry its content.
+func (s *AgentServer) UploadAgentPackage(stream pb.AgentService_UploadAgentPackageServer) error {
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Errorf(codes.InvalidArgument, "Package info is required")
+	}
+	if err != nil {
+		return err
+	}
+	info := first.GetInfo()
+	if info == nil {
+		return status.Errorf(codes.InvalidArgument, "The first message must carry the package info")
+	}
+
+	upload := usecase.PackageUpload{Version: info.GetVersion(), OS: info.GetOs(), Arch: info.GetArch(), Signature: info.GetSignature()}
+	pkg, err := s.updateLogic.UploadPackage(stream.Context(), upload, &uploadReader{stream: stream})
+	if err != nil {
+		var recvErr uploadRecvError
+		switch {
+		case errors.As(err, &recvErr):
+			return recvErr.err
+		case errors.Is(err, usecase.ErrPackageExists):
+			return status.Errorf(codes.AlreadyExists, "%v", err)
+		case errors.Is(err, usecase.ErrInvalidPackage):
+			return status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		log.Printf("Failed to store agent package %s for %s/%s: %v", upload.Version, upload.OS, upload.Arch, err)
+		return status.Errorf(codes.Internal, "Could not store agent package")
+	}
+	return stream.SendAndClose(&pb.UploadAgentPackageResponse{Package: mapModelToProtoAgentPackage(pkg)})
+}
+
+// uploadReader reads the content of an UploadAgentPackage stream.
+type uploadReader struct {
+	stream pb.AgentService_UploadAgentPackageServer
+	buf    []byte
+}
+
+// uploadRecvError wraps a failure to receive from the stream, so it is
+// returned to the client as it is rather than as a storage error.
+type uploadRecvError struct{ err error }
+
+func (e uploadRecvError) Error() string { return e.err.Error() }
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		msg, err := r.stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, uploadRecvError{err}
+		}
+		if msg.GetInfo() != nil {
+			return 0, uploadRecvError{status.Errorf(codes.InvalidArgument, "Package info must only be sent first")}
+		}
+		r.buf = msg.GetData()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (s *AgentServer) ListAgentPackages(ctx context.Context, req *pb.ListAgentPackagesRequest) (*pb.ListAgentPackagesResponse, error) {
+	pkgs, err := s.updateLogic.ListPackages(req.GetVersion())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListAgentPackagesResponse{}
+	for i := range pkgs {
+		resp.Packages = append(resp.Packages, mapModelToProtoAgentPackage(&pkgs[i]))
+	}
+	return resp, nil
+}
+
+// DownloadAgentPackage streams a package to an agent, from the offset of an
+// interrupted download if one is given. The first chunk carries the size and
+// SHA-256 of the whole package.
+func (s *AgentServer) DownloadAgentPackage(req *pb.DownloadAgentPackageRequest, stream pb.AgentService_DownloadAgentPackageServer) error {
+	if req.GetAgentId() == "" || req.GetPackageId() == 0 {
+		return status.Errorf(codes.InvalidArgument, "Agent ID and package ID are required")
+	}
+	pkg, content, err := s.updateLogic.OpenPackage(stream.Context(), uint(req.GetPackageId()), req.GetOffset())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, artifacts.ErrNotFound):
+		return status.Errorf(codes.NotFound, "Package not found")
+	case errors.Is(err, usecase.ErrInvalidPackage):
+		return status.Errorf(codes.OutOfRange, "%v", err)
+	case err != nil:
+		log.Printf("Failed to open agent package %d: %v", req.GetPackageId(), err)
+		return status.Errorf(codes.Internal, "Could not read package")
+	}
+	defer content.Close()
+
+	offset := req.GetOffset()
+	buf := make([]byte, packageChunkSize)
+	first := true
+	for {
+		n, err := io.ReadFull(content, buf)
+		if n > 0 || first {
+			chunk := &pb.AgentPackageChunk{Offset: offset, Data: buf[:n]}
+			if first {
+				chunk.Sha256, chunk.SizeBytes = pkg.SHA256, pkg.SizeBytes
+				first = false
+			}
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+			offset += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			log.Printf("Failed to read agent package %d for agent %s: %v", pkg.ID, req.GetAgentId(), err)
+			return status.Errorf(codes.Internal, "Could not read package")
+		}
+	}
+	log.Printf("Agent %s downloaded package %d (%s for %s/%s) from offset %d.", req.GetAgentId(), pkg.ID, pkg.Version, pkg.OS, pkg.Arch, req.GetOffset())
+	return nil
+}
+
+func (s *AgentServer) ReportUpdateResult(ctx context.Context, req *pb.UpdateResultRequest) (*pb.UpdateResultResponse, error) {
+	if req.GetAgentId() == "" || req.GetRolloutId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent ID and rollout ID are required")
+	}
+	err := s.updateLogic.ReportResult(req.GetAgentId(), uint(req.GetRolloutId()), req.GetSuccess(), req.GetMessage())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, status.Errorf(codes.NotFound, "Agent or rollout not found")
+	case errors.Is(err, usecase.ErrUpdateNotOffered):
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	case err != nil:
+		log.Printf("Failed to record update result of agent %s: %v", req.GetAgentId(), err)
+		return nil, status.Errorf(codes.Internal, "Could not record update result")
+	}
+	return &pb.UpdateResultResponse{Acknowledged: true}, nil
+}
+
+func (s *AgentServer) CreateRollout(ctx context.Context, req *pb.CreateRolloutRequest) (*pb.CreateRolloutResponse, error) {
+	rollout := &model.Rollout{
+		Version:        req.GetVersion(),
+		Percentage:     int(req.GetPercentage()),
+		Groups:         req.GetGroups(),
+		MaxFailureRate: req.GetMaxFailureRate(),
+		MinResults:     int(req.GetMinResults()),
+	}
+	summary, err := s.updateLogic.CreateRollout(rollout)
+	if err != nil {
+		return nil, rolloutError(err, "Could not create rollout")
+	}
+	return &pb.CreateRolloutResponse{Rollout: mapModelToProtoRollout(summary)}, nil
+}
+
+func (s *AgentServer) UpdateRollout(ctx context.Context, req *pb.UpdateRolloutRequest) (*pb.UpdateRolloutResponse, error) {
+	if req.GetRolloutId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Rollout ID is required")
+	}
+	change := usecase.RolloutChange{Status: mapProtoToModelRolloutStatus(req.GetStatus())}
+	if req.Percentage != nil {
+		percentage := int(req.GetPercentage())
+		change.Percentage = &percentage
+	}
+	if len(req.GetGroups()) > 0 || req.GetClearGroups() {
+		change.Groups, change.SetGroups = req.GetGroups(), true
+	}
+	summary, err := s.updateLogic.UpdateRollout(uint(req.GetRolloutId()), change)
+	if err != nil {
+		return nil, rolloutError(err, "Could not update rollout")
+	}
+	return &pb.UpdateRolloutResponse{Rollout: mapModelToProtoRollout(summary)}, nil
+}
+
+func (s *AgentServer) ListRollouts(ctx context.Context, req *pb.ListRolloutsRequest) (*pb.ListRolloutsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	rollouts, err := s.updateLogic.ListRollouts(mapProtoToModelRolloutStatus(req.GetStatus()), limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListRolloutsResponse{}
+	for i := range rollouts {
+		resp.Rollouts = append(resp.Rollouts, mapModelToProtoRollout(&rollouts[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) ListAgentUpdates(ctx context.Context, req *pb.ListAgentUpdatesRequest) (*pb.ListAgentUpdatesResponse, error) {
+	if req.GetRolloutId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Rollout ID is required")
+	}
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	rows, err := s.updateLogic.ListAgentUpdates(uint(req.GetRolloutId()), mapProtoToModelAgentUpdateStatus(req.GetStatus()), limit)
+	if err != nil {
+		return nil, rolloutError(err, "Database error")
+	}
+	resp := &pb.ListAgentUpdatesResponse{}
+	for i := range rows {
+		resp.Updates = append(resp.Updates, mapModelToProtoAgentUpdate(&rows[i]))
+	}
+	return resp, nil
+}
+
+// rolloutError maps use case errors to gRPC status codes.
+func rolloutError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Errorf(codes.NotFound, "Rollout not found")
+	case errors.Is(err, usecase.ErrInvalidRollout):
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	case errors.Is(err, usecase.ErrRolloutInProgress):
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+	log.Printf("%s: %v", fallback, err)
+	return status.Errorf(codes.Internal, "%s", fallback)
+}
+
+// mapModelToProtoUpdateOffer converts an update offer to Protobuf; nil stays nil.
+func mapModelToProtoUpdateOffer(o *usecase.UpdateOffer) *pb.AgentUpdateOffer {
+	if o == nil {
+		return nil
+	}
+	return &pb.AgentUpdateOffer{
+		RolloutId: uint64(o.RolloutID),
+		Version:   o.Package.Version,
+		PackageId: uint64(o.Package.ID),
+		Sha256:    o.Package.SHA256,
+		SizeBytes: o.Package.SizeBytes,
+		Signature: o.Package.Signature,
+	}
+}
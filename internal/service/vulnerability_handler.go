@@ -0,0 +1,101 @@
+// internal/service/vulnerability_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/vuln"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// maxVulnerabilityFeedBytes caps the compressed size of an imported feed,
+// which is held in memory while it is parsed.
+const maxVulnerabilityFeedBytes = 512 << 20
+
+func (s *AgentServer) ImportVulnerabilityFeed(stream pb.AgentService_ImportVulnerabilityFeedServer) error {
+	var data bytes.Buffer
+	var format string
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if format == "" {
+			format = chunk.GetFormat()
+		}
+		if data.Len()+len(chunk.GetData()) > maxVulnerabilityFeedBytes {
+			return status.Errorf(codes.ResourceExhausted, "Vulnerability feed is larger than %d MiB", maxVulnerabilityFeedBytes>>20)
+		}
+		data.Write(chunk.GetData())
+	}
+	if data.Len() == 0 {
+		return status.Errorf(codes.InvalidArgument, "Vulnerability feed is empty")
+	}
+
+	result, err := s.vulnLogic.ImportFeed(data.Bytes(), format)
+	if err != nil {
+		if errors.Is(err, vuln.ErrInvalidFeed) {
+			return status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		log.Printf("Failed to import vulnerability feed: %v", err)
+		return status.Errorf(codes.Internal, "Could not import vulnerability feed")
+	}
+	return stream.SendAndClose(&pb.ImportVulnerabilityFeedResponse{
+		Vulnerabilities: int32(result.Vulnerabilities),
+		Products:        int32(result.Products),
+		AgentsEvaluated: int32(result.AgentsEvaluated),
+		Findings:        int32(result.Findings),
+	})
+}
+
+func (s *AgentServer) ListVulnerabilityFindings(ctx context.Context, req *pb.ListVulnerabilityFindingsRequest) (*pb.ListVulnerabilityFindingsResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	if req.GetAgentId() != "" {
+		if _, err := s.agentLogic.GetAgentByID(req.GetAgentId()); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, status.Errorf(codes.NotFound, "Agent not found")
+			}
+			return nil, status.Errorf(codes.Internal, "Database error")
+		}
+	}
+
+	findings, err := s.vulnLogic.ListFindings(req.GetAgentId(), req.GetVulnerabilityId(), mapProtoToModelSeverity(req.GetMinSeverity()), limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListVulnerabilityFindingsResponse{}
+	for i := range findings {
+		resp.Findings = append(resp.Findings, mapModelToProtoVulnerabilityFinding(&findings[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) ListFleetVulnerabilities(ctx context.Context, req *pb.ListFleetVulnerabilitiesRequest) (*pb.ListFleetVulnerabilitiesResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	vulns, err := s.vulnLogic.ListFleetVulnerabilities(mapProtoToModelSeverity(req.GetMinSeverity()), limit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListFleetVulnerabilitiesResponse{}
+	for i := range vulns {
+		resp.Vulnerabilities = append(resp.Vulnerabilities, mapModelToProtoFleetVulnerability(&vulns[i]))
+	}
+	return resp, nil
+}
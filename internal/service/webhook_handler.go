@@ -0,0 +1,98 @@
+// internal/service/webhook_handler.go
+
+package service
+
+import (
+	pb "agent_server/agent_server/proto"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+func (s *AgentServer) CreateWebhookSubscription(ctx context.Context, req *pb.CreateWebhookSubscriptionRequest) (*pb.CreateWebhookSubscriptionResponse, error) {
+	sub, err := s.webhookLogic.CreateSubscription(&model.WebhookSubscription{
+		URL:         req.GetUrl(),
+		Secret:      req.GetSecret(),
+		EventTypes:  strings.Join(req.GetEventTypes(), ","),
+		Description: req.GetDescription(),
+	})
+	if err != nil {
+		return nil, webhookError(err, "Could not create webhook subscription")
+	}
+	return &pb.CreateWebhookSubscriptionResponse{
+		Subscription: mapModelToProtoWebhookSubscription(sub),
+		Secret:       sub.Secret,
+	}, nil
+}
+
+func (s *AgentServer) ListWebhookSubscriptions(ctx context.Context, req *pb.ListWebhookSubscriptionsRequest) (*pb.ListWebhookSubscriptionsResponse, error) {
+	subs, err := s.webhookLogic.ListSubscriptions()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListWebhookSubscriptionsResponse{}
+	for i := range subs {
+		resp.Subscriptions = append(resp.Subscriptions, mapModelToProtoWebhookSubscription(&subs[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) DeleteWebhookSubscription(ctx context.Context, req *pb.DeleteWebhookSubscriptionRequest) (*pb.DeleteWebhookSubscriptionResponse, error) {
+	if req.GetSubscriptionId() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Subscription ID is required")
+	}
+	if err := s.webhookLogic.DeleteSubscription(uint(req.GetSubscriptionId())); err != nil {
+		return nil, webhookError(err, "Could not delete webhook subscription")
+	}
+	return &pb.DeleteWebhookSubscriptionResponse{}, nil
+}
+
+func (s *AgentServer) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+
+	deliveries, err := s.webhookLogic.ListDeliveries(model.WebhookDeliveryFilter{
+		SubscriptionID: uint(req.GetSubscriptionId()),
+		Status:         mapProtoToModelWebhookStatus(req.GetStatus()),
+		Limit:          limit,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Database error")
+	}
+	resp := &pb.ListWebhookDeliveriesResponse{}
+	for i := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, mapModelToProtoWebhookDelivery(&deliveries[i]))
+	}
+	return resp, nil
+}
+
+func (s *AgentServer) ReplayWebhookDeliveries(ctx context.Context, req *pb.ReplayWebhookDeliveriesRequest) (*pb.ReplayWebhookDeliveriesResponse, error) {
+	ids := make([]uint, len(req.GetDeliveryIds()))
+	for i, id := range req.GetDeliveryIds() {
+		ids[i] = uint(id)
+	}
+	n, err := s.webhookLogic.ReplayDeliveries(ids, uint(req.GetSubscriptionId()))
+	if err != nil {
+		return nil, webhookError(err, "Could not replay webhook deliveries")
+	}
+	return &pb.ReplayWebhookDeliveriesResponse{Replayed: n}, nil
+}
+
+// webhookError maps use case errors to gRPC status codes.
+func webhookError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Errorf(codes.NotFound, "Subscription not found")
+	case errors.Is(err, usecase.ErrInvalidSubscription):
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return status.Errorf(codes.Internal, "%s", fallback)
+}
@@ -0,0 +1,127 @@
+// internal/software/normalize.go
+
+// Package software turns the free-text application names, publishers and
+// versions reported by agents into comparable product identifiers and
+// versions.
+package software
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Parenthesised notes such as "(x64 en-US)" and bitness markers.
+	noisePattern = regexp.MustCompile(`\([^)]*\)|\[[^]]*\]|\b(32|64)[- ]?bit\b`)
+	// Separators between words; '+' is kept for names like notepad++.
+	separatorPattern = regexp.MustCompile(`[^a-z0-9+]+`)
+	// Words left over from version numbers once split on separators:
+	// "118.0.2" becomes "118" "0" "2"; also "v2" and "2016".
+	versionWordPattern = regexp.MustCompile(`^v?[0-9]+$`)
+)
+
+// noiseWords carry no product information.
+var noiseWords = map[string]bool{
+	"x64": true, "x86": true, "x86_64": true, "amd64": true, "arm64": true, "win64": true, "win32": true,
+}
+
+// companySuffixes are dropped from publishers to get the vendor.
+var companySuffixes = map[string]bool{
+	"inc": true, "llc": true, "ltd": true, "limited": true, "corp": true, "corporation": true,
+	"co": true, "gmbh": true, "ag": true, "sa": true, "bv": true, "plc": true, "the": true,
+	"foundation": true, "software": true, "technologies": true, "project": true,
+}
+
+// NormalizeIdentifier lowercases s and joins its words with underscores,
+// the way CPE names products ("Google Chrome" -> "google_chrome").
+func NormalizeIdentifier(s string) string {
+	s = strings.ToLower(strings.ReplaceAll(s, `\`, ""))
+	return strings.Trim(separatorPattern.ReplaceAllString(s, "_"), "_")
+}
+
+// ProductKey builds the identifier used to match feeds: "vendor:product",
+// or "*:product" when the vendor is unknown.
+func ProductKey(vendor, product string) string {
+	if vendor == "" {
+		vendor = "*"
+	} else {
+		vendor = NormalizeIdentifier(vendor)
+	}
+	return vendor + ":" + NormalizeIdentifier(product)
+}
+
+// nameWords splits an application name into normalized words without
+// versions, architecture and language notes.
+func nameWords(name string) []string {
+	name = noisePattern.ReplaceAllString(strings.ToLower(name), " ")
+	var words []string
+	for _, w := range strings.Split(separatorPattern.ReplaceAllString(name, " "), " ") {
+		if w != "" && !noiseWords[w] {
+			words = append(words, w)
+		}
+	}
+	for len(words) > 1 && versionWordPattern.MatchString(words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	return words
+}
+
+// Vendor derives a vendor identifier from a publisher ("Mozilla
+// Corporation" -> "mozilla"). It returns "" when nothing is left.
+func Vendor(publisher string) string {
+	var words []string
+	for _, w := range strings.Fields(separatorPattern.ReplaceAllString(strings.ToLower(publisher), " ")) {
+		if !companySuffixes[w] {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}
+
+// Candidates returns the product keys an installed application may be known
+// by in a feed, most specific first. For "Mozilla Firefox 118.0 (x64 en-US)"
+// published by "Mozilla" they include "mozilla:firefox",
+// "mozilla:mozilla_firefox" and "*:firefox".
+func Candidates(name, publisher string) []string {
+	words := nameWords(name)
+	if len(words) == 0 {
+		return nil
+	}
+	full := strings.Join(words, "_")
+
+	vendors := []string{}
+	if v := Vendor(publisher); v != "" {
+		vendors = append(vendors, v)
+	}
+	vendors = append(vendors, words[0], full)
+
+	// Without the vendor prefix: "google_chrome" -> "chrome".
+	products := []string{full}
+	for _, v := range vendors {
+		if len(words) > 1 && words[0] == v {
+			products = append([]string{strings.Join(words[1:], "_")}, products...)
+			break
+		}
+	}
+
+	seen := map[string]bool{}
+	var keys []string
+	add := func(k string) {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for _, v := range vendors {
+		for _, p := range products {
+			add(v + ":" + p)
+		}
+	}
+	for _, p := range products {
+		add("*:" + p)
+	}
+	return keys
+}
@@ -0,0 +1,179 @@
+// internal/software/rules.go
+
+package software
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default_rules.yaml
+var defaultRulesYAML []byte
+
+// Product is the canonical identity of an installed application.
+type Product struct {
+	Key     string // "vendor:product", see ProductKey
+	Name    string // display name; empty when no rule matched
+	Scheme  string // version scheme; SchemeAuto when unknown
+	Matched bool   // whether a catalog rule matched
+}
+
+// ruleFile is the YAML layout of a rules file (see default_rules.yaml).
+type ruleFile struct {
+	Products []struct {
+		Product string `yaml:"product"`
+		Name    string `yaml:"name"`
+		Scheme  string `yaml:"scheme"`
+		Match   []struct {
+			Name      string `yaml:"name"`
+			Publisher string `yaml:"publisher"`
+		} `yaml:"match"`
+	} `yaml:"products"`
+}
+
+type matcher struct {
+	name, publisher *regexp.Regexp // publisher is nil when any publisher matches
+}
+
+type rule struct {
+	product  Product
+	matchers []matcher
+}
+
+// Rules maps raw application name/publisher pairs to canonical products.
+// A Rules value is immutable and safe for concurrent use.
+type Rules struct {
+	rules []rule
+}
+
+// DefaultRules returns the built-in rules.
+func DefaultRules() *Rules {
+	r, err := ParseRules(defaultRulesYAML)
+	if err != nil {
+		panic("software: invalid built-in rules: " + err.Error())
+	}
+	return r
+}
+
+// LoadRules reads the rules file at path, followed by the built-in rules
+// so that the file can override them. An empty path means the built-in
+// rules only.
+func LoadRules(path string) (*Rules, error) {
+	defaults := DefaultRules()
+	if path == "" {
+		return defaults, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r.rules = append(r.rules, defaults.rules...)
+	return r, nil
+}
+
+// ParseRules parses a rules file. Rules are tried in order.
+func ParseRules(data []byte) (*Rules, error) {
+	var f ruleFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	r := &Rules{}
+	for i, p := range f.Products {
+		vendor, product, ok := strings.Cut(p.Product, ":")
+		if !ok || vendor == "" || product == "" {
+			return nil, fmt.Errorf("products[%d]: product %q must be vendor:product", i, p.Product)
+		}
+		if p.Scheme != "" && !validScheme(p.Scheme) {
+			return nil, fmt.Errorf("products[%d] (%s): unknown scheme %q (want one of %s)", i, p.Product, p.Scheme, strings.Join(Schemes, ", "))
+		}
+		if len(p.Match) == 0 {
+			return nil, fmt.Errorf("products[%d] (%s): at least one match is required", i, p.Product)
+		}
+		ru := rule{product: Product{Key: ProductKey(vendor, product), Name: p.Name, Scheme: p.Scheme, Matched: true}}
+		if ru.product.Name == "" {
+			ru.product.Name = p.Product
+		}
+		for j, m := range p.Match {
+			if m.Name == "" {
+				return nil, fmt.Errorf("products[%d] (%s): match[%d]: name is required", i, p.Product, j)
+			}
+			name, err := regexp.Compile("(?i)" + m.Name)
+			if err != nil {
+				return nil, fmt.Errorf("products[%d] (%s): match[%d]: %w", i, p.Product, j, err)
+			}
+			mt := matcher{name: name}
+			if m.Publisher != "" {
+				if mt.publisher, err = regexp.Compile("(?i)" + m.Publisher); err != nil {
+					return nil, fmt.Errorf("products[%d] (%s): match[%d]: %w", i, p.Product, j, err)
+				}
+			}
+			ru.matchers = append(ru.matchers, mt)
+		}
+		r.rules = append(r.rules, ru)
+	}
+	return r, nil
+}
+
+func validScheme(s string) bool {
+	for _, known := range Schemes {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of products the rules know.
+func (r *Rules) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.rules)
+}
+
+// Normalize returns the product of the first matching rule. Without one,
+// the key is the most specific of Candidates and Matched is false.
+func (r *Rules) Normalize(name, publisher string) Product {
+	name, publisher = strings.TrimSpace(name), strings.TrimSpace(publisher)
+	if r != nil {
+		for _, ru := range r.rules {
+			for _, m := range ru.matchers {
+				if m.name.MatchString(name) && (m.publisher == nil || m.publisher.MatchString(publisher)) {
+					return ru.product
+				}
+			}
+		}
+	}
+	if keys := Candidates(name, publisher); len(keys) > 0 {
+		return Product{Key: keys[0]}
+	}
+	return Product{Key: ProductKey("", name)}
+}
+
+// Candidates returns the product keys an application may be known by in a
+// vulnerability feed: the key of the matching rule, if any, followed by the
+// generic Candidates.
+func (r *Rules) Candidates(name, publisher string) []string {
+	keys := Candidates(name, publisher)
+	p := r.Normalize(name, publisher)
+	if !p.Matched {
+		return keys
+	}
+	_, product, _ := strings.Cut(p.Key, ":")
+	out := []string{p.Key, "*:" + product}
+	for _, k := range keys {
+		if k != out[0] && k != out[1] {
+			out = append(out, k)
+		}
+	}
+	return out
+}
@@ -0,0 +1,329 @@
+// internal/software/version.go
+
+package software
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Version schemes understood by Compare.
+const (
+	// SchemeAuto picks a scheme from the versions being compared.
+	SchemeAuto = ""
+	// SchemeGeneric splits versions into digit and letter runs; it also
+	// fits dotted Windows versions such as "10.0.19041.3570".
+	SchemeGeneric = "generic"
+	// SchemeWindows is an alias of SchemeGeneric for readability in rules.
+	SchemeWindows = "windows"
+	// SchemeSemver follows semver.org: "1.2.0-rc.1" < "1.2.0", build
+	// metadata after "+" is ignored.
+	SchemeSemver = "semver"
+	// SchemeDebian follows dpkg: "[epoch:]upstream[-revision]" where "~"
+	// sorts before anything, so "1.0~rc1" < "1.0".
+	SchemeDebian = "debian"
+)
+
+// Schemes lists the valid scheme names.
+var Schemes = []string{SchemeGeneric, SchemeWindows, SchemeSemver, SchemeDebian}
+
+var (
+	semverPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+	// An epoch ("1:2.3"), a tilde or a numeric revision ("2.7.4-1ubuntu1")
+	// only appear in package manager versions.
+	debianPattern = regexp.MustCompile(`^[0-9]+:|~|-[0-9][0-9A-Za-z.+~]*$`)
+	// A single letter right after the last number is a patch letter, as in
+	// OpenSSL's "1.0.2k".
+	postReleasePattern = regexp.MustCompile(`[0-9][a-z]$`)
+)
+
+// DetectScheme guesses the scheme of a single version string.
+func DetectScheme(v string) string {
+	v = strings.TrimSpace(v)
+	switch {
+	case debianPattern.MatchString(v):
+		return SchemeDebian
+	case semverPattern.MatchString(v) && strings.ContainsAny(v, "-+"):
+		return SchemeSemver
+	}
+	return SchemeGeneric
+}
+
+// Compare compares two versions under scheme and returns -1, 0 or 1.
+// With SchemeAuto the scheme is detected from both versions, preferring
+// Debian and then semver when either version looks like one.
+func Compare(scheme, a, b string) int {
+	if scheme == SchemeAuto {
+		sa, sb := DetectScheme(a), DetectScheme(b)
+		switch {
+		case sa == SchemeDebian || sb == SchemeDebian:
+			scheme = SchemeDebian
+		case sa == SchemeSemver || sb == SchemeSemver:
+			scheme = SchemeSemver
+		}
+	}
+	switch scheme {
+	case SchemeDebian:
+		return compareDebian(a, b)
+	case SchemeSemver:
+		return compareSemver(a, b)
+	}
+	return compareGeneric(a, b)
+}
+
+// CompareVersions compares two versions of unknown scheme; it is
+// Compare(SchemeAuto, a, b).
+func CompareVersions(a, b string) int {
+	return Compare(SchemeAuto, a, b)
+}
+
+// compareGeneric splits versions into runs of digits and letters; digit
+// runs compare numerically and letter runs alphabetically, so "1.10" > "1.9"
+// and "10.0.19041.1" > "10.0.9200". Missing trailing zeros are ignored
+// ("1.0" == "1.0.0") and a trailing letter run marks a pre-release
+// ("1.0beta" < "1.0"), except for a single letter directly after the last
+// number, which marks a post-release ("1.0.2" < "1.0.2a" < "1.0.2k" < "1.0.3").
+func compareGeneric(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		switch {
+		case i >= len(as):
+			return -tail(bs[i:], isPostRelease(b))
+		case i >= len(bs):
+			return tail(as[i:], isPostRelease(a))
+		}
+		if c := comparePart(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// tail decides how the remaining parts of the longer version compare with
+// nothing: zeros are equal, a letter run is a pre-release unless it is the
+// post-release letter of the version, anything else is newer.
+func tail(parts []string, postRelease bool) int {
+	for i, p := range parts {
+		if isDigits(p) {
+			if strings.TrimLeft(p, "0") != "" {
+				return 1
+			}
+			continue
+		}
+		if postRelease && i == len(parts)-1 {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// isPostRelease reports whether v ends in a patch letter such as the "k" of
+// "1.0.2k".
+func isPostRelease(v string) bool {
+	return postReleasePattern.MatchString(strings.ToLower(strings.TrimSpace(v)))
+}
+
+func comparePart(a, b string) int {
+	ad, bd := isDigits(a), isDigits(b)
+	switch {
+	case ad && bd:
+		return compareNumeric(a, b)
+	case ad:
+		return 1 // "1.0.1" > "1.0.beta"
+	case bd:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// versionParts splits "1.2.3-rc1" into ["1" "2" "3" "rc" "1"].
+func versionParts(v string) []string {
+	var parts []string
+	var cur []rune
+	curDigits := false
+	flush := func() {
+		if len(cur) > 0 {
+			parts = append(parts, string(cur))
+			cur = cur[:0]
+		}
+	}
+	for _, r := range strings.ToLower(strings.TrimSpace(v)) {
+		switch {
+		case unicode.IsDigit(r):
+			if !curDigits {
+				flush()
+			}
+			curDigits = true
+			cur = append(cur, r)
+		case unicode.IsLetter(r):
+			if curDigits {
+				flush()
+			}
+			curDigits = false
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return parts
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// compareNumeric compares digit strings of any length.
+func compareNumeric(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareSemver(a, b string) int {
+	coreA, preA := splitSemver(a)
+	coreB, preB := splitSemver(b)
+	if c := compareGeneric(coreA, coreB); c != 0 {
+		return c
+	}
+	switch {
+	case preA == "" && preB == "":
+		return 0
+	case preA == "":
+		return 1 // a release is newer than its pre-releases
+	case preB == "":
+		return -1
+	}
+	idsA, idsB := strings.Split(preA, "."), strings.Split(preB, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		x, y := idsA[i], idsB[i]
+		xd, yd := isDigits(x), isDigits(y)
+		var c int
+		switch {
+		case xd && yd:
+			c = compareNumeric(x, y)
+		case xd:
+			c = -1 // numeric identifiers sort before alphanumeric ones
+		case yd:
+			c = 1
+		default:
+			c = strings.Compare(x, y)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(idsA), len(idsB))
+}
+
+// splitSemver returns the core version and the pre-release, dropping a
+// leading "v" and build metadata.
+func splitSemver(v string) (core, pre string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	core, pre, _ = strings.Cut(v, "-")
+	return core, pre
+}
+
+func compareDebian(a, b string) int {
+	epochA, upA, revA := splitDebian(a)
+	epochB, upB, revB := splitDebian(b)
+	if c := compareNumeric(epochA, epochB); c != 0 {
+		return c
+	}
+	if c := verrevcmp(upA, upB); c != 0 {
+		return c
+	}
+	return verrevcmp(revA, revB)
+}
+
+// splitDebian splits "[epoch:]upstream[-revision]"; the revision starts at
+// the last hyphen.
+func splitDebian(v string) (epoch, upstream, revision string) {
+	v = strings.TrimSpace(v)
+	epoch = "0"
+	if i := strings.IndexByte(v, ':'); i >= 0 && isDigits(v[:i]) {
+		epoch, v = v[:i], v[i+1:]
+	}
+	if i := strings.LastIndexByte(v, '-'); i >= 0 {
+		return epoch, v[:i], v[i+1:]
+	}
+	return epoch, v, ""
+}
+
+// verrevcmp is dpkg's comparison of upstream versions and revisions:
+// non-digit runs compare by debianOrder, digit runs numerically.
+func verrevcmp(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigitByte(a[0])) || (b != "" && !isDigitByte(b[0])) {
+			var ca, cb int
+			if a != "" {
+				ca = debianOrder(a[0])
+			}
+			if b != "" {
+				cb = debianOrder(b[0])
+			}
+			if ca != cb {
+				return compareInts(ca, cb)
+			}
+			a, b = a[1:], b[1:]
+		}
+		var da, db string
+		da, a = leadingDigits(a)
+		db, b = leadingDigits(b)
+		if c := compareNumeric(da, db); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// debianOrder ranks a character in a non-digit run: "~" sorts before the
+// end of the string, letters before other symbols.
+func debianOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case isDigitByte(c):
+		return 0
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return int(c)
+	}
+	return int(c) + 256
+}
+
+func leadingDigits(s string) (digits, rest string) {
+	i := 0
+	for i < len(s) && isDigitByte(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigitByte(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
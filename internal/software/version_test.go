@@ -0,0 +1,58 @@
+package software_test
+
+import (
+	"agent_server/internal/software"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		scheme string
+		a, b   string
+		want   int
+	}{
+		{software.SchemeGeneric, "1.10", "1.9", 1},
+		{software.SchemeGeneric, "1.0", "1.0.0", 0},
+		{software.SchemeGeneric, "1.0beta", "1.0", -1},
+		{software.SchemeGeneric, "1.0rc1", "1.0", -1},
+
+		// A single letter right after the last number is a post-release.
+		{software.SchemeGeneric, "1.0.2", "1.0.2a", -1},
+		{software.SchemeGeneric, "1.0.2a", "1.0.2k", -1},
+		{software.SchemeGeneric, "1.0.2k", "1.0.3", -1},
+		{software.SchemeGeneric, "1.0.2", "1.0.2k", -1},
+		{software.SchemeGeneric, "1.0.2a", "1.0.3", -1},
+		{software.SchemeGeneric, "1.0.2K", "1.0.2k", 0},
+		{software.SchemeGeneric, "1.0.2-k", "1.0.2", -1},
+		{software.SchemeAuto, "1.1.1w", "1.1.1", 1},
+
+		{software.SchemeWindows, "10.0.19041.3570", "10.0.9200.0", 1},
+		{software.SchemeWindows, "118.0.5993.118", "118.0.5993.88", 1},
+
+		{software.SchemeSemver, "1.2.0-rc.1", "1.2.0", -1},
+		{software.SchemeSemver, "1.2.0-rc.2", "1.2.0-rc.10", -1},
+		{software.SchemeSemver, "1.2.0-alpha", "1.2.0-1", 1},
+		{software.SchemeSemver, "1.2.0-alpha", "1.2.0-alpha.1", -1},
+		{software.SchemeSemver, "v1.2.0+build.5", "1.2.0", 0},
+
+		{software.SchemeDebian, "1:1.0", "2.0", 1},
+		{software.SchemeDebian, "1.0~rc1", "1.0", -1},
+		{software.SchemeDebian, "2.7.4-1ubuntu1", "2.7.4-1", 1},
+		{software.SchemeDebian, "2.7.4-1ubuntu1", "2.7.4-1ubuntu1.1", -1},
+		{software.SchemeDebian, "1.0-1", "1.0+dfsg-1", -1},
+
+		// Auto-detection: a numeric revision makes it Debian, not a pre-release.
+		{software.SchemeAuto, "3.0.2-0ubuntu1.10", "3.0.2", 1},
+		{software.SchemeAuto, "1.0.0-beta.2", "1.0.0", -1},
+		{software.SchemeAuto, "1:9.0", "10.0", 1},
+		{software.SchemeAuto, "118.0.2", "118.0", 1},
+	}
+	for _, tt := range tests {
+		if got := software.Compare(tt.scheme, tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q, %q) = %d, want %d", tt.scheme, tt.a, tt.b, got, tt.want)
+		}
+		if got := software.Compare(tt.scheme, tt.b, tt.a); got != -tt.want {
+			t.Errorf("Compare(%q, %q, %q) = %d, want %d", tt.scheme, tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// internal/updates/updates.go
+
+// Package updates holds the rules of agent update rollouts: which agents a
+// rollout reaches, how agent versions compare and how package signatures are
+// checked.
+//
+// An agent is in a rollout when it matches one of the rollout's groups (label
+// selectors such as "ring=canary") and its bucket, a stable number from 0 to
+// 99 derived from the rollout and agent IDs, is below the rollout's
+// percentage. Raising the percentage therefore only adds agents.
+package updates
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Operating systems packages are built for.
+const (
+	OSWindows = "windows"
+	OSLinux   = "linux"
+	OSDarwin  = "darwin"
+)
+
+// OSFamily maps the OS name an agent reports, such as "Windows Server 2022"
+// or "Ubuntu", to the OS of its packages.
+func OSFamily(osName string) string {
+	name := strings.ToLower(osName)
+	switch {
+	case strings.Contains(name, "windows"):
+		return OSWindows
+	case strings.Contains(name, "darwin"), strings.Contains(name, "mac"):
+		return OSDarwin
+	case name == "":
+		return ""
+	}
+	return OSLinux
+}
+
+// ValidOS reports whether os is one of the package operating systems.
+func ValidOS(os string) bool {
+	return os == OSWindows || os == OSLinux || os == OSDarwin
+}
+
+// CompareVersions compares dotted versions such as "1.10.2" and "v1.9" part
+// by part, numerically where both parts are numbers. It returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	pa := strings.FieldsFunc(strings.TrimPrefix(a, "v"), isVersionSeparator)
+	pb := strings.FieldsFunc(strings.TrimPrefix(b, "v"), isVersionSeparator)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y string
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if c := comparePart(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func isVersionSeparator(r rune) bool {
+	return r == '.' || r == '-' || r == '+'
+}
+
+func comparePart(x, y string) int {
+	nx, errX := strconv.Atoi(x)
+	ny, errY := strconv.Atoi(y)
+	switch {
+	case x == y:
+		return 0
+	case x == "":
+		// 1.2 < 1.2.1, but 1.2 > 1.2-rc1.
+		if errY != nil {
+			return 1
+		}
+		return -1
+	case y == "":
+		if errX != nil {
+			return -1
+		}
+		return 1
+	case errX == nil && errY == nil:
+		if nx < ny {
+			return -1
+		}
+		if nx > ny {
+			return 1
+		}
+		return 0
+	case errX == nil:
+		return 1
+	case errY == nil:
+		return -1
+	}
+	return strings.Compare(x, y)
+}
+
+// Bucket places an agent in one of 100 buckets of a rollout.
+func Bucket(rolloutID uint, agentID string) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d/%s", rolloutID, agentID)
+	return int(h.Sum32() % 100)
+}
+
+// ValidateGroups checks that every group is a key=value label selector.
+func ValidateGroups(groups []string) error {
+	for _, g := range groups {
+		if k, _, ok := strings.Cut(g, "="); !ok || k == "" {
+			return fmt.Errorf("group %q must be a label selector such as ring=canary", g)
+		}
+	}
+	return nil
+}
+
+// InGroups reports whether labels match one of groups. No groups match every
+// agent.
+func InGroups(groups []string, labels map[string]string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+	for _, g := range groups {
+		k, v, _ := strings.Cut(g, "=")
+		if got, ok := labels[k]; ok && got == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, errors.New("signing public key must be a base64 ed25519 public key")
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// VerifySignature checks a base64 ed25519 signature of a package's hex
+// SHA-256. Signing the digest keeps signatures cheap to check for large
+// packages.
+func VerifySignature(key ed25519.PublicKey, sha256Hex, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.New("package signature is not valid base64")
+	}
+	digest, err := hex.DecodeString(sha256Hex)
+	if err != nil {
+		return errors.New("package digest is not valid hex")
+	}
+	if !ed25519.Verify(key, digest, sig) {
+		return errors.New("package signature does not match the signing key")
+	}
+	return nil
+}
@@ -3,10 +3,13 @@
 package usecase
 
 import (
+	"agent_server/internal/logging"
 	"agent_server/internal/model"
 	"agent_server/internal/repository"
 	"errors"
-	"log"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,19 +19,33 @@ import (
 type AgentUseCase interface {
 	RegisterAgent(agent *model.Agent) (*model.Agent, error)
 	GetAgentByID(agentID string) (*model.Agent, error)
-	ProcessHeartbeat(agentID, ip string) (int64, error)
+	ProcessHeartbeat(agentID, ip, peerIP string) (int64, error)
 	StoreFirewallRules(agentID string, rules []model.FirewallRule) error
 	StoreInstalledApps(agentID string, apps []model.InstalledApplication) error
-	MarkOfflineAgents() error
+	ListAgents(filter model.AgentFilter) ([]model.Agent, error)
+	DecommissionAgent(agentID string) (*model.Agent, error)
+	GetCurrentFirewallRules(agentID string) ([]model.FirewallRule, error)
+	GetCurrentInstalledApps(agentID string) ([]model.InstalledApplication, error)
+	MarkOfflineAgents(offlineAfter time.Duration) error
+	PurgeExpiredReports(firewallRulesAge, installedAppsAge time.Duration) error
+	GetIPHistory(filter model.IPHistoryFilter) ([]model.AgentIPAddress, error)
 }
 
 type agentUseCase struct {
-	repo repository.AgentRepository
+	repo   repository.AgentRepository
+	beats  *HeartbeatBuffer
+	events EventPublisher
+
+	// offline holds the agents this server marked OFFLINE, so that their
+	// next heartbeat can be announced as a status change.
+	offline sync.Map
 }
 
 // NewAgentUseCase creates a new instance of the agent use case layer.
-func NewAgentUseCase(repo repository.AgentRepository) AgentUseCase {
-	return &agentUseCase{repo: repo}
+// When beats is nil every heartbeat is written to the repository immediately.
+// Fleet events go to events, which may be nil.
+func NewAgentUseCase(repo repository.AgentRepository, beats *HeartbeatBuffer, events EventPublisher) AgentUseCase {
+	return &agentUseCase{repo: repo, beats: beats, events: publisherOrNoop(events)}
 }
 
 // RegisterAgent handles the core logic of registering an agent.
@@ -45,11 +62,30 @@ func (uc *agentUseCase) RegisterAgent(agent *model.Agent) (*model.Agent, error)
 		existingAgent.CPUCores = agent.CPUCores
 		existingAgent.MemoryGB = agent.MemoryGB
 		existingAgent.DiskSpaceGB = agent.DiskSpaceGB
+		existingAgent.Labels = agent.Labels
+		existingAgent.AgentVersion = agent.AgentVersion
+		existingAgent.Arch = agent.Arch
+		if agent.LastKnownIP != "" {
+			existingAgent.LastKnownIP = agent.LastKnownIP
+		}
+		if agent.LastPeerIP != "" {
+			existingAgent.LastPeerIP = agent.LastPeerIP
+		}
+		if agent.InstanceNonce != "" {
+			existingAgent.InstanceNonce = agent.InstanceNonce
+		}
+		previousStatus := existingAgent.Status
 		existingAgent.Status = "ONLINE"
 		existingAgent.LastSeen = time.Now()
 
-		err = uc.repo.UpdateAgent(existingAgent)
-		return existingAgent, err
+		if err = uc.repo.UpdateAgent(existingAgent); err != nil {
+			return existingAgent, err
+		}
+		uc.offline.Delete(agent.AgentID)
+		uc.recordAddress(existingAgent)
+		uc.events.Publish(agentEvent(model.EventAgentUpdated, existingAgent, agentDetails(existingAgent)))
+		uc.publishStatusChange(existingAgent, previousStatus)
+		return existingAgent, nil
 	}
 
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -60,8 +96,56 @@ func (uc *agentUseCase) RegisterAgent(agent *model.Agent) (*model.Agent, error)
 	// Agent does not exist, create a new one.
 	agent.Status = "ONLINE"
 	agent.LastSeen = time.Now()
-	err = uc.repo.CreateAgent(agent)
-	return agent, err
+	if err = uc.repo.CreateAgent(agent); err != nil {
+		return nil, err
+	}
+	if uc.beats != nil {
+		uc.beats.MarkKnown(agent.AgentID)
+	}
+	uc.recordStatusChange(agent.AgentID, "", agent.Status)
+	uc.recordAddress(agent)
+	uc.events.Publish(agentEvent(model.EventAgentRegistered, agent, agentDetails(agent)))
+	return agent, nil
+}
+
+// agentDetails is the event data describing an agent's static information.
+func agentDetails(agent *model.Agent) map[string]interface{} {
+	return map[string]interface{}{
+		"hostname":      agent.Hostname,
+		"os_name":       agent.OSName,
+		"os_version":    agent.OSVersion,
+		"agent_version": agent.AgentVersion,
+	}
+}
+
+// publishStatusChange announces that agent moved from previous to its current status.
+func (uc *agentUseCase) publishStatusChange(agent *model.Agent, previous string) {
+	if previous == agent.Status {
+		return
+	}
+	uc.recordStatusChange(agent.AgentID, previous, agent.Status)
+	uc.events.Publish(agentEvent(model.EventAgentStatusChanged, agent, map[string]interface{}{
+		"from": previous,
+		"to":   agent.Status,
+	}))
+}
+
+// recordStatusChange adds a status transition to the agent's history. A
+// failure is logged but does not fail the operation that changed the status.
+func (uc *agentUseCase) recordStatusChange(agentID, from, to string) {
+	change := &model.AgentStatusChange{AgentID: agentID, FromStatus: from, ToStatus: to, ChangedAt: time.Now()}
+	if err := uc.repo.RecordStatusChange(change); err != nil {
+		logging.Errorf("Failed to record status change of agent %s: %v", agentID, err)
+	}
+}
+
+// recordAddress adds the addresses an agent registered with to its IP
+// history. Like recordStatusChange, a failure is only logged.
+func (uc *agentUseCase) recordAddress(agent *model.Agent) {
+	beat := model.Heartbeat{AgentID: agent.AgentID, IP: agent.LastKnownIP, PeerIP: agent.LastPeerIP, SeenAt: agent.LastSeen}
+	if err := uc.repo.RecordAddresses([]model.Heartbeat{beat}); err != nil {
+		logging.Errorf("Failed to record address of agent %s: %v", agent.AgentID, err)
+	}
 }
 
 // GetAgentByID retrieves a single agent.
@@ -69,9 +153,32 @@ func (uc *agentUseCase) GetAgentByID(agentID string) (*model.Agent, error) {
 	return uc.repo.FindAgentByID(agentID)
 }
 
-// ProcessHeartbeat updates the agent's status.
-func (uc *agentUseCase) ProcessHeartbeat(agentID, ip string) (int64, error) {
-	return uc.repo.UpdateHeartbeat(agentID, ip)
+// ProcessHeartbeat updates the agent's status. With a heartbeat buffer the
+// beat is queued for the next bulk write; it returns 0 for unknown agents.
+func (uc *agentUseCase) ProcessHeartbeat(agentID, ip, peerIP string) (int64, error) {
+	var rows int64 = 1
+	if uc.beats == nil {
+		n, err := uc.repo.UpdateHeartbeat(agentID, ip, peerIP)
+		if err != nil || n == 0 {
+			return n, err
+		}
+		rows = n
+	} else {
+		known, err := uc.beats.Record(agentID, ip, peerIP)
+		if err != nil || !known {
+			return 0, err
+		}
+	}
+
+	uc.events.Publish(NewEvent(model.EventAgentHeartbeat, agentID, map[string]interface{}{"ip": ip, "peer_ip": peerIP}))
+	// The repository records the return to ONLINE in the status history.
+	if _, wasOffline := uc.offline.LoadAndDelete(agentID); wasOffline {
+		uc.events.Publish(NewEvent(model.EventAgentStatusChanged, agentID, map[string]interface{}{
+			"from": "OFFLINE",
+			"to":   "ONLINE",
+		}))
+	}
+	return rows, nil
 }
 
 // StoreFirewallRules validates and stores firewall rules for an agent.
@@ -82,12 +189,78 @@ func (uc *agentUseCase) StoreFirewallRules(agentID string, rules []model.Firewal
 		return err // Return error if agent not found or other DB issue.
 	}
 
-	// Assign the agent's primary key to each rule.
+	// Keep the previous report to tell subscribers what changed.
+	previous, err := uc.repo.FindCurrentFirewallRules(agent.ID)
+	if err != nil {
+		return err
+	}
+
+	// Assign the agent's primary key to each rule and stamp them as one report.
+	reportedAt := time.Now()
 	for i := range rules {
 		rules[i].AgentID = agent.ID
+		rules[i].ReportedAt = reportedAt
+	}
+
+	if err := uc.repo.CreateFirewallRules(rules); err != nil {
+		return err
 	}
 
-	return uc.repo.CreateFirewallRules(rules)
+	added, removed := diffFirewallRules(previous, rules)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	uc.events.Publish(inventoryEvent(agent, "firewall_rules", len(added), len(removed), len(rules)))
+	// The first report is the baseline, not a change.
+	if len(previous) > 0 {
+		uc.events.Publish(agentEvent(model.EventFirewallChanged, agent, map[string]interface{}{
+			"added":   added,
+			"removed": removed,
+		}))
+	}
+	return nil
+}
+
+// inventoryEvent reports how an agent's inventory of the given kind changed.
+func inventoryEvent(agent *model.Agent, inventory string, added, removed, total int) model.Event {
+	return agentEvent(model.EventInventoryChanged, agent, map[string]interface{}{
+		"inventory": inventory,
+		"added":     added,
+		"removed":   removed,
+		"total":     total,
+	})
+}
+
+// diffFirewallRules compares two reports and returns the rules only present
+// in the new one and the rules only present in the old one, as readable strings.
+func diffFirewallRules(old, updated []model.FirewallRule) (added, removed []string) {
+	key := func(r model.FirewallRule) string {
+		state := "enabled"
+		if !r.Enabled {
+			state = "disabled"
+		}
+		return fmt.Sprintf("%s: %s %s %s/%s %s", r.Name, r.Direction, r.Action, r.Port, r.Protocol, state)
+	}
+	oldSet := make(map[string]bool, len(old))
+	for _, r := range old {
+		oldSet[key(r)] = true
+	}
+	newSet := make(map[string]bool, len(updated))
+	for _, r := range updated {
+		k := key(r)
+		newSet[k] = true
+		if !oldSet[k] {
+			added = append(added, k)
+		}
+	}
+	for k := range oldSet {
+		if !newSet[k] {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
 }
 
 // StoreInstalledApps validates and stores installed apps for an agent.
@@ -98,17 +271,100 @@ func (uc *agentUseCase) StoreInstalledApps(agentID string, apps []model.Installe
 		return err
 	}
 
-	// Assign the agent's primary key to each app.
+	previous, err := uc.repo.FindCurrentInstalledApps(agent.ID)
+	if err != nil {
+		return err
+	}
+
+	// Assign the agent's primary key to each app and stamp them as one report.
+	reportedAt := time.Now()
 	for i := range apps {
 		apps[i].AgentID = agent.ID
+		apps[i].ReportedAt = reportedAt
 	}
 
-	return uc.repo.CreateInstalledApps(apps)
+	if err := uc.repo.CreateInstalledApps(apps); err != nil {
+		return err
+	}
+
+	if added, removed := diffInstalledApps(previous, apps); added > 0 || removed > 0 {
+		uc.events.Publish(inventoryEvent(agent, "installed_apps", added, removed, len(apps)))
+	}
+	return nil
+}
+
+// diffInstalledApps counts the name/version pairs only present in the new
+// report and those only present in the old one.
+func diffInstalledApps(old, updated []model.InstalledApplication) (added, removed int) {
+	key := func(a model.InstalledApplication) string { return a.Name + "\x00" + a.Version }
+	oldSet := make(map[string]bool, len(old))
+	for _, a := range old {
+		oldSet[key(a)] = true
+	}
+	newSet := make(map[string]bool, len(updated))
+	for _, a := range updated {
+		newSet[key(a)] = true
+	}
+	for k := range newSet {
+		if !oldSet[k] {
+			added++
+		}
+	}
+	for k := range oldSet {
+		if !newSet[k] {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// ListAgents returns one page of agents matching the filter.
+func (uc *agentUseCase) ListAgents(filter model.AgentFilter) ([]model.Agent, error) {
+	return uc.repo.ListAgents(filter)
+}
+
+// DecommissionAgent retires an agent. Its heartbeats are rejected from now on,
+// until it registers again.
+func (uc *agentUseCase) DecommissionAgent(agentID string) (*model.Agent, error) {
+	agent, err := uc.repo.FindAgentByID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if uc.beats != nil {
+		uc.beats.Forget(agentID)
+	}
+	uc.offline.Delete(agentID)
+	previousStatus := agent.Status
+	agent.Status = "DECOMMISSIONED"
+	if err := uc.repo.UpdateAgent(agent); err != nil {
+		return nil, err
+	}
+	logging.Infof("Agent %s decommissioned.", agentID)
+	uc.publishStatusChange(agent, previousStatus)
+	return agent, nil
 }
-func (uc *agentUseCase) MarkOfflineAgents() error {
 
-    const reportIntervalSeconds = 300 // 5 minutes
+// GetCurrentFirewallRules returns the firewall rules from the agent's latest report.
+func (uc *agentUseCase) GetCurrentFirewallRules(agentID string) ([]model.FirewallRule, error) {
+	agent, err := uc.repo.FindAgentByID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.FindCurrentFirewallRules(agent.ID)
+}
+
+// GetCurrentInstalledApps returns the installed apps from the agent's latest report.
+func (uc *agentUseCase) GetCurrentInstalledApps(agentID string) ([]model.InstalledApplication, error) {
+	agent, err := uc.repo.FindAgentByID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.FindCurrentInstalledApps(agent.ID)
+}
 
+// MarkOfflineAgents flips ONLINE agents that have not been seen for longer
+// than offlineAfter to OFFLINE.
+func (uc *agentUseCase) MarkOfflineAgents(offlineAfter time.Duration) error {
     // 1. تستدعي  من طبقه المخزون  كل الوكلا الذين حالتهم "ONLINE"
     onlineAgents, err := uc.repo.FindAgentsByStatus("ONLINE")
     if err != nil {
@@ -120,26 +376,71 @@ func (uc *agentUseCase) MarkOfflineAgents() error {
         return nil
     }
 
-    log.Printf("Found %d online agents to check.", len(onlineAgents))
+    logging.Debugf("Found %d online agents to check.", len(onlineAgents))
 
     // 2. قم بالمرور على كل عميل وتطبيق منطق 
     for _, agent := range onlineAgents {
-        interval := time.Duration(reportIntervalSeconds) * time.Second
-        gracePeriod := interval / 10 // 10% grace period
-        if gracePeriod < (10 * time.Second) {
-            gracePeriod = 10 * time.Second // Minimum 10 seconds
+        // النبضات المعلقة في الذاكرة أحدث من قاعدة البيانات
+        lastSeen := agent.LastSeen
+        if uc.beats != nil {
+            if buffered, ok := uc.beats.LastSeen(agent.AgentID); ok && buffered.After(lastSeen) {
+                lastSeen = buffered
+            }
         }
 
-        if time.Since(agent.LastSeen) > (interval + gracePeriod) {
-            log.Printf("Agent %s is now considered OFFLINE. Last seen: %v", agent.AgentID, agent.LastSeen)
+        if time.Since(lastSeen) > offlineAfter {
+            logging.Infof("Agent %s is now considered OFFLINE. Last seen: %v", agent.AgentID, agent.LastSeen)
             agent.Status = "OFFLINE"
 
             // 3. اطلب من طبقه  المخزن تحديث حالة العميل
             if err := uc.repo.UpdateAgent(&agent); err != nil {
-                log.Printf("Failed to update agent %s to OFFLINE: %v", agent.AgentID, err)
+                logging.Errorf("Failed to update agent %s to OFFLINE: %v", agent.AgentID, err)
                 // ملاحظة: نحن لا نوقف العملية بأكملها لو فشل تحديث عميل واحد
+                continue
             }
+            uc.offline.Store(agent.AgentID, struct{}{})
+            uc.events.Publish(agentEvent(model.EventAgentOffline, &agent, map[string]interface{}{
+                "hostname":  agent.Hostname,
+                "last_seen": lastSeen.UTC(),
+            }))
+            uc.publishStatusChange(&agent, "ONLINE")
         }
     }
     return nil
-}
\ No newline at end of file
+}
+
+// PurgeExpiredReports deletes firewall and installed-app reports older than
+// the given ages, keeping each agent's latest one. A zero age keeps that
+// report type forever.
+func (uc *agentUseCase) PurgeExpiredReports(firewallRulesAge, installedAppsAge time.Duration) error {
+	now := time.Now()
+	if firewallRulesAge > 0 {
+		n, err := uc.repo.DeleteFirewallRulesBefore(now.Add(-firewallRulesAge))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			logging.Infof("Retention: deleted %d expired firewall rules.", n)
+		}
+	}
+	if installedAppsAge > 0 {
+		n, err := uc.repo.DeleteInstalledAppsBefore(now.Add(-installedAppsAge))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			logging.Infof("Retention: deleted %d expired installed apps.", n)
+		}
+	}
+	return nil
+}
+
+// GetIPHistory returns the addresses agents were seen at, most recently seen
+// first. With an IP and a window it answers which agents used the address
+// during that window.
+func (uc *agentUseCase) GetIPHistory(filter model.IPHistoryFilter) ([]model.AgentIPAddress, error) {
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		return nil, ErrInvalidWindow
+	}
+	return uc.repo.FindIPHistory(filter)
+}
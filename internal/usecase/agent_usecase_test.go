@@ -0,0 +1,51 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAgentIPHistory(t *testing.T) {
+	store := repository.NewMemoryStore()
+	beats := usecase.NewHeartbeatBuffer(store.Agents, config.StaticProvider(config.Default()))
+	agents := usecase.NewAgentUseCase(store.Agents, beats, nil)
+
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "web-01", LastKnownIP: "10.0.0.1", LastPeerIP: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	// The agent moved behind NAT: it reports its private address while the
+	// server sees the router's.
+	if _, err := agents.ProcessHeartbeat("web-01", "192.168.1.20", "203.0.113.9"); err != nil {
+		t.Fatal(err)
+	}
+	if err := beats.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// Re-registering updates the address instead of keeping the first one.
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "web-01", LastKnownIP: "10.0.0.5", LastPeerIP: "10.0.0.5"}); err != nil {
+		t.Fatal(err)
+	}
+	agent, err := agents.GetAgentByID("web-01")
+	if err != nil || agent.LastKnownIP != "10.0.0.5" || agent.LastPeerIP != "10.0.0.5" {
+		t.Fatalf("GetAgentByID = %+v, %v; want the addresses of the latest registration", agent, err)
+	}
+
+	history, err := agents.GetIPHistory(model.IPHistoryFilter{AgentID: "web-01"})
+	if err != nil || len(history) != 3 || history[0].IP != "10.0.0.5" || history[2].IP != "10.0.0.1" {
+		t.Fatalf("GetIPHistory = %+v, %v; want the three addresses, latest first", history, err)
+	}
+	mismatches, err := agents.GetIPHistory(model.IPHistoryFilter{MismatchOnly: true})
+	if err != nil || len(mismatches) != 1 || mismatches[0].PeerIP != "203.0.113.9" {
+		t.Fatalf("GetIPHistory(mismatch only) = %+v, %v; want the NAT address", mismatches, err)
+	}
+
+	now := time.Now()
+	if _, err := agents.GetIPHistory(model.IPHistoryFilter{Since: now, Until: now.Add(-time.Hour)}); !errors.Is(err, usecase.ErrInvalidWindow) {
+		t.Fatalf("GetIPHistory(reversed window) err = %v, want ErrInvalidWindow", err)
+	}
+}
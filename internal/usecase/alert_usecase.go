@@ -0,0 +1,496 @@
+// internal/usecase/alert_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/alerting"
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AlertEventTypes are the events the alert use case evaluates event-driven
+// rules on.
+var AlertEventTypes = []string{
+	model.EventFirewallChanged,
+	model.EventInventoryChanged,
+	model.EventAgentStatusChanged,
+}
+
+// AlertUseCase evaluates the loaded alert rules and keeps one alert per rule
+// and key: PENDING while the condition has held for less than the rule's
+// for, FIRING after that and RESOLVED once it clears. Firing and resolving
+// are notified on the routes of the rule.
+type AlertUseCase interface {
+	// ReloadRules loads the rules at path (see alerting.LoadRules). On
+	// error the previous rules stay in use.
+	ReloadRules(path string) error
+	// Rules returns the loaded rules, by ID.
+	Rules() []*alerting.Rule
+	// EvaluateAll evaluates the periodic rules, expires firewall alerts past
+	// their resolve_after, resolves the alerts of removed rules and purges
+	// resolved alerts past their retention.
+	EvaluateAll(now time.Time) error
+	// HandleEvent evaluates the event-driven rules on one of AlertEventTypes
+	// and resolves the open alerts of decommissioned agents.
+	HandleEvent(event model.Event)
+	// ListAlerts returns alerts of at least minSeverity, newest first.
+	ListAlerts(filter model.AlertFilter, minSeverity string) ([]model.Alert, error)
+}
+
+type alertUseCase struct {
+	repo    repository.AlertRepository
+	agents  repository.AgentRepository
+	metrics repository.MetricsRepository
+	events  EventPublisher // the webhooks route
+	cfg     config.Provider
+
+	mu    sync.RWMutex
+	rules []*alerting.Rule
+
+	// evalMu serializes evaluations: each reads the open alerts of a rule
+	// before creating or resolving them.
+	evalMu sync.Mutex
+	logMu  sync.Mutex
+}
+
+// NewAlertUseCase creates a new instance of the alert use case layer. Alerts
+// of rules that notify webhooks are published to events.
+func NewAlertUseCase(repo repository.AlertRepository, agents repository.AgentRepository, metrics repository.MetricsRepository, events EventPublisher, cfg config.Provider) AlertUseCase {
+	return &alertUseCase{repo: repo, agents: agents, metrics: metrics, events: publisherOrNoop(events), cfg: cfg}
+}
+
+func (uc *alertUseCase) ReloadRules(path string) error {
+	rules, err := alerting.LoadRules(path)
+	if err != nil {
+		return err
+	}
+	uc.mu.Lock()
+	changed := len(rules) > 0 || len(uc.rules) > 0
+	uc.rules = rules
+	uc.mu.Unlock()
+
+	if changed {
+		logging.Infof("Loaded %d alert rules.", len(rules))
+	}
+	return nil
+}
+
+func (uc *alertUseCase) Rules() []*alerting.Rule {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	return uc.rules
+}
+
+func (uc *alertUseCase) EvaluateAll(now time.Time) error {
+	uc.evalMu.Lock()
+	defer uc.evalMu.Unlock()
+
+	now = now.UTC()
+	open, err := uc.repo.FindOpenAlerts("")
+	if err != nil {
+		return err
+	}
+	byRule := make(map[string][]model.Alert)
+	for _, a := range open {
+		byRule[a.RuleID] = append(byRule[a.RuleID], a)
+	}
+
+	var errs []error
+	var fleet *alerting.Fleet
+	for _, rule := range uc.Rules() {
+		alerts := byRule[rule.ID]
+		delete(byRule, rule.ID)
+
+		switch {
+		case !rule.EventDriven():
+			if fleet == nil {
+				if fleet, err = uc.fleet(now); err != nil {
+					return err
+				}
+			}
+			if err := uc.apply(rule, rule.Evaluate(fleet), alerts, now); err != nil {
+				errs = append(errs, fmt.Errorf("rule %s: %w", rule.ID, err))
+			}
+		case rule.ResolveAfter > 0:
+			for i := range alerts {
+				if a := &alerts[i]; a.FiredAt != nil && !now.Before(a.FiredAt.Add(rule.ResolveAfter)) {
+					if err := uc.clear(rule, a, now); err != nil {
+						errs = append(errs, fmt.Errorf("rule %s: %w", rule.ID, err))
+					}
+				}
+			}
+		}
+	}
+
+	// Rules removed from the file leave no route to notify on.
+	for ruleID, alerts := range byRule {
+		for i := range alerts {
+			if err := uc.clear(nil, &alerts[i], now); err != nil {
+				errs = append(errs, fmt.Errorf("removed rule %s: %w", ruleID, err))
+			}
+		}
+		logging.Infof("Resolved %d alerts of removed alert rule %s.", len(alerts), ruleID)
+	}
+
+	if retention := uc.cfg.Current().Alerts.ResolvedRetention(); retention > 0 {
+		n, err := uc.repo.DeleteResolvedAlertsBefore(now.Add(-retention))
+		if err != nil {
+			errs = append(errs, err)
+		} else if n > 0 {
+			logging.Debugf("Deleted %d resolved alerts.", n)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fleet loads the agents that are not decommissioned and the latest raw
+// metric samples, reported within two report intervals.
+func (uc *alertUseCase) fleet(now time.Time) (*alerting.Fleet, error) {
+	fleet := &alerting.Fleet{Samples: make(map[string][]model.MetricSample), Now: now}
+	err := uc.agents.StreamAgents(model.ExportFilter{}, func(a *model.Agent) error {
+		if a.Status != "DECOMMISSIONED" {
+			fleet.Agents = append(fleet.Agents, *a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type sampleKey struct{ agentID, metric, mount string }
+	latest := make(map[sampleKey]*model.AgentMetric)
+	since := now.Add(-2 * uc.cfg.Current().Server.ReportInterval())
+	err = uc.metrics.StreamMetrics(model.MetricResolutionRaw, since, now.Add(time.Second), func(p *model.AgentMetric) error {
+		key := sampleKey{p.AgentID, p.Metric, p.Mount}
+		if last, ok := latest[key]; !ok || p.BucketStart.After(last.BucketStart) {
+			latest[key] = p
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key, p := range latest {
+		fleet.Samples[key.agentID] = append(fleet.Samples[key.agentID], model.MetricSample{Metric: p.Metric, Mount: p.Mount, Value: p.Avg()})
+	}
+	return fleet, nil
+}
+
+// apply moves the open alerts of a rule in scope to match findings, the
+// complete set of keys the condition holds for: new keys raise an alert,
+// pending alerts fire once they have held for the rule's for, and the
+// alerts of keys that are gone clear.
+func (uc *alertUseCase) apply(rule *alerting.Rule, findings []alerting.Finding, open []model.Alert, now time.Time) error {
+	byKey := make(map[string]*model.Alert, len(open))
+	for i := range open {
+		byKey[open[i].Key] = &open[i]
+	}
+
+	var errs []error
+	for _, f := range findings {
+		a, ok := byKey[f.Key]
+		if !ok {
+			if err := uc.raise(rule, f, now); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		delete(byKey, f.Key)
+		if a.State == model.AlertPending && now.Sub(a.StartedAt) >= rule.For {
+			a.Summary, a.Value = f.Summary, f.Value
+			if err := uc.fire(rule, a, now); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, a := range byKey {
+		if err := uc.clear(rule, a, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// raise opens an alert for a finding without one. It fires at once if the
+// condition already held for the rule's for.
+func (uc *alertUseCase) raise(rule *alerting.Rule, f alerting.Finding, now time.Time) error {
+	started := now
+	if !f.Since.IsZero() && f.Since.Before(now) {
+		started = f.Since.UTC()
+	}
+	a := &model.Alert{
+		RuleID:    rule.ID,
+		Key:       f.Key,
+		AgentID:   f.AgentID,
+		Severity:  rule.Severity,
+		State:     model.AlertPending,
+		Summary:   f.Summary,
+		Value:     f.Value,
+		StartedAt: started,
+		UpdatedAt: now,
+	}
+	if now.Sub(started) < rule.For {
+		return uc.repo.CreateAlert(a)
+	}
+	a.State, a.FiredAt = model.AlertFiring, &now
+	if err := uc.repo.CreateAlert(a); err != nil {
+		return err
+	}
+	uc.notify(rule, a, model.EventAlertFiring)
+	return nil
+}
+
+func (uc *alertUseCase) fire(rule *alerting.Rule, a *model.Alert, now time.Time) error {
+	a.State, a.FiredAt = model.AlertFiring, &now
+	if err := uc.repo.UpdateAlert(a); err != nil {
+		return err
+	}
+	uc.notify(rule, a, model.EventAlertFiring)
+	return nil
+}
+
+// clear resolves a firing alert and drops a pending one, which was never
+// notified. rule is nil for the alerts of a removed rule.
+func (uc *alertUseCase) clear(rule *alerting.Rule, a *model.Alert, now time.Time) error {
+	if a.State == model.AlertPending {
+		return uc.repo.DeleteAlert(a.ID)
+	}
+	a.State, a.ResolvedAt = model.AlertResolved, &now
+	if err := uc.repo.UpdateAlert(a); err != nil {
+		return err
+	}
+	if rule != nil {
+		uc.notify(rule, a, model.EventAlertResolved)
+	}
+	return nil
+}
+
+func (uc *alertUseCase) HandleEvent(event model.Event) {
+	var err error
+	switch event.Type {
+	case model.EventFirewallChanged:
+		err = uc.handleFirewallChange(event)
+	case model.EventInventoryChanged:
+		if event.Data["inventory"] == "installed_apps" {
+			err = uc.handleApps(event)
+		}
+	case model.EventAgentStatusChanged:
+		if event.Data["to"] == "DECOMMISSIONED" {
+			err = uc.resolveAgent(event.AgentID, time.Now().UTC())
+		}
+	}
+	if err != nil {
+		logging.Errorf("Failed to evaluate alert rules on %s of agent %s: %v", event.Type, event.AgentID, err)
+	}
+}
+
+// handleFirewallChange raises an alert for every added rule that matches a
+// firewall_rule_added rule and resolves those whose firewall rule was
+// removed.
+func (uc *alertUseCase) handleFirewallChange(event model.Event) error {
+	added, removed := stringList(event.Data["added"]), stringList(event.Data["removed"])
+	now := time.Now().UTC()
+
+	uc.evalMu.Lock()
+	defer uc.evalMu.Unlock()
+
+	var errs []error
+	for _, rule := range uc.Rules() {
+		if rule.Kind != alerting.KindFirewallRuleAdded || !rule.Applies(event.Labels) {
+			continue
+		}
+		open, err := uc.agentAlerts(rule.ID, event.AgentID)
+		if err != nil {
+			return err
+		}
+		for _, f := range rule.EvaluateFirewallRules(event.AgentID, added) {
+			if _, ok := open[f.Key]; ok {
+				continue
+			}
+			if err := uc.raise(rule, f, now); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		for _, s := range removed {
+			if a, ok := open[alerting.Key(event.AgentID, s)]; ok {
+				if err := uc.clear(rule, a, now); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleApps evaluates the app_installed rules against the applications
+// the agent reports now.
+func (uc *alertUseCase) handleApps(event model.Event) error {
+	var rules []*alerting.Rule
+	for _, rule := range uc.Rules() {
+		if rule.Kind == alerting.KindAppInstalled && rule.Applies(event.Labels) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	agent, err := uc.agents.FindAgentByID(event.AgentID)
+	if err != nil {
+		return err
+	}
+	apps, err := uc.agents.FindCurrentInstalledApps(agent.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+
+	uc.evalMu.Lock()
+	defer uc.evalMu.Unlock()
+
+	var errs []error
+	for _, rule := range rules {
+		open, err := uc.agentAlerts(rule.ID, agent.AgentID)
+		if err != nil {
+			return err
+		}
+		var alerts []model.Alert
+		for _, a := range open {
+			alerts = append(alerts, *a)
+		}
+		if err := uc.apply(rule, rule.EvaluateApps(agent.AgentID, apps), alerts, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolveAgent resolves every open alert of a decommissioned agent.
+func (uc *alertUseCase) resolveAgent(agentID string, now time.Time) error {
+	uc.evalMu.Lock()
+	defer uc.evalMu.Unlock()
+
+	open, err := uc.repo.FindOpenAlerts("")
+	if err != nil {
+		return err
+	}
+	rules := make(map[string]*alerting.Rule)
+	for _, rule := range uc.Rules() {
+		rules[rule.ID] = rule
+	}
+	var errs []error
+	for i := range open {
+		if a := &open[i]; a.AgentID == agentID {
+			if err := uc.clear(rules[a.RuleID], a, now); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// agentAlerts returns the open alerts of a rule on one agent, by key.
+func (uc *alertUseCase) agentAlerts(ruleID, agentID string) (map[string]*model.Alert, error) {
+	open, err := uc.repo.FindOpenAlerts(ruleID)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*model.Alert)
+	for i := range open {
+		if open[i].AgentID == agentID {
+			byKey[open[i].Key] = &open[i]
+		}
+	}
+	return byKey, nil
+}
+
+// notify sends an alert.firing or alert.resolved event on the routes of
+// the rule.
+func (uc *alertUseCase) notify(rule *alerting.Rule, a *model.Alert, eventType string) {
+	data := map[string]interface{}{
+		"alert_id":   a.ID,
+		"rule_id":    rule.ID,
+		"rule_name":  rule.Name,
+		"key":        a.Key,
+		"severity":   a.Severity,
+		"state":      a.State,
+		"summary":    a.Summary,
+		"value":      a.Value,
+		"started_at": a.StartedAt,
+	}
+	if a.FiredAt != nil {
+		data["fired_at"] = *a.FiredAt
+	}
+	if a.ResolvedAt != nil {
+		data["resolved_at"] = *a.ResolvedAt
+	}
+	event := NewEvent(eventType, a.AgentID, data)
+
+	if rule.Notifies(alerting.NotifyWebhooks) {
+		uc.events.Publish(event)
+	}
+	if rule.Notifies(alerting.NotifyLog) {
+		uc.writeLog(event, a)
+	}
+}
+
+// writeLog appends the event as a JSON line to alerts.log_file, or logs the
+// alert on the server log when no file is configured.
+func (uc *alertUseCase) writeLog(event model.Event, a *model.Alert) {
+	path := uc.cfg.Current().Alerts.LogFile
+	if path == "" {
+		if event.Type == model.EventAlertFiring {
+			logging.Warnf("Alert %s is firing (%s): %s", a.RuleID, a.Severity, a.Summary)
+		} else {
+			logging.Infof("Alert %s resolved: %s", a.RuleID, a.Summary)
+		}
+		return
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		logging.Errorf("Failed to encode alert %d for the alert log: %v", a.ID, err)
+		return
+	}
+	uc.logMu.Lock()
+	defer uc.logMu.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		logging.Errorf("Failed to open the alert log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		logging.Errorf("Failed to write alert %d to the alert log: %v", a.ID, err)
+	}
+}
+
+func (uc *alertUseCase) ListAlerts(filter model.AlertFilter, minSeverity string) ([]model.Alert, error) {
+	filter.Severities = severitiesFrom(minSeverity)
+	return uc.repo.FindAlerts(filter)
+}
+
+// stringList reads a list of strings from event data, which holds []string
+// when published in process and []interface{} after a JSON round trip.
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, x := range list {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAlertRules = `
+rules:
+  - id: agent-offline
+    severity: high
+    for: 10m
+    labels: {env: prod}
+    notify: [webhooks]
+    agent_status: OFFLINE
+  - id: site-down
+    severity: critical
+    notify: [webhooks]
+    group_offline: {percent: 50, group_by: site}
+  - id: rdp-opened
+    notify: [webhooks, log]
+    firewall_rule_added: {direction: in, action: allow, port: 3389}
+  - id: telnet
+    severity: low
+    notify: [log]
+    app_installed: {name: '^telnet'}
+`
+
+type alertFixture struct {
+	store  *repository.Store
+	bus    *usecase.EventBus
+	agents usecase.AgentUseCase
+	alerts usecase.AlertUseCase
+	sub    *usecase.Subscription
+	log    string
+}
+
+func newAlertFixture(t *testing.T) *alertFixture {
+	t.Helper()
+	dir := t.TempDir()
+	rules := filepath.Join(dir, "alerts.yaml")
+	if err := os.WriteFile(rules, []byte(testAlertRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Alerts.LogFile = filepath.Join(dir, "alerts.log")
+	provider := config.StaticProvider(cfg)
+
+	f := &alertFixture{store: repository.NewMemoryStore(), log: cfg.Alerts.LogFile}
+	f.bus = usecase.NewEventBus(f.store.Agents, provider, nil)
+	f.agents = usecase.NewAgentUseCase(f.store.Agents, nil, f.bus)
+	f.alerts = usecase.NewAlertUseCase(f.store.Alerts, f.store.Agents, f.store.Metrics, f.bus, provider)
+	if err := f.alerts.ReloadRules(rules); err != nil {
+		t.Fatalf("ReloadRules: %v", err)
+	}
+	f.sub = subscribeBus(t, f.bus, usecase.EventFilter{}, 0)
+	for _, a := range []*model.Agent{
+		{AgentID: "web-01", Hostname: "web-01", Labels: model.Labels{"env": "prod", "site": "riyadh"}},
+		{AgentID: "web-02", Hostname: "web-02", Labels: model.Labels{"env": "prod", "site": "riyadh"}},
+		{AgentID: "dev-01", Hostname: "dev-01", Labels: model.Labels{"env": "dev", "site": "jeddah"}},
+	} {
+		if _, err := f.agents.RegisterAgent(a); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+	return f
+}
+
+// handleEvents feeds the events published so far to the alert use case, as
+// the alert evaluator does, and returns the alert notifications among them.
+func (f *alertFixture) handleEvents() []model.Event {
+	var notified []model.Event
+	for events := drain(f.sub); len(events) > 0; events = drain(f.sub) {
+		for _, ev := range events {
+			switch ev.Type {
+			case model.EventAlertFiring, model.EventAlertResolved:
+				notified = append(notified, ev)
+			default:
+				f.alerts.HandleEvent(ev)
+			}
+		}
+	}
+	return notified
+}
+
+func (f *alertFixture) evaluate(t *testing.T, now time.Time) []model.Event {
+	t.Helper()
+	f.handleEvents()
+	if err := f.alerts.EvaluateAll(now); err != nil {
+		t.Fatalf("EvaluateAll: %v", err)
+	}
+	return f.handleEvents()
+}
+
+func alertsByKey(t *testing.T, alerts usecase.AlertUseCase, filter model.AlertFilter) map[string]model.Alert {
+	t.Helper()
+	list, err := alerts.ListAlerts(filter, "")
+	if err != nil {
+		t.Fatalf("ListAlerts: %v", err)
+	}
+	byKey := map[string]model.Alert{}
+	for _, a := range list {
+		byKey[a.Key] = a
+	}
+	return byKey
+}
+
+func TestAlertFiresAfterForAndResolves(t *testing.T) {
+	f := newAlertFixture(t)
+	if err := f.agents.MarkOfflineAgents(0); err != nil {
+		t.Fatalf("MarkOfflineAgents: %v", err)
+	}
+	now := time.Now()
+
+	if events := f.evaluate(t, now); len(events) != 2 || events[0].Type != model.EventAlertFiring || events[0].Data["rule_id"] != "site-down" {
+		t.Fatalf("first evaluation notified %+v, want site-down firing at once for both sites", events)
+	}
+	offline := alertsByKey(t, f.alerts, model.AlertFilter{RuleID: "agent-offline"})
+	if len(offline) != 2 || offline["web-01"].State != model.AlertPending || offline["dev-01"].ID != 0 {
+		t.Fatalf("agent-offline alerts = %+v, want web-01 and web-02 pending, dev-01 out of the rule's labels", offline)
+	}
+	if site := alertsByKey(t, f.alerts, model.AlertFilter{RuleID: "site-down"}); len(site) != 2 || site["site=riyadh"].Value != 100 {
+		t.Fatalf("site-down alerts = %+v, want riyadh and jeddah at 100%%", site)
+	}
+
+	events := f.evaluate(t, now.Add(11*time.Minute))
+	if len(events) != 2 || events[0].Type != model.EventAlertFiring || events[0].Data["rule_id"] != "agent-offline" {
+		t.Fatalf("evaluation after for notified %+v, want agent-offline firing for both agents", events)
+	}
+	if a := alertsByKey(t, f.alerts, model.AlertFilter{AgentID: "web-01"})["web-01"]; a.State != model.AlertFiring || a.FiredAt == nil {
+		t.Fatalf("web-01 alert = %+v, want FIRING", a)
+	}
+
+	if _, err := f.agents.ProcessHeartbeat("web-01", "10.0.0.1", ""); err != nil {
+		t.Fatalf("ProcessHeartbeat: %v", err)
+	}
+	events = f.evaluate(t, now.Add(12*time.Minute))
+	var resolved []string
+	for _, ev := range events {
+		if ev.Type == model.EventAlertResolved {
+			resolved = append(resolved, ev.Data["rule_id"].(string)+"/"+ev.AgentID)
+		}
+	}
+	if len(resolved) != 2 || resolved[0] != "agent-offline/web-01" && resolved[1] != "agent-offline/web-01" {
+		t.Fatalf("resolved %v, want agent-offline of web-01 and site-down of riyadh (50%% is not more than 50%%)", resolved)
+	}
+	if a := alertsByKey(t, f.alerts, model.AlertFilter{AgentID: "web-01"})["web-01"]; a.State != model.AlertResolved || a.ResolvedAt == nil {
+		t.Fatalf("web-01 alert = %+v, want RESOLVED", a)
+	}
+
+	high, err := f.alerts.ListAlerts(model.AlertFilter{States: []string{model.AlertFiring}}, model.SeverityCritical)
+	if err != nil || len(high) != 1 || high[0].Key != "site=jeddah" {
+		t.Fatalf("ListAlerts(FIRING, critical) = %+v, %v; want the jeddah site alert", high, err)
+	}
+}
+
+func TestAlertDropsPendingAlertWhenConditionClears(t *testing.T) {
+	f := newAlertFixture(t)
+	if err := f.agents.MarkOfflineAgents(0); err != nil {
+		t.Fatalf("MarkOfflineAgents: %v", err)
+	}
+	now := time.Now()
+	f.evaluate(t, now)
+	if _, err := f.agents.ProcessHeartbeat("web-01", "10.0.0.1", ""); err != nil {
+		t.Fatalf("ProcessHeartbeat: %v", err)
+	}
+	for _, ev := range f.evaluate(t, now.Add(time.Minute)) {
+		if ev.Data["rule_id"] == "agent-offline" {
+			t.Fatalf("pending alert notified %+v", ev)
+		}
+	}
+	if a, ok := alertsByKey(t, f.alerts, model.AlertFilter{RuleID: "agent-offline"})["web-01"]; ok {
+		t.Fatalf("web-01 alert = %+v, want the pending alert dropped", a)
+	}
+}
+
+func TestAlertOnFirewallRuleAndApplication(t *testing.T) {
+	f := newAlertFixture(t)
+	rdp := model.FirewallRule{Name: "RDP", Port: "3000-4000", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", Enabled: true}
+	ssh := model.FirewallRule{Name: "SSH", Port: "22", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", Enabled: true}
+	if err := f.agents.StoreFirewallRules("web-01", []model.FirewallRule{ssh}); err != nil {
+		t.Fatalf("StoreFirewallRules: %v", err)
+	}
+	if err := f.agents.StoreFirewallRules("web-01", []model.FirewallRule{ssh, rdp}); err != nil {
+		t.Fatalf("StoreFirewallRules: %v", err)
+	}
+	events := f.handleEvents()
+	if len(events) != 1 || events[0].Type != model.EventAlertFiring || events[0].Data["rule_id"] != "rdp-opened" ||
+		!strings.Contains(events[0].Data["summary"].(string), "RDP") {
+		t.Fatalf("notified %+v, want rdp-opened firing for the RDP rule", events)
+	}
+
+	reportApps(t, f.agents, "dev-01", model.InstalledApplication{Name: "Telnet Client", Version: "1.0"})
+	if events := f.handleEvents(); len(events) != 0 {
+		t.Fatalf("telnet notified %+v, want the log only", events)
+	}
+	if a := alertsByKey(t, f.alerts, model.AlertFilter{RuleID: "telnet"})["dev-01"]; a.State != model.AlertFiring || a.Severity != model.SeverityLow {
+		t.Fatalf("telnet alert = %+v, want LOW and FIRING", a)
+	}
+
+	if err := f.agents.StoreFirewallRules("web-01", []model.FirewallRule{ssh}); err != nil {
+		t.Fatalf("StoreFirewallRules: %v", err)
+	}
+	reportApps(t, f.agents, "dev-01", model.InstalledApplication{Name: "PuTTY", Version: "0.80"})
+	if events := f.handleEvents(); len(events) != 1 || events[0].Type != model.EventAlertResolved {
+		t.Fatalf("notified %+v, want rdp-opened resolved", events)
+	}
+	if a := alertsByKey(t, f.alerts, model.AlertFilter{RuleID: "telnet"})["dev-01"]; a.State != model.AlertResolved {
+		t.Fatalf("telnet alert = %+v, want RESOLVED once uninstalled", a)
+	}
+
+	log, err := os.ReadFile(f.log)
+	if err != nil {
+		t.Fatalf("reading the alert log: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(log)), "\n"); len(lines) != 4 ||
+		!strings.Contains(lines[0], `"rdp-opened"`) || !strings.Contains(lines[3], model.EventAlertResolved) {
+		t.Fatalf("alert log = %q, want rdp-opened and telnet firing and resolved", log)
+	}
+}
@@ -0,0 +1,235 @@
+// internal/usecase/availability_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrInvalidWindow is returned for an availability window that does not end
+// after it starts.
+var ErrInvalidWindow = errors.New("invalid time window")
+
+// DefaultAvailabilityWindow is the window of a report that has no start.
+const DefaultAvailabilityWindow = 30 * 24 * time.Hour
+
+// AvailabilityFilter selects the agents and time window of an availability report.
+type AvailabilityFilter struct {
+	AgentID string       // "" = every agent
+	Labels  model.Labels // only agents with all of these labels
+	GroupBy string       // label key to group agents by; "" = no groups
+	Since   time.Time    // zero = DefaultAvailabilityWindow before Until
+	Until   time.Time    // zero = now
+	Limit   int          // most agents returned, least available first; 0 = all
+}
+
+// AvailabilityStats measures an agent, or a group of agents, over a window.
+// Only time with a known ONLINE or OFFLINE status is observed: time before an
+// agent registered or after it was decommissioned does not count.
+type AvailabilityStats struct {
+	Observed time.Duration
+	Downtime time.Duration // time OFFLINE
+	// Outages counts the OFFLINE periods that overlap the window, including
+	// one that started before it.
+	Outages int
+	// Recovered counts the outages that ended with the agent back ONLINE
+	// within the window, and RecoveryTime is their total length.
+	Recovered    int
+	RecoveryTime time.Duration
+}
+
+// Availability is the percentage of observed time the agent was ONLINE, or
+// 0 when nothing was observed.
+func (s AvailabilityStats) Availability() float64 {
+	if s.Observed <= 0 {
+		return 0
+	}
+	return 100 * float64(s.Observed-s.Downtime) / float64(s.Observed)
+}
+
+// MTTR is the mean time to recover from the outages that ended in the window.
+func (s AvailabilityStats) MTTR() time.Duration {
+	if s.Recovered == 0 {
+		return 0
+	}
+	return s.RecoveryTime / time.Duration(s.Recovered)
+}
+
+func (s *AvailabilityStats) add(o AvailabilityStats) {
+	s.Observed += o.Observed
+	s.Downtime += o.Downtime
+	s.Outages += o.Outages
+	s.Recovered += o.Recovered
+	s.RecoveryTime += o.RecoveryTime
+}
+
+// AgentAvailability is the availability of one agent.
+type AgentAvailability struct {
+	AgentID  string
+	Hostname string
+	Labels   model.Labels
+	Status   string // status at the end of the window
+	AvailabilityStats
+}
+
+// GroupAvailability adds up the agents that share a label value. Its
+// availability is weighted by each agent's observed time.
+type GroupAvailability struct {
+	Group  string // the GroupBy label value; "" for agents without the label
+	Agents int
+	AvailabilityStats
+}
+
+// AvailabilityReport is the availability of the fleet over [Since, Until).
+type AvailabilityReport struct {
+	Since  time.Time
+	Until  time.Time
+	Fleet  GroupAvailability   // every selected agent
+	Groups []GroupAvailability // ordered by group, when GroupBy is set
+	Agents []AgentAvailability // least available first
+}
+
+// AvailabilityUseCase computes uptime reports from the agent status history.
+type AvailabilityUseCase interface {
+	Report(filter AvailabilityFilter) (*AvailabilityReport, error)
+}
+
+type availabilityUseCase struct {
+	agents repository.AgentRepository
+}
+
+// NewAvailabilityUseCase creates the availability use case.
+func NewAvailabilityUseCase(agents repository.AgentRepository) AvailabilityUseCase {
+	return &availabilityUseCase{agents: agents}
+}
+
+// availabilityTracker replays one agent's status changes through the window.
+type availabilityTracker struct {
+	agent       AgentAvailability
+	since       time.Time // start of the current status within the window
+	outageStart time.Time
+}
+
+// advance accounts the time until at in the current status.
+func (t *availabilityTracker) advance(at time.Time) {
+	d := at.Sub(t.since)
+	if d <= 0 {
+		return
+	}
+	switch t.agent.Status {
+	case "ONLINE":
+		t.agent.Observed += d
+	case "OFFLINE":
+		t.agent.Observed += d
+		t.agent.Downtime += d
+	}
+	t.since = at
+}
+
+func (t *availabilityTracker) change(to string, at time.Time) {
+	t.advance(at)
+	from := t.agent.Status
+	switch {
+	case to == "OFFLINE" && from != "OFFLINE":
+		t.agent.Outages++
+		t.outageStart = at
+	case from == "OFFLINE" && to == "ONLINE":
+		t.agent.Recovered++
+		t.agent.RecoveryTime += at.Sub(t.outageStart)
+	}
+	t.agent.Status = to
+}
+
+func (uc *availabilityUseCase) Report(filter AvailabilityFilter) (*AvailabilityReport, error) {
+	now := time.Now()
+	until := filter.Until
+	if until.IsZero() {
+		until = now
+	}
+	since := filter.Since
+	if since.IsZero() {
+		since = until.Add(-DefaultAvailabilityWindow)
+	}
+	if !until.After(since) {
+		return nil, ErrInvalidWindow
+	}
+	// The future has not been observed yet.
+	end := until
+	if end.After(now) {
+		end = now
+	}
+
+	selector := model.ExportFilter{AgentID: filter.AgentID, Labels: filter.Labels}
+	trackers := make(map[string]*availabilityTracker)
+	before, err := uc.agents.FindLastStatusChanges(selector, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range before {
+		t := &availabilityTracker{since: since}
+		t.agent = AgentAvailability{AgentID: c.AgentID, Hostname: c.Hostname, Labels: c.Labels, Status: c.ToStatus}
+		if c.ToStatus == "OFFLINE" {
+			t.agent.Outages++
+			t.outageStart = c.ChangedAt
+		}
+		trackers[c.AgentID] = t
+	}
+
+	selector.Since, selector.Until = since, end
+	err = uc.agents.StreamStatusHistory(selector, func(c *model.AgentStatusChangeRow) error {
+		t, ok := trackers[c.AgentID]
+		if !ok {
+			t = &availabilityTracker{since: since}
+			t.agent = AgentAvailability{AgentID: c.AgentID, Hostname: c.Hostname, Labels: c.Labels}
+			trackers[c.AgentID] = t
+		}
+		t.change(c.ToStatus, c.ChangedAt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AvailabilityReport{Since: since, Until: until}
+	groups := make(map[string]*GroupAvailability)
+	for _, t := range trackers {
+		t.advance(end)
+		report.Agents = append(report.Agents, t.agent)
+		report.Fleet.Agents++
+		report.Fleet.add(t.agent.AvailabilityStats)
+		if filter.GroupBy != "" {
+			key := t.agent.Labels[filter.GroupBy]
+			g, ok := groups[key]
+			if !ok {
+				g = &GroupAvailability{Group: key}
+				groups[key] = g
+			}
+			g.Agents++
+			g.add(t.agent.AvailabilityStats)
+		}
+	}
+	for _, g := range groups {
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool { return report.Groups[i].Group < report.Groups[j].Group })
+
+	// Least available first; agents that were never observed go last.
+	sort.Slice(report.Agents, func(i, j int) bool {
+		a, b := report.Agents[i], report.Agents[j]
+		if (a.Observed > 0) != (b.Observed > 0) {
+			return a.Observed > 0
+		}
+		if a.Availability() != b.Availability() {
+			return a.Availability() < b.Availability()
+		}
+		return a.AgentID < b.AgentID
+	})
+	if filter.Limit > 0 && len(report.Agents) > filter.Limit {
+		report.Agents = report.Agents[:filter.Limit]
+	}
+	return report, nil
+}
@@ -0,0 +1,101 @@
+package usecase_test
+
+import (
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAvailabilityReport(t *testing.T) {
+	store := repository.NewMemoryStore()
+	start := time.Now().Add(-24 * time.Hour).Truncate(time.Hour)
+	at := func(h float64) time.Time { return start.Add(time.Duration(h * float64(time.Hour))) }
+
+	for id, site := range map[string]string{"a1": "riyadh", "a2": "jeddah", "a3": "riyadh", "a4": "riyadh"} {
+		if err := store.Agents.CreateAgent(&model.Agent{AgentID: id, Hostname: id + ".lan", Status: "ONLINE", Labels: model.Labels{"site": site}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []model.AgentStatusChange{
+		{AgentID: "a1", ToStatus: "ONLINE", ChangedAt: at(-1)},
+		{AgentID: "a2", ToStatus: "ONLINE", ChangedAt: at(-5)},
+		{AgentID: "a2", FromStatus: "ONLINE", ToStatus: "OFFLINE", ChangedAt: at(-2)},
+		{AgentID: "a1", FromStatus: "ONLINE", ToStatus: "OFFLINE", ChangedAt: at(2)},
+		{AgentID: "a2", FromStatus: "OFFLINE", ToStatus: "ONLINE", ChangedAt: at(1)},
+		{AgentID: "a1", FromStatus: "OFFLINE", ToStatus: "ONLINE", ChangedAt: at(3)},
+		{AgentID: "a3", ToStatus: "ONLINE", ChangedAt: at(5)},
+		{AgentID: "a1", FromStatus: "ONLINE", ToStatus: "OFFLINE", ChangedAt: at(8)},
+		{AgentID: "a2", FromStatus: "ONLINE", ToStatus: "OFFLINE", ChangedAt: at(11)}, // after the window
+	} {
+		if err := store.Agents.RecordStatusChange(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	availability := usecase.NewAvailabilityUseCase(store.Agents)
+	report, err := availability.Report(usecase.AvailabilityFilter{GroupBy: "site", Since: at(0), Until: at(10)})
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	// a4 has no recorded status, so it has no availability to report.
+	if len(report.Agents) != 3 {
+		t.Fatalf("agents = %+v, want a1, a2 and a3", report.Agents)
+	}
+	a1, a2, a3 := report.Agents[0], report.Agents[1], report.Agents[2]
+	if a1.AgentID != "a1" || a1.Availability() != 70 || a1.Outages != 2 || a1.Recovered != 1 || a1.MTTR() != time.Hour || a1.Status != "OFFLINE" {
+		t.Errorf("a1 = %+v (%.1f%%, MTTR %v), want 70%% with 2 outages, one recovered in 1h, ending OFFLINE", a1, a1.Availability(), a1.MTTR())
+	}
+	// a2 was already down when the window started; its recovery counts the whole outage.
+	if a2.AgentID != "a2" || a2.Availability() != 90 || a2.Outages != 1 || a2.MTTR() != 3*time.Hour {
+		t.Errorf("a2 = %+v (%.1f%%, MTTR %v), want 90%% with one outage recovered after 3h", a2, a2.Availability(), a2.MTTR())
+	}
+	// a3 registered halfway through: only the time since then is observed.
+	if a3.AgentID != "a3" || a3.Availability() != 100 || a3.Observed != 5*time.Hour {
+		t.Errorf("a3 = %+v, want 100%% over the 5h since it registered", a3)
+	}
+
+	if f := report.Fleet; f.Agents != 3 || f.Availability() != 84 || f.Outages != 3 || f.MTTR() != 2*time.Hour {
+		t.Errorf("fleet = %+v (%.1f%%, MTTR %v), want 3 agents at 84%% with 3 outages and a 2h MTTR", f, f.Availability(), f.MTTR())
+	}
+	if len(report.Groups) != 2 || report.Groups[0].Group != "jeddah" || report.Groups[1].Group != "riyadh" ||
+		report.Groups[1].Agents != 2 || report.Groups[1].Availability() != 80 {
+		t.Errorf("groups = %+v, want jeddah and riyadh, riyadh at 80%% over 2 agents", report.Groups)
+	}
+
+	limited, err := availability.Report(usecase.AvailabilityFilter{Labels: model.Labels{"site": "riyadh"}, Since: at(0), Until: at(10), Limit: 1})
+	if err != nil || len(limited.Agents) != 1 || limited.Agents[0].AgentID != "a1" || limited.Fleet.Agents != 2 {
+		t.Fatalf("Report(site=riyadh, limit 1) = %+v, %v; want a1 listed out of 2 agents", limited, err)
+	}
+
+	if _, err := availability.Report(usecase.AvailabilityFilter{Since: at(10), Until: at(0)}); !errors.Is(err, usecase.ErrInvalidWindow) {
+		t.Fatalf("Report(reversed window) err = %v, want ErrInvalidWindow", err)
+	}
+}
+
+func TestAvailabilityRecordsHeartbeatRecovery(t *testing.T) {
+	store := repository.NewMemoryStore()
+	agents := usecase.NewAgentUseCase(store.Agents, nil, nil)
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "web-01"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := agents.MarkOfflineAgents(0); err != nil {
+		t.Fatal(err)
+	}
+	// A new use case has not seen the agent go offline, as after a restart.
+	agents = usecase.NewAgentUseCase(store.Agents, nil, nil)
+	if _, err := agents.ProcessHeartbeat("web-01", "10.0.0.1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := usecase.NewAvailabilityUseCase(store.Agents).Report(usecase.AvailabilityFilter{})
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if len(report.Agents) != 1 || report.Agents[0].Outages != 1 || report.Agents[0].Recovered != 1 || report.Agents[0].Status != "ONLINE" {
+		t.Fatalf("agents = %+v, want one recovered outage", report.Agents)
+	}
+}
@@ -0,0 +1,232 @@
+// internal/usecase/clone_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CloneUseCase detects hosts that share an agent_id, typically VMs cloned
+// from an image with the agent already installed.
+//
+// Every registration gives the agent instance a new nonce that it sends back
+// with its heartbeats and its next registration. Once a clone registers, the
+// other instance still presents the previous nonce, which flags the agent.
+// Hosts that do not send nonces are caught by heartbeats alternating between
+// addresses, or by a registration with a different hostname or hardware
+// while the agent is online.
+type CloneUseCase interface {
+	// PrepareRegistration checks a registration before it is stored and sets
+	// agent.InstanceNonce to the nonce to return to the instance. When the
+	// instance presents an outdated nonce and identities are reissued, it
+	// also changes agent.AgentID to the instance's new identity.
+	PrepareRegistration(agent *model.Agent, nonce string) error
+	// CheckHeartbeat checks a heartbeat that was accepted and reports whether
+	// the instance must register again to receive a new identity.
+	CheckHeartbeat(agentID, ip, peerIP, nonce string) bool
+	ListSuspected(limit int) ([]model.Agent, error)
+	// Clear removes the flag once the duplicate hosts have been dealt with.
+	Clear(agentID string) (*model.Agent, error)
+}
+
+type cloneUseCase struct {
+	repo   repository.AgentRepository
+	events EventPublisher
+	cfg    config.Provider
+
+	mu        sync.Mutex
+	nonces    map[string]string // latest nonce of each agent known to this server
+	addresses map[string]*addressTrack
+}
+
+// addressTrack holds the two latest addresses an agent beat from.
+type addressTrack struct {
+	last, previous     string
+	lastAt, previousAt time.Time
+}
+
+// NewCloneUseCase creates the clone detection use case. Events go to events,
+// which may be nil.
+func NewCloneUseCase(repo repository.AgentRepository, events EventPublisher, cfg config.Provider) CloneUseCase {
+	return &cloneUseCase{
+		repo:      repo,
+		events:    publisherOrNoop(events),
+		cfg:       cfg,
+		nonces:    make(map[string]string),
+		addresses: make(map[string]*addressTrack),
+	}
+}
+
+func (uc *cloneUseCase) PrepareRegistration(agent *model.Agent, nonce string) error {
+	existing, err := uc.repo.FindAgentByID(agent.AgentID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if existing != nil {
+		switch {
+		case nonce != "" && existing.InstanceNonce != "" && nonce != existing.InstanceNonce:
+			if uc.cfg.Current().Clones.ReissueIdentities {
+				agent.AgentID = reissuedAgentID(existing.AgentID)
+				uc.flag(existing.AgentID, "another instance registered with an outdated nonce and was given the identity "+agent.AgentID,
+					map[string]interface{}{"reissued_agent_id": agent.AgentID})
+				logging.Warnf("Agent %s: instance with an outdated nonce re-registered as %s.", existing.AgentID, agent.AgentID)
+			} else {
+				uc.flag(existing.AgentID, "registered with the nonce of an earlier registration", nil)
+			}
+		case nonce == "":
+			// Without a nonce the instance cannot be told apart, so only a
+			// registration that contradicts an agent still online is suspect.
+			threshold := uc.cfg.Current().Server.OfflineThreshold()
+			if existing.Status == "ONLINE" && time.Since(existing.LastSeen) < threshold {
+				if reason := registrationConflict(existing, agent); reason != "" {
+					uc.flag(existing.AgentID, reason, nil)
+				}
+			}
+		}
+	}
+
+	agent.InstanceNonce = newInstanceNonce()
+	uc.mu.Lock()
+	uc.nonces[agent.AgentID] = agent.InstanceNonce
+	uc.mu.Unlock()
+	return nil
+}
+
+// registrationConflict describes how a registration contradicts the host
+// already registered under the same agent_id, or returns "".
+func registrationConflict(existing, agent *model.Agent) string {
+	switch {
+	case existing.Hostname != "" && agent.Hostname != existing.Hostname:
+		return fmt.Sprintf("registered as %s while %s was online", agent.Hostname, existing.Hostname)
+	case existing.OSName != "" && agent.OSName != existing.OSName,
+		existing.CPUCores != 0 && agent.CPUCores != existing.CPUCores:
+		return fmt.Sprintf("registered with %s on %d cores while online with %s on %d cores",
+			agent.OSName, agent.CPUCores, existing.OSName, existing.CPUCores)
+	}
+	return ""
+}
+
+func (uc *cloneUseCase) CheckHeartbeat(agentID, ip, peerIP, nonce string) bool {
+	if reason := uc.trackAddress(agentID, ip, peerIP, time.Now()); reason != "" {
+		uc.flag(agentID, reason, nil)
+	}
+	if nonce == "" {
+		return false
+	}
+
+	uc.mu.Lock()
+	current, ok := uc.nonces[agentID]
+	uc.mu.Unlock()
+	if ok && current == nonce {
+		return false
+	}
+	// The agent may have registered through another server since.
+	agent, err := uc.repo.FindAgentByID(agentID)
+	if err != nil {
+		logging.Errorf("Failed to check the nonce of agent %s: %v", agentID, err)
+		return false
+	}
+	uc.mu.Lock()
+	uc.nonces[agentID] = agent.InstanceNonce
+	uc.mu.Unlock()
+	if agent.InstanceNonce == "" || agent.InstanceNonce == nonce {
+		return false
+	}
+
+	uc.flag(agentID, "sent heartbeats with the nonce of an earlier registration", nil)
+	return uc.cfg.Current().Clones.ReissueIdentities
+}
+
+// trackAddress records the address of a heartbeat. A beat that returns to
+// the previous address while the latest one is still recent means two hosts
+// are beating in turn; a host that moved once does not come back so soon.
+func (uc *cloneUseCase) trackAddress(agentID, ip, peerIP string, at time.Time) string {
+	address := ip
+	if peerIP != "" && peerIP != ip {
+		address = ip + " via " + peerIP
+	}
+	if address == "" {
+		return ""
+	}
+	cfg := uc.cfg.Current()
+	window := cfg.Clones.AddressWindow(cfg.Server.ReportInterval())
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	t, ok := uc.addresses[agentID]
+	if !ok {
+		uc.addresses[agentID] = &addressTrack{last: address, lastAt: at}
+		return ""
+	}
+	if address == t.last {
+		t.lastAt = at
+		return ""
+	}
+	var reason string
+	if address == t.previous && at.Sub(t.previousAt) <= window && at.Sub(t.lastAt) <= window {
+		reason = fmt.Sprintf("heartbeats alternate between %s and %s", t.last, address)
+	}
+	t.previous, t.previousAt = t.last, t.lastAt
+	t.last, t.lastAt = address, at
+	return reason
+}
+
+// flag marks the agent and announces it the first time, or whenever data
+// reports an action taken such as a reissued identity.
+func (uc *cloneUseCase) flag(agentID, reason string, data map[string]interface{}) {
+	flagged, err := uc.repo.FlagSuspectedClone(agentID, reason, time.Now())
+	if err != nil {
+		logging.Errorf("Failed to flag agent %s as a suspected clone: %v", agentID, err)
+		return
+	}
+	if !flagged && data == nil {
+		return
+	}
+	if flagged {
+		logging.Warnf("Agent %s is used by more than one host: %s.", agentID, reason)
+	}
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["reason"] = reason
+	uc.events.Publish(NewEvent(model.EventAgentCloneSuspected, agentID, data))
+}
+
+func (uc *cloneUseCase) ListSuspected(limit int) ([]model.Agent, error) {
+	return uc.repo.FindSuspectedClones(limit)
+}
+
+func (uc *cloneUseCase) Clear(agentID string) (*model.Agent, error) {
+	if err := uc.repo.ClearSuspectedClone(agentID); err != nil {
+		return nil, err
+	}
+	// Start over so that addresses from before the fix do not flag it again.
+	uc.mu.Lock()
+	delete(uc.addresses, agentID)
+	uc.mu.Unlock()
+	return uc.repo.FindAgentByID(agentID)
+}
+
+func newInstanceNonce() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// reissuedAgentID derives a new, unique identity for a cloned instance.
+func reissuedAgentID(agentID string) string {
+	var b [4]byte
+	rand.Read(b[:])
+	return agentID + "-" + hex.EncodeToString(b[:])
+}
@@ -0,0 +1,155 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"strings"
+	"testing"
+)
+
+// cloneFixture registers agents the way the RegisterAgent RPC does.
+type cloneFixture struct {
+	t      *testing.T
+	store  *repository.Store
+	agents usecase.AgentUseCase
+	clones usecase.CloneUseCase
+	sub    *usecase.Subscription
+}
+
+func newCloneFixture(t *testing.T, reissue bool) *cloneFixture {
+	cfg := config.Default()
+	cfg.Clones.ReissueIdentities = reissue
+	store, bus := newEventBus(t, 100, 100)
+	f := &cloneFixture{
+		t:      t,
+		store:  store,
+		agents: usecase.NewAgentUseCase(store.Agents, nil, bus),
+		clones: usecase.NewCloneUseCase(store.Agents, bus, config.StaticProvider(cfg)),
+		sub:    subscribeBus(t, bus, usecase.EventFilter{EventTypes: []string{model.EventAgentCloneSuspected}}, 0),
+	}
+	return f
+}
+
+// register returns the agent ID and nonce the instance is given.
+func (f *cloneFixture) register(agent model.Agent, nonce string) (string, string) {
+	f.t.Helper()
+	if err := f.clones.PrepareRegistration(&agent, nonce); err != nil {
+		f.t.Fatalf("PrepareRegistration: %v", err)
+	}
+	if _, err := f.agents.RegisterAgent(&agent); err != nil {
+		f.t.Fatalf("RegisterAgent: %v", err)
+	}
+	return agent.AgentID, agent.InstanceNonce
+}
+
+func (f *cloneFixture) heartbeat(agentID, ip, nonce string) bool {
+	f.t.Helper()
+	if _, err := f.agents.ProcessHeartbeat(agentID, ip, ""); err != nil {
+		f.t.Fatalf("ProcessHeartbeat: %v", err)
+	}
+	return f.clones.CheckHeartbeat(agentID, ip, "", nonce)
+}
+
+func (f *cloneFixture) suspected() map[string]string {
+	f.t.Helper()
+	agents, err := f.clones.ListSuspected(0)
+	if err != nil {
+		f.t.Fatalf("ListSuspected: %v", err)
+	}
+	reasons := make(map[string]string)
+	for _, a := range agents {
+		reasons[a.AgentID] = a.CloneReason
+	}
+	return reasons
+}
+
+func TestCloneDetectedByOutdatedNonce(t *testing.T) {
+	f := newCloneFixture(t, false)
+	web := model.Agent{AgentID: "web-01", Hostname: "web-01"}
+
+	_, first := f.register(web, "")
+	if f.heartbeat("web-01", "10.0.0.1", first) || len(f.suspected()) != 0 {
+		t.Fatal("the only instance was flagged")
+	}
+	// A clone of the VM, nonce included, registers as well.
+	_, second := f.register(web, first)
+	if second == first || len(f.suspected()) != 0 {
+		t.Fatalf("clone registration got nonce %q (first %q), suspected %v", second, first, f.suspected())
+	}
+	if f.heartbeat("web-01", "10.0.0.1", first) {
+		t.Fatal("asked to re-register although identities are not reissued")
+	}
+	if reason := f.suspected()["web-01"]; !strings.Contains(reason, "nonce") {
+		t.Fatalf("suspected = %v, want web-01 flagged for its nonce", f.suspected())
+	}
+	f.heartbeat("web-01", "10.0.0.1", first)
+	if events := drain(f.sub); len(events) != 1 || events[0].AgentID != "web-01" || events[0].Data["reason"] == nil {
+		t.Fatalf("events = %+v, want one agent.clone_suspected event", events)
+	}
+
+	agent, err := f.clones.Clear("web-01")
+	if err != nil || agent.CloneSuspectedAt != nil || len(f.suspected()) != 0 {
+		t.Fatalf("Clear = %+v, %v; want the flag removed", agent, err)
+	}
+}
+
+func TestCloneGetsNewIdentity(t *testing.T) {
+	f := newCloneFixture(t, true)
+	web := model.Agent{AgentID: "web-01", Hostname: "web-01"}
+
+	_, first := f.register(web, "")
+	_, second := f.register(web, first)
+	if !f.heartbeat("web-01", "10.0.0.1", first) {
+		t.Fatal("instance with the outdated nonce was not asked to re-register")
+	}
+	id, third := f.register(web, first)
+	if id == "web-01" || !strings.HasPrefix(id, "web-01-") {
+		t.Fatalf("re-registration got agent ID %q, want a new one", id)
+	}
+	if _, err := f.agents.GetAgentByID(id); err != nil {
+		t.Fatalf("new identity %s not registered: %v", id, err)
+	}
+	// Both instances now beat under their own identity.
+	if f.heartbeat("web-01", "10.0.0.1", second) || f.heartbeat(id, "10.0.0.2", third) {
+		t.Fatal("asked to re-register again")
+	}
+	if suspected := f.suspected(); len(suspected) != 1 || suspected["web-01"] == "" {
+		t.Fatalf("suspected = %v, want only web-01", suspected)
+	}
+	// The agent was already flagged, but the new identity is still announced.
+	events := drain(f.sub)
+	if len(events) != 2 || events[1].Data["reissued_agent_id"] != id {
+		t.Fatalf("events = %+v, want the flag and then the new identity %s", events, id)
+	}
+}
+
+func TestCloneDetectedWithoutNonces(t *testing.T) {
+	f := newCloneFixture(t, false)
+	for _, id := range []string{"moved", "cloned"} {
+		f.register(model.Agent{AgentID: id, Hostname: id, CPUCores: 4}, "")
+	}
+
+	// A host that moved once is not suspect; two hosts beating in turn are.
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.2"} {
+		f.heartbeat("moved", ip, "")
+	}
+	for _, ip := range []string{"10.0.1.1", "10.0.1.2", "10.0.1.1"} {
+		f.heartbeat("cloned", ip, "")
+	}
+	if suspected := f.suspected(); len(suspected) != 1 || !strings.Contains(suspected["cloned"], "alternate") {
+		t.Fatalf("suspected = %v, want only cloned, for alternating addresses", suspected)
+	}
+
+	// Old agents that send no nonce: another host registers while web-01 is online.
+	f.register(model.Agent{AgentID: "web-01", Hostname: "web-01", CPUCores: 4}, "")
+	f.register(model.Agent{AgentID: "web-01", Hostname: "web-01", CPUCores: 4}, "")
+	if _, ok := f.suspected()["web-01"]; ok {
+		t.Fatal("re-registering the same host was flagged")
+	}
+	f.register(model.Agent{AgentID: "web-01", Hostname: "web-07", CPUCores: 4}, "")
+	if reason := f.suspected()["web-01"]; !strings.Contains(reason, "web-07") {
+		t.Fatalf("suspected = %v, want web-01 flagged for the web-07 registration", f.suspected())
+	}
+}
@@ -0,0 +1,446 @@
+// internal/usecase/collection_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/artifacts"
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCollection is returned for a file collection request that
+	// cannot be queued, or a download outside the file.
+	ErrInvalidCollection = errors.New("invalid file collection")
+	// ErrCollectionClosed is returned for an upload to a collection that has
+	// completed, failed or expired.
+	ErrCollectionClosed = errors.New("file collection is no longer accepting uploads")
+	// ErrUploadOffset is returned when an upload does not continue where the
+	// server's copy ends.
+	ErrUploadOffset = errors.New("upload offset does not match the bytes received")
+	// ErrUploadInProgress is returned when the agent starts a second upload
+	// of the same file.
+	ErrUploadInProgress = errors.New("another upload of this file is in progress")
+	// ErrInvalidUpload is returned for an upload header that does not
+	// describe the file being uploaded.
+	ErrInvalidUpload = errors.New("invalid file upload")
+	// ErrCollectionNotReady is returned when downloading a file that has not
+	// been collected completely, or has expired.
+	ErrCollectionNotReady = errors.New("collected file is not available")
+)
+
+// collectionPartBytes is the most an upload stores as one part. A broken
+// upload loses at most the part it was writing.
+const collectionPartBytes = 8 << 20
+
+// CollectionRequest asks an agent for a file.
+type CollectionRequest struct {
+	AgentID  string
+	Path     string
+	Reason   string // why the file is needed, kept for the audit trail
+	MaxBytes int64  // 0 = collection.max_file_bytes
+}
+
+// UploadHeader describes an upload of a collected file.
+type UploadHeader struct {
+	Offset int64 // where the content starts in the file
+	Size   int64 // size of the whole file
+	SHA256 string
+}
+
+// CollectionUseCase collects files such as logs, configs and memory dumps
+// from agents. An operator queues a COLLECT_FILE command; the agent uploads
+// the file in one or more streams, resuming from the bytes the server has
+// after an interruption. Each stream is stored as parts in the artifact
+// store, which are joined into one object once the whole file has arrived
+// and its SHA-256 matches the one the agent declared.
+//
+// The collection completes or fails its command, so it shows up in the
+// command history and events; downloads are published as events too.
+type CollectionUseCase interface {
+	// PrepareRequest validates a request and applies the size limit.
+	PrepareRequest(req CollectionRequest) (CollectionRequest, error)
+	// Track records the collection requested by a queued COLLECT_FILE
+	// command.
+	Track(cmd *model.Command, req CollectionRequest) (*model.FileCollection, error)
+
+	// UploadStatus returns the collection of the agent's command.
+	UploadStatus(agentID string, commandID uint) (*model.FileCollection, error)
+	// Upload stores content from header.Offset. It returns the collection
+	// as it stands when content ends, which is COMPLETE or FAILED after the
+	// last byte.
+	Upload(ctx context.Context, agentID string, commandID uint, header UploadHeader, content io.Reader) (*model.FileCollection, error)
+	// CommandFailed fails the collection of a command the agent reported as
+	// failed, for example because the file does not exist.
+	CommandFailed(agentID string, commandID uint, message string) error
+
+	// ListCollections returns collections matching the filter, newest first.
+	ListCollections(filter model.FileCollectionFilter) ([]model.FileCollection, error)
+	// OpenCollection returns a complete collection and its content from
+	// offset.
+	OpenCollection(ctx context.Context, id uint, offset int64) (*model.FileCollection, io.ReadCloser, error)
+	// PurgeExpired deletes the content of collections older than maxAge
+	// (0 = keep forever) and marks them EXPIRED.
+	PurgeExpired(maxAge time.Duration) error
+}
+
+type collectionUseCase struct {
+	repo     repository.CollectionRepository
+	commands CommandUseCase
+	store    artifacts.Store
+	events   EventPublisher
+	cfg      config.Provider
+
+	mu        sync.Mutex
+	uploading map[uint]bool // by command ID
+}
+
+// NewCollectionUseCase creates the file collection use case. Files are kept
+// in store; events, which may be nil, receive completed collections and
+// downloads.
+func NewCollectionUseCase(repo repository.CollectionRepository, commands CommandUseCase, store artifacts.Store, events EventPublisher, cfg config.Provider) CollectionUseCase {
+	return &collectionUseCase{
+		repo:      repo,
+		commands:  commands,
+		store:     store,
+		events:    publisherOrNoop(events),
+		cfg:       cfg,
+		uploading: make(map[uint]bool),
+	}
+}
+
+func (uc *collectionUseCase) PrepareRequest(req CollectionRequest) (CollectionRequest, error) {
+	limit := uc.cfg.Current().Collection.MaxFileBytes
+	req.Path = strings.TrimSpace(req.Path)
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.Path == "":
+		return req, fmt.Errorf("%w: path is required", ErrInvalidCollection)
+	case len(req.Path) > 4096:
+		return req, fmt.Errorf("%w: path is longer than 4096 bytes", ErrInvalidCollection)
+	case req.MaxBytes < 0:
+		return req, fmt.Errorf("%w: max bytes must not be negative", ErrInvalidCollection)
+	case req.MaxBytes > limit:
+		return req, fmt.Errorf("%w: max bytes must not exceed collection.max_file_bytes (%d)", ErrInvalidCollection, limit)
+	}
+	if req.MaxBytes == 0 {
+		req.MaxBytes = limit
+	}
+	return req, nil
+}
+
+func (uc *collectionUseCase) Track(cmd *model.Command, req CollectionRequest) (*model.FileCollection, error) {
+	c := &model.FileCollection{
+		CommandID: cmd.ID,
+		AgentID:   cmd.AgentID,
+		Path:      req.Path,
+		Reason:    req.Reason,
+		MaxBytes:  req.MaxBytes,
+		Status:    model.CollectionPending,
+	}
+	if err := uc.repo.CreateCollection(c); err != nil {
+		return nil, err
+	}
+	if c.Reason != "" {
+		logging.Infof("Asked agent %s for %s (command %d, collection %d): %s", c.AgentID, c.Path, c.CommandID, c.ID, c.Reason)
+	} else {
+		logging.Infof("Asked agent %s for %s (command %d, collection %d).", c.AgentID, c.Path, c.CommandID, c.ID)
+	}
+	return c, nil
+}
+
+func (uc *collectionUseCase) UploadStatus(agentID string, commandID uint) (*model.FileCollection, error) {
+	return uc.findAgentCollection(agentID, commandID)
+}
+
+// findAgentCollection returns the collection of the agent's command.
+func (uc *collectionUseCase) findAgentCollection(agentID string, commandID uint) (*model.FileCollection, error) {
+	c, err := uc.repo.FindCollectionByCommand(commandID)
+	if err != nil {
+		return nil, err
+	}
+	if c.AgentID != agentID {
+		// Do not reveal collections of other agents.
+		return nil, gorm.ErrRecordNotFound
+	}
+	return c, nil
+}
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+func (uc *collectionUseCase) Upload(ctx context.Context, agentID string, commandID uint, header UploadHeader, content io.Reader) (*model.FileCollection, error) {
+	uc.mu.Lock()
+	if uc.uploading[commandID] {
+		uc.mu.Unlock()
+		return nil, ErrUploadInProgress
+	}
+	uc.uploading[commandID] = true
+	uc.mu.Unlock()
+	defer func() {
+		uc.mu.Lock()
+		delete(uc.uploading, commandID)
+		uc.mu.Unlock()
+	}()
+
+	c, err := uc.findAgentCollection(agentID, commandID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != model.CollectionPending && c.Status != model.CollectionUploading {
+		return nil, fmt.Errorf("%w: it is %s", ErrCollectionClosed, c.Status)
+	}
+	if header.Size < 0 || !sha256Pattern.MatchString(header.SHA256) {
+		return nil, fmt.Errorf("%w: the header needs the file's size and hex SHA-256", ErrInvalidUpload)
+	}
+
+	if c.Status == model.CollectionPending {
+		if header.Size > c.MaxBytes {
+			return uc.fail(c, fmt.Sprintf("the file has %d bytes, more than the limit of %d", header.Size, c.MaxBytes))
+		}
+		c.Status, c.SizeBytes, c.SHA256 = model.CollectionUploading, header.Size, strings.ToLower(header.SHA256)
+		if err := uc.repo.SaveCollection(c); err != nil {
+			return nil, err
+		}
+	} else if header.Size != c.SizeBytes || !strings.EqualFold(header.SHA256, c.SHA256) {
+		return nil, fmt.Errorf("%w: the size or SHA-256 differs from the upload being resumed", ErrInvalidUpload)
+	}
+	if header.Offset != c.ReceivedBytes {
+		return nil, fmt.Errorf("%w: resume from %d", ErrUploadOffset, c.ReceivedBytes)
+	}
+
+	for c.ReceivedBytes < c.SizeBytes {
+		want := c.SizeBytes - c.ReceivedBytes
+		if want > collectionPartBytes {
+			want = collectionPartBytes
+		}
+		key := collectionPartKey(c.ID, c.Parts+1)
+		obj, err := uc.store.Put(ctx, key, io.LimitReader(content, want), 0)
+		if err != nil {
+			return nil, err
+		}
+		if obj.Size == 0 {
+			uc.deleteContent(key)
+			break
+		}
+		c.Parts++
+		c.ReceivedBytes += obj.Size
+		if err := uc.repo.SaveCollection(c); err != nil {
+			return nil, err
+		}
+		if obj.Size < want {
+			break // the agent resumes from here
+		}
+	}
+	if c.ReceivedBytes < c.SizeBytes {
+		return c, nil
+	}
+	if n, _ := content.Read(make([]byte, 1)); n > 0 {
+		return uc.fail(c, fmt.Sprintf("the agent sent more than the declared %d bytes", c.SizeBytes))
+	}
+	return uc.complete(ctx, c)
+}
+
+// complete joins the parts of a fully uploaded file and checks its SHA-256.
+func (uc *collectionUseCase) complete(ctx context.Context, c *model.FileCollection) (*model.FileCollection, error) {
+	parts := &partsReader{ctx: ctx, store: uc.store, collectionID: c.ID, parts: c.Parts}
+	key := collectionKey(c.ID)
+	obj, err := uc.store.Put(ctx, key, parts, 0)
+	parts.Close()
+	if err != nil {
+		return nil, err
+	}
+	if obj.SHA256 != c.SHA256 {
+		uc.deleteContent(key)
+		return uc.fail(c, fmt.Sprintf("SHA-256 mismatch: the agent declared %s, the server received %s", c.SHA256, obj.SHA256))
+	}
+
+	now := time.Now()
+	c.Status, c.StorageKey, c.CompletedAt = model.CollectionComplete, key, &now
+	c.Message = fmt.Sprintf("collected %d bytes", c.SizeBytes)
+	if err := uc.repo.SaveCollection(c); err != nil {
+		return nil, err
+	}
+	uc.deleteParts(c)
+	logging.Infof("Collected %s from agent %s (%d bytes, sha256 %s, collection %d).", c.Path, c.AgentID, c.SizeBytes, c.SHA256, c.ID)
+	if err := uc.commands.CompleteCommand(c.AgentID, c.CommandID, true, c.Message, nil); err != nil {
+		logging.Errorf("Failed to complete command %d of collection %d: %v", c.CommandID, c.ID, err)
+	}
+	uc.events.Publish(NewEvent(model.EventFileCollected, c.AgentID, map[string]interface{}{
+		"collection_id": c.ID,
+		"command_id":    c.CommandID,
+		"path":          c.Path,
+		"reason":        c.Reason,
+		"size_bytes":    c.SizeBytes,
+		"sha256":        c.SHA256,
+	}))
+	return c, nil
+}
+
+// fail records a collection as failed and fails its command.
+func (uc *collectionUseCase) fail(c *model.FileCollection, message string) (*model.FileCollection, error) {
+	now := time.Now()
+	c.Status, c.Message, c.CompletedAt = model.CollectionFailed, message, &now
+	if err := uc.repo.SaveCollection(c); err != nil {
+		return nil, err
+	}
+	uc.deleteParts(c)
+	logging.Warnf("Collection %d of %s from agent %s failed: %s", c.ID, c.Path, c.AgentID, message)
+	if err := uc.commands.CompleteCommand(c.AgentID, c.CommandID, false, message, nil); err != nil {
+		logging.Errorf("Failed to fail command %d of collection %d: %v", c.CommandID, c.ID, err)
+	}
+	return c, nil
+}
+
+func (uc *collectionUseCase) CommandFailed(agentID string, commandID uint, message string) error {
+	c, err := uc.findAgentCollection(agentID, commandID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // not a COLLECT_FILE command
+	}
+	if err != nil {
+		return err
+	}
+	if c.Status != model.CollectionPending && c.Status != model.CollectionUploading {
+		return nil
+	}
+	now := time.Now()
+	c.Status, c.Message, c.CompletedAt = model.CollectionFailed, message, &now
+	if err := uc.repo.SaveCollection(c); err != nil {
+		return err
+	}
+	uc.deleteParts(c)
+	return nil
+}
+
+func (uc *collectionUseCase) ListCollections(filter model.FileCollectionFilter) ([]model.FileCollection, error) {
+	return uc.repo.FindCollections(filter)
+}
+
+func (uc *collectionUseCase) OpenCollection(ctx context.Context, id uint, offset int64) (*model.FileCollection, io.ReadCloser, error) {
+	c, err := uc.repo.FindCollection(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Status != model.CollectionComplete {
+		return nil, nil, fmt.Errorf("%w: it is %s", ErrCollectionNotReady, c.Status)
+	}
+	if offset < 0 || offset > c.SizeBytes {
+		return nil, nil, fmt.Errorf("%w: offset %d is outside the file of %d bytes", ErrInvalidCollection, offset, c.SizeBytes)
+	}
+	content, err := uc.store.Open(ctx, c.StorageKey, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	uc.events.Publish(NewEvent(model.EventFileDownloaded, c.AgentID, map[string]interface{}{
+		"collection_id": c.ID,
+		"command_id":    c.CommandID,
+		"path":          c.Path,
+		"offset":        offset,
+	}))
+	return c, content, nil
+}
+
+func (uc *collectionUseCase) PurgeExpired(maxAge time.Duration) error {
+	if maxAge <= 0 {
+		return nil
+	}
+	expired, err := uc.repo.FindCollectionsCreatedBefore(time.Now().Add(-maxAge), 1000)
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		c := &expired[i]
+		if c.StorageKey != "" {
+			if err := uc.store.Delete(context.Background(), c.StorageKey); err != nil {
+				return err
+			}
+		}
+		uc.deleteParts(c)
+		c.Status, c.StorageKey, c.Parts = model.CollectionExpired, "", 0
+		if err := uc.repo.SaveCollection(c); err != nil {
+			return err
+		}
+	}
+	if len(expired) > 0 {
+		logging.Infof("Deleted %d collected files past their retention period.", len(expired))
+	}
+	return nil
+}
+
+// deleteParts removes the uploaded parts of a collection.
+func (uc *collectionUseCase) deleteParts(c *model.FileCollection) {
+	for i := 1; i <= c.Parts; i++ {
+		uc.deleteContent(collectionPartKey(c.ID, i))
+	}
+}
+
+func (uc *collectionUseCase) deleteContent(key string) {
+	if err := uc.store.Delete(context.Background(), key); err != nil {
+		logging.Errorf("Failed to delete collected file content %s: %v", key, err)
+	}
+}
+
+// collectionKey is where a complete collected file is kept in the artifact
+// store, and collectionPartKey where its uploaded parts are.
+func collectionKey(id uint) string {
+	return fmt.Sprintf("collections/%d/file", id)
+}
+
+func collectionPartKey(id uint, part int) string {
+	return fmt.Sprintf("collections/%d/parts/%06d", id, part)
+}
+
+// partsReader reads the parts of a collection one after the other.
+type partsReader struct {
+	ctx          context.Context
+	store        artifacts.Store
+	collectionID uint
+	parts        int
+
+	next    int
+	current io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next >= r.parts {
+				return 0, io.EOF
+			}
+			r.next++
+			part, err := r.store.Open(r.ctx, collectionPartKey(r.collectionID, r.next), 0)
+			if err != nil {
+				return 0, err
+			}
+			r.current = part
+		}
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
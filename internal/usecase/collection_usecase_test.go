@@ -0,0 +1,226 @@
+package usecase_test
+
+import (
+	"agent_server/internal/artifacts"
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type collectionFixture struct {
+	commands    usecase.CommandUseCase
+	collections usecase.CollectionUseCase
+	sub         *usecase.Subscription
+}
+
+func newCollectionFixture(t *testing.T) *collectionFixture {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Collection.MaxFileBytes = 1 << 20
+	objects, err := artifacts.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	store, bus := newEventBus(t, 100, 100)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "web-01", Hostname: "web-01"}); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	commands := usecase.NewCommandUseCase(store.Agents, store.Commands, bus)
+	return &collectionFixture{
+		commands:    commands,
+		collections: usecase.NewCollectionUseCase(store.Collections, commands, objects, bus, config.StaticProvider(cfg)),
+		sub:         subscribeBus(t, bus, usecase.EventFilter{EventTypes: []string{model.EventFileCollected, model.EventFileDownloaded}}, 0),
+	}
+}
+
+// request queues a COLLECT_FILE command the agent has picked up, as the
+// CollectFile handler and the agent's poll would.
+func (f *collectionFixture) request(t *testing.T, req usecase.CollectionRequest) *model.FileCollection {
+	t.Helper()
+	prepared, err := f.collections.PrepareRequest(req)
+	if err != nil {
+		t.Fatalf("PrepareRequest: %v", err)
+	}
+	cmd, err := f.commands.EnqueueCommand(req.AgentID, model.CommandTypeCollectFile, []byte("payload"))
+	if err != nil {
+		t.Fatalf("EnqueueCommand: %v", err)
+	}
+	c, err := f.collections.Track(cmd, prepared)
+	if err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	if _, err := f.commands.PollCommands(req.AgentID); err != nil {
+		t.Fatalf("PollCommands: %v", err)
+	}
+	return c
+}
+
+func (f *collectionFixture) commandStatus(t *testing.T, id uint) string {
+	t.Helper()
+	cmd, _, err := f.commands.GetCommandOutput(id)
+	if err != nil {
+		t.Fatalf("GetCommandOutput: %v", err)
+	}
+	return cmd.Status
+}
+
+func headerFor(content string, offset int64) usecase.UploadHeader {
+	sum := sha256.Sum256([]byte(content))
+	return usecase.UploadHeader{Offset: offset, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])}
+}
+
+// brokenReader returns data and then fails, like a stream that drops.
+type brokenReader struct{ data string }
+
+func (r *brokenReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestCollectFileResumesAndCompletesCommand(t *testing.T) {
+	f := newCollectionFixture(t)
+	ctx := context.Background()
+	c := f.request(t, usecase.CollectionRequest{AgentID: "web-01", Path: "/var/log/syslog", Reason: "INC-1"})
+	if c.MaxBytes != 1<<20 || c.Status != model.CollectionPending {
+		t.Fatalf("collection = %+v, want PENDING with the configured limit", c)
+	}
+	content := strings.Repeat("log line\n", 100)
+
+	// The first stream ends after part of the file.
+	got, err := f.collections.Upload(ctx, "web-01", c.CommandID, headerFor(content, 0), strings.NewReader(content[:300]))
+	if err != nil || got.Status != model.CollectionUploading || got.ReceivedBytes != 300 {
+		t.Fatalf("first Upload = %+v, %v; want UPLOADING with 300 bytes", got, err)
+	}
+	// A dropped stream keeps nothing of the part it was writing.
+	if _, err := f.collections.Upload(ctx, "web-01", c.CommandID, headerFor(content, 300), &brokenReader{content[300:500]}); err == nil {
+		t.Fatalf("Upload over a broken stream succeeded")
+	}
+	status, err := f.collections.UploadStatus("web-01", c.CommandID)
+	if err != nil || status.ReceivedBytes != 300 {
+		t.Fatalf("UploadStatus = %+v, %v; want 300 bytes", status, err)
+	}
+	if _, err := f.collections.Upload(ctx, "web-01", c.CommandID, headerFor(content, 0), strings.NewReader(content)); !errors.Is(err, usecase.ErrUploadOffset) {
+		t.Fatalf("Upload from offset 0 error = %v, want ErrUploadOffset", err)
+	}
+	if _, err := f.collections.UploadStatus("db-01", c.CommandID); err == nil {
+		t.Fatalf("UploadStatus of another agent succeeded")
+	}
+
+	got, err = f.collections.Upload(ctx, "web-01", c.CommandID, headerFor(content, 300), strings.NewReader(content[300:]))
+	if err != nil || got.Status != model.CollectionComplete || got.SizeBytes != int64(len(content)) {
+		t.Fatalf("resumed Upload = %+v, %v; want COMPLETE", got, err)
+	}
+	if status := f.commandStatus(t, c.CommandID); status != model.CommandSucceeded {
+		t.Fatalf("command status = %s, want SUCCEEDED", status)
+	}
+
+	_, r, err := f.collections.OpenCollection(ctx, c.ID, 9)
+	if err != nil {
+		t.Fatalf("OpenCollection: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != content[9:] {
+		t.Fatalf("downloaded %q, want the file from offset 9", data)
+	}
+	events := drain(f.sub)
+	if len(events) != 2 || events[0].Type != model.EventFileCollected || events[1].Type != model.EventFileDownloaded {
+		t.Fatalf("events = %+v, want collected then downloaded", events)
+	}
+	if events[0].Data["reason"] != "INC-1" {
+		t.Fatalf("collected event data = %v, want the reason", events[0].Data)
+	}
+}
+
+func TestCollectFileFailsOnChecksumMismatch(t *testing.T) {
+	f := newCollectionFixture(t)
+	ctx := context.Background()
+	c := f.request(t, usecase.CollectionRequest{AgentID: "web-01", Path: "/etc/hosts"})
+
+	header := headerFor("127.0.0.1 localhost\n", 0)
+	got, err := f.collections.Upload(ctx, "web-01", c.CommandID, header, strings.NewReader("127.0.0.1 localhosT\n"))
+	if err != nil || got.Status != model.CollectionFailed || !strings.Contains(got.Message, "SHA-256 mismatch") {
+		t.Fatalf("Upload = %+v, %v; want FAILED with a checksum mismatch", got, err)
+	}
+	if status := f.commandStatus(t, c.CommandID); status != model.CommandFailed {
+		t.Fatalf("command status = %s, want FAILED", status)
+	}
+	if _, _, err := f.collections.OpenCollection(ctx, c.ID, 0); !errors.Is(err, usecase.ErrCollectionNotReady) {
+		t.Fatalf("OpenCollection error = %v, want ErrCollectionNotReady", err)
+	}
+	if _, err := f.collections.Upload(ctx, "web-01", c.CommandID, header, strings.NewReader("")); !errors.Is(err, usecase.ErrCollectionClosed) {
+		t.Fatalf("Upload after failure error = %v, want ErrCollectionClosed", err)
+	}
+}
+
+func TestCollectFileEnforcesSizeLimit(t *testing.T) {
+	f := newCollectionFixture(t)
+	if _, err := f.collections.PrepareRequest(usecase.CollectionRequest{AgentID: "web-01", Path: "/core", MaxBytes: 2 << 20}); !errors.Is(err, usecase.ErrInvalidCollection) {
+		t.Fatalf("PrepareRequest over the configured limit error = %v, want ErrInvalidCollection", err)
+	}
+	if _, err := f.collections.PrepareRequest(usecase.CollectionRequest{AgentID: "web-01", Path: " "}); !errors.Is(err, usecase.ErrInvalidCollection) {
+		t.Fatalf("PrepareRequest without a path error = %v, want ErrInvalidCollection", err)
+	}
+
+	c := f.request(t, usecase.CollectionRequest{AgentID: "web-01", Path: "/core", MaxBytes: 10})
+	content := "more than ten bytes"
+	got, err := f.collections.Upload(context.Background(), "web-01", c.CommandID, headerFor(content, 0), strings.NewReader(content))
+	if err != nil || got.Status != model.CollectionFailed || got.ReceivedBytes != 0 {
+		t.Fatalf("Upload over the limit = %+v, %v; want FAILED before storing anything", got, err)
+	}
+	if status := f.commandStatus(t, c.CommandID); status != model.CommandFailed {
+		t.Fatalf("command status = %s, want FAILED", status)
+	}
+}
+
+func TestCollectFileCommandFailureAndRetention(t *testing.T) {
+	f := newCollectionFixture(t)
+	ctx := context.Background()
+
+	missing := f.request(t, usecase.CollectionRequest{AgentID: "web-01", Path: "/no/such/file"})
+	if err := f.collections.CommandFailed("web-01", missing.CommandID, "file not found"); err != nil {
+		t.Fatalf("CommandFailed: %v", err)
+	}
+	failed, err := f.collections.ListCollections(model.FileCollectionFilter{Status: model.CollectionFailed})
+	if err != nil || len(failed) != 1 || failed[0].Message != "file not found" {
+		t.Fatalf("failed collections = %+v, %v; want the missing file", failed, err)
+	}
+
+	c := f.request(t, usecase.CollectionRequest{AgentID: "web-01", Path: "/etc/hostname"})
+	if _, err := f.collections.Upload(ctx, "web-01", c.CommandID, headerFor("web-01\n", 0), strings.NewReader("web-01\n")); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if err := f.collections.PurgeExpired(time.Hour); err != nil {
+		t.Fatalf("PurgeExpired(1h): %v", err)
+	}
+	if _, r, err := f.collections.OpenCollection(ctx, c.ID, 0); err != nil {
+		t.Fatalf("OpenCollection before expiry: %v", err)
+	} else {
+		r.Close()
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if err := f.collections.PurgeExpired(time.Millisecond); err != nil {
+		t.Fatalf("PurgeExpired: %v", err)
+	}
+	if _, _, err := f.collections.OpenCollection(ctx, c.ID, 0); !errors.Is(err, usecase.ErrCollectionNotReady) {
+		t.Fatalf("OpenCollection after expiry error = %v, want ErrCollectionNotReady", err)
+	}
+	expired, err := f.collections.ListCollections(model.FileCollectionFilter{Status: model.CollectionExpired})
+	if err != nil || len(expired) != 2 {
+		t.Fatalf("expired collections = %+v, %v; want both kept as EXPIRED", expired, err)
+	}
+}
@@ -0,0 +1,240 @@
+// internal/usecase/command_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCommandState is returned when a command cannot move to the requested status.
+	ErrInvalidCommandState = errors.New("command is not in a state that allows this operation")
+	// ErrAgentDecommissioned is returned when queuing work for a retired agent.
+	ErrAgentDecommissioned = errors.New("agent is decommissioned")
+)
+
+// CommandUseCase defines the contract for the server-to-agent command queue.
+type CommandUseCase interface {
+	EnqueueCommand(agentID, commandType string, payload []byte) (*model.Command, error)
+	// EnqueueCommandForApproval queues a command that the agent only gets
+	// after ApproveCommand. reason tells the approver why it has to wait.
+	EnqueueCommandForApproval(agentID, commandType string, payload []byte, reason string) (*model.Command, error)
+	ApproveCommand(commandID uint) (*model.Command, error)
+	PollCommands(agentID string) ([]model.Command, error)
+	// CompleteCommand records the result of a command; exitCode is nil
+	// unless the command ran a script.
+	CompleteCommand(agentID string, commandID uint, success bool, message string, exitCode *int32) error
+	// AppendCommandOutput stores output the agent streams while it runs a
+	// command, up to maxBytes per command. It returns whether output was
+	// dropped.
+	AppendCommandOutput(agentID string, commandID uint, chunks []model.CommandOutputChunk, maxBytes int64) (bool, error)
+	GetCommandOutput(commandID uint) (*model.Command, []model.CommandOutputChunk, error)
+	ListCommands(filter model.CommandFilter) ([]model.Command, error)
+	// CancelCommand cancels a command the agent has not picked up, which
+	// also rejects one awaiting approval.
+	CancelCommand(commandID uint) (*model.Command, error)
+}
+
+type commandUseCase struct {
+	agents   repository.AgentRepository
+	commands repository.CommandRepository
+	events   EventPublisher
+}
+
+// NewCommandUseCase creates a new instance of the command use case layer.
+// Fleet events go to events, which may be nil.
+func NewCommandUseCase(agents repository.AgentRepository, commands repository.CommandRepository, events EventPublisher) CommandUseCase {
+	return &commandUseCase{agents: agents, commands: commands, events: publisherOrNoop(events)}
+}
+
+// EnqueueCommand queues a command for an agent to pick up on its next poll.
+func (uc *commandUseCase) EnqueueCommand(agentID, commandType string, payload []byte) (*model.Command, error) {
+	return uc.enqueue(agentID, commandType, payload, model.CommandPending, "")
+}
+
+func (uc *commandUseCase) EnqueueCommandForApproval(agentID, commandType string, payload []byte, reason string) (*model.Command, error) {
+	cmd, err := uc.enqueue(agentID, commandType, payload, model.CommandAwaitingApproval, reason)
+	if err != nil {
+		return nil, err
+	}
+	uc.events.Publish(NewEvent(model.EventCommandApproval, agentID, map[string]interface{}{
+		"command_id": cmd.ID,
+		"type":       cmd.Type,
+		"reason":     reason,
+	}))
+	return cmd, nil
+}
+
+func (uc *commandUseCase) enqueue(agentID, commandType string, payload []byte, status, message string) (*model.Command, error) {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent.Status == "DECOMMISSIONED" {
+		return nil, ErrAgentDecommissioned
+	}
+
+	cmd := &model.Command{
+		AgentID:       agentID,
+		Type:          commandType,
+		Payload:       payload,
+		Status:        status,
+		ResultMessage: message,
+	}
+	if err := uc.commands.CreateCommand(cmd); err != nil {
+		return nil, err
+	}
+	if status == model.CommandAwaitingApproval {
+		logging.Infof("Queued %s command %d for agent %s, awaiting approval: %s", commandType, cmd.ID, agentID, message)
+	} else {
+		logging.Infof("Queued %s command %d for agent %s.", commandType, cmd.ID, agentID)
+	}
+	uc.events.Publish(commandEvent(cmd, status, message))
+	return cmd, nil
+}
+
+// ApproveCommand releases a command awaiting approval to the agent.
+func (uc *commandUseCase) ApproveCommand(commandID uint) (*model.Command, error) {
+	return uc.transition(commandID, []string{model.CommandAwaitingApproval}, model.CommandPending, "approved by operator")
+}
+
+// PollCommands hands the agent its pending commands and marks them SENT.
+func (uc *commandUseCase) PollCommands(agentID string) ([]model.Command, error) {
+	if _, err := uc.agents.FindAgentByID(agentID); err != nil {
+		return nil, err
+	}
+	cmds, err := uc.commands.ClaimPendingCommands(agentID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cmds {
+		uc.events.Publish(commandEvent(&cmds[i], model.CommandSent, ""))
+	}
+	return cmds, nil
+}
+
+// CompleteCommand records the result the agent reported for one of its commands.
+func (uc *commandUseCase) CompleteCommand(agentID string, commandID uint, success bool, message string, exitCode *int32) error {
+	cmd, err := uc.findAgentCommand(agentID, commandID)
+	if err != nil {
+		return err
+	}
+
+	status := model.CommandSucceeded
+	if !success {
+		status = model.CommandFailed
+	}
+	ok, err := uc.commands.TransitionCommand(commandID, []string{model.CommandPending, model.CommandSent}, status, message)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCommandState
+	}
+	if exitCode != nil {
+		if err := uc.commands.SetCommandExitCode(commandID, *exitCode); err != nil {
+			return err
+		}
+	}
+	logging.Infof("Command %d on agent %s finished with status %s.", commandID, agentID, status)
+	uc.events.Publish(commandEvent(cmd, status, message))
+	if !success {
+		uc.events.Publish(NewEvent(model.EventCommandFailed, agentID, map[string]interface{}{
+			"command_id": commandID,
+			"type":       cmd.Type,
+			"message":    message,
+		}))
+	}
+	return nil
+}
+
+// ListCommands returns commands matching the filter, newest first.
+func (uc *commandUseCase) ListCommands(filter model.CommandFilter) ([]model.Command, error) {
+	return uc.commands.FindCommands(filter)
+}
+
+// AppendCommandOutput stores output of a command the agent is running.
+func (uc *commandUseCase) AppendCommandOutput(agentID string, commandID uint, chunks []model.CommandOutputChunk, maxBytes int64) (bool, error) {
+	cmd, err := uc.findAgentCommand(agentID, commandID)
+	if err != nil {
+		return false, err
+	}
+	if cmd.Status != model.CommandSent {
+		return false, ErrInvalidCommandState
+	}
+	truncated, err := uc.commands.AppendCommandOutput(commandID, chunks, maxBytes)
+	if err != nil {
+		return false, err
+	}
+	if truncated && !cmd.OutputTruncated {
+		logging.Warnf("Output of command %d on agent %s exceeds %d bytes; dropping the rest.", commandID, agentID, maxBytes)
+	}
+	return truncated, nil
+}
+
+// GetCommandOutput returns a command with the output stored for it.
+func (uc *commandUseCase) GetCommandOutput(commandID uint) (*model.Command, []model.CommandOutputChunk, error) {
+	cmd, err := uc.commands.FindCommandByID(commandID)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunks, err := uc.commands.FindCommandOutput(commandID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cmd, chunks, nil
+}
+
+// findAgentCommand returns a command of the agent.
+func (uc *commandUseCase) findAgentCommand(agentID string, commandID uint) (*model.Command, error) {
+	cmd, err := uc.commands.FindCommandByID(commandID)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.AgentID != agentID {
+		// Do not reveal commands that belong to other agents.
+		return nil, gorm.ErrRecordNotFound
+	}
+	return cmd, nil
+}
+
+// CancelCommand cancels a command that the agent has not picked up yet.
+func (uc *commandUseCase) CancelCommand(commandID uint) (*model.Command, error) {
+	return uc.transition(commandID, []string{model.CommandPending, model.CommandAwaitingApproval}, model.CommandCancelled, "cancelled by operator")
+}
+
+// transition moves a command from one of the from states to status `to` on
+// an operator's request and publishes the change.
+func (uc *commandUseCase) transition(commandID uint, from []string, to, message string) (*model.Command, error) {
+	if _, err := uc.commands.FindCommandByID(commandID); err != nil {
+		return nil, err
+	}
+	ok, err := uc.commands.TransitionCommand(commandID, from, to, message)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCommandState
+	}
+	cmd, err := uc.commands.FindCommandByID(commandID)
+	if err != nil {
+		return nil, err
+	}
+	uc.events.Publish(commandEvent(cmd, cmd.Status, cmd.ResultMessage))
+	return cmd, nil
+}
+
+// commandEvent reports that a command moved to status.
+func commandEvent(cmd *model.Command, status, message string) model.Event {
+	return NewEvent(model.EventCommandStateChanged, cmd.AgentID, map[string]interface{}{
+		"command_id": cmd.ID,
+		"type":       cmd.Type,
+		"status":     status,
+		"message":    message,
+	})
+}
@@ -0,0 +1,376 @@
+// internal/usecase/compliance_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/compliance"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrPolicyChanged is returned by ReloadPolicies for a policy file that was
+// edited without bumping its version.
+var ErrPolicyChanged = errors.New("policy changed without a new version")
+
+// ComplianceCheckSummary counts the results of one check across the fleet.
+type ComplianceCheckSummary struct {
+	CheckID       string
+	Title         string
+	Severity      string
+	Passed        int
+	Failed        int
+	NotApplicable int
+}
+
+// CompliancePolicySummary is the fleet report of one policy. An agent is
+// compliant when none of the policy's checks fails on it.
+type CompliancePolicySummary struct {
+	model.CompliancePolicy
+	Agents          int
+	CompliantAgents int
+	Checks          []ComplianceCheckSummary // in policy order
+}
+
+// ComplianceUseCase evaluates the loaded compliance policies against the
+// inventory of each agent. It is also an EventPublisher: an agent is
+// re-evaluated when it registers or reports new inventory and its results
+// are dropped when it is decommissioned.
+type ComplianceUseCase interface {
+	EventPublisher
+	// ReloadPolicies loads the policies at path (see compliance.LoadPolicies)
+	// and stores every version not seen before. On error the previous
+	// policies stay in use.
+	ReloadPolicies(path string) error
+	// Policies returns the loaded policies, by ID.
+	Policies() []model.CompliancePolicy
+	// PolicyVersions returns the stored versions of a policy, newest first.
+	PolicyVersions(policyID string) ([]model.CompliancePolicy, error)
+	// EvaluateAgent evaluates every policy that applies to the agent and
+	// stores the results.
+	EvaluateAgent(agentID string) ([]model.ComplianceResult, error)
+	// EvaluateAll evaluates every agent and returns how many were evaluated.
+	EvaluateAll() (int, error)
+	ListResults(filter model.ComplianceResultFilter) ([]model.ComplianceResult, error)
+	// ListEvaluations returns the history of results, newest first.
+	ListEvaluations(filter model.ComplianceEvaluationFilter) ([]model.ComplianceEvaluation, error)
+	// Report summarizes the results of one policy, or of every loaded
+	// policy when policyID is empty.
+	Report(policyID string) ([]CompliancePolicySummary, error)
+}
+
+type complianceUseCase struct {
+	repo    repository.ComplianceRepository
+	agents  repository.AgentRepository
+	catalog SoftwareUseCase // catalog rules resolve the product keys of app checks
+
+	mu       sync.RWMutex
+	policies []*compliance.Policy
+	records  map[string]model.CompliancePolicy // stored version of each loaded policy
+}
+
+// NewComplianceUseCase creates a new instance of the compliance use case layer.
+func NewComplianceUseCase(repo repository.ComplianceRepository, agents repository.AgentRepository, catalog SoftwareUseCase) ComplianceUseCase {
+	return &complianceUseCase{repo: repo, agents: agents, catalog: catalog, records: map[string]model.CompliancePolicy{}}
+}
+
+func (uc *complianceUseCase) ReloadPolicies(path string) error {
+	policies, err := compliance.LoadPolicies(path)
+	if err != nil {
+		return err
+	}
+	records := make(map[string]model.CompliancePolicy, len(policies))
+	for _, p := range policies {
+		record, err := uc.storePolicy(p)
+		if err != nil {
+			return err
+		}
+		records[p.ID] = *record
+	}
+
+	uc.mu.Lock()
+	var removed []string
+	for id := range uc.records {
+		if _, ok := records[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	uc.policies, uc.records = policies, records
+	uc.mu.Unlock()
+
+	for _, id := range removed {
+		if err := uc.repo.DeleteResults("", id); err != nil {
+			logging.Errorf("Failed to delete the results of removed compliance policy %s: %v", id, err)
+		}
+	}
+	if len(policies) > 0 || len(removed) > 0 {
+		logging.Infof("Loaded %d compliance policies.", len(policies))
+	}
+	return nil
+}
+
+// storePolicy returns the stored version of p, saving it first if it is new.
+func (uc *complianceUseCase) storePolicy(p *compliance.Policy) (*model.CompliancePolicy, error) {
+	stored, err := uc.repo.FindPolicyVersion(p.ID, p.Version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		record := &model.CompliancePolicy{
+			PolicyID:    p.ID,
+			Version:     p.Version,
+			Name:        p.Name,
+			Description: p.Description,
+			Checks:      p.Len(),
+			Digest:      p.Digest,
+			Definition:  string(p.Definition),
+			LoadedAt:    time.Now().UTC(),
+		}
+		if err := uc.repo.SavePolicyVersion(record); err == nil {
+			logging.Infof("Stored compliance policy %s version %d.", p.ID, p.Version)
+			return record, nil
+		}
+		// Another server may have stored the same version first.
+		stored, err = uc.repo.FindPolicyVersion(p.ID, p.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.Digest != p.Digest {
+		return nil, fmt.Errorf("%w: %s version %d differs from the stored one; bump its version", ErrPolicyChanged, p.ID, p.Version)
+	}
+	return stored, nil
+}
+
+func (uc *complianceUseCase) Policies() []model.CompliancePolicy {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	out := make([]model.CompliancePolicy, 0, len(uc.policies))
+	for _, p := range uc.policies {
+		out = append(out, uc.records[p.ID])
+	}
+	return out
+}
+
+func (uc *complianceUseCase) PolicyVersions(policyID string) ([]model.CompliancePolicy, error) {
+	return uc.repo.ListPolicyVersions(policyID)
+}
+
+func (uc *complianceUseCase) loaded() []*compliance.Policy {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	return uc.policies
+}
+
+func (uc *complianceUseCase) EvaluateAgent(agentID string) ([]model.ComplianceResult, error) {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent.Status == "DECOMMISSIONED" {
+		return nil, uc.repo.DeleteResults(agentID, "")
+	}
+	previous, err := uc.repo.FindResults(model.ComplianceResultFilter{AgentID: agentID})
+	if err != nil {
+		return nil, err
+	}
+	previousByPolicy := map[string][]model.ComplianceResult{}
+	for _, r := range previous {
+		previousByPolicy[r.PolicyID] = append(previousByPolicy[r.PolicyID], r)
+	}
+
+	policies := uc.loaded()
+	inv := &compliance.Inventory{Agent: agent, Catalog: uc.catalog.Rules(), Now: time.Now().UTC()}
+	if len(policies) > 0 {
+		if inv.FirewallRules, err = uc.agents.FindCurrentFirewallRules(agent.ID); err != nil {
+			return nil, err
+		}
+		if inv.Apps, err = uc.agents.FindCurrentInstalledApps(agent.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var all []model.ComplianceResult
+	for _, p := range policies {
+		old := previousByPolicy[p.ID]
+		delete(previousByPolicy, p.ID)
+		if !p.Applies(agent) {
+			if len(old) > 0 {
+				if err := uc.repo.ReplaceAgentResults(agentID, p.ID, nil); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		results, err := uc.evaluatePolicy(p, inv, old)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, results...)
+	}
+	// Results of policies that are no longer loaded.
+	for policyID := range previousByPolicy {
+		if err := uc.repo.ReplaceAgentResults(agentID, policyID, nil); err != nil {
+			return nil, err
+		}
+	}
+	return all, nil
+}
+
+// evaluatePolicy stores the results of p on one agent. A check keeps its
+// Since time while its status does not change, and the history gets a new
+// entry only when some status changed.
+func (uc *complianceUseCase) evaluatePolicy(p *compliance.Policy, inv *compliance.Inventory, previous []model.ComplianceResult) ([]model.ComplianceResult, error) {
+	agentID := inv.Agent.AgentID
+	old := make(map[string]model.ComplianceResult, len(previous))
+	previousFailed := 0
+	for _, r := range previous {
+		old[r.CheckID] = r
+		if r.Status == model.ComplianceFail {
+			previousFailed++
+		}
+	}
+
+	results := p.Evaluate(inv)
+	changed := len(results) != len(previous) || (len(previous) > 0 && previous[0].PolicyVersion != p.Version)
+	evaluation := &model.ComplianceEvaluation{AgentID: agentID, PolicyID: p.ID, PolicyVersion: p.Version, EvaluatedAt: inv.Now}
+	var failed []string
+	for i := range results {
+		r := &results[i]
+		r.AgentID = agentID
+		r.EvaluatedAt = inv.Now
+		r.Since = inv.Now
+		if prev, ok := old[r.CheckID]; ok && prev.Status == r.Status {
+			r.Since = prev.Since
+		} else {
+			changed = true
+		}
+		switch r.Status {
+		case model.CompliancePass:
+			evaluation.Passed++
+		case model.ComplianceFail:
+			evaluation.Failed++
+			failed = append(failed, r.CheckID)
+		default:
+			evaluation.NotApplicable++
+		}
+	}
+	if err := uc.repo.ReplaceAgentResults(agentID, p.ID, results); err != nil {
+		return nil, err
+	}
+	if !changed {
+		return results, nil
+	}
+	evaluation.FailedChecks = strings.Join(failed, ",")
+	if err := uc.repo.AddEvaluation(evaluation); err != nil {
+		return nil, err
+	}
+	if evaluation.Failed > previousFailed {
+		logging.Infof("Agent %s fails %d checks of compliance policy %s (was %d).", agentID, evaluation.Failed, p.ID, previousFailed)
+	}
+	return results, nil
+}
+
+func (uc *complianceUseCase) EvaluateAll() (int, error) {
+	if len(uc.loaded()) == 0 {
+		return 0, nil
+	}
+	ids, err := uc.agents.ListAgentIDs()
+	if err != nil {
+		return 0, err
+	}
+	evaluated := 0
+	for _, id := range ids {
+		if _, err := uc.EvaluateAgent(id); err != nil {
+			logging.Errorf("Failed to evaluate compliance of agent %s: %v", id, err)
+			continue
+		}
+		evaluated++
+	}
+	return evaluated, nil
+}
+
+func (uc *complianceUseCase) ListResults(filter model.ComplianceResultFilter) ([]model.ComplianceResult, error) {
+	filter.Status = strings.ToUpper(filter.Status)
+	return uc.repo.FindResults(filter)
+}
+
+func (uc *complianceUseCase) ListEvaluations(filter model.ComplianceEvaluationFilter) ([]model.ComplianceEvaluation, error) {
+	return uc.repo.FindEvaluations(filter)
+}
+
+func (uc *complianceUseCase) Report(policyID string) ([]CompliancePolicySummary, error) {
+	var summaries []CompliancePolicySummary
+	for _, record := range uc.Policies() {
+		if policyID != "" && record.PolicyID != policyID {
+			continue
+		}
+		results, err := uc.repo.FindResults(model.ComplianceResultFilter{PolicyID: record.PolicyID})
+		if err != nil {
+			return nil, err
+		}
+		summary := CompliancePolicySummary{CompliancePolicy: record}
+		index := map[string]int{} // check ID -> position in summary.Checks
+		failing := map[string]bool{}
+		agents := map[string]bool{}
+		for _, r := range results {
+			agents[r.AgentID] = true
+			i, ok := index[r.CheckID]
+			if !ok {
+				i = len(summary.Checks)
+				index[r.CheckID] = i
+				summary.Checks = append(summary.Checks, ComplianceCheckSummary{CheckID: r.CheckID, Title: r.Title, Severity: r.Severity})
+			}
+			c := &summary.Checks[i]
+			switch r.Status {
+			case model.CompliancePass:
+				c.Passed++
+			case model.ComplianceFail:
+				c.Failed++
+				failing[r.AgentID] = true
+			default:
+				c.NotApplicable++
+			}
+		}
+		summary.Agents = len(agents)
+		summary.CompliantAgents = len(agents) - len(failing)
+		summaries = append(summaries, summary)
+	}
+	if policyID != "" && len(summaries) == 0 {
+		return nil, fmt.Errorf("%w: compliance policy %s is not loaded", gorm.ErrRecordNotFound, policyID)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].PolicyID < summaries[j].PolicyID })
+	return summaries, nil
+}
+
+// Publish re-evaluates an agent when it registers, reports new inventory or
+// comes back from decommissioning, and clears its results when it is
+// decommissioned.
+func (uc *complianceUseCase) Publish(event model.Event) {
+	switch event.Type {
+	case model.EventInventoryChanged, model.EventAgentRegistered, model.EventAgentUpdated:
+	case model.EventAgentStatusChanged:
+		if event.Data["to"] == "DECOMMISSIONED" {
+			if err := uc.repo.DeleteResults(event.AgentID, ""); err != nil {
+				logging.Errorf("Failed to clear compliance results of agent %s: %v", event.AgentID, err)
+			}
+			return
+		}
+		if event.Data["from"] != "DECOMMISSIONED" {
+			return
+		}
+	default:
+		return
+	}
+	if len(uc.loaded()) == 0 {
+		return
+	}
+	if _, err := uc.EvaluateAgent(event.AgentID); err != nil {
+		logging.Errorf("Failed to evaluate compliance of agent %s: %v", event.AgentID, err)
+	}
+}
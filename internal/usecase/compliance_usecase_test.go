@@ -0,0 +1,172 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const examplePolicy = "../../examples/compliance/workstation-baseline.yaml"
+
+func newComplianceFixture(t *testing.T) (usecase.AgentUseCase, usecase.ComplianceUseCase) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	compliance := usecase.NewComplianceUseCase(store.Compliance, store.Agents, usecase.NewSoftwareUseCase(store.Agents))
+	bus := usecase.NewEventBus(store.Agents, config.StaticProvider(config.Default()), nil, compliance)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	for _, a := range []*model.Agent{
+		{AgentID: "win-01", OSName: "Windows 11 Pro", KernelVersion: "10.0.22631"},
+		{AgentID: "win-02", OSName: "Windows 10 Pro", KernelVersion: "10.0.19045"},
+		{AgentID: "deb-01", OSName: "Debian GNU/Linux", KernelVersion: "6.1.0"},
+	} {
+		if _, err := agents.RegisterAgent(a); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+	return agents, compliance
+}
+
+func resultsByCheck(t *testing.T, compliance usecase.ComplianceUseCase, agentID string) map[string]model.ComplianceResult {
+	t.Helper()
+	results, err := compliance.ListResults(model.ComplianceResultFilter{AgentID: agentID})
+	if err != nil {
+		t.Fatalf("ListResults: %v", err)
+	}
+	byCheck := map[string]model.ComplianceResult{}
+	for _, r := range results {
+		byCheck[r.CheckID] = r
+	}
+	return byCheck
+}
+
+func TestComplianceEvaluatesPolicyAgainstInventory(t *testing.T) {
+	agents, compliance := newComplianceFixture(t)
+	if err := compliance.ReloadPolicies(examplePolicy); err != nil {
+		t.Fatalf("ReloadPolicies: %v", err)
+	}
+	denyAll := model.FirewallRule{Name: "Default deny", Port: "ANY", Protocol: "ANY", Action: "DENY", Direction: "DIRECTION_IN", Enabled: true}
+	chrome := model.InstalledApplication{Name: "Google Chrome", Publisher: "Google LLC", Version: "121.0.6167.85"}
+	for _, id := range []string{"win-01", "win-02", "deb-01"} {
+		if err := agents.StoreFirewallRules(id, []model.FirewallRule{denyAll}); err != nil {
+			t.Fatalf("StoreFirewallRules: %v", err)
+		}
+		reportApps(t, agents, id, chrome)
+	}
+
+	got := resultsByCheck(t, compliance, "win-01")
+	if len(got) != 6 {
+		t.Fatalf("win-01 results = %+v, want the 6 checks of the example policy", got)
+	}
+	for check, r := range got {
+		if r.Status != model.CompliancePass {
+			t.Errorf("win-01 %s = %s (%s), want PASS", check, r.Status, r.Evidence)
+		}
+	}
+	if r := resultsByCheck(t, compliance, "win-02")["windows-11"]; r.Status != model.ComplianceFail || !strings.Contains(r.Evidence, "10.0.19045") {
+		t.Errorf("win-02 windows-11 = %+v, want FAIL with the reported kernel version", r)
+	}
+	if r := resultsByCheck(t, compliance, "deb-01"); len(r) != 0 {
+		t.Errorf("deb-01 results = %+v, want none: the policy applies to Windows only", r)
+	}
+
+	// Installing Telnet fails one check; the others keep their Since time.
+	before := got["chrome-current"].Since
+	reportApps(t, agents, "win-01", chrome, model.InstalledApplication{Name: "Telnet Client", Version: "1.0"})
+	got = resultsByCheck(t, compliance, "win-01")
+	if r := got["no-telnet"]; r.Status != model.ComplianceFail || !strings.Contains(r.Evidence, "Telnet Client") {
+		t.Fatalf("no-telnet = %+v, want FAIL naming the installed app", r)
+	}
+	if !got["chrome-current"].Since.Equal(before) {
+		t.Errorf("chrome-current Since moved from %v to %v without a status change", before, got["chrome-current"].Since)
+	}
+
+	history, err := compliance.ListEvaluations(model.ComplianceEvaluationFilter{AgentID: "win-01"})
+	if err != nil {
+		t.Fatalf("ListEvaluations: %v", err)
+	}
+	// Chrome was missing until the first application report.
+	if len(history) != 3 || history[2].FailedChecks != "chrome-current" || history[1].Failed != 0 || history[0].FailedChecks != "no-telnet" {
+		t.Fatalf("history = %+v, want chrome-current failing, then all passing, then no-telnet failing", history)
+	}
+	// Re-evaluating an unchanged agent adds no history.
+	if _, err := compliance.EvaluateAgent("win-01"); err != nil {
+		t.Fatalf("EvaluateAgent: %v", err)
+	}
+	if again, _ := compliance.ListEvaluations(model.ComplianceEvaluationFilter{AgentID: "win-01"}); len(again) != 3 {
+		t.Fatalf("got %d history entries after an unchanged evaluation, want 3", len(again))
+	}
+
+	report, err := compliance.Report("workstation-baseline")
+	if err != nil || len(report) != 1 {
+		t.Fatalf("Report = %+v, %v", report, err)
+	}
+	if r := report[0]; r.Agents != 2 || r.CompliantAgents != 0 || len(r.Checks) != 6 || r.Checks[1].CheckID != "no-telnet" || r.Checks[1].Failed != 1 {
+		t.Fatalf("report = %+v, want 2 agents, none compliant, and no-telnet failing on one", r)
+	}
+
+	if _, err := agents.DecommissionAgent("win-01"); err != nil {
+		t.Fatalf("DecommissionAgent: %v", err)
+	}
+	if left := resultsByCheck(t, compliance, "win-01"); len(left) != 0 {
+		t.Fatalf("decommissioned agent still has results %+v", left)
+	}
+}
+
+func TestComplianceRequiresNewVersionForChangedPolicy(t *testing.T) {
+	agents, compliance := newComplianceFixture(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "telnet.yaml")
+	write := func(version, severity string) {
+		t.Helper()
+		policy := "id: no-telnet\nversion: " + version + "\nchecks:\n  - id: telnet\n    severity: " + severity + "\n    app_absent: {name: telnet}\n"
+		if err := os.WriteFile(path, []byte(policy), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("1", "low")
+	if err := compliance.ReloadPolicies(dir); err != nil {
+		t.Fatalf("ReloadPolicies: %v", err)
+	}
+	reportApps(t, agents, "deb-01", model.InstalledApplication{Name: "telnet", Version: "0.17"})
+
+	write("1", "high")
+	if err := compliance.ReloadPolicies(dir); !errors.Is(err, usecase.ErrPolicyChanged) {
+		t.Fatalf("reloading an edited policy under the same version: err = %v, want ErrPolicyChanged", err)
+	}
+	if p := compliance.Policies(); len(p) != 1 || p[0].Version != 1 {
+		t.Fatalf("Policies after a rejected reload = %+v, want version 1 still loaded", p)
+	}
+
+	write("2", "high")
+	if err := compliance.ReloadPolicies(dir); err != nil {
+		t.Fatalf("ReloadPolicies(version 2): %v", err)
+	}
+	if n, err := compliance.EvaluateAll(); err != nil || n != 3 {
+		t.Fatalf("EvaluateAll = %d, %v; want 3 agents", n, err)
+	}
+	if r := resultsByCheck(t, compliance, "deb-01")["telnet"]; r.PolicyVersion != 2 || r.Severity != model.SeverityHigh || r.Status != model.ComplianceFail {
+		t.Fatalf("telnet = %+v, want a HIGH failure under version 2", r)
+	}
+	versions, err := compliance.PolicyVersions("no-telnet")
+	if err != nil || len(versions) != 2 || versions[0].Version != 2 || !strings.Contains(versions[1].Definition, "severity: low") {
+		t.Fatalf("PolicyVersions = %+v, %v; want versions 2 and 1 with their definitions", versions, err)
+	}
+
+	// Removing the policy file drops its results.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := compliance.ReloadPolicies(dir); err != nil {
+		t.Fatalf("ReloadPolicies(empty): %v", err)
+	}
+	if left, _ := compliance.ListResults(model.ComplianceResultFilter{}); len(left) != 0 {
+		t.Fatalf("results of a removed policy = %+v", left)
+	}
+}
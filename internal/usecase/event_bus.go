@@ -0,0 +1,383 @@
+// internal/usecase/event_bus.go
+
+package usecase
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSequenceUnavailable is returned when a subscriber asks to resume
+	// after a sequence the bus no longer holds, or one from another epoch.
+	ErrSequenceUnavailable = errors.New("events after this sequence are no longer available")
+	// ErrSubscriberLagged ends a subscription that fell too far behind.
+	ErrSubscriberLagged = errors.New("subscriber fell behind the event stream")
+	// ErrBusClosed ends every subscription when the server shuts down, and is
+	// returned by Subscribe afterwards.
+	ErrBusClosed = errors.New("event bus is closed")
+)
+
+// StreamEventTypes lists the events that WatchEvents subscribers can receive.
+var StreamEventTypes = []string{
+	model.EventAgentRegistered,
+	model.EventAgentUpdated,
+	model.EventAgentStatusChanged,
+	model.EventAgentOffline,
+	model.EventAgentHeartbeat,
+	model.EventFirewallChanged,
+	model.EventInventoryChanged,
+	model.EventCommandStateChanged,
+	model.EventCommandFailed,
+	model.EventCommandApproval,
+	model.EventAgentCloneSuspected,
+	model.EventAlertFiring,
+	model.EventAlertResolved,
+	model.EventRolloutPaused,
+	model.EventFileCollected,
+	model.EventFileDownloaded,
+	model.EventJobRunFinished,
+}
+
+// EventFilter selects the events a subscriber receives. Empty fields match
+// everything; AgentIDs and EventTypes match any listed value and every
+// label must match.
+type EventFilter struct {
+	AgentIDs   []string
+	Labels     map[string]string
+	EventTypes []string
+}
+
+// Matches reports whether the event passes the filter.
+func (f EventFilter) Matches(event model.Event) bool {
+	if len(f.AgentIDs) > 0 && !contains(f.AgentIDs, event.AgentID) {
+		return false
+	}
+	if len(f.EventTypes) > 0 && !contains(f.EventTypes, event.Type) {
+		return false
+	}
+	return event.Labels.Match(f.Labels)
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription is one live reader of the event bus.
+type Subscription struct {
+	bus    *EventBus
+	filter EventFilter
+	events chan model.Event
+	err    error // set under bus.mu before events is closed
+	closed bool
+}
+
+// Events returns the matching events in sequence order. The channel is
+// closed when the subscription ends; Err tells why.
+func (s *Subscription) Events() <-chan model.Event {
+	return s.events
+}
+
+// Err returns ErrSubscriberLagged if the bus dropped the subscriber,
+// ErrBusClosed if the bus was closed, or nil.
+func (s *Subscription) Err() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s, nil)
+}
+
+// EventBus numbers the events published by the use cases, keeps the latest
+// ones for subscribers that reconnect, and fans them out to WatchEvents
+// subscribers, to the webhook outbox and to the sinks (vulnerability
+// matching, firewall analysis, compliance). Publish never waits for a
+// subscriber: one whose buffer is full is dropped and has to resume from the
+// last sequence it received.
+//
+// The outbox is written before Publish returns, so an event whose publisher
+// finished is in the database even if the server crashes right after. The
+// sinks only derive state they rebuild from the next report, so while Run is
+// running they get the events through a queue of events.sink_queue_size
+// events and Publish does not wait on their database work. A full queue
+// makes Publish wait for room rather than drop the event or overtake the
+// queued ones; without Run the sinks are called on the publisher's
+// goroutine.
+//
+// Sequences start again at 1 in every process, so each bus also has a
+// random epoch. A subscriber resumes with the epoch and sequence it last
+// received, and a resume from another epoch is refused.
+type EventBus struct {
+	agents repository.AgentRepository
+	cfg    config.Provider
+	outbox EventPublisher
+	sinks  []EventPublisher
+	epoch  string
+
+	sinkQueue chan model.Event
+	sinkMu    sync.RWMutex // held for reading while queueing, so Run can stop without losing or reordering events
+	async     bool         // Run is delivering sinkQueue
+
+	mu       sync.Mutex
+	closed   bool
+	seq      uint64
+	ring     []model.Event // the last len(ring) events, oldest at next once full
+	next     int
+	count    int
+	subs     map[*Subscription]struct{}
+	labels   map[string]model.Labels // agent labels for events raised without them
+	lastBeat map[string]time.Time    // for heartbeat sampling
+}
+
+// NewEventBus creates a bus that keeps events.buffer_size events for resuming
+// and forwards every event to outbox and sinks after numbering it. outbox may
+// be nil. agents is used to look up the labels of agents that have not raised
+// an event with labels yet.
+func NewEventBus(agents repository.AgentRepository, cfg config.Provider, outbox EventPublisher, sinks ...EventPublisher) *EventBus {
+	var epoch [8]byte
+	rand.Read(epoch[:])
+	return &EventBus{
+		agents:    agents,
+		cfg:       cfg,
+		outbox:    outbox,
+		sinks:     sinks,
+		epoch:     hex.EncodeToString(epoch[:]),
+		sinkQueue: make(chan model.Event, cfg.Current().Events.SinkQueueSize),
+		ring:      make([]model.Event, cfg.Current().Events.BufferSize),
+		subs:      make(map[*Subscription]struct{}),
+		labels:    make(map[string]model.Labels),
+		lastBeat:  make(map[string]time.Time),
+	}
+}
+
+// Publish numbers the event, stores it and hands it to every matching
+// subscriber, the outbox and the sinks.
+func (b *EventBus) Publish(event model.Event) {
+	if event.AgentID != "" && event.Labels == nil {
+		event.Labels = b.labelsFor(event.AgentID)
+	}
+
+	b.mu.Lock()
+	if event.Type == model.EventAgentHeartbeat && !b.sampleHeartbeat(event) {
+		b.mu.Unlock()
+		return
+	}
+	if event.AgentID != "" {
+		b.labels[event.AgentID] = event.Labels
+	}
+	b.seq++
+	event.Sequence = b.seq
+	if len(b.ring) > 0 {
+		b.ring[b.next] = event
+		b.next = (b.next + 1) % len(b.ring)
+		if b.count < len(b.ring) {
+			b.count++
+		}
+	}
+	for sub := range b.subs {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.remove(sub, ErrSubscriberLagged)
+		}
+	}
+	b.mu.Unlock()
+
+	if b.outbox != nil {
+		b.outbox.Publish(event)
+	}
+	b.toSinks(event)
+}
+
+// toSinks queues the event for Run, waiting for room when the queue is full,
+// or delivers it on the caller's goroutine when Run is not running.
+func (b *EventBus) toSinks(event model.Event) {
+	if len(b.sinks) == 0 {
+		return
+	}
+	b.sinkMu.RLock()
+	if b.async {
+		select {
+		case b.sinkQueue <- event:
+		default:
+			logging.Warnf("Event sink queue is full (%d events); event %d waits for room", cap(b.sinkQueue), event.Sequence)
+			b.sinkQueue <- event
+		}
+		b.sinkMu.RUnlock()
+		return
+	}
+	b.sinkMu.RUnlock()
+	b.deliver(event)
+}
+
+func (b *EventBus) deliver(event model.Event) {
+	for _, sink := range b.sinks {
+		sink.Publish(event)
+	}
+}
+
+// Run delivers queued events to the sinks until ctx is done, then delivers
+// what is still queued and returns. Events published afterwards reach the
+// sinks synchronously, after the queued ones.
+func (b *EventBus) Run(ctx context.Context) {
+	b.sinkMu.Lock()
+	b.async = true
+	b.sinkMu.Unlock()
+
+	var stopped chan struct{}
+	for {
+		select {
+		case event := <-b.sinkQueue:
+			b.deliver(event)
+		case <-ctx.Done():
+			// Keep delivering until the publishers waiting on a full queue
+			// have queued their events and released sinkMu.
+			stopped = make(chan struct{})
+			go func() {
+				b.sinkMu.Lock()
+				close(stopped)
+			}()
+			ctx = context.Background()
+		case <-stopped:
+			b.async = false
+			for {
+				select {
+				case event := <-b.sinkQueue:
+					b.deliver(event)
+				default:
+					b.sinkMu.Unlock()
+					return
+				}
+			}
+		}
+	}
+}
+
+// sampleHeartbeat reports whether a heartbeat event should be published
+// under events.heartbeat_sample_seconds. Called with b.mu held.
+func (b *EventBus) sampleHeartbeat(event model.Event) bool {
+	sample := b.cfg.Current().Events.HeartbeatSample()
+	if sample <= 0 {
+		return true
+	}
+	if last, ok := b.lastBeat[event.AgentID]; ok && event.OccurredAt.Sub(last) < sample {
+		return false
+	}
+	b.lastBeat[event.AgentID] = event.OccurredAt
+	return true
+}
+
+// labelsFor returns the cached labels of an agent, loading them once from
+// the repository. Unknown agents get no labels.
+func (b *EventBus) labelsFor(agentID string) model.Labels {
+	b.mu.Lock()
+	labels, ok := b.labels[agentID]
+	b.mu.Unlock()
+	if ok {
+		return labels
+	}
+
+	agent, err := b.agents.FindAgentByID(agentID)
+	if err != nil {
+		logging.Debugf("No labels for event of agent %s: %v", agentID, err)
+		return model.Labels{}
+	}
+	if agent.Labels == nil {
+		return model.Labels{}
+	}
+	return agent.Labels
+}
+
+// Subscribe starts a subscription. With afterSequence > 0 the buffered events
+// after it are delivered first, so a client that reconnects misses nothing;
+// ErrSequenceUnavailable means they are gone, or epoch is not this bus's
+// epoch because the server restarted, and the client must resync. With
+// afterSequence == 0 only new events are delivered and epoch is ignored.
+func (b *EventBus) Subscribe(filter EventFilter, epoch string, afterSequence uint64) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+
+	var backlog []model.Event
+	if afterSequence > 0 {
+		oldest := b.seq - uint64(b.count) + 1
+		if epoch != b.epoch || afterSequence > b.seq || afterSequence+1 < oldest {
+			return nil, ErrSequenceUnavailable
+		}
+		for i := 0; i < b.count; i++ {
+			event := b.ring[(b.next-b.count+i+len(b.ring))%len(b.ring)]
+			if event.Sequence > afterSequence && filter.Matches(event) {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	sub := &Subscription{
+		bus:    b,
+		filter: filter,
+		events: make(chan model.Event, b.cfg.Current().Events.SubscriberBuffer+len(backlog)),
+	}
+	for _, event := range backlog {
+		sub.events <- event
+	}
+	b.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close ends every subscription with ErrBusClosed and refuses new ones, so
+// that streaming RPCs return and a graceful stop of the server can finish.
+// Events published afterwards still reach the sinks.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub, ErrBusClosed)
+	}
+}
+
+// Epoch identifies this bus's sequence numbers; see Subscribe.
+func (b *EventBus) Epoch() string {
+	return b.epoch
+}
+
+// LastSequence returns the sequence of the latest published event.
+func (b *EventBus) LastSequence() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// remove ends a subscription with err. Called with b.mu held.
+func (b *EventBus) remove(sub *Subscription, err error) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	sub.err = err
+	delete(b.subs, sub)
+	close(sub.events)
+}
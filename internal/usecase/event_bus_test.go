@@ -0,0 +1,322 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newEventBus(t *testing.T, bufferSize, subscriberBuffer int) (*repository.Store, *usecase.EventBus) {
+	cfg := config.Default()
+	cfg.Events.BufferSize = bufferSize
+	cfg.Events.SubscriberBuffer = subscriberBuffer
+	store := repository.NewMemoryStore()
+	return store, usecase.NewEventBus(store.Agents, config.StaticProvider(cfg), nil)
+}
+
+func subscribeBus(t *testing.T, bus *usecase.EventBus, filter usecase.EventFilter, after uint64) *usecase.Subscription {
+	t.Helper()
+	sub, err := bus.Subscribe(filter, bus.Epoch(), after)
+	if err != nil {
+		t.Fatalf("Subscribe(after %d): %v", after, err)
+	}
+	t.Cleanup(sub.Close)
+	return sub
+}
+
+// drain returns the events already waiting on the subscription.
+func drain(sub *usecase.Subscription) []model.Event {
+	var events []model.Event
+	for {
+		select {
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, ev)
+		default:
+			return events
+		}
+	}
+}
+
+func sequences(events []model.Event) []uint64 {
+	seqs := make([]uint64, len(events))
+	for i, ev := range events {
+		seqs[i] = ev.Sequence
+	}
+	return seqs
+}
+
+func TestEventBusResumesAfterSequence(t *testing.T) {
+	_, bus := newEventBus(t, 10, 10)
+	for i := 0; i < 3; i++ {
+		bus.Publish(usecase.NewEvent(model.EventCommandStateChanged, "agent-1", nil))
+	}
+
+	sub := subscribeBus(t, bus, usecase.EventFilter{}, 1)
+	bus.Publish(usecase.NewEvent(model.EventCommandStateChanged, "agent-1", nil))
+
+	if got := sequences(drain(sub)); len(got) != 3 || got[0] != 2 || got[2] != 4 {
+		t.Fatalf("resumed after 1 and got sequences %v, want [2 3 4]", got)
+	}
+	if live := subscribeBus(t, bus, usecase.EventFilter{}, 0); len(drain(live)) != 0 {
+		t.Fatal("subscribing from 0 replayed old events")
+	}
+}
+
+func TestEventBusRejectsUnavailableSequence(t *testing.T) {
+	_, bus := newEventBus(t, 3, 10)
+	for i := 0; i < 5; i++ {
+		bus.Publish(usecase.NewEvent(model.EventCommandStateChanged, "agent-1", nil))
+	}
+	// Events 3-5 are buffered: resuming after 2 works, after 1 would miss event 2.
+	subscribeBus(t, bus, usecase.EventFilter{}, 2)
+	for _, after := range []uint64{1, 6} {
+		if _, err := bus.Subscribe(usecase.EventFilter{}, bus.Epoch(), after); !errors.Is(err, usecase.ErrSequenceUnavailable) {
+			t.Errorf("Subscribe(after %d) = %v, want ErrSequenceUnavailable", after, err)
+		}
+	}
+}
+
+func TestEventBusRejectsSequenceFromAnotherEpoch(t *testing.T) {
+	_, before := newEventBus(t, 10, 10)
+	_, after := newEventBus(t, 10, 10)
+	for i := 0; i < 3; i++ {
+		before.Publish(usecase.NewEvent(model.EventCommandStateChanged, "agent-1", nil))
+		after.Publish(usecase.NewEvent(model.EventCommandStateChanged, "agent-1", nil))
+	}
+	if before.Epoch() == after.Epoch() {
+		t.Fatalf("two buses share the epoch %q", before.Epoch())
+	}
+	// Sequence 1 exists on the restarted bus too, but numbers different events.
+	for _, epoch := range []string{before.Epoch(), ""} {
+		if _, err := after.Subscribe(usecase.EventFilter{}, epoch, 1); !errors.Is(err, usecase.ErrSequenceUnavailable) {
+			t.Errorf("Subscribe(epoch %q, after 1) = %v, want ErrSequenceUnavailable", epoch, err)
+		}
+	}
+	subscribeBus(t, after, usecase.EventFilter{}, 1)
+}
+
+func TestEventBusDropsSlowSubscriber(t *testing.T) {
+	_, bus := newEventBus(t, 100, 2)
+	slow := subscribeBus(t, bus, usecase.EventFilter{}, 0)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			bus.Publish(usecase.NewEvent(model.EventAgentHeartbeat, "agent-1", nil))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+
+	got := drain(slow)
+	if len(got) != 2 || !errors.Is(slow.Err(), usecase.ErrSubscriberLagged) {
+		t.Fatalf("slow subscriber got %v, err %v; want 2 events then ErrSubscriberLagged", sequences(got), slow.Err())
+	}
+	resumed := subscribeBus(t, bus, usecase.EventFilter{}, got[1].Sequence)
+	if rest := sequences(drain(resumed)); len(rest) != 3 || rest[0] != 3 {
+		t.Fatalf("resumed subscriber got %v, want [3 4 5]", rest)
+	}
+}
+
+func TestEventBusCloseEndsSubscriptions(t *testing.T) {
+	_, bus := newEventBus(t, 10, 10)
+	sub := subscribeBus(t, bus, usecase.EventFilter{}, 0)
+	bus.Close()
+
+	if _, ok := <-sub.Events(); ok || !errors.Is(sub.Err(), usecase.ErrBusClosed) {
+		t.Fatalf("subscription after Close: open %v, err %v; want closed with ErrBusClosed", ok, sub.Err())
+	}
+	if _, err := bus.Subscribe(usecase.EventFilter{}, "", 0); !errors.Is(err, usecase.ErrBusClosed) {
+		t.Fatalf("Subscribe after Close = %v, want ErrBusClosed", err)
+	}
+	bus.Publish(usecase.NewEvent(model.EventCommandStateChanged, "agent-1", nil))
+	if bus.LastSequence() != 1 {
+		t.Fatalf("LastSequence = %d, want events still numbered after Close", bus.LastSequence())
+	}
+}
+
+// blockingSink records events once release is closed.
+type blockingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	got     []uint64
+}
+
+func (s *blockingSink) Publish(event model.Event) {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.got = append(s.got, event.Sequence)
+}
+
+func (s *blockingSink) sequences() []uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint64(nil), s.got...)
+}
+
+func TestEventBusDeliversToSinksInTheBackground(t *testing.T) {
+	store := repository.NewMemoryStore()
+	sink := &blockingSink{release: make(chan struct{})}
+	outbox := &blockingSink{release: make(chan struct{})}
+	close(outbox.release)
+	bus := usecase.NewEventBus(store.Agents, config.StaticProvider(config.Default()), outbox, sink)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		bus.Run(ctx)
+		close(stopped)
+	}()
+	// Run switches to the queue before its first receive; publishing early
+	// would only make the first event synchronous.
+	time.Sleep(50 * time.Millisecond)
+
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			bus.Publish(usecase.NewEvent(model.EventCommandStateChanged, "agent-1", nil))
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish waited for a blocked sink")
+	}
+	// The outbox does not go through the queue: a returned Publish has
+	// written it.
+	if got := outbox.sequences(); len(got) != 3 {
+		t.Fatalf("outbox got %v, want all 3 events before Publish returned", got)
+	}
+
+	// Stopping Run still hands every queued event to the sinks.
+	cancel()
+	close(sink.release)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after ctx was cancelled")
+	}
+	if got := sink.sequences(); len(got) != 3 {
+		t.Fatalf("sink got %v, want all 3 events", got)
+	}
+	bus.Publish(usecase.NewEvent(model.EventCommandStateChanged, "agent-1", nil))
+	if got := sink.sequences(); len(got) != 4 || got[3] != 4 {
+		t.Fatalf("sink got %v after Run returned, want event 4 delivered synchronously", got)
+	}
+}
+
+func TestEventBusKeepsSinkOrderWhenTheQueueIsFull(t *testing.T) {
+	store := repository.NewMemoryStore()
+	cfg := config.Default()
+	cfg.Events.SinkQueueSize = 1
+	sink := &blockingSink{release: make(chan struct{})}
+	bus := usecase.NewEventBus(store.Agents, config.StaticProvider(cfg), nil, sink)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		bus.Run(ctx)
+		close(stopped)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Run holds event 1 in the blocked sink and event 2 fills the queue, so
+	// the publisher waits on event 3 instead of delivering it first.
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			bus.Publish(usecase.NewEvent(model.EventCommandStateChanged, "agent-1", nil))
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatal("Publish did not wait for room in a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Stopping Run while the publisher waits must neither deadlock nor let
+	// later events overtake the queued ones.
+	cancel()
+	close(sink.release)
+	for _, done := range []chan struct{}{published, stopped} {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("publishing or Run did not finish after the sink was released")
+		}
+	}
+	want := []uint64{1, 2, 3, 4, 5}
+	if got := sink.sequences(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("sink got %v, want %v", got, want)
+	}
+}
+
+func TestEventBusFiltersAgentEvents(t *testing.T) {
+	store, bus := newEventBus(t, 100, 100)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	prod := subscribeBus(t, bus, usecase.EventFilter{Labels: map[string]string{"env": "prod"}}, 0)
+	beats := subscribeBus(t, bus, usecase.EventFilter{EventTypes: []string{model.EventAgentHeartbeat}, AgentIDs: []string{"agent-2"}}, 0)
+
+	for _, a := range []*model.Agent{
+		{AgentID: "agent-1", Labels: model.Labels{"env": "prod"}},
+		{AgentID: "agent-2", Labels: model.Labels{"env": "dev"}},
+	} {
+		if _, err := agents.RegisterAgent(a); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+	for _, id := range []string{"agent-1", "agent-2"} {
+		if _, err := agents.ProcessHeartbeat(id, "10.0.0.1", ""); err != nil {
+			t.Fatalf("ProcessHeartbeat: %v", err)
+		}
+	}
+	if _, err := agents.DecommissionAgent("agent-1"); err != nil {
+		t.Fatalf("DecommissionAgent: %v", err)
+	}
+
+	var types []string
+	for _, ev := range drain(prod) {
+		if ev.AgentID != "agent-1" {
+			t.Fatalf("env=prod subscriber got an event for %s", ev.AgentID)
+		}
+		types = append(types, ev.Type)
+	}
+	want := []string{model.EventAgentRegistered, model.EventAgentHeartbeat, model.EventAgentStatusChanged}
+	if len(types) != len(want) || types[0] != want[0] || types[1] != want[1] || types[2] != want[2] {
+		t.Fatalf("env=prod subscriber got %v, want %v", types, want)
+	}
+	if got := drain(beats); len(got) != 1 || got[0].Labels["env"] != "dev" || got[0].Data["ip"] != "10.0.0.1" {
+		t.Fatalf("heartbeat subscriber got %+v", got)
+	}
+}
+
+func TestEventBusSamplesHeartbeats(t *testing.T) {
+	store := repository.NewMemoryStore()
+	cfg := config.Default()
+	cfg.Events.HeartbeatSampleSeconds = 60
+	bus := usecase.NewEventBus(store.Agents, config.StaticProvider(cfg), nil)
+	sub := subscribeBus(t, bus, usecase.EventFilter{}, 0)
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(usecase.NewEvent(model.EventAgentHeartbeat, "agent-1", nil))
+		bus.Publish(usecase.NewEvent(model.EventAgentHeartbeat, "agent-2", nil))
+	}
+	if got := drain(sub); len(got) != 2 {
+		t.Fatalf("got %d heartbeat events within the sample period, want one per agent", len(got))
+	}
+}
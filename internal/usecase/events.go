@@ -0,0 +1,53 @@
+// internal/usecase/events.go
+
+package usecase
+
+import (
+	"agent_server/internal/model"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// EventPublisher receives the fleet events raised by the use cases. Publish
+// must not block the caller for long and must not fail the operation that
+// raised the event.
+type EventPublisher interface {
+	Publish(event model.Event)
+}
+
+// noopPublisher drops events; it is used when no publisher is configured.
+type noopPublisher struct{}
+
+func (noopPublisher) Publish(model.Event) {}
+
+func publisherOrNoop(p EventPublisher) EventPublisher {
+	if p == nil {
+		return noopPublisher{}
+	}
+	return p
+}
+
+// NewEvent creates an event with a fresh random ID.
+func NewEvent(eventType, agentID string, data map[string]interface{}) model.Event {
+	var id [16]byte
+	rand.Read(id[:])
+	return model.Event{
+		ID:         hex.EncodeToString(id[:]),
+		Type:       eventType,
+		AgentID:    agentID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// agentEvent creates an event about agent that carries its labels, so the
+// event bus does not have to look them up.
+func agentEvent(eventType string, agent *model.Agent, data map[string]interface{}) model.Event {
+	event := NewEvent(eventType, agent.AgentID, data)
+	event.Labels = agent.Labels
+	if event.Labels == nil {
+		event.Labels = model.Labels{}
+	}
+	return event
+}
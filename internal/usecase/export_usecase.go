@@ -0,0 +1,114 @@
+// internal/usecase/export_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/export"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidExport is returned for an unknown dataset or format, or an empty
+// time range.
+var ErrInvalidExport = errors.New("invalid export request")
+
+// ExportRequest selects the dataset, output format and rows of an export.
+type ExportRequest struct {
+	Dataset string // one of export.Agents, export.FirewallRules, ...
+	Format  string // export.CSV, export.JSONL or export.Parquet
+	Filter  model.ExportFilter
+}
+
+// ExportUseCase dumps fleet data for reporting.
+type ExportUseCase interface {
+	// Export encodes the rows selected by req to w as they are read from the
+	// repository and returns how many rows were written. Nothing is written
+	// when req is invalid.
+	Export(req ExportRequest, w io.Writer) (int64, error)
+}
+
+type exportUseCase struct {
+	agents    repository.AgentRepository
+	inventory repository.InventoryRepository
+}
+
+// NewExportUseCase creates the export use case.
+func NewExportUseCase(agents repository.AgentRepository, inventory repository.InventoryRepository) ExportUseCase {
+	return &exportUseCase{agents: agents, inventory: inventory}
+}
+
+func (uc *exportUseCase) Export(req ExportRequest, w io.Writer) (int64, error) {
+	filter := req.Filter
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		return 0, fmt.Errorf("%w: until must be after since", ErrInvalidExport)
+	}
+
+	switch req.Dataset {
+	case export.Agents:
+		return exportDataset(w, req.Format, export.NewAgentRow, func(fn func(*model.Agent) error) error {
+			return uc.agents.StreamAgents(filter, fn)
+		})
+	case export.FirewallRules:
+		return exportDataset(w, req.Format, export.NewFirewallRuleRow, func(fn func(*model.AgentFirewallRule) error) error {
+			return uc.agents.StreamCurrentFirewallRules(filter, fn)
+		})
+	case export.InstalledApps:
+		return exportDataset(w, req.Format, export.NewInstalledAppRow, func(fn func(*model.AgentInstalledApp) error) error {
+			return uc.agents.StreamCurrentInstalledApps(filter, fn)
+		})
+	case export.StatusHistory:
+		return exportDataset(w, req.Format, export.NewStatusChangeRow, func(fn func(*model.AgentStatusChangeRow) error) error {
+			return uc.agents.StreamStatusHistory(filter, fn)
+		})
+	case export.NetworkInterfaces:
+		return exportDataset(w, req.Format, export.NewNetworkInterfaceRow, func(fn func(*model.AgentInventory[model.NetworkInterface]) error) error {
+			return uc.inventory.StreamCurrentNetworkInterfaces(filter, fn)
+		})
+	case export.LocalUsers:
+		return exportDataset(w, req.Format, export.NewLocalUserRow, func(fn func(*model.AgentInventory[model.LocalUser]) error) error {
+			return uc.inventory.StreamCurrentLocalUsers(filter, fn)
+		})
+	case export.LocalGroups:
+		return exportDataset(w, req.Format, export.NewLocalGroupRow, func(fn func(*model.AgentInventory[model.LocalGroup]) error) error {
+			return uc.inventory.StreamCurrentLocalGroups(filter, fn)
+		})
+	case export.Services:
+		return exportDataset(w, req.Format, export.NewServiceRow, func(fn func(*model.AgentInventory[model.SystemService]) error) error {
+			return uc.inventory.StreamCurrentServices(filter, fn)
+		})
+	case export.ListeningSockets:
+		return exportDataset(w, req.Format, export.NewListeningSocketRow, func(fn func(*model.AgentInventory[model.ListeningSocket]) error) error {
+			return uc.inventory.StreamCurrentListeningSockets(filter, fn)
+		})
+	case export.Processes:
+		return exportDataset(w, req.Format, export.NewProcessRow, func(fn func(*model.AgentInventory[model.RunningProcess]) error) error {
+			return uc.inventory.StreamCurrentProcesses(filter, fn)
+		})
+	default:
+		return 0, fmt.Errorf("%w: unknown dataset %q", ErrInvalidExport, req.Dataset)
+	}
+}
+
+// exportDataset encodes every row produced by stream, converted to the
+// export row R, in format.
+func exportDataset[M, R any](w io.Writer, format string, convert func(*M) *R, stream func(func(*M) error) error) (int64, error) {
+	out, err := export.NewWriter[R](w, format)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	var rows int64
+	err = stream(func(m *M) error {
+		if err := out.Write(convert(m)); err != nil {
+			return err
+		}
+		rows++
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, out.Close()
+}
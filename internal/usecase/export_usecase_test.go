@@ -0,0 +1,156 @@
+package usecase_test
+
+import (
+	"agent_server/internal/export"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func newExportFixture(t *testing.T) (usecase.AgentUseCase, usecase.ExportUseCase) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	agents := usecase.NewAgentUseCase(store.Agents, nil, nil)
+	for _, a := range []*model.Agent{
+		{AgentID: "web-01", Hostname: "web-01.example", OSName: "Ubuntu", CPUCores: 4, Labels: model.Labels{"env": "prod", "site": "riyadh"}},
+		{AgentID: "web-02", Hostname: "web-02.example", OSName: "Ubuntu", CPUCores: 2, Labels: model.Labels{"env": "staging"}},
+	} {
+		if _, err := agents.RegisterAgent(a); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+	return agents, usecase.NewExportUseCase(store.Agents, store.Inventory)
+}
+
+func TestExportWritesEachFormat(t *testing.T) {
+	agents, exports := newExportFixture(t)
+	reportApps(t, agents, "web-01", model.InstalledApplication{Name: "nginx", Version: "1.24.0", Publisher: "F5, Inc."})
+	reportApps(t, agents, "web-02", model.InstalledApplication{Name: "curl", Version: "8.5.0"})
+
+	var out bytes.Buffer
+	req := usecase.ExportRequest{Dataset: export.InstalledApps, Format: export.CSV, Filter: model.ExportFilter{Labels: map[string]string{"env": "prod"}}}
+	if n, err := exports.Export(req, &out); err != nil || n != 1 {
+		t.Fatalf("Export(csv) = %d, %v; want 1 row", n, err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(records) != 2 || strings.Join(records[0][:4], ",") != "agent_id,hostname,labels,name" ||
+		records[1][2] != "env=prod,site=riyadh" || records[1][5] != "F5, Inc." || records[1][6] != "" {
+		t.Fatalf("CSV = %q, want a header and nginx with its quoted publisher and no install date", records)
+	}
+
+	out.Reset()
+	req = usecase.ExportRequest{Dataset: export.Agents, Format: export.JSONL}
+	if n, err := exports.Export(req, &out); err != nil || n != 2 {
+		t.Fatalf("Export(jsonl) = %d, %v; want 2 rows", n, err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("JSONL has %d lines, want 2: %s", len(lines), out.String())
+	}
+	var row export.AgentRow
+	if err := json.Unmarshal([]byte(lines[1]), &row); err != nil || row.AgentID != "web-02" || row.CPUCores != 2 || row.Status != "ONLINE" {
+		t.Fatalf("second JSONL row = %+v, %v; want web-02", row, err)
+	}
+
+	out.Reset()
+	req = usecase.ExportRequest{Dataset: export.Agents, Format: export.Parquet, Filter: model.ExportFilter{AgentID: "web-01"}}
+	if n, err := exports.Export(req, &out); err != nil || n != 1 {
+		t.Fatalf("Export(parquet) = %d, %v; want 1 row", n, err)
+	}
+	rows, err := parquet.Read[export.AgentRow](bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("reading Parquet: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Hostname != "web-01.example" || rows[0].Labels != "env=prod,site=riyadh" || rows[0].RegisteredAt.IsZero() {
+		t.Fatalf("Parquet rows = %+v, want web-01", rows)
+	}
+}
+
+func TestExportStatusHistory(t *testing.T) {
+	agents, exports := newExportFixture(t)
+	start := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := agents.MarkOfflineAgents(0); err != nil {
+		t.Fatalf("MarkOfflineAgents: %v", err)
+	}
+	if _, err := agents.ProcessHeartbeat("web-01", "10.0.0.1", ""); err != nil {
+		t.Fatalf("ProcessHeartbeat: %v", err)
+	}
+
+	var out bytes.Buffer
+	req := usecase.ExportRequest{Dataset: export.StatusHistory, Format: export.JSONL, Filter: model.ExportFilter{AgentID: "web-01"}}
+	if n, err := exports.Export(req, &out); err != nil || n != 3 {
+		t.Fatalf("Export = %d, %v; want 3 changes: %s", n, err, out.String())
+	}
+	var changes []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var row export.StatusChangeRow
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatal(err)
+		}
+		changes = append(changes, row.FromStatus+">"+row.ToStatus)
+	}
+	if strings.Join(changes, " ") != ">ONLINE ONLINE>OFFLINE OFFLINE>ONLINE" {
+		t.Fatalf("changes = %v, want registration, offline and back online", changes)
+	}
+
+	out.Reset()
+	req = usecase.ExportRequest{Dataset: export.StatusHistory, Format: export.CSV, Filter: model.ExportFilter{Since: start, Status: "OFFLINE"}}
+	if n, err := exports.Export(req, &out); err != nil || n != 1 || !strings.Contains(out.String(), "web-02,web-02.example,env=staging,ONLINE,OFFLINE,") {
+		t.Fatalf("Export(OFFLINE agents since start) = %d, %v:\n%s\nwant web-02 going offline", n, err, out.String())
+	}
+}
+
+func TestExportHostInventory(t *testing.T) {
+	store := repository.NewMemoryStore()
+	agents := usecase.NewAgentUseCase(store.Agents, nil, nil)
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "web-01", Hostname: "web-01.example", Labels: model.Labels{"env": "prod"}}); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	inventory := usecase.NewInventoryUseCase(store.Inventory, store.Agents, nil)
+	users := []model.LocalUser{{Name: "alice", UID: "1000", Groups: model.StringList{"sudo", "users"}}}
+	if err := inventory.StoreLocalUsers("web-01", users, nil); err != nil {
+		t.Fatalf("StoreLocalUsers: %v", err)
+	}
+	exports := usecase.NewExportUseCase(store.Agents, store.Inventory)
+
+	var out bytes.Buffer
+	req := usecase.ExportRequest{Dataset: export.LocalUsers, Format: export.CSV}
+	if n, err := exports.Export(req, &out); err != nil || n != 1 {
+		t.Fatalf("Export(local users) = %d, %v; want 1 row", n, err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(records) != 2 || records[1][0] != "web-01" || records[1][3] != "alice" || records[1][6] != "sudo,users" || records[1][8] != "" {
+		t.Fatalf("CSV = %q, want alice with her groups joined and no last logon", records)
+	}
+}
+
+func TestExportRejectsInvalidRequests(t *testing.T) {
+	_, exports := newExportFixture(t)
+	now := time.Now()
+	for _, req := range []usecase.ExportRequest{
+		{Dataset: "users", Format: export.CSV},
+		{Dataset: export.Agents, Format: "xlsx"},
+		{Dataset: export.StatusHistory, Format: export.CSV, Filter: model.ExportFilter{Since: now, Until: now.Add(-time.Hour)}},
+	} {
+		var out bytes.Buffer
+		if _, err := exports.Export(req, &out); !errors.Is(err, usecase.ErrInvalidExport) || out.Len() != 0 {
+			t.Errorf("Export(%+v) = %v with %d bytes, want ErrInvalidExport and no output", req, err, out.Len())
+		}
+	}
+}
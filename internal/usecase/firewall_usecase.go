@@ -0,0 +1,101 @@
+// internal/usecase/firewall_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/firewall"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"strings"
+	"time"
+)
+
+// FirewallUseCase analyzes the reported firewall rules of each agent. It is
+// also an EventPublisher: the findings of an agent are dropped when it is
+// decommissioned.
+type FirewallUseCase interface {
+	EventPublisher
+	// AnalyzeAgent recomputes and stores the findings for the agent's latest
+	// firewall report. firewallEnabled is nil if the agent did not report
+	// the state of its firewall.
+	AnalyzeAgent(agentID string, firewallEnabled *bool) ([]model.FirewallFinding, error)
+	// ListFindings returns findings of the given kind (empty for all) and of
+	// at least minSeverity, most severe first.
+	ListFindings(agentID, kind, minSeverity string, limit int) ([]model.FirewallFinding, error)
+}
+
+type firewallUseCase struct {
+	repo   repository.FirewallRepository
+	agents repository.AgentRepository
+}
+
+// NewFirewallUseCase creates a new instance of the firewall analysis use case layer.
+func NewFirewallUseCase(repo repository.FirewallRepository, agents repository.AgentRepository) FirewallUseCase {
+	return &firewallUseCase{repo: repo, agents: agents}
+}
+
+func (uc *firewallUseCase) AnalyzeAgent(agentID string, firewallEnabled *bool) ([]model.FirewallFinding, error) {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent.Status == "DECOMMISSIONED" {
+		return nil, uc.repo.ReplaceAgentFindings(agentID, nil)
+	}
+	rules, err := uc.agents.FindCurrentFirewallRules(agent.ID)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := uc.repo.FindFindings(model.FirewallFindingFilter{AgentID: agentID})
+	if err != nil {
+		return nil, err
+	}
+
+	key := func(f model.FirewallFinding) string {
+		return strings.Join([]string{f.Kind, f.RuleName, f.RelatedRule, f.Port}, "\x00")
+	}
+	detectedAt := make(map[string]time.Time, len(previous))
+	for _, f := range previous {
+		detectedAt[key(f)] = f.DetectedAt
+	}
+
+	now := time.Now().UTC()
+	findings := firewall.Analyze(rules, firewallEnabled)
+	for i := range findings {
+		findings[i].AgentID = agentID
+		findings[i].DetectedAt = now
+		if t, ok := detectedAt[key(findings[i])]; ok {
+			findings[i].DetectedAt = t
+		}
+	}
+	if len(findings) == 0 && len(previous) == 0 {
+		return findings, nil
+	}
+	if err := uc.repo.ReplaceAgentFindings(agentID, findings); err != nil {
+		return nil, err
+	}
+	if len(findings) > len(previous) {
+		logging.Infof("Agent %s has %d firewall findings (was %d).", agentID, len(findings), len(previous))
+	}
+	return findings, nil
+}
+
+func (uc *firewallUseCase) ListFindings(agentID, kind, minSeverity string, limit int) ([]model.FirewallFinding, error) {
+	filter := model.FirewallFindingFilter{AgentID: agentID, Severities: severitiesFrom(minSeverity), Limit: limit}
+	if kind != "" {
+		filter.Kinds = []string{strings.ToUpper(kind)}
+	}
+	return uc.repo.FindFindings(filter)
+}
+
+// Publish clears the findings of an agent when it is decommissioned; its
+// next firewall report after coming back analyzes it again.
+func (uc *firewallUseCase) Publish(event model.Event) {
+	if event.Type != model.EventAgentStatusChanged || event.Data["to"] != "DECOMMISSIONED" {
+		return
+	}
+	if err := uc.repo.ReplaceAgentFindings(event.AgentID, nil); err != nil {
+		logging.Errorf("Failed to clear firewall findings of agent %s: %v", event.AgentID, err)
+	}
+}
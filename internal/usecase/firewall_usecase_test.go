@@ -0,0 +1,128 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"testing"
+)
+
+func newFirewallFixture(t *testing.T) (usecase.AgentUseCase, usecase.FirewallUseCase) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	firewall := usecase.NewFirewallUseCase(store.Firewall, store.Agents)
+	bus := usecase.NewEventBus(store.Agents, config.StaticProvider(config.Default()), nil, firewall)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	for _, id := range []string{"web-01", "web-02"} {
+		if _, err := agents.RegisterAgent(&model.Agent{AgentID: id}); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+	return agents, firewall
+}
+
+func inbound(name, port, protocol, action string) model.FirewallRule {
+	return model.FirewallRule{Name: name, Port: port, Protocol: protocol, Action: action, Direction: "DIRECTION_IN", Enabled: true}
+}
+
+// reportFirewall stores rules the way ReportFirewallStatus does and returns
+// the findings by rule name.
+func reportFirewall(t *testing.T, agents usecase.AgentUseCase, firewall usecase.FirewallUseCase, agentID string, enabled *bool, rules ...model.FirewallRule) map[string]model.FirewallFinding {
+	t.Helper()
+	if err := agents.StoreFirewallRules(agentID, rules); err != nil {
+		t.Fatalf("StoreFirewallRules: %v", err)
+	}
+	findings, err := firewall.AnalyzeAgent(agentID, enabled)
+	if err != nil {
+		t.Fatalf("AnalyzeAgent: %v", err)
+	}
+	byRule := map[string]model.FirewallFinding{}
+	for _, f := range findings {
+		byRule[f.RuleName] = f
+	}
+	return byRule
+}
+
+func TestFirewallAnalysisFindsShadowedConflictingAndRiskyRules(t *testing.T) {
+	agents, firewall := newFirewallFixture(t)
+	got := reportFirewall(t, agents, firewall, "web-01", nil,
+		inbound("Block legacy", "8000-8100", "TCP", "DENY"),
+		inbound("Allow admin UI", "8080", "tcp", "ALLOW"), // inside the denied range
+		inbound("Allow DNS", "53", "UDP", "ALLOW"),
+		inbound("Deny DNS", "53", "udp", "DENY"), // same tuple as "Allow DNS"
+		inbound("Allow SSH", "22,80", "TCP", "ALLOW"),
+		inbound("Deny SSH", "22", "TCP", "DENY"), // comes too late to block 22
+		inbound("Allow everything", "ANY", "ANY", "ALLOW"),
+		inbound("Bad port", "http", "TCP", "ALLOW"),
+		model.FirewallRule{Name: "Disabled", Port: "445", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN"},
+	)
+
+	if f := got["Allow admin UI"]; f.Kind != model.FirewallShadowedRule || f.RelatedRule != "Block legacy" || f.Severity != model.SeverityMedium {
+		t.Errorf("Allow admin UI = %+v, want MEDIUM SHADOWED_RULE by Block legacy", f)
+	}
+	if f := got["Deny DNS"]; f.Kind != model.FirewallConflictingRules || f.RelatedRule != "Allow DNS" {
+		t.Errorf("Deny DNS = %+v, want CONFLICTING_RULES with Allow DNS", f)
+	}
+	if f := got["Deny SSH"]; f.Kind != model.FirewallShadowedRule || f.Severity != model.SeverityHigh {
+		t.Errorf("Deny SSH = %+v, want a HIGH SHADOWED_RULE (an ineffective DENY)", f)
+	}
+	if f := got["Allow SSH"]; f.Kind != model.FirewallRiskyExposure || f.Port != "22" || f.Severity != model.SeverityHigh {
+		t.Errorf("Allow SSH = %+v, want a HIGH RISKY_EXPOSURE on 22", f)
+	}
+	if f := got["Bad port"]; f.Kind != model.FirewallInvalidRule {
+		t.Errorf("Bad port = %+v, want INVALID_RULE", f)
+	}
+	if _, ok := got["Disabled"]; ok {
+		t.Error("a disabled rule was analyzed")
+	}
+
+	// 445 and 3389 reach "Allow everything"; 22 is decided by "Allow SSH".
+	exposed := map[string]string{}
+	risky, err := firewall.ListFindings("", model.FirewallRiskyExposure, "", 0)
+	if err != nil {
+		t.Fatalf("ListFindings: %v", err)
+	}
+	for _, f := range risky {
+		exposed[f.Port] = f.RuleName
+	}
+	if len(exposed) != 3 || exposed["445"] != "Allow everything" || exposed["3389"] != "Allow everything" || risky[0].Port != "445" {
+		t.Fatalf("risky exposures = %+v, want 445 (CRITICAL first), 3389 and 22", risky)
+	}
+	if critical, _ := firewall.ListFindings("", "", model.SeverityCritical, 0); len(critical) != 1 {
+		t.Fatalf("got %d CRITICAL findings, want the SMB exposure only", len(critical))
+	}
+}
+
+func TestFirewallAnalysisTracksStateAcrossReports(t *testing.T) {
+	agents, firewall := newFirewallFixture(t)
+	enabled, disabled := true, false
+	denyRDP := inbound("Deny RDP", "3389", "TCP", "DENY")
+	allowRDP := inbound("Allow RDP", "3389", "TCP", "ALLOW")
+
+	got := reportFirewall(t, agents, firewall, "web-01", &enabled, allowRDP)
+	first := got["Allow RDP"]
+	if first.Kind != model.FirewallRiskyExposure {
+		t.Fatalf("findings = %+v, want RDP exposed", got)
+	}
+
+	// An unchanged finding keeps its first detection time.
+	got = reportFirewall(t, agents, firewall, "web-01", &disabled, allowRDP)
+	if len(got) != 2 || got[""].Kind != model.FirewallDisabled || !got["Allow RDP"].DetectedAt.Equal(first.DetectedAt) {
+		t.Fatalf("findings = %+v, want FIREWALL_DISABLED and the RDP exposure detected at %v", got, first.DetectedAt)
+	}
+
+	// A DENY before the ALLOW closes the port and shadows the ALLOW.
+	got = reportFirewall(t, agents, firewall, "web-01", &enabled, denyRDP, allowRDP)
+	if len(got) != 1 || got["Allow RDP"].Kind != model.FirewallConflictingRules {
+		t.Fatalf("findings = %+v, want only the conflict between Deny RDP and Allow RDP", got)
+	}
+
+	reportFirewall(t, agents, firewall, "web-02", nil, allowRDP)
+	if _, err := agents.DecommissionAgent("web-02"); err != nil {
+		t.Fatalf("DecommissionAgent: %v", err)
+	}
+	if left, _ := firewall.ListFindings("web-02", "", "", 0); len(left) != 0 {
+		t.Fatalf("decommissioned agent still has findings %+v", left)
+	}
+}
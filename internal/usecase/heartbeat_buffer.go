@@ -0,0 +1,178 @@
+// internal/usecase/heartbeat_buffer.go
+
+package usecase
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HeartbeatBuffer coalesces heartbeats in memory and writes them in bulk.
+// Only the latest beat per agent is kept, so an agent that beats several
+// times between flushes costs a single row update.
+type HeartbeatBuffer struct {
+	repo repository.AgentRepository
+	cfg  config.Provider
+
+	mu       sync.Mutex
+	pending  map[string]model.Heartbeat
+	lastSeen map[string]time.Time // survives flushes so the monitor never reads a stale DB value
+	known    map[string]struct{}
+
+	flushNow chan struct{}
+}
+
+// NewHeartbeatBuffer creates a buffer. Call LoadKnownAgents before serving
+// traffic and Run in a goroutine to start flushing.
+func NewHeartbeatBuffer(repo repository.AgentRepository, cfg config.Provider) *HeartbeatBuffer {
+	return &HeartbeatBuffer{
+		repo:     repo,
+		cfg:      cfg,
+		pending:  make(map[string]model.Heartbeat),
+		lastSeen: make(map[string]time.Time),
+		known:    make(map[string]struct{}),
+		flushNow: make(chan struct{}, 1),
+	}
+}
+
+// LoadKnownAgents fills the cache of registered agent IDs from the repository.
+func (b *HeartbeatBuffer) LoadKnownAgents() error {
+	ids, err := b.repo.ListAgentIDs()
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, id := range ids {
+		b.known[id] = struct{}{}
+	}
+	return nil
+}
+
+// MarkKnown adds a newly registered agent to the cache.
+func (b *HeartbeatBuffer) MarkKnown(agentID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.known[agentID] = struct{}{}
+}
+
+// Forget removes an agent from the cache so further beats are rejected.
+func (b *HeartbeatBuffer) Forget(agentID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.known, agentID)
+	delete(b.pending, agentID)
+	delete(b.lastSeen, agentID)
+}
+
+// Record buffers a heartbeat with the address the agent reported and the one
+// it connected from. It returns false if the agent is not registered.
+func (b *HeartbeatBuffer) Record(agentID, ip, peerIP string) (bool, error) {
+	if !b.isKnown(agentID) {
+		// The cache may be behind another replica's registration, so confirm
+		// with the database before rejecting the beat.
+		agent, err := b.repo.FindAgentByID(agentID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		if agent.Status == "DECOMMISSIONED" {
+			return false, nil
+		}
+		b.MarkKnown(agentID)
+	}
+
+	now := time.Now()
+	b.mu.Lock()
+	b.pending[agentID] = model.Heartbeat{AgentID: agentID, IP: ip, PeerIP: peerIP, SeenAt: now}
+	b.lastSeen[agentID] = now
+	full := len(b.pending) >= b.cfg.Current().Heartbeat.MaxBatchSize
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.flushNow <- struct{}{}:
+		default:
+		}
+	}
+	return true, nil
+}
+
+// LastSeen returns the time of the latest heartbeat received by this server,
+// including beats that have not been flushed yet.
+func (b *HeartbeatBuffer) LastSeen(agentID string) (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.lastSeen[agentID]
+	return t, ok
+}
+
+// Flush writes all pending heartbeats to the repository. If the write fails
+// the beats are put back unless a newer beat has arrived meanwhile.
+func (b *HeartbeatBuffer) Flush() error {
+	b.mu.Lock()
+	if len(b.pending) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	batch := make([]model.Heartbeat, 0, len(b.pending))
+	for _, hb := range b.pending {
+		batch = append(batch, hb)
+	}
+	b.pending = make(map[string]model.Heartbeat, len(batch))
+	b.mu.Unlock()
+
+	if err := b.repo.BulkUpdateHeartbeats(batch); err != nil {
+		b.mu.Lock()
+		for _, hb := range batch {
+			if _, newer := b.pending[hb.AgentID]; !newer {
+				b.pending[hb.AgentID] = hb
+			}
+		}
+		b.mu.Unlock()
+		return err
+	}
+	logging.Debugf("Flushed %d buffered heartbeats.", len(batch))
+	return nil
+}
+
+// Run flushes on every interval tick or when the batch is full, and once
+// more when ctx is cancelled.
+func (b *HeartbeatBuffer) Run(ctx context.Context) {
+	timer := time.NewTimer(b.cfg.Current().Heartbeat.FlushInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := b.Flush(); err != nil {
+				logging.Errorf("❌ Final heartbeat flush failed: %v", err)
+			}
+			return
+		case <-timer.C:
+		case <-b.flushNow:
+			timer.Stop()
+		}
+		if err := b.Flush(); err != nil {
+			logging.Errorf("❌ Heartbeat flush failed, will retry: %v", err)
+		}
+		timer.Reset(b.cfg.Current().Heartbeat.FlushInterval())
+	}
+}
+
+func (b *HeartbeatBuffer) isKnown(agentID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.known[agentID]
+	return ok
+}
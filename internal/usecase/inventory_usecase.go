@@ -0,0 +1,250 @@
+// internal/usecase/inventory_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// InventoryUseCase stores and serves the extended host inventory: network
+// interfaces, local users and groups, system services, listening sockets
+// and running processes. Like installed apps, every report is a full
+// snapshot; a report that differs from the previous one publishes
+// inventory.changed with its kind and the number of added and removed items.
+type InventoryUseCase interface {
+	StoreNetworkInterfaces(agentID string, items []model.NetworkInterface) error
+	StoreLocalUsers(agentID string, users []model.LocalUser, groups []model.LocalGroup) error
+	StoreServices(agentID string, items []model.SystemService) error
+	StoreListeningSockets(agentID string, items []model.ListeningSocket) error
+	StoreProcesses(agentID string, items []model.RunningProcess) error
+
+	GetNetworkInterfaces(agentID string) ([]model.NetworkInterface, error)
+	GetLocalUsers(agentID string) ([]model.LocalUser, []model.LocalGroup, error)
+	GetServices(agentID string) ([]model.SystemService, error)
+	GetListeningSockets(agentID string) ([]model.ListeningSocket, error)
+	GetProcesses(agentID string) ([]model.RunningProcess, error)
+
+	// PurgeExpiredReports deletes process snapshots older than processesAge
+	// and the other inventory older than inventoryAge, keeping each agent's
+	// latest report of every kind. A zero age keeps those reports forever.
+	PurgeExpiredReports(inventoryAge, processesAge time.Duration) error
+}
+
+type inventoryUseCase struct {
+	repo   repository.InventoryRepository
+	agents repository.AgentRepository
+	events EventPublisher
+}
+
+// NewInventoryUseCase creates the inventory use case. Fleet events go to
+// events, which may be nil.
+func NewInventoryUseCase(repo repository.InventoryRepository, agents repository.AgentRepository, events EventPublisher) InventoryUseCase {
+	return &inventoryUseCase{repo: repo, agents: agents, events: publisherOrNoop(events)}
+}
+
+func (uc *inventoryUseCase) StoreNetworkInterfaces(agentID string, items []model.NetworkInterface) error {
+	return storeInventory(uc, agentID, model.InventoryNetworkInterfaces, items,
+		uc.repo.FindCurrentNetworkInterfaces, uc.repo.CreateNetworkInterfaces, networkInterfaceKey)
+}
+
+func (uc *inventoryUseCase) StoreServices(agentID string, items []model.SystemService) error {
+	return storeInventory(uc, agentID, model.InventoryServices, items,
+		uc.repo.FindCurrentServices, uc.repo.CreateServices, serviceKey)
+}
+
+func (uc *inventoryUseCase) StoreListeningSockets(agentID string, items []model.ListeningSocket) error {
+	return storeInventory(uc, agentID, model.InventoryListeningSockets, items,
+		uc.repo.FindCurrentListeningSockets, uc.repo.CreateListeningSockets, listeningSocketKey)
+}
+
+func (uc *inventoryUseCase) StoreProcesses(agentID string, items []model.RunningProcess) error {
+	return storeInventory(uc, agentID, model.InventoryProcesses, items,
+		uc.repo.FindCurrentProcesses, uc.repo.CreateProcesses, processKey)
+}
+
+// StoreLocalUsers stores users and groups as one report, and publishes a
+// change event for each of them that changed.
+func (uc *inventoryUseCase) StoreLocalUsers(agentID string, users []model.LocalUser, groups []model.LocalGroup) error {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	previousUsers, err := uc.repo.FindCurrentLocalUsers(agent.ID)
+	if err != nil {
+		return err
+	}
+	previousGroups, err := uc.repo.FindCurrentLocalGroups(agent.ID)
+	if err != nil {
+		return err
+	}
+
+	reportedAt := time.Now()
+	stampInventory(users, agent.ID, reportedAt)
+	stampInventory(groups, agent.ID, reportedAt)
+	if err := uc.repo.CreateLocalUsers(users, groups); err != nil {
+		return err
+	}
+	publishInventoryChanges(uc, agent, model.InventoryLocalUsers, previousUsers, users, localUserKey)
+	publishInventoryChanges(uc, agent, model.InventoryLocalGroups, previousGroups, groups, localGroupKey)
+	return nil
+}
+
+func (uc *inventoryUseCase) GetNetworkInterfaces(agentID string) ([]model.NetworkInterface, error) {
+	return currentInventory(uc, agentID, uc.repo.FindCurrentNetworkInterfaces)
+}
+
+func (uc *inventoryUseCase) GetLocalUsers(agentID string) ([]model.LocalUser, []model.LocalGroup, error) {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	users, err := uc.repo.FindCurrentLocalUsers(agent.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups, err := uc.repo.FindCurrentLocalGroups(agent.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return users, groups, nil
+}
+
+func (uc *inventoryUseCase) GetServices(agentID string) ([]model.SystemService, error) {
+	return currentInventory(uc, agentID, uc.repo.FindCurrentServices)
+}
+
+func (uc *inventoryUseCase) GetListeningSockets(agentID string) ([]model.ListeningSocket, error) {
+	return currentInventory(uc, agentID, uc.repo.FindCurrentListeningSockets)
+}
+
+func (uc *inventoryUseCase) GetProcesses(agentID string) ([]model.RunningProcess, error) {
+	return currentInventory(uc, agentID, uc.repo.FindCurrentProcesses)
+}
+
+func (uc *inventoryUseCase) PurgeExpiredReports(inventoryAge, processesAge time.Duration) error {
+	now := time.Now()
+	ages := map[string]time.Duration{
+		model.InventoryNetworkInterfaces: inventoryAge,
+		model.InventoryLocalUsers:        inventoryAge,
+		model.InventoryLocalGroups:       inventoryAge,
+		model.InventoryServices:          inventoryAge,
+		model.InventoryListeningSockets:  inventoryAge,
+		model.InventoryProcesses:         processesAge,
+	}
+	for kind, age := range ages {
+		if age <= 0 {
+			continue
+		}
+		n, err := uc.repo.DeleteInventoryBefore(kind, now.Add(-age))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			logging.Infof("Retention: deleted %d expired %s.", n, strings.ReplaceAll(kind, "_", " "))
+		}
+	}
+	return nil
+}
+
+// storeInventory stores items as one report of the agent and publishes
+// inventory.changed if they differ from the agent's previous report.
+func storeInventory[T any, P model.InventoryModel[T]](uc *inventoryUseCase, agentID, kind string, items []T,
+	findCurrent func(agentPK uint) ([]T, error), create func([]T) error, key func(*T) string) error {
+	// Business logic: ensure the agent exists before adding its inventory.
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	previous, err := findCurrent(agent.ID)
+	if err != nil {
+		return err
+	}
+
+	stampInventory[T, P](items, agent.ID, time.Now())
+	if err := create(items); err != nil {
+		return err
+	}
+	publishInventoryChanges(uc, agent, kind, previous, items, key)
+	return nil
+}
+
+// stampInventory assigns the agent's primary key to each item and stamps
+// them as one report.
+func stampInventory[T any, P model.InventoryModel[T]](items []T, agentPK uint, reportedAt time.Time) {
+	for i := range items {
+		item := P(&items[i]).Item()
+		item.AgentID = agentPK
+		item.ReportedAt = reportedAt
+	}
+}
+
+func publishInventoryChanges[T any](uc *inventoryUseCase, agent *model.Agent, kind string, previous, items []T, key func(*T) string) {
+	if added, removed := diffInventory(previous, items, key); added > 0 || removed > 0 {
+		uc.events.Publish(inventoryEvent(agent, kind, added, removed, len(items)))
+	}
+}
+
+func currentInventory[T any](uc *inventoryUseCase, agentID string, findCurrent func(agentPK uint) ([]T, error)) ([]T, error) {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	return findCurrent(agent.ID)
+}
+
+// diffInventory counts the distinct keys only present in the new report
+// and those only present in the old one.
+func diffInventory[T any](old, updated []T, key func(*T) string) (added, removed int) {
+	oldSet := make(map[string]bool, len(old))
+	for i := range old {
+		oldSet[key(&old[i])] = true
+	}
+	newSet := make(map[string]bool, len(updated))
+	for i := range updated {
+		newSet[key(&updated[i])] = true
+	}
+	for k := range newSet {
+		if !oldSet[k] {
+			added++
+		}
+	}
+	for k := range oldSet {
+		if !newSet[k] {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// The keys compare the fields an audit cares about. They leave out values
+// that change on their own, such as process IDs, last logons and resource
+// usage, so that routine churn is not reported as a change.
+
+func networkInterfaceKey(i *model.NetworkInterface) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%t", i.Name, i.MAC, strings.Join(i.IPAddresses, ","), i.MTU, i.Up)
+}
+
+func localUserKey(u *model.LocalUser) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%t", u.Name, u.UID, u.FullName, strings.Join(u.Groups, ","), u.Disabled)
+}
+
+func localGroupKey(g *model.LocalGroup) string {
+	return g.Name + "\x00" + g.GID + "\x00" + strings.Join(g.Members, ",")
+}
+
+func serviceKey(s *model.SystemService) string {
+	return strings.Join([]string{s.Name, s.StartType, s.State, s.Account, s.BinaryPath}, "\x00")
+}
+
+func listeningSocketKey(s *model.ListeningSocket) string {
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%s", s.Protocol, s.Address, s.Port, s.ProcessName)
+}
+
+func processKey(p *model.RunningProcess) string {
+	return p.Name + "\x00" + p.User + "\x00" + p.CommandLine
+}
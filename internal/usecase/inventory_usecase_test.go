@@ -0,0 +1,112 @@
+package usecase_test
+
+import (
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newInventoryFixture(t *testing.T) (*repository.Store, usecase.InventoryUseCase, *usecase.Subscription) {
+	t.Helper()
+	store, bus := newEventBus(t, 100, 100)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "web-01", Hostname: "web-01"}); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	sub := subscribeBus(t, bus, usecase.EventFilter{EventTypes: []string{model.EventInventoryChanged}}, 0)
+	return store, usecase.NewInventoryUseCase(store.Inventory, store.Agents, bus), sub
+}
+
+func TestInventoryStoresCurrentReportAndPublishesChanges(t *testing.T) {
+	_, inventory, sub := newInventoryFixture(t)
+	sshd := model.RunningProcess{PID: 812, Name: "sshd", User: "root", CommandLine: "/usr/sbin/sshd -D"}
+	nginx := model.RunningProcess{PID: 990, Name: "nginx", User: "www-data", CommandLine: "nginx: worker process"}
+
+	if err := inventory.StoreProcesses("web-01", []model.RunningProcess{sshd, nginx}); err != nil {
+		t.Fatalf("StoreProcesses: %v", err)
+	}
+	// A restarted process has a new PID but is not a change.
+	sshd.PID, nginx.PID = 813, 991
+	if err := inventory.StoreProcesses("web-01", []model.RunningProcess{sshd, nginx}); err != nil {
+		t.Fatalf("StoreProcesses: %v", err)
+	}
+	if err := inventory.StoreProcesses("web-01", []model.RunningProcess{sshd}); err != nil {
+		t.Fatalf("StoreProcesses: %v", err)
+	}
+
+	events := drain(sub)
+	if len(events) != 2 || events[0].Data["inventory"] != model.InventoryProcesses || events[0].Data["added"] != 2 ||
+		events[1].Data["removed"] != 1 || events[1].Data["total"] != 1 {
+		t.Fatalf("events = %+v, want the first report and the removal of nginx", events)
+	}
+	procs, err := inventory.GetProcesses("web-01")
+	if err != nil || len(procs) != 1 || procs[0].PID != 813 {
+		t.Fatalf("GetProcesses = %+v, %v; want sshd of the last report", procs, err)
+	}
+}
+
+func TestInventoryStoresUsersAndGroupsTogether(t *testing.T) {
+	_, inventory, sub := newInventoryFixture(t)
+	users := []model.LocalUser{{Name: "alice", UID: "1000", Groups: model.StringList{"sudo", "docker"}}}
+	groups := []model.LocalGroup{{Name: "sudo", GID: "27", Members: model.StringList{"alice"}}}
+	if err := inventory.StoreLocalUsers("web-01", users, groups); err != nil {
+		t.Fatalf("StoreLocalUsers: %v", err)
+	}
+	users[0].LastLogon = time.Now()
+	groups[0].Members = model.StringList{"alice", "bob"}
+	if err := inventory.StoreLocalUsers("web-01", users, groups); err != nil {
+		t.Fatalf("StoreLocalUsers: %v", err)
+	}
+
+	events := drain(sub)
+	if len(events) != 3 || events[2].Data["inventory"] != model.InventoryLocalGroups {
+		t.Fatalf("events = %+v, want users and groups once, then only the changed group", events)
+	}
+	gotUsers, gotGroups, err := inventory.GetLocalUsers("web-01")
+	if err != nil || len(gotUsers) != 1 || len(gotUsers[0].Groups) != 2 || len(gotGroups) != 1 || len(gotGroups[0].Members) != 2 {
+		t.Fatalf("GetLocalUsers = %+v, %+v, %v", gotUsers, gotGroups, err)
+	}
+}
+
+func TestInventoryRejectsUnknownAgent(t *testing.T) {
+	_, inventory, _ := newInventoryFixture(t)
+	err := inventory.StoreServices("ghost", []model.SystemService{{Name: "sshd", State: model.ServiceRunning}})
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("StoreServices(unknown agent) = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := inventory.GetListeningSockets("ghost"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetListeningSockets(unknown agent) = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestInventoryPurgesProcessesSeparately(t *testing.T) {
+	_, inventory, _ := newInventoryFixture(t)
+	if err := inventory.StoreNetworkInterfaces("web-01", []model.NetworkInterface{{Name: "eth0", MAC: "00:16:3e:00:00:01", Up: true}}); err != nil {
+		t.Fatalf("StoreNetworkInterfaces: %v", err)
+	}
+	if err := inventory.StoreProcesses("web-01", []model.RunningProcess{{PID: 1, Name: "systemd", User: "root"}}); err != nil {
+		t.Fatalf("StoreProcesses: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := inventory.StoreProcesses("web-01", []model.RunningProcess{{PID: 1, Name: "systemd", User: "root"}, {PID: 812, Name: "sshd", User: "root"}}); err != nil {
+		t.Fatalf("StoreProcesses: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// Both process reports are past their retention, but the latest one is
+	// the agent's current state and stays.
+	if err := inventory.PurgeExpiredReports(0, time.Millisecond); err != nil {
+		t.Fatalf("PurgeExpiredReports: %v", err)
+	}
+	if procs, err := inventory.GetProcesses("web-01"); err != nil || len(procs) != 2 {
+		t.Fatalf("GetProcesses after purge = %+v, %v; want the latest report", procs, err)
+	}
+	if nics, err := inventory.GetNetworkInterfaces("web-01"); err != nil || len(nics) != 1 {
+		t.Fatalf("GetNetworkInterfaces after purge = %+v, %v; want eth0 kept forever", nics, err)
+	}
+}
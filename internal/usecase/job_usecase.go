@@ -0,0 +1,676 @@
+// internal/usecase/job_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/schedule"
+	"agent_server/internal/scripts"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidJob is returned for a scheduled job that cannot be created
+	// or changed as requested.
+	ErrInvalidJob = errors.New("invalid scheduled job")
+	// ErrJobExists is returned when creating a job with the name of another.
+	ErrJobExists = errors.New("a scheduled job with this name already exists")
+	// ErrJobRunInProgress is returned when running a job by hand while its
+	// previous run is still in progress.
+	ErrJobRunInProgress = errors.New("the job's previous run is still in progress")
+)
+
+// JobUseCase runs scheduled fleet jobs: a command queued on the agents a
+// job targets, on a cron schedule or once at a given time. Tick, called by
+// the scheduler worker, starts the runs that are due and moves running ones
+// along, queuing commands as earlier ones finish so that no more than the
+// job's MaxConcurrency are outstanding at a time.
+//
+// A run that was due while the server was down for longer than
+// scheduler.misfire_grace_seconds follows the job's catch-up policy: SKIP
+// records it as skipped, RUN_ONCE runs the job once however many runs were
+// missed. A run that is due while the previous one is still in progress is
+// skipped too, so runs of a job never overlap.
+//
+// The script of a RUN_SCRIPT job is checked against the allowlist before
+// every command is queued; once it is no longer on it, its commands wait for
+// an operator's approval like a script queued by hand.
+type JobUseCase interface {
+	// CreateJob validates a job, fills in its defaults and stores it.
+	CreateJob(job *model.ScheduledJob) (*model.ScheduledJob, error)
+	// PauseJob stops a job from starting runs and its running run from
+	// queuing more commands.
+	PauseJob(id uint) (*model.ScheduledJob, error)
+	// ResumeJob continues a paused job from its next run after now; runs
+	// missed while it was paused are not caught up.
+	ResumeJob(id uint) (*model.ScheduledJob, error)
+	// DeleteJob deletes a job with its run history. Commands already queued
+	// are left alone.
+	DeleteJob(id uint) error
+	// ListJobs returns the jobs with status, or all if it is empty, by name.
+	ListJobs(status string) ([]model.ScheduledJob, error)
+	// RunJobNow starts a run of a job outside its schedule.
+	RunJobNow(id uint) (*model.JobRun, error)
+	ListRuns(filter model.JobRunFilter) ([]model.JobRun, error)
+	// GetRun returns a run and the agents it targets.
+	GetRun(id uint) (*model.JobRun, []model.JobRunTarget, error)
+
+	// Tick starts the runs due at now and advances the running ones.
+	Tick(now time.Time) error
+	// PurgeExpiredRuns deletes runs that finished more than maxAge ago.
+	PurgeExpiredRuns(maxAge time.Duration) error
+}
+
+type jobUseCase struct {
+	repo        repository.JobRepository
+	agents      repository.AgentRepository
+	commandRepo repository.CommandRepository
+	commands    CommandUseCase
+	events      EventPublisher
+	cfg         config.Provider
+
+	// mu serializes Tick with the changes operators make, so a run is not
+	// started twice and a paused job does not queue another command.
+	mu sync.Mutex
+}
+
+// NewJobUseCase creates the scheduled job use case. Commands are queued with
+// commands and their results read from commandRepo; events, which may be
+// nil, receive finished runs.
+func NewJobUseCase(repo repository.JobRepository, agents repository.AgentRepository, commandRepo repository.CommandRepository, commands CommandUseCase, events EventPublisher, cfg config.Provider) JobUseCase {
+	return &jobUseCase{
+		repo:        repo,
+		agents:      agents,
+		commandRepo: commandRepo,
+		commands:    commands,
+		events:      publisherOrNoop(events),
+		cfg:         cfg,
+	}
+}
+
+func (uc *jobUseCase) CreateJob(job *model.ScheduledJob) (*model.ScheduledJob, error) {
+	job.Name = strings.TrimSpace(job.Name)
+	if job.Timezone == "" {
+		job.Timezone = "UTC"
+	}
+	if job.CatchUp == "" {
+		job.CatchUp = model.CatchUpSkip
+	}
+	if err := validateJob(job); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if job.Cron != "" {
+		next, err := nextCronRun(job, now)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, fmt.Errorf("%w: cron expression %q never matches", ErrInvalidJob, job.Cron)
+		}
+		job.NextRunAt = next
+	} else {
+		if !job.RunAt.After(now) {
+			return nil, fmt.Errorf("%w: run_at must be in the future", ErrInvalidJob)
+		}
+		runAt := *job.RunAt
+		job.NextRunAt = &runAt
+	}
+	job.Status = model.JobActive
+	job.LastRunAt = nil
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if _, err := uc.repo.FindJobByName(job.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err := uc.repo.CreateJob(job); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+		}
+		return nil, err
+	}
+	logging.Infof("Created scheduled job %d %q (%s), next run at %s.", job.ID, job.Name, job.CommandType, job.NextRunAt.Format(time.RFC3339))
+	return job, nil
+}
+
+func validateJob(job *model.ScheduledJob) error {
+	switch {
+	case job.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidJob)
+	case job.CommandType == "":
+		return fmt.Errorf("%w: a command is required", ErrInvalidJob)
+	case job.CommandType == model.CommandTypeRunScript && job.ScriptDigest == "":
+		return fmt.Errorf("%w: the script digest is required", ErrInvalidJob)
+	case (job.Cron == "") == (job.RunAt == nil):
+		return fmt.Errorf("%w: exactly one of cron and run_at is required", ErrInvalidJob)
+	case job.CatchUp != model.CatchUpSkip && job.CatchUp != model.CatchUpRunOnce:
+		return fmt.Errorf("%w: unknown catch-up policy %q", ErrInvalidJob, job.CatchUp)
+	case job.MaxConcurrency < 0:
+		return fmt.Errorf("%w: max concurrency must not be negative", ErrInvalidJob)
+	case !job.TargetAll && len(job.TargetAgentIDs) == 0 && len(job.TargetLabels) == 0:
+		return fmt.Errorf("%w: a target is required: all agents, agent IDs or labels", ErrInvalidJob)
+	}
+	if _, err := time.LoadLocation(job.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidJob, job.Timezone)
+	}
+	if job.Cron != "" {
+		if _, err := schedule.Parse(job.Cron); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidJob, err)
+		}
+	}
+	return nil
+}
+
+// nextCronRun returns the first time after t that the job's cron expression
+// matches in its time zone, or nil if there is none.
+func nextCronRun(job *model.ScheduledJob, t time.Time) (*time.Time, error) {
+	cron, err := schedule.Parse(job.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	loc, err := time.LoadLocation(job.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidJob, job.Timezone)
+	}
+	next := cron.Next(t.In(loc))
+	if next.IsZero() {
+		return nil, nil
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+func (uc *jobUseCase) PauseJob(id uint) (*model.ScheduledJob, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	job, err := uc.repo.FindJob(id)
+	if err != nil {
+		return nil, err
+	}
+	switch job.Status {
+	case model.JobPaused:
+		return job, nil
+	case model.JobFinished:
+		return nil, fmt.Errorf("%w: job %q has finished", ErrInvalidJob, job.Name)
+	}
+	job.Status = model.JobPaused
+	if err := uc.repo.SaveJob(job); err != nil {
+		return nil, err
+	}
+	logging.Infof("Paused scheduled job %d %q.", job.ID, job.Name)
+	return job, nil
+}
+
+func (uc *jobUseCase) ResumeJob(id uint) (*model.ScheduledJob, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	job, err := uc.repo.FindJob(id)
+	if err != nil {
+		return nil, err
+	}
+	switch job.Status {
+	case model.JobActive:
+		return job, nil
+	case model.JobFinished:
+		return nil, fmt.Errorf("%w: job %q has finished", ErrInvalidJob, job.Name)
+	}
+	now := time.Now()
+	job.Status = model.JobActive
+	if job.Cron != "" {
+		if job.NextRunAt, err = nextCronRun(job, now); err != nil {
+			return nil, err
+		}
+		if job.NextRunAt == nil {
+			job.Status = model.JobFinished
+		}
+	} else if job.NextRunAt != nil && job.NextRunAt.Before(now) {
+		// A one-off job whose time passed while it was paused runs now.
+		job.NextRunAt = &now
+	}
+	if err := uc.repo.SaveJob(job); err != nil {
+		return nil, err
+	}
+	logging.Infof("Resumed scheduled job %d %q.", job.ID, job.Name)
+	return job, nil
+}
+
+func (uc *jobUseCase) DeleteJob(id uint) error {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if err := uc.repo.DeleteJob(id); err != nil {
+		return err
+	}
+	logging.Infof("Deleted scheduled job %d.", id)
+	return nil
+}
+
+func (uc *jobUseCase) ListJobs(status string) ([]model.ScheduledJob, error) {
+	return uc.repo.FindJobs(status)
+}
+
+func (uc *jobUseCase) RunJobNow(id uint) (*model.JobRun, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	job, err := uc.repo.FindJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == model.JobPaused {
+		return nil, fmt.Errorf("%w: job %q is paused", ErrInvalidJob, job.Name)
+	}
+	if running, err := uc.runningRun(job.ID); err != nil {
+		return nil, err
+	} else if running != nil {
+		return nil, fmt.Errorf("%w: run %d", ErrJobRunInProgress, running.ID)
+	}
+	now := time.Now()
+	job.LastRunAt = &now
+	if err := uc.repo.SaveJob(job); err != nil {
+		return nil, err
+	}
+	return uc.startRun(job, model.TriggerManual, now, now)
+}
+
+func (uc *jobUseCase) ListRuns(filter model.JobRunFilter) ([]model.JobRun, error) {
+	return uc.repo.FindRuns(filter)
+}
+
+func (uc *jobUseCase) GetRun(id uint) (*model.JobRun, []model.JobRunTarget, error) {
+	run, err := uc.repo.FindRun(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	targets, err := uc.repo.FindRunTargets(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return run, targets, nil
+}
+
+func (uc *jobUseCase) Tick(now time.Time) error {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	// Running runs go first, so a run that just finished frees its job for
+	// the next one.
+	running, err := uc.repo.FindRunningRuns()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range running {
+		run := &running[i]
+		job, err := uc.repo.FindJob(run.JobID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("run %d: %w", run.ID, err))
+			continue
+		}
+		if err := uc.advance(job, run, now); err != nil {
+			errs = append(errs, fmt.Errorf("run %d of job %q: %w", run.ID, job.Name, err))
+		}
+	}
+
+	due, err := uc.repo.FindDueJobs(now)
+	if err != nil {
+		return err
+	}
+	for i := range due {
+		if err := uc.trigger(&due[i], now); err != nil {
+			errs = append(errs, fmt.Errorf("job %q: %w", due[i].Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// trigger starts the run of a due job, or records why it was skipped, and
+// moves the job on to its next run.
+func (uc *jobUseCase) trigger(job *model.ScheduledJob, now time.Time) error {
+	scheduledFor := *job.NextRunAt
+	grace := uc.cfg.Current().Scheduler.MisfireGrace()
+
+	if job.Cron != "" {
+		next, err := nextCronRun(job, now)
+		if err != nil {
+			return err
+		}
+		job.NextRunAt = next
+	} else {
+		job.NextRunAt = nil
+	}
+	if job.NextRunAt == nil {
+		job.Status = model.JobFinished
+	}
+
+	trigger := model.TriggerSchedule
+	if now.Sub(scheduledFor) > grace {
+		if job.CatchUp == model.CatchUpSkip {
+			if err := uc.repo.SaveJob(job); err != nil {
+				return err
+			}
+			logging.Warnf("Scheduled job %q missed its run at %s; skipping it.", job.Name, scheduledFor.Format(time.RFC3339))
+			return uc.skipRun(job, model.TriggerSchedule, scheduledFor, now,
+				fmt.Sprintf("Missed the run due at %s while the server was down; the catch-up policy is SKIP", scheduledFor.UTC().Format(time.RFC3339)))
+		}
+		trigger = model.TriggerCatchUp
+	}
+
+	running, err := uc.runningRun(job.ID)
+	if err != nil {
+		return err
+	}
+	if running != nil {
+		if err := uc.repo.SaveJob(job); err != nil {
+			return err
+		}
+		return uc.skipRun(job, trigger, scheduledFor, now, fmt.Sprintf("Run %d was still in progress", running.ID))
+	}
+
+	job.LastRunAt = &now
+	if err := uc.repo.SaveJob(job); err != nil {
+		return err
+	}
+	_, err = uc.startRun(job, trigger, scheduledFor, now)
+	return err
+}
+
+// runningRun returns the job's run in progress, if any.
+func (uc *jobUseCase) runningRun(jobID uint) (*model.JobRun, error) {
+	runs, err := uc.repo.FindRuns(model.JobRunFilter{JobID: jobID, Status: model.JobRunRunning, Limit: 1})
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+// skipRun records a run that did not reach any agent.
+func (uc *jobUseCase) skipRun(job *model.ScheduledJob, trigger string, scheduledFor, now time.Time, message string) error {
+	run := &model.JobRun{
+		JobID:        job.ID,
+		Trigger:      trigger,
+		Status:       model.JobRunSkipped,
+		ScheduledFor: scheduledFor,
+		StartedAt:    now,
+		FinishedAt:   &now,
+		Message:      message,
+	}
+	if err := uc.repo.CreateRun(run, nil); err != nil {
+		return err
+	}
+	uc.publishFinished(job, run)
+	return nil
+}
+
+// startRun records a run with the agents the job targets and queues the
+// first commands.
+func (uc *jobUseCase) startRun(job *model.ScheduledJob, trigger string, scheduledFor, now time.Time) (*model.JobRun, error) {
+	agentIDs, err := uc.resolveTargets(job)
+	if err != nil {
+		return nil, err
+	}
+	if len(agentIDs) == 0 {
+		if err := uc.skipRun(job, trigger, scheduledFor, now, "No agents match the job's target"); err != nil {
+			return nil, err
+		}
+		return uc.lastRun(job.ID)
+	}
+
+	run := &model.JobRun{
+		JobID:        job.ID,
+		Trigger:      trigger,
+		Status:       model.JobRunRunning,
+		ScheduledFor: scheduledFor,
+		StartedAt:    now,
+		Targets:      len(agentIDs),
+	}
+	targets := make([]model.JobRunTarget, len(agentIDs))
+	for i, id := range agentIDs {
+		targets[i] = model.JobRunTarget{AgentID: id, Status: model.JobTargetWaiting}
+	}
+	if err := uc.repo.CreateRun(run, targets); err != nil {
+		return nil, err
+	}
+	logging.Infof("Started run %d of scheduled job %q on %d agents (%s).", run.ID, job.Name, run.Targets, strings.ToLower(trigger))
+	if err := uc.advance(job, run, now); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (uc *jobUseCase) lastRun(jobID uint) (*model.JobRun, error) {
+	runs, err := uc.repo.FindRuns(model.JobRunFilter{JobID: jobID, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &runs[0], nil
+}
+
+// resolveTargets returns the IDs of the agents a job targets: every agent,
+// or the agents it names together with those carrying all of its labels.
+// Decommissioned agents are left out unless they are named, in which case
+// queuing their command fails and says why.
+func (uc *jobUseCase) resolveTargets(job *model.ScheduledJob) ([]string, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if !job.TargetAll {
+		for _, id := range job.TargetAgentIDs {
+			add(id)
+		}
+		if len(job.TargetLabels) == 0 {
+			return ids, nil
+		}
+	}
+	filter := model.ExportFilter{}
+	if !job.TargetAll {
+		filter.Labels = job.TargetLabels
+	}
+	err := uc.agents.StreamAgents(filter, func(a *model.Agent) error {
+		if a.Status != "DECOMMISSIONED" {
+			add(a.AgentID)
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// advance records the results of the run's commands, queues commands for
+// waiting agents while the job's concurrency allows, and finishes the run
+// once every agent has a result.
+func (uc *jobUseCase) advance(job *model.ScheduledJob, run *model.JobRun, now time.Time) error {
+	targets, err := uc.repo.FindRunTargets(run.ID)
+	if err != nil {
+		return err
+	}
+	timeout := uc.cfg.Current().Scheduler.CommandTimeout()
+
+	inFlight := 0
+	for i := range targets {
+		t := &targets[i]
+		if t.Status != model.JobTargetDispatched {
+			continue
+		}
+		done, err := uc.checkTarget(t, now, timeout)
+		if err != nil {
+			return err
+		}
+		if done {
+			if err := uc.repo.SaveRunTarget(t); err != nil {
+				return err
+			}
+		} else {
+			inFlight++
+		}
+	}
+
+	if job.Status != model.JobPaused {
+		approvalReason := uc.approvalReason(job)
+		for i := range targets {
+			t := &targets[i]
+			if t.Status != model.JobTargetWaiting {
+				continue
+			}
+			if job.MaxConcurrency > 0 && inFlight >= job.MaxConcurrency {
+				break
+			}
+			var cmd *model.Command
+			if approvalReason != "" {
+				cmd, err = uc.commands.EnqueueCommandForApproval(t.AgentID, job.CommandType, job.Payload, approvalReason)
+			} else {
+				cmd, err = uc.commands.EnqueueCommand(t.AgentID, job.CommandType, job.Payload)
+			}
+			if err != nil {
+				t.Status, t.FinishedAt = model.JobTargetFailed, &now
+				t.Message = fmt.Sprintf("Could not queue command: %v", err)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Message = "Agent not found"
+				}
+			} else {
+				t.Status, t.CommandID, t.DispatchedAt = model.JobTargetDispatched, cmd.ID, &now
+				if approvalReason != "" {
+					t.Message = "Awaiting approval: " + approvalReason
+				}
+				inFlight++
+			}
+			if err := uc.repo.SaveRunTarget(t); err != nil {
+				return err
+			}
+		}
+	}
+
+	succeeded, failed, open := 0, 0, 0
+	for _, t := range targets {
+		switch t.Status {
+		case model.JobTargetSucceeded:
+			succeeded++
+		case model.JobTargetFailed:
+			failed++
+		default:
+			open++
+		}
+	}
+	changed := succeeded != run.Succeeded || failed != run.Failed
+	run.Succeeded, run.Failed = succeeded, failed
+	if open == 0 {
+		run.Status, run.FinishedAt = model.JobRunSucceeded, &now
+		if failed > 0 {
+			run.Status = model.JobRunFailed
+			run.Message = fmt.Sprintf("The command failed on %d of %d agents", failed, len(targets))
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if err := uc.repo.SaveRun(run); err != nil {
+		return err
+	}
+	if run.FinishedAt != nil {
+		logging.Infof("Run %d of scheduled job %q finished: %d succeeded, %d failed.", run.ID, job.Name, succeeded, failed)
+		uc.publishFinished(job, run)
+	}
+	return nil
+}
+
+// approvalReason returns why the commands of a job need approval: its
+// script was taken off the allowlist after the job was created. It is empty
+// for an allowed script and for other commands.
+func (uc *jobUseCase) approvalReason(job *model.ScheduledJob) string {
+	if job.CommandType != model.CommandTypeRunScript {
+		return ""
+	}
+	script := &scripts.Script{Name: job.ScriptName, Digest: job.ScriptDigest}
+	if scripts.Allowed(uc.cfg.Current().Scripts.Allowlist, script) {
+		return ""
+	}
+	if script.Name != "" {
+		return fmt.Sprintf("library script %s is no longer on the allowlist", script.Name)
+	}
+	return fmt.Sprintf("script %s is no longer on the allowlist", script.Digest)
+}
+
+// checkTarget records the result of a dispatched target's command, and
+// gives up on one that has not finished within timeout. It reports whether
+// the target now has a result.
+func (uc *jobUseCase) checkTarget(t *model.JobRunTarget, now time.Time, timeout time.Duration) (bool, error) {
+	cmd, err := uc.commandRepo.FindCommandByID(t.CommandID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Status, t.Message, t.FinishedAt = model.JobTargetFailed, "Command no longer exists", &now
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	switch cmd.Status {
+	case model.CommandSucceeded:
+		t.Status, t.Message, t.FinishedAt = model.JobTargetSucceeded, cmd.ResultMessage, &now
+		return true, nil
+	case model.CommandFailed, model.CommandCancelled:
+		t.Status, t.Message, t.FinishedAt = model.JobTargetFailed, cmd.ResultMessage, &now
+		if t.Message == "" {
+			t.Message = "Command " + strings.ToLower(cmd.Status)
+		}
+		return true, nil
+	}
+	if timeout <= 0 || t.DispatchedAt == nil || now.Sub(*t.DispatchedAt) < timeout {
+		return false, nil
+	}
+	// An agent that never picked the command up should not run it late.
+	if _, err := uc.commands.CancelCommand(cmd.ID); err != nil && !errors.Is(err, ErrInvalidCommandState) {
+		return false, err
+	}
+	t.Status, t.FinishedAt = model.JobTargetFailed, &now
+	t.Message = fmt.Sprintf("No result within %s", timeout)
+	return true, nil
+}
+
+func (uc *jobUseCase) publishFinished(job *model.ScheduledJob, run *model.JobRun) {
+	uc.events.Publish(NewEvent(model.EventJobRunFinished, "", map[string]interface{}{
+		"job_id":    job.ID,
+		"job":       job.Name,
+		"run_id":    run.ID,
+		"trigger":   run.Trigger,
+		"status":    run.Status,
+		"targets":   run.Targets,
+		"succeeded": run.Succeeded,
+		"failed":    run.Failed,
+		"message":   run.Message,
+	}))
+}
+
+func (uc *jobUseCase) PurgeExpiredRuns(maxAge time.Duration) error {
+	if maxAge <= 0 {
+		return nil
+	}
+	deleted, err := uc.repo.DeleteRunsFinishedBefore(time.Now().Add(-maxAge))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		logging.Infof("Retention: deleted %d expired scheduled job runs.", deleted)
+	}
+	return nil
+}
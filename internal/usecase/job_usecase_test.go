@@ -0,0 +1,347 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type jobFixture struct {
+	commands usecase.CommandUseCase
+	jobs     usecase.JobUseCase
+	sub      *usecase.Subscription
+}
+
+// newJobFixture registers web-01 to web-03 with env=prod and db-01 with
+// env=staging.
+func newJobFixture(t *testing.T, cfg *config.Config) *jobFixture {
+	t.Helper()
+	store, bus := newEventBus(t, 100, 100)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	for _, a := range []*model.Agent{
+		{AgentID: "web-01", Hostname: "web-01", Labels: map[string]string{"env": "prod"}},
+		{AgentID: "web-02", Hostname: "web-02", Labels: map[string]string{"env": "prod"}},
+		{AgentID: "web-03", Hostname: "web-03", Labels: map[string]string{"env": "prod"}},
+		{AgentID: "db-01", Hostname: "db-01", Labels: map[string]string{"env": "staging"}},
+	} {
+		if _, err := agents.RegisterAgent(a); err != nil {
+			t.Fatalf("RegisterAgent(%s): %v", a.AgentID, err)
+		}
+	}
+	commands := usecase.NewCommandUseCase(store.Agents, store.Commands, bus)
+	return &jobFixture{
+		commands: commands,
+		jobs:     usecase.NewJobUseCase(store.Jobs, store.Agents, store.Commands, commands, bus, config.StaticProvider(cfg)),
+		sub:      subscribeBus(t, bus, usecase.EventFilter{EventTypes: []string{model.EventJobRunFinished}}, 0),
+	}
+}
+
+func (f *jobFixture) create(t *testing.T, job *model.ScheduledJob) *model.ScheduledJob {
+	t.Helper()
+	if job.CommandType == "" {
+		job.CommandType = model.CommandTypeReportInventory
+	}
+	created, err := f.jobs.CreateJob(job)
+	if err != nil {
+		t.Fatalf("CreateJob(%s): %v", job.Name, err)
+	}
+	return created
+}
+
+func (f *jobFixture) tick(t *testing.T, now time.Time) {
+	t.Helper()
+	if err := f.jobs.Tick(now); err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+}
+
+// finish has the agent pick up its commands and report a result for each.
+func (f *jobFixture) finish(t *testing.T, agentID string, success bool) {
+	t.Helper()
+	cmds, err := f.commands.PollCommands(agentID)
+	if err != nil {
+		t.Fatalf("PollCommands(%s): %v", agentID, err)
+	}
+	for _, cmd := range cmds {
+		if err := f.commands.CompleteCommand(agentID, cmd.ID, success, "done", nil); err != nil {
+			t.Fatalf("CompleteCommand(%s, %d): %v", agentID, cmd.ID, err)
+		}
+	}
+}
+
+func (f *jobFixture) runs(t *testing.T, jobID uint) []model.JobRun {
+	t.Helper()
+	runs, err := f.jobs.ListRuns(model.JobRunFilter{JobID: jobID})
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	return runs
+}
+
+func targetStatuses(t *testing.T, jobs usecase.JobUseCase, runID uint) map[string]string {
+	t.Helper()
+	_, targets, err := jobs.GetRun(runID)
+	if err != nil {
+		t.Fatalf("GetRun(%d): %v", runID, err)
+	}
+	out := make(map[string]string, len(targets))
+	for _, target := range targets {
+		out[target.AgentID] = target.Status
+	}
+	return out
+}
+
+func TestCreateScheduledJobValidatesAndComputesNextRun(t *testing.T) {
+	f := newJobFixture(t, config.Default())
+	job := f.create(t, &model.ScheduledJob{Name: "nightly-inventory", Cron: "30 2 * * *", Timezone: "Europe/Berlin", TargetAll: true})
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	next := job.NextRunAt.In(berlin)
+	if job.Status != model.JobActive || job.CatchUp != model.CatchUpSkip || next.Hour() != 2 || next.Minute() != 30 || !next.After(time.Now()) {
+		t.Fatalf("job = %+v, next run %s; want ACTIVE, SKIP and the next 02:30 in Berlin", job, next)
+	}
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+	for name, bad := range map[string]*model.ScheduledJob{
+		"no schedule":     {Name: "a", CommandType: model.CommandTypeReportInventory, TargetAll: true},
+		"cron and run_at": {Name: "a", CommandType: model.CommandTypeReportInventory, Cron: "@daily", RunAt: &future, TargetAll: true},
+		"bad cron":        {Name: "a", CommandType: model.CommandTypeReportInventory, Cron: "61 * * * *", TargetAll: true},
+		"never matches":   {Name: "a", CommandType: model.CommandTypeReportInventory, Cron: "0 0 30 2 *", TargetAll: true},
+		"time zone":       {Name: "a", CommandType: model.CommandTypeReportInventory, Cron: "@daily", Timezone: "Mars/Olympus", TargetAll: true},
+		"no target":       {Name: "a", CommandType: model.CommandTypeReportInventory, Cron: "@daily"},
+		"past run_at":     {Name: "a", CommandType: model.CommandTypeReportInventory, RunAt: &past, TargetAll: true},
+		"catch-up":        {Name: "a", CommandType: model.CommandTypeReportInventory, Cron: "@daily", CatchUp: "ALWAYS", TargetAll: true},
+	} {
+		if _, err := f.jobs.CreateJob(bad); !errors.Is(err, usecase.ErrInvalidJob) {
+			t.Errorf("CreateJob(%s) error = %v, want ErrInvalidJob", name, err)
+		}
+	}
+	if _, err := f.jobs.CreateJob(&model.ScheduledJob{Name: "nightly-inventory", CommandType: model.CommandTypeReportInventory, Cron: "@hourly", TargetAll: true}); !errors.Is(err, usecase.ErrJobExists) {
+		t.Fatalf("CreateJob with a duplicate name error = %v, want ErrJobExists", err)
+	}
+}
+
+func TestScheduledJobRunRespectsMaxConcurrency(t *testing.T) {
+	f := newJobFixture(t, config.Default())
+	job := f.create(t, &model.ScheduledJob{
+		Name:           "block-telnet",
+		CommandType:    model.CommandTypeFirewallConfiguration,
+		Payload:        []byte("rule"),
+		Cron:           "*/5 * * * *",
+		MaxConcurrency: 2,
+		TargetLabels:   model.Labels{"env": "prod"},
+	})
+	now := job.NextRunAt.Add(time.Second)
+	f.tick(t, now)
+
+	runs := f.runs(t, job.ID)
+	if len(runs) != 1 || runs[0].Status != model.JobRunRunning || runs[0].Trigger != model.TriggerSchedule || runs[0].Targets != 3 {
+		t.Fatalf("runs = %+v, want one scheduled run on the 3 prod agents", runs)
+	}
+	run := runs[0]
+	got := targetStatuses(t, f.jobs, run.ID)
+	if got["web-01"] != model.JobTargetDispatched || got["web-02"] != model.JobTargetDispatched || got["web-03"] != model.JobTargetWaiting {
+		t.Fatalf("targets = %v, want two dispatched and web-03 waiting", got)
+	}
+	if _, ok := got["db-01"]; ok {
+		t.Fatalf("targets = %v, want only agents labelled env=prod", got)
+	}
+
+	// A finished command frees a slot for the waiting agent.
+	f.finish(t, "web-01", true)
+	f.tick(t, now.Add(15*time.Second))
+	if got := targetStatuses(t, f.jobs, run.ID); got["web-01"] != model.JobTargetSucceeded || got["web-03"] != model.JobTargetDispatched {
+		t.Fatalf("targets after web-01 finished = %v, want web-01 succeeded and web-03 dispatched", got)
+	}
+	if _, err := f.jobs.RunJobNow(job.ID); !errors.Is(err, usecase.ErrJobRunInProgress) {
+		t.Fatalf("RunJobNow during a run error = %v, want ErrJobRunInProgress", err)
+	}
+
+	f.finish(t, "web-02", true)
+	f.finish(t, "web-03", false)
+	f.tick(t, now.Add(30*time.Second))
+	finished, _, err := f.jobs.GetRun(run.ID)
+	if err != nil || finished.Status != model.JobRunFailed || finished.Succeeded != 2 || finished.Failed != 1 || finished.FinishedAt == nil {
+		t.Fatalf("run = %+v, %v; want FAILED with 2 succeeded and 1 failed", finished, err)
+	}
+	events := drain(f.sub)
+	if len(events) != 1 || events[0].Data["status"] != model.JobRunFailed || events[0].Data["job"] != "block-telnet" {
+		t.Fatalf("events = %+v, want one job.run_finished for the failed run", events)
+	}
+}
+
+func TestScheduledJobCatchUpAfterDowntime(t *testing.T) {
+	f := newJobFixture(t, config.Default())
+	skip := f.create(t, &model.ScheduledJob{Name: "skip", Cron: "*/5 * * * *", TargetAgentIDs: model.StringList{"web-01"}})
+	runOnce := f.create(t, &model.ScheduledJob{Name: "run-once", Cron: "*/5 * * * *", CatchUp: model.CatchUpRunOnce, TargetAgentIDs: model.StringList{"db-01"}})
+
+	// The server comes back an hour after both were due; twelve runs were
+	// missed.
+	now := skip.NextRunAt.Add(time.Hour)
+	f.tick(t, now)
+
+	skipped := f.runs(t, skip.ID)
+	if len(skipped) != 1 || skipped[0].Status != model.JobRunSkipped || !strings.Contains(skipped[0].Message, "SKIP") {
+		t.Fatalf("runs of the SKIP job = %+v, want one skipped run", skipped)
+	}
+	caughtUp := f.runs(t, runOnce.ID)
+	if len(caughtUp) != 1 || caughtUp[0].Trigger != model.TriggerCatchUp || caughtUp[0].Status != model.JobRunRunning || !caughtUp[0].ScheduledFor.Equal(*runOnce.NextRunAt) {
+		t.Fatalf("runs of the RUN_ONCE job = %+v, want one catch-up run for the first missed time", caughtUp)
+	}
+
+	jobs, err := f.jobs.ListJobs(model.JobActive)
+	if err != nil || len(jobs) != 2 {
+		t.Fatalf("ListJobs = %+v, %v", jobs, err)
+	}
+	for _, job := range jobs {
+		if !job.NextRunAt.After(now) {
+			t.Fatalf("job %q next run = %s, want after %s", job.Name, job.NextRunAt, now)
+		}
+	}
+	// Nothing else is due until the next scheduled time.
+	f.tick(t, now.Add(time.Second))
+	if runs, _ := f.jobs.ListRuns(model.JobRunFilter{}); len(runs) != 2 {
+		t.Fatalf("runs after another tick = %+v, want still 2", runs)
+	}
+}
+
+func TestScheduledJobPauseOverlapAndTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.Scheduler.CommandTimeoutSeconds = 600
+	f := newJobFixture(t, cfg)
+	job := f.create(t, &model.ScheduledJob{Name: "inventory", Cron: "*/5 * * * *", MaxConcurrency: 1, TargetLabels: model.Labels{"env": "prod"}})
+	now := job.NextRunAt.Add(time.Second)
+	f.tick(t, now)
+	run := f.runs(t, job.ID)[0]
+
+	// A paused job queues nothing more for its run.
+	if _, err := f.jobs.PauseJob(job.ID); err != nil {
+		t.Fatalf("PauseJob: %v", err)
+	}
+	f.finish(t, "web-01", true)
+	f.tick(t, now.Add(time.Minute))
+	if got := targetStatuses(t, f.jobs, run.ID); got["web-01"] != model.JobTargetSucceeded || got["web-02"] != model.JobTargetWaiting {
+		t.Fatalf("targets while paused = %v, want web-02 still waiting", got)
+	}
+	if _, err := f.jobs.RunJobNow(job.ID); !errors.Is(err, usecase.ErrInvalidJob) {
+		t.Fatalf("RunJobNow of a paused job error = %v, want ErrInvalidJob", err)
+	}
+
+	resumed, err := f.jobs.ResumeJob(job.ID)
+	if err != nil || resumed.Status != model.JobActive {
+		t.Fatalf("ResumeJob = %+v, %v", resumed, err)
+	}
+
+	// web-02 is dispatched but never answers: the next scheduled time is
+	// skipped while the run is in progress, and the command is given up on
+	// after the timeout.
+	f.tick(t, resumed.NextRunAt.Add(time.Second))
+	runs := f.runs(t, job.ID)
+	if len(runs) != 2 || runs[0].Status != model.JobRunSkipped || !strings.Contains(runs[0].Message, "still in progress") {
+		t.Fatalf("runs = %+v, want the overlapping run skipped", runs)
+	}
+	f.tick(t, resumed.NextRunAt.Add(11*time.Minute))
+	_, targets, err := f.jobs.GetRun(run.ID)
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	for _, target := range targets {
+		if target.AgentID == "web-02" && (target.Status != model.JobTargetFailed || !strings.Contains(target.Message, "No result")) {
+			t.Fatalf("web-02 = %+v, want FAILED after the command timeout", target)
+		}
+	}
+	cmds, err := f.commands.ListCommands(model.CommandFilter{AgentID: "web-02"})
+	if err != nil || len(cmds) != 1 || cmds[0].Status != model.CommandCancelled {
+		t.Fatalf("web-02 commands = %+v, %v; want the timed-out command cancelled", cmds, err)
+	}
+}
+
+func TestOneOffScheduledJobFinishes(t *testing.T) {
+	f := newJobFixture(t, config.Default())
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+	job := f.create(t, &model.ScheduledJob{Name: "once", RunAt: &at, TargetAll: true})
+
+	f.tick(t, at.Add(-time.Second))
+	if runs := f.runs(t, job.ID); len(runs) != 0 {
+		t.Fatalf("runs before run_at = %+v, want none", runs)
+	}
+	f.tick(t, at.Add(time.Second))
+	runs := f.runs(t, job.ID)
+	if len(runs) != 1 || runs[0].Targets != 4 {
+		t.Fatalf("runs = %+v, want one run on all 4 agents", runs)
+	}
+	jobs, _ := f.jobs.ListJobs(model.JobFinished)
+	if len(jobs) != 1 || jobs[0].NextRunAt != nil {
+		t.Fatalf("finished jobs = %+v, want the one-off job without a next run", jobs)
+	}
+	if _, err := f.jobs.PauseJob(job.ID); !errors.Is(err, usecase.ErrInvalidJob) {
+		t.Fatalf("PauseJob of a finished job error = %v, want ErrInvalidJob", err)
+	}
+}
+
+func TestScheduledScriptTakenOffTheAllowlistNeedsApproval(t *testing.T) {
+	cfg := config.Default()
+	cfg.Scripts.Allowlist = []string{"rotate-logs.sh"}
+	f := newJobFixture(t, cfg)
+	if _, err := f.jobs.CreateJob(&model.ScheduledJob{Name: "no-digest", CommandType: model.CommandTypeRunScript, Cron: "@daily", TargetAll: true}); !errors.Is(err, usecase.ErrInvalidJob) {
+		t.Fatalf("CreateJob of a script without a digest error = %v, want ErrInvalidJob", err)
+	}
+	job := f.create(t, &model.ScheduledJob{
+		Name:           "rotate-logs",
+		CommandType:    model.CommandTypeRunScript,
+		Payload:        []byte("script"),
+		ScriptName:     "rotate-logs.sh",
+		ScriptDigest:   "sha256:" + strings.Repeat("ab", 32),
+		Cron:           "@hourly",
+		MaxConcurrency: 1,
+		TargetLabels:   map[string]string{"env": "prod"},
+	})
+	now := *job.NextRunAt
+	f.tick(t, now)
+	run := f.runs(t, job.ID)[0]
+	pending, err := f.commands.PollCommands("web-01")
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("web-01 polled %v, %v; want the allowed script", pending, err)
+	}
+
+	// The script is taken off the allowlist while the run is in progress.
+	cfg.Scripts.Allowlist = nil
+	if err := f.commands.CompleteCommand("web-01", pending[0].ID, true, "done", nil); err != nil {
+		t.Fatal(err)
+	}
+	f.tick(t, now.Add(time.Minute))
+	if cmds, err := f.commands.PollCommands("web-02"); err != nil || len(cmds) != 0 {
+		t.Fatalf("web-02 polled %v, %v; want nothing before approval", cmds, err)
+	}
+	_, targets, err := f.jobs.GetRun(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var waiting model.JobRunTarget
+	for _, target := range targets {
+		if target.AgentID == "web-02" {
+			waiting = target
+		}
+	}
+	if waiting.Status != model.JobTargetDispatched || waiting.Message != "Awaiting approval: library script rotate-logs.sh is no longer on the allowlist" {
+		t.Fatalf("web-02 target = %+v, want a command awaiting approval", waiting)
+	}
+	cmds, err := f.commands.ListCommands(model.CommandFilter{AgentID: "web-02"})
+	if err != nil || len(cmds) != 1 || cmds[0].Status != model.CommandAwaitingApproval {
+		t.Fatalf("web-02 commands = %+v, %v; want one awaiting approval", cmds, err)
+	}
+
+	// Once approved, it runs like any other command.
+	if _, err := f.commands.ApproveCommand(waiting.CommandID); err != nil {
+		t.Fatalf("ApproveCommand: %v", err)
+	}
+	f.finish(t, "web-02", true)
+	f.tick(t, now.Add(2*time.Minute))
+	if got := targetStatuses(t, f.jobs, run.ID); got["web-02"] != model.JobTargetSucceeded || got["web-03"] != model.JobTargetDispatched {
+		t.Fatalf("targets = %v, want web-02 done and web-03 queued", got)
+	}
+}
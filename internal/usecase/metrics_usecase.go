@@ -0,0 +1,380 @@
+// internal/usecase/metrics_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MetricResolutionAuto asks GetMetrics to pick the resolution from the
+// length of the window and how long each resolution is kept.
+const MetricResolutionAuto = -1
+
+// DefaultMetricsWindow is the window of a metrics query that has no start.
+const DefaultMetricsWindow = 24 * time.Hour
+
+// maxMetricPoints is the most points per series an automatic resolution
+// aims for.
+const maxMetricPoints = 720
+
+// metricsRollupDelay leaves buffered samples time to be written before the
+// bucket they fall in is rolled up.
+const metricsRollupDelay = time.Minute
+
+// metricsRollupChunk bounds the buckets of one resolution aggregated in
+// memory at a time.
+const metricsRollupChunk = 12
+
+// metricResolutions go from finest to coarsest; each is rolled up from the
+// one before it.
+var metricResolutions = []int{model.MetricResolutionRaw, model.MetricResolutionFiveMinute, model.MetricResolutionHour}
+
+// MetricsQuery selects the series of one agent.
+type MetricsQuery struct {
+	AgentID    string
+	Metrics    []string  // nil = every metric
+	Resolution int       // one of the model.MetricResolution values, or MetricResolutionAuto
+	Since      time.Time // zero = DefaultMetricsWindow before Until
+	Until      time.Time // zero = now
+}
+
+// MetricSeries is one metric of an agent, oldest point first.
+type MetricSeries struct {
+	Metric string
+	Mount  string
+	Points []model.AgentMetric
+}
+
+// MetricsResult is the answer to a MetricsQuery.
+type MetricsResult struct {
+	AgentID    string
+	Resolution int // the resolution used, never MetricResolutionAuto
+	Since      time.Time
+	Until      time.Time
+	Series     []MetricSeries
+}
+
+// MetricsUseCase stores the resource metrics agents send with heartbeats and
+// keeps them as raw samples, 5-minute and hourly buckets.
+type MetricsUseCase interface {
+	// Record buffers the samples of a heartbeat from a registered agent.
+	Record(agentID string, samples []model.MetricSample)
+	// Flush writes the buffered samples.
+	Flush() error
+	// Run flushes every heartbeat flush interval, or when the batch is full,
+	// and once more when ctx is cancelled.
+	Run(ctx context.Context)
+	// Rollup aggregates the buckets completed by now and deletes the points
+	// past their retention.
+	Rollup(now time.Time) error
+	GetMetrics(query MetricsQuery) (*MetricsResult, error)
+}
+
+type metricsUseCase struct {
+	metrics repository.MetricsRepository
+	agents  repository.AgentRepository
+	cfg     config.Provider
+
+	mu       sync.Mutex
+	pending  []model.AgentMetric
+	flushNow chan struct{}
+}
+
+// NewMetricsUseCase creates the metrics use case. Call Run in a goroutine to
+// start writing recorded samples.
+func NewMetricsUseCase(metrics repository.MetricsRepository, agents repository.AgentRepository, cfg config.Provider) MetricsUseCase {
+	return &metricsUseCase{metrics: metrics, agents: agents, cfg: cfg, flushNow: make(chan struct{}, 1)}
+}
+
+func (uc *metricsUseCase) Record(agentID string, samples []model.MetricSample) {
+	if len(samples) == 0 {
+		return
+	}
+	// Second precision: a retried heartbeat is then stored once.
+	now := time.Now().UTC().Truncate(time.Second)
+	uc.mu.Lock()
+	for _, s := range samples {
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+		uc.pending = append(uc.pending, model.AgentMetric{
+			AgentID: agentID, Resolution: model.MetricResolutionRaw, BucketStart: now,
+			Metric: s.Metric, Mount: s.Mount, Count: 1, Sum: s.Value, Min: s.Value, Max: s.Value,
+		})
+	}
+	full := len(uc.pending) >= uc.cfg.Current().Heartbeat.MaxBatchSize
+	uc.mu.Unlock()
+
+	if full {
+		select {
+		case uc.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush drops the samples of a failed write: metrics are best effort and must
+// not pile up in memory while the database is down.
+func (uc *metricsUseCase) Flush() error {
+	uc.mu.Lock()
+	batch := uc.pending
+	uc.pending = nil
+	uc.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := uc.metrics.InsertSamples(batch); err != nil {
+		return err
+	}
+	logging.Debugf("Flushed %d metric samples.", len(batch))
+	return nil
+}
+
+func (uc *metricsUseCase) Run(ctx context.Context) {
+	timer := time.NewTimer(uc.cfg.Current().Heartbeat.FlushInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := uc.Flush(); err != nil {
+				logging.Errorf("❌ Final metrics flush failed: %v", err)
+			}
+			return
+		case <-timer.C:
+		case <-uc.flushNow:
+			timer.Stop()
+		}
+		if err := uc.Flush(); err != nil {
+			logging.Errorf("❌ Metrics flush failed, samples dropped: %v", err)
+		}
+		timer.Reset(uc.cfg.Current().Heartbeat.FlushInterval())
+	}
+}
+
+func (uc *metricsUseCase) Rollup(now time.Time) error {
+	now = now.UTC()
+	for i := 1; i < len(metricResolutions); i++ {
+		if err := uc.rollup(metricResolutions[i-1], metricResolutions[i], now); err != nil {
+			return err
+		}
+	}
+
+	cfg := uc.cfg.Current()
+	for _, res := range metricResolutions {
+		n, err := uc.metrics.DeleteMetricsBefore(res, now.Add(-metricsRetention(cfg, res)))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			logging.Debugf("Deleted %d expired metric points at resolution %ds.", n, res)
+		}
+	}
+	return nil
+}
+
+// rollup aggregates the points at resolution from into the buckets at
+// resolution to that ended before now, starting after the last stored one.
+// Re-running it over the same buckets replaces them with the same values.
+func (uc *metricsUseCase) rollup(from, to int, now time.Time) error {
+	first, _, err := uc.metrics.BucketRange(from)
+	if err != nil || first.IsZero() {
+		return err
+	}
+	_, last, err := uc.metrics.BucketRange(to)
+	if err != nil {
+		return err
+	}
+
+	step := resolutionStep(to)
+	start := first.Truncate(step)
+	if !last.IsZero() && last.Add(step).After(start) {
+		start = last.Add(step)
+	}
+	end := now.Add(-metricsRollupDelay).Truncate(step)
+	for start.Before(end) {
+		stop := start.Add(metricsRollupChunk * step)
+		if stop.After(end) {
+			stop = end
+		}
+		buckets := make(metricBuckets)
+		err := uc.metrics.StreamMetrics(from, start, stop, func(p *model.AgentMetric) error {
+			buckets.add(p, to)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := uc.metrics.UpsertBuckets(buckets.points()); err != nil {
+			return err
+		}
+		start = stop
+	}
+	return nil
+}
+
+func (uc *metricsUseCase) GetMetrics(query MetricsQuery) (*MetricsResult, error) {
+	now := time.Now().UTC()
+	until := query.Until.UTC()
+	if query.Until.IsZero() {
+		until = now
+	}
+	since := query.Since.UTC()
+	if query.Since.IsZero() {
+		since = until.Add(-DefaultMetricsWindow)
+	}
+	if !until.After(since) {
+		return nil, ErrInvalidWindow
+	}
+	if _, err := uc.agents.FindAgentByID(query.AgentID); err != nil {
+		return nil, err
+	}
+
+	res := query.Resolution
+	if res == MetricResolutionAuto {
+		res = uc.autoResolution(since, until, now)
+	}
+	points, err := uc.points(query.AgentID, query.Metrics, res, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MetricsResult{AgentID: query.AgentID, Resolution: res, Since: since, Until: until}
+	for _, p := range points {
+		n := len(result.Series)
+		if n == 0 || result.Series[n-1].Metric != p.Metric || result.Series[n-1].Mount != p.Mount {
+			result.Series = append(result.Series, MetricSeries{Metric: p.Metric, Mount: p.Mount})
+			n++
+		}
+		result.Series[n-1].Points = append(result.Series[n-1].Points, p)
+	}
+	return result, nil
+}
+
+// autoResolution is the finest resolution that still holds the start of the
+// window and gives at most maxMetricPoints per series.
+func (uc *metricsUseCase) autoResolution(since, until, now time.Time) int {
+	cfg := uc.cfg.Current()
+	for _, res := range metricResolutions {
+		step := resolutionStep(res)
+		if res == model.MetricResolutionRaw {
+			step = cfg.Server.ReportInterval()
+		}
+		if step > 0 && until.Sub(since)/step <= maxMetricPoints && !since.Before(now.Add(-metricsRetention(cfg, res))) {
+			return res
+		}
+	}
+	return metricResolutions[len(metricResolutions)-1]
+}
+
+// points returns the points of an agent at res, ordered by metric, mount and
+// time. The buckets after the last rollup are computed from finer points, so
+// that recent data shows up before it is rolled up.
+func (uc *metricsUseCase) points(agentID string, metrics []string, res int, since, until time.Time) ([]model.AgentMetric, error) {
+	points, err := uc.metrics.FindMetrics(model.MetricFilter{AgentID: agentID, Metrics: metrics, Resolution: res, Since: since, Until: until})
+	if err != nil || res == model.MetricResolutionRaw {
+		return points, err
+	}
+
+	_, last, err := uc.metrics.BucketRange(res)
+	if err != nil {
+		return nil, err
+	}
+	gap := since
+	if !last.IsZero() && last.Add(resolutionStep(res)).After(gap) {
+		gap = last.Add(resolutionStep(res))
+	}
+	if !gap.Before(until) {
+		return points, nil
+	}
+	finer, err := uc.points(agentID, metrics, finerResolution(res), gap, until)
+	if err != nil {
+		return nil, err
+	}
+	buckets := make(metricBuckets)
+	for i := range finer {
+		buckets.add(&finer[i], res)
+	}
+	points = append(points, buckets.points()...)
+	sort.SliceStable(points, func(i, j int) bool {
+		a, b := points[i], points[j]
+		if a.Metric != b.Metric {
+			return a.Metric < b.Metric
+		}
+		if a.Mount != b.Mount {
+			return a.Mount < b.Mount
+		}
+		return a.BucketStart.Before(b.BucketStart)
+	})
+	return points, nil
+}
+
+// metricBucketKey identifies a bucket of one agent's metric.
+type metricBucketKey struct {
+	agentID string
+	metric  string
+	mount   string
+	start   int64
+}
+
+// metricBuckets merges points into buckets of a coarser resolution.
+type metricBuckets map[metricBucketKey]*model.AgentMetric
+
+func (b metricBuckets) add(p *model.AgentMetric, resolution int) {
+	start := p.BucketStart.UTC().Truncate(resolutionStep(resolution))
+	key := metricBucketKey{p.AgentID, p.Metric, p.Mount, start.Unix()}
+	bucket, ok := b[key]
+	if !ok {
+		b[key] = &model.AgentMetric{
+			AgentID: p.AgentID, Resolution: resolution, BucketStart: start, Metric: p.Metric, Mount: p.Mount,
+			Count: p.Count, Sum: p.Sum, Min: p.Min, Max: p.Max,
+		}
+		return
+	}
+	bucket.Count += p.Count
+	bucket.Sum += p.Sum
+	bucket.Min = math.Min(bucket.Min, p.Min)
+	bucket.Max = math.Max(bucket.Max, p.Max)
+}
+
+func (b metricBuckets) points() []model.AgentMetric {
+	out := make([]model.AgentMetric, 0, len(b))
+	for _, p := range b {
+		out = append(out, *p)
+	}
+	return out
+}
+
+func resolutionStep(res int) time.Duration {
+	return time.Duration(res) * time.Second
+}
+
+// finerResolution is the resolution res is rolled up from.
+func finerResolution(res int) int {
+	for i := 1; i < len(metricResolutions); i++ {
+		if metricResolutions[i] == res {
+			return metricResolutions[i-1]
+		}
+	}
+	return model.MetricResolutionRaw
+}
+
+func metricsRetention(cfg *config.Config, res int) time.Duration {
+	switch res {
+	case model.MetricResolutionRaw:
+		return cfg.Metrics.RawRetention()
+	case model.MetricResolutionFiveMinute:
+		return cfg.Metrics.FiveMinuteRetention()
+	default:
+		return cfg.Metrics.HourlyRetention()
+	}
+}
@@ -0,0 +1,101 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestMetricsRollupAndQuery(t *testing.T) {
+	store := repository.NewMemoryStore()
+	if err := store.Agents.CreateAgent(&model.Agent{AgentID: "web-01", Status: "ONLINE"}); err != nil {
+		t.Fatal(err)
+	}
+	metrics := usecase.NewMetricsUseCase(store.Metrics, store.Agents, config.StaticProvider(config.Default()))
+
+	base := time.Now().UTC().Truncate(time.Hour).Add(-3 * time.Hour)
+	var samples []model.AgentMetric
+	for _, s := range []struct {
+		at time.Duration
+		v  float64
+	}{{time.Minute, 10}, {2 * time.Minute, 20}, {7 * time.Minute, 60}, {65 * time.Minute, 40}} {
+		samples = append(samples, model.AgentMetric{AgentID: "web-01", Resolution: model.MetricResolutionRaw, BucketStart: base.Add(s.at),
+			Metric: model.MetricCPUPercent, Count: 1, Sum: s.v, Min: s.v, Max: s.v})
+	}
+	if err := store.Metrics.InsertSamples(samples); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two hours in, only the first hour is complete.
+	for i := 0; i < 2; i++ {
+		if err := metrics.Rollup(base.Add(2*time.Hour + 30*time.Second)); err != nil {
+			t.Fatalf("Rollup: %v", err)
+		}
+	}
+	series := func(points []model.AgentMetric) string {
+		var out []string
+		for _, p := range points {
+			out = append(out, fmt.Sprintf("%v:%v/%v/%v/%d", p.BucketStart.Sub(base), p.Avg(), p.Min, p.Max, p.Count))
+		}
+		return fmt.Sprint(out)
+	}
+	fiveMinute, _ := store.Metrics.FindMetrics(model.MetricFilter{AgentID: "web-01", Resolution: model.MetricResolutionFiveMinute})
+	if got := series(fiveMinute); got != "[0s:15/10/20/2 5m0s:60/60/60/1 1h5m0s:40/40/40/1]" {
+		t.Fatalf("5-minute buckets = %s", got)
+	}
+	hourly, _ := store.Metrics.FindMetrics(model.MetricFilter{AgentID: "web-01", Resolution: model.MetricResolutionHour})
+	if got := series(hourly); got != "[0s:30/10/60/3]" {
+		t.Fatalf("hourly buckets = %s, want only the completed first hour", got)
+	}
+
+	// A sample that arrives now is not rolled up yet, but shows in every resolution.
+	metrics.Record("web-01", []model.MetricSample{{Metric: model.MetricCPUPercent, Value: 80}, {Metric: model.MetricDiskUsedGB, Mount: "/", Value: 12}})
+	if err := metrics.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	result, err := metrics.GetMetrics(usecase.MetricsQuery{AgentID: "web-01", Metrics: []string{model.MetricCPUPercent},
+		Resolution: model.MetricResolutionHour, Since: base, Until: base.Add(4 * time.Hour)})
+	if err != nil {
+		t.Fatalf("GetMetrics(hourly): %v", err)
+	}
+	if len(result.Series) != 1 || result.Series[0].Metric != model.MetricCPUPercent {
+		t.Fatalf("series = %+v, want cpu_percent only", result.Series)
+	}
+	if got := series(result.Series[0].Points); got != "[0s:30/10/60/3 1h0m0s:40/40/40/1 3h0m0s:80/80/80/1]" {
+		t.Fatalf("hourly series = %s, want the stored hour and the later ones computed from finer points", got)
+	}
+
+	all, err := metrics.GetMetrics(usecase.MetricsQuery{AgentID: "web-01", Resolution: usecase.MetricResolutionAuto, Since: base})
+	if err != nil || all.Resolution != model.MetricResolutionRaw || len(all.Series) != 2 || all.Series[1].Mount != "/" {
+		t.Fatalf("GetMetrics(auto, 3h) = %+v, %v; want raw cpu and disk series", all, err)
+	}
+	month, err := metrics.GetMetrics(usecase.MetricsQuery{AgentID: "web-01", Resolution: usecase.MetricResolutionAuto, Since: base.Add(-30 * 24 * time.Hour)})
+	if err != nil || month.Resolution != model.MetricResolutionHour {
+		t.Fatalf("GetMetrics(auto, 30 days) = %+v, %v; want hourly", month, err)
+	}
+
+	// Three days on, raw samples have expired but their buckets remain.
+	if err := metrics.Rollup(time.Now().Add(72 * time.Hour)); err != nil {
+		t.Fatalf("Rollup(later): %v", err)
+	}
+	if raw, _ := store.Metrics.FindMetrics(model.MetricFilter{AgentID: "web-01", Resolution: model.MetricResolutionRaw}); len(raw) != 0 {
+		t.Fatalf("raw samples after retention = %+v, want none", raw)
+	}
+	if hourly, _ := store.Metrics.FindMetrics(model.MetricFilter{AgentID: "web-01", Resolution: model.MetricResolutionHour}); len(hourly) != 4 {
+		t.Fatalf("hourly buckets after retention = %s, want the 3 cpu hours and the disk hour", series(hourly))
+	}
+
+	if _, err := metrics.GetMetrics(usecase.MetricsQuery{AgentID: "web-01", Since: base, Until: base.Add(-time.Hour)}); !errors.Is(err, usecase.ErrInvalidWindow) {
+		t.Fatalf("GetMetrics(reversed window) err = %v, want ErrInvalidWindow", err)
+	}
+	if _, err := metrics.GetMetrics(usecase.MetricsQuery{AgentID: "nope"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetMetrics(unknown agent) err = %v, want ErrRecordNotFound", err)
+	}
+}
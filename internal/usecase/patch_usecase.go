@@ -0,0 +1,179 @@
+// internal/usecase/patch_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PatchReport is one OS patch status report of an agent.
+type PatchReport struct {
+	Installed    []model.InstalledPatch
+	Pending      []model.PendingUpdate
+	LastUpdateAt time.Time
+	// RebootPending tells whether the host waits for a reboot to finish
+	// installing updates. RebootPendingSince is when that started; it may be
+	// zero, in which case the server tracks it across reports.
+	RebootPending      bool
+	RebootPendingSince time.Time
+}
+
+// PatchUseCase stores the OS patch status agents report and answers fleet
+// questions such as "hosts missing KB X" and "hosts with a reboot pending
+// for more than a week".
+type PatchUseCase interface {
+	StorePatchStatus(agentID string, report PatchReport) error
+	// GetPatchStatus returns the agent's latest status with its installed
+	// and pending patches, or gorm.ErrRecordNotFound if the agent does not
+	// exist. An agent that never reported returns a nil status.
+	GetPatchStatus(agentID string) (*model.AgentPatchStatusRow, []model.InstalledPatch, []model.PendingUpdate, error)
+	ListPatchStatus(filter model.PatchStatusFilter) ([]model.AgentPatchStatusRow, error)
+	// PurgeExpiredReports deletes patch reports older than age, keeping each
+	// agent's latest one. A zero age keeps them forever.
+	PurgeExpiredReports(age time.Duration) error
+}
+
+type patchUseCase struct {
+	repo   repository.PatchRepository
+	agents repository.AgentRepository
+	events EventPublisher
+}
+
+// NewPatchUseCase creates the patch status use case. Fleet events go to
+// events, which may be nil.
+func NewPatchUseCase(repo repository.PatchRepository, agents repository.AgentRepository, events EventPublisher) PatchUseCase {
+	return &patchUseCase{repo: repo, agents: agents, events: publisherOrNoop(events)}
+}
+
+func (uc *patchUseCase) StorePatchStatus(agentID string, report PatchReport) error {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	previous, err := uc.repo.FindPatchStatus(agent.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	var previousInstalled []model.InstalledPatch
+	if previous != nil {
+		if previousInstalled, _, err = uc.repo.FindPatches(agent.ID, previous.ReportedAt); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	status := &model.AgentPatchStatus{
+		AgentID:       agent.ID,
+		LastUpdateAt:  report.LastUpdateAt,
+		RebootPending: report.RebootPending,
+		ReportedAt:    now,
+	}
+	if report.RebootPending {
+		since := rebootPendingSince(report.RebootPendingSince, previous, now)
+		status.RebootPendingSince = &since
+	}
+	for i := range report.Installed {
+		report.Installed[i].PatchID = NormalizePatchID(report.Installed[i].PatchID)
+	}
+	for i := range report.Pending {
+		report.Pending[i].PatchID = NormalizePatchID(report.Pending[i].PatchID)
+		if report.Pending[i].Security {
+			status.SecurityPendingCount++
+		}
+	}
+	status.InstalledCount, status.PendingCount = len(report.Installed), len(report.Pending)
+
+	stampInventory(report.Installed, agent.ID, now)
+	stampInventory(report.Pending, agent.ID, now)
+	if err := uc.repo.SavePatchReport(status, report.Installed, report.Pending); err != nil {
+		return err
+	}
+	if added, removed := diffInventory(previousInstalled, report.Installed, installedPatchKey); added > 0 || removed > 0 {
+		uc.events.Publish(inventoryEvent(agent, model.InventoryPatches, added, removed, len(report.Installed)))
+	}
+	return nil
+}
+
+// rebootPendingSince picks when the pending reboot started: the agent's own
+// value if it sent one, otherwise the start recorded by an earlier report
+// that was already pending, otherwise now.
+func rebootPendingSince(reported time.Time, previous *model.AgentPatchStatus, now time.Time) time.Time {
+	switch {
+	case !reported.IsZero():
+		return reported
+	case previous != nil && previous.RebootPending && previous.RebootPendingSince != nil:
+		return *previous.RebootPendingSince
+	default:
+		return now
+	}
+}
+
+func (uc *patchUseCase) GetPatchStatus(agentID string) (*model.AgentPatchStatusRow, []model.InstalledPatch, []model.PendingUpdate, error) {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	status, err := uc.repo.FindPatchStatus(agent.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	installed, pending, err := uc.repo.FindPatches(agent.ID, status.ReportedAt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	row := &model.AgentPatchStatusRow{
+		AgentKey: agent.AgentID,
+		Hostname: agent.Hostname,
+		OSName:   agent.OSName,
+		Labels:   agent.Labels,
+		Status:   *status,
+	}
+	return row, installed, pending, nil
+}
+
+func (uc *patchUseCase) ListPatchStatus(filter model.PatchStatusFilter) ([]model.AgentPatchStatusRow, error) {
+	filter.MissingPatch = NormalizePatchID(filter.MissingPatch)
+	return uc.repo.FindPatchStatuses(filter)
+}
+
+func (uc *patchUseCase) PurgeExpiredReports(age time.Duration) error {
+	if age <= 0 {
+		return nil
+	}
+	n, err := uc.repo.DeletePatchesBefore(time.Now().Add(-age))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		logging.Infof("Retention: deleted %d expired patches.", n)
+	}
+	return nil
+}
+
+var kbPattern = regexp.MustCompile(`(?i)^kb\d+$`)
+
+// NormalizePatchID trims a patch ID and upper-cases Windows KB numbers, so
+// that "kb5034441" and "KB5034441" name the same update. Package names are
+// kept as they are.
+func NormalizePatchID(id string) string {
+	id = strings.TrimSpace(id)
+	if kbPattern.MatchString(id) {
+		return strings.ToUpper(id)
+	}
+	return id
+}
+
+func installedPatchKey(p *model.InstalledPatch) string {
+	return p.PatchID + "\x00" + p.Version
+}
@@ -0,0 +1,96 @@
+package usecase_test
+
+import (
+	"agent_server/internal/model"
+	"agent_server/internal/usecase"
+	"testing"
+	"time"
+)
+
+func newPatchFixture(t *testing.T) (usecase.PatchUseCase, *usecase.Subscription) {
+	t.Helper()
+	store, bus := newEventBus(t, 100, 100)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	for _, a := range []*model.Agent{
+		{AgentID: "win-01", Hostname: "win-01", OSName: "Windows"},
+		{AgentID: "win-02", Hostname: "win-02", OSName: "Windows"},
+	} {
+		if _, err := agents.RegisterAgent(a); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+	sub := subscribeBus(t, bus, usecase.EventFilter{EventTypes: []string{model.EventInventoryChanged}}, 0)
+	return usecase.NewPatchUseCase(store.Patches, store.Agents, bus), sub
+}
+
+func TestPatchStatusFindsHostsMissingPatch(t *testing.T) {
+	patches, sub := newPatchFixture(t)
+	err := patches.StorePatchStatus("win-01", usecase.PatchReport{
+		Installed: []model.InstalledPatch{{PatchID: "kb5034441"}, {PatchID: "KB5031356"}},
+		Pending:   []model.PendingUpdate{{PatchID: "KB5035845", Security: true}, {PatchID: "KB890830"}},
+	})
+	if err != nil {
+		t.Fatalf("StorePatchStatus: %v", err)
+	}
+	if err := patches.StorePatchStatus("win-02", usecase.PatchReport{Installed: []model.InstalledPatch{{PatchID: "KB5031356"}}}); err != nil {
+		t.Fatalf("StorePatchStatus: %v", err)
+	}
+
+	rows, err := patches.ListPatchStatus(model.PatchStatusFilter{MissingPatch: "kb5034441"})
+	if err != nil || len(rows) != 1 || rows[0].AgentKey != "win-02" {
+		t.Fatalf("ListPatchStatus(missing KB5034441) = %+v, %v; want win-02", rows, err)
+	}
+	status, installed, pending, err := patches.GetPatchStatus("win-01")
+	if err != nil || status == nil || status.Status.InstalledCount != 2 || status.Status.PendingCount != 2 || status.Status.SecurityPendingCount != 1 {
+		t.Fatalf("GetPatchStatus = %+v, %v; want 2 installed, 2 pending, 1 security", status, err)
+	}
+	if len(installed) != 2 || installed[0].PatchID != "KB5034441" || len(pending) != 2 {
+		t.Fatalf("patches = %+v, %+v; want the normalized report", installed, pending)
+	}
+	if events := drain(sub); len(events) != 2 || events[0].Data["inventory"] != model.InventoryPatches {
+		t.Fatalf("events = %+v, want one patches change per agent", events)
+	}
+}
+
+func TestPatchStatusKeepsRebootPendingSince(t *testing.T) {
+	patches, _ := newPatchFixture(t)
+	if err := patches.StorePatchStatus("win-01", usecase.PatchReport{RebootPending: true}); err != nil {
+		t.Fatalf("StorePatchStatus: %v", err)
+	}
+	first, _, _, err := patches.GetPatchStatus("win-01")
+	if err != nil || first == nil || first.Status.RebootPendingSince == nil {
+		t.Fatalf("GetPatchStatus = %+v, %v; want a pending reboot", first, err)
+	}
+	if err := patches.StorePatchStatus("win-01", usecase.PatchReport{RebootPending: true}); err != nil {
+		t.Fatalf("StorePatchStatus: %v", err)
+	}
+	second, _, _, _ := patches.GetPatchStatus("win-01")
+	if second.Status.RebootPendingSince == nil || !second.Status.RebootPendingSince.Equal(*first.Status.RebootPendingSince) {
+		t.Fatalf("RebootPendingSince = %v, want it kept at %v", second.Status.RebootPendingSince, first.Status.RebootPendingSince)
+	}
+
+	week := 7 * 24 * time.Hour
+	err = patches.StorePatchStatus("win-02", usecase.PatchReport{RebootPending: true, RebootPendingSince: time.Now().Add(-8 * 24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("StorePatchStatus: %v", err)
+	}
+	rows, err := patches.ListPatchStatus(model.PatchStatusFilter{RebootPendingBefore: time.Now().Add(-week)})
+	if err != nil || len(rows) != 1 || rows[0].AgentKey != "win-02" {
+		t.Fatalf("ListPatchStatus(reboot pending > 7d) = %+v, %v; want win-02", rows, err)
+	}
+
+	if err := patches.StorePatchStatus("win-02", usecase.PatchReport{}); err != nil {
+		t.Fatalf("StorePatchStatus: %v", err)
+	}
+	if rows, _ := patches.ListPatchStatus(model.PatchStatusFilter{RebootPendingBefore: time.Now()}); len(rows) != 1 || rows[0].AgentKey != "win-01" {
+		t.Fatalf("ListPatchStatus after reboot = %+v, want only win-01", rows)
+	}
+}
+
+func TestPatchStatusOfAgentWithoutReport(t *testing.T) {
+	patches, _ := newPatchFixture(t)
+	status, installed, pending, err := patches.GetPatchStatus("win-01")
+	if err != nil || status != nil || installed != nil || pending != nil {
+		t.Fatalf("GetPatchStatus = %+v, %v, %v, %v; want nothing", status, installed, pending, err)
+	}
+}
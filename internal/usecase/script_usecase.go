@@ -0,0 +1,143 @@
+// internal/usecase/script_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/scripts"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrScriptNotFound is returned for a library script that does not exist.
+	ErrScriptNotFound = errors.New("script not found in the library")
+	// ErrInvalidScript is returned for a script request that cannot run.
+	ErrInvalidScript = errors.New("invalid script")
+)
+
+// ScriptRequest asks to run a library script, by Name, or an inline Body.
+type ScriptRequest struct {
+	Name        string
+	Body        string
+	Interpreter string // empty = from the library script's extension
+	Args        []string
+	Timeout     time.Duration // 0 = scripts.default_timeout_seconds
+	RunAs       string        // empty = the agent's own account
+}
+
+// PreparedScript is a script ready to be queued as a RunScript command.
+type PreparedScript struct {
+	scripts.Script
+	Args    []string
+	Timeout time.Duration
+	RunAs   string
+	// ApprovalReason is why the script has to be approved before it runs,
+	// or empty if it is on the allowlist.
+	ApprovalReason string
+}
+
+// ScriptUseCase resolves RunScript requests against the script library and
+// the allowlist in the scripts config.
+type ScriptUseCase interface {
+	// ReloadLibrary loads the scripts in dir (see scripts.LoadLibrary). On
+	// error the previous library stays in use.
+	ReloadLibrary(dir string) error
+	// Scripts returns the library scripts by name.
+	Scripts() []*scripts.Script
+	// Allowed reports whether s runs without approval.
+	Allowed(s *scripts.Script) bool
+	// PrepareScript resolves the script body, interpreter and timeout of a
+	// request and decides whether it needs approval.
+	PrepareScript(req ScriptRequest) (*PreparedScript, error)
+}
+
+type scriptUseCase struct {
+	cfg config.Provider
+
+	mu      sync.RWMutex
+	library *scripts.Library
+}
+
+// NewScriptUseCase creates the script use case with an empty library; call
+// ReloadLibrary to load one.
+func NewScriptUseCase(cfg config.Provider) ScriptUseCase {
+	return &scriptUseCase{cfg: cfg, library: &scripts.Library{}}
+}
+
+func (uc *scriptUseCase) ReloadLibrary(dir string) error {
+	library, err := scripts.LoadLibrary(dir)
+	if err != nil {
+		return err
+	}
+	uc.mu.Lock()
+	changed := len(library.Scripts()) > 0 || len(uc.library.Scripts()) > 0
+	uc.library = library
+	uc.mu.Unlock()
+
+	if changed {
+		logging.Infof("Loaded %d library scripts.", len(library.Scripts()))
+	}
+	return nil
+}
+
+func (uc *scriptUseCase) Scripts() []*scripts.Script {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	return uc.library.Scripts()
+}
+
+func (uc *scriptUseCase) Allowed(s *scripts.Script) bool {
+	return scripts.Allowed(uc.cfg.Current().Scripts.Allowlist, s)
+}
+
+func (uc *scriptUseCase) PrepareScript(req ScriptRequest) (*PreparedScript, error) {
+	cfg := uc.cfg.Current().Scripts
+	var script scripts.Script
+	switch {
+	case req.Name != "" && req.Body != "":
+		return nil, fmt.Errorf("%w: give either a library script or a script body", ErrInvalidScript)
+	case req.Name != "":
+		uc.mu.RLock()
+		found, ok := uc.library.Lookup(req.Name)
+		uc.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrScriptNotFound, req.Name)
+		}
+		script = *found
+	case strings.TrimSpace(req.Body) != "":
+		script = scripts.Script{Body: req.Body, Digest: scripts.Digest(req.Body)}
+	default:
+		return nil, fmt.Errorf("%w: a library script or a script body is required", ErrInvalidScript)
+	}
+
+	if req.Interpreter != "" {
+		script.Interpreter = req.Interpreter
+	}
+	if !scripts.ValidInterpreter(script.Interpreter) {
+		return nil, fmt.Errorf("%w: interpreter must be one of %s", ErrInvalidScript, strings.Join(scripts.Interpreters, ", "))
+	}
+	timeout := req.Timeout
+	switch {
+	case timeout < 0:
+		return nil, fmt.Errorf("%w: the timeout must not be negative", ErrInvalidScript)
+	case timeout == 0:
+		timeout = cfg.DefaultTimeout()
+	case timeout > cfg.MaxTimeout():
+		return nil, fmt.Errorf("%w: the timeout must not exceed %s", ErrInvalidScript, cfg.MaxTimeout())
+	}
+
+	prepared := &PreparedScript{Script: script, Args: req.Args, Timeout: timeout, RunAs: req.RunAs}
+	if !scripts.Allowed(cfg.Allowlist, &script) {
+		if script.Name != "" {
+			prepared.ApprovalReason = fmt.Sprintf("library script %s is not on the allowlist", script.Name)
+		} else {
+			prepared.ApprovalReason = fmt.Sprintf("script %s is not on the allowlist", script.Digest)
+		}
+	}
+	return prepared, nil
+}
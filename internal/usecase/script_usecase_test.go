@@ -0,0 +1,166 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/scripts"
+	"agent_server/internal/usecase"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newScriptFixture(t *testing.T, allowlist ...string) usecase.ScriptUseCase {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"collect-logs.sh": "tar czf /tmp/logs.tgz /var/log\n",
+		"fix-dns.ps1":     "Clear-DnsClientCache\n",
+		".hidden.sh":      "echo hidden\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.Default()
+	cfg.Scripts.Allowlist = allowlist
+	cfg.Scripts.DefaultTimeoutSeconds = 60
+	cfg.Scripts.MaxTimeoutSeconds = 600
+	uc := usecase.NewScriptUseCase(config.StaticProvider(cfg))
+	if err := uc.ReloadLibrary(dir); err != nil {
+		t.Fatalf("ReloadLibrary: %v", err)
+	}
+	return uc
+}
+
+func TestPrepareScriptResolvesLibraryScript(t *testing.T) {
+	uc := newScriptFixture(t, "collect-logs.sh")
+	if got := uc.Scripts(); len(got) != 2 || got[0].Name != "collect-logs.sh" {
+		t.Fatalf("Scripts() = %+v, want the two visible scripts by name", got)
+	}
+
+	prepared, err := uc.PrepareScript(usecase.ScriptRequest{Name: "collect-logs.sh", Args: []string{"-v"}})
+	if err != nil {
+		t.Fatalf("PrepareScript: %v", err)
+	}
+	if prepared.Interpreter != scripts.InterpreterShell || prepared.Timeout != time.Minute || prepared.ApprovalReason != "" {
+		t.Fatalf("prepared = %+v, want sh with the default timeout and no approval", prepared)
+	}
+	if prepared.Body == "" || prepared.Digest != scripts.Digest(prepared.Body) {
+		t.Fatalf("prepared body %q digest %q, want the library body and its digest", prepared.Body, prepared.Digest)
+	}
+
+	prepared, err = uc.PrepareScript(usecase.ScriptRequest{Name: "fix-dns.ps1"})
+	if err != nil || prepared.Interpreter != scripts.InterpreterPowerShell || prepared.ApprovalReason == "" {
+		t.Fatalf("PrepareScript(fix-dns.ps1) = %+v, %v; want powershell awaiting approval", prepared, err)
+	}
+	if _, err := uc.PrepareScript(usecase.ScriptRequest{Name: ".hidden.sh"}); !errors.Is(err, usecase.ErrScriptNotFound) {
+		t.Fatalf("PrepareScript(.hidden.sh) error = %v, want ErrScriptNotFound", err)
+	}
+}
+
+func TestPrepareScriptAllowsInlineScriptByDigest(t *testing.T) {
+	body := "uptime\n"
+	uc := newScriptFixture(t, scripts.Digest(body))
+
+	prepared, err := uc.PrepareScript(usecase.ScriptRequest{Body: body, Interpreter: scripts.InterpreterBash})
+	if err != nil || prepared.ApprovalReason != "" {
+		t.Fatalf("PrepareScript(allowlisted body) = %+v, %v; want no approval", prepared, err)
+	}
+	prepared, err = uc.PrepareScript(usecase.ScriptRequest{Body: "reboot\n", Interpreter: scripts.InterpreterBash})
+	if err != nil || prepared.ApprovalReason == "" {
+		t.Fatalf("PrepareScript(other body) = %+v, %v; want approval", prepared, err)
+	}
+}
+
+func TestPrepareScriptRejectsInvalidRequests(t *testing.T) {
+	uc := newScriptFixture(t)
+	for name, req := range map[string]usecase.ScriptRequest{
+		"no script":           {},
+		"name and body":       {Name: "collect-logs.sh", Body: "uptime"},
+		"no interpreter":      {Body: "uptime"},
+		"unknown interpreter": {Body: "uptime", Interpreter: "perl"},
+		"negative timeout":    {Name: "collect-logs.sh", Timeout: -time.Second},
+		"timeout over max":    {Name: "collect-logs.sh", Timeout: time.Hour},
+	} {
+		if _, err := uc.PrepareScript(req); !errors.Is(err, usecase.ErrInvalidScript) {
+			t.Errorf("%s: error = %v, want ErrInvalidScript", name, err)
+		}
+	}
+}
+
+func TestCommandAwaitingApprovalIsNotPolled(t *testing.T) {
+	store, bus := newEventBus(t, 100, 100)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "web-01", Hostname: "web-01"}); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	sub := subscribeBus(t, bus, usecase.EventFilter{EventTypes: []string{model.EventCommandApproval}}, 0)
+	commands := usecase.NewCommandUseCase(store.Agents, store.Commands, bus)
+
+	cmd, err := commands.EnqueueCommandForApproval("web-01", model.CommandTypeRunScript, []byte("payload"), "not allowlisted")
+	if err != nil || cmd.Status != model.CommandAwaitingApproval {
+		t.Fatalf("EnqueueCommandForApproval = %+v, %v; want AWAITING_APPROVAL", cmd, err)
+	}
+	if events := drain(sub); len(events) != 1 || events[0].Data["reason"] != "not allowlisted" {
+		t.Fatalf("events = %+v, want one approval request", events)
+	}
+	if polled, err := commands.PollCommands("web-01"); err != nil || len(polled) != 0 {
+		t.Fatalf("PollCommands before approval = %+v, %v; want nothing", polled, err)
+	}
+
+	if _, err := commands.ApproveCommand(cmd.ID); err != nil {
+		t.Fatalf("ApproveCommand: %v", err)
+	}
+	if _, err := commands.ApproveCommand(cmd.ID); !errors.Is(err, usecase.ErrInvalidCommandState) {
+		t.Fatalf("second ApproveCommand error = %v, want ErrInvalidCommandState", err)
+	}
+	if polled, err := commands.PollCommands("web-01"); err != nil || len(polled) != 1 || polled[0].ID != cmd.ID {
+		t.Fatalf("PollCommands after approval = %+v, %v; want the command", polled, err)
+	}
+}
+
+func TestCommandOutputIsCappedAndKeepsExitCode(t *testing.T) {
+	store, bus := newEventBus(t, 100, 100)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "web-01", Hostname: "web-01"}); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	commands := usecase.NewCommandUseCase(store.Agents, store.Commands, bus)
+	cmd, err := commands.EnqueueCommand("web-01", model.CommandTypeRunScript, []byte("payload"))
+	if err != nil {
+		t.Fatalf("EnqueueCommand: %v", err)
+	}
+
+	chunk := []model.CommandOutputChunk{{Stream: model.OutputStdout, Data: []byte("hello ")}}
+	if _, err := commands.AppendCommandOutput("web-01", cmd.ID, chunk, 10); !errors.Is(err, usecase.ErrInvalidCommandState) {
+		t.Fatalf("AppendCommandOutput before poll error = %v, want ErrInvalidCommandState", err)
+	}
+	if _, err := commands.PollCommands("web-01"); err != nil {
+		t.Fatalf("PollCommands: %v", err)
+	}
+	if truncated, err := commands.AppendCommandOutput("web-01", cmd.ID, chunk, 10); err != nil || truncated {
+		t.Fatalf("AppendCommandOutput = %v, %v; want stored", truncated, err)
+	}
+	more := []model.CommandOutputChunk{{Stream: model.OutputStderr, Data: []byte("world!")}}
+	if truncated, err := commands.AppendCommandOutput("web-01", cmd.ID, more, 10); err != nil || !truncated {
+		t.Fatalf("AppendCommandOutput over the cap = %v, %v; want truncated", truncated, err)
+	}
+	exitCode := int32(3)
+	if err := commands.CompleteCommand("web-01", cmd.ID, false, "exit status 3", &exitCode); err != nil {
+		t.Fatalf("CompleteCommand: %v", err)
+	}
+
+	got, chunks, err := commands.GetCommandOutput(cmd.ID)
+	if err != nil {
+		t.Fatalf("GetCommandOutput: %v", err)
+	}
+	if got.ExitCode == nil || *got.ExitCode != 3 || got.OutputBytes != 10 || !got.OutputTruncated {
+		t.Fatalf("command = %+v, want exit code 3 and 10 truncated bytes", got)
+	}
+	if len(chunks) != 2 || string(chunks[1].Data) != "worl" || chunks[1].Stream != model.OutputStderr {
+		t.Fatalf("chunks = %+v, want stdout then the cut stderr", chunks)
+	}
+}
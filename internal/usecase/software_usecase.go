@@ -0,0 +1,281 @@
+// internal/usecase/software_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/software"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// CatalogFilter selects products of the software catalog.
+type CatalogFilter struct {
+	Query        string       // substring of the product key or name, ignoring case
+	Labels       model.Labels // only agents with all of these labels
+	OutdatedOnly bool         // only products with outdated installs
+	Limit        int
+}
+
+// CatalogVersion is one version of a product and how many installs run it.
+type CatalogVersion struct {
+	Version  string
+	Installs int
+	Outdated bool
+}
+
+// CatalogProduct is a canonical product with its installs across the fleet.
+// Installs older than PrevailingVersion, the version most installs run, are
+// outdated: they are the outliers that lag behind the rest of the fleet.
+type CatalogProduct struct {
+	software.Product
+	Installs          int
+	Agents            int
+	LatestVersion     string
+	PrevailingVersion string
+	OutdatedInstalls  int
+	Versions          []CatalogVersion // newest first
+}
+
+// InstallFilter selects the installs of one product.
+type InstallFilter struct {
+	Product      string // product key, required
+	Version      string // "" = every version
+	Labels       model.Labels
+	OutdatedOnly bool
+	Limit        int
+}
+
+// SoftwareInstall is one installed application mapped to its product.
+type SoftwareInstall struct {
+	AgentID    string
+	Hostname   string
+	AppName    string
+	Publisher  string
+	Version    string
+	Outdated   bool
+	ReportedAt time.Time
+}
+
+// SoftwareUseCase maps installed applications to canonical products with
+// catalog rules and aggregates them into a fleet software catalog.
+type SoftwareUseCase interface {
+	// ReloadRules loads the rules file at path (see software.LoadRules).
+	// On error the previous rules stay in use.
+	ReloadRules(path string) error
+	Rules() *software.Rules
+	Catalog(filter CatalogFilter) ([]CatalogProduct, error)
+	// ListInstalls returns the installs of a product, oldest version first.
+	ListInstalls(filter InstallFilter) ([]SoftwareInstall, error)
+}
+
+type softwareUseCase struct {
+	agents repository.AgentRepository
+	rules  atomic.Pointer[software.Rules]
+}
+
+// NewSoftwareUseCase creates a software catalog that starts with the
+// built-in rules.
+func NewSoftwareUseCase(agents repository.AgentRepository) SoftwareUseCase {
+	uc := &softwareUseCase{agents: agents}
+	uc.rules.Store(software.DefaultRules())
+	return uc
+}
+
+func (uc *softwareUseCase) ReloadRules(path string) error {
+	rules, err := software.LoadRules(path)
+	if err != nil {
+		return err
+	}
+	uc.rules.Store(rules)
+	if path != "" {
+		logging.Infof("Loaded %d software catalog rules from %s.", rules.Len(), path)
+	}
+	return nil
+}
+
+func (uc *softwareUseCase) Rules() *software.Rules {
+	return uc.rules.Load()
+}
+
+// catalogEntry accumulates the installs of one product.
+type catalogEntry struct {
+	product  software.Product
+	names    map[string]int // raw name -> installs, for unmatched products
+	agents   map[string]bool
+	installs []SoftwareInstall
+	versions map[string]int
+}
+
+// snapshot groups the current installs of non-decommissioned agents that
+// carry labels by product key.
+func (uc *softwareUseCase) snapshot(labels model.Labels) (map[string]*catalogEntry, error) {
+	agents, err := uc.agents.ListAgents(model.AgentFilter{})
+	if err != nil {
+		return nil, err
+	}
+	byPK := make(map[uint]*model.Agent, len(agents))
+	for i := range agents {
+		a := &agents[i]
+		if a.Status != "DECOMMISSIONED" && a.Labels.Match(labels) {
+			byPK[a.ID] = a
+		}
+	}
+	apps, err := uc.agents.FindAllCurrentInstalledApps()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := uc.Rules()
+	entries := make(map[string]*catalogEntry)
+	for _, app := range apps {
+		agent, ok := byPK[app.AgentID]
+		if !ok {
+			continue
+		}
+		p := rules.Normalize(app.Name, app.Publisher)
+		e := entries[p.Key]
+		if e == nil {
+			e = &catalogEntry{product: p, names: map[string]int{}, agents: map[string]bool{}, versions: map[string]int{}}
+			entries[p.Key] = e
+		}
+		version := strings.TrimSpace(app.Version)
+		e.names[strings.TrimSpace(app.Name)]++
+		e.agents[agent.AgentID] = true
+		e.versions[version]++
+		e.installs = append(e.installs, SoftwareInstall{
+			AgentID:    agent.AgentID,
+			Hostname:   agent.Hostname,
+			AppName:    app.Name,
+			Publisher:  app.Publisher,
+			Version:    version,
+			ReportedAt: app.ReportedAt,
+		})
+	}
+	return entries, nil
+}
+
+// summarize turns an entry into a catalog product. Installs without a
+// version are counted but never outdated.
+func (e *catalogEntry) summarize() CatalogProduct {
+	p := CatalogProduct{Product: e.product, Installs: len(e.installs), Agents: len(e.agents)}
+	if p.Name == "" {
+		p.Name = mostCommon(e.names)
+	}
+	for v, n := range e.versions {
+		p.Versions = append(p.Versions, CatalogVersion{Version: v, Installs: n})
+	}
+	scheme := p.Scheme
+	sort.Slice(p.Versions, func(i, j int) bool {
+		a, b := p.Versions[i].Version, p.Versions[j].Version
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+		if c := software.Compare(scheme, a, b); c != 0 {
+			return c > 0
+		}
+		return a < b
+	})
+	// The prevailing version is the most installed one, the newest on a tie.
+	prevailing := -1
+	for i, v := range p.Versions {
+		if v.Version != "" && (prevailing < 0 || v.Installs > p.Versions[prevailing].Installs) {
+			prevailing = i
+		}
+	}
+	if prevailing < 0 {
+		return p
+	}
+	p.LatestVersion = p.Versions[0].Version
+	p.PrevailingVersion = p.Versions[prevailing].Version
+	for i := range p.Versions {
+		v := &p.Versions[i]
+		if v.Version != "" && software.Compare(scheme, v.Version, p.PrevailingVersion) < 0 {
+			v.Outdated = true
+			p.OutdatedInstalls += v.Installs
+		}
+	}
+	return p
+}
+
+func (uc *softwareUseCase) Catalog(filter CatalogFilter) ([]CatalogProduct, error) {
+	entries, err := uc.snapshot(filter.Labels)
+	if err != nil {
+		return nil, err
+	}
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+	var products []CatalogProduct
+	for _, e := range entries {
+		p := e.summarize()
+		if query != "" && !strings.Contains(p.Key, query) && !strings.Contains(strings.ToLower(p.Name), query) {
+			continue
+		}
+		if filter.OutdatedOnly && p.OutdatedInstalls == 0 {
+			continue
+		}
+		products = append(products, p)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		if products[i].Installs != products[j].Installs {
+			return products[i].Installs > products[j].Installs
+		}
+		return products[i].Key < products[j].Key
+	})
+	if filter.Limit > 0 && len(products) > filter.Limit {
+		products = products[:filter.Limit]
+	}
+	return products, nil
+}
+
+func (uc *softwareUseCase) ListInstalls(filter InstallFilter) ([]SoftwareInstall, error) {
+	entries, err := uc.snapshot(filter.Labels)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := entries[strings.ToLower(strings.TrimSpace(filter.Product))]
+	if !ok {
+		return nil, nil
+	}
+	p := e.summarize()
+	outdated := make(map[string]bool)
+	for _, v := range p.Versions {
+		outdated[v.Version] = v.Outdated
+	}
+
+	var installs []SoftwareInstall
+	for _, in := range e.installs {
+		in.Outdated = outdated[in.Version]
+		if filter.Version != "" && in.Version != filter.Version {
+			continue
+		}
+		if filter.OutdatedOnly && !in.Outdated {
+			continue
+		}
+		installs = append(installs, in)
+	}
+	sort.SliceStable(installs, func(i, j int) bool {
+		if c := software.Compare(p.Scheme, installs[i].Version, installs[j].Version); c != 0 {
+			return c < 0
+		}
+		return installs[i].AgentID < installs[j].AgentID
+	})
+	if filter.Limit > 0 && len(installs) > filter.Limit {
+		installs = installs[:filter.Limit]
+	}
+	return installs, nil
+}
+
+// mostCommon returns the key with the highest count, the smallest on a tie.
+func mostCommon(counts map[string]int) string {
+	best, bestN := "", 0
+	for k, n := range counts {
+		if n > bestN || (n == bestN && k < best) {
+			best, bestN = k, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,141 @@
+package usecase_test
+
+import (
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/software"
+	"agent_server/internal/usecase"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newCatalogFixture(t *testing.T) (usecase.AgentUseCase, usecase.SoftwareUseCase) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	agents := usecase.NewAgentUseCase(store.Agents, nil, nil)
+	for _, a := range []*model.Agent{
+		{AgentID: "win-01", Hostname: "win-01.corp", Labels: model.Labels{"os": "windows"}},
+		{AgentID: "win-02", Labels: model.Labels{"os": "windows"}},
+		{AgentID: "win-03", Labels: model.Labels{"os": "windows"}},
+		{AgentID: "deb-01", Labels: model.Labels{"os": "linux"}},
+	} {
+		if _, err := agents.RegisterAgent(a); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+	return agents, usecase.NewSoftwareUseCase(store.Agents)
+}
+
+func catalogByKey(t *testing.T, catalog usecase.SoftwareUseCase, filter usecase.CatalogFilter) map[string]usecase.CatalogProduct {
+	t.Helper()
+	products, err := catalog.Catalog(filter)
+	if err != nil {
+		t.Fatalf("Catalog: %v", err)
+	}
+	byKey := map[string]usecase.CatalogProduct{}
+	for _, p := range products {
+		byKey[p.Key] = p
+	}
+	return byKey
+}
+
+func TestSoftwareCatalogGroupsNamesAndVersions(t *testing.T) {
+	agents, catalog := newCatalogFixture(t)
+	reportApps(t, agents, "win-01",
+		model.InstalledApplication{Name: "Google Chrome", Publisher: "Google LLC", Version: "118.0.5993.118"},
+		model.InstalledApplication{Name: "Slack", Version: "4.35.126"},
+	)
+	reportApps(t, agents, "win-02", model.InstalledApplication{Name: "Chrome", Version: "118.0.5993.118"})
+	reportApps(t, agents, "win-03", model.InstalledApplication{Name: "Google Chrome", Publisher: "Google LLC", Version: "118.0.5993.88"})
+	reportApps(t, agents, "deb-01",
+		model.InstalledApplication{Name: "google-chrome-stable", Version: "119.0.6045.105-1"},
+		model.InstalledApplication{Name: "libssl3", Version: "3.0.2-0ubuntu1.10"},
+	)
+
+	got := catalogByKey(t, catalog, usecase.CatalogFilter{})
+	chrome, ok := got["google:chrome"]
+	if !ok || chrome.Installs != 4 || chrome.Agents != 4 || len(chrome.Versions) != 3 {
+		t.Fatalf("google:chrome = %+v, want 4 installs of 3 versions under one product", chrome)
+	}
+	// 118.0.5993.88 < 118.0.5993.118 although it sorts after it as a string.
+	if chrome.LatestVersion != "119.0.6045.105-1" || chrome.PrevailingVersion != "118.0.5993.118" || chrome.OutdatedInstalls != 1 {
+		t.Fatalf("google:chrome latest %q prevailing %q outdated %d; want 119.0.6045.105-1, 118.0.5993.118, 1",
+			chrome.LatestVersion, chrome.PrevailingVersion, chrome.OutdatedInstalls)
+	}
+	if last := chrome.Versions[2]; last.Version != "118.0.5993.88" || !last.Outdated {
+		t.Fatalf("oldest version = %+v, want 118.0.5993.88 outdated", last)
+	}
+	if slack := got["slack:slack"]; slack.Matched || slack.Name != "Slack" || slack.Installs != 1 {
+		t.Fatalf("slack:slack = %+v, want an unmatched product named after the app", slack)
+	}
+	if ssl := got["openssl:openssl"]; !ssl.Matched || ssl.Name != "OpenSSL" {
+		t.Fatalf("openssl:openssl = %+v, want libssl3 mapped by the built-in rules", ssl)
+	}
+
+	linux := catalogByKey(t, catalog, usecase.CatalogFilter{Labels: model.Labels{"os": "linux"}})
+	if len(linux) != 2 || linux["google:chrome"].Installs != 1 {
+		t.Fatalf("os=linux catalog = %+v, want chrome and openssl from deb-01", linux)
+	}
+	if outdated := catalogByKey(t, catalog, usecase.CatalogFilter{OutdatedOnly: true}); len(outdated) != 1 {
+		t.Fatalf("outdated-only catalog = %+v, want google:chrome only", outdated)
+	}
+
+	installs, err := catalog.ListInstalls(usecase.InstallFilter{Product: "google:chrome", OutdatedOnly: true})
+	if err != nil || len(installs) != 1 || installs[0].AgentID != "win-03" || !installs[0].Outdated {
+		t.Fatalf("outdated chrome installs = %+v, %v; want win-03", installs, err)
+	}
+	all, _ := catalog.ListInstalls(usecase.InstallFilter{Product: "google:chrome"})
+	if len(all) != 4 || all[0].Version != "118.0.5993.88" || all[3].AgentID != "deb-01" {
+		t.Fatalf("chrome installs = %+v, want 4 from oldest to newest", all)
+	}
+}
+
+func TestSoftwareRulesFileOverridesBuiltIns(t *testing.T) {
+	agents, catalog := newCatalogFixture(t)
+	reportApps(t, agents, "win-01",
+		model.InstalledApplication{Name: "Contoso Agent", Publisher: "Contoso Ltd", Version: "2.0.0-rc.1"},
+		model.InstalledApplication{Name: "Google Chrome", Publisher: "Google LLC", Version: "118.0"},
+	)
+	reportApps(t, agents, "win-02", model.InstalledApplication{Name: "contoso-agent", Version: "2.0.0"})
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	rules := `
+products:
+  - product: contoso:agent
+    name: Contoso Agent
+    scheme: semver
+    match:
+      - name: '^contoso[ -]agent$'
+  - product: google:chrome_enterprise
+    name: Chrome Enterprise
+    match:
+      - name: '^google chrome$'
+        publisher: google
+`
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.ReloadRules(path); err != nil {
+		t.Fatalf("ReloadRules: %v", err)
+	}
+
+	got := catalogByKey(t, catalog, usecase.CatalogFilter{})
+	contoso := got["contoso:agent"]
+	if contoso.Installs != 2 || contoso.Scheme != software.SchemeSemver || contoso.LatestVersion != "2.0.0" {
+		t.Fatalf("contoso:agent = %+v, want 2 installs with 2.0.0 newer than 2.0.0-rc.1", contoso)
+	}
+	if _, ok := got["google:chrome_enterprise"]; !ok {
+		t.Fatalf("catalog = %v, want the file rule to win over the built-in chrome rule", got)
+	}
+
+	if err := os.WriteFile(path, []byte("products:\n  - product: nocolon\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.ReloadRules(path); err == nil {
+		t.Fatal("ReloadRules accepted an invalid rules file")
+	}
+	if _, ok := catalogByKey(t, catalog, usecase.CatalogFilter{})["contoso:agent"]; !ok {
+		t.Fatal("an invalid rules file replaced the previous rules")
+	}
+}
@@ -0,0 +1,592 @@
+// internal/usecase/update_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/artifacts"
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/updates"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidPackage is returned for an agent package that cannot be
+	// stored or read.
+	ErrInvalidPackage = errors.New("invalid agent package")
+	// ErrPackageExists is returned when uploading a version, OS and arch
+	// that already has a package.
+	ErrPackageExists = errors.New("agent package already exists")
+	// ErrInvalidRollout is returned for a rollout that cannot be created or
+	// changed as requested.
+	ErrInvalidRollout = errors.New("invalid rollout")
+	// ErrRolloutInProgress is returned when creating a rollout while another
+	// one is active or paused.
+	ErrRolloutInProgress = errors.New("another rollout is active or paused")
+	// ErrUpdateNotOffered is returned for an update result of an agent that
+	// has no open update in the rollout.
+	ErrUpdateNotOffered = errors.New("no update was offered to the agent in this rollout")
+)
+
+// PackageUpload describes an agent package being uploaded.
+type PackageUpload struct {
+	Version   string
+	OS        string
+	Arch      string
+	Signature string // base64 ed25519 signature of the package's hex SHA-256
+}
+
+// RolloutChange changes an open rollout; zero fields keep their value.
+type RolloutChange struct {
+	Percentage *int
+	Groups     []string
+	SetGroups  bool   // replace the groups with Groups, which may be empty
+	Status     string // model.RolloutActive, RolloutPaused or RolloutCancelled
+}
+
+// RolloutSummary is a rollout with the number of its updates in each state.
+type RolloutSummary struct {
+	model.Rollout
+	Counts model.RolloutCounts
+}
+
+// UpdateOffer tells an agent which package to update to.
+type UpdateOffer struct {
+	RolloutID uint
+	Package   model.AgentPackage
+}
+
+// UpdateUseCase distributes new agent versions. Operators upload signed
+// packages per version, OS and arch, then start a rollout of a version; the
+// registration and heartbeat responses offer the update to the agents the
+// rollout reaches (see package updates). A rollout pauses itself when too
+// many of its updates fail.
+//
+// Only one rollout is open (active or paused) at a time. It is kept in
+// memory with its packages and offers, so checking a heartbeat does not touch
+// the database unless the agent has to be looked up or is offered the update
+// for the first time.
+type UpdateUseCase interface {
+	// Load reads the open rollout; call it once at startup.
+	Load() error
+
+	// UploadPackage stores a package read from content.
+	UploadPackage(ctx context.Context, info PackageUpload, content io.Reader) (*model.AgentPackage, error)
+	ListPackages(version string) ([]model.AgentPackage, error)
+	// OpenPackage returns a package and its content from offset.
+	OpenPackage(ctx context.Context, packageID uint, offset int64) (*model.AgentPackage, io.ReadCloser, error)
+
+	CreateRollout(rollout *model.Rollout) (*RolloutSummary, error)
+	UpdateRollout(id uint, change RolloutChange) (*RolloutSummary, error)
+	// ListRollouts returns rollouts with status, or all if it is empty,
+	// newest first.
+	ListRollouts(status string, limit int) ([]RolloutSummary, error)
+	ListAgentUpdates(rolloutID uint, status string, limit int) ([]model.AgentUpdateRow, error)
+
+	// CheckRegistration is called after an agent registered. It records the
+	// update as succeeded when the agent now runs the rollout's version and
+	// returns the update to offer, if any.
+	CheckRegistration(agent *model.Agent) *UpdateOffer
+	// CheckHeartbeat returns the update to offer to an agent, if any.
+	CheckHeartbeat(agentID string) *UpdateOffer
+	// ReportResult records the outcome of an update the agent installed.
+	ReportResult(agentID string, rolloutID uint, success bool, message string) error
+}
+
+type updateUseCase struct {
+	repo   repository.UpdateRepository
+	agents repository.AgentRepository
+	store  artifacts.Store
+	events EventPublisher
+	cfg    config.Provider
+
+	mu       sync.Mutex
+	rollout  *model.Rollout                // the open rollout, nil if none
+	packages map[string]model.AgentPackage // its packages by os/arch
+	offers   map[uint]string               // status of its updates by agent primary key
+	facts    map[string]agentFacts         // by agent ID
+}
+
+// agentFacts is what deciding on an update needs to know about an agent.
+type agentFacts struct {
+	pk      uint
+	version string
+	target  string // os/arch of its packages
+	labels  map[string]string
+}
+
+// NewUpdateUseCase creates the agent update use case. Packages are kept in
+// store; events, which may be nil, receive rollout pauses.
+func NewUpdateUseCase(repo repository.UpdateRepository, agents repository.AgentRepository, store artifacts.Store, events EventPublisher, cfg config.Provider) UpdateUseCase {
+	return &updateUseCase{
+		repo:   repo,
+		agents: agents,
+		store:  store,
+		events: publisherOrNoop(events),
+		cfg:    cfg,
+		facts:  make(map[string]agentFacts),
+	}
+}
+
+func (uc *updateUseCase) Load() error {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	return uc.loadLocked()
+}
+
+// loadLocked reads the open rollout with its packages and offers.
+func (uc *updateUseCase) loadLocked() error {
+	open, err := uc.repo.FindRollouts([]string{model.RolloutActive, model.RolloutPaused}, 1)
+	if err != nil {
+		return err
+	}
+	uc.rollout, uc.packages, uc.offers = nil, nil, nil
+	if len(open) == 0 {
+		return nil
+	}
+	rollout := open[0]
+	pkgs, err := uc.repo.FindPackages(rollout.Version)
+	if err != nil {
+		return err
+	}
+	rows, err := uc.repo.FindAgentUpdates(rollout.ID, "", 0)
+	if err != nil {
+		return err
+	}
+	uc.rollout = &rollout
+	uc.packages = make(map[string]model.AgentPackage, len(pkgs))
+	for _, p := range pkgs {
+		uc.packages[p.OS+"/"+p.Arch] = p
+	}
+	uc.offers = make(map[uint]string, len(rows))
+	for _, row := range rows {
+		uc.offers[row.Update.AgentID] = row.Update.Status
+	}
+	return nil
+}
+
+var (
+	versionPattern = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z.+_-]{0,63}$`)
+	archPattern    = regexp.MustCompile(`^[0-9a-z_]{1,32}$`)
+)
+
+func (uc *updateUseCase) UploadPackage(ctx context.Context, info PackageUpload, content io.Reader) (*model.AgentPackage, error) {
+	switch {
+	case !versionPattern.MatchString(info.Version):
+		return nil, fmt.Errorf("%w: version %q is not valid", ErrInvalidPackage, info.Version)
+	case !updates.ValidOS(info.OS):
+		return nil, fmt.Errorf("%w: os must be %s, %s or %s", ErrInvalidPackage, updates.OSWindows, updates.OSLinux, updates.OSDarwin)
+	case !archPattern.MatchString(info.Arch):
+		return nil, fmt.Errorf("%w: arch %q is not valid", ErrInvalidPackage, info.Arch)
+	}
+	existing, err := uc.repo.FindPackages(info.Version)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range existing {
+		if p.OS == info.OS && p.Arch == info.Arch {
+			return nil, fmt.Errorf("%w: %s for %s/%s", ErrPackageExists, info.Version, info.OS, info.Arch)
+		}
+	}
+
+	cfg := uc.cfg.Current().Updates
+	pkg := &model.AgentPackage{Version: info.Version, OS: info.OS, Arch: info.Arch, Signature: info.Signature}
+	key := packageKey(pkg)
+	obj, err := uc.store.Put(ctx, key, content, cfg.MaxPackageBytes)
+	if errors.Is(err, artifacts.ErrTooLarge) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pkg.SHA256, pkg.SizeBytes = obj.SHA256, obj.Size
+
+	if err := uc.checkPackage(pkg, cfg); err != nil {
+		uc.deleteContent(key)
+		return nil, err
+	}
+	if err := uc.repo.CreatePackage(pkg); err != nil {
+		uc.deleteContent(key)
+		return nil, err
+	}
+	logging.Infof("Stored agent package %s for %s/%s (%d bytes, sha256 %s).", pkg.Version, pkg.OS, pkg.Arch, pkg.SizeBytes, pkg.SHA256)
+
+	// A package added to the version being rolled out reaches its agents at
+	// once.
+	uc.mu.Lock()
+	if uc.rollout != nil && uc.rollout.Version == pkg.Version {
+		uc.packages[pkg.OS+"/"+pkg.Arch] = *pkg
+	}
+	uc.mu.Unlock()
+	return pkg, nil
+}
+
+// checkPackage verifies the content and signature of a stored package.
+func (uc *updateUseCase) checkPackage(pkg *model.AgentPackage, cfg config.UpdatesConfig) error {
+	if pkg.SizeBytes == 0 {
+		return fmt.Errorf("%w: the package is empty", ErrInvalidPackage)
+	}
+	return checkSignature(pkg, cfg)
+}
+
+// checkSignature verifies the signature of pkg with the configured key.
+// Without a key packages are refused unless updates.allow_unsigned is set.
+func checkSignature(pkg *model.AgentPackage, cfg config.UpdatesConfig) error {
+	if cfg.SigningPublicKey == "" {
+		if cfg.AllowUnsigned {
+			return nil
+		}
+		return fmt.Errorf("%w: no signing key is configured; set updates.signing_public_key, or updates.allow_unsigned for testing", ErrInvalidPackage)
+	}
+	key, err := updates.ParsePublicKey(cfg.SigningPublicKey)
+	if err != nil {
+		return err
+	}
+	if pkg.Signature == "" {
+		return fmt.Errorf("%w: packages must be signed", ErrInvalidPackage)
+	}
+	if err := updates.VerifySignature(key, pkg.SHA256, pkg.Signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	return nil
+}
+
+func (uc *updateUseCase) deleteContent(key string) {
+	if err := uc.store.Delete(context.Background(), key); err != nil {
+		logging.Errorf("Failed to delete rejected agent package %s: %v", key, err)
+	}
+}
+
+// packageKey is where the content of pkg is kept in the artifact store.
+func packageKey(pkg *model.AgentPackage) string {
+	return fmt.Sprintf("packages/%s/%s-%s", pkg.Version, pkg.OS, pkg.Arch)
+}
+
+func (uc *updateUseCase) ListPackages(version string) ([]model.AgentPackage, error) {
+	return uc.repo.FindPackages(version)
+}
+
+func (uc *updateUseCase) OpenPackage(ctx context.Context, packageID uint, offset int64) (*model.AgentPackage, io.ReadCloser, error) {
+	pkg, err := uc.repo.FindPackage(packageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset < 0 || offset > pkg.SizeBytes {
+		return nil, nil, fmt.Errorf("%w: offset %d is outside the package of %d bytes", ErrInvalidPackage, offset, pkg.SizeBytes)
+	}
+	content, err := uc.store.Open(ctx, packageKey(pkg), offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pkg, content, nil
+}
+
+func (uc *updateUseCase) CreateRollout(rollout *model.Rollout) (*RolloutSummary, error) {
+	if err := validateRollout(rollout); err != nil {
+		return nil, err
+	}
+	pkgs, err := uc.repo.FindPackages(rollout.Version)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("%w: no packages were uploaded for version %s", ErrInvalidRollout, rollout.Version)
+	}
+	// Packages may have been uploaded under another key or without one.
+	cfg := uc.cfg.Current().Updates
+	for i := range pkgs {
+		if err := checkSignature(&pkgs[i], cfg); err != nil {
+			return nil, fmt.Errorf("%w: package for %s/%s: %v", ErrInvalidRollout, pkgs[i].OS, pkgs[i].Arch, err)
+		}
+	}
+	if rollout.MinResults == 0 {
+		rollout.MinResults = 1
+	}
+	rollout.Status = model.RolloutActive
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if err := uc.loadLocked(); err != nil {
+		return nil, err
+	}
+	if uc.rollout != nil {
+		return nil, fmt.Errorf("%w: rollout %d of version %s", ErrRolloutInProgress, uc.rollout.ID, uc.rollout.Version)
+	}
+	if err := uc.repo.CreateRollout(rollout); err != nil {
+		return nil, err
+	}
+	logging.Infof("Started rollout %d of agent version %s to %d%% of agents.", rollout.ID, rollout.Version, rollout.Percentage)
+	if err := uc.loadLocked(); err != nil {
+		return nil, err
+	}
+	return &RolloutSummary{Rollout: *rollout}, nil
+}
+
+func validateRollout(r *model.Rollout) error {
+	switch {
+	case r.Version == "":
+		return fmt.Errorf("%w: version is required", ErrInvalidRollout)
+	case r.Percentage < 0 || r.Percentage > 100:
+		return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidRollout)
+	case r.MaxFailureRate < 0 || r.MaxFailureRate > 1:
+		return fmt.Errorf("%w: max failure rate must be between 0 and 1", ErrInvalidRollout)
+	case r.MinResults < 0:
+		return fmt.Errorf("%w: min results must not be negative", ErrInvalidRollout)
+	}
+	if err := updates.ValidateGroups(r.Groups); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRollout, err)
+	}
+	return nil
+}
+
+func (uc *updateUseCase) UpdateRollout(id uint, change RolloutChange) (*RolloutSummary, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	rollout, err := uc.repo.FindRollout(id)
+	if err != nil {
+		return nil, err
+	}
+	if rollout.Status == model.RolloutCancelled {
+		return nil, fmt.Errorf("%w: rollout %d was cancelled", ErrInvalidRollout, id)
+	}
+	if change.Percentage != nil {
+		rollout.Percentage = *change.Percentage
+	}
+	if change.SetGroups {
+		rollout.Groups = change.Groups
+	}
+	switch change.Status {
+	case "":
+	case model.RolloutActive:
+		rollout.Status, rollout.PauseReason = model.RolloutActive, ""
+	case model.RolloutPaused:
+		if rollout.Status != model.RolloutPaused {
+			rollout.Status, rollout.PauseReason = model.RolloutPaused, "paused by operator"
+		}
+	case model.RolloutCancelled:
+		rollout.Status = model.RolloutCancelled
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidRollout, change.Status)
+	}
+	if err := validateRollout(rollout); err != nil {
+		return nil, err
+	}
+	if err := uc.repo.SaveRollout(rollout); err != nil {
+		return nil, err
+	}
+	logging.Infof("Rollout %d of agent version %s is %s at %d%%.", rollout.ID, rollout.Version, rollout.Status, rollout.Percentage)
+	if err := uc.loadLocked(); err != nil {
+		return nil, err
+	}
+	return uc.summarize(*rollout)
+}
+
+func (uc *updateUseCase) summarize(rollout model.Rollout) (*RolloutSummary, error) {
+	counts, err := uc.repo.CountAgentUpdates(rollout.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &RolloutSummary{Rollout: rollout, Counts: counts}, nil
+}
+
+func (uc *updateUseCase) ListRollouts(status string, limit int) ([]RolloutSummary, error) {
+	var statuses []string
+	if status != "" {
+		statuses = []string{status}
+	}
+	rollouts, err := uc.repo.FindRollouts(statuses, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]RolloutSummary, 0, len(rollouts))
+	for _, r := range rollouts {
+		summary, err := uc.summarize(r)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *summary)
+	}
+	return out, nil
+}
+
+func (uc *updateUseCase) ListAgentUpdates(rolloutID uint, status string, limit int) ([]model.AgentUpdateRow, error) {
+	if _, err := uc.repo.FindRollout(rolloutID); err != nil {
+		return nil, err
+	}
+	return uc.repo.FindAgentUpdates(rolloutID, status, limit)
+}
+
+func (uc *updateUseCase) CheckRegistration(agent *model.Agent) *UpdateOffer {
+	facts := agentFacts{
+		pk:      agent.ID,
+		version: agent.AgentVersion,
+		target:  updates.OSFamily(agent.OSName) + "/" + agent.Arch,
+		labels:  agent.Labels,
+	}
+	uc.mu.Lock()
+	uc.facts[agent.AgentID] = facts
+	rollout := uc.rollout
+	updated := rollout != nil && uc.offers[facts.pk] == model.AgentUpdateOffered &&
+		facts.version != "" && updates.CompareVersions(facts.version, rollout.Version) >= 0
+	uc.mu.Unlock()
+
+	if updated {
+		if err := uc.finish(rollout.ID, facts.pk, true, "registered with version "+facts.version); err != nil {
+			logging.Errorf("Failed to record the update of agent %s: %v", agent.AgentID, err)
+		}
+	}
+	return uc.offer(agent.AgentID, facts)
+}
+
+func (uc *updateUseCase) CheckHeartbeat(agentID string) *UpdateOffer {
+	uc.mu.Lock()
+	facts, ok := uc.facts[agentID]
+	open := uc.rollout != nil
+	uc.mu.Unlock()
+	if !open {
+		return nil
+	}
+	if !ok {
+		agent, err := uc.agents.FindAgentByID(agentID)
+		if err != nil {
+			logging.Errorf("Failed to look up agent %s for updates: %v", agentID, err)
+			return nil
+		}
+		facts = agentFacts{
+			pk:      agent.ID,
+			version: agent.AgentVersion,
+			target:  updates.OSFamily(agent.OSName) + "/" + agent.Arch,
+			labels:  agent.Labels,
+		}
+		uc.mu.Lock()
+		uc.facts[agentID] = facts
+		uc.mu.Unlock()
+	}
+	return uc.offer(agentID, facts)
+}
+
+// offer returns the open rollout's package for the agent if the rollout
+// reaches it, recording the offer the first time.
+func (uc *updateUseCase) offer(agentID string, facts agentFacts) *UpdateOffer {
+	uc.mu.Lock()
+	rollout := uc.rollout
+	if rollout == nil || rollout.Status != model.RolloutActive || facts.version == "" ||
+		updates.CompareVersions(facts.version, rollout.Version) >= 0 ||
+		!updates.InGroups(rollout.Groups, facts.labels) || updates.Bucket(rollout.ID, agentID) >= rollout.Percentage {
+		uc.mu.Unlock()
+		return nil
+	}
+	pkg, ok := uc.packages[facts.target]
+	status, offered := uc.offers[facts.pk]
+	if !ok || (offered && status != model.AgentUpdateOffered) {
+		uc.mu.Unlock()
+		return nil
+	}
+	if !offered {
+		uc.offers[facts.pk] = model.AgentUpdateOffered
+	}
+	uc.mu.Unlock()
+
+	if !offered {
+		update := &model.AgentUpdate{
+			RolloutID:   rollout.ID,
+			AgentID:     facts.pk,
+			FromVersion: facts.version,
+			ToVersion:   rollout.Version,
+			Status:      model.AgentUpdateOffered,
+			OfferedAt:   time.Now(),
+		}
+		if _, err := uc.repo.CreateAgentUpdate(update); err != nil {
+			logging.Errorf("Failed to record the update offered to agent %s: %v", agentID, err)
+			uc.mu.Lock()
+			delete(uc.offers, facts.pk)
+			uc.mu.Unlock()
+			return nil
+		}
+		logging.Infof("Offered agent version %s to agent %s (rollout %d).", rollout.Version, agentID, rollout.ID)
+	}
+	return &UpdateOffer{RolloutID: rollout.ID, Package: pkg}
+}
+
+func (uc *updateUseCase) ReportResult(agentID string, rolloutID uint, success bool, message string) error {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return err
+	}
+	if _, err := uc.repo.FindRollout(rolloutID); err != nil {
+		return err
+	}
+	return uc.finish(rolloutID, agent.ID, success, message)
+}
+
+// finish records the result of an offered update and pauses the rollout if
+// too many of its updates failed.
+func (uc *updateUseCase) finish(rolloutID, agentPK uint, success bool, message string) error {
+	status := model.AgentUpdateSucceeded
+	if !success {
+		status = model.AgentUpdateFailed
+	}
+	ok, err := uc.repo.FinishAgentUpdate(rolloutID, agentPK, status, message, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUpdateNotOffered
+	}
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if uc.rollout == nil || uc.rollout.ID != rolloutID {
+		return nil
+	}
+	uc.offers[agentPK] = status
+	if !success {
+		return uc.checkFailureRateLocked()
+	}
+	return nil
+}
+
+// checkFailureRateLocked pauses the open rollout once it has enough results
+// and their failure rate exceeds its limit.
+func (uc *updateUseCase) checkFailureRateLocked() error {
+	rollout := uc.rollout
+	if rollout.Status != model.RolloutActive || rollout.MaxFailureRate <= 0 {
+		return nil
+	}
+	counts, err := uc.repo.CountAgentUpdates(rollout.ID)
+	if err != nil {
+		return err
+	}
+	results := counts.Succeeded + counts.Failed
+	rate := float64(counts.Failed) / float64(results)
+	if results < rollout.MinResults || rate <= rollout.MaxFailureRate {
+		return nil
+	}
+
+	paused := *rollout
+	paused.Status = model.RolloutPaused
+	paused.PauseReason = fmt.Sprintf("%d of %d updates failed (%.0f%%), above the limit of %.0f%%",
+		counts.Failed, results, rate*100, rollout.MaxFailureRate*100)
+	if err := uc.repo.SaveRollout(&paused); err != nil {
+		return err
+	}
+	uc.rollout = &paused
+	logging.Warnf("Paused rollout %d of agent version %s: %s.", paused.ID, paused.Version, paused.PauseReason)
+	uc.events.Publish(NewEvent(model.EventRolloutPaused, "", map[string]interface{}{
+		"rollout_id":   paused.ID,
+		"version":      paused.Version,
+		"failed":       counts.Failed,
+		"results":      results,
+		"failure_rate": rate,
+		"reason":       paused.PauseReason,
+	}))
+	return nil
+}
@@ -0,0 +1,234 @@
+package usecase_test
+
+import (
+	"agent_server/internal/artifacts"
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type updateFixture struct {
+	store   *repository.Store
+	agents  usecase.AgentUseCase
+	updates usecase.UpdateUseCase
+	key     ed25519.PrivateKey
+	sub     *usecase.Subscription
+}
+
+func newUpdateFixture(t *testing.T) *updateFixture {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	cfg := config.Default()
+	cfg.Updates.SigningPublicKey = base64.StdEncoding.EncodeToString(pub)
+	objects, err := artifacts.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	store, bus := newEventBus(t, 100, 100)
+	f := &updateFixture{
+		store:   store,
+		agents:  usecase.NewAgentUseCase(store.Agents, nil, bus),
+		updates: usecase.NewUpdateUseCase(store.Updates, store.Agents, objects, bus, config.StaticProvider(cfg)),
+		key:     priv,
+		sub:     subscribeBus(t, bus, usecase.EventFilter{EventTypes: []string{model.EventRolloutPaused}}, 0),
+	}
+	if err := f.updates.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return f
+}
+
+// sign returns the signature of content as agentctl users would create it.
+func (f *updateFixture) sign(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(f.key, sum[:]))
+}
+
+func (f *updateFixture) upload(t *testing.T, version, os, arch, content string) *model.AgentPackage {
+	t.Helper()
+	info := usecase.PackageUpload{Version: version, OS: os, Arch: arch, Signature: f.sign(content)}
+	pkg, err := f.updates.UploadPackage(context.Background(), info, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("UploadPackage(%s %s/%s): %v", version, os, arch, err)
+	}
+	return pkg
+}
+
+// register registers an agent and returns the update offered to it.
+func (f *updateFixture) register(t *testing.T, agent *model.Agent) *usecase.UpdateOffer {
+	t.Helper()
+	registered, err := f.agents.RegisterAgent(agent)
+	if err != nil {
+		t.Fatalf("RegisterAgent(%s): %v", agent.AgentID, err)
+	}
+	return f.updates.CheckRegistration(registered)
+}
+
+func TestUploadPackageVerifiesSignature(t *testing.T) {
+	f := newUpdateFixture(t)
+	pkg := f.upload(t, "2.0.0", "linux", "amd64", "new agent")
+	if pkg.SizeBytes != int64(len("new agent")) || pkg.SHA256 == "" {
+		t.Fatalf("package = %+v, want its size and digest", pkg)
+	}
+
+	_, content, err := f.updates.OpenPackage(context.Background(), pkg.ID, 4)
+	if err != nil {
+		t.Fatalf("OpenPackage: %v", err)
+	}
+	rest, _ := io.ReadAll(content)
+	content.Close()
+	if string(rest) != "agent" {
+		t.Fatalf("content from offset 4 = %q, want %q", rest, "agent")
+	}
+
+	forged := usecase.PackageUpload{Version: "2.0.0", OS: "windows", Arch: "amd64", Signature: f.sign("something else")}
+	if _, err := f.updates.UploadPackage(context.Background(), forged, strings.NewReader("new agent")); !errors.Is(err, usecase.ErrInvalidPackage) {
+		t.Fatalf("UploadPackage(bad signature) = %v, want ErrInvalidPackage", err)
+	}
+	duplicate := usecase.PackageUpload{Version: "2.0.0", OS: "linux", Arch: "amd64", Signature: f.sign("new agent")}
+	if _, err := f.updates.UploadPackage(context.Background(), duplicate, strings.NewReader("new agent")); !errors.Is(err, usecase.ErrPackageExists) {
+		t.Fatalf("UploadPackage(duplicate) = %v, want ErrPackageExists", err)
+	}
+	if pkgs, _ := f.updates.ListPackages(""); len(pkgs) != 1 {
+		t.Fatalf("ListPackages = %+v, want only the signed package", pkgs)
+	}
+}
+
+func TestUnsignedPackagesNeedExplicitOptIn(t *testing.T) {
+	cfg := config.Default()
+	objects, err := artifacts.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	store, bus := newEventBus(t, 100, 100)
+	updates := usecase.NewUpdateUseCase(store.Updates, store.Agents, objects, bus, config.StaticProvider(cfg))
+	upload := func(arch string) error {
+		info := usecase.PackageUpload{Version: "2.0.0", OS: "linux", Arch: arch}
+		_, err := updates.UploadPackage(context.Background(), info, strings.NewReader("new agent"))
+		return err
+	}
+
+	if err := upload("amd64"); !errors.Is(err, usecase.ErrInvalidPackage) {
+		t.Fatalf("UploadPackage(unsigned, default config) = %v, want ErrInvalidPackage", err)
+	}
+	cfg.Updates.AllowUnsigned = true
+	if err := upload("amd64"); err != nil {
+		t.Fatalf("UploadPackage(unsigned, allow_unsigned) = %v", err)
+	}
+
+	// A package accepted while unsigned uploads were allowed cannot be
+	// rolled out once they no longer are.
+	cfg.Updates.AllowUnsigned = false
+	if _, err := updates.CreateRollout(&model.Rollout{Version: "2.0.0", Percentage: 100}); !errors.Is(err, usecase.ErrInvalidRollout) {
+		t.Fatalf("CreateRollout(unsigned package, default config) = %v, want ErrInvalidRollout", err)
+	}
+	cfg.Updates.AllowUnsigned = true
+	if _, err := updates.CreateRollout(&model.Rollout{Version: "2.0.0", Percentage: 100}); err != nil {
+		t.Fatalf("CreateRollout(allow_unsigned) = %v", err)
+	}
+}
+
+func TestRolloutOffersUpdateToEligibleAgents(t *testing.T) {
+	f := newUpdateFixture(t)
+	pkg := f.upload(t, "2.0.0", "linux", "amd64", "new agent")
+	if offer := f.register(t, &model.Agent{AgentID: "web-01", OSName: "Ubuntu", Arch: "amd64", AgentVersion: "1.9.0", Labels: map[string]string{"ring": "canary"}}); offer != nil {
+		t.Fatalf("offer without a rollout = %+v, want none", offer)
+	}
+
+	rollout, err := f.updates.CreateRollout(&model.Rollout{Version: "2.0.0", Percentage: 0, Groups: []string{"ring=canary"}})
+	if err != nil {
+		t.Fatalf("CreateRollout: %v", err)
+	}
+	if offer := f.updates.CheckHeartbeat("web-01"); offer != nil {
+		t.Fatalf("offer at 0%% = %+v, want none", offer)
+	}
+	if _, err := f.updates.CreateRollout(&model.Rollout{Version: "2.0.0", Percentage: 100}); !errors.Is(err, usecase.ErrRolloutInProgress) {
+		t.Fatalf("second CreateRollout = %v, want ErrRolloutInProgress", err)
+	}
+
+	percentage := 100
+	if _, err := f.updates.UpdateRollout(rollout.ID, usecase.RolloutChange{Percentage: &percentage}); err != nil {
+		t.Fatalf("UpdateRollout: %v", err)
+	}
+	offer := f.updates.CheckHeartbeat("web-01")
+	if offer == nil || offer.RolloutID != rollout.ID || offer.Package.ID != pkg.ID {
+		t.Fatalf("offer at 100%% = %+v, want package %d", offer, pkg.ID)
+	}
+	for _, agent := range []*model.Agent{
+		{AgentID: "web-02", OSName: "Ubuntu", Arch: "amd64", AgentVersion: "1.9.0", Labels: map[string]string{"ring": "stable"}},
+		{AgentID: "web-03", OSName: "Ubuntu", Arch: "amd64", AgentVersion: "2.1.0", Labels: map[string]string{"ring": "canary"}},
+		{AgentID: "mac-01", OSName: "Darwin", Arch: "arm64", AgentVersion: "1.9.0", Labels: map[string]string{"ring": "canary"}},
+	} {
+		if offer := f.register(t, agent); offer != nil {
+			t.Fatalf("offer to %s = %+v, want none", agent.AgentID, offer)
+		}
+	}
+
+	// The agent comes back with the new version.
+	if offer := f.register(t, &model.Agent{AgentID: "web-01", OSName: "Ubuntu", Arch: "amd64", AgentVersion: "2.0.0", Labels: map[string]string{"ring": "canary"}}); offer != nil {
+		t.Fatalf("offer after updating = %+v, want none", offer)
+	}
+	rows, err := f.updates.ListAgentUpdates(rollout.ID, "", 0)
+	if err != nil || len(rows) != 1 || rows[0].AgentKey != "web-01" || rows[0].Update.Status != model.AgentUpdateSucceeded || rows[0].Update.FromVersion != "1.9.0" {
+		t.Fatalf("ListAgentUpdates = %+v, %v; want web-01 updated from 1.9.0", rows, err)
+	}
+}
+
+func TestRolloutPausesOnFailureRate(t *testing.T) {
+	f := newUpdateFixture(t)
+	f.upload(t, "2.0.0", "windows", "amd64", "new agent")
+	rollout, err := f.updates.CreateRollout(&model.Rollout{Version: "2.0.0", Percentage: 100, MaxFailureRate: 0.5, MinResults: 2})
+	if err != nil {
+		t.Fatalf("CreateRollout: %v", err)
+	}
+	for _, id := range []string{"win-01", "win-02", "win-03"} {
+		if offer := f.register(t, &model.Agent{AgentID: id, OSName: "Windows 11", Arch: "amd64", AgentVersion: "1.0.0"}); offer == nil {
+			t.Fatalf("no offer to %s", id)
+		}
+	}
+
+	if err := f.updates.ReportResult("win-01", rollout.ID, false, "installer exited with 1603"); err != nil {
+		t.Fatalf("ReportResult: %v", err)
+	}
+	if err := f.updates.ReportResult("win-01", rollout.ID, true, ""); !errors.Is(err, usecase.ErrUpdateNotOffered) {
+		t.Fatalf("second ReportResult = %v, want ErrUpdateNotOffered", err)
+	}
+	if events := drain(f.sub); len(events) != 0 {
+		t.Fatalf("events below min results = %+v, want none", events)
+	}
+	if err := f.updates.ReportResult("win-02", rollout.ID, false, "disk full"); err != nil {
+		t.Fatalf("ReportResult: %v", err)
+	}
+
+	events := drain(f.sub)
+	if len(events) != 1 || events[0].Data["failed"] != 2 {
+		t.Fatalf("events = %+v, want one rollout pause after 2 failures", events)
+	}
+	if offer := f.updates.CheckHeartbeat("win-03"); offer != nil {
+		t.Fatalf("offer of a paused rollout = %+v, want none", offer)
+	}
+	rollouts, err := f.updates.ListRollouts(model.RolloutPaused, 10)
+	if err != nil || len(rollouts) != 1 || rollouts[0].PauseReason == "" || rollouts[0].Counts != (model.RolloutCounts{Offered: 1, Failed: 2}) {
+		t.Fatalf("ListRollouts(paused) = %+v, %v; want the rollout with 1 offered and 2 failed", rollouts, err)
+	}
+
+	if _, err := f.updates.UpdateRollout(rollout.ID, usecase.RolloutChange{Status: model.RolloutActive}); err != nil {
+		t.Fatalf("UpdateRollout(resume): %v", err)
+	}
+	if offer := f.updates.CheckHeartbeat("win-03"); offer == nil {
+		t.Fatal("no offer after resuming the rollout")
+	}
+}
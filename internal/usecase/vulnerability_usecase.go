@@ -0,0 +1,326 @@
+// internal/usecase/vulnerability_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/vuln"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// feedRecheckInterval is how often a server checks whether another replica
+// imported a newer feed.
+const feedRecheckInterval = 30 * time.Second
+
+// FeedImport summarizes an ImportFeed call.
+type FeedImport struct {
+	Vulnerabilities int
+	Products        int
+	AgentsEvaluated int
+	Findings        int
+}
+
+// FindingDetail is a finding with the advisory it comes from.
+type FindingDetail struct {
+	model.VulnerabilityFinding
+	Summary string
+	Aliases []string
+}
+
+// FleetVulnerability is a vulnerability with the number of agents it affects.
+type FleetVulnerability struct {
+	model.Vulnerability
+	AffectedAgents int64
+}
+
+// VulnerabilityUseCase matches installed applications against an offline
+// vulnerability feed. It is also an EventPublisher: an agent is re-evaluated
+// when its installed apps change and its findings are dropped when it is
+// decommissioned.
+type VulnerabilityUseCase interface {
+	EventPublisher
+	// LoadFeed reads the stored feed into memory; call it once at startup.
+	LoadFeed() error
+	// ImportFeed replaces the feed with data (see vuln.ParseFeed) and
+	// re-evaluates every agent.
+	ImportFeed(data []byte, format string) (*FeedImport, error)
+	// EvaluateAgent recomputes and stores the findings of one agent.
+	EvaluateAgent(agentID string) ([]model.VulnerabilityFinding, error)
+	// ListFindings returns findings of at least minSeverity. vulnerabilityID
+	// may be an alias, e.g. the CVE of a GHSA advisory.
+	ListFindings(agentID, vulnerabilityID, minSeverity string, limit int) ([]FindingDetail, error)
+	// ListFleetVulnerabilities returns the vulnerabilities found on at least
+	// one agent, most severe first.
+	ListFleetVulnerabilities(minSeverity string, limit int) ([]FleetVulnerability, error)
+}
+
+type vulnerabilityUseCase struct {
+	repo    repository.VulnerabilityRepository
+	agents  repository.AgentRepository
+	catalog SoftwareUseCase // catalog rules add product keys and version schemes
+
+	mu        sync.Mutex
+	matcher   *vuln.Matcher
+	loadedAt  time.Time // ImportedAt of the feed in matcher
+	checkedAt time.Time
+}
+
+// NewVulnerabilityUseCase creates a new instance of the vulnerability use case layer.
+func NewVulnerabilityUseCase(repo repository.VulnerabilityRepository, agents repository.AgentRepository, catalog SoftwareUseCase) VulnerabilityUseCase {
+	return &vulnerabilityUseCase{repo: repo, agents: agents, catalog: catalog, matcher: vuln.NewMatcher(nil)}
+}
+
+func (uc *vulnerabilityUseCase) LoadFeed() error {
+	vulns, err := uc.repo.ListVulnerabilities()
+	if err != nil {
+		return err
+	}
+	var importedAt time.Time
+	for _, v := range vulns {
+		if v.ImportedAt.After(importedAt) {
+			importedAt = v.ImportedAt
+		}
+	}
+
+	uc.mu.Lock()
+	uc.matcher = vuln.NewMatcher(vulns)
+	uc.loadedAt = importedAt
+	uc.checkedAt = time.Now()
+	uc.mu.Unlock()
+	if len(vulns) > 0 {
+		logging.Infof("Loaded vulnerability feed with %d entries (imported %s).", len(vulns), importedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// currentMatcher returns the in-memory feed, reloading it first when another
+// server imported a newer one.
+func (uc *vulnerabilityUseCase) currentMatcher() *vuln.Matcher {
+	uc.mu.Lock()
+	m, loadedAt, due := uc.matcher, uc.loadedAt, time.Since(uc.checkedAt) >= feedRecheckInterval
+	if due {
+		uc.checkedAt = time.Now()
+	}
+	uc.mu.Unlock()
+	if !due {
+		return m
+	}
+
+	latest, err := uc.repo.LatestVulnerabilityImport()
+	if err != nil {
+		logging.Warnf("Failed to check for a newer vulnerability feed: %v", err)
+		return m
+	}
+	if latest.Equal(loadedAt) {
+		return m
+	}
+	if err := uc.LoadFeed(); err != nil {
+		logging.Warnf("Failed to reload the vulnerability feed: %v", err)
+		return m
+	}
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	return uc.matcher
+}
+
+func (uc *vulnerabilityUseCase) ImportFeed(data []byte, format string) (*FeedImport, error) {
+	vulns, err := vuln.ParseFeed(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if len(vulns) == 0 {
+		return nil, fmt.Errorf("%w: no vulnerabilities with affected applications", vuln.ErrInvalidFeed)
+	}
+	importedAt := time.Now().UTC().Truncate(time.Millisecond)
+	result := &FeedImport{Vulnerabilities: len(vulns)}
+	products := map[string]bool{}
+	for i := range vulns {
+		vulns[i].ImportedAt = importedAt
+		for _, a := range vulns[i].Affected {
+			products[a.Product] = true
+		}
+	}
+	result.Products = len(products)
+
+	if err := uc.repo.ReplaceVulnerabilities(vulns); err != nil {
+		return nil, err
+	}
+	if err := uc.LoadFeed(); err != nil {
+		return nil, err
+	}
+	logging.Infof("Imported vulnerability feed: %d vulnerabilities for %d products.", result.Vulnerabilities, result.Products)
+
+	ids, err := uc.agents.ListAgentIDs()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		findings, err := uc.EvaluateAgent(id)
+		if err != nil {
+			logging.Errorf("Failed to evaluate agent %s against the new vulnerability feed: %v", id, err)
+			continue
+		}
+		result.AgentsEvaluated++
+		result.Findings += len(findings)
+	}
+	logging.Infof("Vulnerability feed matched %d findings on %d agents.", result.Findings, result.AgentsEvaluated)
+	return result, nil
+}
+
+func (uc *vulnerabilityUseCase) EvaluateAgent(agentID string) ([]model.VulnerabilityFinding, error) {
+	agent, err := uc.agents.FindAgentByID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if agent.Status == "DECOMMISSIONED" {
+		return nil, uc.repo.ReplaceAgentFindings(agentID, nil)
+	}
+	apps, err := uc.agents.FindCurrentInstalledApps(agent.ID)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := uc.repo.FindFindings(model.VulnerabilityFindingFilter{AgentID: agentID})
+	if err != nil {
+		return nil, err
+	}
+
+	key := func(vulnID, app, version string) string { return vulnID + "\x00" + app + "\x00" + version }
+	detectedAt := make(map[string]time.Time, len(previous))
+	for _, f := range previous {
+		detectedAt[key(f.VulnerabilityID, f.AppName, f.AppVersion)] = f.DetectedAt
+	}
+
+	now := time.Now().UTC()
+	matches := uc.currentMatcher().Match(apps, uc.catalog.Rules())
+	findings := make([]model.VulnerabilityFinding, 0, len(matches))
+	for _, m := range matches {
+		f := model.VulnerabilityFinding{
+			AgentID:         agentID,
+			VulnerabilityID: m.Vulnerability.VulnerabilityID,
+			Severity:        m.Vulnerability.Severity,
+			CVSSScore:       m.Vulnerability.CVSSScore,
+			Product:         m.Product,
+			AppName:         m.App.Name,
+			AppVersion:      m.App.Version,
+			FixedVersion:    m.FixedVersion,
+			DetectedAt:      now,
+		}
+		if t, ok := detectedAt[key(f.VulnerabilityID, f.AppName, f.AppVersion)]; ok {
+			f.DetectedAt = t
+		}
+		findings = append(findings, f)
+	}
+	if len(findings) == 0 && len(previous) == 0 {
+		return findings, nil
+	}
+	if err := uc.repo.ReplaceAgentFindings(agentID, findings); err != nil {
+		return nil, err
+	}
+	if len(findings) > len(previous) {
+		logging.Infof("Agent %s has %d vulnerable applications (was %d).", agentID, len(findings), len(previous))
+	}
+	return findings, nil
+}
+
+func (uc *vulnerabilityUseCase) ListFindings(agentID, vulnerabilityID, minSeverity string, limit int) ([]FindingDetail, error) {
+	m := uc.currentMatcher()
+	filter := model.VulnerabilityFindingFilter{AgentID: agentID, Severities: severitiesFrom(minSeverity), Limit: limit}
+	if vulnerabilityID != "" {
+		// Findings are stored under the feed's own ID.
+		if v, ok := m.Lookup(vulnerabilityID); ok {
+			vulnerabilityID = v.VulnerabilityID
+		}
+		filter.VulnerabilityIDs = []string{vulnerabilityID}
+	}
+	findings, err := uc.repo.FindFindings(filter)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]FindingDetail, len(findings))
+	for i, f := range findings {
+		details[i].VulnerabilityFinding = f
+		if v, ok := m.Lookup(f.VulnerabilityID); ok {
+			details[i].Summary = v.Summary
+			details[i].Aliases = v.AliasList()
+		}
+	}
+	return details, nil
+}
+
+func (uc *vulnerabilityUseCase) ListFleetVulnerabilities(minSeverity string, limit int) ([]FleetVulnerability, error) {
+	counts, err := uc.repo.CountAffectedAgents()
+	if err != nil {
+		return nil, err
+	}
+	m := uc.currentMatcher()
+	minRank := model.SeverityRank(strings.ToUpper(minSeverity))
+	var out []FleetVulnerability
+	for id, n := range counts {
+		v, ok := m.Lookup(id)
+		if !ok {
+			// Findings of a feed replaced since; they go away on re-evaluation.
+			continue
+		}
+		if model.SeverityRank(v.Severity) < minRank {
+			continue
+		}
+		out = append(out, FleetVulnerability{Vulnerability: *v, AffectedAgents: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if ra, rb := model.SeverityRank(a.Severity), model.SeverityRank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		if a.CVSSScore != b.CVSSScore {
+			return a.CVSSScore > b.CVSSScore
+		}
+		if a.AffectedAgents != b.AffectedAgents {
+			return a.AffectedAgents > b.AffectedAgents
+		}
+		return a.VulnerabilityID < b.VulnerabilityID
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// Publish re-evaluates an agent when its installed apps change, when it
+// comes back from decommissioning, and clears its findings when it is
+// decommissioned.
+func (uc *vulnerabilityUseCase) Publish(event model.Event) {
+	switch event.Type {
+	case model.EventInventoryChanged:
+		if event.Data["inventory"] != "installed_apps" {
+			return
+		}
+	case model.EventAgentStatusChanged:
+		if event.Data["from"] != "DECOMMISSIONED" && event.Data["to"] != "DECOMMISSIONED" {
+			return
+		}
+	default:
+		return
+	}
+	if event.Type == model.EventInventoryChanged && uc.currentMatcher().Len() == 0 {
+		return
+	}
+	if _, err := uc.EvaluateAgent(event.AgentID); err != nil {
+		logging.Errorf("Failed to evaluate vulnerabilities of agent %s: %v", event.AgentID, err)
+	}
+}
+
+// severitiesFrom returns minSeverity and every severity above it, or nil
+// for all severities.
+func severitiesFrom(minSeverity string) []string {
+	rank := model.SeverityRank(strings.ToUpper(minSeverity))
+	if rank == 0 {
+		return nil
+	}
+	return append([]string(nil), model.Severities[rank:]...)
+}
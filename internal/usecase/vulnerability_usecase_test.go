@@ -0,0 +1,212 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"agent_server/internal/vuln"
+	"errors"
+	"testing"
+)
+
+// osvFeed has a range fixed in 1.6.1 (aliased to a CVE) and a range
+// affected up to 3.0.7 inclusive.
+const osvFeed = `[
+  {
+    "id": "GHSA-0001", "aliases": ["CVE-2023-4863"], "summary": "Heap overflow in libwebp",
+    "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:U/C:H/I:H/A:H"}],
+    "affected": [{"package": {"ecosystem": "Debian", "name": "libwebp"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.6.1"}]}]}]
+  },
+  {
+    "id": "OSV-0002", "summary": "OpenSSL issue", "database_specific": {"severity": "moderate"},
+    "affected": [{"package": {"name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "3.0.0"}, {"last_affected": "3.0.7"}]}]}]
+  }
+]`
+
+// nvdFeed affects Firefox before 118.0.2 and exactly Chrome 116.0.5845.187.
+const nvdFeed = `{
+  "format": "NVD_CVE",
+  "vulnerabilities": [{"cve": {
+    "id": "CVE-2023-5217",
+    "published": "2023-09-28T16:15:10.980",
+    "descriptions": [{"lang": "en", "value": "Heap buffer overflow in vp8 encoding in libvpx."}],
+    "metrics": {"cvssMetricV31": [{"cvssData": {"baseScore": 8.8, "baseSeverity": "HIGH"}}]},
+    "configurations": [{"nodes": [{"cpeMatch": [
+      {"vulnerable": true, "criteria": "cpe:2.3:a:mozilla:firefox:*:*:*:*:*:*:*:*", "versionEndExcluding": "118.0.2"},
+      {"vulnerable": true, "criteria": "cpe:2.3:a:google:chrome:116.0.5845.187:*:*:*:*:*:*:*"},
+      {"vulnerable": true, "criteria": "cpe:2.3:o:microsoft:windows_10:-:*:*:*:*:*:*:*"}
+    ]}]}]
+  }}]
+}`
+
+func newVulnerabilityFixture(t *testing.T) (usecase.AgentUseCase, usecase.VulnerabilityUseCase) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	vulns := usecase.NewVulnerabilityUseCase(store.Vulnerabilities, store.Agents, usecase.NewSoftwareUseCase(store.Agents))
+	bus := usecase.NewEventBus(store.Agents, config.StaticProvider(config.Default()), nil, vulns)
+	agents := usecase.NewAgentUseCase(store.Agents, nil, bus)
+	for _, id := range []string{"web-01", "web-02"} {
+		if _, err := agents.RegisterAgent(&model.Agent{AgentID: id}); err != nil {
+			t.Fatalf("RegisterAgent: %v", err)
+		}
+	}
+	return agents, vulns
+}
+
+func reportApps(t *testing.T, agents usecase.AgentUseCase, agentID string, apps ...model.InstalledApplication) {
+	t.Helper()
+	if err := agents.StoreInstalledApps(agentID, apps); err != nil {
+		t.Fatalf("StoreInstalledApps: %v", err)
+	}
+}
+
+func findingIDs(t *testing.T, vulns usecase.VulnerabilityUseCase, agentID string) map[string]usecase.FindingDetail {
+	t.Helper()
+	findings, err := vulns.ListFindings(agentID, "", "", 0)
+	if err != nil {
+		t.Fatalf("ListFindings: %v", err)
+	}
+	byID := map[string]usecase.FindingDetail{}
+	for _, f := range findings {
+		byID[f.VulnerabilityID] = f
+	}
+	return byID
+}
+
+func TestVulnerabilityImportMatchesInstalledApps(t *testing.T) {
+	agents, vulns := newVulnerabilityFixture(t)
+	reportApps(t, agents, "web-01",
+		model.InstalledApplication{Name: "libwebp", Version: "1.5.0"},
+		model.InstalledApplication{Name: "openssl", Version: "3.0.7"},
+		model.InstalledApplication{Name: "Mozilla Firefox 118.0 (x64 en-US)", Publisher: "Mozilla", Version: "118.0"},
+		model.InstalledApplication{Name: "Google Chrome", Publisher: "Google LLC", Version: "116.0.5845.187"},
+	)
+	reportApps(t, agents, "web-02",
+		model.InstalledApplication{Name: "libwebp", Version: "1.6.1"},
+		model.InstalledApplication{Name: "openssl", Version: "3.0.8"},
+		model.InstalledApplication{Name: "Mozilla Firefox", Publisher: "Mozilla Corporation", Version: "118.0.2"},
+	)
+
+	for _, feed := range []string{osvFeed, nvdFeed} {
+		if _, err := vulns.ImportFeed([]byte(feed), vuln.FormatAuto); err != nil {
+			t.Fatalf("ImportFeed: %v", err)
+		}
+		// Importing the NVD feed replaces the OSV one.
+		if feed == nvdFeed {
+			if got := findingIDs(t, vulns, "web-01"); len(got) != 1 {
+				t.Fatalf("after the NVD import web-01 has findings %v, want CVE-2023-5217 only", got)
+			}
+		}
+	}
+
+	if _, err := vulns.ImportFeed([]byte(osvFeed), vuln.FormatOSV); err != nil {
+		t.Fatalf("ImportFeed: %v", err)
+	}
+	got := findingIDs(t, vulns, "web-01")
+	if len(got) != 2 || got["GHSA-0001"].FixedVersion != "1.6.1" || got["OSV-0002"].Severity != model.SeverityMedium {
+		t.Fatalf("web-01 findings = %+v, want GHSA-0001 fixed in 1.6.1 and OSV-0002 (MEDIUM)", got)
+	}
+	if f := got["GHSA-0001"]; f.CVSSScore != 8.8 || f.Severity != model.SeverityHigh || f.Aliases[0] != "CVE-2023-4863" {
+		t.Fatalf("GHSA-0001 finding = %+v, want CVSS 8.8 HIGH aliased to CVE-2023-4863", f)
+	}
+	if got := findingIDs(t, vulns, "web-02"); len(got) != 0 {
+		t.Fatalf("web-02 runs fixed versions but has findings %v", got)
+	}
+
+	// "Which hosts have CVE-X", by alias.
+	hosts, err := vulns.ListFindings("", "cve-2023-4863", "", 0)
+	if err != nil || len(hosts) != 1 || hosts[0].AgentID != "web-01" {
+		t.Fatalf("ListFindings(CVE-2023-4863) = %+v, %v; want web-01", hosts, err)
+	}
+	if high, _ := vulns.ListFindings("", "", model.SeverityHigh, 0); len(high) != 1 {
+		t.Fatalf("got %d findings of at least HIGH, want 1", len(high))
+	}
+}
+
+func TestVulnerabilityNVDRanges(t *testing.T) {
+	agents, vulns := newVulnerabilityFixture(t)
+	reportApps(t, agents, "web-01",
+		model.InstalledApplication{Name: "Mozilla Firefox 118.0 (x64 en-US)", Publisher: "Mozilla", Version: "118.0"},
+		model.InstalledApplication{Name: "Google Chrome", Publisher: "Google LLC", Version: "116.0.5845.187"},
+	)
+	reportApps(t, agents, "web-02",
+		model.InstalledApplication{Name: "Mozilla Firefox", Publisher: "Mozilla Corporation", Version: "118.0.2"},
+		model.InstalledApplication{Name: "Google Chrome", Publisher: "Google LLC", Version: "116.0.5845.188"},
+	)
+	result, err := vulns.ImportFeed([]byte(nvdFeed), vuln.FormatNVD)
+	if err != nil {
+		t.Fatalf("ImportFeed: %v", err)
+	}
+	// The Windows CPE is an operating system and is not indexed.
+	if result.Vulnerabilities != 1 || result.Products != 2 || result.AgentsEvaluated != 2 || result.Findings != 2 {
+		t.Fatalf("ImportFeed = %+v, want 1 vulnerability, 2 products, 2 agents, 2 findings", result)
+	}
+
+	findings, _ := vulns.ListFindings("web-01", "", "", 0)
+	products := map[string]string{}
+	for _, f := range findings {
+		products[f.Product] = f.FixedVersion
+	}
+	if fixed, ok := products["mozilla:firefox"]; !ok || fixed != "118.0.2" {
+		t.Fatalf("web-01 findings by product = %v, want mozilla:firefox fixed in 118.0.2", products)
+	}
+	if _, ok := products["google:chrome"]; !ok {
+		t.Fatalf("web-01 findings by product = %v, want the exact Chrome version", products)
+	}
+
+	fleet, err := vulns.ListFleetVulnerabilities("", 0)
+	if err != nil || len(fleet) != 1 || fleet[0].AffectedAgents != 1 || fleet[0].Summary == "" {
+		t.Fatalf("ListFleetVulnerabilities = %+v, %v; want CVE-2023-5217 on one agent", fleet, err)
+	}
+}
+
+func TestVulnerabilityReevaluatedOnInventoryChange(t *testing.T) {
+	agents, vulns := newVulnerabilityFixture(t)
+	if _, err := vulns.ImportFeed([]byte(osvFeed), ""); err != nil {
+		t.Fatalf("ImportFeed: %v", err)
+	}
+
+	reportApps(t, agents, "web-01", model.InstalledApplication{Name: "libwebp", Version: "1.5.0"})
+	first := findingIDs(t, vulns, "web-01")["GHSA-0001"]
+	if first.DetectedAt.IsZero() {
+		t.Fatal("vulnerable report did not produce a finding")
+	}
+
+	// Another change keeps the first detection time of unchanged findings.
+	reportApps(t, agents, "web-01",
+		model.InstalledApplication{Name: "libwebp", Version: "1.5.0"},
+		model.InstalledApplication{Name: "openssl", Version: "3.0.1"},
+	)
+	got := findingIDs(t, vulns, "web-01")
+	if len(got) != 2 || !got["GHSA-0001"].DetectedAt.Equal(first.DetectedAt) {
+		t.Fatalf("after a second report findings = %+v, want 2 with GHSA-0001 detected at %v", got, first.DetectedAt)
+	}
+
+	reportApps(t, agents, "web-01", model.InstalledApplication{Name: "libwebp", Version: "1.6.1"})
+	if got := findingIDs(t, vulns, "web-01"); len(got) != 0 {
+		t.Fatalf("after upgrading findings = %v, want none", got)
+	}
+
+	reportApps(t, agents, "web-02", model.InstalledApplication{Name: "libwebp", Version: "1.0"})
+	if _, err := agents.DecommissionAgent("web-02"); err != nil {
+		t.Fatalf("DecommissionAgent: %v", err)
+	}
+	if got := findingIDs(t, vulns, "web-02"); len(got) != 0 {
+		t.Fatalf("decommissioned agent still has findings %v", got)
+	}
+}
+
+func TestVulnerabilityImportRejectsInvalidFeed(t *testing.T) {
+	_, vulns := newVulnerabilityFixture(t)
+	for _, feed := range []string{`not json`, `[]`, `{"id": "OSV-1", "affected": []}`} {
+		if _, err := vulns.ImportFeed([]byte(feed), ""); !errors.Is(err, vuln.ErrInvalidFeed) {
+			t.Errorf("ImportFeed(%s) = %v, want ErrInvalidFeed", feed, err)
+		}
+	}
+	if _, err := vulns.ImportFeed([]byte(osvFeed), "csv"); !errors.Is(err, vuln.ErrInvalidFeed) {
+		t.Errorf("ImportFeed with format csv = %v, want ErrInvalidFeed", err)
+	}
+}
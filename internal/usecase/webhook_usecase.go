@@ -0,0 +1,303 @@
+// internal/usecase/webhook_usecase.go
+
+package usecase
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/webhook"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mrand "math/rand/v2"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidSubscription is returned for a subscription with a bad URL or
+// unknown event types.
+var ErrInvalidSubscription = errors.New("invalid webhook subscription")
+
+// EventTypes lists the events that webhooks can subscribe to: every stream
+// event except heartbeats, which are too frequent to deliver one by one.
+var EventTypes = []string{
+	model.EventAgentRegistered,
+	model.EventAgentUpdated,
+	model.EventAgentStatusChanged,
+	model.EventAgentOffline,
+	model.EventFirewallChanged,
+	model.EventInventoryChanged,
+	model.EventCommandStateChanged,
+	model.EventCommandFailed,
+	model.EventCommandApproval,
+	model.EventAgentCloneSuspected,
+	model.EventAlertFiring,
+	model.EventAlertResolved,
+	model.EventRolloutPaused,
+	model.EventFileCollected,
+	model.EventFileDownloaded,
+	model.EventJobRunFinished,
+}
+
+// WebhookSender delivers one signed webhook request; webhook.Client implements it.
+type WebhookSender interface {
+	Send(ctx context.Context, r webhook.Request) (int, error)
+}
+
+// WebhookUseCase manages webhook subscriptions and the delivery outbox. It is
+// also an EventPublisher: every published event is written to the outbox once
+// per matching subscription and delivered by DispatchDue.
+type WebhookUseCase interface {
+	EventPublisher
+	CreateSubscription(sub *model.WebhookSubscription) (*model.WebhookSubscription, error)
+	ListSubscriptions() ([]model.WebhookSubscription, error)
+	DeleteSubscription(id uint) error
+	ListDeliveries(filter model.WebhookDeliveryFilter) ([]model.WebhookDelivery, error)
+	// ReplayDeliveries re-queues the given deliveries, or all DEAD deliveries
+	// of subscriptionID when ids is empty. It returns how many were re-queued.
+	ReplayDeliveries(ids []uint, subscriptionID uint) (int64, error)
+	// DispatchDue sends one batch of due deliveries and returns how many were attempted.
+	DispatchDue(ctx context.Context) (int, error)
+}
+
+type webhookUseCase struct {
+	repo   repository.WebhookRepository
+	sender WebhookSender
+	cfg    config.Provider
+}
+
+// NewWebhookUseCase creates a new instance of the webhook use case layer.
+func NewWebhookUseCase(repo repository.WebhookRepository, sender WebhookSender, cfg config.Provider) WebhookUseCase {
+	return &webhookUseCase{repo: repo, sender: sender, cfg: cfg}
+}
+
+// CreateSubscription validates and stores a subscription. A random secret is
+// generated when none is given.
+func (uc *webhookUseCase) CreateSubscription(sub *model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+
+	var types []string
+	for _, t := range strings.Split(sub.EventTypes, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !isKnownEventType(t) {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, t)
+		}
+		types = append(types, t)
+	}
+	sub.EventTypes = strings.Join(types, ",")
+
+	if sub.Secret == "" {
+		var b [32]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		sub.Secret = hex.EncodeToString(b[:])
+	}
+	sub.Enabled = true
+
+	if err := uc.repo.CreateSubscription(sub); err != nil {
+		return nil, err
+	}
+	logging.Infof("Webhook subscription %d created for %s (events: %s).", sub.ID, sub.URL, orAll(sub.EventTypes))
+	return sub, nil
+}
+
+func (uc *webhookUseCase) ListSubscriptions() ([]model.WebhookSubscription, error) {
+	return uc.repo.ListSubscriptions()
+}
+
+func (uc *webhookUseCase) DeleteSubscription(id uint) error {
+	if err := uc.repo.DeleteSubscription(id); err != nil {
+		return err
+	}
+	logging.Infof("Webhook subscription %d deleted.", id)
+	return nil
+}
+
+func (uc *webhookUseCase) ListDeliveries(filter model.WebhookDeliveryFilter) ([]model.WebhookDelivery, error) {
+	return uc.repo.FindDeliveries(filter)
+}
+
+func (uc *webhookUseCase) ReplayDeliveries(ids []uint, subscriptionID uint) (int64, error) {
+	if len(ids) == 0 {
+		if subscriptionID == 0 {
+			return 0, fmt.Errorf("%w: give delivery IDs or a subscription", ErrInvalidSubscription)
+		}
+		dead, err := uc.repo.FindDeliveries(model.WebhookDeliveryFilter{SubscriptionID: subscriptionID, Status: model.WebhookDead})
+		if err != nil {
+			return 0, err
+		}
+		for _, d := range dead {
+			ids = append(ids, d.ID)
+		}
+	}
+	n, err := uc.repo.ResetDeliveries(ids)
+	if err != nil {
+		return 0, err
+	}
+	logging.Infof("Re-queued %d webhook deliveries.", n)
+	return n, nil
+}
+
+// Publish writes the event to the outbox for every enabled subscription
+// that wants it. Errors are logged; the event is lost only if the outbox
+// write itself fails.
+func (uc *webhookUseCase) Publish(event model.Event) {
+	if !uc.cfg.Current().Webhooks.Enabled || !isKnownEventType(event.Type) {
+		return
+	}
+	subs, err := uc.repo.ListSubscriptions()
+	if err != nil {
+		logging.Errorf("Failed to load webhook subscriptions for event %s: %v", event.Type, err)
+		return
+	}
+
+	var body []byte
+	var deliveries []model.WebhookDelivery
+	for _, sub := range subs {
+		if !sub.Enabled || !sub.Matches(event.Type) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(event); err != nil {
+				logging.Errorf("Failed to encode event %s: %v", event.Type, err)
+				return
+			}
+		}
+		deliveries = append(deliveries, model.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			AgentID:        event.AgentID,
+			Payload:        body,
+			Status:         model.WebhookPending,
+			NextAttemptAt:  event.OccurredAt,
+		})
+	}
+	if err := uc.repo.EnqueueDeliveries(deliveries); err != nil {
+		logging.Errorf("Failed to queue %d webhook deliveries for event %s: %v", len(deliveries), event.Type, err)
+	}
+}
+
+// DispatchDue claims a batch of due deliveries and sends them in parallel.
+func (uc *webhookUseCase) DispatchDue(ctx context.Context) (int, error) {
+	cfg := uc.cfg.Current().Webhooks
+	// The lease must outlast one attempt so a delivery is never sent twice at once.
+	lease := 2*cfg.Timeout() + time.Minute
+	due, err := uc.repo.ClaimDueDeliveries(time.Now(), lease, cfg.BatchSize)
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+
+	subs := make(map[uint]*model.WebhookSubscription)
+	for _, d := range due {
+		if _, ok := subs[d.SubscriptionID]; ok {
+			continue
+		}
+		sub, err := uc.repo.FindSubscriptionByID(d.SubscriptionID)
+		if err != nil {
+			sub = nil // deleted since the event was queued
+		}
+		subs[d.SubscriptionID] = sub
+	}
+
+	var wg sync.WaitGroup
+	for i := range due {
+		wg.Add(1)
+		go func(d *model.WebhookDelivery) {
+			defer wg.Done()
+			uc.attempt(ctx, cfg, subs[d.SubscriptionID], d)
+		}(&due[i])
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+func (uc *webhookUseCase) attempt(ctx context.Context, cfg config.WebhookConfig, sub *model.WebhookSubscription, d *model.WebhookDelivery) {
+	d.Attempts++
+	var err error
+	switch {
+	case sub == nil:
+		err = errors.New("subscription no longer exists")
+		d.Attempts = cfg.MaxAttempts
+	case !sub.Enabled:
+		err = errors.New("subscription is disabled")
+		d.Attempts = cfg.MaxAttempts
+	default:
+		reqCtx, cancel := context.WithTimeout(ctx, cfg.Timeout())
+		d.LastStatusCode, err = uc.sender.Send(reqCtx, webhook.Request{
+			URL:        sub.URL,
+			Secret:     sub.Secret,
+			EventType:  d.EventType,
+			EventID:    d.EventID,
+			DeliveryID: d.ID,
+			Body:       d.Payload,
+		})
+		cancel()
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		d.Status = model.WebhookDelivered
+		d.DeliveredAt = &now
+		d.LastError = ""
+	case d.Attempts >= cfg.MaxAttempts:
+		d.Status = model.WebhookDead
+		d.LastError = err.Error()
+		logging.Warnf("Webhook delivery %d (%s) is dead after %d attempts: %v", d.ID, d.EventType, d.Attempts, err)
+	default:
+		d.NextAttemptAt = now.Add(backoff(cfg, d.Attempts))
+		d.LastError = err.Error()
+		logging.Debugf("Webhook delivery %d attempt %d failed, retrying at %v: %v", d.ID, d.Attempts, d.NextAttemptAt, err)
+	}
+
+	if err := uc.repo.SaveDeliveryAttempt(d); err != nil {
+		logging.Errorf("Failed to save webhook delivery %d: %v", d.ID, err)
+	}
+}
+
+// backoff doubles the wait after every failed attempt, capped at the maximum,
+// and randomizes the second half so retries from an outage spread out.
+func backoff(cfg config.WebhookConfig, attempts int) time.Duration {
+	d := cfg.InitialBackoff()
+	for i := 1; i < attempts && d < cfg.MaxBackoff(); i++ {
+		d *= 2
+	}
+	if d > cfg.MaxBackoff() {
+		d = cfg.MaxBackoff()
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + mrand.N(d/2+1)
+}
+
+func isKnownEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+func orAll(types string) string {
+	if types == "" {
+		return "all"
+	}
+	return types
+}
@@ -0,0 +1,351 @@
+package usecase_test
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/model"
+	"agent_server/internal/repository"
+	"agent_server/internal/usecase"
+	"agent_server/internal/webhook"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver is a webhook endpoint that checks signatures and records events.
+type receiver struct {
+	t      *testing.T
+	secret string
+
+	mu     sync.Mutex
+	status int
+	events []model.Event
+	ids    []string
+}
+
+func newReceiver(t *testing.T, secret string) (*receiver, *httptest.Server) {
+	r := &receiver{t: t, secret: secret, status: http.StatusNoContent}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return r, srv
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if err := webhook.Verify(r.secret, req.Header.Get(webhook.HeaderSignature), body, time.Minute); err != nil {
+		r.t.Errorf("bad signature on %s: %v", req.Header.Get(webhook.HeaderEvent), err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var ev model.Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		r.t.Errorf("bad body %q: %v", body, err)
+	}
+	if got := req.Header.Get(webhook.HeaderEvent); got != ev.Type {
+		r.t.Errorf("%s header = %q, body type %q", webhook.HeaderEvent, got, ev.Type)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = append(r.ids, req.Header.Get(webhook.HeaderEventID))
+	if r.status < 300 {
+		r.events = append(r.events, ev)
+	}
+	w.WriteHeader(r.status)
+}
+
+func (r *receiver) setStatus(code int) {
+	r.mu.Lock()
+	r.status = code
+	r.mu.Unlock()
+}
+
+func (r *receiver) received() ([]model.Event, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.Event(nil), r.events...), append([]string(nil), r.ids...)
+}
+
+func newWebhookTestSetup(t *testing.T, maxAttempts int) (*repository.Store, usecase.WebhookUseCase) {
+	cfg := config.Default()
+	cfg.Webhooks.Enabled = true
+	cfg.Webhooks.MaxAttempts = maxAttempts
+	// No backoff so that failed deliveries are due again immediately.
+	cfg.Webhooks.InitialBackoffSeconds = 0
+	cfg.Webhooks.MaxBackoffSeconds = 0
+	store := repository.NewMemoryStore()
+	return store, usecase.NewWebhookUseCase(store.Webhooks, webhook.NewClient(), config.StaticProvider(cfg))
+}
+
+func subscribe(t *testing.T, hooks usecase.WebhookUseCase, url, events string) *model.WebhookSubscription {
+	t.Helper()
+	sub, err := hooks.CreateSubscription(&model.WebhookSubscription{URL: url, Secret: "s3cret", EventTypes: events})
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	return sub
+}
+
+func dispatch(t *testing.T, hooks usecase.WebhookUseCase) int {
+	t.Helper()
+	n, err := hooks.DispatchDue(context.Background())
+	if err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	return n
+}
+
+func TestWebhookDeliversSignedEvents(t *testing.T) {
+	store, hooks := newWebhookTestSetup(t, 3)
+	recv, srv := newReceiver(t, "s3cret")
+	subscribe(t, hooks, srv.URL, "agent.offline,agent.registered")
+
+	agents := usecase.NewAgentUseCase(store.Agents, nil, hooks)
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "agent-1", Hostname: "host-1"}); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := agents.MarkOfflineAgents(time.Millisecond); err != nil {
+		t.Fatalf("MarkOfflineAgents: %v", err)
+	}
+
+	if n := dispatch(t, hooks); n != 2 {
+		t.Fatalf("DispatchDue attempted %d deliveries, want 2", n)
+	}
+	events, _ := recv.received()
+	// Deliveries are sent in parallel; receivers order events by occurred_at.
+	sort.Slice(events, func(i, j int) bool { return events[i].OccurredAt.Before(events[j].OccurredAt) })
+	if len(events) != 2 || events[0].Type != model.EventAgentRegistered || events[1].Type != model.EventAgentOffline {
+		t.Fatalf("received %+v, want agent.registered then agent.offline", events)
+	}
+	if events[1].AgentID != "agent-1" || events[1].Data["hostname"] != "host-1" {
+		t.Fatalf("offline event = %+v", events[1])
+	}
+	if n := dispatch(t, hooks); n != 0 {
+		t.Fatalf("delivered events were sent again (%d)", n)
+	}
+}
+
+func TestWebhookRetriesThenDeadLettersAndReplays(t *testing.T) {
+	store, hooks := newWebhookTestSetup(t, 3)
+	recv, srv := newReceiver(t, "s3cret")
+	recv.setStatus(http.StatusInternalServerError)
+	sub := subscribe(t, hooks, srv.URL, model.EventCommandFailed)
+
+	commands := usecase.NewCommandUseCase(store.Agents, store.Commands, hooks)
+	if err := store.Agents.CreateAgent(&model.Agent{AgentID: "agent-1", Status: "ONLINE"}); err != nil {
+		t.Fatalf("CreateAgent: %v", err)
+	}
+	cmd, err := commands.EnqueueCommand("agent-1", model.CommandTypeFirewallConfiguration, nil)
+	if err != nil {
+		t.Fatalf("EnqueueCommand: %v", err)
+	}
+	if err := commands.CompleteCommand("agent-1", cmd.ID, false, "access denied", nil); err != nil {
+		t.Fatalf("CompleteCommand: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if n := dispatch(t, hooks); n != 1 {
+			t.Fatalf("attempt %d: DispatchDue attempted %d deliveries, want 1", i+1, n)
+		}
+	}
+	if n := dispatch(t, hooks); n != 0 {
+		t.Fatalf("dead delivery was attempted again")
+	}
+
+	dead, err := hooks.ListDeliveries(model.WebhookDeliveryFilter{Status: model.WebhookDead})
+	if err != nil {
+		t.Fatalf("ListDeliveries: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 3 || dead[0].LastStatusCode != http.StatusInternalServerError || dead[0].EventType != model.EventCommandFailed {
+		t.Fatalf("dead deliveries = %+v", dead)
+	}
+
+	recv.setStatus(http.StatusOK)
+	if n, err := hooks.ReplayDeliveries(nil, sub.ID); err != nil || n != 1 {
+		t.Fatalf("ReplayDeliveries = %d, %v; want 1, nil", n, err)
+	}
+	dispatch(t, hooks)
+
+	events, ids := recv.received()
+	if len(events) != 1 || events[0].Data["message"] != "access denied" {
+		t.Fatalf("received %+v after replay", events)
+	}
+	for _, id := range ids {
+		if id != events[0].ID {
+			t.Fatalf("event ID changed between attempts: %v", ids)
+		}
+	}
+	delivered, _ := hooks.ListDeliveries(model.WebhookDeliveryFilter{Status: model.WebhookDelivered})
+	if len(delivered) != 1 || delivered[0].DeliveredAt == nil {
+		t.Fatalf("delivered = %+v", delivered)
+	}
+}
+
+func TestWebhookFirewallChangedOnlyOnDifference(t *testing.T) {
+	store, hooks := newWebhookTestSetup(t, 3)
+	recv, srv := newReceiver(t, "s3cret")
+	subscribe(t, hooks, srv.URL, "firewall.changed")
+
+	agents := usecase.NewAgentUseCase(store.Agents, nil, hooks)
+	if _, err := agents.RegisterAgent(&model.Agent{AgentID: "agent-1"}); err != nil {
+		t.Fatalf("RegisterAgent: %v", err)
+	}
+	report := func(rules ...model.FirewallRule) {
+		t.Helper()
+		time.Sleep(2 * time.Millisecond) // distinct ReportedAt per report
+		if err := agents.StoreFirewallRules("agent-1", rules); err != nil {
+			t.Fatalf("StoreFirewallRules: %v", err)
+		}
+	}
+	http80 := model.FirewallRule{Name: "Allow HTTP", Port: "80", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", Enabled: true}
+	ssh := model.FirewallRule{Name: "Allow SSH", Port: "22", Protocol: "TCP", Action: "ALLOW", Direction: "DIRECTION_IN", Enabled: true}
+
+	report(http80)      // baseline
+	report(http80)      // unchanged
+	report(http80, ssh) // ssh added
+	dispatch(t, hooks)
+
+	events, _ := recv.received()
+	if len(events) != 1 {
+		t.Fatalf("received %d firewall.changed events, want 1: %+v", len(events), events)
+	}
+	added, _ := events[0].Data["added"].([]interface{})
+	if len(added) != 1 || events[0].Data["removed"] != nil {
+		t.Fatalf("firewall.changed data = %+v", events[0].Data)
+	}
+}
+
+func TestWebhookRejectsInvalidSubscription(t *testing.T) {
+	_, hooks := newWebhookTestSetup(t, 3)
+	for _, sub := range []model.WebhookSubscription{
+		{URL: "ftp://example.test/hook"},
+		{URL: "/relative"},
+		{URL: "https://example.test/hook", EventTypes: "agent.exploded"},
+	} {
+		if _, err := hooks.CreateSubscription(&sub); err == nil {
+			t.Errorf("CreateSubscription(%+v) succeeded, want error", sub)
+		}
+	}
+
+	sub, err := hooks.CreateSubscription(&model.WebhookSubscription{URL: "https://example.test/hook"})
+	if err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if len(sub.Secret) != 64 {
+		t.Fatalf("generated secret %q, want 32 random bytes in hex", sub.Secret)
+	}
+}
+
+// queueFailedCommand makes hooks queue one command.failed delivery to url.
+func queueFailedCommand(t *testing.T, store *repository.Store, hooks usecase.WebhookUseCase, url string) {
+	t.Helper()
+	subscribe(t, hooks, url, model.EventCommandFailed)
+	commands := usecase.NewCommandUseCase(store.Agents, store.Commands, hooks)
+	if err := store.Agents.CreateAgent(&model.Agent{AgentID: "agent-1", Status: "ONLINE"}); err != nil {
+		t.Fatalf("CreateAgent: %v", err)
+	}
+	cmd, err := commands.EnqueueCommand("agent-1", model.CommandTypeFirewallConfiguration, nil)
+	if err != nil {
+		t.Fatalf("EnqueueCommand: %v", err)
+	}
+	if err := commands.CompleteCommand("agent-1", cmd.ID, false, "access denied", nil); err != nil {
+		t.Fatalf("CompleteCommand: %v", err)
+	}
+}
+
+func pendingDelivery(t *testing.T, hooks usecase.WebhookUseCase) model.WebhookDelivery {
+	t.Helper()
+	pending, err := hooks.ListDeliveries(model.WebhookDeliveryFilter{Status: model.WebhookPending})
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending deliveries = %+v, %v; want one", pending, err)
+	}
+	return pending[0]
+}
+
+func TestWebhookBackoffDoublesUpToTheMaximum(t *testing.T) {
+	cfg := config.Default()
+	cfg.Webhooks.Enabled = true
+	cfg.Webhooks.MaxAttempts = 10
+	cfg.Webhooks.InitialBackoffSeconds = 10
+	cfg.Webhooks.MaxBackoffSeconds = 60
+	store := repository.NewMemoryStore()
+	hooks := usecase.NewWebhookUseCase(store.Webhooks, webhook.NewClient(), config.StaticProvider(cfg))
+	recv, srv := newReceiver(t, "s3cret")
+	recv.setStatus(http.StatusBadGateway)
+	queueFailedCommand(t, store, hooks, srv.URL)
+
+	// Each retry waits a random time in the second half of a window that
+	// doubles from the initial backoff and stops at the maximum.
+	for attempt, window := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 60 * time.Second, 60 * time.Second} {
+		before := time.Now()
+		if n := dispatch(t, hooks); n != 1 {
+			t.Fatalf("attempt %d: DispatchDue attempted %d deliveries, want 1", attempt+1, n)
+		}
+		d := pendingDelivery(t, hooks)
+		wait := d.NextAttemptAt.Sub(before)
+		if d.Attempts != attempt+1 || wait < window/2 || wait > window+time.Second {
+			t.Fatalf("attempt %d: retry in %v, want between %v and %v", d.Attempts, wait, window/2, window)
+		}
+		// Make the delivery due again without waiting for the backoff.
+		d.NextAttemptAt = time.Now()
+		if err := store.Webhooks.SaveDeliveryAttempt(&d); err != nil {
+			t.Fatalf("SaveDeliveryAttempt: %v", err)
+		}
+	}
+}
+
+// blockingSender holds every request until release is closed.
+type blockingSender struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSender) Send(ctx context.Context, r webhook.Request) (int, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return http.StatusOK, nil
+}
+
+func TestWebhookDispatchLeasesClaimedDeliveries(t *testing.T) {
+	cfg := config.Default()
+	cfg.Webhooks.Enabled = true
+	cfg.Webhooks.TimeoutSeconds = 5
+	store := repository.NewMemoryStore()
+	sender := &blockingSender{started: make(chan struct{}, 1), release: make(chan struct{})}
+	hooks := usecase.NewWebhookUseCase(store.Webhooks, sender, config.StaticProvider(cfg))
+	queueFailedCommand(t, store, hooks, "https://example.test/hook")
+
+	claimed := time.Now()
+	done := make(chan int)
+	go func() {
+		n, _ := hooks.DispatchDue(context.Background())
+		done <- n
+	}()
+	<-sender.started
+
+	// While the first attempt is in flight, the delivery is leased for two
+	// request timeouts plus a minute, so another dispatcher cannot send it
+	// at the same time.
+	d := pendingDelivery(t, hooks)
+	if lease := d.NextAttemptAt.Sub(claimed); lease < 2*cfg.Webhooks.Timeout()+time.Minute-time.Second || lease > 2*cfg.Webhooks.Timeout()+time.Minute+time.Second {
+		t.Fatalf("delivery leased for %v, want %v", lease, 2*cfg.Webhooks.Timeout()+time.Minute)
+	}
+	if n := dispatch(t, hooks); n != 0 {
+		t.Fatalf("second DispatchDue claimed %d leased deliveries, want 0", n)
+	}
+
+	close(sender.release)
+	if n := <-done; n != 1 {
+		t.Fatalf("first DispatchDue attempted %d deliveries, want 1", n)
+	}
+	delivered, _ := hooks.ListDeliveries(model.WebhookDeliveryFilter{Status: model.WebhookDelivered})
+	if len(delivered) != 1 || delivered[0].Attempts != 1 {
+		t.Fatalf("delivered = %+v, want one delivery sent once", delivered)
+	}
+}
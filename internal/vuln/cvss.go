@@ -0,0 +1,80 @@
+// internal/vuln/cvss.go
+
+package vuln
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// cvss3Weights holds the metric values of the CVSS v3.1 specification.
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// CVSS3BaseScore computes the base score of a CVSS v3.0/v3.1 vector such
+// as "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H" (9.8).
+func CVSS3BaseScore(vector string) (float64, error) {
+	parts := strings.Split(vector, "/")
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "CVSS:3") {
+		return 0, fmt.Errorf("not a CVSS v3 vector: %q", vector)
+	}
+	values := map[string]string{}
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, ":"); ok {
+			values[k] = v
+		}
+	}
+	scope := values["S"]
+	if scope != "U" && scope != "C" {
+		return 0, fmt.Errorf("CVSS vector %q: missing scope", vector)
+	}
+	w := map[string]float64{}
+	for metric, weights := range cvss3Weights {
+		value, ok := weights[values[metric]]
+		if !ok {
+			return 0, fmt.Errorf("CVSS vector %q: missing or invalid %s", vector, metric)
+		}
+		w[metric] = value
+	}
+	// Privileges weigh more when the scope changes.
+	if scope == "C" {
+		switch values["PR"] {
+		case "L":
+			w["PR"] = 0.68
+		case "H":
+			w["PR"] = 0.5
+		}
+	}
+
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	impact := 6.42 * iss
+	if scope == "C" {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * w["PR"] * w["UI"]
+	if scope == "C" {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), nil
+}
+
+// roundUp rounds to one decimal upwards, avoiding floating point artefacts
+// as described in appendix A of the v3.1 specification.
+func roundUp(x float64) float64 {
+	i := int64(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
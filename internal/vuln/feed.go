@@ -0,0 +1,164 @@
+// internal/vuln/feed.go
+
+// Package vuln reads offline vulnerability feeds and matches the installed
+// applications reported by agents against them.
+//
+// Two feed formats are understood: OSV (a single advisory, a JSON array of
+// advisories, or a zip of advisory files as published by osv.dev) and the
+// NVD CVE API 2.0 JSON. Either may be gzip-compressed. Products are keyed
+// as "vendor:product" (from NVD CPEs) or "*:name" (OSV packages) using
+// software.ProductKey, so they can be compared with software.Candidates.
+package vuln
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"agent_server/internal/model"
+)
+
+// Feed formats accepted by ParseFeed.
+const (
+	FormatAuto = ""
+	FormatOSV  = "osv"
+	FormatNVD  = "nvd"
+)
+
+// ErrInvalidFeed is returned for feeds that cannot be read.
+var ErrInvalidFeed = errors.New("invalid vulnerability feed")
+
+// ParseFeed decodes a feed in the given format, detecting it when format is
+// FormatAuto. Advisories without any affected product are dropped, and when
+// an ID appears twice the last one wins.
+func ParseFeed(data []byte, format string) ([]model.Vulnerability, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+	}
+
+	var vulns []model.Vulnerability
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		vulns, err = parseZip(data, format)
+	case format == FormatOSV:
+		vulns, err = parseOSV(data)
+	case format == FormatNVD:
+		vulns, err = parseNVD(data)
+	case format == FormatAuto:
+		if looksLikeNVD(data) {
+			vulns, err = parseNVD(data)
+		} else {
+			vulns, err = parseOSV(data)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q (want osv or nvd)", ErrInvalidFeed, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dedupe(vulns), nil
+}
+
+// parseZip reads every .json file of an archive, such as an osv.dev
+// ecosystem export.
+func parseZip(data []byte, format string) ([]model.Vulnerability, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+	var vulns []model.Vulnerability
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || path.Ext(f.Name) != ".json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFeed, f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFeed, f.Name, err)
+		}
+		parsed, err := ParseFeed(content, format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		vulns = append(vulns, parsed...)
+	}
+	return vulns, nil
+}
+
+// looksLikeNVD checks for the top-level keys of an NVD API response.
+func looksLikeNVD(data []byte) bool {
+	var probe struct {
+		Format          string            `json:"format"`
+		Vulnerabilities []json.RawMessage `json:"vulnerabilities"`
+	}
+	if json.Unmarshal(data, &probe) != nil {
+		return false
+	}
+	return strings.HasPrefix(probe.Format, "NVD_CVE") || len(probe.Vulnerabilities) > 0
+}
+
+func dedupe(vulns []model.Vulnerability) []model.Vulnerability {
+	index := make(map[string]int, len(vulns))
+	var out []model.Vulnerability
+	for _, v := range vulns {
+		if len(v.Affected) == 0 {
+			continue
+		}
+		if i, ok := index[v.VulnerabilityID]; ok {
+			out[i] = v
+			continue
+		}
+		index[v.VulnerabilityID] = len(out)
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].VulnerabilityID < out[j].VulnerabilityID })
+	return out
+}
+
+// severityForScore maps a CVSS base score to its qualitative rating.
+func severityForScore(score float64) string {
+	switch {
+	case score >= 9:
+		return model.SeverityCritical
+	case score >= 7:
+		return model.SeverityHigh
+	case score >= 4:
+		return model.SeverityMedium
+	case score > 0:
+		return model.SeverityLow
+	}
+	return model.SeverityUnknown
+}
+
+// normalizeSeverity accepts the ratings used by feeds ("HIGH", "Moderate").
+func normalizeSeverity(s string) string {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "CRITICAL":
+		return model.SeverityCritical
+	case "HIGH", "IMPORTANT":
+		return model.SeverityHigh
+	case "MEDIUM", "MODERATE":
+		return model.SeverityMedium
+	case "LOW":
+		return model.SeverityLow
+	}
+	return model.SeverityUnknown
+}
@@ -0,0 +1,176 @@
+package vuln_test
+
+import (
+	"agent_server/internal/model"
+	"agent_server/internal/software"
+	"agent_server/internal/vuln"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func parseFixture(t *testing.T, name, format string) []model.Vulnerability {
+	t.Helper()
+	vulns, err := vuln.ParseFeed(readFixture(t, name), format)
+	if err != nil {
+		t.Fatalf("ParseFeed(%s): %v", name, err)
+	}
+	return vulns
+}
+
+var (
+	osvWant = []model.Vulnerability{
+		{
+			VulnerabilityID: "GHSA-xxxx-yyyy-zzzz",
+			Summary:         "Prototype pollution in lodash",
+			Severity:        model.SeverityHigh,
+			Source:          vuln.FormatOSV,
+			Affected:        model.AffectedProducts{{Product: "*:lodash", Ranges: []model.VersionRange{{Start: "4.0.0"}}}},
+		},
+		{
+			VulnerabilityID: "OSV-2023-0001",
+			Aliases:         "CVE-2023-5678",
+			Summary:         "Excessive time spent in DH key generation.",
+			Severity:        model.SeverityMedium,
+			CVSSScore:       5.3,
+			Source:          vuln.FormatOSV,
+			Affected: model.AffectedProducts{{
+				Product: "*:openssl",
+				Ranges:  []model.VersionRange{{End: "1.1.1x"}, {Start: "3.0.0", End: "3.0.12", EndIncluded: true}},
+				// The GIT range is dropped: installed versions are never commits.
+				Versions: []string{"3.1.4"},
+			}},
+			PublishedAt: time.Date(2023, 11, 6, 16, 15, 0, 0, time.UTC),
+			ModifiedAt:  time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC),
+		},
+	}
+	nvdWant = []model.Vulnerability{
+		{
+			VulnerabilityID: "CVE-2023-4863",
+			Summary:         "Heap buffer overflow in libwebp in Google Chrome prior to 116.0.5845.187.",
+			Severity:        model.SeverityHigh,
+			CVSSScore:       8.8,
+			Source:          vuln.FormatNVD,
+			Affected: model.AffectedProducts{
+				{Product: "google:chrome", Ranges: []model.VersionRange{{End: "116.0.5845.187"}}},
+				{Product: "mozilla:firefox", Ranges: []model.VersionRange{{Start: "115.0", End: "117.0", EndIncluded: true}}, Versions: []string{"102.15.0"}},
+			},
+			PublishedAt: time.Date(2023, 9, 12, 15, 15, 24, 327e6, time.UTC),
+			ModifiedAt:  time.Date(2024, 1, 7, 3, 15, 8, 293e6, time.UTC),
+		},
+		{
+			VulnerabilityID: "CVE-2024-0001",
+			Summary:         `Every version of a\:b is affected.`,
+			Severity:        model.SeverityMedium,
+			CVSSScore:       5,
+			Source:          vuln.FormatNVD,
+			Affected:        model.AffectedProducts{{Product: "acme:a_b", Ranges: []model.VersionRange{{}}}},
+			PublishedAt:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			ModifiedAt:      time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+	}
+)
+
+func TestParseOSVFeed(t *testing.T) {
+	for _, format := range []string{vuln.FormatOSV, vuln.FormatAuto} {
+		if got := parseFixture(t, "osv.json", format); !reflect.DeepEqual(got, osvWant) {
+			t.Errorf("ParseFeed(osv.json, %q) =\n%+v\nwant\n%+v", format, got, osvWant)
+		}
+	}
+}
+
+func TestParseNVDFeed(t *testing.T) {
+	for _, format := range []string{vuln.FormatNVD, vuln.FormatAuto} {
+		if got := parseFixture(t, "nvd.json", format); !reflect.DeepEqual(got, nvdWant) {
+			t.Errorf("ParseFeed(nvd.json, %q) =\n%+v\nwant\n%+v", format, got, nvdWant)
+		}
+	}
+}
+
+func TestParseCompressedFeeds(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(readFixture(t, "nvd.json"))
+	zw.Close()
+	if got, err := vuln.ParseFeed(gz.Bytes(), vuln.FormatAuto); err != nil || !reflect.DeepEqual(got, nvdWant) {
+		t.Fatalf("ParseFeed(nvd.json.gz) = %+v, %v", got, err)
+	}
+
+	// An osv.dev export is a zip of one advisory per file.
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	for name, body := range map[string]string{
+		"GHSA-xxxx-yyyy-zzzz.json": `{"id": "GHSA-xxxx-yyyy-zzzz", "summary": "Prototype pollution in lodash", "database_specific": {"severity": "HIGH"},
+			"affected": [{"package": {"name": "lodash"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "4.0.0"}]}]}]}`,
+		"README.txt": "not an advisory",
+	} {
+		f, _ := w.Create(name)
+		f.Write([]byte(body))
+	}
+	w.Close()
+	got, err := vuln.ParseFeed(archive.Bytes(), vuln.FormatOSV)
+	if err != nil || !reflect.DeepEqual(got, osvWant[:1]) {
+		t.Fatalf("ParseFeed(osv.zip) = %+v, %v; want %+v", got, err, osvWant[:1])
+	}
+}
+
+func TestParseFeedRejectsInvalidInput(t *testing.T) {
+	for name, input := range map[string]struct {
+		data, format string
+	}{
+		"not json":       {`{"id":`, vuln.FormatOSV},
+		"osv without id": {`[{"summary": "no id", "affected": [{"package": {"name": "x"}, "versions": ["1"]}]}]`, vuln.FormatOSV},
+		"nvd without id": {`{"format": "NVD_CVE", "vulnerabilities": [{"cve": {}}]}`, vuln.FormatNVD},
+		"unknown format": {`[]`, "cve5"},
+	} {
+		if _, err := vuln.ParseFeed([]byte(input.data), input.format); !errors.Is(err, vuln.ErrInvalidFeed) {
+			t.Errorf("ParseFeed(%s) = %v, want ErrInvalidFeed", name, err)
+		}
+	}
+}
+
+func TestMatcherUsesFeedRanges(t *testing.T) {
+	m := vuln.NewMatcher(parseFixture(t, "osv.json", vuln.FormatAuto))
+	if v, ok := m.Lookup("cve-2023-5678"); !ok || v.VulnerabilityID != "OSV-2023-0001" {
+		t.Fatalf("Lookup(alias) = %+v, %v", v, ok)
+	}
+
+	tests := []struct {
+		version string
+		fixed   string
+		matched bool
+	}{
+		{"1.1.1w", "1.1.1x", true}, // the fix is the next patch letter
+		{"1.1.1x", "", false},
+		{"3.0.12", "", true}, // last_affected is included
+		{"3.0.13", "", false},
+		{"3.1.4", "", true},
+		{"3.1.5", "", false},
+	}
+	for _, tt := range tests {
+		matches := m.Match([]model.InstalledApplication{{Name: "OpenSSL", Version: tt.version}}, software.DefaultRules())
+		if !tt.matched {
+			if len(matches) != 0 {
+				t.Errorf("OpenSSL %s matched %+v, want no match", tt.version, matches)
+			}
+			continue
+		}
+		if len(matches) != 1 || matches[0].Vulnerability.VulnerabilityID != "OSV-2023-0001" || matches[0].FixedVersion != tt.fixed {
+			t.Errorf("OpenSSL %s matched %+v, want OSV-2023-0001 fixed in %q", tt.version, matches, tt.fixed)
+		}
+	}
+}
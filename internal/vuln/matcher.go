@@ -0,0 +1,121 @@
+// internal/vuln/matcher.go
+
+package vuln
+
+import (
+	"strings"
+
+	"agent_server/internal/model"
+	"agent_server/internal/software"
+)
+
+// Match is an installed application affected by a vulnerability.
+type Match struct {
+	Vulnerability *model.Vulnerability
+	App           model.InstalledApplication
+	Product       string // the product key that matched
+	FixedVersion  string // "" when no fix is known
+}
+
+type productEntry struct {
+	vuln     *model.Vulnerability
+	affected *model.AffectedProduct
+}
+
+// Matcher is an index of a feed by product key. It is immutable and safe
+// for concurrent use.
+type Matcher struct {
+	vulns     []model.Vulnerability
+	byProduct map[string][]productEntry
+	byID      map[string]*model.Vulnerability
+}
+
+// NewMatcher indexes vulns. The slice must not be modified afterwards.
+func NewMatcher(vulns []model.Vulnerability) *Matcher {
+	m := &Matcher{
+		vulns:     vulns,
+		byProduct: make(map[string][]productEntry),
+		byID:      make(map[string]*model.Vulnerability, len(vulns)),
+	}
+	for i := range vulns {
+		v := &vulns[i]
+		m.byID[strings.ToUpper(v.VulnerabilityID)] = v
+		for _, alias := range v.AliasList() {
+			if _, ok := m.byID[strings.ToUpper(alias)]; !ok {
+				m.byID[strings.ToUpper(alias)] = v
+			}
+		}
+		for j := range v.Affected {
+			a := &v.Affected[j]
+			m.byProduct[a.Product] = append(m.byProduct[a.Product], productEntry{vuln: v, affected: a})
+		}
+	}
+	return m
+}
+
+// Len returns the number of vulnerabilities in the feed.
+func (m *Matcher) Len() int {
+	return len(m.vulns)
+}
+
+// Lookup finds a vulnerability by its ID or one of its aliases, ignoring case.
+func (m *Matcher) Lookup(id string) (*model.Vulnerability, bool) {
+	v, ok := m.byID[strings.ToUpper(strings.TrimSpace(id))]
+	return v, ok
+}
+
+// Match returns the vulnerabilities affecting apps, at most one per
+// application and vulnerability. Applications without a version are
+// skipped. Catalog rules, if any, add the canonical product key and
+// version scheme of known applications.
+func (m *Matcher) Match(apps []model.InstalledApplication, rules *software.Rules) []Match {
+	var matches []Match
+	for _, app := range apps {
+		if strings.TrimSpace(app.Version) == "" {
+			continue
+		}
+		scheme := rules.Normalize(app.Name, app.Publisher).Scheme
+		seen := map[*model.Vulnerability]bool{}
+		for _, key := range rules.Candidates(app.Name, app.Publisher) {
+			for _, e := range m.byProduct[key] {
+				if seen[e.vuln] {
+					continue
+				}
+				if fixed, ok := affects(e.affected, app.Version, scheme); ok {
+					seen[e.vuln] = true
+					matches = append(matches, Match{Vulnerability: e.vuln, App: app, Product: key, FixedVersion: fixed})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// affects reports whether version is affected and, for a range with a
+// known fix, the first fixed version.
+func affects(a *model.AffectedProduct, version, scheme string) (string, bool) {
+	for _, v := range a.Versions {
+		if software.Compare(scheme, version, v) == 0 {
+			return "", true
+		}
+	}
+	for _, r := range a.Ranges {
+		if r.Start != "" {
+			c := software.Compare(scheme, version, r.Start)
+			if c < 0 || (c == 0 && r.StartExcluded) {
+				continue
+			}
+		}
+		if r.End != "" {
+			c := software.Compare(scheme, version, r.End)
+			if c > 0 || (c == 0 && !r.EndIncluded) {
+				continue
+			}
+		}
+		if r.EndIncluded {
+			return "", true
+		}
+		return r.End, true
+	}
+	return "", false
+}
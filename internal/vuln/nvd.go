@@ -0,0 +1,178 @@
+// internal/vuln/nvd.go
+
+package vuln
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"agent_server/internal/model"
+	"agent_server/internal/software"
+)
+
+// nvdTimeLayout is used by the NVD API for published/lastModified, which
+// carry no time zone.
+const nvdTimeLayout = "2006-01-02T15:04:05.000"
+
+// nvdFeed is the subset of the NVD CVE API 2.0 response the matcher needs.
+type nvdFeed struct {
+	Vulnerabilities []struct {
+		CVE nvdCVE `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+type nvdMetric struct {
+	CVSSData struct {
+		BaseScore    float64 `json:"baseScore"`
+		BaseSeverity string  `json:"baseSeverity"`
+	} `json:"cvssData"`
+	BaseSeverity string `json:"baseSeverity"` // v2 keeps it outside cvssData
+}
+
+type nvdCVE struct {
+	ID           string `json:"id"`
+	Published    string `json:"published"`
+	LastModified string `json:"lastModified"`
+	Descriptions []struct {
+		Lang  string `json:"lang"`
+		Value string `json:"value"`
+	} `json:"descriptions"`
+	Metrics struct {
+		V31 []nvdMetric `json:"cvssMetricV31"`
+		V30 []nvdMetric `json:"cvssMetricV30"`
+		V2  []nvdMetric `json:"cvssMetricV2"`
+	} `json:"metrics"`
+	Configurations []struct {
+		Nodes []struct {
+			CPEMatch []struct {
+				Vulnerable            bool   `json:"vulnerable"`
+				Criteria              string `json:"criteria"`
+				VersionStartIncluding string `json:"versionStartIncluding"`
+				VersionStartExcluding string `json:"versionStartExcluding"`
+				VersionEndIncluding   string `json:"versionEndIncluding"`
+				VersionEndExcluding   string `json:"versionEndExcluding"`
+			} `json:"cpeMatch"`
+		} `json:"nodes"`
+	} `json:"configurations"`
+}
+
+func parseNVD(data []byte) ([]model.Vulnerability, error) {
+	var feed nvdFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+	vulns := make([]model.Vulnerability, 0, len(feed.Vulnerabilities))
+	for _, item := range feed.Vulnerabilities {
+		if item.CVE.ID == "" {
+			return nil, fmt.Errorf("%w: NVD entry without id", ErrInvalidFeed)
+		}
+		vulns = append(vulns, item.CVE.toModel())
+	}
+	return vulns, nil
+}
+
+func (c nvdCVE) toModel() model.Vulnerability {
+	v := model.Vulnerability{
+		VulnerabilityID: c.ID,
+		Source:          FormatNVD,
+		Severity:        model.SeverityUnknown,
+	}
+	v.PublishedAt, _ = time.Parse(nvdTimeLayout, c.Published)
+	v.ModifiedAt, _ = time.Parse(nvdTimeLayout, c.LastModified)
+	for _, d := range c.Descriptions {
+		if d.Lang == "en" {
+			v.Summary = firstLine(d.Value)
+			break
+		}
+	}
+	for _, metrics := range [][]nvdMetric{c.Metrics.V31, c.Metrics.V30, c.Metrics.V2} {
+		if len(metrics) == 0 {
+			continue
+		}
+		m := metrics[0]
+		v.CVSSScore = m.CVSSData.BaseScore
+		v.Severity = normalizeSeverity(m.CVSSData.BaseSeverity + m.BaseSeverity)
+		if v.Severity == model.SeverityUnknown {
+			v.Severity = severityForScore(v.CVSSScore)
+		}
+		break
+	}
+
+	byProduct := map[string]*model.AffectedProduct{}
+	for _, conf := range c.Configurations {
+		for _, node := range conf.Nodes {
+			for _, m := range node.CPEMatch {
+				if !m.Vulnerable {
+					continue
+				}
+				part, vendor, product, version, ok := splitCPE(m.Criteria)
+				// Only applications: operating systems and hardware are
+				// not reported as installed apps.
+				if !ok || part != "a" {
+					continue
+				}
+				key := software.ProductKey(vendor, product)
+				p := byProduct[key]
+				if p == nil {
+					p = &model.AffectedProduct{Product: key}
+					byProduct[key] = p
+				}
+				r := model.VersionRange{
+					Start: m.VersionStartIncluding,
+					End:   m.VersionEndExcluding,
+				}
+				if m.VersionStartExcluding != "" {
+					r.Start, r.StartExcluded = m.VersionStartExcluding, true
+				}
+				if m.VersionEndIncluding != "" {
+					r.End, r.EndIncluded = m.VersionEndIncluding, true
+				}
+				switch {
+				case r != model.VersionRange{}:
+					p.Ranges = append(p.Ranges, r)
+				case version == "*" || version == "-":
+					// Every version is affected.
+					p.Ranges = append(p.Ranges, r)
+				default:
+					p.Versions = append(p.Versions, version)
+				}
+			}
+		}
+	}
+	keys := make([]string, 0, len(byProduct))
+	for k := range byProduct {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v.Affected = append(v.Affected, *byProduct[k])
+	}
+	return v
+}
+
+// splitCPE extracts the fields of a CPE 2.3 formatted string
+// ("cpe:2.3:a:google:chrome:*:*:*:*:*:*:*:*"), removing escapes.
+func splitCPE(cpe string) (part, vendor, product, version string, ok bool) {
+	var fields []string
+	var cur strings.Builder
+	for i := 0; i < len(cpe); i++ {
+		switch {
+		case cpe[i] == '\\' && i+1 < len(cpe):
+			i++
+			cur.WriteByte(cpe[i])
+		case cpe[i] == ':':
+			fields = append(fields, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(cpe[i])
+		}
+	}
+	fields = append(fields, cur.String())
+	if len(fields) < 6 || fields[0] != "cpe" || fields[1] != "2.3" {
+		return "", "", "", "", false
+	}
+	return fields[2], fields[3], fields[4], fields[5], true
+}
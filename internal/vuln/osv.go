@@ -0,0 +1,167 @@
+// internal/vuln/osv.go
+
+package vuln
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"agent_server/internal/model"
+	"agent_server/internal/software"
+)
+
+// osvAdvisory is the subset of the OSV schema (https://ossf.github.io/osv-schema/)
+// the matcher needs.
+type osvAdvisory struct {
+	ID        string    `json:"id"`
+	Aliases   []string  `json:"aliases"`
+	Summary   string    `json:"summary"`
+	Details   string    `json:"details"`
+	Published time.Time `json:"published"`
+	Modified  time.Time `json:"modified"`
+	Severity  []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced   string `json:"introduced"`
+				Fixed        string `json:"fixed"`
+				LastAffected string `json:"last_affected"`
+			} `json:"events"`
+		} `json:"ranges"`
+		Versions []string `json:"versions"`
+	} `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// parseOSV accepts a single advisory, an array of advisories or an object
+// with a "vulns" array (the osv.dev query response).
+func parseOSV(data []byte) ([]model.Vulnerability, error) {
+	data = bytes.TrimSpace(data)
+	var advisories []osvAdvisory
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		if err := json.Unmarshal(data, &advisories); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+	default:
+		var wrapped struct {
+			Vulns []osvAdvisory `json:"vulns"`
+			osvAdvisory
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+		advisories = wrapped.Vulns
+		if wrapped.ID != "" {
+			advisories = append(advisories, wrapped.osvAdvisory)
+		}
+	}
+
+	vulns := make([]model.Vulnerability, 0, len(advisories))
+	for _, a := range advisories {
+		if a.ID == "" {
+			return nil, fmt.Errorf("%w: OSV advisory without id", ErrInvalidFeed)
+		}
+		vulns = append(vulns, a.toModel())
+	}
+	return vulns, nil
+}
+
+func (a osvAdvisory) toModel() model.Vulnerability {
+	v := model.Vulnerability{
+		VulnerabilityID: a.ID,
+		Aliases:         strings.Join(a.Aliases, ","),
+		Summary:         a.Summary,
+		Source:          FormatOSV,
+		PublishedAt:     a.Published,
+		ModifiedAt:      a.Modified,
+	}
+	if v.Summary == "" {
+		v.Summary = firstLine(a.Details)
+	}
+	for _, s := range a.Severity {
+		if strings.HasPrefix(s.Type, "CVSS_V3") {
+			if score, err := CVSS3BaseScore(s.Score); err == nil && score > v.CVSSScore {
+				v.CVSSScore = score
+			}
+		}
+	}
+	v.Severity = normalizeSeverity(a.DatabaseSpecific.Severity)
+	if v.Severity == model.SeverityUnknown {
+		v.Severity = severityForScore(v.CVSSScore)
+	}
+
+	byProduct := map[string]*model.AffectedProduct{}
+	for _, aff := range a.Affected {
+		if aff.Package.Name == "" {
+			continue
+		}
+		key := software.ProductKey("", aff.Package.Name)
+		p := byProduct[key]
+		if p == nil {
+			p = &model.AffectedProduct{Product: key}
+			byProduct[key] = p
+		}
+		p.Versions = append(p.Versions, aff.Versions...)
+		for _, r := range aff.Ranges {
+			// GIT ranges are commit hashes, which installed versions never are.
+			if r.Type == "GIT" {
+				continue
+			}
+			var open *model.VersionRange
+			for _, ev := range r.Events {
+				switch {
+				case ev.Introduced != "":
+					open = &model.VersionRange{}
+					if ev.Introduced != "0" {
+						open.Start = ev.Introduced
+					}
+				case open != nil && ev.Fixed != "":
+					open.End = ev.Fixed
+					p.Ranges = append(p.Ranges, *open)
+					open = nil
+				case open != nil && ev.LastAffected != "":
+					open.End, open.EndIncluded = ev.LastAffected, true
+					p.Ranges = append(p.Ranges, *open)
+					open = nil
+				}
+			}
+			if open != nil {
+				p.Ranges = append(p.Ranges, *open)
+			}
+		}
+	}
+	keys := make([]string, 0, len(byProduct))
+	for k, p := range byProduct {
+		if len(p.Ranges) > 0 || len(p.Versions) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v.Affected = append(v.Affected, *byProduct[k])
+	}
+	return v
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+// Package webhook signs and sends webhook requests.
+//
+// Every request carries these headers:
+//
+//	X-Webhook-Event:       event type, e.g. agent.offline
+//	X-Webhook-Event-Id:    unique event ID; retries and replays reuse it
+//	X-Webhook-Delivery:    outbox row ID
+//	X-Webhook-Signature:   t=<unix seconds>,v1=<hex HMAC-SHA256(secret, "<t>.<body>")>
+//
+// Receivers should check the signature with Verify and reject old timestamps
+// to stop replayed requests. Deliveries are sent in parallel and retried, so
+// receivers must order events by occurred_at and drop duplicate event IDs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Event-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// ErrInvalidSignature is returned by Verify for a missing, malformed, stale
+// or wrong signature.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the X-Webhook-Signature value for body sent at ts.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + mac(secret, t, body)
+}
+
+// Verify checks a signature header against body. Signatures older than
+// tolerance are rejected; a zero tolerance skips the age check.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			v1 = v
+		}
+	}
+	if t == "" || v1 == "" {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		unix, err := strconv.ParseInt(t, 10, 64)
+		if err != nil || time.Since(time.Unix(unix, 0)) > tolerance {
+			return ErrInvalidSignature
+		}
+	}
+	if !hmac.Equal([]byte(v1), []byte(mac(secret, t, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret, t string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Request is one signed POST to a subscriber.
+type Request struct {
+	URL        string
+	Secret     string
+	EventType  string
+	EventID    string
+	DeliveryID uint
+	Body       []byte
+}
+
+// Client sends webhook requests.
+type Client struct {
+	HTTP *http.Client
+}
+
+// NewClient creates a client that does not follow redirects, so a
+// subscriber cannot bounce signed payloads to another host.
+func NewClient() *Client {
+	return &Client{HTTP: &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Send posts the request and returns the response status code. Any status
+// outside 2xx is returned as an error together with the code.
+func (c *Client) Send(ctx context.Context, r Request) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "agent-server-webhooks/1")
+	req.Header.Set(HeaderEvent, r.EventType)
+	req.Header.Set(HeaderEventID, r.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(r.DeliveryID), 10))
+	req.Header.Set(HeaderSignature, Sign(r.Secret, time.Now(), r.Body))
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
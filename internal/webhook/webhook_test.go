@@ -0,0 +1,121 @@
+package webhook_test
+
+import (
+	"agent_server/internal/webhook"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var payload = []byte(`{"id":"evt-1","type":"agent.offline"}`)
+
+// The expected value was computed independently:
+//
+//	hmac.new(b"whsec_test", b"1700000000." + payload, hashlib.sha256).hexdigest()
+func TestSignMatchesKnownSignature(t *testing.T) {
+	got := webhook.Sign("whsec_test", time.Unix(1700000000, 0), payload)
+	want := "t=1700000000,v1=fe9815b802c7df37c478cbc34ee486ff09aa414c4a64ad01f3b93536dfd4623a"
+	if got != want {
+		t.Fatalf("Sign = %q, want %q", got, want)
+	}
+	if err := webhook.Verify("whsec_test", want, payload, 0); err != nil {
+		t.Fatalf("Verify(known signature) = %v", err)
+	}
+}
+
+func TestVerifyRejectsBadSignatures(t *testing.T) {
+	now := time.Now()
+	valid := webhook.Sign("whsec_test", now, payload)
+	if err := webhook.Verify("whsec_test", valid, payload, time.Minute); err != nil {
+		t.Fatalf("Verify(valid) = %v", err)
+	}
+	tests := []struct {
+		name, secret, header string
+		body                 []byte
+	}{
+		{"wrong secret", "other", valid, payload},
+		{"changed body", "whsec_test", valid, []byte(`{"id":"evt-2","type":"agent.offline"}`)},
+		{"stale", "whsec_test", webhook.Sign("whsec_test", now.Add(-time.Hour), payload), payload},
+		{"empty", "whsec_test", "", payload},
+		{"no v1", "whsec_test", "t=" + strconv.FormatInt(now.Unix(), 10), payload},
+		{"no t", "whsec_test", "v1=fe9815b802c7df37c478cbc34ee486ff09aa414c4a64ad01f3b93536dfd4623a", payload},
+		{"bad t", "whsec_test", "t=soon,v1=00", payload},
+	}
+	for _, tt := range tests {
+		if err := webhook.Verify(tt.secret, tt.header, tt.body, time.Minute); !errors.Is(err, webhook.ErrInvalidSignature) {
+			t.Errorf("Verify(%s) = %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+}
+
+func TestClientSendsSignedHeaders(t *testing.T) {
+	var got http.Header
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	code, err := webhook.NewClient().Send(context.Background(), webhook.Request{
+		URL:        srv.URL,
+		Secret:     "whsec_test",
+		EventType:  "agent.offline",
+		EventID:    "evt-1",
+		DeliveryID: 42,
+		Body:       payload,
+	})
+	if err != nil || code != http.StatusAccepted {
+		t.Fatalf("Send = %d, %v; want 202, nil", code, err)
+	}
+	if string(body) != string(payload) {
+		t.Fatalf("body = %s, want %s", body, payload)
+	}
+	for header, want := range map[string]string{
+		"Content-Type":         "application/json",
+		webhook.HeaderEvent:    "agent.offline",
+		webhook.HeaderEventID:  "evt-1",
+		webhook.HeaderDelivery: "42",
+	} {
+		if got.Get(header) != want {
+			t.Errorf("%s = %q, want %q", header, got.Get(header), want)
+		}
+	}
+	signature := got.Get(webhook.HeaderSignature)
+	if !regexp.MustCompile(`^t=\d+,v1=[0-9a-f]{64}$`).MatchString(signature) {
+		t.Fatalf("%s = %q, want t=<unix seconds>,v1=<hex HMAC-SHA256>", webhook.HeaderSignature, signature)
+	}
+	if err := webhook.Verify("whsec_test", signature, body, time.Minute); err != nil {
+		t.Fatalf("Verify(sent signature) = %v", err)
+	}
+}
+
+func TestClientReportsFailuresAndDoesNotFollowRedirects(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("signed payload followed a redirect to another host")
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, other.URL, http.StatusTemporaryRedirect)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := webhook.NewClient()
+	if code, err := client.Send(context.Background(), webhook.Request{URL: srv.URL, Body: payload}); err == nil || code != http.StatusServiceUnavailable {
+		t.Fatalf("Send to a failing subscriber = %d, %v; want 503 and an error", code, err)
+	}
+	if code, err := client.Send(context.Background(), webhook.Request{URL: srv.URL + "/moved", Body: payload}); err == nil || code != http.StatusTemporaryRedirect {
+		t.Fatalf("Send to a redirect = %d, %v; want 307 and an error", code, err)
+	}
+}
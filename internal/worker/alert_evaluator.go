@@ -0,0 +1,84 @@
+// internal/worker/alert_evaluator.go
+
+package worker
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/usecase"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// AlertEvaluator يقيّم قواعد التنبيه الدورية كل evaluate_interval_seconds
+// ويستمع إلى ناقل الأحداث لقواعد الجدار الناري والتطبيقات، فلا يبطئ تقييمها الطلب الذي نشر الحدث
+type AlertEvaluator struct {
+	alertLogic usecase.AlertUseCase
+	events     *usecase.EventBus
+	cfg        config.Provider
+}
+
+// NewAlertEvaluator هو المُصنِّع لمقيّم التنبيهات
+func NewAlertEvaluator(logic usecase.AlertUseCase, events *usecase.EventBus, cfg config.Provider) *AlertEvaluator {
+	return &AlertEvaluator{alertLogic: logic, events: events, cfg: cfg}
+}
+
+// Run يعمل حتى إلغاء السياق
+func (e *AlertEvaluator) Run(ctx context.Context) {
+	log.Println("✅ Starting alert evaluator...")
+
+	var last uint64
+	sub := e.subscribe(last)
+	if sub == nil {
+		return
+	}
+	defer func() { sub.Close() }()
+
+	timer := time.NewTimer(e.cfg.Current().Alerts.EvaluateInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok && errors.Is(sub.Err(), usecase.ErrBusClosed) {
+				// أُغلق الناقل لأن الخادم يتوقف
+				return
+			}
+			if !ok {
+				// تأخر المقيّم عن الأحداث فأسقطه الناقل، نكمل من آخر حدث وصلنا
+				logging.Errorf("❌ Alert evaluator dropped from the event stream: %v", sub.Err())
+				if sub = e.subscribe(last); sub == nil {
+					return
+				}
+				continue
+			}
+			last = event.Sequence
+			e.alertLogic.HandleEvent(event)
+			continue
+		case <-timer.C:
+		}
+
+		if err := e.alertLogic.EvaluateAll(time.Now()); err != nil {
+			logging.Errorf("❌ Error during alert evaluation: %v", err)
+		}
+		timer.Reset(e.cfg.Current().Alerts.EvaluateInterval())
+	}
+}
+
+// subscribe يستأنف بعد last، وإن لم تعد الأحداث محفوظة يبدأ من الأحداث الجديدة
+// ويعيد nil إن كان الناقل قد أُغلق
+func (e *AlertEvaluator) subscribe(last uint64) *usecase.Subscription {
+	filter := usecase.EventFilter{EventTypes: usecase.AlertEventTypes}
+	sub, err := e.events.Subscribe(filter, e.events.Epoch(), last)
+	if err != nil {
+		logging.Warnf("Alert evaluator missed events after sequence %d: %v", last, err)
+		if sub, err = e.events.Subscribe(filter, "", 0); err != nil {
+			return nil // لا يفشل بدون تسلسل إلا بعد إغلاق الناقل
+		}
+	}
+	return sub
+}
@@ -0,0 +1,50 @@
+// internal/worker/compliance_evaluator.go
+
+package worker
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/usecase"
+	"context"
+	"log"
+	"time"
+)
+
+// ComplianceEvaluator يعيد تقييم سياسات الامتثال لكل الوكلاء بشكل دوري
+// التقارير الجديدة تُقيّم فورًا عبر ناقل الأحداث، وهذه الدورة مطلوبة للفحوص المعتمدة على الوقت
+// مثل reported_within التي تفشل دون أن يرسل الوكيل أي شيء
+type ComplianceEvaluator struct {
+	complianceLogic usecase.ComplianceUseCase
+	cfg             config.Provider
+}
+
+// NewComplianceEvaluator هو المُصنِّع لمقيّم الامتثال الدوري
+func NewComplianceEvaluator(logic usecase.ComplianceUseCase, cfg config.Provider) *ComplianceEvaluator {
+	return &ComplianceEvaluator{complianceLogic: logic, cfg: cfg}
+}
+
+// Run يعمل حتى إلغاء السياق
+func (e *ComplianceEvaluator) Run(ctx context.Context) {
+	log.Println("✅ Starting compliance evaluator...")
+
+	timer := time.NewTimer(e.cfg.Current().Compliance.EvaluateInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		started := time.Now()
+		n, err := e.complianceLogic.EvaluateAll()
+		if err != nil {
+			logging.Errorf("❌ Error during compliance evaluation: %v", err)
+		} else if n > 0 {
+			logging.Debugf("Evaluated compliance of %d agents in %s.", n, time.Since(started).Round(time.Millisecond))
+		}
+		timer.Reset(e.cfg.Current().Compliance.EvaluateInterval())
+	}
+}
@@ -0,0 +1,48 @@
+// internal/worker/metrics_rollup.go
+
+package worker
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/usecase"
+	"context"
+	"log"
+	"time"
+)
+
+// MetricsRollup يجمع مقاييس الموارد بشكل دوري: العينات الخام إلى فترات 5 دقائق ثم إلى ساعات
+// ويحذف النقاط التي تجاوزت مدة الاحتفاظ لكل دقة
+type MetricsRollup struct {
+	metricsLogic usecase.MetricsUseCase
+	cfg          config.Provider
+}
+
+// NewMetricsRollup هو المُصنِّع لمجمّع المقاييس الدوري
+func NewMetricsRollup(logic usecase.MetricsUseCase, cfg config.Provider) *MetricsRollup {
+	return &MetricsRollup{metricsLogic: logic, cfg: cfg}
+}
+
+// Run يعمل حتى إلغاء السياق
+func (r *MetricsRollup) Run(ctx context.Context) {
+	log.Println("✅ Starting metrics rollup...")
+
+	timer := time.NewTimer(r.cfg.Current().Metrics.RollupInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		started := time.Now()
+		if err := r.metricsLogic.Rollup(started); err != nil {
+			logging.Errorf("❌ Error during metrics rollup: %v", err)
+		} else {
+			logging.Debugf("Rolled up metrics in %s.", time.Since(started).Round(time.Millisecond))
+		}
+		timer.Reset(r.cfg.Current().Metrics.RollupInterval())
+	}
+}
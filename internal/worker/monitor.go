@@ -3,42 +3,70 @@
 package worker
 
 import (
-	"agent_server/internal/usecase" 
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/usecase"
 	"log"
 	"time"
 )
 
-// Monitor هو الهيكل الذي يمثل تغير حاله الوكيل 
+// Monitor هو الهيكل الذي يمثل تغير حاله الوكيل
 type Monitor struct {
-	agentLogic usecase.AgentUseCase // يعتمد على  (منطق العمل) ليقوم بالفعل
+	agentLogic      usecase.AgentUseCase      // يعتمد على  (منطق العمل) ليقوم بالفعل
+	inventoryLogic  usecase.InventoryUseCase  // لتطبيق سياسة الاحتفاظ على الجرد الموسع
+	patchLogic      usecase.PatchUseCase      // لتطبيق سياسة الاحتفاظ على تقارير التحديثات
+	collectionLogic usecase.CollectionUseCase // لحذف الملفات المجمعة بعد انتهاء مدة الاحتفاظ
+	jobLogic        usecase.JobUseCase        // لحذف سجل تشغيلات المهام المجدولة القديمة
+	cfg             config.Provider           // يقرأ الإعدادات الحالية في كل دورة لدعم التحديث أثناء التشغيل
 }
 
 // NewMonitor هو المُصنِّع لتغير الحاله الجديد
-func NewMonitor(logic usecase.AgentUseCase) *Monitor {
-	return &Monitor{agentLogic: logic}
+func NewMonitor(logic usecase.AgentUseCase, inventory usecase.InventoryUseCase, patches usecase.PatchUseCase, collections usecase.CollectionUseCase, jobs usecase.JobUseCase, cfg config.Provider) *Monitor {
+	return &Monitor{agentLogic: logic, inventoryLogic: inventory, patchLogic: patches, collectionLogic: collections, jobLogic: jobs, cfg: cfg}
 }
 
 // Start يبدأ عملية المراقبة الدورية في الخلفية
 func (m *Monitor) Start() {
 	log.Println("✅ Starting offline agent monitor...")
 
-	
-	ticker := time.NewTicker(1 * time.Minute) 
-	defer ticker.Stop()
+	// نستخدم مؤقتًا يُعاد ضبطه في كل دورة بدل Ticker ثابت
+	// حتى يُطبق أي تغيير على monitor_interval_seconds دون إعادة تشغيل
+	timer := time.NewTimer(m.cfg.Current().Server.MonitorInterval())
+	defer timer.Stop()
 
-	
-	for range ticker.C {
-		log.Println("Inspector at work: Checking for offline agents...")
-
-		
-		err := m.agentLogic.MarkOfflineAgents()
-		if err != nil {
-			log.Printf("❌ Error during offline agent check: %v", err)
-		}
+	for range timer.C {
+		cfg := m.cfg.Current()
+		m.runOnce(cfg)
+		timer.Reset(cfg.Server.MonitorInterval())
 	}
+}
 
+// runOnce ينفذ دورة واحدة: فحص الوكلاء غير المتصلين ثم تطبيق سياسة الاحتفاظ
+func (m *Monitor) runOnce(cfg *config.Config) {
+	logging.Debugf("Inspector at work: Checking for offline agents...")
 
+	if err := m.agentLogic.MarkOfflineAgents(cfg.Server.OfflineThreshold()); err != nil {
+		logging.Errorf("❌ Error during offline agent check: %v", err)
+	}
 
-
-
-}
\ No newline at end of file
+	const day = 24 * time.Hour
+	firewallAge := time.Duration(cfg.Retention.FirewallRulesDays) * day
+	appsAge := time.Duration(cfg.Retention.InstalledAppsDays) * day
+	if err := m.agentLogic.PurgeExpiredReports(firewallAge, appsAge); err != nil {
+		logging.Errorf("❌ Error while applying retention policy: %v", err)
+	}
+	inventoryAge := time.Duration(cfg.Retention.HostInventoryDays) * day
+	processesAge := time.Duration(cfg.Retention.ProcessesDays) * day
+	if err := m.inventoryLogic.PurgeExpiredReports(inventoryAge, processesAge); err != nil {
+		logging.Errorf("❌ Error while applying inventory retention policy: %v", err)
+	}
+	if err := m.patchLogic.PurgeExpiredReports(time.Duration(cfg.Retention.PatchesDays) * day); err != nil {
+		logging.Errorf("❌ Error while applying patch retention policy: %v", err)
+	}
+	if err := m.collectionLogic.PurgeExpired(time.Duration(cfg.Retention.CollectedFilesDays) * day); err != nil {
+		logging.Errorf("❌ Error while applying collected file retention policy: %v", err)
+	}
+	if err := m.jobLogic.PurgeExpiredRuns(time.Duration(cfg.Retention.JobRunsDays) * day); err != nil {
+		logging.Errorf("❌ Error while applying scheduled job run retention policy: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+// internal/worker/scheduler.go
+
+package worker
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/usecase"
+	"context"
+	"log"
+	"time"
+)
+
+// Scheduler يشغّل المهام المجدولة كل interval_seconds: يبدأ التشغيلات المستحقة
+// ويرسل الأوامر للوكلاء المنتظرين كلما أنهى وكلاء آخرون أوامرهم
+type Scheduler struct {
+	jobLogic usecase.JobUseCase
+	cfg      config.Provider
+}
+
+// NewScheduler هو المُصنِّع لمشغل المهام المجدولة
+func NewScheduler(logic usecase.JobUseCase, cfg config.Provider) *Scheduler {
+	return &Scheduler{jobLogic: logic, cfg: cfg}
+}
+
+// Run يعمل حتى إلغاء السياق
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Println("✅ Starting job scheduler...")
+
+	// الدورة الأولى فورًا حتى تُعالج المواعيد التي فاتت أثناء توقف الخادم
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if err := s.jobLogic.Tick(time.Now()); err != nil {
+			logging.Errorf("❌ Error while running scheduled jobs: %v", err)
+		}
+		timer.Reset(s.cfg.Current().Scheduler.Interval())
+	}
+}
@@ -0,0 +1,55 @@
+// internal/worker/webhook_dispatcher.go
+
+package worker
+
+import (
+	"agent_server/internal/config"
+	"agent_server/internal/logging"
+	"agent_server/internal/usecase"
+	"context"
+	"log"
+	"time"
+)
+
+// WebhookDispatcher يسلم الأحداث من جدول الـ outbox إلى الاشتراكات بشكل دوري
+type WebhookDispatcher struct {
+	webhookLogic usecase.WebhookUseCase
+	cfg          config.Provider
+}
+
+// NewWebhookDispatcher هو المُصنِّع لموزع الـ webhooks
+func NewWebhookDispatcher(logic usecase.WebhookUseCase, cfg config.Provider) *WebhookDispatcher {
+	return &WebhookDispatcher{webhookLogic: logic, cfg: cfg}
+}
+
+// Run يعمل حتى إلغاء السياق، والتسليمات التي لم تكتمل تبقى في الجدول للمحاولة لاحقًا
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	log.Println("✅ Starting webhook dispatcher...")
+
+	timer := time.NewTimer(d.cfg.Current().Webhooks.PollInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		cfg := d.cfg.Current().Webhooks
+		if cfg.Enabled {
+			// نكمل مباشرة ما دامت هناك دفعات ممتلئة بدل انتظار الدورة التالية
+			for ctx.Err() == nil {
+				n, err := d.webhookLogic.DispatchDue(ctx)
+				if err != nil {
+					logging.Errorf("❌ Error while dispatching webhooks: %v", err)
+					break
+				}
+				if n < cfg.BatchSize {
+					break
+				}
+			}
+		}
+		timer.Reset(cfg.PollInterval())
+	}
+}